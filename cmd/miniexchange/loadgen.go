@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/loadgen"
+)
+
+// runLoadgen runs the loadgen subcommand and returns the exit code:
+//
+//	miniexchange loadgen [flags]
+//
+// It registers brokers through the HTTP API, sends them an order flow over
+// HTTP or the binary order entry protocol, and prints throughput, errors
+// and latency for every endpoint. Interrupting it stops the load early and
+// still prints the report.
+func runLoadgen(args []string) int {
+	cfg := loadgen.DefaultConfig()
+	cfg.BrokerPrefix = "loadgen-" + strconv.FormatInt(time.Now().Unix(), 36)
+
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: miniexchange loadgen [flags]")
+		fs.PrintDefaults()
+	}
+	baseURL := fs.String("url", "http://localhost:8080", "Base URL of the HTTP API, used to register brokers and, with -protocol http, to send orders")
+	apiKey := fs.String("api-key", os.Getenv("ADMIN_API_KEY"), "Admin API key, used for every request and OUCH logon (default $ADMIN_API_KEY)")
+	protocol := fs.String("protocol", "http", "Protocol to send orders over: http or ouch")
+	ouchAddr := fs.String("ouch-addr", "localhost:9200", "Address of the binary order entry gateway, with -protocol ouch")
+	fs.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "Seed of the random generator choosing the order flow")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "How long to send load")
+	fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "Requests per second, sent whether or not earlier ones were answered; 0 sends back to back from every worker")
+	fs.StringVar(&cfg.Arrival, "arrival", cfg.Arrival, "Arrival process with -rate: poisson or uniform")
+	fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "Requests in flight at most")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "Timeout of a single request")
+	fs.IntVar(&cfg.Brokers, "brokers", cfg.Brokers, "Number of brokers to register and trade as")
+	fs.StringVar(&cfg.BrokerPrefix, "broker-prefix", cfg.BrokerPrefix, "Prefix of the registered broker IDs; unique per run by default")
+	fs.Float64Var(&cfg.Cash, "cash", cfg.Cash, "Initial cash of every broker, in dollars")
+	fs.Int64Var(&cfg.Shares, "shares", cfg.Shares, "Initial holding of every broker in every symbol")
+	mix := fs.String("mix", formatMix(cfg), "Relative weights of limit orders, market orders and cancels of accepted limit orders")
+	symbols := fs.String("symbols", strings.Join(cfg.Symbols, ","), "Comma-separated symbols to trade")
+	fs.Float64Var(&cfg.Price, "price", cfg.Price, "Centre of limit prices, in dollars")
+	fs.StringVar(&cfg.PriceDistribution, "price-dist", cfg.PriceDistribution, "Distribution of limit prices: normal or uniform")
+	fs.Float64Var(&cfg.PriceSpread, "price-spread", cfg.PriceSpread, "Standard deviation (normal) or half-width (uniform) of limit prices, in dollars")
+	fs.Int64Var(&cfg.MaxQuantity, "max-quantity", cfg.MaxQuantity, "Largest order quantity; quantities are uniform from 1")
+	fs.DurationVar(&cfg.Lifetime, "lifetime", cfg.Lifetime, "How long limit orders rest before expiring")
+	asJSON := fs.Bool("json", false, "Print the report as JSON, with full latency histograms")
+	distribution := fs.Bool("histogram", false, "Print every endpoint's full latency histogram")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if err := parseMix(*mix, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: -mix: %v\n", err)
+		return 2
+	}
+	cfg.Symbols = nil
+	for _, s := range strings.Split(*symbols, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			cfg.Symbols = append(cfg.Symbols, s)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	api := loadgen.NewHTTPTransport(*baseURL, *apiKey, cfg.Concurrency)
+	defer api.Close()
+	brokers := cfg.BrokerIDs()
+	for _, id := range brokers {
+		if err := api.RegisterBroker(ctx, id, cfg.Cash, cfg.Symbols, cfg.Shares); err != nil {
+			fmt.Fprintf(os.Stderr, "loadgen: register broker %s: %v\n", id, err)
+			return 1
+		}
+	}
+
+	var t loadgen.Transport = api
+	switch *protocol {
+	case "http":
+	case "ouch":
+		ot, err := loadgen.DialOUCH(*ouchAddr, *apiKey, brokers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+			return 1
+		}
+		defer ot.Close()
+		t = ot
+	default:
+		fmt.Fprintf(os.Stderr, "loadgen: unknown protocol %q\n", *protocol)
+		return 2
+	}
+
+	fmt.Fprintf(os.Stderr, "sending load over %s as %d brokers (%s-*) for %s\n", *protocol, cfg.Brokers, cfg.BrokerPrefix, cfg.Duration)
+	report, err := loadgen.Run(ctx, cfg, t)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout, *distribution)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// formatMix formats the order mix weights as -mix takes them.
+func formatMix(cfg loadgen.Config) string {
+	return fmt.Sprintf("limit=%g,market=%g,cancel=%g", cfg.LimitWeight, cfg.MarketWeight, cfg.CancelWeight)
+}
+
+// parseMix sets the order mix weights from a -mix value. Weights left out
+// are zero.
+func parseMix(s string, cfg *loadgen.Config) error {
+	cfg.LimitWeight, cfg.MarketWeight, cfg.CancelWeight = 0, 0, 0
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("%q is not name=weight", part)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("weight of %s: %w", name, err)
+		}
+		switch name {
+		case "limit":
+			cfg.LimitWeight = w
+		case "market":
+			cfg.MarketWeight = w
+		case "cancel":
+			cfg.CancelWeight = w
+		default:
+			return fmt.Errorf("unknown order kind %q; use limit, market or cancel", name)
+		}
+	}
+	return nil
+}
@@ -5,23 +5,44 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"google.golang.org/grpc"
+
+	"github.com/efreitasn/miniexchange/internal/audit"
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/config"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/fix"
 	"github.com/efreitasn/miniexchange/internal/handler"
+	"github.com/efreitasn/miniexchange/internal/itchfeed"
+	"github.com/efreitasn/miniexchange/internal/metrics"
+	"github.com/efreitasn/miniexchange/internal/ouchgw"
+	"github.com/efreitasn/miniexchange/internal/rpc"
 	"github.com/efreitasn/miniexchange/internal/service"
 	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/internal/tracing"
 )
 
 func main() {
 	healthcheck := flag.Bool("healthcheck", false, "Run health check against running server")
 	flag.Parse()
 
+	// Subcommands run offline, without the server's configuration.
+	switch flag.Arg(0) {
+	case "replay":
+		os.Exit(runReplay(flag.Args()[1:]))
+	case "simulate":
+		os.Exit(runSimulate(flag.Args()[1:]))
+	case "loadgen":
+		os.Exit(runLoadgen(flag.Args()[1:]))
+	}
+
 	// Handle -healthcheck flag: HTTP GET to localhost:PORT/healthz, exit 0/1.
 	if *healthcheck {
 		port := os.Getenv("PORT")
@@ -59,22 +80,60 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
+	// OpenTelemetry tracing. The propagator is installed even without an
+	// exporter so that incoming trace context reaches webhook deliveries.
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, cfg.TraceOTLPEndpoint, cfg.TraceSampleRatio, os.Stdout)
+	if err != nil {
+		logger.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	// Instantiate stores.
 	brokerStore := store.NewBrokerStore()
 	orderStore := store.NewOrderStore()
 	tradeStore := store.NewTradeStore()
 	webhookStore := store.NewWebhookStore()
+	apiKeyStore := store.NewAPIKeyStore()
+	deliveryStore := store.NewDeliveryStore(cfg.WebhookDeadLetters, cfg.WebhookDeliveryLog)
+
+	// Audit trail of every order lifecycle event, in rotating JSON Lines
+	// files.
+	auditLog, err := audit.Open(cfg.AuditLogDir, int64(cfg.AuditLogMaxSizeMB)<<20, cfg.AuditLogMaxFiles)
+	if err != nil {
+		logger.Error("failed to open audit log", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	auditSvc := service.NewAuditService(auditLog, logger)
 
 	// Domain.
 	symbols := domain.NewSymbolRegistry()
 
-	// Engine.
-	books := engine.NewBookManager()
-	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+	// Prometheus metrics, fed by book updates for the trade and depth
+	// metrics and by the components below for the rest.
+	mx := metrics.New()
 
-	// Services (webhook first — needed by expiry manager).
-	webhookSvc := service.NewWebhookService(webhookStore, brokerStore, cfg.WebhookTimeout)
-	brokerSvc := service.NewBrokerService(brokerStore, symbols)
+	// Webhook service first — the matcher and the expiry manager dispatch
+	// through it. Every dispatched event is also retained for the broker
+	// event stream.
+	eventStreamSvc := service.NewEventStreamService(brokerStore, cfg.EventBufferSize, clock.System)
+	webhookSvc := service.NewWebhookService(webhookStore, deliveryStore, brokerStore, cfg.WebhookTimeout, service.DeliveryPolicy{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		MaxAge:      cfg.WebhookMaxAge,
+		Workers:     cfg.WebhookWorkers,
+	}, eventStreamSvc, mx, clock.System)
+
+	// Engine. The server runs on the wall clock with random IDs; replay
+	// substitutes its own.
+	books := engine.NewBookManager(clock.System)
+	books.AddListener(mx)
+	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, webhookSvc, auditSvc, clock.System, clock.UUIDs)
+
+	// Services.
+	brokerSvc := service.NewBrokerService(brokerStore, symbols, clock.System)
+	apiKeySvc := service.NewAPIKeyService(apiKeyStore, brokerStore, cfg.AdminAPIKey)
+	if cfg.AdminAPIKey == "" {
+		logger.Warn("ADMIN_API_KEY is not set; brokers cannot be registered over HTTP or gRPC")
+	}
 
 	// Expiry manager (depends on webhook service as dispatcher).
 	expiryMgr := engine.NewExpiryManager(
@@ -83,13 +142,36 @@ func main() {
 		orderStore,
 		brokerStore,
 		webhookSvc,
+		mx,
+		auditSvc,
+		clock.System,
 	)
+	mx.WatchExpiryQueue(expiryMgr.ActiveOrderCount)
 
-	orderSvc := service.NewOrderService(matcher, expiryMgr, brokerStore, orderStore, tradeStore, webhookSvc, symbols)
+	orderSvc := service.NewOrderService(matcher, expiryMgr, brokerStore, orderStore, tradeStore, webhookSvc, symbols, mx, auditSvc, clock.System, clock.UUIDs)
 	stockSvc := service.NewStockService(tradeStore, books, matcher, cfg.VWAPWindow, symbols)
 
+	// Market data fan-out, fed by every book update.
+	marketDataSvc := service.NewMarketDataService(symbols, cfg.MarketDataBuffer)
+	books.AddListener(marketDataSvc)
+
+	// Candles, seeded from the trade store and kept current by book updates.
+	candleSvc := service.NewCandleService(tradeStore, symbols, clock.System)
+	books.AddListener(candleSvc)
+
+	// Rolling 24h ticker statistics, maintained the same way.
+	tickerSvc := service.NewTickerService(tradeStore, symbols, clock.System)
+	books.AddListener(tickerSvc)
+
+	// Binary (ITCH-style) market data feed, fed by the same book updates.
+	var itchFeed *itchfeed.Feed
+	if cfg.ITCHPort != 0 || cfg.ITCHUDPAddr != "" {
+		itchFeed = itchfeed.NewFeed(cfg.ITCHRingSize, logger)
+		books.AddListener(itchFeed)
+	}
+
 	// Router.
-	router := handler.NewRouter(brokerSvc, orderSvc, stockSvc, webhookSvc, logger)
+	router := handler.NewRouter(brokerSvc, orderSvc, stockSvc, webhookSvc, marketDataSvc, eventStreamSvc, candleSvc, tickerSvc, apiKeySvc, auditSvc, mx, logger)
 
 	// Start expiration goroutine with cancellable context.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -115,20 +197,163 @@ func main() {
 		}
 	}()
 
+	// FIX order entry and market data gateway, on its own port.
+	var fixAcceptor *fix.Acceptor
+	if cfg.FIXPort != 0 {
+		fixStore, err := fix.NewFileStore(cfg.FIXStoreDir)
+		if err != nil {
+			logger.Error("failed to open fix store", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fixAddr := fmt.Sprintf(":%d", cfg.FIXPort)
+		ln, err := net.Listen("tcp", fixAddr)
+		if err != nil {
+			logger.Error("failed to listen for fix", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fixAcceptor = fix.NewAcceptor(cfg.FIXCompID, fixStore, orderSvc, brokerSvc, apiKeySvc, marketDataSvc, eventStreamSvc, logger)
+		go func() {
+			logger.Info("fix acceptor starting", slog.String("addr", fixAddr), slog.String("comp_id", cfg.FIXCompID))
+			if err := fixAcceptor.Serve(ln); err != nil {
+				logger.Error("fix acceptor error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// gRPC API, backed by the same services as the router, on its own port.
+	var grpcSrv *grpc.Server
+	if cfg.GRPCPort != 0 {
+		grpcAddr := fmt.Sprintf(":%d", cfg.GRPCPort)
+		ln, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logger.Error("failed to listen for grpc", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		grpcSrv = rpc.NewServer(apiKeySvc, brokerSvc, orderSvc, stockSvc, marketDataSvc, eventStreamSvc, logger)
+		go func() {
+			logger.Info("grpc server starting", slog.String("addr", grpcAddr))
+			if err := grpcSrv.Serve(ln); err != nil {
+				logger.Error("grpc server error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Binary (OUCH-style) order entry gateway, on its own port.
+	var ouchGateway *ouchgw.Gateway
+	if cfg.OUCHPort != 0 {
+		ouchAddr := fmt.Sprintf(":%d", cfg.OUCHPort)
+		ln, err := net.Listen("tcp", ouchAddr)
+		if err != nil {
+			logger.Error("failed to listen for ouch", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		ouchGateway = ouchgw.NewGateway(orderSvc, brokerSvc, apiKeySvc, eventStreamSvc, logger)
+		go func() {
+			logger.Info("ouch gateway starting", slog.String("addr", ouchAddr))
+			if err := ouchGateway.Serve(ln); err != nil {
+				logger.Error("ouch gateway error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Market data feed outputs: UDP packets, and TCP replay plus UDP
+	// retransmission on ITCH_PORT.
+	if itchFeed != nil && cfg.ITCHUDPAddr != "" {
+		conn, err := net.Dial("udp", cfg.ITCHUDPAddr)
+		if err != nil {
+			logger.Error("failed to open itch udp socket", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("itch publisher starting", slog.String("addr", cfg.ITCHUDPAddr), slog.String("session", itchFeed.Session()))
+		itchFeed.Publish(conn)
+	}
+	if itchFeed != nil && cfg.ITCHPort != 0 {
+		itchAddr := fmt.Sprintf(":%d", cfg.ITCHPort)
+		ln, err := net.Listen("tcp", itchAddr)
+		if err != nil {
+			logger.Error("failed to listen for itch replay", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		pc, err := net.ListenPacket("udp", itchAddr)
+		if err != nil {
+			logger.Error("failed to listen for itch retransmission", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("itch replay and retransmission starting", slog.String("addr", itchAddr), slog.String("session", itchFeed.Session()))
+		go func() {
+			if err := itchFeed.ServeReplay(ln); err != nil {
+				logger.Error("itch replay error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+		go func() {
+			if err := itchFeed.ServeRetransmit(pc); err != nil {
+				logger.Error("itch retransmission error", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}()
+	}
+
 	// Wait for SIGINT/SIGTERM.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
 	logger.Info("shutdown signal received", slog.String("signal", sig.String()))
 
-	// Graceful shutdown: stop HTTP server, cancel context (stops expiry goroutine).
+	// Graceful shutdown: stop HTTP server, log out FIX sessions, stop gRPC
+	// server, end OUCH sessions, end the market data feed session, cancel
+	// context (stops expiry goroutine), stop webhook delivery, close the audit
+	// log, flush traces.
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("server shutdown error", slog.String("error", err.Error()))
 	}
+	if fixAcceptor != nil {
+		if err := fixAcceptor.Close(); err != nil {
+			logger.Error("fix acceptor shutdown error", slog.String("error", err.Error()))
+		}
+	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	if ouchGateway != nil {
+		if err := ouchGateway.Close(); err != nil {
+			logger.Error("ouch gateway shutdown error", slog.String("error", err.Error()))
+		}
+	}
+	if itchFeed != nil {
+		if err := itchFeed.Close(); err != nil {
+			logger.Error("itch feed shutdown error", slog.String("error", err.Error()))
+		}
+	}
 	cancel()
+	webhookSvc.Close()
+	if err := auditLog.Close(); err != nil {
+		logger.Error("audit log shutdown error", slog.String("error", err.Error()))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("tracing shutdown error", slog.String("error", err.Error()))
+	}
 
 	logger.Info("server stopped")
 }
+
+// stopGRPC drains in-flight calls, then cancels whatever is still running
+// (typically open streams) once ctx expires.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/efreitasn/miniexchange/internal/replay"
+)
+
+// runReplay runs the replay subcommand and returns the exit code:
+//
+//	miniexchange replay [-o output] [input]
+//
+// It reads JSON Lines commands from input, or standard input, and writes
+// the trades and final state to output, or standard output.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: miniexchange replay [-o output] [input]")
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "Write the output to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	res, err := replay.Run(in, w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d commands: %d trades, %d rejected, %d orders, %d brokers\n",
+		res.Commands, res.Trades, res.Rejected, res.Orders, res.Brokers)
+	return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/efreitasn/miniexchange/internal/simulate"
+)
+
+// runSimulate runs the simulate subcommand and returns the exit code:
+//
+//	miniexchange simulate [flags]
+//
+// It runs agent populations against a fresh exchange and prints a summary
+// of each symbol's trading and each population's activity.
+func runSimulate(args []string) int {
+	cfg := simulate.DefaultConfig()
+
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: miniexchange simulate [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "Seed of the random generator; the same seed and flags produce the same summary")
+	fs.IntVar(&cfg.Steps, "steps", cfg.Steps, "Number of steps to simulate")
+	fs.DurationVar(&cfg.StepInterval, "step-interval", cfg.StepInterval, "Virtual time between steps")
+	symbols := fs.String("symbols", strings.Join(cfg.Symbols, ","), "Comma-separated symbols to trade")
+	fs.Float64Var(&cfg.ReferencePrice, "price", cfg.ReferencePrice, "Initial reference price of every symbol, in dollars")
+	fs.Float64Var(&cfg.Volatility, "volatility", cfg.Volatility, "Standard deviation of the reference price's relative change per step")
+	fs.Float64Var(&cfg.Cash, "cash", cfg.Cash, "Initial cash of every agent, in dollars")
+	fs.Int64Var(&cfg.Shares, "shares", cfg.Shares, "Initial holding of every agent in every symbol")
+	fs.IntVar(&cfg.PathPoints, "path-points", cfg.PathPoints, "Price path samples per symbol in the summary")
+	fs.IntVar(&cfg.Noise.Count, "noise", cfg.Noise.Count, "Number of noise traders")
+	fs.Float64Var(&cfg.Noise.Rate, "noise-rate", cfg.Noise.Rate, "Probability that a noise trader sends an order in a step")
+	fs.Float64Var(&cfg.Noise.MarketRatio, "noise-market-ratio", cfg.Noise.MarketRatio, "Share of noise orders that are market orders")
+	fs.Float64Var(&cfg.Noise.PriceSigma, "noise-sigma", cfg.Noise.PriceSigma, "Standard deviation of a noise limit price's distance from the reference, relative to it")
+	fs.Int64Var(&cfg.Noise.MaxQuantity, "noise-max-quantity", cfg.Noise.MaxQuantity, "Largest noise order quantity")
+	fs.DurationVar(&cfg.Noise.Lifetime, "noise-lifetime", cfg.Noise.Lifetime, "How long noise limit orders rest before expiring")
+	fs.IntVar(&cfg.Maker.Count, "makers", cfg.Maker.Count, "Number of market makers")
+	fs.Float64Var(&cfg.Maker.HalfSpread, "maker-half-spread", cfg.Maker.HalfSpread, "Distance of each quote from the reference price, in dollars")
+	fs.Int64Var(&cfg.Maker.Quantity, "maker-quantity", cfg.Maker.Quantity, "Size of each quote")
+	fs.IntVar(&cfg.Momentum.Count, "momentum", cfg.Momentum.Count, "Number of momentum takers")
+	fs.IntVar(&cfg.Momentum.Lookback, "momentum-lookback", cfg.Momentum.Lookback, "Steps over which momentum takers measure the price move")
+	fs.Float64Var(&cfg.Momentum.Threshold, "momentum-threshold", cfg.Momentum.Threshold, "Relative price move that triggers a momentum order")
+	fs.Int64Var(&cfg.Momentum.Quantity, "momentum-quantity", cfg.Momentum.Quantity, "Momentum order quantity")
+	asJSON := fs.Bool("json", false, "Print the summary as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	cfg.Symbols = nil
+	for _, s := range strings.Split(*symbols, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			cfg.Symbols = append(cfg.Symbols, s)
+		}
+	}
+
+	summary, err := simulate.Run(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(summary)
+	} else {
+		err = writeSimulationSummary(os.Stdout, summary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeSimulationSummary prints a summary as tables, followed by each
+// symbol's price path.
+func writeSimulationSummary(out io.Writer, s *simulate.Summary) error {
+	fmt.Fprintf(out, "seed %d, %d steps over %s\n\n", s.Seed, s.Steps, s.Duration)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "SYMBOL\tTRADES\tVOLUME\tNOTIONAL\tVWAP\tOPEN\tHIGH\tLOW\tCLOSE\tREFERENCE\tAVG SPREAD")
+	for _, sym := range s.Symbols {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\t%s\t%s\t%s\t%s\t%s\t%.2f\t%s\n",
+			sym.Symbol, sym.Trades, sym.Volume, sym.Notional,
+			price(sym.VWAP), price(sym.Open), price(sym.High), price(sym.Low), price(sym.Close),
+			sym.ReferencePrice, price(sym.AverageSpread))
+	}
+
+	fmt.Fprintln(w, "\nPOPULATION\tAGENTS\tORDERS\tREJECTED\tCANCELLED\tBOUGHT\tSOLD\tPNL")
+	for _, pop := range s.Populations {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.2f\n",
+			pop.Population, pop.Agents, pop.Orders, pop.Rejected, pop.Cancelled, pop.Bought, pop.Sold, pop.PnL)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, sym := range s.Symbols {
+		points := []string{"-"}
+		if len(sym.Path) > 0 {
+			points = points[:0]
+		}
+		for _, p := range sym.Path {
+			points = append(points, fmt.Sprintf("%.2f", p.Price))
+		}
+		if _, err := fmt.Fprintf(out, "\n%s price path: %s\n", sym.Symbol, strings.Join(points, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// price formats an optional price, or "-" when there is none.
+func price(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *p)
+}
@@ -0,0 +1,371 @@
+// Package audit keeps the exchange's audit trail: one JSON record per order
+// lifecycle event, appended to a local file in JSON Lines and never
+// rewritten. The current file is rotated once it reaches a size limit, and
+// records can be read back by order ID across the current and rotated files.
+//
+// Append is safe to call on a nil *Log, which discards the record, so
+// components built without an audit log need no checks.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// currentFile is the name of the file records are appended to. Rotated
+// files are named audit-<UTC time>.jsonl, so they sort oldest first.
+const (
+	currentFile    = "audit.jsonl"
+	rotatedPrefix  = "audit-"
+	rotatedSuffix  = ".jsonl"
+	rotatedTimeFmt = "20060102T150405.000000000Z"
+)
+
+// Record is one audit event about an order.
+type Record struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	OrderID   string    `json:"order_id"`
+	BrokerID  string    `json:"broker_id"`
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	Type      string    `json:"type"`
+	Price     *float64  `json:"price,omitempty"`
+	Quantity  int64     `json:"quantity"`
+	ExpiresAt *string   `json:"expires_at,omitempty"`
+
+	// order.rejected
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// order.cancelled
+	Initiator string `json:"initiator,omitempty"`
+
+	// order.matched
+	Trade *Trade `json:"trade,omitempty"`
+
+	// order.amended
+	ReplacementOrderID string `json:"replacement_order_id,omitempty"`
+
+	Before        *State         `json:"before,omitempty"`
+	After         *State         `json:"after,omitempty"`
+	BalanceDeltas []BalanceDelta `json:"balance_deltas,omitempty"`
+}
+
+// Trade is the execution an order.matched record describes.
+type Trade struct {
+	TradeID             string  `json:"trade_id"`
+	Price               float64 `json:"price"`
+	Quantity            int64   `json:"quantity"`
+	Aggressor           bool    `json:"aggressor"`
+	CounterpartyOrderID string  `json:"counterparty_order_id"`
+}
+
+// State is an order's status and quantities before or after an event.
+type State struct {
+	Status            string   `json:"status"`
+	Price             *float64 `json:"price,omitempty"`
+	FilledQuantity    int64    `json:"filled_quantity"`
+	RemainingQuantity int64    `json:"remaining_quantity"`
+	CancelledQuantity int64    `json:"cancelled_quantity"`
+}
+
+// BalanceDelta is the change an event made to a broker's balance: cash and
+// reserved cash in dollars, and the quantity and reserved quantity held of
+// the order's symbol.
+type BalanceDelta struct {
+	BrokerID        string  `json:"broker_id"`
+	Cash            float64 `json:"cash"`
+	ReservedCash    float64 `json:"reserved_cash"`
+	Holding         int64   `json:"holding"`
+	ReservedHolding int64   `json:"reserved_holding"`
+}
+
+// Log appends records to the current file in a directory, rotating it once
+// it reaches maxSize bytes.
+type Log struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	seq     uint64
+	rotated time.Time // name time of the last rotated file
+}
+
+// Open opens the audit log in dir, creating the directory if needed. The
+// current file is rotated once appending a record would take it past
+// maxSize bytes. maxFiles is the number of rotated files kept, oldest
+// removed first; 0 keeps them all. Sequence numbers continue from the last
+// record on disk.
+func Open(dir string, maxSize int64, maxFiles int) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create audit log dir: %w", err)
+	}
+	l := &Log{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0 && l.seq == 0; i-- {
+		f, err := os.Open(files[i])
+		if err != nil {
+			return nil, err
+		}
+		err = readRecords(f, func(rec *Record) { l.seq = rec.Seq })
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.openCurrent(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// openCurrent opens the current file for appending. A torn final record
+// from a crash mid-write is terminated so the next record starts on a line
+// of its own.
+func (l *Log) openCurrent() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, currentFile), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, info.Size()
+
+	if l.size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, l.size-1); err != nil {
+			f.Close()
+			return err
+		}
+		if last[0] != '\n' {
+			n, err := f.Write([]byte{'\n'})
+			l.size += int64(n)
+			if err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Append assigns rec the next sequence number, and the current time if it
+// has none, and writes it as one line. A nil Log discards the record.
+func (l *Log) Append(rec *Record) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return errors.New("audit log is closed")
+	}
+
+	l.seq++
+	rec.Seq = l.seq
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// A failed rotation still leaves a file to write to, unless the current
+	// file could not be reopened either; the record is kept over the size
+	// limit.
+	var rotateErr error
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			rotateErr = fmt.Errorf("rotate audit log: %w", err)
+			if l.f == nil {
+				return rotateErr
+			}
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	return errors.Join(rotateErr, err)
+}
+
+// rotate renames the current file aside and starts a new one, then removes
+// the oldest rotated files beyond maxFiles. If the file cannot be renamed,
+// it is reopened to carry on appending to. l.mu must be held.
+func (l *Log) rotate() error {
+	err := l.f.Close()
+	l.f = nil
+	if err == nil {
+		// Names must not collide, even on a coarse clock.
+		now := time.Now().UTC()
+		if !now.After(l.rotated) {
+			now = l.rotated.Add(time.Nanosecond)
+		}
+		l.rotated = now
+		name := rotatedPrefix + now.Format(rotatedTimeFmt) + rotatedSuffix
+		err = os.Rename(filepath.Join(l.dir, currentFile), filepath.Join(l.dir, name))
+	}
+	if openErr := l.openCurrent(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if l.maxFiles == 0 {
+		return nil
+	}
+	rotated, err := l.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(rotated) > l.maxFiles {
+		if err := os.Remove(rotated[0]); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Query returns every record about orderID, as the order or as its
+// replacement, oldest first. It reads the rotated files and the current
+// file in full, without holding up appends: the files are opened together,
+// so a rotation meanwhile neither hides nor repeats records, and a record
+// being appended as the current file is read is left out.
+func (l *Log) Query(orderID string) ([]Record, error) {
+	if orderID == "" {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	files, err := l.openFiles()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var records []Record
+	for _, f := range files {
+		err := readRecords(f, func(rec *Record) {
+			if rec.OrderID == orderID || rec.ReplacementOrderID == orderID {
+				records = append(records, *rec)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// openFiles opens every file returned by files. l.mu must be held.
+func (l *Log) openFiles() ([]*os.File, error) {
+	paths, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*os.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Close closes the current file. Appending afterwards fails.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// files returns the rotated files, oldest first, followed by the current
+// file if it exists.
+func (l *Log) files() ([]string, error) {
+	files, err := l.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	current := filepath.Join(l.dir, currentFile)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files, nil
+}
+
+// rotatedFiles returns the rotated files, oldest first.
+func (l *Log) rotatedFiles() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasPrefix(name, rotatedPrefix) && strings.HasSuffix(name, rotatedSuffix) {
+			files = append(files, filepath.Join(l.dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readRecords calls fn for each record in f. A torn line left by a crash
+// mid-write is skipped.
+func readRecords(f *os.File, fn func(*Record)) error {
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec Record
+			if json.Unmarshal(line, &rec) == nil {
+				fn(&rec)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mustOpen opens a log in dir, failing the test on error.
+func mustOpen(t *testing.T, dir string, maxSize int64, maxFiles int) *Log {
+	t.Helper()
+	l, err := Open(dir, maxSize, maxFiles)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// mustAppend appends a record about orderID, failing the test on error.
+func mustAppend(t *testing.T, l *Log, event, orderID string) *Record {
+	t.Helper()
+	rec := &Record{Event: event, OrderID: orderID, BrokerID: "broker-1", Symbol: "AAPL"}
+	if err := l.Append(rec); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	return rec
+}
+
+// rotatedCount returns the number of rotated files in dir.
+func rotatedCount(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	n := 0
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), rotatedPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLog_AppendAndQuery(t *testing.T) {
+	l := mustOpen(t, t.TempDir(), 1<<20, 0)
+
+	mustAppend(t, l, "order.received", "o1")
+	mustAppend(t, l, "order.received", "o2")
+	mustAppend(t, l, "order.accepted", "o1")
+	amended := &Record{Event: "order.amended", OrderID: "o1", ReplacementOrderID: "o3"}
+	if err := l.Append(amended); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	records, err := l.Query("o1")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	var events []string
+	for _, rec := range records {
+		events = append(events, rec.Event)
+	}
+	if got := strings.Join(events, ","); got != "order.received,order.accepted,order.amended" {
+		t.Errorf("unexpected events for o1: %s", got)
+	}
+	if records[0].Seq != 1 || records[1].Seq != 3 || records[2].Seq != 4 {
+		t.Errorf("unexpected sequence numbers: %d, %d, %d", records[0].Seq, records[1].Seq, records[2].Seq)
+	}
+	if records[0].Time.IsZero() {
+		t.Error("expected Append to set the time")
+	}
+
+	// A replacement's trail includes the amendment that created it.
+	records, err = l.Query("o3")
+	if err != nil || len(records) != 1 || records[0].Event != "order.amended" {
+		t.Errorf("expected the amendment for o3, got %+v, %v", records, err)
+	}
+
+	for _, id := range []string{"unknown", ""} {
+		records, err = l.Query(id)
+		if err != nil || len(records) != 0 {
+			t.Errorf("%q: expected no records, got %+v, %v", id, records, err)
+		}
+	}
+}
+
+func TestLog_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	// Every record is well over 64 bytes, so each append rotates.
+	l := mustOpen(t, dir, 64, 0)
+
+	for i := 0; i < 4; i++ {
+		mustAppend(t, l, "order.received", "o1")
+	}
+	if n := rotatedCount(t, dir); n != 3 {
+		t.Fatalf("expected 3 rotated files, got %d", n)
+	}
+
+	records, err := l.Query("o1")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records across files, got %d", len(records))
+	}
+	for i, rec := range records {
+		if rec.Seq != uint64(i+1) {
+			t.Errorf("record %d: expected seq %d, got %d", i, i+1, rec.Seq)
+		}
+	}
+}
+
+func TestLog_FailedRotationKeepsAppending(t *testing.T) {
+	dir := t.TempDir()
+	l := mustOpen(t, dir, 64, 0)
+	mustAppend(t, l, "order.received", "o1")
+
+	// Put a non-empty directory where the next rotated file would go, so
+	// that renaming onto it fails.
+	l.rotated = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	blocker := filepath.Join(dir, rotatedPrefix+l.rotated.Add(time.Nanosecond).Format(rotatedTimeFmt)+rotatedSuffix)
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := l.Append(&Record{Event: "order.accepted", OrderID: "o1"}); err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	mustAppend(t, l, "order.cancelled", "o1")
+	records, err := l.Query("o1")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(records) != 3 || records[1].Event != "order.accepted" || records[2].Seq != 3 {
+		t.Errorf("expected every record kept, got %+v", records)
+	}
+}
+
+func TestLog_PrunesRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	l := mustOpen(t, dir, 64, 2)
+
+	for i := 0; i < 5; i++ {
+		mustAppend(t, l, "order.received", "o1")
+	}
+	if n := rotatedCount(t, dir); n != 2 {
+		t.Fatalf("expected 2 rotated files, got %d", n)
+	}
+
+	records, err := l.Query("o1")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(records) != 3 || records[0].Seq != 3 {
+		t.Errorf("expected the 3 newest records, got %+v", records)
+	}
+}
+
+func TestLog_ContinuesAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := mustOpen(t, dir, 1<<20, 0)
+	mustAppend(t, l, "order.received", "o1")
+	mustAppend(t, l, "order.accepted", "o1")
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := l.Append(&Record{Event: "order.cancelled", OrderID: "o1"}); err == nil {
+		t.Error("expected append to a closed log to fail")
+	}
+
+	l = mustOpen(t, dir, 1<<20, 0)
+	if rec := mustAppend(t, l, "order.cancelled", "o1"); rec.Seq != 3 {
+		t.Errorf("expected seq 3 after reopening, got %d", rec.Seq)
+	}
+	records, err := l.Query("o1")
+	if err != nil || len(records) != 3 {
+		t.Errorf("expected 3 records, got %+v, %v", records, err)
+	}
+}
+
+func TestLog_SkipsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	l := mustOpen(t, dir, 1<<20, 0)
+	mustAppend(t, l, "order.received", "o1")
+	l.Close()
+
+	// A crash mid-write leaves half a record at the end of the file.
+	f, err := os.OpenFile(filepath.Join(dir, currentFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	f.WriteString(`{"seq":2,"event":"order.acc`)
+	f.Close()
+
+	l = mustOpen(t, dir, 1<<20, 0)
+	if rec := mustAppend(t, l, "order.accepted", "o1"); rec.Seq != 2 {
+		t.Errorf("expected the torn record's seq to be reused, got %d", rec.Seq)
+	}
+	records, err := l.Query("o1")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(records) != 2 || records[1].Event != "order.accepted" {
+		t.Errorf("expected the torn record skipped, got %+v", records)
+	}
+}
+
+func TestLog_NilDiscards(t *testing.T) {
+	var l *Log
+	if err := l.Append(&Record{Event: "order.received"}); err != nil {
+		t.Errorf("expected a nil log to discard records, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtual(t *testing.T) {
+	start := time.Date(2026, 1, 15, 14, 30, 0, 0, time.UTC)
+	v := NewVirtual(start)
+	if !v.Now().Equal(start) {
+		t.Fatalf("expected %v, got %v", start, v.Now())
+	}
+
+	v.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !v.Now().Equal(want) {
+		t.Errorf("after Advance: expected %v, got %v", want, v.Now())
+	}
+
+	later := start.Add(time.Hour)
+	v.Set(later)
+	if !v.Now().Equal(later) {
+		t.Errorf("after Set: expected %v, got %v", later, v.Now())
+	}
+}
+
+func TestSequence(t *testing.T) {
+	s := NewSequence()
+	for _, want := range []string{
+		"00000000-0000-4000-8000-000000000001",
+		"00000000-0000-4000-8000-000000000002",
+		"00000000-0000-4000-8000-000000000003",
+	} {
+		if got := s.NewID(); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+
+	// Two sequences generate the same IDs.
+	if a, b := NewSequence().NewID(), NewSequence().NewID(); a != b {
+		t.Errorf("expected fresh sequences to agree, got %s and %s", a, b)
+	}
+}
+
+func TestUUIDs(t *testing.T) {
+	a, b := UUIDs.NewID(), UUIDs.NewID()
+	if len(a) != 36 || a == b {
+		t.Errorf("expected two distinct UUIDs, got %s and %s", a, b)
+	}
+}
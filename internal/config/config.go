@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"time"
@@ -13,11 +14,33 @@ type Config struct {
 	LogLevel           string
 	ExpirationInterval time.Duration
 	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	WebhookMaxAge      time.Duration
+	WebhookWorkers     int
+	WebhookDeadLetters int // failed deliveries kept per webhook
+	WebhookDeliveryLog int // succeeded deliveries kept per webhook
 	VWAPWindow         time.Duration
 	ReadTimeout        time.Duration
 	WriteTimeout       time.Duration
 	IdleTimeout        time.Duration
 	ShutdownTimeout    time.Duration
+	MarketDataBuffer   int
+	EventBufferSize    int
+	FIXPort            int // 0 disables the FIX acceptor
+	FIXCompID          string
+	FIXStoreDir        string
+	GRPCPort           int    // 0 disables the gRPC server
+	OUCHPort           int    // 0 disables the binary order entry gateway
+	ITCHPort           int    // 0 disables the market data replay and retransmission services
+	ITCHUDPAddr        string // empty disables the UDP market data publisher
+	ITCHRingSize       int
+	AdminAPIKey        string // empty means no caller can act as admin
+	TraceExporter      string // none, otlp or stdout
+	TraceOTLPEndpoint  string
+	TraceSampleRatio   float64
+	AuditLogDir        string
+	AuditLogMaxSizeMB  int // size at which the current audit file is rotated
+	AuditLogMaxFiles   int // rotated audit files kept, 0 keeps all
 }
 
 // Load reads configuration from environment variables, applies defaults,
@@ -43,6 +66,46 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid WEBHOOK_TIMEOUT: %w", err)
 	}
 
+	webhookMaxAttempts, err := getInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %w", err)
+	}
+	if webhookMaxAttempts < 1 {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %d, must be >= 1", webhookMaxAttempts)
+	}
+
+	webhookMaxAge, err := getDuration("WEBHOOK_MAX_AGE", 24*time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_AGE: %w", err)
+	}
+	if webhookMaxAge <= 0 {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_AGE: %v, must be > 0", webhookMaxAge)
+	}
+
+	webhookWorkers, err := getInt("WEBHOOK_WORKERS", 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_WORKERS: %w", err)
+	}
+	if webhookWorkers < 1 {
+		return nil, fmt.Errorf("invalid WEBHOOK_WORKERS: %d, must be >= 1", webhookWorkers)
+	}
+
+	webhookDeadLetters, err := getInt("WEBHOOK_DEAD_LETTERS", 1000)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_DEAD_LETTERS: %w", err)
+	}
+	if webhookDeadLetters < 1 {
+		return nil, fmt.Errorf("invalid WEBHOOK_DEAD_LETTERS: %d, must be >= 1", webhookDeadLetters)
+	}
+
+	webhookDeliveryLog, err := getInt("WEBHOOK_DELIVERY_LOG", 100)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_DELIVERY_LOG: %w", err)
+	}
+	if webhookDeliveryLog < 1 {
+		return nil, fmt.Errorf("invalid WEBHOOK_DELIVERY_LOG: %d, must be >= 1", webhookDeliveryLog)
+	}
+
 	vwapWindow, err := getDuration("VWAP_WINDOW", 5*time.Minute)
 	if err != nil {
 		return nil, fmt.Errorf("invalid VWAP_WINDOW: %w", err)
@@ -68,16 +131,147 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
 	}
 
+	marketDataBuffer, err := getInt("MARKET_DATA_BUFFER", 256)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MARKET_DATA_BUFFER: %w", err)
+	}
+	if marketDataBuffer < 1 {
+		return nil, fmt.Errorf("invalid MARKET_DATA_BUFFER: %d, must be >= 1", marketDataBuffer)
+	}
+
+	eventBufferSize, err := getInt("EVENT_BUFFER_SIZE", 1000)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EVENT_BUFFER_SIZE: %w", err)
+	}
+	if eventBufferSize < 1 {
+		return nil, fmt.Errorf("invalid EVENT_BUFFER_SIZE: %d, must be >= 1", eventBufferSize)
+	}
+
+	fixPort, err := getInt("FIX_PORT", 9878)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FIX_PORT: %w", err)
+	}
+	if fixPort < 0 || fixPort > 65535 {
+		return nil, fmt.Errorf("invalid FIX_PORT: %d, must be between 0 and 65535", fixPort)
+	}
+
+	fixCompID := getStr("FIX_COMP_ID", "MINIEXCHANGE")
+	fixStoreDir := getStr("FIX_STORE_DIR", "fix-sessions")
+
+	grpcPort, err := getInt("GRPC_PORT", 9090)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC_PORT: %w", err)
+	}
+	if grpcPort < 0 || grpcPort > 65535 {
+		return nil, fmt.Errorf("invalid GRPC_PORT: %d, must be between 0 and 65535", grpcPort)
+	}
+
+	ouchPort, err := getInt("OUCH_PORT", 9200)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OUCH_PORT: %w", err)
+	}
+	if ouchPort < 0 || ouchPort > 65535 {
+		return nil, fmt.Errorf("invalid OUCH_PORT: %d, must be between 0 and 65535", ouchPort)
+	}
+
+	itchPort, err := getInt("ITCH_PORT", 9300)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ITCH_PORT: %w", err)
+	}
+	if itchPort < 0 || itchPort > 65535 {
+		return nil, fmt.Errorf("invalid ITCH_PORT: %d, must be between 0 and 65535", itchPort)
+	}
+
+	itchUDPAddr := getStr("ITCH_UDP_ADDR", "")
+	if itchUDPAddr != "" {
+		if _, _, err := net.SplitHostPort(itchUDPAddr); err != nil {
+			return nil, fmt.Errorf("invalid ITCH_UDP_ADDR: %w", err)
+		}
+	}
+
+	itchRingSize, err := getInt("ITCH_RING_SIZE", 100000)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ITCH_RING_SIZE: %w", err)
+	}
+	if itchRingSize < 1 {
+		return nil, fmt.Errorf("invalid ITCH_RING_SIZE: %d, must be >= 1", itchRingSize)
+	}
+
+	adminAPIKey := getStr("ADMIN_API_KEY", "")
+	if adminAPIKey != "" && len(adminAPIKey) < 16 {
+		return nil, fmt.Errorf("invalid ADMIN_API_KEY: must be at least 16 characters")
+	}
+
+	traceExporter := getStr("TRACE_EXPORTER", "none")
+	switch traceExporter {
+	case "none", "otlp", "stdout":
+	default:
+		return nil, fmt.Errorf("invalid TRACE_EXPORTER: %q, must be one of: none, otlp, stdout", traceExporter)
+	}
+
+	traceOTLPEndpoint := getStr("TRACE_OTLP_ENDPOINT", "localhost:4318")
+	if _, _, err := net.SplitHostPort(traceOTLPEndpoint); err != nil {
+		return nil, fmt.Errorf("invalid TRACE_OTLP_ENDPOINT: %w", err)
+	}
+
+	traceSampleRatio, err := getFloat("TRACE_SAMPLE_RATIO", 1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACE_SAMPLE_RATIO: %w", err)
+	}
+	if traceSampleRatio < 0 || traceSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid TRACE_SAMPLE_RATIO: %v, must be between 0 and 1", traceSampleRatio)
+	}
+
+	auditLogDir := getStr("AUDIT_LOG_DIR", "audit-log")
+
+	auditLogMaxSizeMB, err := getInt("AUDIT_LOG_MAX_SIZE_MB", 100)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_LOG_MAX_SIZE_MB: %w", err)
+	}
+	if auditLogMaxSizeMB < 1 {
+		return nil, fmt.Errorf("invalid AUDIT_LOG_MAX_SIZE_MB: %d, must be >= 1", auditLogMaxSizeMB)
+	}
+
+	auditLogMaxFiles, err := getInt("AUDIT_LOG_MAX_FILES", 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_LOG_MAX_FILES: %w", err)
+	}
+	if auditLogMaxFiles < 0 {
+		return nil, fmt.Errorf("invalid AUDIT_LOG_MAX_FILES: %d, must be >= 0", auditLogMaxFiles)
+	}
+
 	return &Config{
 		Port:               port,
 		LogLevel:           logLevel,
 		ExpirationInterval: expirationInterval,
 		WebhookTimeout:     webhookTimeout,
+		WebhookMaxAttempts: webhookMaxAttempts,
+		WebhookMaxAge:      webhookMaxAge,
+		WebhookWorkers:     webhookWorkers,
+		WebhookDeadLetters: webhookDeadLetters,
+		WebhookDeliveryLog: webhookDeliveryLog,
 		VWAPWindow:         vwapWindow,
 		ReadTimeout:        readTimeout,
 		WriteTimeout:       writeTimeout,
 		IdleTimeout:        idleTimeout,
 		ShutdownTimeout:    shutdownTimeout,
+		MarketDataBuffer:   marketDataBuffer,
+		EventBufferSize:    eventBufferSize,
+		FIXPort:            fixPort,
+		FIXCompID:          fixCompID,
+		FIXStoreDir:        fixStoreDir,
+		GRPCPort:           grpcPort,
+		OUCHPort:           ouchPort,
+		ITCHPort:           itchPort,
+		ITCHUDPAddr:        itchUDPAddr,
+		ITCHRingSize:       itchRingSize,
+		AdminAPIKey:        adminAPIKey,
+		TraceExporter:      traceExporter,
+		TraceOTLPEndpoint:  traceOTLPEndpoint,
+		TraceSampleRatio:   traceSampleRatio,
+		AuditLogDir:        auditLogDir,
+		AuditLogMaxSizeMB:  auditLogMaxSizeMB,
+		AuditLogMaxFiles:   auditLogMaxFiles,
 	}, nil
 }
 
@@ -97,6 +291,14 @@ func getInt(key string, defaultVal int) (int, error) {
 	return strconv.Atoi(v)
 }
 
+func getFloat(key string, defaultVal float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
 func getDuration(key string, defaultVal time.Duration) (time.Duration, error) {
 	v := os.Getenv(key)
 	if v == "" {
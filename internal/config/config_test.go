@@ -10,8 +10,14 @@ func clearEnv(t *testing.T) {
 	t.Helper()
 	for _, key := range []string{
 		"PORT", "LOG_LEVEL", "EXPIRATION_INTERVAL", "WEBHOOK_TIMEOUT",
+		"WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_MAX_AGE", "WEBHOOK_WORKERS", "WEBHOOK_DEAD_LETTERS",
+		"WEBHOOK_DELIVERY_LOG",
 		"VWAP_WINDOW", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT",
-		"SHUTDOWN_TIMEOUT",
+		"SHUTDOWN_TIMEOUT", "MARKET_DATA_BUFFER", "EVENT_BUFFER_SIZE",
+		"FIX_PORT", "FIX_COMP_ID", "FIX_STORE_DIR", "GRPC_PORT", "OUCH_PORT",
+		"ITCH_PORT", "ITCH_UDP_ADDR", "ITCH_RING_SIZE",
+		"ADMIN_API_KEY", "TRACE_EXPORTER", "TRACE_OTLP_ENDPOINT", "TRACE_SAMPLE_RATIO",
+		"AUDIT_LOG_DIR", "AUDIT_LOG_MAX_SIZE_MB", "AUDIT_LOG_MAX_FILES",
 	} {
 		t.Setenv(key, "")
 		os.Unsetenv(key)
@@ -53,6 +59,72 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.ShutdownTimeout != 10*time.Second {
 		t.Errorf("ShutdownTimeout = %v, want 10s", cfg.ShutdownTimeout)
 	}
+	if cfg.MarketDataBuffer != 256 {
+		t.Errorf("MarketDataBuffer = %d, want 256", cfg.MarketDataBuffer)
+	}
+	if cfg.EventBufferSize != 1000 {
+		t.Errorf("EventBufferSize = %d, want 1000", cfg.EventBufferSize)
+	}
+	if cfg.FIXPort != 9878 {
+		t.Errorf("FIXPort = %d, want 9878", cfg.FIXPort)
+	}
+	if cfg.FIXCompID != "MINIEXCHANGE" {
+		t.Errorf("FIXCompID = %q, want %q", cfg.FIXCompID, "MINIEXCHANGE")
+	}
+	if cfg.FIXStoreDir != "fix-sessions" {
+		t.Errorf("FIXStoreDir = %q, want %q", cfg.FIXStoreDir, "fix-sessions")
+	}
+	if cfg.GRPCPort != 9090 {
+		t.Errorf("GRPCPort = %d, want 9090", cfg.GRPCPort)
+	}
+	if cfg.OUCHPort != 9200 {
+		t.Errorf("OUCHPort = %d, want 9200", cfg.OUCHPort)
+	}
+	if cfg.ITCHPort != 9300 {
+		t.Errorf("ITCHPort = %d, want 9300", cfg.ITCHPort)
+	}
+	if cfg.ITCHUDPAddr != "" {
+		t.Errorf("ITCHUDPAddr = %q, want empty", cfg.ITCHUDPAddr)
+	}
+	if cfg.ITCHRingSize != 100000 {
+		t.Errorf("ITCHRingSize = %d, want 100000", cfg.ITCHRingSize)
+	}
+	if cfg.WebhookMaxAttempts != 8 {
+		t.Errorf("WebhookMaxAttempts = %d, want 8", cfg.WebhookMaxAttempts)
+	}
+	if cfg.WebhookMaxAge != 24*time.Hour {
+		t.Errorf("WebhookMaxAge = %v, want 24h", cfg.WebhookMaxAge)
+	}
+	if cfg.WebhookWorkers != 16 {
+		t.Errorf("WebhookWorkers = %d, want 16", cfg.WebhookWorkers)
+	}
+	if cfg.WebhookDeadLetters != 1000 {
+		t.Errorf("WebhookDeadLetters = %d, want 1000", cfg.WebhookDeadLetters)
+	}
+	if cfg.WebhookDeliveryLog != 100 {
+		t.Errorf("WebhookDeliveryLog = %d, want 100", cfg.WebhookDeliveryLog)
+	}
+	if cfg.AdminAPIKey != "" {
+		t.Errorf("AdminAPIKey = %q, want empty", cfg.AdminAPIKey)
+	}
+	if cfg.TraceExporter != "none" {
+		t.Errorf("TraceExporter = %q, want %q", cfg.TraceExporter, "none")
+	}
+	if cfg.TraceOTLPEndpoint != "localhost:4318" {
+		t.Errorf("TraceOTLPEndpoint = %q, want %q", cfg.TraceOTLPEndpoint, "localhost:4318")
+	}
+	if cfg.TraceSampleRatio != 1 {
+		t.Errorf("TraceSampleRatio = %v, want 1", cfg.TraceSampleRatio)
+	}
+	if cfg.AuditLogDir != "audit-log" {
+		t.Errorf("AuditLogDir = %q, want %q", cfg.AuditLogDir, "audit-log")
+	}
+	if cfg.AuditLogMaxSizeMB != 100 {
+		t.Errorf("AuditLogMaxSizeMB = %d, want 100", cfg.AuditLogMaxSizeMB)
+	}
+	if cfg.AuditLogMaxFiles != 0 {
+		t.Errorf("AuditLogMaxFiles = %d, want 0", cfg.AuditLogMaxFiles)
+	}
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -129,3 +201,250 @@ func TestLoad_InvalidDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_InvalidMarketDataBuffer(t *testing.T) {
+	for _, v := range []string{"not-a-number", "0", "-5"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("MARKET_DATA_BUFFER", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for MARKET_DATA_BUFFER=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidEventBufferSize(t *testing.T) {
+	for _, v := range []string{"not-a-number", "0", "-5"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("EVENT_BUFFER_SIZE", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for EVENT_BUFFER_SIZE=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidFIXPort(t *testing.T) {
+	for _, v := range []string{"not-a-number", "-1", "65536"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("FIX_PORT", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for FIX_PORT=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_FIXDisabled(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("FIX_PORT", "0")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.FIXPort != 0 {
+		t.Errorf("FIXPort = %d, want 0", cfg.FIXPort)
+	}
+}
+
+func TestLoad_InvalidGRPCPort(t *testing.T) {
+	for _, v := range []string{"not-a-number", "-1", "65536"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("GRPC_PORT", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for GRPC_PORT=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidOUCHPort(t *testing.T) {
+	for _, v := range []string{"not-a-number", "-1", "65536"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("OUCH_PORT", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for OUCH_PORT=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidITCHPort(t *testing.T) {
+	for _, v := range []string{"not-a-number", "-1", "65536"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("ITCH_PORT", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for ITCH_PORT=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidITCHUDPAddr(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("ITCH_UDP_ADDR", "239.1.1.1")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for an address without a port")
+	}
+}
+
+func TestLoad_InvalidITCHRingSize(t *testing.T) {
+	for _, v := range []string{"not-a-number", "0", "-1"} {
+		t.Run(v, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("ITCH_RING_SIZE", v)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("expected error for ITCH_RING_SIZE=%q", v)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidWebhookDeliveryPolicy(t *testing.T) {
+	for key, values := range map[string][]string{
+		"WEBHOOK_MAX_ATTEMPTS": {"not-a-number", "0"},
+		"WEBHOOK_MAX_AGE":      {"not-a-duration", "0s", "-1h"},
+		"WEBHOOK_WORKERS":      {"not-a-number", "0"},
+		"WEBHOOK_DEAD_LETTERS": {"not-a-number", "-1"},
+		"WEBHOOK_DELIVERY_LOG": {"not-a-number", "0"},
+	} {
+		for _, v := range values {
+			t.Run(key+"="+v, func(t *testing.T) {
+				clearEnv(t)
+				t.Setenv(key, v)
+
+				_, err := Load()
+				if err == nil {
+					t.Fatalf("expected error for %s=%q", key, v)
+				}
+			})
+		}
+	}
+}
+
+func TestLoad_AdminAPIKey(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("ADMIN_API_KEY", "0123456789abcdef")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AdminAPIKey != "0123456789abcdef" {
+		t.Errorf("AdminAPIKey = %q, want %q", cfg.AdminAPIKey, "0123456789abcdef")
+	}
+}
+
+func TestLoad_InvalidAdminAPIKey(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("ADMIN_API_KEY", "short")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for an admin key shorter than 16 characters")
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TRACE_EXPORTER", "otlp")
+	t.Setenv("TRACE_OTLP_ENDPOINT", "otel-collector:4318")
+	t.Setenv("TRACE_SAMPLE_RATIO", "0.25")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TraceExporter != "otlp" {
+		t.Errorf("TraceExporter = %q, want %q", cfg.TraceExporter, "otlp")
+	}
+	if cfg.TraceOTLPEndpoint != "otel-collector:4318" {
+		t.Errorf("TraceOTLPEndpoint = %q, want %q", cfg.TraceOTLPEndpoint, "otel-collector:4318")
+	}
+	if cfg.TraceSampleRatio != 0.25 {
+		t.Errorf("TraceSampleRatio = %v, want 0.25", cfg.TraceSampleRatio)
+	}
+}
+
+func TestLoad_InvalidTracing(t *testing.T) {
+	for key, values := range map[string][]string{
+		"TRACE_EXPORTER":      {"jaeger"},
+		"TRACE_OTLP_ENDPOINT": {"otel-collector"},
+		"TRACE_SAMPLE_RATIO":  {"not-a-number", "-0.1", "1.5"},
+	} {
+		for _, v := range values {
+			t.Run(key+"="+v, func(t *testing.T) {
+				clearEnv(t)
+				t.Setenv(key, v)
+
+				_, err := Load()
+				if err == nil {
+					t.Fatalf("expected error for %s=%q", key, v)
+				}
+			})
+		}
+	}
+}
+
+func TestLoad_AuditLog(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AUDIT_LOG_DIR", "/var/lib/miniexchange/audit")
+	t.Setenv("AUDIT_LOG_MAX_SIZE_MB", "10")
+	t.Setenv("AUDIT_LOG_MAX_FILES", "30")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AuditLogDir != "/var/lib/miniexchange/audit" {
+		t.Errorf("AuditLogDir = %q, want %q", cfg.AuditLogDir, "/var/lib/miniexchange/audit")
+	}
+	if cfg.AuditLogMaxSizeMB != 10 {
+		t.Errorf("AuditLogMaxSizeMB = %d, want 10", cfg.AuditLogMaxSizeMB)
+	}
+	if cfg.AuditLogMaxFiles != 30 {
+		t.Errorf("AuditLogMaxFiles = %d, want 30", cfg.AuditLogMaxFiles)
+	}
+}
+
+func TestLoad_InvalidAuditLog(t *testing.T) {
+	for key, values := range map[string][]string{
+		"AUDIT_LOG_MAX_SIZE_MB": {"abc", "0"},
+		"AUDIT_LOG_MAX_FILES":   {"abc", "-1"},
+	} {
+		for _, v := range values {
+			t.Run(key+"="+v, func(t *testing.T) {
+				clearEnv(t)
+				t.Setenv(key, v)
+
+				_, err := Load()
+				if err == nil {
+					t.Fatalf("expected error for %s=%q", key, v)
+				}
+			})
+		}
+	}
+}
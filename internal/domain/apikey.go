@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// APIKeyRole is the role of an authenticated API caller.
+type APIKeyRole string
+
+const (
+	// APIKeyRoleBroker may act only on its own broker's resources.
+	APIKeyRoleBroker APIKeyRole = "broker"
+	// APIKeyRoleAdmin may act on any broker and call operational endpoints.
+	APIKeyRoleAdmin APIKeyRole = "admin"
+)
+
+// APIKey is a credential issued to a broker. Only the SHA-256 hash of the
+// secret is kept; the secret itself is shown once, when the key is created
+// or rotated.
+type APIKey struct {
+	KeyID     string
+	BrokerID  string
+	Hash      string // hex-encoded SHA-256 of the secret
+	Prefix    string // first characters of the secret, to tell keys apart
+	CreatedAt time.Time
+	RotatedAt *time.Time
+}
+
+// Principal is the identity a request was authenticated as.
+type Principal struct {
+	Role     APIKeyRole
+	BrokerID string // empty for admins
+	KeyID    string // empty for the configured admin key
+}
+
+// CanActFor reports whether the principal may act on brokerID's resources.
+// Admins may act on any broker.
+func (p Principal) CanActFor(brokerID string) bool {
+	return p.Role == APIKeyRoleAdmin || (p.Role == APIKeyRoleBroker && p.BrokerID == brokerID)
+}
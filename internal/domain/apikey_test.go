@@ -0,0 +1,24 @@
+package domain
+
+import "testing"
+
+func TestPrincipal_CanActFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        Principal
+		brokerID string
+		want     bool
+	}{
+		{"own broker", Principal{Role: APIKeyRoleBroker, BrokerID: "b1"}, "b1", true},
+		{"other broker", Principal{Role: APIKeyRoleBroker, BrokerID: "b1"}, "b2", false},
+		{"admin", Principal{Role: APIKeyRoleAdmin}, "b2", true},
+		{"no role", Principal{BrokerID: "b1"}, "b1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.CanActFor(tt.brokerID); got != tt.want {
+				t.Errorf("CanActFor(%q) = %v, want %v", tt.brokerID, got, tt.want)
+			}
+		})
+	}
+}
@@ -13,7 +13,13 @@ var (
 	ErrInsufficientHoldings = errors.New("insufficient_holdings")
 	ErrNoLiquidity          = errors.New("no_liquidity")
 	ErrSymbolNotFound       = errors.New("symbol_not_found")
+	ErrSymbolHalted         = errors.New("symbol_halted")
 	ErrWebhookNotFound      = errors.New("webhook_not_found")
+	ErrSlowConsumer         = errors.New("slow_consumer")
+	ErrAPIKeyNotFound       = errors.New("api_key_not_found")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrDeliveryNotFound     = errors.New("delivery_not_found")
+	ErrDeliveryNotFailed    = errors.New("delivery_not_failed")
 )
 
 // ValidationError represents a request validation failure.
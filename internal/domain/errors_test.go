@@ -29,7 +29,13 @@ func TestSentinelErrors_AreDistinct(t *testing.T) {
 		ErrInsufficientHoldings,
 		ErrNoLiquidity,
 		ErrSymbolNotFound,
+		ErrSymbolHalted,
 		ErrWebhookNotFound,
+		ErrSlowConsumer,
+		ErrAPIKeyNotFound,
+		ErrUnauthorized,
+		ErrDeliveryNotFound,
+		ErrDeliveryNotFailed,
 	}
 	for i := 0; i < len(errs); i++ {
 		for j := i + 1; j < len(errs); j++ {
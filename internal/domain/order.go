@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // OrderType distinguishes limit orders from market orders.
 type OrderType string
@@ -48,6 +51,64 @@ type Order struct {
 	CancelledAt       *time.Time
 	ExpiredAt         *time.Time
 	Trades            []*Trade
+	History           OrderHistory
+}
+
+// OrderEventType names a step in an order's lifecycle.
+type OrderEventType string
+
+const (
+	OrderEventAccepted        OrderEventType = "accepted"
+	OrderEventPartiallyFilled OrderEventType = "partially_filled"
+	OrderEventFilled          OrderEventType = "filled"
+	OrderEventAmended         OrderEventType = "amended"
+	OrderEventCancelRequested OrderEventType = "cancel_requested"
+	OrderEventCancelled       OrderEventType = "cancelled"
+	OrderEventExpired         OrderEventType = "expired"
+)
+
+// OrderEvent is one step in an order's lifecycle, with the order's filled
+// and remaining quantities once it was taken.
+type OrderEvent struct {
+	Type               OrderEventType
+	Time               time.Time
+	Quantity           int64  // shares filled, cancelled or expired; an amendment's new quantity
+	Price              int64  // cents: the fill price or an amendment's new price, 0 otherwise
+	TradeID            string // fills only
+	ReplacementOrderID string // amendments only
+	FilledQuantity     int64
+	RemainingQuantity  int64
+}
+
+// OrderHistory is an order's lifecycle events, oldest first. It is safe for
+// concurrent use, so the history can be read while the order is on the
+// book. The zero value is an empty history.
+type OrderHistory struct {
+	mu     sync.Mutex
+	events []OrderEvent
+}
+
+// Append adds an event to the end of the history.
+func (h *OrderHistory) Append(e OrderEvent) {
+	h.mu.Lock()
+	h.events = append(h.events, e)
+	h.mu.Unlock()
+}
+
+// Events returns a copy of the history, oldest first.
+func (h *OrderHistory) Events() []OrderEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]OrderEvent(nil), h.events...)
+}
+
+// Record appends e to the order's history with the order's current filled
+// and remaining quantities. The caller must hold the order's book lock, or
+// the order must be off the book, so the quantities are settled.
+func (o *Order) Record(e OrderEvent) {
+	e.FilledQuantity = o.FilledQuantity
+	e.RemainingQuantity = o.RemainingQuantity
+	o.History.Append(e)
 }
 
 // AveragePrice computes the volume-weighted average execution price
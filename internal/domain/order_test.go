@@ -60,3 +60,29 @@ func TestOrder_AveragePrice_NilTrades(t *testing.T) {
 		t.Error("AveragePrice() returned true, want false for nil trades")
 	}
 }
+
+func TestOrder_Record(t *testing.T) {
+	o := &Order{Quantity: 10, FilledQuantity: 4, RemainingQuantity: 6}
+	at := time.Now()
+	o.Record(OrderEvent{Type: OrderEventPartiallyFilled, Time: at, Quantity: 4, Price: 15000, TradeID: "t1"})
+
+	o.FilledQuantity, o.RemainingQuantity = 4, 0
+	o.Record(OrderEvent{Type: OrderEventCancelled, Time: at, Quantity: 6})
+
+	events := o.History.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if e := events[0]; e.Type != OrderEventPartiallyFilled || e.TradeID != "t1" || e.FilledQuantity != 4 || e.RemainingQuantity != 6 {
+		t.Errorf("unexpected fill event: %+v", e)
+	}
+	if e := events[1]; e.Type != OrderEventCancelled || e.Quantity != 6 || e.RemainingQuantity != 0 {
+		t.Errorf("unexpected cancel event: %+v", e)
+	}
+
+	// Events returns a copy.
+	events[0].Type = OrderEventExpired
+	if o.History.Events()[0].Type != OrderEventPartiallyFilled {
+		t.Error("expected Events to return a copy")
+	}
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 // SymbolRegistry tracks known stock symbols in a thread-safe manner.
 // Symbols are implicitly registered when they appear in any order
@@ -30,3 +33,16 @@ func (r *SymbolRegistry) Exists(symbol string) bool {
 	defer r.mu.RUnlock()
 	return r.symbols[symbol]
 }
+
+// List returns all registered symbols in sorted order. Safe for concurrent use.
+func (r *SymbolRegistry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	symbols := make([]string, 0, len(r.symbols))
+	for symbol := range r.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
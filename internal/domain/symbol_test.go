@@ -22,6 +22,22 @@ func TestSymbolRegistry_RegisterAndExists(t *testing.T) {
 	}
 }
 
+func TestSymbolRegistry_List(t *testing.T) {
+	r := NewSymbolRegistry()
+	if got := r.List(); len(got) != 0 {
+		t.Fatalf("List() = %v, want empty", got)
+	}
+
+	r.Register("GOOG")
+	r.Register("AAPL")
+	r.Register("GOOG")
+
+	got := r.List()
+	if len(got) != 2 || got[0] != "AAPL" || got[1] != "GOOG" {
+		t.Errorf("List() = %v, want [AAPL GOOG]", got)
+	}
+}
+
 func TestSymbolRegistry_ConcurrentAccess(t *testing.T) {
 	r := NewSymbolRegistry()
 	var wg sync.WaitGroup
@@ -3,10 +3,13 @@ package domain
 import "time"
 
 // Trade represents a matched execution between a bid and an ask order.
+// Each execution produces two records sharing a TradeID, one per order.
 type Trade struct {
 	TradeID    string
 	OrderID    string
-	Price      int64 // cents
+	Side       OrderSide // side of the order this record belongs to
+	Aggressor  bool      // true on the incoming order's record
+	Price      int64     // cents
 	Quantity   int64
 	ExecutedAt time.Time
 }
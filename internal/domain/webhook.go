@@ -1,13 +1,130 @@
 package domain
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
-// Webhook represents a broker's subscription to an event notification.
+// WebhookStatus is whether a webhook subscription receives deliveries.
+type WebhookStatus string
+
+const (
+	// WebhookStatusActive subscriptions receive a delivery for every
+	// matching event.
+	WebhookStatusActive WebhookStatus = "active"
+	// WebhookStatusPaused subscriptions receive nothing until resumed.
+	// Events that occur while paused are not delivered later.
+	WebhookStatusPaused WebhookStatus = "paused"
+)
+
+// Webhook represents a broker's subscription to an event notification. A
+// broker may have several subscriptions for the same event, one per URL.
 type Webhook struct {
 	WebhookID string
 	BrokerID  string
 	Event     string
 	URL       string
+	Status    WebhookStatus
+	Filters   WebhookFilters
+	Version   int // the payload schema version delivered
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// Secret signs every delivery. After a rotation, PreviousSecret also
+	// signs deliveries until PreviousSecretExpiresAt, so receivers can
+	// switch secrets without rejecting deliveries in between.
+	Secret                  string
+	PreviousSecret          string
+	PreviousSecretExpiresAt time.Time
+	SecretRotatedAt         *time.Time
+}
+
+// SigningSecrets returns the secrets that sign a delivery made at now: the
+// current secret, followed by the previous one while it is still accepted.
+func (w *Webhook) SigningSecrets(now time.Time) []string {
+	if w.PreviousSecret != "" && now.Before(w.PreviousSecretExpiresAt) {
+		return []string{w.Secret, w.PreviousSecret}
+	}
+	return []string{w.Secret}
+}
+
+// WebhookFilters narrow the events a subscription receives. Zero values
+// match everything, and a filter on a field the event does not have, such as
+// a side on symbol.halted, does not exclude it.
+type WebhookFilters struct {
+	Symbols     []string  // any of these symbols
+	Side        OrderSide // orders and trades on this side
+	MinNotional int64     // trades worth at least this much, in cents
+}
+
+// IsZero reports whether the filters match every event.
+func (f WebhookFilters) IsZero() bool {
+	return len(f.Symbols) == 0 && f.Side == "" && f.MinNotional == 0
+}
+
+// Equal reports whether f and g are the same filters. Symbols are compared
+// in order, so callers keep them sorted.
+func (f WebhookFilters) Equal(g WebhookFilters) bool {
+	return slices.Equal(f.Symbols, g.Symbols) && f.Side == g.Side && f.MinNotional == g.MinNotional
+}
+
+// DeliveryStatus is where a webhook delivery is in its lifecycle.
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending deliveries are waiting for their next attempt.
+	DeliveryStatusPending DeliveryStatus = "pending"
+	// DeliveryStatusSucceeded deliveries got a 2xx response. They are kept
+	// for a while as a record of what was delivered.
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	// DeliveryStatusFailed deliveries ran out of attempts or time. They are
+	// dead letters: kept for inspection and manual redelivery.
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event on its way to a webhook. Every attempt sends
+// the same DeliveryID and Payload, so receivers can drop duplicates. A
+// broker's deliveries to a URL are attempted one at a time, in Sequence
+// order, across its webhooks. A ping is a delivery too, made straight away
+// outside the queue, with a Sequence of 0.
+type WebhookDelivery struct {
+	DeliveryID string
+	WebhookID  string
+	BrokerID   string
+	Event      string
+	Sequence   uint64 // the broker's event sequence number, also in Payload
+	Payload    []byte
+	Status     DeliveryStatus
+	CreatedAt  time.Time
+
+	// TraceContext holds the W3C trace context headers of the dispatch that
+	// queued the delivery, so each attempt joins the originating trace. It
+	// is nil when there was no trace, and is never modified once set.
+	TraceContext map[string]string
+
+	// QueuedAt is when the delivery was last queued: at creation, or at its
+	// latest manual redelivery. Attempts counts the attempts since then, and
+	// the retry policy's age limit runs from it.
+	QueuedAt      time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+
+	LastAttemptAt  *time.Time
+	LastStatusCode int // 0 when the last attempt got no response
+	LastError      string
+	CompletedAt    *time.Time
+
+	// History records the delivery's attempts, oldest first. Only the most
+	// recent ones are kept.
+	History []DeliveryAttempt
+}
+
+// DeliveryAttempt records one attempt to deliver a webhook.
+type DeliveryAttempt struct {
+	AttemptedAt     time.Time
+	URL             string
+	StatusCode      int // 0 when the attempt got no response
+	Latency         time.Duration
+	Error           string // empty when the attempt succeeded
+	ResponseSnippet string // the start of the response body
 }
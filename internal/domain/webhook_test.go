@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhook_SigningSecrets(t *testing.T) {
+	now := time.Now()
+	w := &Webhook{Secret: "new"}
+	if got := w.SigningSecrets(now); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Errorf("never rotated: got %v", got)
+	}
+
+	w.PreviousSecret = "old"
+	w.PreviousSecretExpiresAt = now.Add(time.Hour)
+	if got := w.SigningSecrets(now); !reflect.DeepEqual(got, []string{"new", "old"}) {
+		t.Errorf("within grace period: got %v", got)
+	}
+	if got := w.SigningSecrets(now.Add(time.Hour)); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Errorf("after grace period: got %v", got)
+	}
+}
+
+func TestWebhookFilters_IsZeroAndEqual(t *testing.T) {
+	if !(WebhookFilters{}).IsZero() || !(WebhookFilters{Symbols: []string{}}).IsZero() {
+		t.Error("empty filters should be zero")
+	}
+
+	f := WebhookFilters{Symbols: []string{"AAPL", "MSFT"}, Side: OrderSideBid, MinNotional: 100000}
+	if f.IsZero() {
+		t.Error("filters with a symbol list should not be zero")
+	}
+	if !f.Equal(WebhookFilters{Symbols: []string{"AAPL", "MSFT"}, Side: OrderSideBid, MinNotional: 100000}) {
+		t.Error("identical filters should be equal")
+	}
+	for name, g := range map[string]WebhookFilters{
+		"symbols":      {Symbols: []string{"AAPL"}, Side: OrderSideBid, MinNotional: 100000},
+		"side":         {Symbols: []string{"AAPL", "MSFT"}, Side: OrderSideAsk, MinNotional: 100000},
+		"min notional": {Symbols: []string{"AAPL", "MSFT"}, Side: OrderSideBid},
+	} {
+		if f.Equal(g) {
+			t.Errorf("filters differing in %s should not be equal", name)
+		}
+	}
+}
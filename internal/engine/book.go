@@ -1,9 +1,14 @@
 package engine
 
 import (
+	"context"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/google/btree"
 )
@@ -23,6 +28,18 @@ type PriceLevel struct {
 	OrderCount    int
 }
 
+// LevelOrders lists the resting orders at a single price, in time priority.
+type LevelOrders struct {
+	Price   int64
+	Entries []OrderBookEntry
+}
+
+// QueuePosition describes what rests ahead of an order at its price level.
+type QueuePosition struct {
+	OrdersAhead   int
+	QuantityAhead int64
+}
+
 // bidLess defines ordering for the bid side: price descending, then
 // created_at ascending, then order_id ascending. This means Min()
 // returns the best bid (highest price, earliest time).
@@ -57,9 +74,25 @@ type OrderBook struct {
 	bids   *btree.BTreeG[OrderBookEntry]
 	asks   *btree.BTreeG[OrderBookEntry]
 	index  map[string]OrderBookEntry // order_id → entry
+	halted bool                      // new orders are rejected while set
+
+	// Update publishing. pending collects changes while mu is held; pubMu
+	// serializes fan-out so updates leave in Seq order without keeping mu
+	// locked while listeners run. Updates are stamped with clock's time.
+	pub     *publisher
+	clock   clock.Clock
+	pubMu   sync.Mutex
+	seq     uint64
+	pending *pendingUpdate
+
+	// Tracing for the current write lock holder: the span covering the
+	// time the lock is held, and the caller's context for the publish span.
+	held    trace.Span
+	heldCtx context.Context
 }
 
-// NewOrderBook creates an order book for the given symbol.
+// NewOrderBook creates an order book for the given symbol, on the wall
+// clock.
 func NewOrderBook(symbol string) *OrderBook {
 	const degree = 32
 	return &OrderBook{
@@ -67,8 +100,127 @@ func NewOrderBook(symbol string) *OrderBook {
 		bids:   btree.NewG[OrderBookEntry](degree, bidLess),
 		asks:   btree.NewG[OrderBookEntry](degree, askLess),
 		index:  make(map[string]OrderBookEntry),
+		clock:  clock.System,
+	}
+}
+
+// lock acquires the write lock and, if anyone is listening, starts
+// recording changes for the next BookUpdate. The wait for the lock and the
+// time it is held are traced as separate spans under ctx; the returned
+// context carries the held span.
+func (ob *OrderBook) lock(ctx context.Context) context.Context {
+	symbol := trace.WithAttributes(attribute.String("order.symbol", ob.symbol))
+	_, wait := tracer().Start(ctx, "engine.lock_wait", symbol)
+	ob.mu.Lock()
+	wait.End()
+
+	ob.heldCtx = ctx
+	ctx, ob.held = tracer().Start(ctx, "engine.lock_held", symbol)
+	if ob.pub.active() {
+		ob.pending = &pendingUpdate{seen: make(map[levelKey]bool)}
+	}
+	return ctx
+}
+
+// unlockAndPublish releases the write lock and publishes the changes made
+// since lock, if any. The publish lock is taken before the write lock is
+// released so that concurrent passes on the same symbol publish in order.
+func (ob *OrderBook) unlockAndPublish() {
+	p := ob.pending
+	ob.pending = nil
+	held, ctx := ob.held, ob.heldCtx
+	ob.held, ob.heldCtx = nil, nil
+	if p == nil || (len(p.trades) == 0 && len(p.touched) == 0) {
+		held.End()
+		ob.mu.Unlock()
+		return
+	}
+
+	ob.seq++
+	u := &BookUpdate{
+		Symbol:    ob.symbol,
+		Seq:       ob.seq,
+		Trades:    p.trades,
+		Orders:    p.orders,
+		Levels:    make([]LevelUpdate, len(p.touched)),
+		Timestamp: ob.clock.Now(),
+	}
+	for i, k := range p.touched {
+		u.Levels[i] = ob.levelAt(k.side, k.price)
+	}
+	if best := ob.TopBids(1); len(best) > 0 {
+		u.BestBid = &best[0]
+	}
+	if best := ob.TopAsks(1); len(best) > 0 {
+		u.BestAsk = &best[0]
 	}
+
+	ob.pubMu.Lock()
+	held.End()
+	ob.mu.Unlock()
+	_, span := tracer().Start(ctx, "engine.publish", trace.WithAttributes(
+		attribute.String("order.symbol", ob.symbol),
+		attribute.Int64("book.seq", int64(u.Seq)),
+		attribute.Int("book.trades", len(u.Trades)),
+	))
+	ob.pub.publish(u)
+	span.End()
+	ob.pubMu.Unlock()
+}
+
+// touch marks a price level as changed in the pending update.
+func (ob *OrderBook) touch(side domain.OrderSide, price int64) {
+	if ob.pending != nil {
+		ob.pending.touch(side, price)
+	}
+}
+
+// recordTrade adds an execution to the pending update, along with the
+// matching execution of the resting order.
+func (ob *OrderBook) recordTrade(ev TradeEvent) {
+	if ob.pending != nil {
+		restingSide := domain.OrderSideAsk
+		if ev.AggressorSide == domain.OrderSideAsk {
+			restingSide = domain.OrderSideBid
+		}
+		ob.pending.trades = append(ob.pending.trades, ev)
+		ob.pending.orders = append(ob.pending.orders, OrderEvent{
+			Type:     OrderExecuted,
+			OrderID:  ev.RestingOrderID,
+			Side:     restingSide,
+			Price:    ev.Price,
+			Quantity: ev.Quantity,
+			TradeID:  ev.TradeID,
+		})
+	}
+}
+
+// recordOrder adds an order-level change to the pending update.
+func (ob *OrderBook) recordOrder(ev OrderEvent) {
+	if ob.pending != nil {
+		ob.pending.orders = append(ob.pending.orders, ev)
+	}
+}
+
+// levelAt aggregates the resting orders at a single price on one side.
+func (ob *OrderBook) levelAt(side domain.OrderSide, price int64) LevelUpdate {
+	tree := ob.asks
+	if side == domain.OrderSideBid {
+		tree = ob.bids
+	}
+	lu := LevelUpdate{Side: side, Price: price}
+	// The zero CreatedAt and OrderID sort before every real entry at the price.
+	tree.AscendGreaterOrEqual(OrderBookEntry{Price: price}, func(entry OrderBookEntry) bool {
+		if entry.Price != price {
+			return false
+		}
+		lu.TotalQuantity += entry.Order.RemainingQuantity
+		lu.OrderCount++
+		return true
+	})
+	return lu
 }
+
 // RLock acquires the read lock on the order book.
 func (ob *OrderBook) RLock() {
 	ob.mu.RLock()
@@ -83,12 +235,28 @@ func (ob *OrderBook) RUnlock() {
 func (ob *OrderBook) InsertBid(entry OrderBookEntry) {
 	ob.bids.ReplaceOrInsert(entry)
 	ob.index[entry.OrderID] = entry
+	ob.touch(domain.OrderSideBid, entry.Price)
+	ob.recordOrder(OrderEvent{
+		Type:     OrderAdded,
+		OrderID:  entry.OrderID,
+		Side:     domain.OrderSideBid,
+		Price:    entry.Price,
+		Quantity: entry.Order.RemainingQuantity,
+	})
 }
 
 // InsertAsk adds an entry to the ask side of the book.
 func (ob *OrderBook) InsertAsk(entry OrderBookEntry) {
 	ob.asks.ReplaceOrInsert(entry)
 	ob.index[entry.OrderID] = entry
+	ob.touch(domain.OrderSideAsk, entry.Price)
+	ob.recordOrder(OrderEvent{
+		Type:     OrderAdded,
+		OrderID:  entry.OrderID,
+		Side:     domain.OrderSideAsk,
+		Price:    entry.Price,
+		Quantity: entry.Order.RemainingQuantity,
+	})
 }
 
 // Remove deletes an order from the book by order ID using the
@@ -101,8 +269,14 @@ func (ob *OrderBook) Remove(orderID string) {
 	}
 	delete(ob.index, orderID)
 	// Try both sides — Delete is a no-op if the entry isn't found.
-	ob.bids.Delete(entry)
-	ob.asks.Delete(entry)
+	if _, ok := ob.bids.Delete(entry); ok {
+		ob.touch(domain.OrderSideBid, entry.Price)
+		ob.recordOrder(OrderEvent{Type: OrderRemoved, OrderID: orderID, Side: domain.OrderSideBid, Price: entry.Price})
+	}
+	if _, ok := ob.asks.Delete(entry); ok {
+		ob.touch(domain.OrderSideAsk, entry.Price)
+		ob.recordOrder(OrderEvent{Type: OrderRemoved, OrderID: orderID, Side: domain.OrderSideAsk, Price: entry.Price})
+	}
 }
 
 // BestBid returns the highest-priority bid (highest price, earliest time).
@@ -153,6 +327,68 @@ func topLevels(tree *btree.BTreeG[OrderBookEntry], n int) []PriceLevel {
 	return levels
 }
 
+// TopBidOrders returns the individual orders of up to n bid price levels,
+// best price first and in time priority within each level.
+func (ob *OrderBook) TopBidOrders(n int) []LevelOrders {
+	return topOrders(ob.bids, n)
+}
+
+// TopAskOrders returns the individual orders of up to n ask price levels,
+// best price first and in time priority within each level.
+func (ob *OrderBook) TopAskOrders(n int) []LevelOrders {
+	return topOrders(ob.asks, n)
+}
+
+// topOrders iterates the B-tree in order and groups entries into at most
+// n price levels.
+func topOrders(tree *btree.BTreeG[OrderBookEntry], n int) []LevelOrders {
+	if n <= 0 {
+		return nil
+	}
+	levels := make([]LevelOrders, 0, n)
+	tree.Ascend(func(entry OrderBookEntry) bool {
+		if len(levels) > 0 && levels[len(levels)-1].Price == entry.Price {
+			levels[len(levels)-1].Entries = append(levels[len(levels)-1].Entries, entry)
+			return true
+		}
+		if len(levels) >= n {
+			return false
+		}
+		levels = append(levels, LevelOrders{
+			Price:   entry.Price,
+			Entries: []OrderBookEntry{entry},
+		})
+		return true
+	})
+	return levels
+}
+
+// QueuePosition returns the number of orders and the quantity resting ahead
+// of the given order at its price level. Returns false if the order is not
+// on the book.
+func (ob *OrderBook) QueuePosition(orderID string) (QueuePosition, bool) {
+	entry, ok := ob.index[orderID]
+	if !ok {
+		return QueuePosition{}, false
+	}
+	tree := ob.asks
+	if entry.Order.Side == domain.OrderSideBid {
+		tree = ob.bids
+	}
+
+	var pos QueuePosition
+	// The zero CreatedAt and OrderID sort before every real entry at the price.
+	tree.AscendGreaterOrEqual(OrderBookEntry{Price: entry.Price}, func(e OrderBookEntry) bool {
+		if e.OrderID == orderID {
+			return false
+		}
+		pos.OrdersAhead++
+		pos.QuantityAhead += e.Order.RemainingQuantity
+		return true
+	})
+	return pos, true
+}
+
 // WalkAsks iterates asks in order (lowest price first). The callback
 // returns true to continue, false to stop. Used for market buy simulation.
 func (ob *OrderBook) WalkAsks(fn func(OrderBookEntry) bool) {
@@ -179,15 +415,29 @@ func (ob *OrderBook) AskCount() int {
 type BookManager struct {
 	mu    sync.RWMutex
 	books map[string]*OrderBook
+	pub   *publisher
+	clock clock.Clock
 }
 
-// NewBookManager creates a new BookManager.
-func NewBookManager() *BookManager {
+// NewBookManager creates a new BookManager whose books stamp their updates
+// with clk's time. A nil clk is the wall clock.
+func NewBookManager(clk clock.Clock) *BookManager {
+	if clk == nil {
+		clk = clock.System
+	}
 	return &BookManager{
 		books: make(map[string]*OrderBook),
+		pub:   &publisher{},
+		clock: clk,
 	}
 }
 
+// AddListener registers a listener for updates on every book managed by
+// this BookManager, including books created later.
+func (bm *BookManager) AddListener(l BookListener) {
+	bm.pub.add(l)
+}
+
 // GetOrCreate returns the order book for the given symbol, creating
 // one if it doesn't already exist.
 func (bm *BookManager) GetOrCreate(symbol string) *OrderBook {
@@ -205,6 +455,8 @@ func (bm *BookManager) GetOrCreate(symbol string) *OrderBook {
 		return book
 	}
 	book = NewOrderBook(symbol)
+	book.pub = bm.pub
+	book.clock = bm.clock
 	bm.books[symbol] = book
 	return book
 }
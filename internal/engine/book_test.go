@@ -255,6 +255,110 @@ func TestOrderBook_TopAsks_ZeroN(t *testing.T) {
 	}
 }
 
+func TestOrderBook_TopBidOrders(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.InsertBid(makeEntry(200, baseTime.Add(time.Second), "b2", 5))
+	ob.InsertBid(makeEntry(200, baseTime, "b1", 10))
+	ob.InsertBid(makeEntry(100, baseTime, "b3", 20))
+	ob.InsertBid(makeEntry(50, baseTime, "b4", 1))
+
+	levels := ob.TopBidOrders(2)
+	if len(levels) != 2 {
+		t.Fatalf("expected 2 price levels, got %d", len(levels))
+	}
+	if levels[0].Price != 200 || len(levels[0].Entries) != 2 {
+		t.Fatalf("level 0: got price=%d orders=%d", levels[0].Price, len(levels[0].Entries))
+	}
+	// Time priority within the level.
+	if levels[0].Entries[0].OrderID != "b1" || levels[0].Entries[1].OrderID != "b2" {
+		t.Errorf("expected [b1, b2] at 200, got [%s, %s]", levels[0].Entries[0].OrderID, levels[0].Entries[1].OrderID)
+	}
+	if levels[1].Price != 100 || len(levels[1].Entries) != 1 || levels[1].Entries[0].OrderID != "b3" {
+		t.Errorf("level 1: got %+v", levels[1])
+	}
+}
+
+func TestOrderBook_TopAskOrders(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.InsertAsk(makeEntry(200, baseTime, "a3", 20))
+	ob.InsertAsk(makeEntry(100, baseTime.Add(time.Second), "a2", 5))
+	ob.InsertAsk(makeEntry(100, baseTime, "a1", 10))
+
+	levels := ob.TopAskOrders(5)
+	if len(levels) != 2 {
+		t.Fatalf("expected 2 price levels, got %d", len(levels))
+	}
+	if levels[0].Price != 100 || len(levels[0].Entries) != 2 || levels[0].Entries[0].OrderID != "a1" {
+		t.Errorf("level 0: got %+v", levels[0])
+	}
+	if levels[1].Price != 200 || len(levels[1].Entries) != 1 {
+		t.Errorf("level 1: got %+v", levels[1])
+	}
+}
+
+func TestOrderBook_TopBidOrders_ZeroN(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.InsertBid(makeEntry(100, baseTime, "b1", 10))
+	if levels := ob.TopBidOrders(0); levels != nil {
+		t.Errorf("expected nil for n=0, got %v", levels)
+	}
+}
+
+func TestOrderBook_QueuePosition(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	for i, id := range []string{"b1", "b2", "b3"} {
+		e := makeEntry(200, baseTime.Add(time.Duration(i)*time.Second), id, int64(10*(i+1)))
+		e.Order.Side = domain.OrderSideBid
+		ob.InsertBid(e)
+	}
+	// A better-priced order does not count towards the queue at 200.
+	better := makeEntry(300, baseTime, "b0", 99)
+	better.Order.Side = domain.OrderSideBid
+	ob.InsertBid(better)
+
+	tests := []struct {
+		orderID  string
+		orders   int
+		quantity int64
+	}{
+		{"b0", 0, 0},
+		{"b1", 0, 0},
+		{"b2", 1, 10},
+		{"b3", 2, 30},
+	}
+	for _, tc := range tests {
+		pos, ok := ob.QueuePosition(tc.orderID)
+		if !ok {
+			t.Fatalf("%s: expected order to be on the book", tc.orderID)
+		}
+		if pos.OrdersAhead != tc.orders || pos.QuantityAhead != tc.quantity {
+			t.Errorf("%s: expected %d orders / %d ahead, got %+v", tc.orderID, tc.orders, tc.quantity, pos)
+		}
+	}
+}
+
+func TestOrderBook_QueuePosition_AskSide(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	a1 := makeEntry(100, baseTime, "a1", 7)
+	a1.Order.Side = domain.OrderSideAsk
+	a2 := makeEntry(100, baseTime.Add(time.Second), "a2", 3)
+	a2.Order.Side = domain.OrderSideAsk
+	ob.InsertAsk(a1)
+	ob.InsertAsk(a2)
+
+	pos, ok := ob.QueuePosition("a2")
+	if !ok || pos.OrdersAhead != 1 || pos.QuantityAhead != 7 {
+		t.Errorf("expected 1 order / 7 ahead, got %+v (ok=%v)", pos, ok)
+	}
+}
+
+func TestOrderBook_QueuePosition_NotOnBook(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	if _, ok := ob.QueuePosition("missing"); ok {
+		t.Error("expected false for an order not on the book")
+	}
+}
+
 func TestOrderBook_WalkAsks(t *testing.T) {
 	ob := NewOrderBook("AAPL")
 	ob.InsertAsk(makeEntry(300, baseTime, "a3", 1))
@@ -349,7 +453,7 @@ func TestOrderBook_AskTimePriority(t *testing.T) {
 // BookManager tests
 
 func TestBookManager_GetOrCreate(t *testing.T) {
-	bm := NewBookManager()
+	bm := NewBookManager(nil)
 	book1 := bm.GetOrCreate("AAPL")
 	if book1 == nil {
 		t.Fatal("expected non-nil book")
@@ -372,7 +476,7 @@ func TestBookManager_GetOrCreate(t *testing.T) {
 }
 
 func TestBookManager_GetOrCreate_Concurrent(t *testing.T) {
-	bm := NewBookManager()
+	bm := NewBookManager(nil)
 	const goroutines = 50
 	results := make(chan *OrderBook, goroutines)
 
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// TradeEvent is a single execution published in a BookUpdate. Unlike the
+// trade store, which records one trade per side, a TradeEvent is emitted
+// exactly once per match.
+type TradeEvent struct {
+	TradeID        string
+	Price          int64
+	Quantity       int64
+	AggressorSide  domain.OrderSide // side of the incoming order
+	RestingOrderID string
+	ExecutedAt     time.Time
+}
+
+// OrderEventType identifies what happened to a resting order.
+type OrderEventType int
+
+const (
+	// OrderAdded means the order was placed on the book.
+	OrderAdded OrderEventType = iota + 1
+	// OrderExecuted means some or all of the order's shares were filled.
+	OrderExecuted
+	// OrderRemoved means the order left the book, either because it was
+	// fully filled or because it was cancelled or expired.
+	OrderRemoved
+)
+
+// OrderEvent is a change to a single resting order, for consumers that
+// track the book order by order rather than by price level.
+type OrderEvent struct {
+	Type    OrderEventType
+	OrderID string
+	Side    domain.OrderSide
+	Price   int64 // limit price; the execution price for OrderExecuted
+	// Quantity is the shares placed for OrderAdded and the shares filled
+	// for OrderExecuted. It is zero for OrderRemoved; consumers derive the
+	// shares removed from the order's earlier events.
+	Quantity int64
+	TradeID  string // OrderExecuted only
+}
+
+// LevelUpdate carries the new aggregate state of a price level touched by
+// a book mutation. TotalQuantity == 0 means the level was removed.
+type LevelUpdate struct {
+	Side          domain.OrderSide
+	Price         int64
+	TotalQuantity int64
+	OrderCount    int
+}
+
+// BookUpdate describes every change made to a single symbol's book by one
+// matching pass, cancellation, or expiration. Seq is assigned per symbol
+// and increases by exactly one per published update. Orders lists the same
+// changes order by order, in the sequence they were made.
+type BookUpdate struct {
+	Symbol    string
+	Seq       uint64
+	Trades    []TradeEvent
+	Orders    []OrderEvent
+	Levels    []LevelUpdate
+	BestBid   *PriceLevel // nil if the bid side is empty
+	BestAsk   *PriceLevel // nil if the ask side is empty
+	Timestamp time.Time
+}
+
+// BookListener receives book updates after each mutation of a symbol's book.
+// Updates for the same symbol are delivered one at a time in Seq order.
+// OnBookUpdate is called without the book lock held but still on the
+// matching goroutine, so implementations must not block.
+type BookListener interface {
+	OnBookUpdate(u *BookUpdate)
+}
+
+// publisher fans out book updates to the registered listeners.
+type publisher struct {
+	mu        sync.RWMutex
+	listeners []BookListener
+}
+
+func (p *publisher) add(l BookListener) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.listeners = append(p.listeners, l)
+}
+
+// active reports whether any listener is registered. Books skip change
+// tracking entirely when nobody is listening.
+func (p *publisher) active() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.listeners) > 0
+}
+
+func (p *publisher) publish(u *BookUpdate) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, l := range p.listeners {
+		l.OnBookUpdate(u)
+	}
+}
+
+// levelKey identifies a price level on one side of the book.
+type levelKey struct {
+	side  domain.OrderSide
+	price int64
+}
+
+// pendingUpdate accumulates the changes made while the book's write lock is
+// held. It is turned into a BookUpdate when the lock is released.
+type pendingUpdate struct {
+	trades  []TradeEvent
+	orders  []OrderEvent
+	touched []levelKey
+	seen    map[levelKey]bool
+}
+
+func (p *pendingUpdate) touch(side domain.OrderSide, price int64) {
+	k := levelKey{side: side, price: price}
+	if p.seen[k] {
+		return
+	}
+	p.seen[k] = true
+	p.touched = append(p.touched, k)
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// recordingListener collects every BookUpdate it receives.
+type recordingListener struct {
+	mu      sync.Mutex
+	updates []*BookUpdate
+}
+
+func (l *recordingListener) OnBookUpdate(u *BookUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updates = append(l.updates, u)
+}
+
+func (l *recordingListener) get() []*BookUpdate {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]*BookUpdate, len(l.updates))
+	copy(result, l.updates)
+	return result
+}
+
+func TestBookListener_RestingOrderPublishesLevel(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	l := &recordingListener{}
+	m.books.AddListener(l)
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := l.get()
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(updates))
+	}
+	u := updates[0]
+	if u.Symbol != "AAPL" || u.Seq != 1 {
+		t.Errorf("got symbol=%s seq=%d, want AAPL/1", u.Symbol, u.Seq)
+	}
+	if len(u.Trades) != 0 {
+		t.Errorf("expected no trades, got %d", len(u.Trades))
+	}
+	want := LevelUpdate{Side: domain.OrderSideBid, Price: 15000, TotalQuantity: 10, OrderCount: 1}
+	if len(u.Levels) != 1 || u.Levels[0] != want {
+		t.Errorf("got levels %+v, want [%+v]", u.Levels, want)
+	}
+	if u.BestBid == nil || u.BestBid.Price != 15000 {
+		t.Errorf("expected best bid at 15000, got %+v", u.BestBid)
+	}
+	if u.BestAsk != nil {
+		t.Errorf("expected no best ask, got %+v", u.BestAsk)
+	}
+}
+
+func TestBookListener_MatchPublishesTradeOncePerFill(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	l := &recordingListener{}
+	m.books.AddListener(l)
+	registerBroker(bs, "buyer", 1000000, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 100}})
+
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := l.get()
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got %d", len(updates))
+	}
+	u := updates[1]
+	if u.Seq != 2 {
+		t.Errorf("expected seq 2, got %d", u.Seq)
+	}
+	if len(u.Trades) != 1 {
+		t.Fatalf("expected 1 trade event, got %d", len(u.Trades))
+	}
+	tr := u.Trades[0]
+	if tr.Price != 15000 || tr.Quantity != 4 || tr.AggressorSide != domain.OrderSideBid {
+		t.Errorf("unexpected trade event %+v", tr)
+	}
+	want := LevelUpdate{Side: domain.OrderSideAsk, Price: 15000, TotalQuantity: 6, OrderCount: 1}
+	if len(u.Levels) != 1 || u.Levels[0] != want {
+		t.Errorf("got levels %+v, want [%+v]", u.Levels, want)
+	}
+}
+
+func TestBookListener_UpdatesStampedWithClock(t *testing.T) {
+	start := time.Date(2030, 1, 2, 9, 30, 0, 0, time.UTC)
+	clk := clock.NewVirtual(start)
+	books := NewBookManager(clk)
+	bs := store.NewBrokerStore()
+	m := NewMatcher(books, bs, store.NewOrderStore(), store.NewTradeStore(), domain.NewSymbolRegistry(), nil, nil, clk, nil)
+	l := &recordingListener{}
+	books.AddListener(l)
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	clk.Advance(time.Minute)
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := l.get()
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(updates))
+	}
+	if want := start.Add(time.Minute); !updates[0].Timestamp.Equal(want) {
+		t.Errorf("got timestamp %v, want %v", updates[0].Timestamp, want)
+	}
+}
+
+func TestBookListener_OrderEvents(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	l := &recordingListener{}
+	m.books.AddListener(l)
+	registerBroker(bs, "buyer", 1000000, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 100}})
+
+	first := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5)
+	second := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15100, 5)
+	for _, o := range []*domain.Order{first, second} {
+		if _, err := m.MatchLimitOrder(context.Background(), o); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	bid := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15100, 12)
+	if _, err := m.MatchLimitOrder(context.Background(), bid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(context.Background(), bid.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := l.get()
+	if len(updates) != 4 {
+		t.Fatalf("expected 4 updates, got %d", len(updates))
+	}
+	added := updates[0].Orders
+	if len(added) != 1 || added[0] != (OrderEvent{Type: OrderAdded, OrderID: first.OrderID, Side: domain.OrderSideAsk, Price: 15000, Quantity: 5}) {
+		t.Errorf("unexpected add events %+v", added)
+	}
+
+	match := updates[2]
+	want := []OrderEvent{
+		{Type: OrderExecuted, OrderID: first.OrderID, Side: domain.OrderSideAsk, Price: 15000, Quantity: 5, TradeID: match.Trades[0].TradeID},
+		{Type: OrderRemoved, OrderID: first.OrderID, Side: domain.OrderSideAsk, Price: 15000},
+		{Type: OrderExecuted, OrderID: second.OrderID, Side: domain.OrderSideAsk, Price: 15100, Quantity: 5, TradeID: match.Trades[1].TradeID},
+		{Type: OrderRemoved, OrderID: second.OrderID, Side: domain.OrderSideAsk, Price: 15100},
+		{Type: OrderAdded, OrderID: bid.OrderID, Side: domain.OrderSideBid, Price: 15100, Quantity: 2},
+	}
+	if len(match.Orders) != len(want) {
+		t.Fatalf("got order events %+v, want %+v", match.Orders, want)
+	}
+	for i := range want {
+		if match.Orders[i] != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, match.Orders[i], want[i])
+		}
+	}
+
+	cancel := updates[3].Orders
+	if len(cancel) != 1 || cancel[0] != (OrderEvent{Type: OrderRemoved, OrderID: bid.OrderID, Side: domain.OrderSideBid, Price: 15100}) {
+		t.Errorf("unexpected cancel events %+v", cancel)
+	}
+}
+
+func TestBookListener_CancelPublishesLevelRemoval(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	l := &recordingListener{}
+	m.books.AddListener(l)
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)
+	if _, err := m.MatchLimitOrder(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(context.Background(), order.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := l.get()
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got %d", len(updates))
+	}
+	want := LevelUpdate{Side: domain.OrderSideBid, Price: 15000}
+	if len(updates[1].Levels) != 1 || updates[1].Levels[0] != want {
+		t.Errorf("got levels %+v, want [%+v]", updates[1].Levels, want)
+	}
+	if updates[1].BestBid != nil {
+		t.Errorf("expected empty bid side, got %+v", updates[1].BestBid)
+	}
+}
+
+func TestBookListener_ExpiryPublishesLevelRemoval(t *testing.T) {
+	books := NewBookManager(nil)
+	l := &recordingListener{}
+	books.AddListener(l)
+	em, _, _ := newTestExpiryManager(time.Second, nil)
+	em.books = books
+
+	now := time.Now()
+	order := newTestLimitOrder("o1", "b1", "AAPL", domain.OrderSideAsk, 15000, 5, now.Add(-time.Second))
+	book := books.GetOrCreate("AAPL")
+	book.InsertAsk(OrderBookEntry{Price: order.Price, CreatedAt: order.CreatedAt, OrderID: order.OrderID, Order: order})
+	em.Add(order)
+	em.tick(now)
+
+	updates := l.get()
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(updates))
+	}
+	want := LevelUpdate{Side: domain.OrderSideAsk, Price: 15000}
+	if len(updates[0].Levels) != 1 || updates[0].Levels[0] != want {
+		t.Errorf("got levels %+v, want [%+v]", updates[0].Levels, want)
+	}
+}
+
+func TestBookListener_NoListenerNoTracking(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	book := m.books.GetOrCreate("AAPL")
+	if book.seq != 0 {
+		t.Errorf("expected no sequence numbers assigned without listeners, got %d", book.seq)
+	}
+}
+
+func TestBookListener_ConcurrentPassesPublishInOrder(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	l := &recordingListener{}
+	m.books.AddListener(l)
+	registerBroker(bs, "buyer", 100000000, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", int64(10000+i), 1))
+		}(i)
+	}
+	wg.Wait()
+
+	updates := l.get()
+	if len(updates) != 50 {
+		t.Fatalf("expected 50 updates, got %d", len(updates))
+	}
+	for i, u := range updates {
+		if u.Seq != uint64(i+1) {
+			t.Fatalf("update %d has seq %d, want %d", i, u.Seq, i+1)
+		}
+	}
+}
@@ -6,14 +6,30 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
 
 // WebhookDispatcher is an interface for dispatching webhook notifications
 // from the engine layer without depending on the service layer directly.
+// Like OrderDispatcher, it is called with the symbol's book locked.
 type WebhookDispatcher interface {
-	DispatchOrderExpired(order *domain.Order)
+	DispatchOrderExpired(ctx context.Context, order *domain.Order)
+}
+
+// ExpiryObserver receives the duration of each expiration pass, for
+// monitoring.
+type ExpiryObserver interface {
+	ObserveExpiryTick(d time.Duration)
+}
+
+// ExpiryAuditor records each order expiration to the audit trail.
+type ExpiryAuditor interface {
+	RecordOrderExpired(order *domain.Order)
 }
 
 // ExpiryManager tracks active limit orders sorted by expires_at and
@@ -24,24 +40,38 @@ type ExpiryManager struct {
 	orderStore   *store.OrderStore
 	brokerStore  *store.BrokerStore
 	webhookSvc   WebhookDispatcher
+	observer     ExpiryObserver  // optional
+	auditor      ExpiryAuditor   // optional
+	clock        clock.Clock     // decides when orders are due
 	activeOrders []*domain.Order // sorted by expires_at ASC
 	mu           sync.Mutex      // protects activeOrders slice
 }
 
 // NewExpiryManager creates a new ExpiryManager with the given dependencies.
+// observer and auditor may be nil. Orders are due once clk reaches their
+// expires_at; a nil clk is the wall clock.
 func NewExpiryManager(
 	interval time.Duration,
 	books *BookManager,
 	orderStore *store.OrderStore,
 	brokerStore *store.BrokerStore,
 	webhookSvc WebhookDispatcher,
+	observer ExpiryObserver,
+	auditor ExpiryAuditor,
+	clk clock.Clock,
 ) *ExpiryManager {
+	if clk == nil {
+		clk = clock.System
+	}
 	return &ExpiryManager{
 		interval:     interval,
 		books:        books,
 		orderStore:   orderStore,
 		brokerStore:  brokerStore,
 		webhookSvc:   webhookSvc,
+		observer:     observer,
+		auditor:      auditor,
+		clock:        clk,
 		activeOrders: make([]*domain.Order, 0),
 	}
 }
@@ -90,16 +120,27 @@ func (e *ExpiryManager) Start(ctx context.Context) {
 			select {
 			case <-ctx.Done():
 				return
-			case t := <-ticker.C:
-				e.tick(t)
+			case <-ticker.C:
+				e.tick(e.clock.Now())
 			}
 		}
 	}()
 }
 
+// ExpireDue expires every order due at the clock's current time. Replay
+// calls it as its virtual clock moves, in place of Start.
+func (e *ExpiryManager) ExpireDue() {
+	e.tick(e.clock.Now())
+}
+
 // tick iterates from the front of the sorted activeOrders slice and
 // expires all orders where expires_at <= now.
 func (e *ExpiryManager) tick(now time.Time) {
+	if e.observer != nil {
+		start := time.Now()
+		defer func() { e.observer.ObserveExpiryTick(time.Since(start)) }()
+	}
+
 	// Collect orders to expire under the expiry manager lock.
 	e.mu.Lock()
 	var toExpire []*domain.Order
@@ -126,18 +167,26 @@ func (e *ExpiryManager) tick(now time.Time) {
 
 // expireOrder handles the expiration of a single order: acquires the
 // per-symbol write lock, re-checks status, transitions to expired,
-// releases reservation, removes from book, and fires webhook.
+// releases reservation, removes from book, records the expiration to the
+// audit trail, and fires webhook. Each
+// expiration is traced as its own trace.
 func (e *ExpiryManager) expireOrder(order *domain.Order) {
+	ctx, span := tracer().Start(context.Background(), "engine.expire_order", trace.WithAttributes(
+		attribute.String("order.id", order.OrderID),
+		attribute.String("order.symbol", order.Symbol),
+	))
+	defer span.End()
+
 	// Step 1: Acquire per-symbol write lock.
 	book := e.books.GetOrCreate(order.Symbol)
-	book.mu.Lock()
+	book.lock(ctx)
 
 	// Step 2: Re-check status (may have been filled/cancelled since last check).
 	switch order.Status {
 	case domain.OrderStatusPending, domain.OrderStatusPartiallyFilled:
 		// Still eligible for expiration.
 	default:
-		book.mu.Unlock()
+		book.unlockAndPublish()
 		return
 	}
 
@@ -147,6 +196,11 @@ func (e *ExpiryManager) expireOrder(order *domain.Order) {
 	order.RemainingQuantity = 0
 	order.Status = domain.OrderStatusExpired
 	order.ExpiredAt = order.ExpiresAt
+	order.Record(domain.OrderEvent{
+		Type:     domain.OrderEventExpired,
+		Time:     *order.ExpiresAt,
+		Quantity: order.CancelledQuantity,
+	})
 
 	// Step 4: Remove from book.
 	book.Remove(order.OrderID)
@@ -167,18 +221,22 @@ func (e *ExpiryManager) expireOrder(order *domain.Order) {
 		broker.Mu.Unlock()
 	}
 
-	// Release per-symbol lock before webhook dispatch to avoid blocking
-	// the matching engine on network I/O.
-	book.mu.Unlock()
-
-	// Step 6: Fire webhook (outside lock).
+	// Step 6: Audit and fire the webhook under the lock, like the matcher,
+	// so the expiry is recorded and numbered after every execution of the
+	// order. The webhook is only queued here; it is delivered in the
+	// background.
+	if e.auditor != nil {
+		e.auditor.RecordOrderExpired(order)
+	}
 	if e.webhookSvc != nil {
-		e.webhookSvc.DispatchOrderExpired(order)
+		e.webhookSvc.DispatchOrderExpired(ctx, order)
 	}
+
+	book.unlockAndPublish()
 }
 
 // ActiveOrderCount returns the number of orders currently tracked for
-// expiration. Useful for testing.
+// expiration.
 func (e *ExpiryManager) ActiveOrderCount() int {
 	e.mu.Lock()
 	defer e.mu.Unlock()
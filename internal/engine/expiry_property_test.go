@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -28,16 +29,16 @@ func TestProperty_ExpirationStateTransition(t *testing.T) {
 		doPartialFill := qty >= 2 && rapid.Bool().Draw(t, "doPartialFill")
 
 		// Set up dependencies.
-		books := NewBookManager()
+		books := NewBookManager(nil)
 		orderStore := store.NewOrderStore()
 		brokerStore := store.NewBrokerStore()
 		webhook := &mockWebhookDispatcher{}
-		em := NewExpiryManager(time.Second, books, orderStore, brokerStore, webhook)
+		em := NewExpiryManager(time.Second, books, orderStore, brokerStore, webhook, nil, nil, nil)
 
 		// Also create a matcher for placing orders properly.
 		symbols := domain.NewSymbolRegistry()
 		tradeStore := store.NewTradeStore()
-		m := NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+		m := NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 
 		// Base time: "now" is a fixed reference point.
 		now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
@@ -97,13 +98,13 @@ func TestProperty_ExpirationStateTransition(t *testing.T) {
 				// Place a counterparty order at a compatible price first.
 				if isBid {
 					counterOrder := newLimitOrder("counterparty", domain.OrderSideAsk, sym, price, fillQty)
-					_, err := m.MatchLimitOrder(counterOrder)
+					_, err := m.MatchLimitOrder(context.Background(), counterOrder)
 					if err != nil {
 						t.Fatalf("failed to place counterparty ask %d: %v", i, err)
 					}
 				} else {
 					counterOrder := newLimitOrder("counterparty", domain.OrderSideBid, sym, price, fillQty)
-					_, err := m.MatchLimitOrder(counterOrder)
+					_, err := m.MatchLimitOrder(context.Background(), counterOrder)
 					if err != nil {
 						t.Fatalf("failed to place counterparty bid %d: %v", i, err)
 					}
@@ -117,7 +118,7 @@ func TestProperty_ExpirationStateTransition(t *testing.T) {
 			} else {
 				order = newLimitOrder("main", domain.OrderSideAsk, sym, price, qty)
 			}
-			_, err := m.MatchLimitOrder(order)
+			_, err := m.MatchLimitOrder(context.Background(), order)
 			if err != nil {
 				t.Fatalf("failed to place order %d: %v", i, err)
 			}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
@@ -16,7 +17,7 @@ type mockWebhookDispatcher struct {
 	expired []*domain.Order
 }
 
-func (m *mockWebhookDispatcher) DispatchOrderExpired(order *domain.Order) {
+func (m *mockWebhookDispatcher) DispatchOrderExpired(_ context.Context, order *domain.Order) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.expired = append(m.expired, order)
@@ -32,10 +33,10 @@ func (m *mockWebhookDispatcher) getExpired() []*domain.Order {
 
 // helper to create a test ExpiryManager with all dependencies.
 func newTestExpiryManager(interval time.Duration, webhook WebhookDispatcher) (*ExpiryManager, *BookManager, *store.BrokerStore) {
-	books := NewBookManager()
+	books := NewBookManager(nil)
 	orderStore := store.NewOrderStore()
 	brokerStore := store.NewBrokerStore()
-	em := NewExpiryManager(interval, books, orderStore, brokerStore, webhook)
+	em := NewExpiryManager(interval, books, orderStore, brokerStore, webhook, nil, nil, nil)
 	return em, books, brokerStore
 }
 
@@ -353,6 +354,11 @@ func TestExpiryManager_Tick_PartiallyFilledOrder(t *testing.T) {
 	if o1.FilledQuantity != 5 {
 		t.Errorf("expected filled_quantity preserved at 5, got %d", o1.FilledQuantity)
 	}
+	events := o1.History.Events()
+	if len(events) != 1 || events[0].Type != domain.OrderEventExpired || events[0].Quantity != 5 ||
+		!events[0].Time.Equal(*o1.ExpiresAt) || events[0].FilledQuantity != 5 {
+		t.Errorf("expected an expired event for 5 shares at expires_at, got %+v", events)
+	}
 
 	broker.Mu.Lock()
 	if broker.ReservedCash != 0 {
@@ -503,3 +509,53 @@ func TestExpiryManager_Tick_MultipleSymbols(t *testing.T) {
 		t.Fatalf("expected 2 webhook dispatches, got %d", len(expired))
 	}
 }
+
+// tickRecorder records the durations passed to ObserveExpiryTick.
+type tickRecorder struct {
+	ticks []time.Duration
+}
+
+func (r *tickRecorder) ObserveExpiryTick(d time.Duration) {
+	r.ticks = append(r.ticks, d)
+}
+
+func TestExpiryManager_Tick_Observed(t *testing.T) {
+	rec := &tickRecorder{}
+	em := NewExpiryManager(time.Second, NewBookManager(nil), store.NewOrderStore(), store.NewBrokerStore(), nil, rec, nil, nil)
+
+	em.tick(time.Now())
+	em.tick(time.Now())
+
+	if len(rec.ticks) != 2 {
+		t.Fatalf("expected 2 observed ticks, got %d", len(rec.ticks))
+	}
+	for i, d := range rec.ticks {
+		if d < 0 {
+			t.Errorf("tick %d: negative duration %v", i, d)
+		}
+	}
+}
+
+func TestExpiryManager_ExpireDue_UsesClock(t *testing.T) {
+	start := time.Date(2026, 1, 15, 14, 30, 0, 0, time.UTC)
+	clk := clock.NewVirtual(start)
+	books := NewBookManager(nil)
+	brokerStore := store.NewBrokerStore()
+	brokerStore.Create(&domain.Broker{BrokerID: "b1", ReservedCash: 1000, Holdings: make(map[string]*domain.Holding)})
+	em := NewExpiryManager(time.Hour, books, store.NewOrderStore(), brokerStore, nil, nil, nil, clk)
+
+	o1 := newTestLimitOrder("o1", "b1", "AAPL", domain.OrderSideBid, 100, 10, start.Add(time.Minute))
+	books.GetOrCreate("AAPL").InsertBid(OrderBookEntry{Price: o1.Price, CreatedAt: o1.CreatedAt, OrderID: o1.OrderID, Order: o1})
+	em.Add(o1)
+
+	em.ExpireDue()
+	if o1.Status != domain.OrderStatusPending {
+		t.Fatalf("expected the order to stay pending before expires_at, got %s", o1.Status)
+	}
+
+	clk.Advance(time.Minute)
+	em.ExpireDue()
+	if o1.Status != domain.OrderStatusExpired {
+		t.Errorf("expected the order to expire at expires_at, got %s", o1.Status)
+	}
+}
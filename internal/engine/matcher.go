@@ -1,10 +1,14 @@
 package engine
 
 import (
+	"context"
 	"time"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
@@ -24,6 +28,45 @@ type QuoteResult struct {
 	PriceLevels       []QuotePriceLevel
 }
 
+// OrderAuditor records what the matcher does to orders to the audit trail.
+// It is called with the symbol's book locked, so the records of a book's
+// orders are appended in the order the events happened.
+type OrderAuditor interface {
+	// RecordOrderAccepted records a new order before it matches.
+	RecordOrderAccepted(order *domain.Order)
+	// RecordOrderMatched records trade, one of order's executions, made
+	// when filledBefore of the order had filled, against counterpartyOrderID.
+	RecordOrderMatched(order *domain.Order, trade *domain.Trade, filledBefore int64, counterpartyOrderID string)
+	// RecordOrderUnfilled records the cancellation, at, of the part of a
+	// market order that found no liquidity.
+	RecordOrderUnfilled(order *domain.Order, at time.Time)
+	// RecordOrderCancelled records a cancellation through CancelOrder,
+	// with the ctx it was requested under.
+	RecordOrderCancelled(ctx context.Context, order *domain.Order)
+}
+
+// Fill is one execution of a matching pass: the incoming order's record of
+// the trade, and the resting order it matched with that order's record.
+type Fill struct {
+	Trade        *domain.Trade
+	Resting      *domain.Order
+	RestingTrade *domain.Trade
+}
+
+// OrderDispatcher notifies brokers of what the matcher does to their
+// orders. Like OrderAuditor, it is called with the symbol's book locked, so
+// that the notifications of a book's orders are numbered and queued in the
+// order the events happened.
+type OrderDispatcher interface {
+	// DispatchOrderMatched notifies the brokers of a new order and of the
+	// orders it matched, once its matching pass is done. fills are in the
+	// order they were made.
+	DispatchOrderMatched(ctx context.Context, order *domain.Order, fills []Fill)
+	// DispatchOrderCancelled notifies a broker of a cancellation through
+	// CancelOrder.
+	DispatchOrderCancelled(ctx context.Context, order *domain.Order)
+}
+
 // Matcher implements the matching engine for limit and market orders.
 type Matcher struct {
 	books       *BookManager
@@ -31,22 +74,42 @@ type Matcher struct {
 	orderStore  *store.OrderStore
 	tradeStore  *store.TradeStore
 	symbols     *domain.SymbolRegistry
+	dispatcher  OrderDispatcher // optional
+	auditor     OrderAuditor    // optional
+	clock       clock.Clock
+	ids         clock.IDGenerator
 }
 
-// NewMatcher creates a new Matcher with the given dependencies.
+// NewMatcher creates a new Matcher with the given dependencies. dispatcher
+// and auditor may be nil. Orders and trades are stamped with clk's time and given IDs from
+// ids; nil means the wall clock and random UUIDs.
 func NewMatcher(
 	books *BookManager,
 	brokerStore *store.BrokerStore,
 	orderStore *store.OrderStore,
 	tradeStore *store.TradeStore,
 	symbols *domain.SymbolRegistry,
+	dispatcher OrderDispatcher,
+	auditor OrderAuditor,
+	clk clock.Clock,
+	ids clock.IDGenerator,
 ) *Matcher {
+	if clk == nil {
+		clk = clock.System
+	}
+	if ids == nil {
+		ids = clock.UUIDs
+	}
 	return &Matcher{
 		books:       books,
 		brokerStore: brokerStore,
 		orderStore:  orderStore,
 		tradeStore:  tradeStore,
 		symbols:     symbols,
+		dispatcher:  dispatcher,
+		auditor:     auditor,
+		clock:       clk,
+		ids:         ids,
 	}
 }
 
@@ -56,15 +119,30 @@ func NewMatcher(
 // remainder on the book.
 //
 // The caller must provide a fully populated Order with Type, BrokerID,
-// Side, Symbol, Price, and Quantity set. The matcher assigns OrderID,
-// CreatedAt, and manages all status transitions.
+// Side, Symbol, Price, and Quantity set. The matcher assigns OrderID if
+// the caller has not, sets CreatedAt, and manages all status transitions.
 //
-// The per-symbol write lock is held for the entire matching pass.
-func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error) {
+// The per-symbol write lock is held for the entire matching pass. The
+// resulting book update is published to listeners after it is released.
+// The pass is traced under ctx.
+func (m *Matcher) MatchLimitOrder(ctx context.Context, order *domain.Order) ([]*domain.Trade, error) {
+	ctx, span := tracer().Start(ctx, "engine.match_limit_order", orderAttributes(order))
+	defer span.End()
+
+	trades, err := m.matchLimitOrder(ctx, order)
+	recordMatch(span, order, trades, err)
+	return trades, err
+}
+
+func (m *Matcher) matchLimitOrder(ctx context.Context, order *domain.Order) ([]*domain.Trade, error) {
 	book := m.books.GetOrCreate(order.Symbol)
 
-	book.mu.Lock()
-	defer book.mu.Unlock()
+	ctx = book.lock(ctx)
+	defer book.unlockAndPublish()
+
+	if book.halted {
+		return nil, domain.ErrSymbolHalted
+	}
 
 	// Step 1: Validate and reserve.
 	broker, err := m.brokerStore.Get(order.BrokerID)
@@ -97,19 +175,30 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 	// Register the symbol and initialize the order record.
 	m.symbols.Register(order.Symbol)
 
-	order.OrderID = uuid.New().String()
-	order.CreatedAt = time.Now()
+	if order.OrderID == "" {
+		order.OrderID = m.ids.NewID()
+	}
+	order.CreatedAt = m.clock.Now()
 	order.RemainingQuantity = order.Quantity
 	order.FilledQuantity = 0
 	order.CancelledQuantity = 0
 	order.Status = domain.OrderStatusPending
 	order.Trades = []*domain.Trade{}
+	order.Record(domain.OrderEvent{
+		Type:     domain.OrderEventAccepted,
+		Time:     order.CreatedAt,
+		Quantity: order.Quantity,
+	})
 
 	m.orderStore.Create(order)
+	if m.auditor != nil {
+		m.auditor.RecordOrderAccepted(order)
+	}
 
 	// Step 2–3: Match loop.
-	executedAt := time.Now()
+	executedAt := m.clock.Now()
 	var trades []*domain.Trade
+	var fills []Fill
 
 	for order.RemainingQuantity > 0 {
 		// Step 3a: Peek best opposite.
@@ -153,9 +242,10 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 		}
 
 		// Step 3e: Execute the trade.
-		tradeID := uuid.New().String()
+		tradeID := m.ids.NewID()
 
 		// Update both orders.
+		orderFilledBefore, restingFilledBefore := order.FilledQuantity, resting.FilledQuantity
 		order.RemainingQuantity -= fillQty
 		order.FilledQuantity += fillQty
 		resting.RemainingQuantity -= fillQty
@@ -182,6 +272,12 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 			askOrder = order
 		}
 
+		_, settle := tracer().Start(ctx, "engine.settle", trace.WithAttributes(
+			attribute.String("trade.id", tradeID),
+			attribute.Int64("trade.price", executionPrice),
+			attribute.Int64("trade.quantity", fillQty),
+		))
+
 		// Settle buyer.
 		buyer, _ := m.brokerStore.Get(bidOrder.BrokerID)
 		buyer.Mu.Lock()
@@ -200,11 +296,14 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 		seller.Holdings[order.Symbol].Quantity -= fillQty
 		seller.Holdings[order.Symbol].ReservedQuantity -= fillQty
 		seller.Mu.Unlock()
+		settle.End()
 
 		// Create trade records for both orders.
 		incomingTrade := &domain.Trade{
 			TradeID:    tradeID,
 			OrderID:    order.OrderID,
+			Side:       order.Side,
+			Aggressor:  true,
 			Price:      executionPrice,
 			Quantity:   fillQty,
 			ExecutedAt: executedAt,
@@ -212,6 +311,7 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 		restingTrade := &domain.Trade{
 			TradeID:    tradeID,
 			OrderID:    resting.OrderID,
+			Side:       resting.Side,
 			Price:      executionPrice,
 			Quantity:   fillQty,
 			ExecutedAt: executedAt,
@@ -219,13 +319,30 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 
 		order.Trades = append(order.Trades, incomingTrade)
 		resting.Trades = append(resting.Trades, restingTrade)
+		order.Record(fillEvent(order, incomingTrade))
+		resting.Record(fillEvent(resting, restingTrade))
 
 		trades = append(trades, incomingTrade)
+		fills = append(fills, Fill{Trade: incomingTrade, Resting: resting, RestingTrade: restingTrade})
+		if m.auditor != nil {
+			m.auditor.RecordOrderMatched(order, incomingTrade, orderFilledBefore, resting.OrderID)
+			m.auditor.RecordOrderMatched(resting, restingTrade, restingFilledBefore, order.OrderID)
+		}
 
 		// Append to trade store for both sides.
 		m.tradeStore.Append(order.Symbol, incomingTrade)
 		m.tradeStore.Append(order.Symbol, restingTrade)
 
+		book.touch(resting.Side, resting.Price)
+		book.recordTrade(TradeEvent{
+			TradeID:        tradeID,
+			Price:          executionPrice,
+			Quantity:       fillQty,
+			AggressorSide:  order.Side,
+			RestingOrderID: resting.OrderID,
+			ExecutedAt:     executedAt,
+		})
+
 		// Remove resting order from book if fully filled.
 		if resting.RemainingQuantity == 0 {
 			book.Remove(resting.OrderID)
@@ -246,6 +363,9 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 			book.InsertAsk(entry)
 		}
 	}
+	if m.dispatcher != nil {
+		m.dispatcher.DispatchOrderMatched(ctx, order, fills)
+	}
 
 	return trades, nil
 }
@@ -258,12 +378,27 @@ func (m *Matcher) MatchLimitOrder(order *domain.Order) ([]*domain.Trade, error)
 // book to estimate cost. For market asks, available_quantity is checked and
 // shares are reserved before matching.
 //
-// The per-symbol write lock is held for the entire matching pass.
-func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error) {
+// The per-symbol write lock is held for the entire matching pass. The
+// resulting book update is published to listeners after it is released.
+// The pass is traced under ctx.
+func (m *Matcher) MatchMarketOrder(ctx context.Context, order *domain.Order) ([]*domain.Trade, error) {
+	ctx, span := tracer().Start(ctx, "engine.match_market_order", orderAttributes(order))
+	defer span.End()
+
+	trades, err := m.matchMarketOrder(ctx, order)
+	recordMatch(span, order, trades, err)
+	return trades, err
+}
+
+func (m *Matcher) matchMarketOrder(ctx context.Context, order *domain.Order) ([]*domain.Trade, error) {
 	book := m.books.GetOrCreate(order.Symbol)
 
-	book.mu.Lock()
-	defer book.mu.Unlock()
+	ctx = book.lock(ctx)
+	defer book.unlockAndPublish()
+
+	if book.halted {
+		return nil, domain.ErrSymbolHalted
+	}
 
 	// Step 0: No-liquidity check — if opposite side is empty, reject immediately.
 	if order.Side == domain.OrderSideBid {
@@ -322,19 +457,30 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 	// Register the symbol and initialize the order record.
 	m.symbols.Register(order.Symbol)
 
-	order.OrderID = uuid.New().String()
-	order.CreatedAt = time.Now()
+	if order.OrderID == "" {
+		order.OrderID = m.ids.NewID()
+	}
+	order.CreatedAt = m.clock.Now()
 	order.RemainingQuantity = order.Quantity
 	order.FilledQuantity = 0
 	order.CancelledQuantity = 0
 	order.Status = domain.OrderStatusPending
 	order.Trades = []*domain.Trade{}
+	order.Record(domain.OrderEvent{
+		Type:     domain.OrderEventAccepted,
+		Time:     order.CreatedAt,
+		Quantity: order.Quantity,
+	})
 
 	m.orderStore.Create(order)
+	if m.auditor != nil {
+		m.auditor.RecordOrderAccepted(order)
+	}
 
 	// Step 2–3: Match loop (no price compatibility check for market orders).
-	executedAt := time.Now()
+	executedAt := m.clock.Now()
 	var trades []*domain.Trade
+	var fills []Fill
 
 	for order.RemainingQuantity > 0 {
 		// Peek best opposite.
@@ -364,9 +510,10 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 		executionPrice := resting.Price
 
 		// Execute the trade.
-		tradeID := uuid.New().String()
+		tradeID := m.ids.NewID()
 
 		// Update both orders.
+		orderFilledBefore, restingFilledBefore := order.FilledQuantity, resting.FilledQuantity
 		order.RemainingQuantity -= fillQty
 		order.FilledQuantity += fillQty
 		resting.RemainingQuantity -= fillQty
@@ -393,6 +540,12 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 			askOrder = order
 		}
 
+		_, settle := tracer().Start(ctx, "engine.settle", trace.WithAttributes(
+			attribute.String("trade.id", tradeID),
+			attribute.Int64("trade.price", executionPrice),
+			attribute.Int64("trade.quantity", fillQty),
+		))
+
 		// Settle buyer.
 		buyer, _ := m.brokerStore.Get(bidOrder.BrokerID)
 		buyer.Mu.Lock()
@@ -413,11 +566,14 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 		seller.Holdings[order.Symbol].Quantity -= fillQty
 		seller.Holdings[order.Symbol].ReservedQuantity -= fillQty
 		seller.Mu.Unlock()
+		settle.End()
 
 		// Create trade records for both orders.
 		incomingTrade := &domain.Trade{
 			TradeID:    tradeID,
 			OrderID:    order.OrderID,
+			Side:       order.Side,
+			Aggressor:  true,
 			Price:      executionPrice,
 			Quantity:   fillQty,
 			ExecutedAt: executedAt,
@@ -425,6 +581,7 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 		restingTrade := &domain.Trade{
 			TradeID:    tradeID,
 			OrderID:    resting.OrderID,
+			Side:       resting.Side,
 			Price:      executionPrice,
 			Quantity:   fillQty,
 			ExecutedAt: executedAt,
@@ -432,13 +589,30 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 
 		order.Trades = append(order.Trades, incomingTrade)
 		resting.Trades = append(resting.Trades, restingTrade)
+		order.Record(fillEvent(order, incomingTrade))
+		resting.Record(fillEvent(resting, restingTrade))
 
 		trades = append(trades, incomingTrade)
+		fills = append(fills, Fill{Trade: incomingTrade, Resting: resting, RestingTrade: restingTrade})
+		if m.auditor != nil {
+			m.auditor.RecordOrderMatched(order, incomingTrade, orderFilledBefore, resting.OrderID)
+			m.auditor.RecordOrderMatched(resting, restingTrade, restingFilledBefore, order.OrderID)
+		}
 
 		// Append to trade store for both sides.
 		m.tradeStore.Append(order.Symbol, incomingTrade)
 		m.tradeStore.Append(order.Symbol, restingTrade)
 
+		book.touch(resting.Side, resting.Price)
+		book.recordTrade(TradeEvent{
+			TradeID:        tradeID,
+			Price:          executionPrice,
+			Quantity:       fillQty,
+			AggressorSide:  order.Side,
+			RestingOrderID: resting.OrderID,
+			ExecutedAt:     executedAt,
+		})
+
 		// Remove resting order from book if fully filled.
 		if resting.RemainingQuantity == 0 {
 			book.Remove(resting.OrderID)
@@ -446,7 +620,9 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 	}
 
 	// Step 4: IOC cancellation — never rest on book.
+	var cancelledAt time.Time
 	if order.RemainingQuantity > 0 {
+		cancelledAt = m.clock.Now()
 		order.CancelledQuantity = order.RemainingQuantity
 		order.RemainingQuantity = 0
 		if order.FilledQuantity == order.Quantity {
@@ -454,6 +630,11 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 		} else {
 			order.Status = domain.OrderStatusCancelled
 		}
+		order.Record(domain.OrderEvent{
+			Type:     domain.OrderEventCancelled,
+			Time:     cancelledAt,
+			Quantity: order.CancelledQuantity,
+		})
 	}
 
 	// Release remaining reservation for market asks.
@@ -463,6 +644,12 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 		seller.Holdings[order.Symbol].ReservedQuantity -= order.CancelledQuantity
 		seller.Mu.Unlock()
 	}
+	if m.auditor != nil && order.Status == domain.OrderStatusCancelled {
+		m.auditor.RecordOrderUnfilled(order, cancelledAt)
+	}
+	if m.dispatcher != nil {
+		m.dispatcher.DispatchOrderMatched(ctx, order, fills)
+	}
 
 	return trades, nil
 }
@@ -474,7 +661,20 @@ func (m *Matcher) MatchMarketOrder(order *domain.Order) ([]*domain.Trade, error)
 // Returns ErrOrderNotFound if the order does not exist.
 // Returns ErrOrderNotCancellable if the order is in a terminal state
 // (filled, cancelled, or expired).
-func (m *Matcher) CancelOrder(orderID string) (*domain.Order, error) {
+func (m *Matcher) CancelOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	ctx, span := tracer().Start(ctx, "engine.cancel_order", trace.WithAttributes(
+		attribute.String("order.id", orderID),
+	))
+	defer span.End()
+
+	order, err := m.cancelOrder(ctx, orderID)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return order, err
+}
+
+func (m *Matcher) cancelOrder(ctx context.Context, orderID string) (*domain.Order, error) {
 	// Step 1: Look up the order.
 	order, err := m.orderStore.Get(orderID)
 	if err != nil {
@@ -482,17 +682,21 @@ func (m *Matcher) CancelOrder(orderID string) (*domain.Order, error) {
 	}
 
 	// Step 2: Validate status — only pending or partially_filled can be cancelled.
+	// The request goes into the order's history either way. A terminal
+	// order is off the book, so its quantities can be read without the lock.
 	switch order.Status {
 	case domain.OrderStatusPending, domain.OrderStatusPartiallyFilled:
 		// OK — proceed with cancellation.
 	default:
+		order.Record(domain.OrderEvent{Type: domain.OrderEventCancelRequested, Time: m.clock.Now()})
 		return nil, domain.ErrOrderNotCancellable
 	}
 
 	// Step 3: Acquire per-symbol write lock.
 	book := m.books.GetOrCreate(order.Symbol)
-	book.mu.Lock()
-	defer book.mu.Unlock()
+	ctx = book.lock(ctx)
+	defer book.unlockAndPublish()
+	order.Record(domain.OrderEvent{Type: domain.OrderEventCancelRequested, Time: m.clock.Now()})
 
 	// Re-check status under lock (another goroutine may have changed it).
 	switch order.Status {
@@ -506,11 +710,16 @@ func (m *Matcher) CancelOrder(orderID string) (*domain.Order, error) {
 	book.Remove(order.OrderID)
 
 	// Step 5: Update order fields.
-	now := time.Now()
+	now := m.clock.Now()
 	order.CancelledQuantity = order.RemainingQuantity
 	order.RemainingQuantity = 0
 	order.Status = domain.OrderStatusCancelled
 	order.CancelledAt = &now
+	order.Record(domain.OrderEvent{
+		Type:     domain.OrderEventCancelled,
+		Time:     now,
+		Quantity: order.CancelledQuantity,
+	})
 
 	// Step 6: Release reservation.
 	broker, err := m.brokerStore.Get(order.BrokerID)
@@ -527,10 +736,67 @@ func (m *Matcher) CancelOrder(orderID string) (*domain.Order, error) {
 		}
 		broker.Mu.Unlock()
 	}
+	if m.auditor != nil {
+		m.auditor.RecordOrderCancelled(ctx, order)
+	}
+	if m.dispatcher != nil {
+		m.dispatcher.DispatchOrderCancelled(ctx, order)
+	}
 
 	return order, nil
 }
 
+// fillEvent returns the history event for trade, one of order's executions,
+// once order's quantities reflect it.
+func fillEvent(order *domain.Order, trade *domain.Trade) domain.OrderEvent {
+	t := domain.OrderEventPartiallyFilled
+	if order.RemainingQuantity == 0 {
+		t = domain.OrderEventFilled
+	}
+	return domain.OrderEvent{
+		Type:     t,
+		Time:     trade.ExecutedAt,
+		Quantity: trade.Quantity,
+		Price:    trade.Price,
+		TradeID:  trade.TradeID,
+	}
+}
+
+// SetHalted halts or resumes trading in a symbol and reports whether that
+// changed anything. While a symbol is halted, new orders for it are rejected
+// with ErrSymbolHalted; resting orders stay on the book and can still be
+// cancelled or expire.
+func (m *Matcher) SetHalted(symbol string, halted bool) bool {
+	book := m.books.GetOrCreate(symbol)
+
+	book.lock(context.Background())
+	defer book.unlockAndPublish()
+
+	changed := book.halted != halted
+	book.halted = halted
+	return changed
+}
+
+// Halted reports whether trading in a symbol is halted.
+func (m *Matcher) Halted(symbol string) bool {
+	book := m.books.GetOrCreate(symbol)
+
+	book.mu.RLock()
+	defer book.mu.RUnlock()
+	return book.halted
+}
+
+// QueuePosition reports how much resting quantity is ahead of an order at
+// its price level. Returns false if the order is not resting on the book.
+func (m *Matcher) QueuePosition(symbol, orderID string) (QueuePosition, bool) {
+	book := m.books.GetOrCreate(symbol)
+
+	book.mu.RLock()
+	defer book.mu.RUnlock()
+
+	return book.QueuePosition(orderID)
+}
+
 // SimulateMarketOrder performs a read-only walk of the opposite side of the
 // book to estimate the result of a market order without actually placing it.
 // For bid quotes it walks asks (lowest first); for ask quotes it walks bids
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -29,14 +30,14 @@ func TestProperty_PriceCompatibilityDeterminesMatching(t *testing.T) {
 
 		// Place the ask order on the book first.
 		askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", askPrice, qty)
-		_, err := m.MatchLimitOrder(askOrder)
+		_, err := m.MatchLimitOrder(context.Background(), askOrder)
 		if err != nil {
 			t.Fatalf("failed to place ask: %v", err)
 		}
 
 		// Now submit the bid order.
 		bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", bidPrice, qty)
-		trades, err := m.MatchLimitOrder(bidOrder)
+		trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 		if err != nil {
 			t.Fatalf("failed to place bid: %v", err)
 		}
@@ -85,13 +86,13 @@ func TestProperty_ExecutionPriceEqualsAskPrice(t *testing.T) {
 
 		// Place ask on book, then submit bid.
 		askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", askPrice, qty)
-		_, err := m.MatchLimitOrder(askOrder)
+		_, err := m.MatchLimitOrder(context.Background(), askOrder)
 		if err != nil {
 			t.Fatalf("failed to place ask: %v", err)
 		}
 
 		bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", bidPrice, qty)
-		trades, err := m.MatchLimitOrder(bidOrder)
+		trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 		if err != nil {
 			t.Fatalf("failed to place bid: %v", err)
 		}
@@ -116,13 +117,13 @@ func TestProperty_ExecutionPriceEqualsAskPrice(t *testing.T) {
 
 		// Place bid on book first, then submit ask.
 		bidOrder2 := newLimitOrder("buyer2", domain.OrderSideBid, "TEST", bidPrice, qty)
-		_, err = m2.MatchLimitOrder(bidOrder2)
+		_, err = m2.MatchLimitOrder(context.Background(), bidOrder2)
 		if err != nil {
 			t.Fatalf("failed to place bid: %v", err)
 		}
 
 		askOrder2 := newLimitOrder("seller2", domain.OrderSideAsk, "TEST", askPrice, qty)
-		trades2, err := m2.MatchLimitOrder(askOrder2)
+		trades2, err := m2.MatchLimitOrder(context.Background(), askOrder2)
 		if err != nil {
 			t.Fatalf("failed to place ask: %v", err)
 		}
@@ -189,7 +190,7 @@ func TestProperty_QuantityConservation(t *testing.T) {
 		// Place asks on the book.
 		for i, a := range askSpecs {
 			askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", a.price, a.qty)
-			_, err := m.MatchLimitOrder(askOrder)
+			_, err := m.MatchLimitOrder(context.Background(), askOrder)
 			if err != nil {
 				t.Fatalf("failed to place ask %d: %v", i, err)
 			}
@@ -200,7 +201,7 @@ func TestProperty_QuantityConservation(t *testing.T) {
 		// Submit bids that may or may not match.
 		for i, b := range bidSpecs {
 			bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", b.price, b.qty)
-			_, err := m.MatchLimitOrder(bidOrder)
+			_, err := m.MatchLimitOrder(context.Background(), bidOrder)
 			if err != nil {
 				t.Fatalf("failed to place bid %d: %v", i, err)
 			}
@@ -246,7 +247,7 @@ func TestProperty_AveragePriceComputation(t *testing.T) {
 		// Place all asks on the book.
 		for i, a := range asks {
 			askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", a.price, a.qty)
-			_, err := m.MatchLimitOrder(askOrder)
+			_, err := m.MatchLimitOrder(context.Background(), askOrder)
 			if err != nil {
 				t.Fatalf("failed to place ask %d: %v", i, err)
 			}
@@ -256,7 +257,7 @@ func TestProperty_AveragePriceComputation(t *testing.T) {
 		bidQty := rapid.Int64Range(1, totalShares).Draw(t, "bidQty")
 		// Use a high price to ensure matching.
 		bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", 10000, bidQty)
-		trades, err := m.MatchLimitOrder(bidOrder)
+		trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 		if err != nil {
 			t.Fatalf("failed to place bid: %v", err)
 		}
@@ -306,7 +307,7 @@ func TestProperty_AveragePriceNullWhenNoTrades(t *testing.T) {
 
 		// Place a bid with no asks on the book — no trades possible.
 		bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", price, qty)
-		trades, err := m.MatchLimitOrder(bidOrder)
+		trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -363,7 +364,7 @@ func TestProperty_MarketOrderIOCSemantics(t *testing.T) {
 			// Place resting asks.
 			for i, r := range restingOrders {
 				askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", r.price, r.qty)
-				_, err := m.MatchLimitOrder(askOrder)
+				_, err := m.MatchLimitOrder(context.Background(), askOrder)
 				if err != nil {
 					t.Fatalf("failed to place ask %d: %v", i, err)
 				}
@@ -371,7 +372,7 @@ func TestProperty_MarketOrderIOCSemantics(t *testing.T) {
 
 			// Submit market bid.
 			marketOrder := newMarketOrder("buyer", domain.OrderSideBid, "TEST", marketQty)
-			_, err := m.MatchMarketOrder(marketOrder)
+			_, err := m.MatchMarketOrder(context.Background(), marketOrder)
 			if err != nil {
 				t.Fatalf("unexpected error on market bid: %v", err)
 			}
@@ -388,7 +389,7 @@ func TestProperty_MarketOrderIOCSemantics(t *testing.T) {
 			// Place resting bids.
 			for i, r := range restingOrders {
 				bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", r.price, r.qty)
-				_, err := m.MatchLimitOrder(bidOrder)
+				_, err := m.MatchLimitOrder(context.Background(), bidOrder)
 				if err != nil {
 					t.Fatalf("failed to place bid %d: %v", i, err)
 				}
@@ -396,7 +397,7 @@ func TestProperty_MarketOrderIOCSemantics(t *testing.T) {
 
 			// Submit market ask.
 			marketOrder := newMarketOrder("seller", domain.OrderSideAsk, "TEST", marketQty)
-			_, err := m.MatchMarketOrder(marketOrder)
+			_, err := m.MatchMarketOrder(context.Background(), marketOrder)
 			if err != nil {
 				t.Fatalf("unexpected error on market ask: %v", err)
 			}
@@ -493,14 +494,14 @@ func TestProperty_CancellationStateTransition(t *testing.T) {
 
 				// Place the ask at a compatible price.
 				askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", price, fillQty)
-				_, err := m.MatchLimitOrder(askOrder)
+				_, err := m.MatchLimitOrder(context.Background(), askOrder)
 				if err != nil {
 					t.Fatalf("failed to place ask: %v", err)
 				}
 			}
 
 			order = newLimitOrder("buyer", domain.OrderSideBid, "TEST", price, qty)
-			_, err := m.MatchLimitOrder(order)
+			_, err := m.MatchLimitOrder(context.Background(), order)
 			if err != nil {
 				t.Fatalf("failed to place bid: %v", err)
 			}
@@ -518,14 +519,14 @@ func TestProperty_CancellationStateTransition(t *testing.T) {
 
 				// Place a bid at a compatible price.
 				bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", price, fillQty)
-				_, err := m.MatchLimitOrder(bidOrder)
+				_, err := m.MatchLimitOrder(context.Background(), bidOrder)
 				if err != nil {
 					t.Fatalf("failed to place bid: %v", err)
 				}
 			}
 
 			order = newLimitOrder("seller", domain.OrderSideAsk, "TEST", price, qty)
-			_, err := m.MatchLimitOrder(order)
+			_, err := m.MatchLimitOrder(context.Background(), order)
 			if err != nil {
 				t.Fatalf("failed to place ask: %v", err)
 			}
@@ -543,7 +544,7 @@ func TestProperty_CancellationStateTransition(t *testing.T) {
 		prevFilledQty := order.FilledQuantity
 
 		// Cancel the order.
-		cancelled, err := m.CancelOrder(order.OrderID)
+		cancelled, err := m.CancelOrder(context.Background(), order.OrderID)
 		if err != nil {
 			t.Fatalf("expected cancellation to succeed, got error: %v", err)
 		}
@@ -603,13 +604,13 @@ func TestProperty_CancellationTerminalStateFails(t *testing.T) {
 
 		// Place ask, then matching bid to get a filled order.
 		askOrder := newLimitOrder("seller", domain.OrderSideAsk, "TEST", price, qty)
-		_, err := m.MatchLimitOrder(askOrder)
+		_, err := m.MatchLimitOrder(context.Background(), askOrder)
 		if err != nil {
 			t.Fatalf("failed to place ask: %v", err)
 		}
 
 		bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "TEST", price, qty)
-		_, err = m.MatchLimitOrder(bidOrder)
+		_, err = m.MatchLimitOrder(context.Background(), bidOrder)
 		if err != nil {
 			t.Fatalf("failed to place bid: %v", err)
 		}
@@ -619,24 +620,24 @@ func TestProperty_CancellationTerminalStateFails(t *testing.T) {
 		}
 
 		// Cancelling a filled order should fail.
-		_, err = m.CancelOrder(bidOrder.OrderID)
+		_, err = m.CancelOrder(context.Background(), bidOrder.OrderID)
 		if err != domain.ErrOrderNotCancellable {
 			t.Fatalf("expected ErrOrderNotCancellable for filled order, got %v", err)
 		}
 
 		// Test cancelling an already-cancelled order.
 		pendingBid := newLimitOrder("buyer", domain.OrderSideBid, "TEST", price, qty)
-		_, err = m.MatchLimitOrder(pendingBid)
+		_, err = m.MatchLimitOrder(context.Background(), pendingBid)
 		if err != nil {
 			t.Fatalf("failed to place pending bid: %v", err)
 		}
 
-		_, err = m.CancelOrder(pendingBid.OrderID)
+		_, err = m.CancelOrder(context.Background(), pendingBid.OrderID)
 		if err != nil {
 			t.Fatalf("first cancel should succeed: %v", err)
 		}
 
-		_, err = m.CancelOrder(pendingBid.OrderID)
+		_, err = m.CancelOrder(context.Background(), pendingBid.OrderID)
 		if err != domain.ErrOrderNotCancellable {
 			t.Fatalf("expected ErrOrderNotCancellable for already-cancelled order, got %v", err)
 		}
@@ -703,11 +704,11 @@ func TestProperty_QuoteSimulationAccuracy(t *testing.T) {
 			// Place identical resting asks on both books.
 			for i, r := range restingOrders {
 				ask1 := newLimitOrder("seller", domain.OrderSideAsk, "TEST", r.price, r.qty)
-				if _, err := m1.MatchLimitOrder(ask1); err != nil {
+				if _, err := m1.MatchLimitOrder(context.Background(), ask1); err != nil {
 					t.Fatalf("m1: failed to place ask %d: %v", i, err)
 				}
 				ask2 := newLimitOrder("seller", domain.OrderSideAsk, "TEST", r.price, r.qty)
-				if _, err := m2.MatchLimitOrder(ask2); err != nil {
+				if _, err := m2.MatchLimitOrder(context.Background(), ask2); err != nil {
 					t.Fatalf("m2: failed to place ask %d: %v", i, err)
 				}
 			}
@@ -729,11 +730,11 @@ func TestProperty_QuoteSimulationAccuracy(t *testing.T) {
 			// Place identical resting bids on both books.
 			for i, r := range restingOrders {
 				bid1 := newLimitOrder("buyer", domain.OrderSideBid, "TEST", r.price, r.qty)
-				if _, err := m1.MatchLimitOrder(bid1); err != nil {
+				if _, err := m1.MatchLimitOrder(context.Background(), bid1); err != nil {
 					t.Fatalf("m1: failed to place bid %d: %v", i, err)
 				}
 				bid2 := newLimitOrder("buyer", domain.OrderSideBid, "TEST", r.price, r.qty)
-				if _, err := m2.MatchLimitOrder(bid2); err != nil {
+				if _, err := m2.MatchLimitOrder(context.Background(), bid2); err != nil {
 					t.Fatalf("m2: failed to place bid %d: %v", i, err)
 				}
 			}
@@ -747,7 +748,7 @@ func TestProperty_QuoteSimulationAccuracy(t *testing.T) {
 		if side == domain.OrderSideAsk {
 			marketOrder = newMarketOrder("seller", side, "TEST", quoteQty)
 		}
-		_, err := m2.MatchMarketOrder(marketOrder)
+		_, err := m2.MatchMarketOrder(context.Background(), marketOrder)
 		if err != nil {
 			t.Fatalf("market order execution failed: %v", err)
 		}
@@ -860,10 +861,10 @@ func TestProperty_CashConservation(t *testing.T) {
 			if isBid {
 				order := newLimitOrder(brokerID, domain.OrderSideBid, "AAPL", price, qty)
 				// Ignore errors — insufficient balance is expected for random orders.
-				m.MatchLimitOrder(order)
+				m.MatchLimitOrder(context.Background(), order)
 			} else {
 				order := newLimitOrder(brokerID, domain.OrderSideAsk, "AAPL", price, qty)
-				m.MatchLimitOrder(order)
+				m.MatchLimitOrder(context.Background(), order)
 			}
 		}
 
@@ -944,10 +945,10 @@ func TestProperty_HoldingsConservation(t *testing.T) {
 			if isBid {
 				order := newLimitOrder(brokerID, domain.OrderSideBid, symbol, price, qty)
 				// Ignore errors — insufficient balance/holdings is expected for random orders.
-				m.MatchLimitOrder(order)
+				m.MatchLimitOrder(context.Background(), order)
 			} else {
 				order := newLimitOrder(brokerID, domain.OrderSideAsk, symbol, price, qty)
-				m.MatchLimitOrder(order)
+				m.MatchLimitOrder(context.Background(), order)
 			}
 		}
 
@@ -1030,7 +1031,7 @@ func TestProperty_ReservationConsistency(t *testing.T) {
 				order = newLimitOrder(brokerID, domain.OrderSideAsk, symbol, price, qty)
 			}
 
-			_, err := m.MatchLimitOrder(order)
+			_, err := m.MatchLimitOrder(context.Background(), order)
 			if err == nil {
 				placedOrderIDs = append(placedOrderIDs, order.OrderID)
 			}
@@ -1047,7 +1048,7 @@ func TestProperty_ReservationConsistency(t *testing.T) {
 					continue
 				}
 				cancelled[idx] = true
-				m.CancelOrder(placedOrderIDs[idx])
+				m.CancelOrder(context.Background(), placedOrderIDs[idx])
 			}
 		}
 
@@ -1,6 +1,9 @@
 package engine
 
 import (
+	"context"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -10,12 +13,12 @@ import (
 
 // newTestMatcher creates a Matcher with fresh stores for testing.
 func newTestMatcher() (*Matcher, *store.BrokerStore, *store.OrderStore, *store.TradeStore) {
-	books := NewBookManager()
+	books := NewBookManager(nil)
 	brokerStore := store.NewBrokerStore()
 	orderStore := store.NewOrderStore()
 	tradeStore := store.NewTradeStore()
 	symbols := domain.NewSymbolRegistry()
-	m := NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+	m := NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 	return m, brokerStore, orderStore, tradeStore
 }
 
@@ -53,7 +56,7 @@ func TestMatchLimitOrder_BidNoMatch_RestsOnBook(t *testing.T) {
 	registerBroker(bs, "buyer", 100000, nil) // $1000.00
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5) // $150 × 5
-	trades, err := m.MatchLimitOrder(order)
+	trades, err := m.MatchLimitOrder(context.Background(), order)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -84,7 +87,7 @@ func TestMatchLimitOrder_AskNoMatch_RestsOnBook(t *testing.T) {
 	})
 
 	order := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5)
-	trades, err := m.MatchLimitOrder(order)
+	trades, err := m.MatchLimitOrder(context.Background(), order)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -110,14 +113,14 @@ func TestMatchLimitOrder_FullMatch(t *testing.T) {
 
 	// Seller places ask at $150.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5)
-	_, err := m.MatchLimitOrder(askOrder)
+	_, err := m.MatchLimitOrder(context.Background(), askOrder)
 	if err != nil {
 		t.Fatalf("ask order error: %v", err)
 	}
 
 	// Buyer places bid at $150 — should fully match.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	trades, err := m.MatchLimitOrder(bidOrder)
+	trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 	if err != nil {
 		t.Fatalf("bid order error: %v", err)
 	}
@@ -153,11 +156,11 @@ func TestMatchLimitOrder_ExecutionPriceIsAskPrice(t *testing.T) {
 
 	// Ask at $100.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
-	m.MatchLimitOrder(askOrder)
+	m.MatchLimitOrder(context.Background(), askOrder)
 
 	// Bid at $150 — execution price should be $100 (the ask price).
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	trades, _ := m.MatchLimitOrder(bidOrder)
+	trades, _ := m.MatchLimitOrder(context.Background(), bidOrder)
 
 	if len(trades) != 1 {
 		t.Fatalf("expected 1 trade, got %d", len(trades))
@@ -176,11 +179,11 @@ func TestMatchLimitOrder_IncomingAsk_ExecutionPriceIsIncomingAskPrice(t *testing
 
 	// Bid at $150 rests on book.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	m.MatchLimitOrder(bidOrder)
+	m.MatchLimitOrder(context.Background(), bidOrder)
 
 	// Ask at $100 comes in — execution price = incoming ask price = $100.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
-	trades, _ := m.MatchLimitOrder(askOrder)
+	trades, _ := m.MatchLimitOrder(context.Background(), askOrder)
 
 	if len(trades) != 1 {
 		t.Fatalf("expected 1 trade, got %d", len(trades))
@@ -199,11 +202,11 @@ func TestMatchLimitOrder_PartialFill_RestsRemainder(t *testing.T) {
 
 	// Ask for 3 shares at $100.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 3)
-	m.MatchLimitOrder(askOrder)
+	m.MatchLimitOrder(context.Background(), askOrder)
 
 	// Bid for 5 shares at $100 — fills 3, rests 2.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5)
-	trades, _ := m.MatchLimitOrder(bidOrder)
+	trades, _ := m.MatchLimitOrder(context.Background(), bidOrder)
 
 	if len(trades) != 1 {
 		t.Fatalf("expected 1 trade, got %d", len(trades))
@@ -241,12 +244,12 @@ func TestMatchLimitOrder_MultipleFills(t *testing.T) {
 	// Two asks at different prices.
 	ask1 := newLimitOrder("s1", domain.OrderSideAsk, "AAPL", 10000, 3) // $100 × 3
 	ask2 := newLimitOrder("s2", domain.OrderSideAsk, "AAPL", 11000, 4) // $110 × 4
-	m.MatchLimitOrder(ask1)
-	m.MatchLimitOrder(ask2)
+	m.MatchLimitOrder(context.Background(), ask1)
+	m.MatchLimitOrder(context.Background(), ask2)
 
 	// Bid for 5 at $110 — fills 3 at $100 then 2 at $110.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 11000, 5)
-	trades, _ := m.MatchLimitOrder(bidOrder)
+	trades, _ := m.MatchLimitOrder(context.Background(), bidOrder)
 
 	if len(trades) != 2 {
 		t.Fatalf("expected 2 trades, got %d", len(trades))
@@ -276,11 +279,11 @@ func TestMatchLimitOrder_NoPriceCompatibility(t *testing.T) {
 
 	// Ask at $200.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 20000, 5)
-	m.MatchLimitOrder(askOrder)
+	m.MatchLimitOrder(context.Background(), askOrder)
 
 	// Bid at $100 — no match.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5)
-	trades, _ := m.MatchLimitOrder(bidOrder)
+	trades, _ := m.MatchLimitOrder(context.Background(), bidOrder)
 
 	if len(trades) != 0 {
 		t.Errorf("expected 0 trades, got %d", len(trades))
@@ -300,7 +303,7 @@ func TestMatchLimitOrder_InsufficientBalance(t *testing.T) {
 	registerBroker(bs, "buyer", 1000, nil) // only $10
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5) // needs $750
-	_, err := m.MatchLimitOrder(order)
+	_, err := m.MatchLimitOrder(context.Background(), order)
 	if err != domain.ErrInsufficientBalance {
 		t.Errorf("expected ErrInsufficientBalance, got %v", err)
 	}
@@ -313,7 +316,7 @@ func TestMatchLimitOrder_InsufficientHoldings(t *testing.T) {
 	})
 
 	order := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5) // needs 5, has 2
-	_, err := m.MatchLimitOrder(order)
+	_, err := m.MatchLimitOrder(context.Background(), order)
 	if err != domain.ErrInsufficientHoldings {
 		t.Errorf("expected ErrInsufficientHoldings, got %v", err)
 	}
@@ -323,7 +326,7 @@ func TestMatchLimitOrder_BrokerNotFound(t *testing.T) {
 	m, _, _, _ := newTestMatcher()
 
 	order := newLimitOrder("nonexistent", domain.OrderSideBid, "AAPL", 15000, 5)
-	_, err := m.MatchLimitOrder(order)
+	_, err := m.MatchLimitOrder(context.Background(), order)
 	if err != domain.ErrBrokerNotFound {
 		t.Errorf("expected ErrBrokerNotFound, got %v", err)
 	}
@@ -338,11 +341,11 @@ func TestMatchLimitOrder_BalanceSettlement(t *testing.T) {
 
 	// Ask at $100 for 5 shares.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
-	m.MatchLimitOrder(askOrder)
+	m.MatchLimitOrder(context.Background(), askOrder)
 
 	// Bid at $100 for 5 shares — full match.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5)
-	m.MatchLimitOrder(bidOrder)
+	m.MatchLimitOrder(context.Background(), bidOrder)
 
 	// Buyer: cash = 10000 - (100*5) = $500 = 50000 cents.
 	if buyer.CashBalance != 950000 {
@@ -376,11 +379,11 @@ func TestMatchLimitOrder_PriceImprovement(t *testing.T) {
 
 	// Ask at $100.
 	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
-	m.MatchLimitOrder(askOrder)
+	m.MatchLimitOrder(context.Background(), askOrder)
 
 	// Bid at $150 — execution at $100, buyer gets price improvement.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	m.MatchLimitOrder(bidOrder)
+	m.MatchLimitOrder(context.Background(), bidOrder)
 
 	// Buyer reserved 150*5=750 ($75000 cents), paid 100*5=500 ($50000 cents).
 	// reserved_cash should be 0 (all released), cash = 1000000 - 50000 = 950000.
@@ -403,12 +406,12 @@ func TestMatchLimitOrder_AveragePrice(t *testing.T) {
 	registerBroker(bs, "buyer", 5000000, nil)
 
 	// Two asks: 3 @ $100, 2 @ $110.
-	m.MatchLimitOrder(newLimitOrder("s1", domain.OrderSideAsk, "AAPL", 10000, 3))
-	m.MatchLimitOrder(newLimitOrder("s2", domain.OrderSideAsk, "AAPL", 11000, 2))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("s1", domain.OrderSideAsk, "AAPL", 10000, 3))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("s2", domain.OrderSideAsk, "AAPL", 11000, 2))
 
 	// Bid for 5 @ $110.
 	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 11000, 5)
-	m.MatchLimitOrder(bidOrder)
+	m.MatchLimitOrder(context.Background(), bidOrder)
 
 	// avg = (10000*3 + 11000*2) / 5 = (30000 + 22000) / 5 = 52000 / 5 = 10400
 	avg, ok := bidOrder.AveragePrice()
@@ -427,8 +430,8 @@ func TestMatchLimitOrder_TradesAppendedToTradeStore(t *testing.T) {
 	})
 	registerBroker(bs, "buyer", 1000000, nil)
 
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
 
 	// Each trade generates 2 trade records (one per order side).
 	trades := ts.GetBySymbol("AAPL")
@@ -441,7 +444,7 @@ func TestMatchLimitOrder_SymbolRegistered(t *testing.T) {
 	m, bs, _, _ := newTestMatcher()
 	registerBroker(bs, "buyer", 1000000, nil)
 
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 1))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 1))
 
 	if !m.symbols.Exists("AAPL") {
 		t.Error("expected AAPL to be registered in symbol registry")
@@ -453,7 +456,7 @@ func TestMatchLimitOrder_OrderStoredInOrderStore(t *testing.T) {
 	registerBroker(bs, "buyer", 1000000, nil)
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 1)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	retrieved, err := os.Get(order.OrderID)
 	if err != nil {
@@ -469,7 +472,7 @@ func TestMatchLimitOrder_ReservationForUnfilledBid(t *testing.T) {
 	buyer := registerBroker(bs, "buyer", 1000000, nil)
 
 	// Bid for 5 at $100 — no match, rests on book.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
 
 	// Reserved: 10000 * 5 = 50000.
 	if buyer.ReservedCash != 50000 {
@@ -484,7 +487,7 @@ func TestMatchLimitOrder_ReservationForUnfilledAsk(t *testing.T) {
 	})
 
 	// Ask for 5 — no match, rests on book.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
 
 	if seller.Holdings["AAPL"].ReservedQuantity != 5 {
 		t.Errorf("expected reserved qty 5, got %d", seller.Holdings["AAPL"].ReservedQuantity)
@@ -499,10 +502,10 @@ func TestMatchLimitOrder_SelfTrade(t *testing.T) {
 	})
 
 	// Place ask.
-	m.MatchLimitOrder(newLimitOrder("broker1", domain.OrderSideAsk, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("broker1", domain.OrderSideAsk, "AAPL", 10000, 5))
 	// Place bid — matches own ask.
 	bidOrder := newLimitOrder("broker1", domain.OrderSideBid, "AAPL", 10000, 5)
-	trades, err := m.MatchLimitOrder(bidOrder)
+	trades, err := m.MatchLimitOrder(context.Background(), bidOrder)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -542,11 +545,11 @@ func TestMatchMarketOrder_FullFill_Buy(t *testing.T) {
 	})
 
 	// Place resting ask at $100.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
 
 	// Market buy 10 shares.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	trades, err := m.MatchMarketOrder(order)
+	trades, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -576,11 +579,11 @@ func TestMatchMarketOrder_FullFill_Sell(t *testing.T) {
 	})
 
 	// Place resting bid at $100.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 10))
 
 	// Market sell 10 shares.
 	order := newMarketOrder("seller", domain.OrderSideAsk, "AAPL", 10)
-	trades, err := m.MatchMarketOrder(order)
+	trades, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -604,11 +607,11 @@ func TestMatchMarketOrder_PartialFill_IOCCancel(t *testing.T) {
 	})
 
 	// Place resting ask for only 5 shares at $100.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
 
 	// Market buy 10 shares — only 5 available.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	trades, err := m.MatchMarketOrder(order)
+	trades, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -641,7 +644,7 @@ func TestMatchMarketOrder_NoLiquidity_Bid(t *testing.T) {
 
 	// No asks on the book.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	_, err := m.MatchMarketOrder(order)
+	_, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != domain.ErrNoLiquidity {
 		t.Fatalf("expected ErrNoLiquidity, got %v", err)
@@ -660,7 +663,7 @@ func TestMatchMarketOrder_NoLiquidity_Ask(t *testing.T) {
 
 	// No bids on the book.
 	order := newMarketOrder("seller", domain.OrderSideAsk, "AAPL", 10)
-	_, err := m.MatchMarketOrder(order)
+	_, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != domain.ErrNoLiquidity {
 		t.Fatalf("expected ErrNoLiquidity, got %v", err)
@@ -678,11 +681,11 @@ func TestMatchMarketOrder_InsufficientBalance(t *testing.T) {
 	})
 
 	// Place resting ask at $100.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
 
 	// Market buy 10 shares — costs $1000, buyer only has $10.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	_, err := m.MatchMarketOrder(order)
+	_, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != domain.ErrInsufficientBalance {
 		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
@@ -697,11 +700,11 @@ func TestMatchMarketOrder_InsufficientHoldings(t *testing.T) {
 	})
 
 	// Place resting bid.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 20))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 20))
 
 	// Market sell 10 shares — seller only has 5.
 	order := newMarketOrder("seller", domain.OrderSideAsk, "AAPL", 10)
-	_, err := m.MatchMarketOrder(order)
+	_, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != domain.ErrInsufficientHoldings {
 		t.Fatalf("expected ErrInsufficientHoldings, got %v", err)
@@ -716,12 +719,12 @@ func TestMatchMarketOrder_ExecutionPriceIsRestingPrice(t *testing.T) {
 	})
 
 	// Place asks at different prices.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)) // $100
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 11000, 5)) // $110
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)) // $100
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 11000, 5)) // $110
 
 	// Market buy 10 — should fill 5@$100 + 5@$110.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	trades, err := m.MatchMarketOrder(order)
+	trades, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -751,11 +754,11 @@ func TestMatchMarketOrder_NeverRestsOnBook(t *testing.T) {
 	})
 
 	// Place resting ask for only 3 shares.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 3))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 3))
 
 	// Market buy 10 — only 3 fill, remainder cancelled.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	// Verify the market order is NOT on the book.
 	book := m.books.GetOrCreate("AAPL")
@@ -771,10 +774,10 @@ func TestMatchMarketOrder_OrderStoredInOrderStore(t *testing.T) {
 		"AAPL": {Quantity: 100},
 	})
 
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
 
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	stored, err := os.Get(order.OrderID)
 	if err != nil {
@@ -793,11 +796,11 @@ func TestMatchMarketOrder_BalanceSettlement_Buy(t *testing.T) {
 	})
 
 	// Place ask at $100.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
 
 	// Market buy 5 shares at $100 each = $500 total.
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 5)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	// Buyer should have $5000 - $500 = $4500.
 	if buyer.CashBalance != 450000 {
@@ -821,11 +824,11 @@ func TestMatchMarketOrder_BalanceSettlement_Sell(t *testing.T) {
 	})
 
 	// Place bid at $100.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 5))
 
 	// Market sell 5 shares.
 	order := newMarketOrder("seller", domain.OrderSideAsk, "AAPL", 5)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	// Seller should have $500 in cash.
 	if seller.CashBalance != 50000 {
@@ -845,11 +848,11 @@ func TestMatchMarketOrder_MarketAsk_ReservationReleasedOnPartialFill(t *testing.
 	})
 
 	// Place bid for only 3 shares.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 3))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 3))
 
 	// Market sell 10 — only 3 fill, 7 cancelled.
 	order := newMarketOrder("seller", domain.OrderSideAsk, "AAPL", 10)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	if order.FilledQuantity != 3 {
 		t.Errorf("expected filled 3, got %d", order.FilledQuantity)
@@ -872,10 +875,10 @@ func TestMatchMarketOrder_BrokerNotFound(t *testing.T) {
 	registerBroker(bs, "seller", 0, map[string]*domain.Holding{
 		"AAPL": {Quantity: 100},
 	})
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
 
 	order := newMarketOrder("nonexistent", domain.OrderSideBid, "AAPL", 5)
-	_, err := m.MatchMarketOrder(order)
+	_, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != domain.ErrBrokerNotFound {
 		t.Fatalf("expected ErrBrokerNotFound, got %v", err)
@@ -896,13 +899,13 @@ func TestMatchMarketOrder_MultiplePriceLevels(t *testing.T) {
 	})
 
 	// Place asks at 3 different price levels.
-	m.MatchLimitOrder(newLimitOrder("seller1", domain.OrderSideAsk, "AAPL", 10000, 100)) // $100
-	m.MatchLimitOrder(newLimitOrder("seller2", domain.OrderSideAsk, "AAPL", 11000, 200)) // $110
-	m.MatchLimitOrder(newLimitOrder("seller3", domain.OrderSideAsk, "AAPL", 12000, 50))  // $120
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller1", domain.OrderSideAsk, "AAPL", 10000, 100)) // $100
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller2", domain.OrderSideAsk, "AAPL", 11000, 200)) // $110
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller3", domain.OrderSideAsk, "AAPL", 12000, 50))  // $120
 
 	// Market buy 250 — sweeps $100 (100), $110 (150 of 200).
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 250)
-	trades, err := m.MatchMarketOrder(order)
+	trades, err := m.MatchMarketOrder(context.Background(), order)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -931,10 +934,10 @@ func TestMatchMarketOrder_TradesAppendedToTradeStore(t *testing.T) {
 		"AAPL": {Quantity: 100},
 	})
 
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10))
 
 	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
-	m.MatchMarketOrder(order)
+	m.MatchMarketOrder(context.Background(), order)
 
 	// Should have trades from the limit order placement (0 trades) + market order (2 trade records: one per side).
 	allTrades := ts.GetBySymbol("AAPL")
@@ -945,14 +948,91 @@ func TestMatchMarketOrder_TradesAppendedToTradeStore(t *testing.T) {
 
 // --- CancelOrder tests ---
 
+func TestSetHalted_RejectsNewOrders(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "buyer", 100000, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{
+		"AAPL": {Quantity: 10},
+	})
+	resting := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5)
+	if _, err := m.MatchLimitOrder(context.Background(), resting); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !m.SetHalted("AAPL", true) {
+		t.Error("expected halting to change the symbol's state")
+	}
+	if m.SetHalted("AAPL", true) {
+		t.Error("expected halting a halted symbol to change nothing")
+	}
+	if !m.Halted("AAPL") || m.Halted("MSFT") {
+		t.Error("expected only AAPL to be halted")
+	}
+
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)); err != domain.ErrSymbolHalted {
+		t.Errorf("limit order: expected ErrSymbolHalted, got %v", err)
+	}
+	if _, err := m.MatchMarketOrder(context.Background(), newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 5)); err != domain.ErrSymbolHalted {
+		t.Errorf("market order: expected ErrSymbolHalted, got %v", err)
+	}
+	if b, _ := bs.Get("buyer"); b.ReservedCash != 0 {
+		t.Errorf("rejected order reserved cash: %d", b.ReservedCash)
+	}
+
+	// Resting orders can still be cancelled while halted.
+	if _, err := m.CancelOrder(context.Background(), resting.OrderID); err != nil {
+		t.Errorf("cancel while halted: unexpected error: %v", err)
+	}
+
+	if !m.SetHalted("AAPL", false) {
+		t.Error("expected resuming to change the symbol's state")
+	}
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)); err != nil {
+		t.Errorf("after resume: unexpected error: %v", err)
+	}
+}
+
+func TestQueuePosition_RestingAndFilled(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 100}})
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	first := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10)
+	m.MatchLimitOrder(context.Background(), first)
+	second := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
+	m.MatchLimitOrder(context.Background(), second)
+
+	pos, ok := m.QueuePosition("AAPL", second.OrderID)
+	if !ok || pos.OrdersAhead != 1 || pos.QuantityAhead != 10 {
+		t.Fatalf("expected 1 order / 10 ahead, got %+v (ok=%v)", pos, ok)
+	}
+
+	// A partial fill of the first order shrinks the quantity ahead.
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 4))
+	pos, _ = m.QueuePosition("AAPL", second.OrderID)
+	if pos.QuantityAhead != 6 {
+		t.Errorf("expected 6 ahead after partial fill, got %d", pos.QuantityAhead)
+	}
+
+	// Filling the first order completely takes it off the book.
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 10000, 6))
+	if _, ok := m.QueuePosition("AAPL", first.OrderID); ok {
+		t.Error("expected filled order to have no queue position")
+	}
+	pos, _ = m.QueuePosition("AAPL", second.OrderID)
+	if pos.OrdersAhead != 0 || pos.QuantityAhead != 0 {
+		t.Errorf("expected front of queue, got %+v", pos)
+	}
+}
+
 func TestCancelOrder_PendingBid(t *testing.T) {
 	m, bs, _, _ := newTestMatcher()
 	registerBroker(bs, "buyer", 1000000, nil) // $10,000
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5) // $150 × 5
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
-	cancelled, err := m.CancelOrder(order.OrderID)
+	cancelled, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -980,9 +1060,9 @@ func TestCancelOrder_PendingAsk(t *testing.T) {
 	})
 
 	order := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
-	cancelled, err := m.CancelOrder(order.OrderID)
+	cancelled, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1005,17 +1085,17 @@ func TestCancelOrder_PartiallyFilledBid(t *testing.T) {
 	})
 
 	// Seller places ask for 5 shares at $150.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5))
 
 	// Buyer places bid for 10 shares at $150 — fills 5, rests 5.
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	if order.Status != domain.OrderStatusPartiallyFilled {
 		t.Fatalf("expected partially_filled, got %s", order.Status)
 	}
 
-	cancelled, err := m.CancelOrder(order.OrderID)
+	cancelled, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1045,17 +1125,17 @@ func TestCancelOrder_PartiallyFilledAsk(t *testing.T) {
 	})
 
 	// Buyer places bid for 5 shares at $150.
-	m.MatchLimitOrder(newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5))
 
 	// Seller places ask for 10 shares at $150 — fills 5, rests 5.
 	order := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	if order.Status != domain.OrderStatusPartiallyFilled {
 		t.Fatalf("expected partially_filled, got %s", order.Status)
 	}
 
-	cancelled, err := m.CancelOrder(order.OrderID)
+	cancelled, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1076,7 +1156,7 @@ func TestCancelOrder_PartiallyFilledAsk(t *testing.T) {
 func TestCancelOrder_OrderNotFound(t *testing.T) {
 	m, _, _, _ := newTestMatcher()
 
-	_, err := m.CancelOrder("nonexistent-order-id")
+	_, err := m.CancelOrder(context.Background(), "nonexistent-order-id")
 	if err != domain.ErrOrderNotFound {
 		t.Errorf("expected ErrOrderNotFound, got %v", err)
 	}
@@ -1090,15 +1170,15 @@ func TestCancelOrder_FilledOrderNotCancellable(t *testing.T) {
 	})
 
 	// Create a fully filled order.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10))
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	if order.Status != domain.OrderStatusFilled {
 		t.Fatalf("expected filled, got %s", order.Status)
 	}
 
-	_, err := m.CancelOrder(order.OrderID)
+	_, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != domain.ErrOrderNotCancellable {
 		t.Errorf("expected ErrOrderNotCancellable, got %v", err)
 	}
@@ -1109,16 +1189,16 @@ func TestCancelOrder_AlreadyCancelledNotCancellable(t *testing.T) {
 	registerBroker(bs, "buyer", 1000000, nil)
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	// Cancel once.
-	_, err := m.CancelOrder(order.OrderID)
+	_, err := m.CancelOrder(context.Background(), order.OrderID)
 	if err != nil {
 		t.Fatalf("first cancel failed: %v", err)
 	}
 
 	// Cancel again — should fail.
-	_, err = m.CancelOrder(order.OrderID)
+	_, err = m.CancelOrder(context.Background(), order.OrderID)
 	if err != domain.ErrOrderNotCancellable {
 		t.Errorf("expected ErrOrderNotCancellable on second cancel, got %v", err)
 	}
@@ -1129,7 +1209,7 @@ func TestCancelOrder_ReleasesReservedCashForBid(t *testing.T) {
 	broker := registerBroker(bs, "buyer", 1000000, nil) // $10,000
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5) // $150 × 5 = $750
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	// Verify reservation was made.
 	broker.Mu.Lock()
@@ -1138,7 +1218,7 @@ func TestCancelOrder_ReleasesReservedCashForBid(t *testing.T) {
 	}
 	broker.Mu.Unlock()
 
-	m.CancelOrder(order.OrderID)
+	m.CancelOrder(context.Background(), order.OrderID)
 
 	// Verify reservation was released.
 	broker.Mu.Lock()
@@ -1155,7 +1235,7 @@ func TestCancelOrder_ReleasesReservedQuantityForAsk(t *testing.T) {
 	})
 
 	order := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	// Verify reservation was made.
 	broker.Mu.Lock()
@@ -1164,7 +1244,7 @@ func TestCancelOrder_ReleasesReservedQuantityForAsk(t *testing.T) {
 	}
 	broker.Mu.Unlock()
 
-	m.CancelOrder(order.OrderID)
+	m.CancelOrder(context.Background(), order.OrderID)
 
 	// Verify reservation was released.
 	broker.Mu.Lock()
@@ -1179,14 +1259,14 @@ func TestCancelOrder_RemovesFromBook(t *testing.T) {
 	registerBroker(bs, "buyer", 1000000, nil)
 
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	book := m.books.GetOrCreate("AAPL")
 	if book.BidCount() != 1 {
 		t.Fatalf("expected 1 bid on book, got %d", book.BidCount())
 	}
 
-	m.CancelOrder(order.OrderID)
+	m.CancelOrder(context.Background(), order.OrderID)
 
 	if book.BidCount() != 0 {
 		t.Errorf("expected 0 bids on book after cancel, got %d", book.BidCount())
@@ -1201,11 +1281,11 @@ func TestCancelOrder_QuantityConservation(t *testing.T) {
 	})
 
 	// Partial fill: 3 out of 10.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 3))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 3))
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
-	m.CancelOrder(order.OrderID)
+	m.CancelOrder(context.Background(), order.OrderID)
 
 	// quantity == filled_quantity + remaining_quantity + cancelled_quantity
 	total := order.FilledQuantity + order.RemainingQuantity + order.CancelledQuantity
@@ -1223,11 +1303,11 @@ func TestCancelOrder_PartialFillReleasesOnlyUnfilledReservation(t *testing.T) {
 	})
 
 	// Seller offers 3 at $150.
-	m.MatchLimitOrder(newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 3))
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 3))
 
 	// Buyer bids 10 at $150 — fills 3, rests 7.
 	order := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)
-	m.MatchLimitOrder(order)
+	m.MatchLimitOrder(context.Background(), order)
 
 	// Reserved cash should be for the remaining 7 shares: 15000 × 7 = 105000.
 	broker.Mu.Lock()
@@ -1237,7 +1317,7 @@ func TestCancelOrder_PartialFillReleasesOnlyUnfilledReservation(t *testing.T) {
 		t.Fatalf("expected reserved_cash 105000 before cancel, got %d", reservedBefore)
 	}
 
-	m.CancelOrder(order.OrderID)
+	m.CancelOrder(context.Background(), order.OrderID)
 
 	// After cancel, reserved cash should be 0 (released 15000 × 7 = 105000).
 	broker.Mu.Lock()
@@ -1261,8 +1341,8 @@ func TestSimulateMarketOrder_BidQuote_WalksAsks(t *testing.T) {
 	// Place asks at different prices.
 	ask1 := newLimitOrder("seller1", domain.OrderSideAsk, "AAPL", 10000, 5)
 	ask2 := newLimitOrder("seller2", domain.OrderSideAsk, "AAPL", 10500, 10)
-	m.MatchLimitOrder(ask1)
-	m.MatchLimitOrder(ask2)
+	m.MatchLimitOrder(context.Background(), ask1)
+	m.MatchLimitOrder(context.Background(), ask2)
 
 	result := m.SimulateMarketOrder("AAPL", domain.OrderSideBid, 8)
 
@@ -1299,8 +1379,8 @@ func TestSimulateMarketOrder_AskQuote_WalksBids(t *testing.T) {
 
 	bid1 := newLimitOrder("buyer1", domain.OrderSideBid, "GOOG", 20000, 10)
 	bid2 := newLimitOrder("buyer2", domain.OrderSideBid, "GOOG", 19500, 5)
-	m.MatchLimitOrder(bid1)
-	m.MatchLimitOrder(bid2)
+	m.MatchLimitOrder(context.Background(), bid1)
+	m.MatchLimitOrder(context.Background(), bid2)
 
 	result := m.SimulateMarketOrder("GOOG", domain.OrderSideAsk, 12)
 
@@ -1355,7 +1435,7 @@ func TestSimulateMarketOrder_PartialLiquidity(t *testing.T) {
 	})
 
 	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5)
-	m.MatchLimitOrder(ask)
+	m.MatchLimitOrder(context.Background(), ask)
 
 	result := m.SimulateMarketOrder("AAPL", domain.OrderSideBid, 20)
 
@@ -1382,7 +1462,7 @@ func TestSimulateMarketOrder_DoesNotModifyBook(t *testing.T) {
 	})
 
 	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 10)
-	m.MatchLimitOrder(ask)
+	m.MatchLimitOrder(context.Background(), ask)
 
 	book := m.books.GetOrCreate("AAPL")
 
@@ -1415,8 +1495,8 @@ func TestSimulateMarketOrder_SinglePriceLevel_MultipleOrders(t *testing.T) {
 	// Two asks at the same price.
 	ask1 := newLimitOrder("seller1", domain.OrderSideAsk, "AAPL", 10000, 5)
 	ask2 := newLimitOrder("seller2", domain.OrderSideAsk, "AAPL", 10000, 7)
-	m.MatchLimitOrder(ask1)
-	m.MatchLimitOrder(ask2)
+	m.MatchLimitOrder(context.Background(), ask1)
+	m.MatchLimitOrder(context.Background(), ask2)
 
 	result := m.SimulateMarketOrder("AAPL", domain.OrderSideBid, 10)
 
@@ -1443,7 +1523,7 @@ func TestSimulateMarketOrder_ExactQuantityMatch(t *testing.T) {
 	})
 
 	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
-	m.MatchLimitOrder(ask)
+	m.MatchLimitOrder(context.Background(), ask)
 
 	result := m.SimulateMarketOrder("AAPL", domain.OrderSideBid, 10)
 
@@ -1458,3 +1538,262 @@ func TestSimulateMarketOrder_ExactQuantityMatch(t *testing.T) {
 		t.Errorf("expected estimated_total %d, got %v", expectedTotal, result.EstimatedTotal)
 	}
 }
+
+func TestMatchLimitOrder_TradeRecordsCarrySideAndAggressor(t *testing.T) {
+	m, bs, _, ts := newTestMatcher()
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{
+		"AAPL": {Quantity: 10},
+	})
+	registerBroker(bs, "buyer", 1000000, nil)
+
+	askOrder := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 5)
+	if _, err := m.MatchLimitOrder(context.Background(), askOrder); err != nil {
+		t.Fatalf("ask order error: %v", err)
+	}
+	bidOrder := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 5)
+	if _, err := m.MatchLimitOrder(context.Background(), bidOrder); err != nil {
+		t.Fatalf("bid order error: %v", err)
+	}
+
+	records := ts.GetBySymbol("AAPL")
+	if len(records) != 2 {
+		t.Fatalf("expected 2 trade records, got %d", len(records))
+	}
+	for _, r := range records {
+		switch r.OrderID {
+		case bidOrder.OrderID:
+			if r.Side != domain.OrderSideBid || !r.Aggressor {
+				t.Errorf("incoming record: got side=%s aggressor=%v, want bid/true", r.Side, r.Aggressor)
+			}
+		case askOrder.OrderID:
+			if r.Side != domain.OrderSideAsk || r.Aggressor {
+				t.Errorf("resting record: got side=%s aggressor=%v, want ask/false", r.Side, r.Aggressor)
+			}
+		default:
+			t.Errorf("unexpected record for order %s", r.OrderID)
+		}
+	}
+}
+
+// historyTypes returns the types of the events in an order's history.
+func historyTypes(o *domain.Order) []domain.OrderEventType {
+	var types []domain.OrderEventType
+	for _, e := range o.History.Events() {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestOrderHistory_FillsAndCancel(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "buyer", 5000000, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{
+		"AAPL": {Quantity: 100},
+	})
+
+	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
+	m.MatchLimitOrder(context.Background(), ask)
+	bid := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15100, 4)
+	trades, _ := m.MatchLimitOrder(context.Background(), bid)
+	if _, err := m.CancelOrder(context.Background(), ask.OrderID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if _, err := m.CancelOrder(context.Background(), ask.OrderID); err != domain.ErrOrderNotCancellable {
+		t.Fatalf("expected ErrOrderNotCancellable, got %v", err)
+	}
+
+	want := []domain.OrderEventType{
+		domain.OrderEventAccepted,
+		domain.OrderEventPartiallyFilled,
+		domain.OrderEventCancelRequested,
+		domain.OrderEventCancelled,
+		domain.OrderEventCancelRequested,
+	}
+	if got := historyTypes(ask); !slices.Equal(got, want) {
+		t.Fatalf("ask history: expected %v, got %v", want, got)
+	}
+	events := ask.History.Events()
+	if e := events[0]; e.Quantity != 10 || e.RemainingQuantity != 10 || !e.Time.Equal(ask.CreatedAt) {
+		t.Errorf("unexpected accepted event: %+v", e)
+	}
+	if e := events[1]; e.TradeID != trades[0].TradeID || e.Price != 15000 || e.Quantity != 4 ||
+		e.FilledQuantity != 4 || e.RemainingQuantity != 6 {
+		t.Errorf("unexpected fill event: %+v", e)
+	}
+	if e := events[3]; e.Quantity != 6 || e.FilledQuantity != 4 || e.RemainingQuantity != 0 {
+		t.Errorf("unexpected cancelled event: %+v", e)
+	}
+
+	want = []domain.OrderEventType{domain.OrderEventAccepted, domain.OrderEventFilled}
+	if got := historyTypes(bid); !slices.Equal(got, want) {
+		t.Errorf("bid history: expected %v, got %v", want, got)
+	}
+}
+
+func TestOrderHistory_MarketOrderRemainderCancelled(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "buyer", 5000000, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{
+		"AAPL": {Quantity: 100},
+	})
+
+	m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 10000, 5))
+	order := newMarketOrder("buyer", domain.OrderSideBid, "AAPL", 10)
+	if _, err := m.MatchMarketOrder(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []domain.OrderEventType{
+		domain.OrderEventAccepted,
+		domain.OrderEventPartiallyFilled,
+		domain.OrderEventCancelled,
+	}
+	if got := historyTypes(order); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if e := order.History.Events()[2]; e.Quantity != 5 || e.FilledQuantity != 5 {
+		t.Errorf("unexpected cancelled event: %+v", e)
+	}
+}
+
+// lockCheckingAuditor records each audit call, noting whether the symbol's
+// book was locked at the time.
+type lockCheckingAuditor struct {
+	books  *BookManager
+	calls  []string
+	locked bool // every call so far was made with the book locked
+}
+
+func (a *lockCheckingAuditor) note(call string, order *domain.Order) {
+	book := a.books.GetOrCreate(order.Symbol)
+	if book.mu.TryLock() {
+		book.mu.Unlock()
+		a.locked = false
+	}
+	a.calls = append(a.calls, call)
+}
+
+func (a *lockCheckingAuditor) RecordOrderAccepted(order *domain.Order) {
+	a.note("accepted "+order.BrokerID, order)
+}
+
+func (a *lockCheckingAuditor) RecordOrderMatched(order *domain.Order, trade *domain.Trade, filledBefore int64, counterpartyOrderID string) {
+	a.note(fmt.Sprintf("matched %s %d+%d", order.BrokerID, filledBefore, trade.Quantity), order)
+}
+
+func (a *lockCheckingAuditor) RecordOrderUnfilled(order *domain.Order, at time.Time) {
+	a.note(fmt.Sprintf("unfilled %s %d", order.BrokerID, order.CancelledQuantity), order)
+}
+
+func (a *lockCheckingAuditor) RecordOrderCancelled(ctx context.Context, order *domain.Order) {
+	a.note(fmt.Sprintf("cancelled %s %d", order.BrokerID, order.CancelledQuantity), order)
+}
+
+func TestMatcher_AuditsUnderBookLock(t *testing.T) {
+	books := NewBookManager(nil)
+	bs := store.NewBrokerStore()
+	auditor := &lockCheckingAuditor{books: books, locked: true}
+	m := NewMatcher(books, bs, store.NewOrderStore(), store.NewTradeStore(), domain.NewSymbolRegistry(), nil, auditor, nil, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 10}})
+	registerBroker(bs, "buyer", 1000000, nil)
+	ctx := context.Background()
+
+	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
+	if _, err := m.MatchLimitOrder(ctx, ask); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchLimitOrder(ctx, newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchMarketOrder(ctx, &domain.Order{Type: domain.OrderTypeMarket, BrokerID: "buyer", Side: domain.OrderSideBid, Symbol: "AAPL", Quantity: 3}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(ctx, ask.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registerBroker(bs, "seller2", 0, map[string]*domain.Holding{"AAPL": {Quantity: 1}})
+	if _, err := m.MatchLimitOrder(ctx, newLimitOrder("seller2", domain.OrderSideAsk, "AAPL", 15000, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchMarketOrder(ctx, &domain.Order{Type: domain.OrderTypeMarket, BrokerID: "buyer", Side: domain.OrderSideBid, Symbol: "AAPL", Quantity: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"accepted seller",
+		"accepted buyer", "matched buyer 0+4", "matched seller 0+4",
+		"accepted buyer", "matched buyer 0+3", "matched seller 4+3",
+		"cancelled seller 3",
+		"accepted seller2",
+		"accepted buyer", "matched buyer 0+1", "matched seller2 0+1", "unfilled buyer 4",
+	}
+	if !slices.Equal(auditor.calls, want) {
+		t.Errorf("got audit calls\n%q\nwant\n%q", auditor.calls, want)
+	}
+	if !auditor.locked {
+		t.Error("expected every audit call to be made with the book locked")
+	}
+}
+
+// lockCheckingDispatcher records each dispatch call, noting whether the
+// symbol's book was locked at the time.
+type lockCheckingDispatcher struct {
+	lockCheckingAuditor
+}
+
+func (d *lockCheckingDispatcher) DispatchOrderMatched(ctx context.Context, order *domain.Order, fills []Fill) {
+	call := fmt.Sprintf("matched %s %s", order.BrokerID, order.Status)
+	for _, f := range fills {
+		call += fmt.Sprintf(" %s+%d", f.Resting.BrokerID, f.Trade.Quantity)
+	}
+	d.note(call, order)
+}
+
+func (d *lockCheckingDispatcher) DispatchOrderCancelled(ctx context.Context, order *domain.Order) {
+	d.note(fmt.Sprintf("cancelled %s %d", order.BrokerID, order.CancelledQuantity), order)
+}
+
+func TestMatcher_DispatchesUnderBookLock(t *testing.T) {
+	books := NewBookManager(nil)
+	bs := store.NewBrokerStore()
+	dispatcher := &lockCheckingDispatcher{lockCheckingAuditor{books: books, locked: true}}
+	m := NewMatcher(books, bs, store.NewOrderStore(), store.NewTradeStore(), domain.NewSymbolRegistry(), dispatcher, nil, nil, nil)
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 10}})
+	registerBroker(bs, "buyer", 1000000, nil)
+	ctx := context.Background()
+
+	ask := newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)
+	if _, err := m.MatchLimitOrder(ctx, ask); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchLimitOrder(ctx, newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.MatchMarketOrder(ctx, &domain.Order{Type: domain.OrderTypeMarket, BrokerID: "buyer", Side: domain.OrderSideBid, Symbol: "AAPL", Quantity: 9}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(ctx, ask.OrderID); err == nil {
+		t.Fatal("expected cancelling a filled order to fail")
+	}
+	bid := newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 14000, 2)
+	if _, err := m.MatchLimitOrder(ctx, bid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(ctx, bid.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"matched seller pending",
+		"matched buyer filled seller+4",
+		"matched buyer cancelled seller+6",
+		"matched buyer pending",
+		"cancelled buyer 2",
+	}
+	if !slices.Equal(dispatcher.calls, want) {
+		t.Errorf("got dispatch calls\n%q\nwant\n%q", dispatcher.calls, want)
+	}
+	if !dispatcher.locked {
+		t.Error("expected every dispatch call to be made with the book locked")
+	}
+}
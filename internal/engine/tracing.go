@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// tracer returns the engine's tracer from the global provider, looked up on
+// each use so spans follow whichever provider is installed.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/efreitasn/miniexchange/internal/engine")
+}
+
+// orderAttributes describes an incoming order on a span. Its ID is not
+// known until the matcher accepts it.
+func orderAttributes(order *domain.Order) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("order.broker_id", order.BrokerID),
+		attribute.String("order.symbol", order.Symbol),
+		attribute.String("order.side", string(order.Side)),
+		attribute.String("order.type", string(order.Type)),
+	)
+}
+
+// recordMatch records the outcome of a matching pass on its span.
+func recordMatch(span trace.Span, order *domain.Order, trades []*domain.Trade, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(
+		attribute.String("order.id", order.OrderID),
+		attribute.String("order.status", string(order.Status)),
+		attribute.Int("match.trades", len(trades)),
+	)
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// recordSpans installs a tracer provider that records every span for the
+// rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// spansByName indexes ended spans by name. Each name is expected once.
+func spansByName(t *testing.T, rec *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		if _, dup := spans[s.Name()]; dup {
+			t.Fatalf("span %s recorded more than once", s.Name())
+		}
+		spans[s.Name()] = s
+	}
+	return spans
+}
+
+func TestMatchLimitOrder_Traced(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	m.books.AddListener(&recordingListener{})
+	registerBroker(bs, "seller", 0, map[string]*domain.Holding{"AAPL": {Quantity: 10}})
+	registerBroker(bs, "buyer", 1000000, nil)
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("seller", domain.OrderSideAsk, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := recordSpans(t)
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	if _, err := m.MatchLimitOrder(ctx, newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root.End()
+
+	spans := spansByName(t, rec)
+	match := spans["engine.match_limit_order"]
+	for child, parent := range map[string]string{
+		"engine.match_limit_order": "root",
+		"engine.lock_wait":         "engine.match_limit_order",
+		"engine.lock_held":         "engine.match_limit_order",
+		"engine.publish":           "engine.match_limit_order",
+		"engine.settle":            "engine.lock_held",
+	} {
+		s, ok := spans[child]
+		if !ok {
+			t.Fatalf("missing span %s; got %v", child, rec.Ended())
+		}
+		if s.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Errorf("%s: expected parent %s", child, parent)
+		}
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range match.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["order.status"] != "filled" || attrs["match.trades"] != "1" || attrs["order.id"] == "" {
+		t.Errorf("unexpected match attributes: %v", attrs)
+	}
+
+	// The lock is released before listeners run.
+	if held, pub := spans["engine.lock_held"], spans["engine.publish"]; pub.StartTime().Before(held.EndTime()) {
+		t.Error("publish span started before the lock was released")
+	}
+}
+
+func TestMatchLimitOrder_TracedRejection(t *testing.T) {
+	m, bs, _, _ := newTestMatcher()
+	registerBroker(bs, "buyer", 100, nil)
+
+	rec := recordSpans(t)
+	if _, err := m.MatchLimitOrder(context.Background(), newLimitOrder("buyer", domain.OrderSideBid, "AAPL", 15000, 10)); err != domain.ErrInsufficientBalance {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+
+	match := spansByName(t, rec)["engine.match_limit_order"]
+	if match == nil {
+		t.Fatal("missing engine.match_limit_order span")
+	}
+	if match.Status().Code != codes.Error || match.Status().Description != domain.ErrInsufficientBalance.Error() {
+		t.Errorf("unexpected status: %+v", match.Status())
+	}
+}
+
+// ctxDispatcher records the span context each expiry is dispatched under.
+type ctxDispatcher struct {
+	spans []trace.SpanContext
+}
+
+func (d *ctxDispatcher) DispatchOrderExpired(ctx context.Context, _ *domain.Order) {
+	d.spans = append(d.spans, trace.SpanContextFromContext(ctx))
+}
+
+func TestExpiryManager_Tick_Traced(t *testing.T) {
+	d := &ctxDispatcher{}
+	em, _, _ := newTestExpiryManager(time.Second, d)
+	now := time.Now()
+	em.Add(newTestLimitOrder("o1", "b1", "AAPL", domain.OrderSideBid, 100, 10, now.Add(-time.Second)))
+
+	rec := recordSpans(t)
+	em.tick(now)
+
+	expire := spansByName(t, rec)["engine.expire_order"]
+	if expire == nil {
+		t.Fatal("missing engine.expire_order span")
+	}
+	if expire.Parent().IsValid() {
+		t.Error("expected each expiration to start its own trace")
+	}
+	if len(d.spans) != 1 || d.spans[0].SpanID() != expire.SpanContext().SpanID() {
+		t.Errorf("expected order.expired dispatched under the expiry span, got %v", d.spans)
+	}
+}
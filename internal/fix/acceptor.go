@@ -0,0 +1,238 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+// logonTimeout bounds how long a new connection may take to send Logon.
+const logonTimeout = 10 * time.Second
+
+// Acceptor accepts FIX 4.4 initiator connections for order entry and market
+// data. A session's SenderCompID is the ID of a registered broker: orders
+// entered on the session belong to that broker. The Logon's Password is an
+// API key that may act for the broker, as on the REST API. At most one
+// connection per broker may be logged on at a time.
+type Acceptor struct {
+	compID     string
+	store      *FileStore
+	orderSvc   *service.OrderService
+	brokerSvc  *service.BrokerService
+	apiKeySvc  *service.APIKeyService
+	marketData *service.MarketDataService
+	events     *service.EventStreamService
+	logger     *slog.Logger
+
+	mu     sync.Mutex
+	ln     net.Listener
+	closed bool
+	states map[string]*sessionState // SenderCompID → state, kept across reconnects
+	conns  map[net.Conn]*session    // nil until the connection has logged on
+	wg     sync.WaitGroup
+}
+
+// NewAcceptor creates an Acceptor that identifies itself as compID and
+// persists session state in store. Execution reports are driven by the
+// broker events published to events, and market data requests are served
+// from marketData.
+func NewAcceptor(
+	compID string,
+	store *FileStore,
+	orderSvc *service.OrderService,
+	brokerSvc *service.BrokerService,
+	apiKeySvc *service.APIKeyService,
+	marketData *service.MarketDataService,
+	events *service.EventStreamService,
+	logger *slog.Logger,
+) *Acceptor {
+	return &Acceptor{
+		compID:     compID,
+		store:      store,
+		orderSvc:   orderSvc,
+		brokerSvc:  brokerSvc,
+		apiKeySvc:  apiKeySvc,
+		marketData: marketData,
+		events:     events,
+		logger:     logger,
+		states:     make(map[string]*sessionState),
+		conns:      make(map[net.Conn]*session),
+	}
+}
+
+// Serve accepts connections on ln until Close is called. It always returns
+// a non-nil error, except after Close.
+func (a *Acceptor) Serve(ln net.Listener) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	a.ln = ln
+	a.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			a.mu.Lock()
+			closed := a.closed
+			a.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		a.mu.Lock()
+		if a.closed {
+			a.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		a.conns[conn] = nil
+		a.wg.Add(1)
+		a.mu.Unlock()
+
+		go func() {
+			defer a.wg.Done()
+			a.handleConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections, logs out every active session, and
+// waits for their connections to close.
+func (a *Acceptor) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	if a.ln != nil {
+		a.ln.Close()
+	}
+	for conn, s := range a.conns {
+		if s != nil {
+			s.stop()
+		} else {
+			conn.Close()
+		}
+	}
+	a.mu.Unlock()
+
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for _, st := range a.states {
+		errs = append(errs, st.store.close())
+	}
+	return errors.Join(errs...)
+}
+
+// handleConn waits for a valid Logon and then runs the session.
+func (a *Acceptor) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		a.mu.Lock()
+		delete(a.conns, conn)
+		a.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(logonTimeout))
+	raw, err := ReadMessage(r)
+	if err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	logon, err := ParseMessage(raw)
+	if err != nil || logon.MsgType() != msgTypeLogon {
+		a.logger.Warn("fix connection dropped: first message is not a valid logon",
+			slog.String("remote_addr", conn.RemoteAddr().String()))
+		return
+	}
+
+	senderCompID, _ := logon.Get(tagSenderCompID)
+	targetCompID, _ := logon.Get(tagTargetCompID)
+	if targetCompID != a.compID || !a.brokerSvc.Exists(senderCompID) {
+		a.logger.Warn("fix logon rejected: unknown comp id",
+			slog.String("sender_comp_id", senderCompID),
+			slog.String("target_comp_id", targetCompID))
+		a.rejectLogon(conn, senderCompID, "Unknown SenderCompID or TargetCompID")
+		return
+	}
+	password, _ := logon.Get(tagPassword)
+	if p, err := a.apiKeySvc.Authenticate(password); err != nil || !p.CanActFor(senderCompID) {
+		a.logger.Warn("fix logon rejected: invalid api key", slog.String("sender_comp_id", senderCompID))
+		a.rejectLogon(conn, senderCompID, "Password must be an API key that may act for the SenderCompID")
+		return
+	}
+
+	st, err := a.acquire(senderCompID)
+	if err != nil {
+		a.logger.Error("fix logon rejected", slog.String("sender_comp_id", senderCompID), slog.String("error", err.Error()))
+		a.rejectLogon(conn, senderCompID, err.Error())
+		return
+	}
+	defer a.release(st)
+
+	s := newSession(a, st, conn, r)
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.conns[conn] = s
+	a.mu.Unlock()
+
+	s.run(logon)
+}
+
+// rejectLogon sends an unsequenced Logout to a connection that never got a
+// session, so the counterparty can tell why it was dropped.
+func (a *Acceptor) rejectLogon(conn net.Conn, senderCompID, text string) {
+	m := NewMessage(msgTypeLogout).
+		Set(tagSenderCompID, a.compID).
+		Set(tagTargetCompID, senderCompID).
+		Set(tagMsgSeqNum, "1").
+		SetTime(tagSendingTime, time.Now()).
+		Set(tagText, text)
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	conn.Write(m.Bytes())
+}
+
+var errSessionActive = errors.New("session is already logged on")
+
+// acquire returns the broker's session state, loading it on first use, and
+// marks it active.
+func (a *Acceptor) acquire(senderCompID string) (*sessionState, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st, ok := a.states[senderCompID]
+	if !ok {
+		ss, err := a.store.open(senderCompID + "-" + a.compID)
+		if err != nil {
+			return nil, err
+		}
+		st = newSessionState(senderCompID, ss)
+		a.states[senderCompID] = st
+	}
+	if st.active {
+		return nil, errSessionActive
+	}
+	st.active = true
+	return st, nil
+}
+
+func (a *Acceptor) release(st *sessionState) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	st.active = false
+}
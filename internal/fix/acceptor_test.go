@@ -0,0 +1,620 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+const (
+	testCompID   = "EX"
+	testAdminKey = "test-admin-key-0123456789"
+)
+
+type testEnv struct {
+	acceptor  *Acceptor
+	addr      string
+	orderSvc  *service.OrderService
+	events    *service.EventStreamService
+	expiryMgr *engine.ExpiryManager
+	apiKeySvc *service.APIKeyService
+}
+
+// newTestEnv starts an acceptor on a loopback port with its session state
+// in dir, and registers broker1 (cash) and broker2 (AAPL shares).
+func newTestEnv(t *testing.T, dir string) *testEnv {
+	t.Helper()
+	bs := store.NewBrokerStore()
+	os := store.NewOrderStore()
+	ts := store.NewTradeStore()
+	ws := store.NewWebhookStore()
+	sr := domain.NewSymbolRegistry()
+	bm := engine.NewBookManager(nil)
+	eventStreamSvc := service.NewEventStreamService(bs, 64, nil)
+	webhookSvc := service.NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, service.DeliveryPolicy{}, eventStreamSvc, nil, nil)
+	t.Cleanup(webhookSvc.Close)
+	m := engine.NewMatcher(bm, bs, os, ts, sr, webhookSvc, nil, nil, nil)
+	e := engine.NewExpiryManager(20*time.Millisecond, bm, os, bs, webhookSvc, nil, nil, nil)
+	brokerSvc := service.NewBrokerService(bs, sr, nil)
+	orderSvc := service.NewOrderService(m, e, bs, os, ts, webhookSvc, sr, nil, nil, nil, nil)
+	marketDataSvc := service.NewMarketDataService(sr, 64)
+	bm.AddListener(marketDataSvc)
+	apiKeySvc := service.NewAPIKeyService(store.NewAPIKeyStore(), bs, testAdminKey)
+
+	for _, req := range []service.RegisterBrokerRequest{
+		{BrokerID: "broker1", InitialCash: 100_000},
+		{BrokerID: "broker2", InitialHoldings: []service.HoldingInput{{Symbol: "AAPL", Quantity: 1000}}},
+	} {
+		if _, err := brokerSvc.Register(req); err != nil {
+			t.Fatalf("register %s: %v", req.BrokerID, err)
+		}
+	}
+
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := NewAcceptor(testCompID, fs, orderSvc, brokerSvc, apiKeySvc, marketDataSvc, eventStreamSvc, logger)
+	go a.Serve(ln)
+	t.Cleanup(func() { a.Close() })
+
+	return &testEnv{
+		acceptor:  a,
+		addr:      ln.Addr().String(),
+		orderSvc:  orderSvc,
+		events:    eventStreamSvc,
+		expiryMgr: e,
+		apiKeySvc: apiKeySvc,
+	}
+}
+
+// testClient is a minimal FIX initiator.
+type testClient struct {
+	t       *testing.T
+	conn    net.Conn
+	r       *bufio.Reader
+	sender  string
+	nextSeq int
+}
+
+func (env *testEnv) dial(t *testing.T, sender string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", env.addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn), sender: sender, nextSeq: 1}
+}
+
+// logon dials and completes a logon, failing the test if it is refused.
+func (env *testEnv) logon(t *testing.T, sender string) *testClient {
+	t.Helper()
+	c := env.dial(t, sender)
+	c.send(logonMessage())
+	c.expect(msgTypeLogon)
+	return c
+}
+
+// logonMessage returns a Logon with the admin key, which may act for every
+// broker.
+func logonMessage() *Message {
+	return NewMessage(msgTypeLogon).Set(tagEncryptMethod, "0").Set(tagHeartBtInt, "30").Set(tagPassword, testAdminKey)
+}
+
+// send sends m with the next sequence number.
+func (c *testClient) send(m *Message) {
+	c.t.Helper()
+	c.sendSeq(m, c.nextSeq)
+	c.nextSeq++
+}
+
+// sendSeq sends m with an explicit sequence number.
+func (c *testClient) sendSeq(m *Message, seq int) {
+	c.t.Helper()
+	out := NewMessage(m.MsgType()).
+		Set(tagSenderCompID, c.sender).
+		Set(tagTargetCompID, testCompID).
+		Set(tagMsgSeqNum, strconv.Itoa(seq)).
+		SetTime(tagSendingTime, time.Now())
+	out.fields = append(out.fields, m.fields[1:]...)
+	if _, err := c.conn.Write(out.Bytes()); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+func (c *testClient) read() *Message {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	raw, err := ReadMessage(c.r)
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	m, err := ParseMessage(raw)
+	if err != nil {
+		c.t.Fatalf("parse: %v", err)
+	}
+	return m
+}
+
+// expect reads the next message and checks its type.
+func (c *testClient) expect(msgType string) *Message {
+	c.t.Helper()
+	m := c.read()
+	if m.MsgType() != msgType {
+		c.t.Fatalf("got MsgType %q, want %q: %s", m.MsgType(), msgType, m)
+	}
+	return m
+}
+
+// expectExec reads an execution report and checks ExecType and OrdStatus.
+func (c *testClient) expectExec(execType, ordStatus string) *Message {
+	c.t.Helper()
+	m := c.expect(msgTypeExecutionReport)
+	gotExec, _ := m.Get(tagExecType)
+	gotStatus, _ := m.Get(tagOrdStatus)
+	if gotExec != execType || gotStatus != ordStatus {
+		c.t.Fatalf("got ExecType=%q OrdStatus=%q, want %q/%q: %s", gotExec, gotStatus, execType, ordStatus, m)
+	}
+	return m
+}
+
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := ReadMessage(c.r); err == nil {
+		c.t.Fatal("expected the connection to be closed")
+	}
+}
+
+func assertField(t *testing.T, m *Message, tag int, want string) {
+	t.Helper()
+	if got, _ := m.Get(tag); got != want {
+		t.Errorf("tag %d = %q, want %q", tag, got, want)
+	}
+}
+
+func limitOrder(clOrdID, side, price string, qty int) *Message {
+	return NewMessage(msgTypeNewOrderSingle).
+		Set(tagClOrdID, clOrdID).
+		Set(tagAccount, "12345678901").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, side).
+		SetInt(tagOrderQty, int64(qty)).
+		Set(tagOrdType, ordTypeLimit).
+		Set(tagPrice, price)
+}
+
+func replaceRequest(origClOrdID, clOrdID, price string, qty int) *Message {
+	return NewMessage(msgTypeOrderCancelReplace).
+		Set(tagOrigClOrdID, origClOrdID).
+		Set(tagClOrdID, clOrdID).
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy).
+		SetTime(tagTransactTime, time.Now()).
+		SetInt(tagOrderQty, int64(qty)).
+		Set(tagOrdType, ordTypeLimit).
+		Set(tagPrice, price)
+}
+
+// restAsk rests a broker2 ask through the order service.
+func (env *testEnv) restAsk(t *testing.T, price float64, qty int64) *domain.Order {
+	t.Helper()
+	expiresAt := time.Now().Add(time.Hour)
+	order, err := env.orderSvc.SubmitOrder(context.Background(), service.SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "broker2",
+		DocumentNumber: "98765432100",
+		Side:           domain.OrderSideAsk,
+		Symbol:         "AAPL",
+		Price:          &price,
+		Quantity:       qty,
+		ExpiresAt:      &expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("rest ask: %v", err)
+	}
+	return order
+}
+
+func TestAcceptor_Logon(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.dial(t, "broker1")
+	c.send(logonMessage())
+
+	resp := c.expect(msgTypeLogon)
+	assertField(t, resp, tagSenderCompID, testCompID)
+	assertField(t, resp, tagTargetCompID, "broker1")
+	assertField(t, resp, tagMsgSeqNum, "1")
+	assertField(t, resp, tagHeartBtInt, "30")
+}
+
+func TestAcceptor_Logon_UnknownBroker(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.dial(t, "nobody")
+	c.send(logonMessage())
+
+	c.expect(msgTypeLogout)
+	c.expectClosed()
+}
+
+func TestAcceptor_Logon_BrokerKey(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	_, secret, err := env.apiKeySvc.Create("broker1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := env.dial(t, "broker1")
+	c.send(logonMessage().Set(tagPassword, secret))
+	c.expect(msgTypeLogon)
+}
+
+func TestAcceptor_Logon_InvalidPassword(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	_, otherSecret, err := env.apiKeySvc.Create("broker2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, logon := range map[string]*Message{
+		"missing":        NewMessage(msgTypeLogon).Set(tagEncryptMethod, "0").Set(tagHeartBtInt, "30"),
+		"unknown key":    logonMessage().Set(tagPassword, "mx_not-a-key"),
+		"another broker": logonMessage().Set(tagPassword, otherSecret),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := env.dial(t, "broker1")
+			c.send(logon)
+			c.expect(msgTypeLogout)
+			c.expectClosed()
+		})
+	}
+}
+
+func TestAcceptor_Logon_AlreadyLoggedOn(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	env.logon(t, "broker1")
+
+	second := env.dial(t, "broker1")
+	second.send(logonMessage())
+	second.expect(msgTypeLogout)
+	second.expectClosed()
+}
+
+func TestAcceptor_TestRequest(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "ping-1"))
+	hb := c.expect(msgTypeHeartbeat)
+	assertField(t, hb, tagTestReqID, "ping-1")
+	assertField(t, hb, tagMsgSeqNum, "2")
+}
+
+func TestAcceptor_Logout(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage(msgTypeLogout))
+	c.expect(msgTypeLogout)
+	c.expectClosed()
+
+	// The broker can log on again once the session is released.
+	c = env.dial(t, "broker1")
+	c.sendSeq(logonMessage(), 3)
+	c.expect(msgTypeLogon)
+}
+
+func TestAcceptor_NewOrderSingle_AckAndFill(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(limitOrder("c1", sideBuy, "10.00", 10))
+	ack := c.expectExec(execTypeNew, ordStatusNew)
+	assertField(t, ack, tagClOrdID, "c1")
+	assertField(t, ack, tagLeavesQty, "10")
+	assertField(t, ack, tagCumQty, "0")
+	orderID, _ := ack.Get(tagOrderID)
+
+	// A counter-order from another broker fills part of it.
+	env.restAsk(t, 10, 4)
+	fill := c.expectExec(execTypeTrade, ordStatusPartial)
+	assertField(t, fill, tagOrderID, orderID)
+	assertField(t, fill, tagLastPx, "10.00")
+	assertField(t, fill, tagLastQty, "4")
+	assertField(t, fill, tagCumQty, "4")
+	assertField(t, fill, tagLeavesQty, "6")
+	assertField(t, fill, tagAvgPx, "10.00")
+
+	env.restAsk(t, 10, 6)
+	fill = c.expectExec(execTypeTrade, ordStatusFilled)
+	assertField(t, fill, tagCumQty, "10")
+	assertField(t, fill, tagLeavesQty, "0")
+}
+
+func TestAcceptor_NewOrderSingle_MarketRemainderCancelled(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	env.restAsk(t, 10, 5)
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage(msgTypeNewOrderSingle).
+		Set(tagClOrdID, "m1").
+		Set(tagAccount, "12345678901").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy).
+		Set(tagOrderQty, "8").
+		Set(tagOrdType, ordTypeMarket))
+
+	c.expectExec(execTypeNew, ordStatusNew)
+	c.expectExec(execTypeTrade, ordStatusPartial)
+	cancelled := c.expectExec(execTypeCanceled, ordStatusCanceled)
+	assertField(t, cancelled, tagCumQty, "5")
+	assertField(t, cancelled, tagLeavesQty, "0")
+}
+
+func TestAcceptor_NewOrderSingle_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    *Message
+		reason string
+	}{
+		{"bad side", limitOrder("r2", "7", "10.00", 1), ordRejOther},
+		{"bad price", limitOrder("r3", sideBuy, "10.001", 1), ordRejOther},
+		{"gtd without expire time", limitOrder("r4", sideBuy, "10.00", 1).Set(tagTimeInForce, timeInForceGTD), ordRejOther},
+		{"insufficient cash", limitOrder("r5", sideBuy, "10.00", 1_000_000), ordRejOther},
+	}
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.t = t
+			c.send(tc.msg)
+			rej := c.expectExec(execTypeRejected, ordStatusRejected)
+			assertField(t, rej, tagOrderID, "NONE")
+			assertField(t, rej, tagOrdRejReason, tc.reason)
+		})
+	}
+}
+
+func TestAcceptor_NewOrderSingle_DuplicateClOrdID(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(limitOrder("dup", sideBuy, "10.00", 1))
+	c.expectExec(execTypeNew, ordStatusNew)
+	c.send(limitOrder("dup", sideBuy, "10.00", 1))
+	rej := c.expectExec(execTypeRejected, ordStatusRejected)
+	assertField(t, rej, tagOrdRejReason, ordRejDuplicateOrder)
+}
+
+func TestAcceptor_NewOrderSingle_MissingTag(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage(msgTypeNewOrderSingle).Set(tagClOrdID, "x"))
+	rej := c.expect(msgTypeReject)
+	assertField(t, rej, tagRefTagID, strconv.Itoa(tagAccount))
+	assertField(t, rej, tagSessionRejectReason, sessionRejectRequiredTagMissing)
+}
+
+func TestAcceptor_UnsupportedMsgType(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage("AE"))
+	rej := c.expect(msgTypeBusinessMessageReject)
+	assertField(t, rej, tagRefMsgType, "AE")
+}
+
+func TestAcceptor_OrderCancelRequest(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(limitOrder("c1", sideBuy, "10.00", 10))
+	c.expectExec(execTypeNew, ordStatusNew)
+
+	c.send(NewMessage(msgTypeOrderCancelRequest).
+		Set(tagOrigClOrdID, "c1").
+		Set(tagClOrdID, "c2").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy))
+	cancelled := c.expectExec(execTypeCanceled, ordStatusCanceled)
+	assertField(t, cancelled, tagClOrdID, "c2")
+	assertField(t, cancelled, tagOrigClOrdID, "c1")
+	assertField(t, cancelled, tagLeavesQty, "0")
+
+	// The order is no longer open.
+	c.send(NewMessage(msgTypeOrderCancelRequest).Set(tagOrigClOrdID, "c2").Set(tagClOrdID, "c3"))
+	rej := c.expect(msgTypeOrderCancelReject)
+	assertField(t, rej, tagCxlRejReason, cxlRejTooLate)
+	assertField(t, rej, tagCxlRejResponseTo, cxlRejResponseToCancel)
+}
+
+func TestAcceptor_OrderCancelRequest_UnknownOrder(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(NewMessage(msgTypeOrderCancelRequest).Set(tagOrigClOrdID, "nope").Set(tagClOrdID, "c1"))
+	rej := c.expect(msgTypeOrderCancelReject)
+	assertField(t, rej, tagOrderID, "NONE")
+	assertField(t, rej, tagCxlRejReason, cxlRejUnknownOrder)
+}
+
+func TestAcceptor_OrderCancelReplace(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(limitOrder("c1", sideBuy, "10.00", 10))
+	ack := c.expectExec(execTypeNew, ordStatusNew)
+	origID, _ := ack.Get(tagOrderID)
+	env.restAsk(t, 10, 3)
+	c.expectExec(execTypeTrade, ordStatusPartial)
+
+	c.send(replaceRequest("c1", "c2", "9.50", 12))
+	replaced := c.expectExec(execTypeReplaced, ordStatusPartial)
+	assertField(t, replaced, tagClOrdID, "c2")
+	assertField(t, replaced, tagOrigClOrdID, "c1")
+	assertField(t, replaced, tagPrice, "9.50")
+	assertField(t, replaced, tagOrderQty, "12")
+	assertField(t, replaced, tagCumQty, "3")
+	assertField(t, replaced, tagLeavesQty, "9")
+	if newID, _ := replaced.Get(tagOrderID); newID == origID {
+		t.Error("expected the replacement to be a new exchange order")
+	}
+
+	// Fills of the replacement accumulate on top of the original's.
+	env.restAsk(t, 9.5, 9)
+	fill := c.expectExec(execTypeTrade, ordStatusFilled)
+	assertField(t, fill, tagCumQty, "12")
+	assertField(t, fill, tagAvgPx, "9.62")
+}
+
+func TestAcceptor_OrderCancelReplace_MarketOrderRejected(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(limitOrder("c1", sideBuy, "10.00", 10))
+	c.expectExec(execTypeNew, ordStatusNew)
+	c.send(replaceRequest("c1", "c2", "10.00", 5).Set(tagOrdType, ordTypeMarket))
+	rej := c.expect(msgTypeOrderCancelReject)
+	assertField(t, rej, tagCxlRejResponseTo, cxlRejResponseToReplace)
+	assertField(t, rej, tagOrdStatus, ordStatusNew)
+}
+
+func TestAcceptor_Expiry(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	expireAt := time.Now().UTC().Add(50 * time.Millisecond)
+	c.send(limitOrder("c1", sideBuy, "10.00", 10).
+		Set(tagTimeInForce, timeInForceGTD).
+		SetTime(tagExpireTime, expireAt))
+	c.expectExec(execTypeNew, ordStatusNew)
+
+	// Subscribing synchronises with the acceptance event's publication, so
+	// the expiry goroutine is ordered after the order service is done with
+	// the order.
+	sub, _, err := env.events.Subscribe("broker1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env.events.Unsubscribe("broker1", sub)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.expiryMgr.Start(ctx)
+
+	expired := c.expectExec(execTypeExpired, ordStatusExpired)
+	assertField(t, expired, tagLeavesQty, "0")
+}
+
+func TestAcceptor_ResendRequest(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1") // our seq 1 → Logon (admin)
+
+	c.send(limitOrder("c1", sideBuy, "10.00", 1)) // → ER seq 2
+	ack := c.expectExec(execTypeNew, ordStatusNew)
+	c.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "t")) // → Heartbeat seq 3
+
+	c.expect(msgTypeHeartbeat)
+
+	c.send(NewMessage(msgTypeResendRequest).Set(tagBeginSeqNo, "1").Set(tagEndSeqNo, "0"))
+
+	// The Logon is admin and is gap-filled; the execution report is resent.
+	gap := c.expect(msgTypeSequenceReset)
+	assertField(t, gap, tagMsgSeqNum, "1")
+	assertField(t, gap, tagGapFillFlag, "Y")
+	assertField(t, gap, tagNewSeqNo, "2")
+
+	resent := c.expect(msgTypeExecutionReport)
+	assertField(t, resent, tagMsgSeqNum, "2")
+	assertField(t, resent, tagPossDupFlag, "Y")
+	execID, _ := ack.Get(tagExecID)
+	assertField(t, resent, tagExecID, execID)
+	origTime, _ := ack.Get(tagSendingTime)
+	assertField(t, resent, tagOrigSendingTime, origTime)
+
+	gap = c.expect(msgTypeSequenceReset)
+	assertField(t, gap, tagMsgSeqNum, "3")
+	assertField(t, gap, tagNewSeqNo, "4")
+}
+
+func TestAcceptor_InboundGap(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	// Skip sequence number 2.
+	c.sendSeq(NewMessage(msgTypeHeartbeat), 3)
+	req := c.expect(msgTypeResendRequest)
+	assertField(t, req, tagBeginSeqNo, "2")
+	assertField(t, req, tagEndSeqNo, "0")
+
+	// Gap-fill it; the session resumes at 4.
+	c.sendSeq(NewMessage(msgTypeSequenceReset).
+		Set(tagPossDupFlag, "Y").
+		Set(tagGapFillFlag, "Y").
+		Set(tagNewSeqNo, "4"), 2)
+	c.sendSeq(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "after-gap"), 4)
+	hb := c.expect(msgTypeHeartbeat)
+	assertField(t, hb, tagTestReqID, "after-gap")
+}
+
+func TestAcceptor_SeqNumTooLow(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.sendSeq(NewMessage(msgTypeHeartbeat), 1)
+	c.expect(msgTypeLogout)
+	c.expectClosed()
+}
+
+func TestAcceptor_PersistsSequenceNumbers(t *testing.T) {
+	dir := t.TempDir()
+	env := newTestEnv(t, dir)
+	c := env.logon(t, "broker1")
+	c.send(limitOrder("c1", sideBuy, "10.00", 1))
+	c.expectExec(execTypeNew, ordStatusNew)
+	env.acceptor.Close()
+	c.expect(msgTypeLogout)
+
+	// A restarted acceptor continues both sequences and can still resend.
+	env = newTestEnv(t, dir)
+	c2 := env.dial(t, "broker1")
+	c2.nextSeq = c.nextSeq
+	c2.send(logonMessage())
+	logon := c2.expect(msgTypeLogon)
+	assertField(t, logon, tagMsgSeqNum, "4")
+
+	c2.send(NewMessage(msgTypeResendRequest).Set(tagBeginSeqNo, "2").Set(tagEndSeqNo, "2"))
+	resent := c2.expect(msgTypeExecutionReport)
+	assertField(t, resent, tagClOrdID, "c1")
+	assertField(t, resent, tagPossDupFlag, "Y")
+}
+
+func TestAcceptor_ResetSeqNumFlag(t *testing.T) {
+	dir := t.TempDir()
+	env := newTestEnv(t, dir)
+	c := env.logon(t, "broker1")
+	c.send(NewMessage(msgTypeLogout))
+	c.expect(msgTypeLogout)
+	c.expectClosed()
+
+	c = env.dial(t, "broker1")
+	c.send(logonMessage().Set(tagResetSeqNumFlag, "Y"))
+	logon := c.expect(msgTypeLogon)
+	assertField(t, logon, tagMsgSeqNum, "1")
+	assertField(t, logon, tagResetSeqNumFlag, "Y")
+}
@@ -0,0 +1,501 @@
+package fix
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+// recentTradesLimit matches the trades snapshot of the market data service.
+const recentTradesLimit = 50
+
+// mdFeed is the session's mirror of one symbol's market data, built from
+// the market data service's snapshots and updates. Every request for the
+// symbol is served from it, so the service is subscribed to each channel
+// at most once per session.
+type mdFeed struct {
+	depth, trades         bool // channels subscribed
+	haveDepth, haveTrades bool // snapshot received
+	bids, asks            map[int64]service.BookPriceLevel
+	recentTrades          []service.MarketTrade
+}
+
+// mdRequest is an active MarketDataRequest (V).
+type mdRequest struct {
+	id           string
+	depth        int // levels per side; 0 for the full book
+	bids, offers bool
+	trades       bool
+	snapshotOnly bool
+	views        map[string]*mdView // symbol → what the client has been sent
+}
+
+func (r *mdRequest) wantsBook() bool {
+	return r.bids || r.offers
+}
+
+// mdView is the book a client was last sent for one symbol of a request,
+// so incremental refreshes carry only what changed within its depth.
+type mdView struct {
+	sent bool
+	bids []service.BookPriceLevel
+	asks []service.BookPriceLevel
+}
+
+// mdMessages returns the market data subscriber's message channel, or nil
+// (blocking forever in a select) if nothing is subscribed.
+func (s *session) mdMessages() <-chan *service.MarketDataMessage {
+	if s.mdSub == nil {
+		return nil
+	}
+	return s.mdSub.Messages()
+}
+
+// mdDone returns the market data subscriber's done channel, or nil.
+func (s *session) mdDone() <-chan struct{} {
+	if s.mdSub == nil {
+		return nil
+	}
+	return s.mdSub.Done()
+}
+
+// closeMarketData ends every market data subscription of the session.
+func (s *session) closeMarketData() {
+	if s.mdSub != nil {
+		s.a.marketData.Close(s.mdSub)
+		s.mdSub = nil
+	}
+}
+
+// onMarketDataRequest handles a MarketDataRequest (V): a one-off snapshot,
+// a subscription to a snapshot followed by incremental refreshes, or an
+// unsubscribe.
+func (s *session) onMarketDataRequest(m *Message, seq int) error {
+	for _, tag := range []int{tagMDReqID, tagSubscriptionReqType} {
+		if _, ok := m.Get(tag); !ok {
+			s.sendReject(seq, msgTypeMarketDataRequest, tag, sessionRejectRequiredTagMissing, "Required tag missing")
+			return nil
+		}
+	}
+	id, _ := m.Get(tagMDReqID)
+	reqType, _ := m.Get(tagSubscriptionReqType)
+
+	switch reqType {
+	case subscriptionUnsubscribe:
+		if _, ok := s.mdRequests[id]; !ok {
+			return s.rejectMarketData(id, "", "Unknown MDReqID")
+		}
+		delete(s.mdRequests, id)
+		s.releaseMarketData()
+		return nil
+	case subscriptionSnapshot, subscriptionSubscribe:
+	default:
+		return s.rejectMarketData(id, mdReqRejUnsupportedSubReqType, "Unsupported SubscriptionRequestType")
+	}
+
+	if _, ok := s.mdRequests[id]; ok {
+		return s.rejectMarketData(id, mdReqRejDuplicateMDReqID, "Duplicate MDReqID")
+	}
+	req := &mdRequest{
+		id:           id,
+		snapshotOnly: reqType == subscriptionSnapshot,
+		views:        make(map[string]*mdView),
+	}
+
+	depth, ok := m.GetInt(tagMarketDepth)
+	if !ok || depth < 0 {
+		return s.rejectMarketData(id, mdReqRejUnsupportedMarketDepth, "MarketDepth (264) must be a non-negative integer")
+	}
+	req.depth = depth
+	if v, ok := m.Get(tagMDUpdateType); ok && v != mdUpdateTypeIncremental && !req.snapshotOnly {
+		return s.rejectMarketData(id, mdReqRejUnsupportedUpdateType, "Only incremental refresh (MDUpdateType 1) is supported")
+	}
+
+	entryTypes := m.GetAll(tagMDEntryType)
+	if len(entryTypes) == 0 {
+		s.sendReject(seq, msgTypeMarketDataRequest, tagMDEntryType, sessionRejectRequiredTagMissing, "Required tag missing")
+		return nil
+	}
+	for _, t := range entryTypes {
+		switch t {
+		case mdEntryBid:
+			req.bids = true
+		case mdEntryOffer:
+			req.offers = true
+		case mdEntryTrade:
+			req.trades = true
+		default:
+			return s.rejectMarketData(id, mdReqRejUnsupportedEntryType, "Unsupported MDEntryType "+t)
+		}
+	}
+
+	symbols := m.GetAll(tagSymbol)
+	if len(symbols) == 0 {
+		s.sendReject(seq, msgTypeMarketDataRequest, tagSymbol, sessionRejectRequiredTagMissing, "Required tag missing")
+		return nil
+	}
+	for _, symbol := range symbols {
+		req.views[symbol] = &mdView{}
+	}
+
+	if s.mdSub == nil {
+		s.mdSub = s.a.marketData.NewSubscriber()
+	}
+	for symbol := range req.views {
+		if err := s.subscribeFeed(symbol, req.wantsBook(), req.trades); err != nil {
+			s.releaseMarketData()
+			if errors.Is(err, domain.ErrSymbolNotFound) {
+				return s.rejectMarketData(id, mdReqRejUnknownSymbol, "Unknown symbol "+symbol)
+			}
+			return s.rejectMarketData(id, "", err.Error())
+		}
+	}
+
+	s.mdRequests[id] = req
+	for symbol := range req.views {
+		if err := s.sendSnapshotIfReady(req, symbol); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeFeed makes sure the session receives the channels for symbol.
+// Snapshots of newly subscribed channels arrive through mdMessages.
+func (s *session) subscribeFeed(symbol string, depth, trades bool) error {
+	feed, ok := s.mdFeeds[symbol]
+	if !ok {
+		feed = &mdFeed{
+			bids: make(map[int64]service.BookPriceLevel),
+			asks: make(map[int64]service.BookPriceLevel),
+		}
+		s.mdFeeds[symbol] = feed
+	}
+	if depth && !feed.depth {
+		if err := s.a.marketData.Subscribe(s.mdSub, symbol, service.ChannelDepth); err != nil {
+			return err
+		}
+		feed.depth = true
+	}
+	if trades && !feed.trades {
+		if err := s.a.marketData.Subscribe(s.mdSub, symbol, service.ChannelTrades); err != nil {
+			return err
+		}
+		feed.trades = true
+	}
+	return nil
+}
+
+// releaseMarketData unsubscribes from every channel no request needs any
+// more and forgets its mirrored state.
+func (s *session) releaseMarketData() {
+	type need struct{ depth, trades bool }
+	needed := make(map[string]need)
+	for _, req := range s.mdRequests {
+		for symbol := range req.views {
+			n := needed[symbol]
+			n.depth = n.depth || req.wantsBook()
+			n.trades = n.trades || req.trades
+			needed[symbol] = n
+		}
+	}
+
+	for symbol, feed := range s.mdFeeds {
+		n := needed[symbol]
+		if feed.depth && !n.depth {
+			s.a.marketData.Unsubscribe(s.mdSub, symbol, service.ChannelDepth)
+			feed.depth, feed.haveDepth = false, false
+			feed.bids = make(map[int64]service.BookPriceLevel)
+			feed.asks = make(map[int64]service.BookPriceLevel)
+		}
+		if feed.trades && !n.trades {
+			s.a.marketData.Unsubscribe(s.mdSub, symbol, service.ChannelTrades)
+			feed.trades, feed.haveTrades = false, false
+			feed.recentTrades = nil
+		}
+		if !feed.depth && !feed.trades {
+			delete(s.mdFeeds, symbol)
+		}
+	}
+}
+
+// resubscribeMarketData replaces a subscriber the market data service
+// dropped as a slow consumer. Fresh snapshots bring the mirrors up to date
+// and clients receive the net book changes as incremental refreshes; trades
+// published while the subscriber was behind are not reported.
+func (s *session) resubscribeMarketData() error {
+	s.log.Warn("fix market data subscriber dropped, resubscribing")
+	s.a.marketData.Close(s.mdSub)
+	s.mdSub = s.a.marketData.NewSubscriber()
+	for symbol, feed := range s.mdFeeds {
+		depth, trades := feed.depth, feed.trades
+		feed.depth, feed.trades = false, false
+		feed.haveDepth, feed.haveTrades = false, false
+		if err := s.subscribeFeed(symbol, depth, trades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onMarketData applies a message from the market data service to the
+// symbol's mirror and brings every request for the symbol up to date.
+func (s *session) onMarketData(msg *service.MarketDataMessage) error {
+	feed, ok := s.mdFeeds[msg.Symbol]
+	if !ok {
+		return nil
+	}
+
+	var newTrades []service.MarketTrade
+	switch msg.Channel {
+	case service.ChannelDepth:
+		if !feed.depth {
+			return nil
+		}
+		if msg.Type == service.MessageTypeSnapshot {
+			feed.bids = make(map[int64]service.BookPriceLevel)
+			feed.asks = make(map[int64]service.BookPriceLevel)
+			feed.haveDepth = true
+		}
+		applyLevels(feed.bids, msg.Bids)
+		applyLevels(feed.asks, msg.Asks)
+	case service.ChannelTrades:
+		if !feed.trades {
+			return nil
+		}
+		if msg.Type == service.MessageTypeSnapshot {
+			feed.recentTrades = append([]service.MarketTrade(nil), msg.Trades...)
+			feed.haveTrades = true
+		} else {
+			newTrades = msg.Trades
+			feed.recentTrades = append(feed.recentTrades, msg.Trades...)
+			if n := len(feed.recentTrades); n > recentTradesLimit {
+				feed.recentTrades = append([]service.MarketTrade(nil), feed.recentTrades[n-recentTradesLimit:]...)
+			}
+		}
+	default:
+		return nil
+	}
+
+	for _, id := range s.mdRequestIDs() {
+		req := s.mdRequests[id]
+		view, ok := req.views[msg.Symbol]
+		if !ok {
+			continue
+		}
+		if !view.sent {
+			if err := s.sendSnapshotIfReady(req, msg.Symbol); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.sendIncremental(req, msg.Symbol, msg.Channel == service.ChannelDepth, newTrades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mdRequestIDs returns the active request IDs in a stable order.
+func (s *session) mdRequestIDs() []string {
+	ids := make([]string, 0, len(s.mdRequests))
+	for id := range s.mdRequests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sendSnapshotIfReady sends a MarketDataSnapshotFullRefresh (W) for one
+// symbol of a request once the mirror holds every channel it needs. A
+// snapshot-only request ends when all of its symbols have been sent.
+func (s *session) sendSnapshotIfReady(req *mdRequest, symbol string) error {
+	feed := s.mdFeeds[symbol]
+	if (req.wantsBook() && !feed.haveDepth) || (req.trades && !feed.haveTrades) {
+		return nil
+	}
+	view := req.views[symbol]
+
+	entries := &Message{}
+	n := 0
+	if req.bids {
+		view.bids = topLevels(feed.bids, true, req.depth)
+		for i, l := range view.bids {
+			addLevelFields(entries.Add(tagMDEntryType, mdEntryBid), l).Add(tagMDEntryPositionNo, strconv.Itoa(i+1))
+			n++
+		}
+	}
+	if req.offers {
+		view.asks = topLevels(feed.asks, false, req.depth)
+		for i, l := range view.asks {
+			addLevelFields(entries.Add(tagMDEntryType, mdEntryOffer), l).Add(tagMDEntryPositionNo, strconv.Itoa(i+1))
+			n++
+		}
+	}
+	if req.trades {
+		for _, t := range feed.recentTrades {
+			addTradeFields(entries.Add(tagMDEntryType, mdEntryTrade), t)
+			n++
+		}
+	}
+	view.sent = true
+
+	m := NewMessage(msgTypeMarketDataSnapshot).
+		Set(tagMDReqID, req.id).
+		Set(tagSymbol, symbol).
+		SetInt(tagNoMDEntries, int64(n))
+	m.fields = append(m.fields, entries.fields...)
+	if err := s.send(m); err != nil {
+		return err
+	}
+
+	if req.snapshotOnly {
+		for _, v := range req.views {
+			if !v.sent {
+				return nil
+			}
+		}
+		delete(s.mdRequests, req.id)
+		s.releaseMarketData()
+	}
+	return nil
+}
+
+// sendIncremental sends a MarketDataIncrementalRefresh (X) with the book
+// changes within the request's depth and any new trades. Removed levels
+// come first, so a client that caps its book at the requested depth never
+// overflows.
+func (s *session) sendIncremental(req *mdRequest, symbol string, bookChanged bool, trades []service.MarketTrade) error {
+	feed := s.mdFeeds[symbol]
+	view := req.views[symbol]
+
+	var deletes, updates Message
+	n := 0
+	if bookChanged {
+		if req.bids {
+			next := topLevels(feed.bids, true, req.depth)
+			n += diffLevels(&deletes, &updates, symbol, mdEntryBid, view.bids, next)
+			view.bids = next
+		}
+		if req.offers {
+			next := topLevels(feed.asks, false, req.depth)
+			n += diffLevels(&deletes, &updates, symbol, mdEntryOffer, view.asks, next)
+			view.asks = next
+		}
+	}
+	if req.trades {
+		for _, t := range trades {
+			addTradeFields(addEntryHeader(&updates, mdUpdateActionNew, mdEntryTrade, symbol), t)
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	m := NewMessage(msgTypeMarketDataIncremental).
+		Set(tagMDReqID, req.id).
+		SetInt(tagNoMDEntries, int64(n))
+	m.fields = append(m.fields, deletes.fields...)
+	m.fields = append(m.fields, updates.fields...)
+	return s.send(m)
+}
+
+// rejectMarketData sends a MarketDataRequestReject (Y). reason is omitted
+// when empty.
+func (s *session) rejectMarketData(mdReqID, reason, text string) error {
+	m := NewMessage(msgTypeMarketDataReject).Set(tagMDReqID, mdReqID)
+	if reason != "" {
+		m.Set(tagMDReqRejReason, reason)
+	}
+	return s.send(m.Set(tagText, text))
+}
+
+// applyLevels applies level updates to a mirrored side of the book. A
+// TotalQuantity of 0 removes the level.
+func applyLevels(side map[int64]service.BookPriceLevel, levels []service.BookPriceLevel) {
+	for _, l := range levels {
+		if l.TotalQuantity == 0 {
+			delete(side, l.Price)
+		} else {
+			side[l.Price] = l
+		}
+	}
+}
+
+// topLevels returns the best depth levels of a side, best first; all of
+// them if depth is 0.
+func topLevels(side map[int64]service.BookPriceLevel, descending bool, depth int) []service.BookPriceLevel {
+	levels := make([]service.BookPriceLevel, 0, len(side))
+	for _, l := range side {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return levels
+}
+
+// diffLevels adds the entries that turn prev into next: deletes for levels
+// that left the view, and new or change entries for the rest. It returns
+// the number of entries added.
+func diffLevels(deletes, updates *Message, symbol, entryType string, prev, next []service.BookPriceLevel) int {
+	n := 0
+	inNext := make(map[int64]service.BookPriceLevel, len(next))
+	for _, l := range next {
+		inNext[l.Price] = l
+	}
+	inPrev := make(map[int64]service.BookPriceLevel, len(prev))
+	for _, l := range prev {
+		inPrev[l.Price] = l
+		if _, ok := inNext[l.Price]; !ok {
+			addEntryHeader(deletes, mdUpdateActionDelete, entryType, symbol).
+				Add(tagMDEntryPx, formatPrice(l.Price))
+			n++
+		}
+	}
+	for _, l := range next {
+		action := mdUpdateActionNew
+		if old, ok := inPrev[l.Price]; ok {
+			if old == l {
+				continue
+			}
+			action = mdUpdateActionChange
+		}
+		addLevelFields(addEntryHeader(updates, action, entryType, symbol), l)
+		n++
+	}
+	return n
+}
+
+// addEntryHeader starts an incremental refresh entry.
+func addEntryHeader(m *Message, action, entryType, symbol string) *Message {
+	return m.Add(tagMDUpdateAction, action).
+		Add(tagMDEntryType, entryType).
+		Add(tagSymbol, symbol)
+}
+
+// addLevelFields appends the fields of an aggregated price level entry.
+func addLevelFields(m *Message, l service.BookPriceLevel) *Message {
+	return m.Add(tagMDEntryPx, formatPrice(l.Price)).
+		Add(tagMDEntrySize, strconv.FormatInt(l.TotalQuantity, 10)).
+		Add(tagNumberOfOrders, strconv.Itoa(l.OrderCount))
+}
+
+// addTradeFields appends the fields of a trade entry.
+func addTradeFields(m *Message, t service.MarketTrade) *Message {
+	at := t.ExecutedAt.UTC()
+	return m.Add(tagMDEntryPx, formatPrice(t.Price)).
+		Add(tagMDEntrySize, strconv.FormatInt(t.Quantity, 10)).
+		Add(tagMDEntryDate, at.Format("20060102")).
+		Add(tagMDEntryTime, at.Format("15:04:05.000"))
+}
@@ -0,0 +1,221 @@
+package fix
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+// restBid rests a broker1 bid through the order service.
+func (env *testEnv) restBid(t *testing.T, price float64, qty int64) *domain.Order {
+	t.Helper()
+	expiresAt := time.Now().Add(time.Hour)
+	order, err := env.orderSvc.SubmitOrder(context.Background(), service.SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "broker1",
+		DocumentNumber: "12345678901",
+		Side:           domain.OrderSideBid,
+		Symbol:         "AAPL",
+		Price:          &price,
+		Quantity:       qty,
+		ExpiresAt:      &expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("rest bid: %v", err)
+	}
+	return order
+}
+
+func mdRequestMessage(id, reqType string, depth int, entryTypes ...string) *Message {
+	m := NewMessage(msgTypeMarketDataRequest).
+		Set(tagMDReqID, id).
+		Set(tagSubscriptionReqType, reqType).
+		SetInt(tagMarketDepth, int64(depth))
+	if reqType == subscriptionSubscribe {
+		m.Set(tagMDUpdateType, mdUpdateTypeIncremental)
+	}
+	m.SetInt(tagNoMDEntryTypes, int64(len(entryTypes)))
+	for _, t := range entryTypes {
+		m.Add(tagMDEntryType, t)
+	}
+	return m.Set(tagNoRelatedSym, "1").Set(tagSymbol, "AAPL")
+}
+
+func assertAll(t *testing.T, m *Message, tag int, want ...string) {
+	t.Helper()
+	if got := m.GetAll(tag); !slices.Equal(got, want) {
+		t.Errorf("tag %d = %v, want %v", tag, got, want)
+	}
+}
+
+func TestMarketData_SnapshotAndIncrementalBook(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	env.restBid(t, 9.90, 100)
+	env.restBid(t, 9.80, 50)
+	env.restAsk(t, 10.10, 30)
+	c := env.logon(t, "broker1")
+
+	c.send(mdRequestMessage("md1", subscriptionSubscribe, 0, mdEntryBid, mdEntryOffer))
+	snap := c.expect(msgTypeMarketDataSnapshot)
+	assertField(t, snap, tagMDReqID, "md1")
+	assertField(t, snap, tagSymbol, "AAPL")
+	assertField(t, snap, tagNoMDEntries, "3")
+	assertAll(t, snap, tagMDEntryType, mdEntryBid, mdEntryBid, mdEntryOffer)
+	assertAll(t, snap, tagMDEntryPx, "9.90", "9.80", "10.10")
+	assertAll(t, snap, tagMDEntrySize, "100", "50", "30")
+	assertAll(t, snap, tagMDEntryPositionNo, "1", "2", "1")
+
+	// A new level.
+	env.restAsk(t, 10.20, 10)
+	inc := c.expect(msgTypeMarketDataIncremental)
+	assertField(t, inc, tagMDReqID, "md1")
+	assertAll(t, inc, tagMDUpdateAction, mdUpdateActionNew)
+	assertAll(t, inc, tagMDEntryType, mdEntryOffer)
+	assertAll(t, inc, tagSymbol, "AAPL")
+	assertAll(t, inc, tagMDEntryPx, "10.20")
+
+	// A changed level.
+	bid := env.restBid(t, 9.90, 25)
+	inc = c.expect(msgTypeMarketDataIncremental)
+	assertAll(t, inc, tagMDUpdateAction, mdUpdateActionChange)
+	assertAll(t, inc, tagMDEntrySize, "125")
+	assertAll(t, inc, tagNumberOfOrders, "2")
+
+	// A removed level.
+	env.orderSvc.CancelOrder(context.Background(), bid.OrderID)
+	c.expect(msgTypeMarketDataIncremental)
+	ask := env.restAsk(t, 10.30, 5)
+	c.expect(msgTypeMarketDataIncremental)
+	env.orderSvc.CancelOrder(context.Background(), ask.OrderID)
+	inc = c.expect(msgTypeMarketDataIncremental)
+	assertAll(t, inc, tagMDUpdateAction, mdUpdateActionDelete)
+	assertAll(t, inc, tagMDEntryPx, "10.30")
+}
+
+func TestMarketData_TopOfBook(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	env.restBid(t, 9.90, 100)
+	c := env.logon(t, "broker1")
+
+	c.send(mdRequestMessage("tob", subscriptionSubscribe, 1, mdEntryBid))
+	snap := c.expect(msgTypeMarketDataSnapshot)
+	assertAll(t, snap, tagMDEntryPx, "9.90")
+
+	// A worse bid is outside the requested depth.
+	env.restBid(t, 9.50, 10)
+	// A better one replaces the top level: the old one is deleted first.
+	env.restBid(t, 9.95, 10)
+	inc := c.expect(msgTypeMarketDataIncremental)
+	assertAll(t, inc, tagMDUpdateAction, mdUpdateActionDelete, mdUpdateActionNew)
+	assertAll(t, inc, tagMDEntryPx, "9.90", "9.95")
+}
+
+func TestMarketData_Trades(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	env.restAsk(t, 10, 5)
+	env.restBid(t, 10, 5)
+	c.send(mdRequestMessage("tr", subscriptionSubscribe, 0, mdEntryTrade))
+	snap := c.expect(msgTypeMarketDataSnapshot)
+	assertAll(t, snap, tagMDEntryType, mdEntryTrade)
+	assertAll(t, snap, tagMDEntryPx, "10.00")
+	assertAll(t, snap, tagMDEntrySize, "5")
+	if len(snap.GetAll(tagMDEntryDate)) != 1 || len(snap.GetAll(tagMDEntryTime)) != 1 {
+		t.Errorf("expected trade date and time: %s", snap)
+	}
+
+	env.restAsk(t, 10.50, 3)
+	env.restBid(t, 10.50, 2)
+	inc := c.expect(msgTypeMarketDataIncremental)
+	assertAll(t, inc, tagMDUpdateAction, mdUpdateActionNew)
+	assertAll(t, inc, tagMDEntryType, mdEntryTrade)
+	assertAll(t, inc, tagMDEntryPx, "10.50")
+	assertAll(t, inc, tagMDEntrySize, "2")
+}
+
+func TestMarketData_SnapshotOnly(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	env.restBid(t, 9.90, 100)
+	c := env.logon(t, "broker1")
+
+	c.send(mdRequestMessage("snap", subscriptionSnapshot, 0, mdEntryBid))
+	c.expect(msgTypeMarketDataSnapshot)
+
+	// No refreshes follow: the next message is the heartbeat.
+	env.restBid(t, 9.80, 10)
+	c.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "after"))
+	c.expect(msgTypeHeartbeat)
+
+	// The MDReqID is free again.
+	c.send(mdRequestMessage("snap", subscriptionSnapshot, 0, mdEntryBid))
+	snap := c.expect(msgTypeMarketDataSnapshot)
+	assertAll(t, snap, tagMDEntryPx, "9.90", "9.80")
+}
+
+func TestMarketData_Unsubscribe(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(mdRequestMessage("md1", subscriptionSubscribe, 0, mdEntryBid))
+	snap := c.expect(msgTypeMarketDataSnapshot)
+	assertField(t, snap, tagNoMDEntries, "0")
+
+	c.send(NewMessage(msgTypeMarketDataRequest).
+		Set(tagMDReqID, "md1").
+		Set(tagSubscriptionReqType, subscriptionUnsubscribe))
+	c.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "sync"))
+	c.expect(msgTypeHeartbeat)
+
+	env.restBid(t, 9.90, 100)
+	c.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, "after"))
+	c.expect(msgTypeHeartbeat)
+}
+
+func TestMarketData_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    *Message
+		reason string
+	}{
+		{"unknown symbol", mdRequestMessage("r1", subscriptionSubscribe, 0, mdEntryBid).Set(tagSymbol, "ZZZZ"), mdReqRejUnknownSymbol},
+		{"duplicate id", mdRequestMessage("live", subscriptionSubscribe, 0, mdEntryBid), mdReqRejDuplicateMDReqID},
+		{"bad subscription type", mdRequestMessage("r2", "9", 0, mdEntryBid), mdReqRejUnsupportedSubReqType},
+		{"negative depth", mdRequestMessage("r3", subscriptionSubscribe, -1, mdEntryBid), mdReqRejUnsupportedMarketDepth},
+		{"full refresh updates", mdRequestMessage("r4", subscriptionSubscribe, 0, mdEntryBid).Set(tagMDUpdateType, "0"), mdReqRejUnsupportedUpdateType},
+		{"unsupported entry type", mdRequestMessage("r5", subscriptionSubscribe, 0, "4"), mdReqRejUnsupportedEntryType},
+		{"unknown id on unsubscribe", mdRequestMessage("r6", subscriptionUnsubscribe, 0, mdEntryBid), ""},
+	}
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+	c.send(mdRequestMessage("live", subscriptionSubscribe, 0, mdEntryBid))
+	c.expect(msgTypeMarketDataSnapshot)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.t = t
+			c.send(tc.msg)
+			rej := c.expect(msgTypeMarketDataReject)
+			id, _ := tc.msg.Get(tagMDReqID)
+			assertField(t, rej, tagMDReqID, id)
+			assertField(t, rej, tagMDReqRejReason, tc.reason)
+		})
+	}
+}
+
+func TestMarketData_NotResent(t *testing.T) {
+	env := newTestEnv(t, t.TempDir())
+	c := env.logon(t, "broker1")
+
+	c.send(mdRequestMessage("md1", subscriptionSubscribe, 0, mdEntryBid)) // → W seq 2
+	c.expect(msgTypeMarketDataSnapshot)
+
+	c.send(NewMessage(msgTypeResendRequest).Set(tagBeginSeqNo, "2").Set(tagEndSeqNo, "0"))
+	gap := c.expect(msgTypeSequenceReset)
+	assertField(t, gap, tagMsgSeqNum, "2")
+	assertField(t, gap, tagNewSeqNo, "3")
+}
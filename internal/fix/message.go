@@ -0,0 +1,232 @@
+// Package fix implements a FIX 4.4 acceptor for order entry and market
+// data: session management (logon, heartbeats, sequence numbers, resend
+// requests) with state persisted on disk, and translation between FIX
+// application messages and the order and market data services.
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	beginString = "FIX.4.4"
+	soh         = '\x01'
+
+	// maxBodyLength bounds the BodyLength a counterparty may declare, so a
+	// corrupt header cannot make the reader allocate unbounded memory.
+	maxBodyLength = 64 * 1024
+
+	// timestampFormat is the FIX UTCTimestamp format with milliseconds.
+	timestampFormat = "20060102-15:04:05.000"
+)
+
+var (
+	errGarbled     = errors.New("garbled message")
+	errBadChecksum = errors.New("checksum mismatch")
+)
+
+// field is a single tag=value pair.
+type field struct {
+	tag   int
+	value string
+}
+
+// Message is a FIX message as an ordered list of fields, excluding
+// BeginString (8), BodyLength (9), and CheckSum (10), which are computed on
+// encoding. Field order is preserved so repeating groups survive a round
+// trip.
+type Message struct {
+	fields []field
+}
+
+// NewMessage creates a message of the given MsgType.
+func NewMessage(msgType string) *Message {
+	return &Message{fields: []field{{tagMsgType, msgType}}}
+}
+
+// MsgType returns the message's MsgType (35), or "" if absent.
+func (m *Message) MsgType() string {
+	v, _ := m.Get(tagMsgType)
+	return v
+}
+
+// Get returns the value of the first occurrence of tag.
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.fields {
+		if f.tag == tag {
+			return f.value, true
+		}
+	}
+	return "", false
+}
+
+// GetInt returns the value of tag parsed as an integer.
+func (m *Message) GetInt(tag int) (int, bool) {
+	v, ok := m.Get(tag)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// GetAll returns the values of every occurrence of tag, in order.
+func (m *Message) GetAll(tag int) []string {
+	var values []string
+	for _, f := range m.fields {
+		if f.tag == tag {
+			values = append(values, f.value)
+		}
+	}
+	return values
+}
+
+// Set replaces the value of the first occurrence of tag, or appends it.
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.fields {
+		if m.fields[i].tag == tag {
+			m.fields[i].value = value
+			return m
+		}
+	}
+	return m.Add(tag, value)
+}
+
+// Add appends a field, even if tag is already present. Use it for
+// repeating groups.
+func (m *Message) Add(tag int, value string) *Message {
+	m.fields = append(m.fields, field{tag, value})
+	return m
+}
+
+// SetInt is Set with an integer value.
+func (m *Message) SetInt(tag int, value int64) *Message {
+	return m.Set(tag, strconv.FormatInt(value, 10))
+}
+
+// SetTime is Set with a UTCTimestamp value.
+func (m *Message) SetTime(tag int, t time.Time) *Message {
+	return m.Set(tag, t.UTC().Format(timestampFormat))
+}
+
+// Bytes encodes the message with BeginString, BodyLength, and CheckSum.
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, f := range m.fields {
+		body.WriteString(strconv.Itoa(f.tag))
+		body.WriteByte('=')
+		body.WriteString(f.value)
+		body.WriteByte(soh)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "8=%s%c9=%d%c", beginString, soh, body.Len(), soh)
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "10=%03d%c", checksum(out.Bytes()), soh)
+	return out.Bytes()
+}
+
+// String renders the message with '|' in place of SOH, for logging.
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{soh}, []byte{'|'}))
+}
+
+// checksum is the FIX CheckSum: the byte sum modulo 256.
+func checksum(b []byte) int {
+	var sum int
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+// ParseMessage decodes a complete raw message as returned by ReadMessage,
+// verifying BeginString, BodyLength, and CheckSum.
+func ParseMessage(raw []byte) (*Message, error) {
+	if !bytes.HasSuffix(raw, []byte{soh}) {
+		return nil, errGarbled
+	}
+
+	var fields []field
+	for _, part := range bytes.Split(raw[:len(raw)-1], []byte{soh}) {
+		eq := bytes.IndexByte(part, '=')
+		if eq <= 0 {
+			return nil, errGarbled
+		}
+		tag, err := strconv.Atoi(string(part[:eq]))
+		if err != nil || tag <= 0 {
+			return nil, errGarbled
+		}
+		fields = append(fields, field{tag, string(part[eq+1:])})
+	}
+
+	if len(fields) < 4 || fields[0].tag != tagBeginString || fields[1].tag != tagBodyLength ||
+		fields[len(fields)-1].tag != tagCheckSum {
+		return nil, errGarbled
+	}
+	if fields[0].value != beginString {
+		return nil, fmt.Errorf("%w: unsupported BeginString %q", errGarbled, fields[0].value)
+	}
+
+	trailer := bytes.LastIndex(raw[:len(raw)-1], []byte{soh}) + 1
+	want, err := strconv.Atoi(fields[len(fields)-1].value)
+	if err != nil || want != checksum(raw[:trailer]) {
+		return nil, errBadChecksum
+	}
+
+	// Everything after BodyLength's delimiter and before CheckSum.
+	headerLen := len("8=") + len(fields[0].value) + 1 + len("9=") + len(fields[1].value) + 1
+	if bodyLen, err := strconv.Atoi(fields[1].value); err != nil || bodyLen != trailer-headerLen {
+		return nil, fmt.Errorf("%w: BodyLength mismatch", errGarbled)
+	}
+
+	m := &Message{fields: fields[2 : len(fields)-1]}
+	if len(m.fields) == 0 || m.fields[0].tag != tagMsgType {
+		return nil, fmt.Errorf("%w: MsgType must be the first body field", errGarbled)
+	}
+	return m, nil
+}
+
+// ReadMessage reads one raw message from r, using BodyLength to find its
+// end. It does not validate the checksum; ParseMessage does.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	begin, err := r.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(begin, []byte("8=")) {
+		return nil, errGarbled
+	}
+	length, err := r.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(length, []byte("9=")) {
+		return nil, errGarbled
+	}
+	bodyLen, err := strconv.Atoi(string(length[2 : len(length)-1]))
+	if err != nil || bodyLen < 0 || bodyLen > maxBodyLength {
+		return nil, errGarbled
+	}
+
+	// Body plus the 7-byte trailer "10=NNN\x01".
+	rest := make([]byte, bodyLen+7)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 0, len(begin)+len(length)+len(rest))
+	raw = append(raw, begin...)
+	raw = append(raw, length...)
+	raw = append(raw, rest...)
+	return raw, nil
+}
@@ -0,0 +1,123 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMessage_BytesAndParseRoundTrip(t *testing.T) {
+	m := NewMessage(msgTypeNewOrderSingle).
+		Set(tagSenderCompID, "broker1").
+		Set(tagMsgSeqNum, "7").
+		Set(tagClOrdID, "c1").
+		Add(tagSymbol, "AAPL").
+		Add(tagSymbol, "MSFT")
+
+	raw := m.Bytes()
+	if !bytes.HasPrefix(raw, []byte("8=FIX.4.4\x019=")) {
+		t.Fatalf("unexpected header: %q", raw)
+	}
+
+	parsed, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.MsgType() != msgTypeNewOrderSingle {
+		t.Errorf("MsgType = %q, want D", parsed.MsgType())
+	}
+	if seq, ok := parsed.GetInt(tagMsgSeqNum); !ok || seq != 7 {
+		t.Errorf("MsgSeqNum = %d (ok=%v), want 7", seq, ok)
+	}
+	if got := parsed.GetAll(tagSymbol); len(got) != 2 || got[0] != "AAPL" || got[1] != "MSFT" {
+		t.Errorf("repeating symbols = %v, want [AAPL MSFT]", got)
+	}
+	if !bytes.Equal(parsed.Bytes(), raw) {
+		t.Errorf("re-encoded message differs:\n got %q\nwant %q", parsed.Bytes(), raw)
+	}
+}
+
+func TestMessage_KnownChecksum(t *testing.T) {
+	// Heartbeat from the FIX specification's examples, modulo CompIDs.
+	m := NewMessage(msgTypeHeartbeat).
+		Set(tagSenderCompID, "A").
+		Set(tagTargetCompID, "B").
+		Set(tagMsgSeqNum, "1").
+		Set(tagSendingTime, "20260217-14:05:00.000")
+	raw := string(m.Bytes())
+
+	body := "35=0\x0149=A\x0156=B\x0134=1\x0152=20260217-14:05:00.000\x01"
+	prefix := fmt.Sprintf("8=FIX.4.4\x019=%d\x01%s", len(body), body)
+	want := fmt.Sprintf("%s10=%03d\x01", prefix, checksum([]byte(prefix)))
+	if raw != want {
+		t.Errorf("got %q, want %q", raw, want)
+	}
+}
+
+func TestMessage_Set_ReplacesFirstOccurrence(t *testing.T) {
+	m := NewMessage(msgTypeHeartbeat).Set(tagTestReqID, "a").Set(tagTestReqID, "b")
+	if got := m.GetAll(tagTestReqID); len(got) != 1 || got[0] != "b" {
+		t.Errorf("got %v, want [b]", got)
+	}
+}
+
+func TestParseMessage_Errors(t *testing.T) {
+	valid := NewMessage(msgTypeHeartbeat).Set(tagMsgSeqNum, "1").Bytes()
+
+	badChecksum := bytes.Clone(valid)
+	badChecksum[len(badChecksum)-2]++
+
+	// A BodyLength off by one, with a checksum that matches it.
+	body := "35=0\x0134=1\x01"
+	prefix := fmt.Sprintf("8=FIX.4.4\x019=%d\x01%s", len(body)+1, body)
+	badLength := []byte(fmt.Sprintf("%s10=%03d\x01", prefix, checksum([]byte(prefix))))
+
+	tests := []struct {
+		name string
+		raw  []byte
+		want error
+	}{
+		{"no trailing SOH", valid[:len(valid)-1], errGarbled},
+		{"bad checksum", badChecksum, errBadChecksum},
+		{"bad body length", badLength, errGarbled},
+		{"wrong begin string", bytes.Replace(valid, []byte("FIX.4.4"), []byte("FIX.4.2"), 1), errGarbled},
+		{"not tag=value", []byte("8=FIX.4.4\x01garbage\x01"), errGarbled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMessage(tc.raw)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestReadMessage_SplitsStream(t *testing.T) {
+	first := NewMessage(msgTypeHeartbeat).Set(tagMsgSeqNum, "1").Bytes()
+	second := NewMessage(msgTypeTestRequest).Set(tagMsgSeqNum, "2").Set(tagTestReqID, "x").Bytes()
+	r := bufio.NewReader(bytes.NewReader(append(bytes.Clone(first), second...)))
+
+	for i, want := range [][]byte{first, second} {
+		raw, err := ReadMessage(r)
+		if err != nil {
+			t.Fatalf("message %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(raw, want) {
+			t.Fatalf("message %d: got %q, want %q", i, raw, want)
+		}
+	}
+	if _, err := ReadMessage(r); err == nil {
+		t.Fatal("expected EOF after the last message")
+	}
+}
+
+func TestReadMessage_RejectsOversizedBody(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("8=FIX.4.4\x019=99999999\x01"))
+	if _, err := ReadMessage(r); !errors.Is(err, errGarbled) {
+		t.Fatalf("expected errGarbled, got %v", err)
+	}
+}
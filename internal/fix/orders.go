@@ -0,0 +1,505 @@
+package fix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/google/uuid"
+)
+
+// fixOrder is an open order entered over FIX, tracked so execution reports
+// can carry the client's identifiers and FIX cumulative quantities. After a
+// cancel/replace, the replacement is a new exchange order; its FIX OrderQty,
+// CumQty, and AvgPx include what the orders it replaced had filled.
+type fixOrder struct {
+	orderID     string // exchange order ID
+	clOrdID     string // latest ClOrdID
+	origClOrdID string // ClOrdID it replaced, if any
+	account     string
+	symbol      string
+	side        string // FIX Side
+	ordType     string // FIX OrdType
+	price       int64  // cents; 0 for market orders
+
+	orderQty       int64 // FIX OrderQty
+	exchangeQty    int64 // quantity of the current exchange order
+	exchangeFilled int64
+	baseQty        int64 // filled by the orders this one replaced
+	baseNotional   int64
+	cumQty         int64
+	cumNotional    int64 // sum(price × quantity), in cents
+	ordStatus      string
+
+	acceptExecType string // execTypeNew, or execTypeReplaced for a replacement
+	acknowledged   bool   // its New or Replaced report has been sent
+	replaced       bool   // cancelled as part of a replace; its report is the replacement's
+}
+
+func (fo *fixOrder) leavesQty() int64 {
+	return fo.exchangeQty - fo.exchangeFilled
+}
+
+func (fo *fixOrder) avgPx() string {
+	if fo.cumQty == 0 {
+		return "0"
+	}
+	return formatPrice(fo.cumNotional / fo.cumQty)
+}
+
+// brokerEvent is the subset of a broker event payload the acceptor needs.
+type brokerEvent struct {
+	Data struct {
+		OrderID       string  `json:"order_id"`
+		TradePrice    float64 `json:"trade_price"`
+		TradeQuantity int64   `json:"trade_quantity"`
+	} `json:"data"`
+}
+
+// onEvent turns a broker event about an order entered on this session into
+// an execution report. Events for orders entered through other channels
+// are skipped.
+func (s *session) onEvent(ev service.BrokerEvent) error {
+	id := ev.ID
+	s.st.lastEventID = &id
+
+	var payload brokerEvent
+	if err := json.Unmarshal(ev.Data, &payload); err != nil {
+		return fmt.Errorf("decode %s event: %w", ev.Event, err)
+	}
+	fo, ok := s.st.orders[payload.Data.OrderID]
+	if !ok {
+		return nil
+	}
+	if err := s.acknowledge(fo); err != nil {
+		return err
+	}
+
+	switch ev.Event {
+	case "trade.executed":
+		price, err := domain.DollarsToCents(payload.Data.TradePrice)
+		if err != nil {
+			return fmt.Errorf("decode trade price: %w", err)
+		}
+		qty := payload.Data.TradeQuantity
+		fo.exchangeFilled += qty
+		fo.cumQty += qty
+		fo.cumNotional += price * qty
+
+		status := ordStatusPartial
+		if fo.leavesQty() == 0 {
+			status = ordStatusFilled
+			delete(s.st.orders, fo.orderID)
+		}
+		return s.sendExecutionReport(fo, execTypeTrade, status, func(m *Message) {
+			m.Set(tagLastPx, formatPrice(price)).SetInt(tagLastQty, qty)
+		})
+	case "order.cancelled":
+		delete(s.st.orders, fo.orderID)
+		if fo.replaced {
+			return nil
+		}
+		fo.exchangeFilled = fo.exchangeQty
+		return s.sendExecutionReport(fo, execTypeCanceled, ordStatusCanceled, nil)
+	case "order.expired":
+		delete(s.st.orders, fo.orderID)
+		fo.exchangeFilled = fo.exchangeQty
+		return s.sendExecutionReport(fo, execTypeExpired, ordStatusExpired, nil)
+	}
+	return nil
+}
+
+// acknowledge sends an order's New, or Replaced for a replacement, unless
+// it has been sent already. order.accepted only fires for an order that
+// rests, so the acknowledgement goes out ahead of whichever report comes
+// first.
+func (s *session) acknowledge(fo *fixOrder) error {
+	if fo.acknowledged {
+		return nil
+	}
+	fo.acknowledged = true
+	status := ordStatusNew
+	if fo.cumQty > 0 {
+		status = ordStatusPartial
+	}
+	return s.sendExecutionReport(fo, fo.acceptExecType, status, nil)
+}
+
+// onNewOrderSingle submits a NewOrderSingle (D). The acknowledgement and
+// fills are reported from the events the order service publishes.
+func (s *session) onNewOrderSingle(m *Message, seq int) error {
+	for _, tag := range []int{tagClOrdID, tagAccount, tagSymbol, tagSide, tagOrderQty, tagOrdType} {
+		if _, ok := m.Get(tag); !ok {
+			s.sendReject(seq, msgTypeNewOrderSingle, tag, sessionRejectRequiredTagMissing, "Required tag missing")
+			return nil
+		}
+	}
+	clOrdID, _ := m.Get(tagClOrdID)
+	if _, ok := s.st.clOrdIDs[clOrdID]; ok {
+		return s.rejectOrder(m, ordRejDuplicateOrder, "Duplicate ClOrdID")
+	}
+
+	req, err := s.parseOrder(m)
+	if err != nil {
+		return s.rejectOrder(m, ordRejOther, err.Error())
+	}
+	order, err := s.a.orderSvc.SubmitOrder(context.Background(), *req)
+	if err != nil {
+		return s.rejectOrder(m, ordRejOther, err.Error())
+	}
+
+	ordType, _ := m.Get(tagOrdType)
+	side, _ := m.Get(tagSide)
+	fo := &fixOrder{
+		orderID:        order.OrderID,
+		clOrdID:        clOrdID,
+		account:        req.DocumentNumber,
+		symbol:         req.Symbol,
+		side:           side,
+		ordType:        ordType,
+		price:          order.Price,
+		orderQty:       req.Quantity,
+		exchangeQty:    order.Quantity,
+		acceptExecType: execTypeNew,
+	}
+	s.st.orders[order.OrderID] = fo
+	s.st.clOrdIDs[clOrdID] = order.OrderID
+
+	if err := s.drainEvents(); err != nil {
+		return err
+	}
+
+	// A market order's unfilled remainder is cancelled immediately (IOC)
+	// without an event of its own. Market orders never rest, so their
+	// status is final once SubmitOrder returns; one that found nothing to
+	// match has had no event at all.
+	if order.Type == domain.OrderTypeMarket && order.Status == domain.OrderStatusCancelled {
+		if err := s.acknowledge(fo); err != nil {
+			return err
+		}
+		delete(s.st.orders, fo.orderID)
+		fo.exchangeFilled = fo.exchangeQty
+		return s.sendExecutionReport(fo, execTypeCanceled, ordStatusCanceled, nil)
+	}
+	return nil
+}
+
+// parseOrder converts a NewOrderSingle to an order service request.
+func (s *session) parseOrder(m *Message) (*service.SubmitOrderRequest, error) {
+	account, _ := m.Get(tagAccount)
+	symbol, _ := m.Get(tagSymbol)
+	req := &service.SubmitOrderRequest{
+		BrokerID:       s.st.brokerID,
+		DocumentNumber: account,
+		Symbol:         symbol,
+	}
+
+	side, _ := m.Get(tagSide)
+	switch side {
+	case sideBuy:
+		req.Side = domain.OrderSideBid
+	case sideSell:
+		req.Side = domain.OrderSideAsk
+	default:
+		return nil, fmt.Errorf("unsupported Side %q", side)
+	}
+
+	qty, ok := m.GetInt(tagOrderQty)
+	if !ok {
+		return nil, errors.New("quantity must be an integer")
+	}
+	req.Quantity = int64(qty)
+
+	ordType, _ := m.Get(tagOrdType)
+	tif, _ := m.Get(tagTimeInForce)
+	switch ordType {
+	case ordTypeMarket:
+		if tif != "" && tif != timeInForceIOC {
+			return nil, errors.New("market orders support only TimeInForce IOC (3)")
+		}
+		req.Type = domain.OrderTypeMarket
+	case ordTypeLimit:
+		price, err := parsePrice(m)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt, err := parseExpiry(m, tif)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = domain.OrderTypeLimit
+		req.Price = &price
+		req.ExpiresAt = &expiresAt
+	default:
+		return nil, fmt.Errorf("unsupported OrdType %q", ordType)
+	}
+	return req, nil
+}
+
+// onOrderCancelRequest cancels an open order (F). The cancellation is
+// reported from the order.cancelled event.
+func (s *session) onOrderCancelRequest(m *Message, seq int) error {
+	for _, tag := range []int{tagClOrdID, tagOrigClOrdID} {
+		if _, ok := m.Get(tag); !ok {
+			s.sendReject(seq, msgTypeOrderCancelRequest, tag, sessionRejectRequiredTagMissing, "Required tag missing")
+			return nil
+		}
+	}
+	clOrdID, _ := m.Get(tagClOrdID)
+	origClOrdID, _ := m.Get(tagOrigClOrdID)
+
+	fo, reason, text := s.lookupForCancel(clOrdID, origClOrdID)
+	if fo == nil {
+		return s.sendCancelReject(m, nil, cxlRejResponseToCancel, reason, text)
+	}
+	if _, err := s.a.orderSvc.CancelOrder(context.Background(), fo.orderID); err != nil {
+		return s.sendCancelReject(m, fo, cxlRejResponseToCancel, cancelRejectReason(err), err.Error())
+	}
+
+	fo.origClOrdID = fo.clOrdID
+	fo.clOrdID = clOrdID
+	s.st.clOrdIDs[clOrdID] = fo.orderID
+	return s.drainEvents()
+}
+
+// onOrderCancelReplace replaces an open limit order (G) with new price,
+// quantity, and optionally expiry. The exchange cancels the order and
+// enters a new one, reported as a single Replaced execution report.
+func (s *session) onOrderCancelReplace(m *Message, seq int) error {
+	for _, tag := range []int{tagClOrdID, tagOrigClOrdID, tagOrderQty, tagOrdType, tagPrice} {
+		if _, ok := m.Get(tag); !ok {
+			s.sendReject(seq, msgTypeOrderCancelReplace, tag, sessionRejectRequiredTagMissing, "Required tag missing")
+			return nil
+		}
+	}
+	clOrdID, _ := m.Get(tagClOrdID)
+	origClOrdID, _ := m.Get(tagOrigClOrdID)
+
+	fo, reason, text := s.lookupForCancel(clOrdID, origClOrdID)
+	if fo == nil {
+		return s.sendCancelReject(m, nil, cxlRejResponseToReplace, reason, text)
+	}
+	if ordType, _ := m.Get(tagOrdType); ordType != ordTypeLimit || fo.ordType != ordTypeLimit {
+		return s.sendCancelReject(m, fo, cxlRejResponseToReplace, cxlRejOther, "Only limit orders can be replaced")
+	}
+
+	qty, ok := m.GetInt(tagOrderQty)
+	if !ok {
+		return s.sendCancelReject(m, fo, cxlRejResponseToReplace, cxlRejOther, "quantity must be an integer")
+	}
+	price, err := parsePrice(m)
+	if err != nil {
+		return s.sendCancelReject(m, fo, cxlRejResponseToReplace, cxlRejOther, err.Error())
+	}
+	req := service.ReplaceOrderRequest{
+		OrderID: fo.orderID,
+		Price:   price,
+		// OrderQty covers the whole chain of replaced orders; the exchange
+		// order only needs to cover what this one has not filled.
+		Quantity: int64(qty) - fo.baseQty,
+	}
+	if tif, _ := m.Get(tagTimeInForce); tif != "" {
+		expiresAt, err := parseExpiry(m, tif)
+		if err != nil {
+			return s.sendCancelReject(m, fo, cxlRejResponseToReplace, cxlRejOther, err.Error())
+		}
+		req.ExpiresAt = &expiresAt
+	}
+
+	cancelled, replacement, err := s.a.orderSvc.ReplaceOrder(context.Background(), req)
+	if err != nil {
+		// If the original was cancelled before the replacement failed, the
+		// cancellation is still reported.
+		if err := s.sendCancelReject(m, fo, cxlRejResponseToReplace, cancelRejectReason(err), err.Error()); err != nil {
+			return err
+		}
+		return s.drainEvents()
+	}
+
+	s.st.clOrdIDs[clOrdID] = fo.orderID
+	if replacement == nil {
+		// Filled up to the new quantity while being cancelled: report the
+		// cancellation under the replace request's ClOrdID.
+		fo.origClOrdID = fo.clOrdID
+		fo.clOrdID = clOrdID
+		return s.drainEvents()
+	}
+
+	// The original's fills are final once it is cancelled, even if some
+	// are still to be reported from queued events.
+	baseQty, baseNotional := fo.baseQty, fo.baseNotional
+	for _, t := range cancelled.Trades {
+		baseQty += t.Quantity
+		baseNotional += t.Price * t.Quantity
+	}
+
+	fo.replaced = true
+	next := &fixOrder{
+		orderID:        replacement.OrderID,
+		clOrdID:        clOrdID,
+		origClOrdID:    fo.clOrdID,
+		account:        fo.account,
+		symbol:         fo.symbol,
+		side:           fo.side,
+		ordType:        fo.ordType,
+		price:          replacement.Price,
+		orderQty:       int64(qty),
+		exchangeQty:    replacement.Quantity,
+		baseQty:        baseQty,
+		baseNotional:   baseNotional,
+		cumQty:         baseQty,
+		cumNotional:    baseNotional,
+		acceptExecType: execTypeReplaced,
+	}
+	s.st.orders[replacement.OrderID] = next
+	s.st.clOrdIDs[clOrdID] = replacement.OrderID
+	return s.drainEvents()
+}
+
+// lookupForCancel finds the open order a cancel or replace request refers
+// to. It returns nil with a CxlRejReason and text if there is none.
+func (s *session) lookupForCancel(clOrdID, origClOrdID string) (*fixOrder, string, string) {
+	if _, ok := s.st.clOrdIDs[clOrdID]; ok {
+		return nil, cxlRejOther, "Duplicate ClOrdID"
+	}
+	orderID, ok := s.st.clOrdIDs[origClOrdID]
+	if !ok {
+		return nil, cxlRejUnknownOrder, "Unknown OrigClOrdID"
+	}
+	fo, ok := s.st.orders[orderID]
+	if !ok || fo.clOrdID != origClOrdID {
+		return nil, cxlRejTooLate, "Order is no longer open under this OrigClOrdID"
+	}
+	return fo, "", ""
+}
+
+func cancelRejectReason(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotCancellable):
+		return cxlRejTooLate
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return cxlRejUnknownOrder
+	}
+	return cxlRejOther
+}
+
+// sendExecutionReport reports fo's current state. extra adds fields such
+// as LastPx and LastQty.
+func (s *session) sendExecutionReport(fo *fixOrder, execType, ordStatus string, extra func(*Message)) error {
+	fo.ordStatus = ordStatus
+	m := NewMessage(msgTypeExecutionReport).
+		Set(tagOrderID, fo.orderID).
+		Set(tagClOrdID, fo.clOrdID)
+	if fo.origClOrdID != "" {
+		m.Set(tagOrigClOrdID, fo.origClOrdID)
+	}
+	m.Set(tagExecID, uuid.NewString()).
+		Set(tagExecType, execType).
+		Set(tagOrdStatus, ordStatus).
+		Set(tagAccount, fo.account).
+		Set(tagSymbol, fo.symbol).
+		Set(tagSide, fo.side).
+		Set(tagOrdType, fo.ordType)
+	if fo.ordType == ordTypeLimit {
+		m.Set(tagPrice, formatPrice(fo.price))
+	}
+	m.SetInt(tagOrderQty, fo.orderQty)
+	if extra != nil {
+		extra(m)
+	}
+	m.SetInt(tagLeavesQty, fo.leavesQty()).
+		SetInt(tagCumQty, fo.cumQty).
+		Set(tagAvgPx, fo.avgPx()).
+		SetTime(tagTransactTime, time.Now())
+	return s.send(m)
+}
+
+// rejectOrder sends a Rejected execution report for a NewOrderSingle that
+// never became an exchange order.
+func (s *session) rejectOrder(req *Message, reason, text string) error {
+	m := NewMessage(msgTypeExecutionReport).Set(tagOrderID, "NONE")
+	for _, tag := range []int{tagClOrdID, tagAccount, tagSymbol, tagSide, tagOrdType, tagOrderQty} {
+		if v, ok := req.Get(tag); ok {
+			m.Set(tag, v)
+		}
+	}
+	m.Set(tagExecID, uuid.NewString()).
+		Set(tagExecType, execTypeRejected).
+		Set(tagOrdStatus, ordStatusRejected).
+		Set(tagOrdRejReason, reason).
+		Set(tagLeavesQty, "0").
+		Set(tagCumQty, "0").
+		Set(tagAvgPx, "0").
+		SetTime(tagTransactTime, time.Now()).
+		Set(tagText, text)
+	return s.send(m)
+}
+
+// sendCancelReject sends an OrderCancelReject. fo is nil when the request
+// did not identify an open order.
+func (s *session) sendCancelReject(req *Message, fo *fixOrder, responseTo, reason, text string) error {
+	clOrdID, _ := req.Get(tagClOrdID)
+	origClOrdID, _ := req.Get(tagOrigClOrdID)
+	orderID, ordStatus := "NONE", ordStatusRejected
+	if fo != nil {
+		orderID, ordStatus = fo.orderID, fo.ordStatus
+	}
+	return s.send(NewMessage(msgTypeOrderCancelReject).
+		Set(tagOrderID, orderID).
+		Set(tagClOrdID, clOrdID).
+		Set(tagOrigClOrdID, origClOrdID).
+		Set(tagOrdStatus, ordStatus).
+		Set(tagCxlRejResponseTo, responseTo).
+		Set(tagCxlRejReason, reason).
+		Set(tagText, text))
+}
+
+// parsePrice reads Price (44) in dollars.
+func parsePrice(m *Message) (float64, error) {
+	v, ok := m.Get(tagPrice)
+	if !ok {
+		return 0, errors.New("price is required for limit orders")
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.New("price must be a decimal number")
+	}
+	return price, nil
+}
+
+// parseExpiry derives a limit order's expiry from TimeInForce: Day orders
+// (the default) expire at the next midnight UTC, and GTD orders at
+// ExpireTime (126).
+func parseExpiry(m *Message, tif string) (time.Time, error) {
+	switch tif {
+	case "", timeInForceDay:
+		return time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour), nil
+	case timeInForceGTD:
+		v, ok := m.Get(tagExpireTime)
+		if !ok {
+			return time.Time{}, errors.New("ExpireTime (126) is required for TimeInForce GTD")
+		}
+		return parseTimestamp(v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported TimeInForce %q for limit orders", tif)
+	}
+}
+
+// parseTimestamp parses a UTCTimestamp with or without milliseconds.
+func parseTimestamp(v string) (time.Time, error) {
+	for _, layout := range []string{timestampFormat, "20060102-15:04:05"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UTCTimestamp %q", v)
+}
+
+func formatPrice(cents int64) string {
+	return strconv.FormatFloat(domain.CentsToDollars(cents), 'f', 2, 64)
+}
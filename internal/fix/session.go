@@ -0,0 +1,550 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+const (
+	// writeTimeout bounds a single write to the counterparty.
+	writeTimeout = 10 * time.Second
+
+	// timerInterval is how often heartbeat and test request deadlines are
+	// checked.
+	timerInterval = 250 * time.Millisecond
+)
+
+// sessionState is the state of a broker's session that outlives a single
+// connection: the persisted sequence numbers and message log, the orders
+// entered over FIX, and the position in the broker's event stream.
+type sessionState struct {
+	brokerID string
+	store    *sessionStore
+	active   bool // guarded by Acceptor.mu
+
+	// Accessed only by the active session's goroutine.
+	orders      map[string]*fixOrder // exchange order ID → open order
+	clOrdIDs    map[string]string    // every ClOrdID seen → exchange order ID
+	lastEventID *uint64
+}
+
+func newSessionState(brokerID string, store *sessionStore) *sessionState {
+	return &sessionState{
+		brokerID: brokerID,
+		store:    store,
+		orders:   make(map[string]*fixOrder),
+		clOrdIDs: make(map[string]string),
+	}
+}
+
+// inbound is a raw message or read error from the reader goroutine.
+type inbound struct {
+	raw []byte
+	err error
+}
+
+// session runs one logged-on connection. All protocol state is handled on
+// the goroutine that calls run; a separate goroutine only reads from the
+// connection.
+type session struct {
+	a    *Acceptor
+	st   *sessionState
+	conn net.Conn
+	r    *bufio.Reader
+	log  *slog.Logger
+
+	quit     chan struct{}
+	stopOnce sync.Once
+
+	sub        *service.EventSubscription
+	heartBtInt time.Duration
+	lastSent   time.Time
+	lastRecv   time.Time
+	testReqAt  time.Time // zero unless a TestRequest is outstanding
+	resendTo   int       // highest sequence number requested for resend, or 0
+
+	// Market data subscriptions end with the connection.
+	mdSub      *service.Subscriber // nil until the first MarketDataRequest
+	mdFeeds    map[string]*mdFeed
+	mdRequests map[string]*mdRequest
+}
+
+func newSession(a *Acceptor, st *sessionState, conn net.Conn, r *bufio.Reader) *session {
+	return &session{
+		a:    a,
+		st:   st,
+		conn: conn,
+		r:    r,
+		log:  a.logger.With(slog.String("sender_comp_id", st.brokerID)),
+		quit: make(chan struct{}),
+
+		mdFeeds:    make(map[string]*mdFeed),
+		mdRequests: make(map[string]*mdRequest),
+	}
+}
+
+// stop asks the session to log out and disconnect.
+func (s *session) stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+}
+
+// errLogout ends the session after a Logout has been exchanged or sent.
+var errLogout = errors.New("logout")
+
+// run completes the logon handshake and processes messages and broker
+// events until the connection ends.
+func (s *session) run(logon *Message) {
+	if err := s.onLogon(logon); err != nil {
+		s.log.Warn("fix logon failed", slog.String("error", err.Error()))
+		return
+	}
+	s.log.Info("fix session logged on", slog.Duration("heartbeat_interval", s.heartBtInt))
+
+	if err := s.subscribe(); err != nil {
+		s.log.Error("fix event subscription failed", slog.String("error", err.Error()))
+		return
+	}
+	defer func() { s.a.events.Unsubscribe(s.st.brokerID, s.sub) }()
+	defer s.closeMarketData()
+
+	in := make(chan inbound)
+	go func() {
+		for {
+			raw, err := ReadMessage(s.r)
+			select {
+			case in <- inbound{raw, err}:
+			case <-s.quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	// Unblock the reader goroutine when the session ends for any reason.
+	defer s.stop()
+
+	ticker := time.NewTicker(timerInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case msg := <-in:
+			if msg.err != nil {
+				s.log.Info("fix connection closed", slog.String("reason", msg.err.Error()))
+				return
+			}
+			s.lastRecv = time.Now()
+			s.testReqAt = time.Time{}
+			err = s.onMessage(msg.raw)
+		case ev := <-s.sub.Events():
+			err = s.onEvent(ev)
+		case <-s.sub.Done():
+			// Dropped as a slow consumer: resume from the last event handled.
+			err = s.subscribe()
+		case msg := <-s.mdMessages():
+			err = s.onMarketData(msg)
+		case <-s.mdDone():
+			err = s.resubscribeMarketData()
+		case now := <-ticker.C:
+			err = s.onTimer(now)
+		case <-s.quit:
+			s.sendLogout("Acceptor shutting down")
+			return
+		}
+		if err == errLogout {
+			s.log.Info("fix session logged out")
+			return
+		}
+		if err != nil {
+			s.log.Warn("fix session ended", slog.String("error", err.Error()))
+			return
+		}
+	}
+}
+
+// onLogon validates the counterparty's Logon, applies any sequence reset,
+// and answers with a Logon.
+func (s *session) onLogon(logon *Message) error {
+	hb, ok := logon.GetInt(tagHeartBtInt)
+	if !ok || hb < 0 {
+		s.sendLogout("HeartBtInt (108) must be a non-negative integer")
+		return fmt.Errorf("invalid HeartBtInt")
+	}
+	if v, _ := logon.Get(tagEncryptMethod); v != "0" {
+		s.sendLogout("EncryptMethod (98) must be 0")
+		return fmt.Errorf("unsupported EncryptMethod %q", v)
+	}
+	seq, ok := logon.GetInt(tagMsgSeqNum)
+	if !ok {
+		s.sendLogout("MsgSeqNum (34) missing")
+		return fmt.Errorf("missing MsgSeqNum")
+	}
+
+	reset, _ := logon.Get(tagResetSeqNumFlag)
+	if reset == "Y" {
+		if err := s.st.store.reset(); err != nil {
+			return err
+		}
+	}
+
+	_, expected := s.st.store.seqNums()
+	if seq < expected {
+		s.sendLogout(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seq))
+		return fmt.Errorf("logon MsgSeqNum %d below expected %d", seq, expected)
+	}
+
+	s.heartBtInt = time.Duration(hb) * time.Second
+	s.lastRecv = time.Now()
+
+	resp := NewMessage(msgTypeLogon).
+		Set(tagEncryptMethod, "0").
+		Set(tagHeartBtInt, strconv.Itoa(hb))
+	if reset == "Y" {
+		resp.Set(tagResetSeqNumFlag, "Y")
+	}
+
+	if seq > expected {
+		// Answer the logon, then ask for what we missed. The logon itself
+		// is not counted until the gap is filled.
+		if err := s.send(resp); err != nil {
+			return err
+		}
+		return s.requestResend(expected, seq)
+	}
+	if err := s.advanceTarget(); err != nil {
+		return err
+	}
+	return s.send(resp)
+}
+
+// onMessage applies sequence number rules and dispatches one message.
+func (s *session) onMessage(raw []byte) error {
+	m, err := ParseMessage(raw)
+	if err != nil {
+		// Garbled messages are ignored; the counterparty detects the gap.
+		s.log.Warn("fix message ignored", slog.String("error", err.Error()))
+		return nil
+	}
+
+	msgType := m.MsgType()
+	seq, ok := m.GetInt(tagMsgSeqNum)
+	if !ok {
+		s.sendLogout("MsgSeqNum (34) missing or invalid")
+		return errLogout
+	}
+	sender, _ := m.Get(tagSenderCompID)
+	target, _ := m.Get(tagTargetCompID)
+	if sender != s.st.brokerID || target != s.a.compID {
+		s.sendReject(seq, msgType, 0, sessionRejectCompIDProblem, "CompID problem")
+		s.sendLogout("CompID problem")
+		return errLogout
+	}
+
+	// A SequenceReset in reset mode ignores MsgSeqNum entirely.
+	if msgType == msgTypeSequenceReset {
+		if gapFill, _ := m.Get(tagGapFillFlag); gapFill != "Y" {
+			return s.onSequenceReset(m, seq)
+		}
+	}
+
+	_, expected := s.st.store.seqNums()
+	switch {
+	case seq > expected:
+		// Honour a resend request even while we wait for our own gap.
+		if msgType == msgTypeResendRequest {
+			if err := s.onResendRequest(m, seq); err != nil {
+				return err
+			}
+		}
+		if s.resendTo < seq {
+			return s.requestResend(expected, seq)
+		}
+		return nil
+	case seq < expected:
+		if possDup, _ := m.Get(tagPossDupFlag); possDup == "Y" {
+			return nil
+		}
+		s.sendLogout(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seq))
+		return errLogout
+	}
+
+	if msgType == msgTypeSequenceReset {
+		return s.onSequenceReset(m, seq)
+	}
+	if err := s.advanceTarget(); err != nil {
+		return err
+	}
+	if s.resendTo != 0 && seq >= s.resendTo {
+		s.resendTo = 0
+	}
+
+	switch msgType {
+	case msgTypeHeartbeat, msgTypeReject:
+		return nil
+	case msgTypeTestRequest:
+		id, _ := m.Get(tagTestReqID)
+		return s.send(NewMessage(msgTypeHeartbeat).Set(tagTestReqID, id))
+	case msgTypeResendRequest:
+		return s.onResendRequest(m, seq)
+	case msgTypeLogout:
+		s.sendLogout("")
+		return errLogout
+	case msgTypeLogon:
+		s.sendReject(seq, msgType, 0, sessionRejectValueIncorrect, "Already logged on")
+		return nil
+	case msgTypeNewOrderSingle:
+		return s.onNewOrderSingle(m, seq)
+	case msgTypeOrderCancelRequest:
+		return s.onOrderCancelRequest(m, seq)
+	case msgTypeOrderCancelReplace:
+		return s.onOrderCancelReplace(m, seq)
+	case msgTypeMarketDataRequest:
+		return s.onMarketDataRequest(m, seq)
+	default:
+		return s.send(NewMessage(msgTypeBusinessMessageReject).
+			Set(tagRefSeqNum, strconv.Itoa(seq)).
+			Set(tagRefMsgType, msgType).
+			Set(tagBusinessRejectReason, businessRejectUnsupportedMsgType).
+			Set(tagText, "Unsupported MsgType"))
+	}
+}
+
+// onSequenceReset moves the expected incoming sequence number forward, for
+// both gap fills and resets. Moving it backwards is rejected.
+func (s *session) onSequenceReset(m *Message, seq int) error {
+	newSeq, ok := m.GetInt(tagNewSeqNo)
+	sender, expected := s.st.store.seqNums()
+	if !ok || newSeq < expected {
+		s.sendReject(seq, msgTypeSequenceReset, tagNewSeqNo, sessionRejectValueIncorrect,
+			fmt.Sprintf("NewSeqNo must be at least %d", expected))
+		return nil
+	}
+	if s.resendTo != 0 && newSeq > s.resendTo {
+		s.resendTo = 0
+	}
+	return s.st.store.setSeqNums(sender, newSeq)
+}
+
+// onResendRequest resends stored messages in the requested range with
+// PossDupFlag set. Runs of admin and market data messages, which are not
+// stored, are replaced by a SequenceReset-GapFill.
+func (s *session) onResendRequest(m *Message, seq int) error {
+	begin, ok1 := m.GetInt(tagBeginSeqNo)
+	end, ok2 := m.GetInt(tagEndSeqNo)
+	if !ok1 || !ok2 || begin < 1 {
+		s.sendReject(seq, msgTypeResendRequest, tagBeginSeqNo, sessionRejectValueIncorrect, "Invalid BeginSeqNo/EndSeqNo")
+		return nil
+	}
+
+	next, _ := s.st.store.seqNums()
+	if end == 0 || end >= next {
+		end = next - 1
+	}
+
+	gapStart := 0
+	for i := begin; i <= end; i++ {
+		raw, ok := s.st.store.message(i)
+		if !ok {
+			if gapStart == 0 {
+				gapStart = i
+			}
+			continue
+		}
+		if gapStart != 0 {
+			if err := s.sendGapFill(gapStart, i); err != nil {
+				return err
+			}
+			gapStart = 0
+		}
+		if err := s.resend(raw); err != nil {
+			return err
+		}
+	}
+	if gapStart != 0 {
+		return s.sendGapFill(gapStart, end+1)
+	}
+	return nil
+}
+
+// resend rewrites a stored message's header for a possible duplicate and
+// writes it without consuming a new sequence number.
+func (s *session) resend(raw []byte) error {
+	stored, err := ParseMessage(raw)
+	if err != nil {
+		return fmt.Errorf("stored message: %w", err)
+	}
+	origSendingTime, _ := stored.Get(tagSendingTime)
+
+	m := &Message{}
+	for _, f := range stored.fields {
+		switch f.tag {
+		case tagSendingTime, tagPossDupFlag, tagOrigSendingTime:
+		case tagMsgSeqNum:
+			m.fields = append(m.fields, f,
+				field{tagPossDupFlag, "Y"},
+				field{tagSendingTime, time.Now().UTC().Format(timestampFormat)},
+				field{tagOrigSendingTime, origSendingTime})
+		default:
+			m.fields = append(m.fields, f)
+		}
+	}
+	return s.write(m.Bytes())
+}
+
+// sendGapFill tells the counterparty to skip from seq to newSeq.
+func (s *session) sendGapFill(seq, newSeq int) error {
+	m := s.header(msgTypeSequenceReset, seq).
+		Set(tagPossDupFlag, "Y").
+		SetTime(tagSendingTime, time.Now()).
+		Set(tagGapFillFlag, "Y").
+		Set(tagNewSeqNo, strconv.Itoa(newSeq))
+	return s.write(m.Bytes())
+}
+
+// requestResend asks the counterparty to resend from begin onwards, having
+// received through seq.
+func (s *session) requestResend(begin, seq int) error {
+	s.resendTo = seq
+	return s.send(NewMessage(msgTypeResendRequest).
+		Set(tagBeginSeqNo, strconv.Itoa(begin)).
+		Set(tagEndSeqNo, "0"))
+}
+
+// onTimer sends heartbeats and detects a silent counterparty.
+func (s *session) onTimer(now time.Time) error {
+	if s.heartBtInt == 0 {
+		return nil
+	}
+	if now.Sub(s.lastSent) >= s.heartBtInt {
+		if err := s.send(NewMessage(msgTypeHeartbeat)); err != nil {
+			return err
+		}
+	}
+
+	silence := now.Sub(s.lastRecv)
+	switch {
+	case s.testReqAt.IsZero() && silence >= s.heartBtInt+s.heartBtInt/5:
+		s.testReqAt = now
+		return s.send(NewMessage(msgTypeTestRequest).Set(tagTestReqID, strconv.FormatInt(now.UnixNano(), 10)))
+	case !s.testReqAt.IsZero() && now.Sub(s.testReqAt) >= s.heartBtInt:
+		s.sendLogout("Heartbeat timeout")
+		return fmt.Errorf("no response to TestRequest")
+	}
+	return nil
+}
+
+// subscribe (re)opens the broker event subscription, resuming after the
+// last event handled, and processes the backlog.
+func (s *session) subscribe() error {
+	if s.sub != nil {
+		s.a.events.Unsubscribe(s.st.brokerID, s.sub)
+	}
+	sub, backlog, err := s.a.events.Subscribe(s.st.brokerID, s.st.lastEventID)
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	for _, ev := range backlog {
+		if err := s.onEvent(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainEvents handles every event already queued. Called after a request
+// to the order service, whose events are published before it returns, so
+// reports for the request go out before anything that depends on them.
+func (s *session) drainEvents() error {
+	for {
+		select {
+		case ev := <-s.sub.Events():
+			if err := s.onEvent(ev); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// advanceTarget records that the expected incoming message was processed.
+func (s *session) advanceTarget() error {
+	sender, target := s.st.store.seqNums()
+	return s.st.store.setSeqNums(sender, target+1)
+}
+
+// header starts an outgoing message with the standard header through
+// MsgSeqNum.
+func (s *session) header(msgType string, seq int) *Message {
+	return NewMessage(msgType).
+		Set(tagSenderCompID, s.a.compID).
+		Set(tagTargetCompID, s.st.brokerID).
+		Set(tagMsgSeqNum, strconv.Itoa(seq))
+}
+
+// send assigns the next sequence number to m, stores it if it can be
+// resent, and writes it.
+func (s *session) send(m *Message) error {
+	sender, target := s.st.store.seqNums()
+	out := s.header(m.MsgType(), sender).SetTime(tagSendingTime, time.Now())
+	out.fields = append(out.fields, m.fields[1:]...)
+	raw := out.Bytes()
+
+	if isResendable(m.MsgType()) {
+		if err := s.st.store.saveMessage(sender, raw); err != nil {
+			return err
+		}
+	}
+	if err := s.st.store.setSeqNums(sender+1, target); err != nil {
+		return err
+	}
+	return s.write(raw)
+}
+
+func (s *session) write(raw []byte) error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.conn.Write(raw); err != nil {
+		return err
+	}
+	s.lastSent = time.Now()
+	return nil
+}
+
+// sendLogout sends a Logout, ignoring write errors since the connection is
+// about to close anyway.
+func (s *session) sendLogout(text string) {
+	m := NewMessage(msgTypeLogout)
+	if text != "" {
+		m.Set(tagText, text)
+	}
+	if err := s.send(m); err != nil {
+		s.log.Debug("fix logout not sent", slog.String("error", err.Error()))
+	}
+}
+
+// sendReject sends a session-level Reject for the message with sequence
+// number refSeq. refTag is omitted when 0.
+func (s *session) sendReject(refSeq int, refMsgType string, refTag int, reason, text string) {
+	m := NewMessage(msgTypeReject).
+		Set(tagRefSeqNum, strconv.Itoa(refSeq)).
+		Set(tagRefMsgType, refMsgType).
+		Set(tagSessionRejectReason, reason).
+		Set(tagText, text)
+	if refTag != 0 {
+		m.Set(tagRefTagID, strconv.Itoa(refTag))
+	}
+	if err := s.send(m); err != nil {
+		s.log.Debug("fix reject not sent", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,171 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FileStore persists FIX session state under a directory. Each session has
+// two files: <id>.seqnums with the next outgoing and expected incoming
+// sequence numbers, and <id>.messages with every outgoing application
+// message, so a counterparty can request a resend of anything it missed,
+// even across restarts.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create fix store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// open loads or creates the state of one session.
+func (s *FileStore) open(sessionID string) (*sessionStore, error) {
+	ss := &sessionStore{
+		seqPath:       filepath.Join(s.dir, sessionID+".seqnums"),
+		msgPath:       filepath.Join(s.dir, sessionID+".messages"),
+		nextSenderSeq: 1,
+		nextTargetSeq: 1,
+		messages:      make(map[int][]byte),
+	}
+	if err := ss.load(); err != nil {
+		return nil, fmt.Errorf("load fix session %s: %w", sessionID, err)
+	}
+
+	f, err := os.OpenFile(ss.msgPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	ss.msgFile = f
+	return ss, nil
+}
+
+// sessionStore is the persisted state of one session. Messages are kept in
+// memory as well as on disk for resends.
+type sessionStore struct {
+	seqPath string
+	msgPath string
+
+	mu            sync.Mutex
+	nextSenderSeq int
+	nextTargetSeq int
+	messages      map[int][]byte // seq → raw outgoing application message
+	msgFile       *os.File
+}
+
+func (ss *sessionStore) load() error {
+	data, err := os.ReadFile(ss.seqPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if _, err := fmt.Sscanf(string(data), "%d %d", &ss.nextSenderSeq, &ss.nextTargetSeq); err != nil {
+			return fmt.Errorf("parse %s: %w", ss.seqPath, err)
+		}
+	}
+
+	f, err := os.Open(ss.msgPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Records are "<seq> <length>\n<raw>\n". A torn final record from a
+	// crash mid-write is dropped.
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+		seqStr, lenStr, ok := strings.Cut(strings.TrimSuffix(line, "\n"), " ")
+		if !ok {
+			return fmt.Errorf("parse %s: malformed record header", ss.msgPath)
+		}
+		seq, err1 := strconv.Atoi(seqStr)
+		n, err2 := strconv.Atoi(lenStr)
+		if err1 != nil || err2 != nil || n < 0 {
+			return fmt.Errorf("parse %s: malformed record header", ss.msgPath)
+		}
+		raw := make([]byte, n+1)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return nil
+		}
+		ss.messages[seq] = raw[:n]
+	}
+}
+
+// seqNums returns the next outgoing and expected incoming sequence numbers.
+func (ss *sessionStore) seqNums() (sender, target int) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.nextSenderSeq, ss.nextTargetSeq
+}
+
+// setSeqNums persists new sequence numbers. The file is replaced atomically
+// so a crash never leaves it half-written.
+func (ss *sessionStore) setSeqNums(sender, target int) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.nextSenderSeq, ss.nextTargetSeq = sender, target
+
+	tmp := ss.seqPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", sender, target)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ss.seqPath)
+}
+
+// saveMessage appends an outgoing application message.
+func (ss *sessionStore) saveMessage(seq int, raw []byte) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.messages[seq] = raw
+
+	rec := make([]byte, 0, len(raw)+24)
+	rec = fmt.Appendf(rec, "%d %d\n", seq, len(raw))
+	rec = append(rec, raw...)
+	rec = append(rec, '\n')
+	_, err := ss.msgFile.Write(rec)
+	return err
+}
+
+// message returns the stored outgoing message with the given sequence
+// number, if it was an application message.
+func (ss *sessionStore) message(seq int) ([]byte, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	raw, ok := ss.messages[seq]
+	return raw, ok
+}
+
+// reset clears the message log and restarts both sequences at 1, as for a
+// logon with ResetSeqNumFlag.
+func (ss *sessionStore) reset() error {
+	ss.mu.Lock()
+	ss.messages = make(map[int][]byte)
+	err := ss.msgFile.Truncate(0)
+	ss.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ss.setSeqNums(1, 1)
+}
+
+func (ss *sessionStore) close() error {
+	return ss.msgFile.Close()
+}
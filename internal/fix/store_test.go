@@ -0,0 +1,106 @@
+package fix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ss, err := fs.open("broker1-EX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender, target := ss.seqNums(); sender != 1 || target != 1 {
+		t.Fatalf("new session seqnums = %d/%d, want 1/1", sender, target)
+	}
+
+	msg := NewMessage(msgTypeExecutionReport).Set(tagMsgSeqNum, "2").Bytes()
+	if err := ss.saveMessage(2, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ss.setSeqNums(3, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ss.close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := fs.open("broker1-EX")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.close()
+	if sender, target := reopened.seqNums(); sender != 3 || target != 5 {
+		t.Errorf("reopened seqnums = %d/%d, want 3/5", sender, target)
+	}
+	got, ok := reopened.message(2)
+	if !ok || !bytes.Equal(got, msg) {
+		t.Errorf("reopened message 2 = %q (ok=%v), want %q", got, ok, msg)
+	}
+	if _, ok := reopened.message(1); ok {
+		t.Error("expected no message 1")
+	}
+}
+
+func TestFileStore_DropsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+	ss, _ := fs.open("s")
+	msg := NewMessage(msgTypeExecutionReport).Bytes()
+	ss.saveMessage(1, msg)
+	ss.close()
+
+	// Simulate a crash half-way through writing the next record.
+	f, err := os.OpenFile(filepath.Join(dir, "s.messages"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.WriteString("2 500\n8=FIX.4.4")
+	f.Close()
+
+	reopened, err := fs.open("s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.close()
+	if _, ok := reopened.message(1); !ok {
+		t.Error("expected message 1 to survive")
+	}
+	if _, ok := reopened.message(2); ok {
+		t.Error("expected torn message 2 to be dropped")
+	}
+}
+
+func TestFileStore_Reset(t *testing.T) {
+	fs, _ := NewFileStore(t.TempDir())
+	ss, _ := fs.open("s")
+	defer ss.close()
+
+	ss.saveMessage(1, NewMessage(msgTypeExecutionReport).Bytes())
+	ss.setSeqNums(10, 20)
+
+	if err := ss.reset(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender, target := ss.seqNums(); sender != 1 || target != 1 {
+		t.Errorf("seqnums after reset = %d/%d, want 1/1", sender, target)
+	}
+	if _, ok := ss.message(1); ok {
+		t.Error("expected message log to be cleared")
+	}
+
+	// The log on disk is cleared too.
+	reopened, _ := fs.open("s")
+	defer reopened.close()
+	if _, ok := reopened.message(1); ok {
+		t.Error("expected message log on disk to be cleared")
+	}
+}
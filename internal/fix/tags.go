@@ -0,0 +1,218 @@
+package fix
+
+// Tags used by the acceptor.
+const (
+	tagAccount              = 1
+	tagAvgPx                = 6
+	tagBeginSeqNo           = 7
+	tagBeginString          = 8
+	tagBodyLength           = 9
+	tagCheckSum             = 10
+	tagClOrdID              = 11
+	tagCumQty               = 14
+	tagEndSeqNo             = 16
+	tagExecID               = 17
+	tagLastPx               = 31
+	tagLastQty              = 32
+	tagMsgSeqNum            = 34
+	tagMsgType              = 35
+	tagNewSeqNo             = 36
+	tagOrderID              = 37
+	tagOrderQty             = 38
+	tagOrdStatus            = 39
+	tagOrdType              = 40
+	tagOrigClOrdID          = 41
+	tagPossDupFlag          = 43
+	tagPrice                = 44
+	tagRefSeqNum            = 45
+	tagSenderCompID         = 49
+	tagSendingTime          = 52
+	tagSide                 = 54
+	tagSymbol               = 55
+	tagTargetCompID         = 56
+	tagText                 = 58
+	tagTimeInForce          = 59
+	tagTransactTime         = 60
+	tagEncryptMethod        = 98
+	tagCxlRejReason         = 102
+	tagOrdRejReason         = 103
+	tagHeartBtInt           = 108
+	tagTestReqID            = 112
+	tagOrigSendingTime      = 122
+	tagGapFillFlag          = 123
+	tagExpireTime           = 126
+	tagResetSeqNumFlag      = 141
+	tagNoRelatedSym         = 146
+	tagExecType             = 150
+	tagLeavesQty            = 151
+	tagMDReqID              = 262
+	tagSubscriptionReqType  = 263
+	tagMarketDepth          = 264
+	tagMDUpdateType         = 265
+	tagNoMDEntryTypes       = 267
+	tagNoMDEntries          = 268
+	tagMDEntryType          = 269
+	tagMDEntryPx            = 270
+	tagMDEntrySize          = 271
+	tagMDEntryDate          = 272
+	tagMDEntryTime          = 273
+	tagMDUpdateAction       = 279
+	tagMDReqRejReason       = 281
+	tagMDEntryPositionNo    = 290
+	tagNumberOfOrders       = 346
+	tagRefTagID             = 371
+	tagRefMsgType           = 372
+	tagSessionRejectReason  = 373
+	tagBusinessRejectReason = 380
+	tagCxlRejResponseTo     = 434
+	tagPassword             = 554
+)
+
+// Message types.
+const (
+	msgTypeHeartbeat             = "0"
+	msgTypeTestRequest           = "1"
+	msgTypeResendRequest         = "2"
+	msgTypeReject                = "3"
+	msgTypeSequenceReset         = "4"
+	msgTypeLogout                = "5"
+	msgTypeExecutionReport       = "8"
+	msgTypeOrderCancelReject     = "9"
+	msgTypeLogon                 = "A"
+	msgTypeNewOrderSingle        = "D"
+	msgTypeOrderCancelRequest    = "F"
+	msgTypeOrderCancelReplace    = "G"
+	msgTypeMarketDataRequest     = "V"
+	msgTypeMarketDataSnapshot    = "W"
+	msgTypeMarketDataIncremental = "X"
+	msgTypeMarketDataReject      = "Y"
+	msgTypeBusinessMessageReject = "j"
+)
+
+// isAdminMsgType reports whether a message type belongs to the session
+// layer. Admin messages are never resent; a gap fill replaces them.
+func isAdminMsgType(msgType string) bool {
+	switch msgType {
+	case msgTypeHeartbeat, msgTypeTestRequest, msgTypeResendRequest, msgTypeReject,
+		msgTypeSequenceReset, msgTypeLogout, msgTypeLogon:
+		return true
+	}
+	return false
+}
+
+// isResendable reports whether an outgoing message is stored for resends.
+// Admin messages and market data, which would be stale by the time it is
+// resent, are gap-filled instead.
+func isResendable(msgType string) bool {
+	switch msgType {
+	case msgTypeMarketDataSnapshot, msgTypeMarketDataIncremental, msgTypeMarketDataReject:
+		return false
+	}
+	return !isAdminMsgType(msgType)
+}
+
+// Side (54) values.
+const (
+	sideBuy  = "1"
+	sideSell = "2"
+)
+
+// OrdType (40) values.
+const (
+	ordTypeMarket = "1"
+	ordTypeLimit  = "2"
+)
+
+// TimeInForce (59) values.
+const (
+	timeInForceDay = "0"
+	timeInForceIOC = "3"
+	timeInForceGTD = "6"
+)
+
+// ExecType (150) values.
+const (
+	execTypeNew      = "0"
+	execTypeCanceled = "4"
+	execTypeReplaced = "5"
+	execTypeRejected = "8"
+	execTypeExpired  = "C"
+	execTypeTrade    = "F"
+)
+
+// OrdStatus (39) values.
+const (
+	ordStatusNew      = "0"
+	ordStatusPartial  = "1"
+	ordStatusFilled   = "2"
+	ordStatusCanceled = "4"
+	ordStatusRejected = "8"
+	ordStatusExpired  = "C"
+)
+
+// OrdRejReason (103) values.
+const (
+	ordRejDuplicateOrder = "6"
+	ordRejOther          = "99"
+)
+
+// CxlRejReason (102) values.
+const (
+	cxlRejTooLate      = "0"
+	cxlRejUnknownOrder = "1"
+	cxlRejOther        = "99"
+)
+
+// CxlRejResponseTo (434) values.
+const (
+	cxlRejResponseToCancel  = "1"
+	cxlRejResponseToReplace = "2"
+)
+
+// SessionRejectReason (373) values.
+const (
+	sessionRejectRequiredTagMissing = "1"
+	sessionRejectValueIncorrect     = "5"
+	sessionRejectCompIDProblem      = "9"
+)
+
+// BusinessRejectReason (380) values.
+const (
+	businessRejectUnsupportedMsgType = "3"
+)
+
+// SubscriptionRequestType (263) values.
+const (
+	subscriptionSnapshot    = "0"
+	subscriptionSubscribe   = "1"
+	subscriptionUnsubscribe = "2"
+)
+
+// MDUpdateType (265) values.
+const (
+	mdUpdateTypeIncremental = "1"
+)
+
+// MDEntryType (269) values.
+const (
+	mdEntryBid   = "0"
+	mdEntryOffer = "1"
+	mdEntryTrade = "2"
+)
+
+// MDUpdateAction (279) values.
+const (
+	mdUpdateActionNew    = "0"
+	mdUpdateActionChange = "1"
+	mdUpdateActionDelete = "2"
+)
+
+// MDReqRejReason (281) values.
+const (
+	mdReqRejUnknownSymbol          = "0"
+	mdReqRejDuplicateMDReqID       = "1"
+	mdReqRejUnsupportedSubReqType  = "4"
+	mdReqRejUnsupportedMarketDepth = "5"
+	mdReqRejUnsupportedUpdateType  = "6"
+	mdReqRejUnsupportedEntryType   = "8"
+)
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIKeyHandler handles HTTP requests for broker API key endpoints.
+type APIKeyHandler struct {
+	apiKeySvc *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(apiKeySvc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeySvc: apiKeySvc}
+}
+
+// apiKeyResponse is a single API key, without its secret.
+type apiKeyResponse struct {
+	KeyID     string  `json:"key_id"`
+	BrokerID  string  `json:"broker_id"`
+	Prefix    string  `json:"prefix"`
+	CreatedAt string  `json:"created_at"`
+	RotatedAt *string `json:"rotated_at"`
+}
+
+// apiKeySecretResponse is the JSON response for creating or rotating a key.
+// It is the only time the secret is returned.
+type apiKeySecretResponse struct {
+	apiKeyResponse
+	APIKey string `json:"api_key"`
+}
+
+// apiKeyListResponse is the JSON response for GET /brokers/{broker_id}/api-keys.
+type apiKeyListResponse struct {
+	APIKeys []apiKeyResponse `json:"api_keys"`
+}
+
+// Create handles POST /brokers/{broker_id}/api-keys.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	brokerID := chi.URLParam(r, "broker_id")
+
+	k, secret, err := h.apiKeySvc.Create(brokerID)
+	if err != nil {
+		mapAPIKeyError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, apiKeySecretResponse{
+		apiKeyResponse: buildAPIKeyResponse(k),
+		APIKey:         secret,
+	})
+}
+
+// List handles GET /brokers/{broker_id}/api-keys.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	brokerID := chi.URLParam(r, "broker_id")
+
+	keys, err := h.apiKeySvc.List(brokerID)
+	if err != nil {
+		mapAPIKeyError(w, err)
+		return
+	}
+
+	resp := apiKeyListResponse{APIKeys: make([]apiKeyResponse, len(keys))}
+	for i, k := range keys {
+		resp.APIKeys[i] = buildAPIKeyResponse(k)
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// Rotate handles POST /brokers/{broker_id}/api-keys/{key_id}/rotate.
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	brokerID := chi.URLParam(r, "broker_id")
+	keyID := chi.URLParam(r, "key_id")
+
+	k, secret, err := h.apiKeySvc.Rotate(brokerID, keyID)
+	if err != nil {
+		mapAPIKeyError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, apiKeySecretResponse{
+		apiKeyResponse: buildAPIKeyResponse(k),
+		APIKey:         secret,
+	})
+}
+
+// Revoke handles DELETE /brokers/{broker_id}/api-keys/{key_id}.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	brokerID := chi.URLParam(r, "broker_id")
+	keyID := chi.URLParam(r, "key_id")
+
+	if err := h.apiKeySvc.Revoke(brokerID, keyID); err != nil {
+		mapAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildAPIKeyResponse converts a domain API key to its response form.
+func buildAPIKeyResponse(k *domain.APIKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		KeyID:     k.KeyID,
+		BrokerID:  k.BrokerID,
+		Prefix:    k.Prefix,
+		CreatedAt: k.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if k.RotatedAt != nil {
+		s := k.RotatedAt.UTC().Format("2006-01-02T15:04:05Z")
+		resp.RotatedAt = &s
+	}
+	return resp
+}
+
+// mapAPIKeyError maps domain errors to HTTP responses for API key endpoints.
+func mapAPIKeyError(w http.ResponseWriter, err error) {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		WriteError(w, http.StatusBadRequest, "validation_error", validationErr.Message)
+		return
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrBrokerNotFound):
+		WriteError(w, http.StatusNotFound, "broker_not_found", err.Error())
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		WriteError(w, http.StatusNotFound, "api_key_not_found", err.Error())
+	default:
+		WriteError(w, http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/efreitasn/miniexchange/internal/audit"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditHandler handles HTTP requests for the audit trail.
+type AuditHandler struct {
+	auditSvc *service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(auditSvc *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditSvc: auditSvc}
+}
+
+// auditRecordListResponse is the JSON response for
+// GET /audit/orders/{order_id}. Records are returned as they are written to
+// the audit log.
+type auditRecordListResponse struct {
+	OrderID string         `json:"order_id"`
+	Records []audit.Record `json:"records"`
+}
+
+// ListOrderRecords handles GET /audit/orders/{order_id}.
+func (h *AuditHandler) ListOrderRecords(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "order_id")
+
+	records, err := h.auditSvc.ListOrderRecords(orderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			WriteError(w, http.StatusNotFound, "order_not_found", "No audit records for order "+orderID)
+			return
+		}
+		WriteError(w, http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, auditRecordListResponse{OrderID: orderID, Records: records})
+}
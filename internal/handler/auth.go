@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// principalKey is the context key under which authenticate stores the
+// caller's domain.Principal.
+type principalKey struct{}
+
+// authenticate returns middleware that resolves the API key in the
+// Authorization header ("Bearer <key>") to a principal and stores it in the
+// request context. Requests without a valid key get 401 Unauthorized.
+func authenticate(apiKeySvc *service.APIKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := apiKeySvc.Authenticate(bearerToken(r))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="miniexchange"`)
+				WriteError(w, http.StatusUnauthorized, "unauthorized",
+					"A valid API key is required in the Authorization header")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+		})
+	}
+}
+
+// requireAdmin is middleware that rejects callers without the admin role
+// with 403 Forbidden. It must run after authenticate.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principalFrom(r).Role != domain.APIKeyRoleAdmin {
+			WriteError(w, http.StatusForbidden, "forbidden", "This endpoint requires the admin role")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireBrokerParam is middleware that rejects callers who may not act for
+// the broker in the {broker_id} URL parameter. It must run after
+// authenticate.
+func requireBrokerParam(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(w, r, chi.URLParam(r, "broker_id")) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize reports whether the caller may act for brokerID. If not, it
+// writes 403 Forbidden and the handler must return.
+func authorize(w http.ResponseWriter, r *http.Request, brokerID string) bool {
+	if principalFrom(r).CanActFor(brokerID) {
+		return true
+	}
+	WriteError(w, http.StatusForbidden, "forbidden", "API key may not act for broker "+brokerID)
+	return false
+}
+
+// authorizeOrder reports whether the caller may act for order's broker. If
+// not, it writes 404 Not Found, as for an unknown order, so that callers
+// cannot learn which order IDs other brokers hold, and the handler must
+// return.
+func authorizeOrder(w http.ResponseWriter, r *http.Request, order *domain.Order) bool {
+	if principalFrom(r).CanActFor(order.BrokerID) {
+		return true
+	}
+	mapOrderError(w, domain.ErrOrderNotFound)
+	return false
+}
+
+// principalFrom returns the principal stored by authenticate, or the zero
+// Principal, which may act for no broker.
+func principalFrom(r *http.Request) domain.Principal {
+	p, _ := r.Context().Value(principalKey{}).(domain.Principal)
+	return p
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>"
+// header, or "" if there is none.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
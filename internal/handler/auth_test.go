@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// doAs sends a request authenticated with key, or without an Authorization
+// header if key is empty.
+func (env *testEnv) doAs(t *testing.T, key, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	return rr
+}
+
+// createAPIKey issues a key for brokerID as admin and returns its secret.
+func (env *testEnv) createAPIKey(t *testing.T, brokerID string) string {
+	t.Helper()
+	rr := env.doJSON(t, "POST", "/brokers/"+brokerID+"/api-keys", nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create api key for %s: expected 201, got %d: %s", brokerID, rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	return resp["api_key"].(string)
+}
+
+func TestAuth_PublicRoutes(t *testing.T) {
+	env := newTestEnv()
+
+	for _, path := range []string{"/healthz", "/stocks/AAPL/price", "/stocks/ticker"} {
+		rr := env.doAs(t, "", "GET", path, nil)
+		if rr.Code == http.StatusUnauthorized {
+			t.Errorf("GET %s: public route returned 401", path)
+		}
+	}
+}
+
+func TestAuth_MissingOrInvalidKey(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	for name, header := range map[string]string{
+		"missing":      "",
+		"unknown key":  "Bearer mx_not-a-key",
+		"wrong scheme": "Basic " + testAdminKey,
+		"no token":     "Bearer",
+	} {
+		req := httptest.NewRequest("GET", "/brokers/b1/balance", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, rr.Code)
+			continue
+		}
+		if got := rr.Header().Get("WWW-Authenticate"); got == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", name)
+		}
+		var resp map[string]any
+		decodeJSON(t, rr, &resp)
+		if resp["error"] != "unauthorized" {
+			t.Errorf("%s: expected error unauthorized, got %v", name, resp["error"])
+		}
+	}
+}
+
+func TestAuth_RegisterBrokerRequiresAdmin(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+	key := env.createAPIKey(t, "b1")
+
+	rr := env.doAs(t, key, "POST", "/brokers", map[string]any{"broker_id": "b2", "initial_cash": 100})
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if resp["error"] != "forbidden" {
+		t.Errorf("expected error forbidden, got %v", resp["error"])
+	}
+}
+
+func TestAuth_TradingHaltsRequireAdmin(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+	key := env.createAPIKey(t, "b1")
+
+	for _, path := range []string{"/stocks/AAPL/halt", "/stocks/AAPL/resume"} {
+		if rr := env.doAs(t, key, "POST", path, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("POST %s with a broker key: expected 403, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestAuth_BrokerRoutesAreScoped(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+	env.registerBroker(t, "b2", 1000, nil)
+	key := env.createAPIKey(t, "b1")
+
+	for _, path := range []string{"/brokers/b1/balance", "/brokers/b1/orders", "/brokers/b1/api-keys"} {
+		if rr := env.doAs(t, key, "GET", path, nil); rr.Code != http.StatusOK {
+			t.Errorf("GET %s with own key: expected 200, got %d", path, rr.Code)
+		}
+	}
+	for _, path := range []string{"/brokers/b2/balance", "/brokers/b2/orders", "/brokers/b2/events", "/brokers/b2/api-keys"} {
+		if rr := env.doAs(t, key, "GET", path, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("GET %s with another broker's key: expected 403, got %d", path, rr.Code)
+		}
+	}
+	if rr := env.doAs(t, key, "POST", "/brokers/b2/api-keys", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("create key for another broker: expected 403, got %d", rr.Code)
+	}
+}
+
+func TestAuth_APIKeyLifecycle(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	// A key is created without a request body.
+	rr := env.doAs(t, testAdminKey, "POST", "/brokers/b1/api-keys", nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created map[string]any
+	decodeJSON(t, rr, &created)
+	secret := created["api_key"].(string)
+	keyID := created["key_id"].(string)
+	if created["broker_id"] != "b1" || created["rotated_at"] != nil {
+		t.Errorf("unexpected create response: %v", created)
+	}
+	if prefix, _ := created["prefix"].(string); len(prefix) == 0 || secret[:len(prefix)] != prefix {
+		t.Errorf("prefix %q should start the secret", prefix)
+	}
+
+	// The key works, and the listing never returns the secret.
+	rr = env.doAs(t, secret, "GET", "/brokers/b1/api-keys", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d", rr.Code)
+	}
+	if bytes.Contains(rr.Body.Bytes(), []byte(secret)) {
+		t.Error("list response must not contain the secret")
+	}
+	var list struct {
+		APIKeys []map[string]any `json:"api_keys"`
+	}
+	decodeJSON(t, rr, &list)
+	if len(list.APIKeys) != 1 || list.APIKeys[0]["key_id"] != keyID {
+		t.Fatalf("unexpected list: %v", list.APIKeys)
+	}
+
+	// Rotating keeps the ID and replaces the secret.
+	rr = env.doAs(t, secret, "POST", "/brokers/b1/api-keys/"+keyID+"/rotate", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("rotate: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var rotated map[string]any
+	decodeJSON(t, rr, &rotated)
+	newSecret := rotated["api_key"].(string)
+	if rotated["key_id"] != keyID || rotated["rotated_at"] == nil || newSecret == secret {
+		t.Errorf("unexpected rotate response: %v", rotated)
+	}
+	if rr := env.doAs(t, secret, "GET", "/brokers/b1/balance", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("old secret after rotation: expected 401, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, newSecret, "GET", "/brokers/b1/balance", nil); rr.Code != http.StatusOK {
+		t.Errorf("new secret after rotation: expected 200, got %d", rr.Code)
+	}
+
+	// Revoking stops it working.
+	rr = env.doAs(t, newSecret, "DELETE", "/brokers/b1/api-keys/"+keyID, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := env.doAs(t, newSecret, "GET", "/brokers/b1/balance", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked secret: expected 401, got %d", rr.Code)
+	}
+	if rr := env.doJSON(t, "DELETE", "/brokers/b1/api-keys/"+keyID, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("revoke twice: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestAuth_APIKeyErrors(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+	env.registerBroker(t, "b2", 1000, nil)
+	env.createAPIKey(t, "b1")
+	rr := env.doJSON(t, "GET", "/brokers/b1/api-keys", nil)
+	var list struct {
+		APIKeys []map[string]any `json:"api_keys"`
+	}
+	decodeJSON(t, rr, &list)
+	b1Key := list.APIKeys[0]["key_id"].(string)
+
+	if rr := env.doJSON(t, "POST", "/brokers/ghost/api-keys", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown broker: expected 404, got %d", rr.Code)
+	}
+	if rr := env.doJSON(t, "POST", "/brokers/b2/api-keys/"+b1Key+"/rotate", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("rotate another broker's key: expected 404, got %d", rr.Code)
+	}
+	if rr := env.doJSON(t, "DELETE", "/brokers/b1/api-keys/missing", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown key: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestAuth_OrdersAreScoped(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	env.registerBroker(t, "b2", 50000, nil)
+	k1 := env.createAPIKey(t, "b1")
+	k2 := env.createAPIKey(t, "b2")
+
+	order := map[string]any{
+		"type":            "limit",
+		"broker_id":       "b1",
+		"document_number": "DOC1",
+		"side":            "bid",
+		"symbol":          "AAPL",
+		"price":           10.0,
+		"quantity":        10,
+		"expires_at":      futureRFC3339(),
+	}
+
+	// b2 cannot submit as b1.
+	if rr := env.doAs(t, k2, "POST", "/orders", order); rr.Code != http.StatusForbidden {
+		t.Fatalf("submit as another broker: expected 403, got %d", rr.Code)
+	}
+
+	rr := env.doAs(t, k1, "POST", "/orders", order)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("submit: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created map[string]any
+	decodeJSON(t, rr, &created)
+	orderID := created["order_id"].(string)
+
+	// b2 can neither read nor cancel it, and cannot tell it from an order
+	// that does not exist.
+	for _, method := range []string{"GET", "DELETE"} {
+		other := env.doAs(t, k2, method, "/orders/"+orderID, nil)
+		unknown := env.doAs(t, k2, method, "/orders/no-such-order", nil)
+		if other.Code != http.StatusNotFound {
+			t.Errorf("%s another broker's order: expected 404, got %d", method, other.Code)
+		}
+		if other.Body.String() != unknown.Body.String() {
+			t.Errorf("%s another broker's order: body %s differs from unknown order's %s", method, other.Body.String(), unknown.Body.String())
+		}
+	}
+	rr = env.doAs(t, k1, "GET", "/orders/"+orderID, nil)
+	var got map[string]any
+	decodeJSON(t, rr, &got)
+	if got["status"] != "pending" {
+		t.Errorf("order should still be pending, got %v", got["status"])
+	}
+
+	// b1 can.
+	if rr := env.doAs(t, k1, "DELETE", "/orders/"+orderID, nil); rr.Code != http.StatusOK {
+		t.Errorf("cancel own order: expected 200, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k1, "GET", "/orders/missing", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown order: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestAuth_WebhooksAreScoped(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+	env.registerBroker(t, "b2", 1000, nil)
+	k1 := env.createAPIKey(t, "b1")
+	k2 := env.createAPIKey(t, "b2")
+
+	body := map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hooks",
+		"events":    []string{"trade.executed"},
+	}
+	if rr := env.doAs(t, k2, "POST", "/webhooks", body); rr.Code != http.StatusForbidden {
+		t.Errorf("register for another broker: expected 403, got %d", rr.Code)
+	}
+	rr := env.doAs(t, k1, "POST", "/webhooks", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created webhookListResponse
+	decodeJSON(t, rr, &created)
+	webhookID := created.Webhooks[0].WebhookID
+
+	if rr := env.doAs(t, k2, "GET", "/webhooks?broker_id=b1", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("list another broker's webhooks: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "POST", "/webhooks/"+webhookID+"/rotate-secret", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("rotate another broker's webhook secret: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "GET", "/webhooks/"+webhookID+"/deliveries", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("list another broker's webhook deliveries: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "POST", "/webhooks/"+webhookID+"/deliveries/d-1/redeliver", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("redeliver another broker's webhook delivery: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "POST", "/webhooks/"+webhookID+"/ping", nil); rr.Code != http.StatusForbidden {
+		t.Errorf("ping another broker's webhook: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "PATCH", "/webhooks/"+webhookID, map[string]any{"status": "paused"}); rr.Code != http.StatusForbidden {
+		t.Errorf("pause another broker's webhook: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k2, "DELETE", "/webhooks/"+webhookID, nil); rr.Code != http.StatusForbidden {
+		t.Errorf("delete another broker's webhook: expected 403, got %d", rr.Code)
+	}
+	if rr := env.doAs(t, k1, "DELETE", "/webhooks/"+webhookID, nil); rr.Code != http.StatusNoContent {
+		t.Errorf("delete own webhook: expected 204, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// CandleHandler handles HTTP requests for candle (OHLCV) endpoints.
+type CandleHandler struct {
+	candleSvc *service.CandleService
+}
+
+// NewCandleHandler creates a new CandleHandler.
+func NewCandleHandler(candleSvc *service.CandleService) *CandleHandler {
+	return &CandleHandler{candleSvc: candleSvc}
+}
+
+// candleResponse is a single bucket in the candles response.
+type candleResponse struct {
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Open       float64  `json:"open"`
+	High       float64  `json:"high"`
+	Low        float64  `json:"low"`
+	Close      float64  `json:"close"`
+	Volume     int64    `json:"volume"`
+	VWAP       *float64 `json:"vwap"`
+	TradeCount int      `json:"trade_count"`
+	Partial    bool     `json:"partial"`
+}
+
+// candlesResponse is the JSON response for GET /stocks/{symbol}/candles.
+type candlesResponse struct {
+	Symbol   string           `json:"symbol"`
+	Interval string           `json:"interval"`
+	Candles  []candleResponse `json:"candles"`
+}
+
+// GetCandles handles GET /stocks/{symbol}/candles.
+func (h *CandleHandler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := service.CandleRequest{
+		Symbol:   chi.URLParam(r, "symbol"),
+		Interval: service.CandleInterval(q.Get("interval")),
+	}
+
+	var ok bool
+	if req.From, ok = parseOptionalTime(w, r, "from"); !ok {
+		return
+	}
+	if req.To, ok = parseOptionalTime(w, r, "to"); !ok {
+		return
+	}
+
+	switch q.Get("fill_empty") {
+	case "", "false":
+	case "true":
+		req.FillEmpty = true
+	default:
+		WriteError(w, http.StatusBadRequest, "validation_error", "fill_empty must be true or false")
+		return
+	}
+
+	result, err := h.candleSvc.GetCandles(req)
+	if err != nil {
+		mapStockError(w, err)
+		return
+	}
+
+	candles := make([]candleResponse, len(result.Candles))
+	for i, c := range result.Candles {
+		candles[i] = candleResponse{
+			Start:      c.Start.UTC().Format("2006-01-02T15:04:05Z"),
+			End:        c.End.UTC().Format("2006-01-02T15:04:05Z"),
+			Open:       domain.CentsToDollars(c.Open),
+			High:       domain.CentsToDollars(c.High),
+			Low:        domain.CentsToDollars(c.Low),
+			Close:      domain.CentsToDollars(c.Close),
+			Volume:     c.Volume,
+			TradeCount: c.TradeCount,
+			Partial:    c.Partial,
+		}
+		if vwap, ok := c.VWAP(); ok {
+			v := domain.CentsToDollars(vwap)
+			candles[i].VWAP = &v
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, candlesResponse{
+		Symbol:   result.Symbol,
+		Interval: string(result.Interval),
+		Candles:  candles,
+	})
+}
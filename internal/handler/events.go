@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// sseWriteWait is the time allowed to write and flush a single event.
+	sseWriteWait = 10 * time.Second
+	// sseHeartbeatPeriod is how often a comment line is sent on an idle
+	// stream so intermediaries do not time the connection out.
+	sseHeartbeatPeriod = 15 * time.Second
+)
+
+// EventStreamHandler serves the per-broker Server-Sent Events stream.
+type EventStreamHandler struct {
+	eventStreamSvc *service.EventStreamService
+}
+
+// NewEventStreamHandler creates a new EventStreamHandler.
+func NewEventStreamHandler(eventStreamSvc *service.EventStreamService) *EventStreamHandler {
+	return &EventStreamHandler{eventStreamSvc: eventStreamSvc}
+}
+
+// Stream handles GET /brokers/{broker_id}/events. Each event is written with
+// its per-broker ID, event type, and the same JSON payload the webhook would
+// receive. Clients resume by sending the last ID they saw in the
+// Last-Event-ID header (or the last_event_id query parameter, for clients
+// that cannot set headers); retained events after it are replayed first.
+func (h *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	brokerID := chi.URLParam(r, "broker_id")
+
+	lastEventID, ok := parseLastEventID(r)
+	if !ok {
+		WriteError(w, http.StatusBadRequest, "validation_error", "Last-Event-ID must be a non-negative integer")
+		return
+	}
+
+	sub, backlog, err := h.eventStreamSvc.Subscribe(brokerID, lastEventID)
+	if err != nil {
+		mapBrokerError(w, err)
+		return
+	}
+	defer h.eventStreamSvc.Unsubscribe(brokerID, sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for _, ev := range backlog {
+		if err := writeSSEEvent(w, rc, ev); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(sseHeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-sub.Events():
+			if err := writeSSEEvent(w, rc, ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := writeSSE(w, rc, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-sub.Done():
+			// Dropped for falling behind; the client reconnects with
+			// Last-Event-ID and resumes from the retained buffer.
+			if errors.Is(sub.Err(), domain.ErrSlowConsumer) {
+				_ = writeSSE(w, rc, ": "+domain.ErrSlowConsumer.Error()+"\n\n") // best effort; the stream ends here
+			}
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parseLastEventID reads the resume position from the Last-Event-ID header,
+// falling back to the last_event_id query parameter. It returns nil if
+// neither is present, and false if the value is not a valid ID.
+func parseLastEventID(r *http.Request) (*uint64, bool) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
+
+func writeSSEEvent(w http.ResponseWriter, rc *http.ResponseController, ev service.BrokerEvent) error {
+	return writeSSE(w, rc, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Event, ev.Data))
+}
+
+// writeSSE writes one chunk and flushes it. The write deadline is extended
+// per chunk so the server's WriteTimeout does not cut long-lived streams.
+func writeSSE(w http.ResponseWriter, rc *http.ResponseController, chunk string) error {
+	_ = rc.SetWriteDeadline(time.Now().Add(sseWriteWait)) // unsupported by some writers, e.g. in tests
+	if _, err := w.Write([]byte(chunk)); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,45 +14,85 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/audit"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/metrics"
 	"github.com/efreitasn/miniexchange/internal/service"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
 
+// testAdminKey is the admin API key of every test environment. doJSON and
+// doRaw send it, so tests that are not about authorization act as admin.
+const testAdminKey = "test-admin-key-0123456789"
+
 // testEnv bundles all dependencies for handler integration tests.
 type testEnv struct {
-	router     http.Handler
-	brokerSvc  *service.BrokerService
-	orderSvc   *service.OrderService
-	stockSvc   *service.StockService
-	webhookSvc *service.WebhookService
+	router         http.Handler
+	brokerSvc      *service.BrokerService
+	orderSvc       *service.OrderService
+	stockSvc       *service.StockService
+	webhookSvc     *service.WebhookService
+	deliveryStore  *store.DeliveryStore
+	marketDataSvc  *service.MarketDataService
+	eventStreamSvc *service.EventStreamService
+	apiKeySvc      *service.APIKeyService
 }
 
 func newTestEnv() *testEnv {
+	return newAuditedTestEnv(nil)
+}
+
+// newAuditedTestEnv is newTestEnv with orders audited to auditSvc, which
+// also serves the audit trail.
+func newAuditedTestEnv(auditSvc *service.AuditService) *testEnv {
 	bs := store.NewBrokerStore()
 	os := store.NewOrderStore()
 	ts := store.NewTradeStore()
 	ws := store.NewWebhookStore()
 	sr := domain.NewSymbolRegistry()
-	bm := engine.NewBookManager()
-	m := engine.NewMatcher(bm, bs, os, ts, sr)
-	e := engine.NewExpiryManager(time.Hour, bm, os, bs, nil) // long interval, no auto-expiry in tests
-
-	webhookSvc := service.NewWebhookService(ws, bs, 5*time.Second)
-	brokerSvc := service.NewBrokerService(bs, sr)
-	orderSvc := service.NewOrderService(m, e, bs, os, ts, webhookSvc, sr)
+	mx := metrics.New()
+	eventStreamSvc := service.NewEventStreamService(bs, 16, nil)
+	ds := store.NewDeliveryStore(100, 100)
+	webhookSvc := service.NewWebhookService(ws, ds, bs, 5*time.Second, service.DeliveryPolicy{}, eventStreamSvc, mx, nil)
+
+	bm := engine.NewBookManager(nil)
+	m := engine.NewMatcher(bm, bs, os, ts, sr, webhookSvc, auditSvc, nil, nil)
+	bm.AddListener(mx)
+	e := engine.NewExpiryManager(time.Hour, bm, os, bs, nil, mx, auditSvc, nil) // long interval, no auto-expiry in tests
+	mx.WatchExpiryQueue(e.ActiveOrderCount)
+
+	brokerSvc := service.NewBrokerService(bs, sr, nil)
+	orderSvc := service.NewOrderService(m, e, bs, os, ts, webhookSvc, sr, mx, auditSvc, nil, nil)
 	stockSvc := service.NewStockService(ts, bm, m, 5*time.Minute, sr)
+	marketDataSvc := service.NewMarketDataService(sr, 64)
+	bm.AddListener(marketDataSvc)
+	candleSvc := service.NewCandleService(ts, sr, nil)
+	bm.AddListener(candleSvc)
+	tickerSvc := service.NewTickerService(ts, sr, nil)
+	bm.AddListener(tickerSvc)
+	apiKeySvc := service.NewAPIKeyService(store.NewAPIKeyStore(), bs, testAdminKey)
 
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	router := NewRouter(brokerSvc, orderSvc, stockSvc, webhookSvc, logger)
+	router := NewRouter(brokerSvc, orderSvc, stockSvc, webhookSvc, marketDataSvc, eventStreamSvc, candleSvc, tickerSvc, apiKeySvc, auditSvc, mx, logger)
 
 	return &testEnv{
-		router:     router,
-		brokerSvc:  brokerSvc,
-		orderSvc:   orderSvc,
-		stockSvc:   stockSvc,
-		webhookSvc: webhookSvc,
+		router:         router,
+		brokerSvc:      brokerSvc,
+		orderSvc:       orderSvc,
+		stockSvc:       stockSvc,
+		webhookSvc:     webhookSvc,
+		deliveryStore:  ds,
+		marketDataSvc:  marketDataSvc,
+		eventStreamSvc: eventStreamSvc,
+		apiKeySvc:      apiKeySvc,
 	}
 }
 
@@ -67,6 +109,7 @@ func (env *testEnv) doJSON(t *testing.T, method, path string, body any) *httptes
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
 	rr := httptest.NewRecorder()
 	env.router.ServeHTTP(rr, req)
 	return rr
@@ -79,6 +122,7 @@ func (env *testEnv) doRaw(t *testing.T, method, path, contentType, rawBody strin
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
 	rr := httptest.NewRecorder()
 	env.router.ServeHTTP(rr, req)
 	return rr
@@ -446,6 +490,119 @@ func TestOrder_Get_Success(t *testing.T) {
 	}
 }
 
+func TestOrder_Get_QueuePosition(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 10000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 5)
+	second := env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 3)
+	if _, ok := second["queue_position"]; ok {
+		t.Fatalf("expected no queue_position on submit response, got %v", second["queue_position"])
+	}
+
+	rr := env.doJSON(t, "GET", "/orders/"+second["order_id"].(string), nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	pos, ok := resp["queue_position"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected queue_position object, got %v", resp["queue_position"])
+	}
+	if pos["orders_ahead"] != 1.0 || pos["quantity_ahead"] != 5.0 {
+		t.Fatalf("expected 1 order / 5 ahead, got %v", pos)
+	}
+
+	// Not resting once cancelled.
+	env.doJSON(t, "DELETE", "/orders/"+second["order_id"].(string), nil)
+	rr = env.doJSON(t, "GET", "/orders/"+second["order_id"].(string), nil)
+	resp = nil
+	decodeJSON(t, rr, &resp)
+	if v, ok := resp["queue_position"]; !ok || v != nil {
+		t.Fatalf("expected queue_position=null, got %v (present=%v)", v, ok)
+	}
+}
+
+func TestOrder_Get_History(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "seller", 0, []map[string]any{
+		{"symbol": "AAPL", "quantity": 100},
+	})
+	env.registerBroker(t, "buyer", 20000, nil)
+	askID := env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.00, 10)["order_id"].(string)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.00, 4)
+	env.doJSON(t, "DELETE", "/orders/"+askID, nil)
+
+	rr := env.doJSON(t, "GET", "/orders/"+askID, nil)
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if _, ok := resp["history"]; ok {
+		t.Fatal("expected no history without include=history")
+	}
+
+	rr = env.doJSON(t, "GET", "/orders/"+askID+"?include=history", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	resp = nil
+	decodeJSON(t, rr, &resp)
+	history, _ := resp["history"].([]any)
+	var types []string
+	for _, e := range history {
+		types = append(types, e.(map[string]any)["type"].(string))
+	}
+	if got := strings.Join(types, ","); got != "accepted,partially_filled,cancel_requested,cancelled" {
+		t.Fatalf("unexpected history: %s", got)
+	}
+	fill := history[1].(map[string]any)
+	if fill["price"] != 150.0 || fill["quantity"] != 4.0 || fill["trade_id"] == nil ||
+		fill["filled_quantity"] != 4.0 || fill["remaining_quantity"] != 6.0 {
+		t.Errorf("unexpected fill event: %v", fill)
+	}
+	request := history[2].(map[string]any)
+	if _, ok := request["quantity"]; ok {
+		t.Errorf("expected no quantity on the cancel request, got %v", request)
+	}
+	if resp["queue_position"] != nil {
+		t.Errorf("expected queue_position=null, got %v", resp["queue_position"])
+	}
+
+	// A market order's unfilled remainder is cancelled.
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.00, 3)
+	rr = env.doJSON(t, "POST", "/orders", map[string]any{
+		"type": "market", "broker_id": "buyer", "document_number": "MKT001",
+		"side": "bid", "symbol": "AAPL", "quantity": 5,
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	resp = nil
+	decodeJSON(t, rr, &resp)
+	rr = env.doJSON(t, "GET", "/orders/"+resp["order_id"].(string)+"?include=history", nil)
+	resp = nil
+	decodeJSON(t, rr, &resp)
+	if history, _ := resp["history"].([]any); len(history) != 3 ||
+		history[2].(map[string]any)["type"] != "cancelled" || history[2].(map[string]any)["quantity"] != 2.0 {
+		t.Errorf("expected accepted, partially_filled and cancelled for 2, got %v", resp["history"])
+	}
+}
+
+func TestOrder_Get_InvalidInclude(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 10000, nil)
+	orderID := env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 5)["order_id"].(string)
+
+	rr := env.doJSON(t, "GET", "/orders/"+orderID+"?include=trades", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if resp["error"] != "validation_error" {
+		t.Errorf("expected error=validation_error, got %v", resp["error"])
+	}
+}
+
 func TestOrder_Get_NotFound(t *testing.T) {
 	env := newTestEnv()
 	rr := env.doJSON(t, "GET", "/orders/nonexistent", nil)
@@ -502,8 +659,258 @@ func TestOrder_Cancel_NotFound(t *testing.T) {
 	}
 }
 
+func TestOrder_HaltAndResumeSymbol(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "seller", 0, []map[string]any{
+		{"symbol": "AAPL", "quantity": 100},
+	})
+	env.registerBroker(t, "buyer", 20000, nil)
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 100.0, 5)
+
+	rr := env.doJSON(t, "POST", "/stocks/AAPL/halt", map[string]any{"reason": "pending news"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("halt: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if resp["symbol"] != "AAPL" || resp["halted"] != true {
+		t.Errorf("unexpected halt response: %v", resp)
+	}
+
+	body := map[string]any{
+		"type":            "limit",
+		"broker_id":       "buyer",
+		"document_number": "D1",
+		"side":            "bid",
+		"symbol":          "AAPL",
+		"price":           100.0,
+		"quantity":        5,
+		"expires_at":      futureRFC3339(),
+	}
+	rr = env.doJSON(t, "POST", "/orders", body)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("submit while halted: expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	if resp["error"] != "symbol_halted" {
+		t.Fatalf("expected error=symbol_halted, got %v", resp["error"])
+	}
+
+	// The reason is optional, so a request without a body is accepted.
+	rr = env.doJSON(t, "POST", "/stocks/AAPL/resume", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("resume: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	if resp["halted"] != false {
+		t.Errorf("unexpected resume response: %v", resp)
+	}
+	order := env.submitLimitOrder(t, "buyer", "bid", "AAPL", 100.0, 5)
+	if order["status"] != "filled" {
+		t.Errorf("expected the order to fill after resuming, got status %v", order["status"])
+	}
+}
+
+func TestOrder_HaltSymbol_Errors(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "seller", 0, []map[string]any{
+		{"symbol": "AAPL", "quantity": 100},
+	})
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 100.0, 5)
+
+	if rr := env.doJSON(t, "POST", "/stocks/MSFT/halt", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := env.doJSON(t, "POST", "/stocks/MSFT/resume", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+	long := map[string]any{"reason": strings.Repeat("x", 257)}
+	if rr := env.doJSON(t, "POST", "/stocks/AAPL/halt", long); rr.Code != http.StatusBadRequest {
+		t.Errorf("long reason: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := env.doRaw(t, "POST", "/stocks/AAPL/halt", "application/json", "{"); rr.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
 // --- Stock Endpoints ---
 
+func TestMetrics(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "seller", 0, []map[string]any{
+		{"symbol": "AAPL", "quantity": 100},
+	})
+	env.registerBroker(t, "buyer", 1000, nil)
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 100.0, 5)
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 101.0, 5)
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 101.0, 5)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 100.0, 2)
+	env.doJSON(t, "GET", "/orders/nonexistent", nil)
+	rr := env.doJSON(t, "POST", "/orders", map[string]any{
+		"type":            "limit",
+		"broker_id":       "buyer",
+		"document_number": "D1",
+		"side":            "bid",
+		"symbol":          "AAPL",
+		"price":           100.0,
+		"quantity":        50,
+		"expires_at":      futureRFC3339(),
+	})
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// /metrics is public.
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain Content-Type, got %q", ct)
+	}
+
+	body := rr.Body.String()
+	for _, line := range []string{
+		`miniexchange_http_requests_total{method="POST",route="/orders",status="201"} 4`,
+		`miniexchange_http_requests_total{method="POST",route="/orders",status="409"} 1`,
+		`miniexchange_http_requests_total{method="GET",route="/orders/{order_id}",status="404"} 1`,
+		`miniexchange_http_request_duration_seconds_count{method="POST",route="/orders"} 5`,
+		// The balance check is part of matching, so the rejected order counts.
+		`miniexchange_matcher_duration_seconds_count{symbol="AAPL",type="limit"} 5`,
+		`miniexchange_orders_accepted_total{type="limit"} 4`,
+		`miniexchange_orders_rejected_total{reason="insufficient_balance"} 1`,
+		`miniexchange_trades_total{symbol="AAPL"} 1`,
+		`miniexchange_trade_notional_dollars_total{symbol="AAPL"} 200`,
+		`miniexchange_book_resting_orders{side="ask",symbol="AAPL"} 3`,
+		`miniexchange_book_price_levels{side="ask",symbol="AAPL"} 2`,
+		`miniexchange_book_resting_orders{side="bid",symbol="AAPL"} 0`,
+		`miniexchange_expiry_queue_length 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp, prop := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(prop)
+	})
+
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 1000, nil)
+
+	const traceID, callerSpanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	body := fmt.Sprintf(`{"type":"limit","broker_id":"buyer","document_number":"D1","side":"bid","symbol":"AAPL","price":10.0,"quantity":1,"expires_at":%q}`, futureRFC3339())
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+callerSpanID+"-01")
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	env.doJSON(t, "GET", "/nowhere", nil)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = s
+	}
+	server, ok := spans["POST /orders"]
+	if !ok {
+		t.Fatalf("missing POST /orders span, got %v", rec.Ended())
+	}
+	if server.SpanKind() != trace.SpanKindServer ||
+		server.SpanContext().TraceID().String() != traceID ||
+		server.Parent().SpanID().String() != callerSpanID {
+		t.Errorf("expected a server span continuing the caller's trace, got %+v", server.SpanContext())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range server.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/orders" || attrs["http.response.status_code"] != "201" {
+		t.Errorf("unexpected server span attributes: %v", attrs)
+	}
+	for _, name := range []string{"handler.parse_json", "service.submit_order"} {
+		if s, ok := spans[name]; !ok || s.Parent().SpanID() != server.SpanContext().SpanID() {
+			t.Errorf("expected %s under the server span", name)
+		}
+	}
+	if _, ok := spans["GET"]; !ok {
+		t.Error("expected an unmatched route's span to be named after the method alone")
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	log, err := audit.Open(t.TempDir(), 1<<20, 0)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer log.Close()
+	env := newAuditedTestEnv(service.NewAuditService(log, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	env.registerBroker(t, "b1", 10000, nil)
+	key := env.createAPIKey(t, "b1")
+
+	byBroker := env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 5)["order_id"].(string)
+	if rr := env.doAs(t, key, "DELETE", "/orders/"+byBroker, nil); rr.Code != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	byAdmin := env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 5)["order_id"].(string)
+	if rr := env.doJSON(t, "DELETE", "/orders/"+byAdmin, nil); rr.Code != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for orderID, initiator := range map[string]string{byBroker: "broker", byAdmin: "admin"} {
+		rr := env.doJSON(t, "GET", "/audit/orders/"+orderID, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			OrderID string           `json:"order_id"`
+			Records []map[string]any `json:"records"`
+		}
+		decodeJSON(t, rr, &resp)
+		if resp.OrderID != orderID || len(resp.Records) != 3 {
+			t.Fatalf("expected 3 records for %s, got %+v", orderID, resp)
+		}
+		cancelled := resp.Records[2]
+		if cancelled["event"] != "order.cancelled" || cancelled["initiator"] != initiator {
+			t.Errorf("expected order.cancelled by %s, got %v", initiator, cancelled)
+		}
+		if deltas, _ := cancelled["balance_deltas"].([]any); len(deltas) != 1 ||
+			deltas[0].(map[string]any)["reserved_cash"] != -500.0 {
+			t.Errorf("expected the reservation released, got %v", cancelled["balance_deltas"])
+		}
+	}
+
+	if rr := env.doAs(t, key, "GET", "/audit/orders/"+byBroker, nil); rr.Code != http.StatusForbidden {
+		t.Errorf("broker key: expected 403, got %d", rr.Code)
+	}
+	rr := env.doJSON(t, "GET", "/audit/orders/nonexistent", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown order: expected 404, got %d", rr.Code)
+	}
+	var errResp map[string]any
+	decodeJSON(t, rr, &errResp)
+	if errResp["error"] != "order_not_found" {
+		t.Errorf("expected error=order_not_found, got %v", errResp["error"])
+	}
+
+	// Without an audit log there is no audit trail to serve.
+	if rr := newTestEnv().doJSON(t, "GET", "/audit/orders/"+byBroker, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unaudited: expected 404, got %d", rr.Code)
+	}
+}
+
 func TestStock_GetPrice_Success(t *testing.T) {
 	env := newTestEnv()
 	env.registerBroker(t, "seller", 0, []map[string]any{
@@ -577,6 +984,75 @@ func TestStock_GetBook_InvalidDepth(t *testing.T) {
 	}
 }
 
+func TestStock_GetL3Book_Success(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+
+	first := env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 4)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 147.0, 2)
+
+	rr := env.doJSON(t, "GET", "/stocks/AAPL/book/l3", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Symbol string `json:"symbol"`
+		Bids   []struct {
+			Price         float64 `json:"price"`
+			TotalQuantity int64   `json:"total_quantity"`
+			Orders        []struct {
+				OrderRef  string `json:"order_ref"`
+				Quantity  int64  `json:"quantity"`
+				CreatedAt string `json:"created_at"`
+			} `json:"orders"`
+		} `json:"bids"`
+		Asks       []any  `json:"asks"`
+		SnapshotAt string `json:"snapshot_at"`
+	}
+	decodeJSON(t, rr, &resp)
+	if resp.Symbol != "AAPL" || resp.Asks == nil || len(resp.Asks) != 0 {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+	if len(resp.Bids) != 2 {
+		t.Fatalf("expected 2 bid levels, got %d", len(resp.Bids))
+	}
+	best := resp.Bids[0]
+	if best.Price != 148.0 || best.TotalQuantity != 14 || len(best.Orders) != 2 {
+		t.Fatalf("unexpected best level: %+v", best)
+	}
+	if best.Orders[0].Quantity != 10 || best.Orders[1].Quantity != 4 {
+		t.Fatalf("expected quantities [10, 4], got %+v", best.Orders)
+	}
+	if best.Orders[0].OrderRef == "" || best.Orders[0].OrderRef == first["order_id"] {
+		t.Fatalf("expected anonymised order ref, got %q", best.Orders[0].OrderRef)
+	}
+	if best.Orders[0].CreatedAt == "" {
+		t.Fatal("expected created_at")
+	}
+	if strings.Contains(rr.Body.String(), "b1") {
+		t.Fatalf("L3 book leaks broker ID: %s", rr.Body.String())
+	}
+}
+
+func TestStock_GetL3Book_Errors(t *testing.T) {
+	env := newTestEnv()
+
+	rr := env.doJSON(t, "GET", "/stocks/NOPE/book/l3", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	env.registerBroker(t, "b1", 10000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 100.0, 1)
+	for _, q := range []string{"depth=0", "depth=51", "depth=abc"} {
+		rr := env.doJSON(t, "GET", "/stocks/AAPL/book/l3?"+q, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", q, rr.Code, rr.Body.String())
+		}
+	}
+}
+
 func TestStock_GetQuote_Success(t *testing.T) {
 	env := newTestEnv()
 	env.registerBroker(t, "seller", 0, []map[string]any{
@@ -653,46 +1129,343 @@ func TestWebhook_List_Success(t *testing.T) {
 	}
 }
 
-func TestWebhook_Delete_Success(t *testing.T) {
+func TestWebhook_Upsert_FiltersAndSecondURL(t *testing.T) {
 	env := newTestEnv()
 	env.registerBroker(t, "b1", 1000, nil)
 
-	body := map[string]any{
+	rr := env.doJSON(t, "POST", "/webhooks", map[string]any{
 		"broker_id": "b1",
-		"url":       "https://example.com/hook",
+		"url":       "https://example.com/large-aapl",
 		"events":    []string{"trade.executed"},
+		"filters":   map[string]any{"symbols": []string{"AAPL"}, "min_notional": 10000.00},
+		"version":   1,
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	wh := resp["webhooks"].([]any)[0].(map[string]any)
+	filters := wh["filters"].(map[string]any)
+	if wh["status"] != "active" || wh["version"] != 1.0 {
+		t.Errorf("unexpected status or version: %v", wh)
+	}
+	if symbols := filters["symbols"].([]any); len(symbols) != 1 || symbols[0] != "AAPL" ||
+		filters["side"] != nil || filters["min_notional"] != 10000.0 {
+		t.Errorf("unexpected filters: %v", filters)
 	}
-	rr := env.doJSON(t, "POST", "/webhooks", body)
-	var createResp map[string]any
-	decodeJSON(t, rr, &createResp)
-	webhooks := createResp["webhooks"].([]any)
-	whID := webhooks[0].(map[string]any)["webhook_id"].(string)
 
-	rr = env.doJSON(t, "DELETE", "/webhooks/"+whID, nil)
-	if rr.Code != http.StatusNoContent {
-		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	// A second URL for the same event is a second subscription.
+	rr = env.doJSON(t, "POST", "/webhooks", map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/all",
+		"events":    []string{"trade.executed"},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a second URL, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	filters = resp["webhooks"].([]any)[0].(map[string]any)["filters"].(map[string]any)
+	if symbols, ok := filters["symbols"].([]any); !ok || len(symbols) != 0 {
+		t.Errorf("expected an empty symbols list, got %v", filters["symbols"])
 	}
-}
 
-func TestWebhook_Delete_NotFound(t *testing.T) {
-	env := newTestEnv()
-	rr := env.doJSON(t, "DELETE", "/webhooks/nonexistent", nil)
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d", rr.Code)
+	rr = env.doJSON(t, "GET", "/webhooks?broker_id=b1", nil)
+	decodeJSON(t, rr, &resp)
+	if got := len(resp["webhooks"].([]any)); got != 2 {
+		t.Errorf("expected 2 webhooks, got %d", got)
 	}
-}
 
-// --- Matching Scenarios ---
+	rr = env.doJSON(t, "POST", "/webhooks", map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hook",
+		"events":    []string{"trade.executed"},
+		"filters":   map[string]any{"side": "buy"},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid side filter: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
 
-func TestMatch_SamePrice(t *testing.T) {
+func TestWebhook_Update(t *testing.T) {
 	env := newTestEnv()
-	env.registerBroker(t, "seller", 0, []map[string]any{
-		{"symbol": "AAPL", "quantity": 100},
-	})
-	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "b1", 1000, nil)
 
-	// Ask at 150, then bid at 150 → trade at 150.
-	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	rr := env.doJSON(t, "POST", "/webhooks", map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hook",
+		"events":    []string{"trade.executed"},
+	})
+	var createResp map[string]any
+	decodeJSON(t, rr, &createResp)
+	whID := createResp["webhooks"].([]any)[0].(map[string]any)["webhook_id"].(string)
+
+	rr = env.doJSON(t, "PATCH", "/webhooks/"+whID, map[string]any{
+		"status":  "paused",
+		"filters": map[string]any{"side": "ask"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if resp["status"] != "paused" || resp["filters"].(map[string]any)["side"] != "ask" {
+		t.Errorf("unexpected webhook after pausing: %v", resp)
+	}
+	if _, ok := resp["signing_secret"]; ok {
+		t.Error("PATCH must not return the signing secret")
+	}
+
+	rr = env.doJSON(t, "PATCH", "/webhooks/"+whID, map[string]any{"status": "active"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	decodeJSON(t, rr, &resp)
+	if resp["status"] != "active" || resp["filters"].(map[string]any)["side"] != "ask" {
+		t.Errorf("unexpected webhook after resuming: %v", resp)
+	}
+
+	if rr := env.doJSON(t, "PATCH", "/webhooks/"+whID, map[string]any{"status": "off"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid status: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := env.doJSON(t, "PATCH", "/webhooks/"+whID, map[string]any{"url": "https://example.com/other"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := env.doJSON(t, "PATCH", "/webhooks/nonexistent", map[string]any{"status": "paused"}); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown webhook: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWebhook_Delete_Success(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	body := map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hook",
+		"events":    []string{"trade.executed"},
+	}
+	rr := env.doJSON(t, "POST", "/webhooks", body)
+	var createResp map[string]any
+	decodeJSON(t, rr, &createResp)
+	webhooks := createResp["webhooks"].([]any)
+	whID := webhooks[0].(map[string]any)["webhook_id"].(string)
+
+	rr = env.doJSON(t, "DELETE", "/webhooks/"+whID, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWebhook_SigningSecret(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	body := map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hook",
+		"events":    []string{"trade.executed"},
+	}
+	rr := env.doJSON(t, "POST", "/webhooks", body)
+	var created webhookListResponse
+	decodeJSON(t, rr, &created)
+	secret := created.Webhooks[0].SigningSecret
+	if !strings.HasPrefix(secret, "whsec_") {
+		t.Fatalf("expected a signing_secret on creation, got %q", secret)
+	}
+	whID := created.Webhooks[0].WebhookID
+
+	// Neither re-registration nor listing shows it again.
+	rr = env.doJSON(t, "POST", "/webhooks", body)
+	if strings.Contains(rr.Body.String(), "signing_secret") {
+		t.Errorf("re-registration returned the secret: %s", rr.Body.String())
+	}
+	rr = env.doJSON(t, "GET", "/webhooks?broker_id=b1", nil)
+	if strings.Contains(rr.Body.String(), "signing_secret") {
+		t.Errorf("listing returned the secret: %s", rr.Body.String())
+	}
+
+	// Rotation returns a new one.
+	rr = env.doJSON(t, "POST", "/webhooks/"+whID+"/rotate-secret", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("rotate: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var rotated webhookResponse
+	decodeJSON(t, rr, &rotated)
+	if rotated.WebhookID != whID || rotated.SigningSecret == "" || rotated.SigningSecret == secret {
+		t.Errorf("unexpected rotate response: %+v", rotated)
+	}
+
+	rr = env.doJSON(t, "POST", "/webhooks/nonexistent/rotate-secret", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("rotate unknown webhook: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestWebhook_Deliveries(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	rr := env.doJSON(t, "POST", "/webhooks", map[string]any{
+		"broker_id": "b1",
+		"url":       "https://example.com/hook",
+		"events":    []string{"order.cancelled"},
+	})
+	var created webhookListResponse
+	decodeJSON(t, rr, &created)
+	whID := created.Webhooks[0].WebhookID
+
+	lastAttempt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	env.deliveryStore.Save(&domain.WebhookDelivery{
+		DeliveryID:     "d-1",
+		WebhookID:      whID,
+		BrokerID:       "b1",
+		Event:          "order.cancelled",
+		Sequence:       7,
+		Payload:        []byte(`{"event":"order.cancelled","sequence":7}`),
+		Status:         domain.DeliveryStatusFailed,
+		CreatedAt:      lastAttempt.Add(-time.Hour),
+		QueuedAt:       lastAttempt.Add(-time.Hour),
+		Attempts:       8,
+		LastAttemptAt:  &lastAttempt,
+		LastStatusCode: 503,
+		LastError:      "unexpected status 503",
+		CompletedAt:    &lastAttempt,
+		History: []domain.DeliveryAttempt{{
+			AttemptedAt:     lastAttempt,
+			URL:             "https://example.com/hook",
+			StatusCode:      503,
+			Latency:         1500 * time.Microsecond,
+			Error:           "unexpected status 503",
+			ResponseSnippet: "try later",
+		}},
+	})
+
+	rr = env.doJSON(t, "GET", "/webhooks/"+whID+"/deliveries?status=failed", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var list deliveryListResponse
+	decodeJSON(t, rr, &list)
+	if len(list.Deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(list.Deliveries))
+	}
+	d := list.Deliveries[0]
+	if d.DeliveryID != "d-1" || d.Sequence != 7 || d.Status != "failed" || d.Attempts != 8 ||
+		d.LastStatusCode == nil || *d.LastStatusCode != 503 ||
+		d.LastAttemptAt == nil || *d.LastAttemptAt != "2026-01-02T03:04:05Z" ||
+		d.NextAttemptAt != nil || string(d.Payload) != `{"event":"order.cancelled","sequence":7}` {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+	if len(d.History) != 1 {
+		t.Fatalf("expected 1 attempt in history, got %d", len(d.History))
+	}
+	if a := d.History[0]; a.AttemptedAt != "2026-01-02T03:04:05Z" || a.URL != "https://example.com/hook" ||
+		a.StatusCode == nil || *a.StatusCode != 503 || a.LatencyMs != 1.5 ||
+		a.Error == nil || *a.Error != "unexpected status 503" || a.ResponseSnippet != "try later" {
+		t.Errorf("unexpected attempt: %+v", a)
+	}
+
+	rr = env.doJSON(t, "GET", "/webhooks/"+whID+"/deliveries?status=bogus", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid status: expected 400, got %d", rr.Code)
+	}
+	rr = env.doJSON(t, "GET", "/webhooks/nonexistent/deliveries", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown webhook: expected 404, got %d", rr.Code)
+	}
+
+	// Redelivery queues the delivery again; it cannot be redelivered twice.
+	rr = env.doJSON(t, "POST", "/webhooks/"+whID+"/deliveries/d-1/redeliver", nil)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("redeliver: expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var redelivered deliveryResponse
+	decodeJSON(t, rr, &redelivered)
+	if redelivered.Status != "pending" || redelivered.Attempts != 0 {
+		t.Errorf("unexpected redelivery: %+v", redelivered)
+	}
+	rr = env.doJSON(t, "POST", "/webhooks/"+whID+"/deliveries/d-1/redeliver", nil)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("redeliver pending: expected 409, got %d", rr.Code)
+	}
+	rr = env.doJSON(t, "POST", "/webhooks/"+whID+"/deliveries/nonexistent/redeliver", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("redeliver unknown delivery: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestWebhook_Ping(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 1000, nil)
+
+	// Nothing listens on port 1, so the ping fails without leaving the host.
+	rr := env.doJSON(t, "POST", "/webhooks", map[string]any{
+		"broker_id": "b1",
+		"url":       "https://127.0.0.1:1/hook",
+		"events":    []string{"trade.executed"},
+	})
+	var created webhookListResponse
+	decodeJSON(t, rr, &created)
+	whID := created.Webhooks[0].WebhookID
+
+	rr = env.doJSON(t, "POST", "/webhooks/"+whID+"/ping", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ping: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var ping deliveryResponse
+	decodeJSON(t, rr, &ping)
+	if ping.Event != "webhook.ping" || ping.Status != "failed" || ping.Attempts != 1 ||
+		ping.LastError == nil || ping.LastStatusCode != nil || len(ping.History) != 1 {
+		t.Errorf("unexpected ping: %+v", ping)
+	}
+	var payload struct {
+		Event string `json:"event"`
+		Data  struct {
+			WebhookID       string `json:"webhook_id"`
+			SubscribedEvent string `json:"subscribed_event"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(ping.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Event != "webhook.ping" || payload.Data.WebhookID != whID || payload.Data.SubscribedEvent != "trade.executed" {
+		t.Errorf("unexpected payload: %s", ping.Payload)
+	}
+
+	// The ping shows up in the webhook's deliveries.
+	rr = env.doJSON(t, "GET", "/webhooks/"+whID+"/deliveries", nil)
+	var list deliveryListResponse
+	decodeJSON(t, rr, &list)
+	if len(list.Deliveries) != 1 || list.Deliveries[0].DeliveryID != ping.DeliveryID {
+		t.Errorf("unexpected deliveries: %+v", list.Deliveries)
+	}
+
+	rr = env.doJSON(t, "POST", "/webhooks/nonexistent/ping", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown webhook: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestWebhook_Delete_NotFound(t *testing.T) {
+	env := newTestEnv()
+	rr := env.doJSON(t, "DELETE", "/webhooks/nonexistent", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+// --- Matching Scenarios ---
+
+func TestMatch_SamePrice(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "seller", 0, []map[string]any{
+		{"symbol": "AAPL", "quantity": 100},
+	})
+	env.registerBroker(t, "buyer", 50000, nil)
+
+	// Ask at 150, then bid at 150 → trade at 150.
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
 	resp := env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 10)
 
 	if resp["status"] != "filled" {
@@ -882,3 +1655,432 @@ func TestResponseFormat_TimestampRFC3339(t *testing.T) {
 		t.Fatalf("expires_at not RFC 3339: %s", expiresAt)
 	}
 }
+
+// --- Market Data WebSocket ---
+
+// dialMarketData opens a WebSocket to /ws/market-data on a test server.
+func (env *testEnv) dialMarketData(t *testing.T) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(env.router)
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/market-data"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial market data: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWSJSON reads one JSON message with a short deadline.
+func readWSJSON(t *testing.T, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg map[string]any
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read market data message: %v", err)
+	}
+	return msg
+}
+
+func TestMarketData_SubscribeDepth_SnapshotThenUpdate(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+
+	conn := env.dialMarketData(t)
+	if err := conn.WriteJSON(map[string]string{"op": "subscribe", "channel": "depth", "symbol": "AAPL"}); err != nil {
+		t.Fatalf("write subscribe: %v", err)
+	}
+
+	snap := readWSJSON(t, conn)
+	if snap["type"] != "snapshot" || snap["channel"] != "depth" || snap["seq"] != 1.0 {
+		t.Fatalf("unexpected snapshot header: %v", snap)
+	}
+	bids := snap["data"].(map[string]any)["bids"].([]any)
+	if len(bids) != 1 || bids[0].(map[string]any)["price"] != 148.0 {
+		t.Fatalf("unexpected snapshot bids: %v", bids)
+	}
+
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 5)
+
+	upd := readWSJSON(t, conn)
+	if upd["type"] != "update" || upd["seq"] != 2.0 {
+		t.Fatalf("unexpected update header: %v", upd)
+	}
+	level := upd["data"].(map[string]any)["bids"].([]any)[0].(map[string]any)
+	if level["total_quantity"] != 15.0 || level["order_count"] != 2.0 {
+		t.Fatalf("unexpected level update: %v", level)
+	}
+}
+
+func TestMarketData_SubscribeTrades_ReceivesTrade(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "seller", 0, []map[string]any{{"symbol": "AAPL", "quantity": 100}})
+
+	conn := env.dialMarketData(t)
+	if err := conn.WriteJSON(map[string]string{"op": "subscribe", "channel": "trades", "symbol": "AAPL"}); err != nil {
+		t.Fatalf("write subscribe: %v", err)
+	}
+	if snap := readWSJSON(t, conn); snap["type"] != "snapshot" {
+		t.Fatalf("expected snapshot, got %v", snap)
+	}
+
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 10)
+
+	upd := readWSJSON(t, conn)
+	trades := upd["data"].(map[string]any)["trades"].([]any)
+	if len(trades) != 1 {
+		t.Fatalf("expected 1 trade, got %v", trades)
+	}
+	trade := trades[0].(map[string]any)
+	if trade["price"] != 150.0 || trade["quantity"] != 10.0 || trade["aggressor_side"] != "bid" {
+		t.Fatalf("unexpected trade: %v", trade)
+	}
+}
+
+func TestMarketData_InvalidCommands(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+	conn := env.dialMarketData(t)
+
+	tests := []struct {
+		cmd       map[string]string
+		wantError string
+	}{
+		{map[string]string{"op": "subscribe", "channel": "depth", "symbol": "MSFT"}, "symbol_not_found"},
+		{map[string]string{"op": "subscribe", "channel": "candles", "symbol": "AAPL"}, "validation_error"},
+		{map[string]string{"op": "publish", "channel": "depth", "symbol": "AAPL"}, "validation_error"},
+	}
+	for _, tt := range tests {
+		if err := conn.WriteJSON(tt.cmd); err != nil {
+			t.Fatalf("write command: %v", err)
+		}
+		msg := readWSJSON(t, conn)
+		if msg["type"] != "error" || msg["error"] != tt.wantError {
+			t.Errorf("command %v: got %v, want error %s", tt.cmd, msg, tt.wantError)
+		}
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatalf("write raw: %v", err)
+	}
+	if msg := readWSJSON(t, conn); msg["error"] != "invalid_request" {
+		t.Errorf("expected invalid_request, got %v", msg)
+	}
+}
+
+// --- Broker Event Stream (SSE) ---
+
+// sseEvent is one parsed Server-Sent Event.
+type sseEvent struct {
+	id    string
+	event string
+	data  map[string]any
+}
+
+// openEventStream connects to /brokers/{broker_id}/events on a test server.
+func (env *testEnv) openEventStream(t *testing.T, brokerID, lastEventID string) *bufio.Reader {
+	t.Helper()
+	srv := httptest.NewServer(env.router)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/brokers/"+brokerID+"/events", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readSSEEvent reads lines until a complete event, skipping comments.
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	type result struct {
+		ev  sseEvent
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var ev sseEvent
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && ev.event != "":
+				done <- result{ev: ev}
+				return
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data); err != nil {
+					done <- result{err: err}
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("read event: %v", res.err)
+		}
+		return res.ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return sseEvent{}
+	}
+}
+
+func TestEventStream_OrderAndTradeEvents(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "seller", 0, []map[string]any{{"symbol": "AAPL", "quantity": 100}})
+
+	stream := env.openEventStream(t, "seller", "")
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 4)
+
+	accepted := readSSEEvent(t, stream)
+	if accepted.id != "1" || accepted.event != "order.accepted" {
+		t.Fatalf("got id=%s event=%s, want 1/order.accepted", accepted.id, accepted.event)
+	}
+	if data := accepted.data["data"].(map[string]any); data["status"] != "pending" || data["broker_id"] != "seller" {
+		t.Errorf("unexpected order.accepted data: %v", data)
+	}
+
+	trade := readSSEEvent(t, stream)
+	if trade.id != "2" || trade.event != "trade.executed" {
+		t.Fatalf("got id=%s event=%s, want 2/trade.executed", trade.id, trade.event)
+	}
+	data := trade.data["data"].(map[string]any)
+	if data["trade_quantity"] != 4.0 || data["order_remaining_quantity"] != 6.0 {
+		t.Errorf("unexpected trade.executed data: %v", data)
+	}
+}
+
+func TestEventStream_ResumeWithLastEventID(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	order := env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+	if rr := env.doJSON(t, "DELETE", "/orders/"+order["order_id"].(string), nil); rr.Code != http.StatusOK {
+		t.Fatalf("cancel order: expected 200, got %d", rr.Code)
+	}
+
+	stream := env.openEventStream(t, "b1", "1")
+	ev := readSSEEvent(t, stream)
+	if ev.id != "2" || ev.event != "order.cancelled" {
+		t.Fatalf("got id=%s event=%s, want 2/order.cancelled", ev.id, ev.event)
+	}
+
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 147.0, 1)
+	if ev := readSSEEvent(t, stream); ev.id != "3" || ev.event != "order.accepted" {
+		t.Fatalf("got id=%s event=%s, want 3/order.accepted", ev.id, ev.event)
+	}
+}
+
+func TestEventStream_Errors(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+
+	rr := env.doJSON(t, "GET", "/brokers/ghost/events", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown broker: expected 404, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/brokers/b1/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	req.Header.Set("Authorization", "Bearer "+testAdminKey)
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID: expected 400, got %d", rr.Code)
+	}
+}
+
+// --- Trade Tape ---
+
+func TestStock_GetTrades_Success(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "seller", 0, []map[string]any{{"symbol": "AAPL", "quantity": 100}})
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 4)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 3)
+
+	rr := env.doJSON(t, "GET", "/stocks/AAPL/trades?limit=1", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var page map[string]any
+	decodeJSON(t, rr, &page)
+	trades := page["trades"].([]any)
+	if len(trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(trades))
+	}
+	first := trades[0].(map[string]any)
+	if first["quantity"] != 4.0 || first["aggressor_side"] != "bid" || first["price"] != 150.0 {
+		t.Errorf("unexpected trade: %v", first)
+	}
+	if _, ok := first["broker_id"]; ok {
+		t.Error("tape must not expose broker_id")
+	}
+	cursor, ok := page["next_cursor"].(string)
+	if !ok {
+		t.Fatalf("expected next_cursor, got %v", page["next_cursor"])
+	}
+
+	rr = env.doJSON(t, "GET", "/stocks/AAPL/trades?limit=1&cursor="+cursor, nil)
+	decodeJSON(t, rr, &page)
+	trades = page["trades"].([]any)
+	if len(trades) != 1 || trades[0].(map[string]any)["quantity"] != 3.0 {
+		t.Fatalf("unexpected second page: %v", trades)
+	}
+	if page["next_cursor"] != nil {
+		t.Errorf("expected null next_cursor on last page, got %v", page["next_cursor"])
+	}
+}
+
+func TestStock_GetTrades_ValidationErrors(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+
+	for _, path := range []string{
+		"/stocks/AAPL/trades?from=yesterday",
+		"/stocks/AAPL/trades?limit=abc",
+		"/stocks/AAPL/trades?limit=0",
+		"/stocks/AAPL/trades?cursor=bogus",
+	} {
+		rr := env.doJSON(t, "GET", path, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rr.Code)
+		}
+	}
+	if rr := env.doJSON(t, "GET", "/stocks/MSFT/trades", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: expected 404, got %d", rr.Code)
+	}
+}
+
+// --- Candles ---
+
+func TestStock_GetCandles_Success(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "seller", 0, []map[string]any{{"symbol": "AAPL", "quantity": 100}})
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 4)
+
+	rr := env.doJSON(t, "GET", "/stocks/AAPL/candles?interval=1h", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	decodeJSON(t, rr, &resp)
+	if resp["interval"] != "1h" {
+		t.Errorf("expected interval 1h, got %v", resp["interval"])
+	}
+	candles := resp["candles"].([]any)
+	if len(candles) != 1 {
+		t.Fatalf("expected 1 candle, got %d", len(candles))
+	}
+	c := candles[0].(map[string]any)
+	if c["open"] != 150.0 || c["close"] != 150.0 || c["volume"] != 4.0 || c["vwap"] != 150.0 || c["trade_count"] != 1.0 {
+		t.Errorf("unexpected candle: %v", c)
+	}
+	if c["partial"] != true {
+		t.Errorf("expected the current bucket to be partial, got %v", c["partial"])
+	}
+}
+
+func TestStock_GetCandles_ValidationErrors(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "b1", 50000, nil)
+	env.submitLimitOrder(t, "b1", "bid", "AAPL", 148.0, 10)
+
+	for _, path := range []string{
+		"/stocks/AAPL/candles",
+		"/stocks/AAPL/candles?interval=3m",
+		"/stocks/AAPL/candles?interval=1m&from=yesterday",
+		"/stocks/AAPL/candles?interval=1m&fill_empty=yes",
+	} {
+		rr := env.doJSON(t, "GET", path, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rr.Code)
+		}
+	}
+	if rr := env.doJSON(t, "GET", "/stocks/MSFT/candles?interval=1m", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: expected 404, got %d", rr.Code)
+	}
+}
+
+// --- Ticker ---
+
+func TestStock_Ticker_SingleAndAll(t *testing.T) {
+	env := newTestEnv()
+	env.registerBroker(t, "buyer", 50000, nil)
+	env.registerBroker(t, "seller", 0, []map[string]any{{"symbol": "AAPL", "quantity": 100}})
+	env.submitLimitOrder(t, "seller", "ask", "AAPL", 150.0, 10)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 150.0, 4)
+	env.submitLimitOrder(t, "buyer", "bid", "AAPL", 148.0, 2)
+
+	rr := env.doJSON(t, "GET", "/stocks/AAPL/ticker", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var ticker map[string]any
+	decodeJSON(t, rr, &ticker)
+	if ticker["open"] != 150.0 || ticker["last"] != 150.0 || ticker["change"] != 0.0 || ticker["change_percent"] != 0.0 {
+		t.Errorf("unexpected prices: %v", ticker)
+	}
+	if ticker["volume"] != 4.0 || ticker["notional"] != 600.0 || ticker["trade_count"] != 1.0 {
+		t.Errorf("unexpected totals: %v", ticker)
+	}
+	if bid := ticker["best_bid"].(map[string]any); bid["price"] != 148.0 {
+		t.Errorf("unexpected best bid: %v", bid)
+	}
+	if ask := ticker["best_ask"].(map[string]any); ask["price"] != 150.0 || ask["total_quantity"] != 6.0 {
+		t.Errorf("unexpected best ask: %v", ask)
+	}
+
+	rr = env.doJSON(t, "GET", "/stocks/ticker", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var list map[string]any
+	decodeJSON(t, rr, &list)
+	if tickers := list["tickers"].([]any); len(tickers) != 1 || tickers[0].(map[string]any)["symbol"] != "AAPL" {
+		t.Errorf("unexpected ticker list: %v", tickers)
+	}
+
+	if rr := env.doJSON(t, "GET", "/stocks/MSFT/ticker", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a single frame.
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long the connection may stay silent before it is
+	// considered dead. Pings are sent at wsPingPeriod to keep it alive.
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = 50 * time.Second
+	// wsMaxMessageSize bounds client → server command frames.
+	wsMaxMessageSize = 4096
+)
+
+// MarketDataHandler serves the real-time market data WebSocket.
+type MarketDataHandler struct {
+	marketDataSvc *service.MarketDataService
+	upgrader      websocket.Upgrader
+}
+
+// NewMarketDataHandler creates a new MarketDataHandler.
+func NewMarketDataHandler(marketDataSvc *service.MarketDataService) *MarketDataHandler {
+	return &MarketDataHandler{
+		marketDataSvc: marketDataSvc,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			CheckOrigin:     func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// marketDataCommand is a client → server message.
+type marketDataCommand struct {
+	Op      string `json:"op"`
+	Channel string `json:"channel"`
+	Symbol  string `json:"symbol"`
+}
+
+// marketDataEnvelope is a server → client snapshot or update.
+type marketDataEnvelope struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	Symbol    string `json:"symbol"`
+	Seq       uint64 `json:"seq"`
+	Timestamp string `json:"timestamp"`
+	Data      any    `json:"data"`
+}
+
+// marketTradeResponse is a single trade on the trades channel.
+type marketTradeResponse struct {
+	TradeID       string  `json:"trade_id"`
+	Price         float64 `json:"price"`
+	Quantity      int64   `json:"quantity"`
+	AggressorSide string  `json:"aggressor_side"`
+	ExecutedAt    string  `json:"executed_at"`
+}
+
+// tradesData is the payload of a trades channel message.
+type tradesData struct {
+	Trades []marketTradeResponse `json:"trades"`
+}
+
+// topOfBookData is the payload of a top_of_book channel message.
+type topOfBookData struct {
+	BestBid *bookLevelResponse `json:"best_bid"`
+	BestAsk *bookLevelResponse `json:"best_ask"`
+}
+
+// depthData is the payload of a depth channel message.
+type depthData struct {
+	Bids []bookLevelResponse `json:"bids"`
+	Asks []bookLevelResponse `json:"asks"`
+}
+
+// marketDataAck confirms an unsubscribe.
+type marketDataAck struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Symbol  string `json:"symbol"`
+}
+
+// marketDataError reports a rejected command without closing the connection.
+type marketDataError struct {
+	Type    string `json:"type"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Stream handles GET /ws/market-data. After the upgrade, clients send
+// {"op":"subscribe"|"unsubscribe","channel":...,"symbol":...} commands and
+// receive a snapshot followed by sequenced updates for each subscription.
+// Connections that cannot keep up are closed with a policy-violation frame.
+func (h *MarketDataHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error response.
+		return
+	}
+	defer conn.Close()
+
+	sub := h.marketDataSvc.NewSubscriber()
+	defer h.marketDataSvc.Close(sub)
+
+	// Replies to commands are produced by the reader and written by the
+	// writer loop below, which owns the connection's write side.
+	replies := make(chan any, 16)
+	readerDone := make(chan struct{})
+	go h.readCommands(conn, sub, replies, readerDone)
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-sub.Messages():
+			if err := writeWSJSON(conn, buildMarketDataEnvelope(msg)); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if err := writeWSJSON(conn, reply); err != nil {
+				return
+			}
+		case <-sub.Done():
+			reason := "subscriber closed"
+			if errors.Is(sub.Err(), domain.ErrSlowConsumer) {
+				reason = domain.ErrSlowConsumer.Error()
+			}
+			_ = conn.WriteControl(websocket.CloseMessage, // best effort; the connection is closed right after
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+				time.Now().Add(wsWriteWait))
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-readerDone:
+			return
+		}
+	}
+}
+
+// readCommands reads client commands until the connection fails or closes.
+func (h *MarketDataHandler) readCommands(conn *websocket.Conn, sub *service.Subscriber, replies chan<- any, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait)) // only fails on a closed connection
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd marketDataCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			if !sendReply(replies, sub, marketDataError{
+				Type:    "error",
+				Error:   "invalid_request",
+				Message: "Commands must be JSON objects with op, channel, and symbol",
+			}) {
+				return
+			}
+			continue
+		}
+
+		channel := service.MarketDataChannel(cmd.Channel)
+		var reply any
+		switch cmd.Op {
+		case "subscribe":
+			// The snapshot itself acknowledges a successful subscription.
+			if err := h.marketDataSvc.Subscribe(sub, cmd.Symbol, channel); err != nil {
+				reply = buildMarketDataError(err)
+			}
+		case "unsubscribe":
+			h.marketDataSvc.Unsubscribe(sub, cmd.Symbol, channel)
+			reply = marketDataAck{Type: "unsubscribed", Channel: cmd.Channel, Symbol: cmd.Symbol}
+		default:
+			reply = marketDataError{
+				Type:    "error",
+				Error:   "validation_error",
+				Message: "Unknown op: " + cmd.Op + ". Must be one of: subscribe, unsubscribe",
+			}
+		}
+		if reply != nil && !sendReply(replies, sub, reply) {
+			return
+		}
+	}
+}
+
+// sendReply queues a reply for the writer, giving up if the subscriber
+// has been closed in the meantime.
+func sendReply(replies chan<- any, sub *service.Subscriber, reply any) bool {
+	select {
+	case replies <- reply:
+		return true
+	case <-sub.Done():
+		return false
+	}
+}
+
+func writeWSJSON(conn *websocket.Conn, v any) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(v)
+}
+
+// buildMarketDataEnvelope converts a service message into its wire format.
+func buildMarketDataEnvelope(msg *service.MarketDataMessage) marketDataEnvelope {
+	env := marketDataEnvelope{
+		Type:      msg.Type,
+		Channel:   string(msg.Channel),
+		Symbol:    msg.Symbol,
+		Seq:       msg.Seq,
+		Timestamp: msg.Timestamp.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+
+	switch msg.Channel {
+	case service.ChannelTrades:
+		trades := make([]marketTradeResponse, len(msg.Trades))
+		for i, t := range msg.Trades {
+			trades[i] = marketTradeResponse{
+				TradeID:       t.TradeID,
+				Price:         domain.CentsToDollars(t.Price),
+				Quantity:      t.Quantity,
+				AggressorSide: string(t.AggressorSide),
+				ExecutedAt:    t.ExecutedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			}
+		}
+		env.Data = tradesData{Trades: trades}
+	case service.ChannelTopOfBook:
+		env.Data = topOfBookData{
+			BestBid: buildOptionalLevel(msg.BestBid),
+			BestAsk: buildOptionalLevel(msg.BestAsk),
+		}
+	case service.ChannelDepth:
+		env.Data = depthData{
+			Bids: buildBookLevels(msg.Bids),
+			Asks: buildBookLevels(msg.Asks),
+		}
+	}
+	return env
+}
+
+// buildMarketDataError maps a Subscribe error to an in-band error message.
+func buildMarketDataError(err error) marketDataError {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return marketDataError{Type: "error", Error: "validation_error", Message: validationErr.Message}
+	case errors.Is(err, domain.ErrSymbolNotFound):
+		return marketDataError{Type: "error", Error: "symbol_not_found", Message: err.Error()}
+	default:
+		return marketDataError{Type: "error", Error: "internal_error", Message: "An unexpected error occurred"}
+	}
+}
+
+func buildBookLevels(levels []service.BookPriceLevel) []bookLevelResponse {
+	result := make([]bookLevelResponse, len(levels))
+	for i, l := range levels {
+		result[i] = bookLevelResponse{
+			Price:         domain.CentsToDollars(l.Price),
+			TotalQuantity: l.TotalQuantity,
+			OrderCount:    l.OrderCount,
+		}
+	}
+	return result
+}
+
+func buildOptionalLevel(l *service.BookPriceLevel) *bookLevelResponse {
+	if l == nil {
+		return nil
+	}
+	return &bookLevelResponse{
+		Price:         domain.CentsToDollars(l.Price),
+		TotalQuantity: l.TotalQuantity,
+		OrderCount:    l.OrderCount,
+	}
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
@@ -55,6 +56,45 @@ type limitOrderResponse struct {
 	Trades            []tradeResponse `json:"trades"`
 }
 
+// queuePositionResponse is the JSON representation of a resting order's
+// place in its price level's queue.
+type queuePositionResponse struct {
+	OrdersAhead   int   `json:"orders_ahead"`
+	QuantityAhead int64 `json:"quantity_ahead"`
+}
+
+// limitOrderLookupResponse is the GET /orders/{order_id} response for limit
+// orders. QueuePosition is null when the order is not resting on the book.
+// History is present only with ?include=history.
+type limitOrderLookupResponse struct {
+	limitOrderResponse
+	QueuePosition *queuePositionResponse `json:"queue_position"`
+	History       []orderEventResponse   `json:"history,omitempty"`
+}
+
+// marketOrderLookupResponse is the GET /orders/{order_id} response for
+// market orders. History is present only with ?include=history.
+type marketOrderLookupResponse struct {
+	marketOrderResponse
+	History []orderEventResponse `json:"history,omitempty"`
+}
+
+// orderEventResponse is one entry of an order's history. Quantity is the
+// shares filled, cancelled or expired, the quantity accepted, or an
+// amendment's new quantity; price is a fill's price or an amendment's new
+// price. The filled and remaining quantities are the order's once the
+// event was taken.
+type orderEventResponse struct {
+	Type               string   `json:"type"`
+	Time               string   `json:"time"`
+	Quantity           int64    `json:"quantity,omitempty"`
+	Price              *float64 `json:"price,omitempty"`
+	TradeID            string   `json:"trade_id,omitempty"`
+	ReplacementOrderID string   `json:"replacement_order_id,omitempty"`
+	FilledQuantity     int64    `json:"filled_quantity"`
+	RemainingQuantity  int64    `json:"remaining_quantity"`
+}
+
 // marketOrderResponse is the JSON response for market orders.
 // Omits price, expires_at, cancelled_at, expired_at entirely.
 type marketOrderResponse struct {
@@ -82,6 +122,19 @@ type tradeResponse struct {
 	ExecutedAt string  `json:"executed_at"`
 }
 
+// haltRequest is the optional JSON request body for POST
+// /stocks/{symbol}/halt.
+type haltRequest struct {
+	Reason string `json:"reason"`
+}
+
+// tradingStatusResponse is the JSON response for the halt and resume
+// endpoints.
+type tradingStatusResponse struct {
+	Symbol string `json:"symbol"`
+	Halted bool   `json:"halted"`
+}
+
 // SubmitOrder handles POST /orders.
 func (h *OrderHandler) SubmitOrder(w http.ResponseWriter, r *http.Request) {
 	var req submitOrderRequest
@@ -89,6 +142,9 @@ func (h *OrderHandler) SubmitOrder(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
+	if !authorize(w, r, req.BrokerID) {
+		return
+	}
 
 	// Parse expires_at if provided.
 	var expiresAt *time.Time
@@ -101,7 +157,7 @@ func (h *OrderHandler) SubmitOrder(w http.ResponseWriter, r *http.Request) {
 		expiresAt = &t
 	}
 
-	order, err := h.orderSvc.SubmitOrder(service.SubmitOrderRequest{
+	order, err := h.orderSvc.SubmitOrder(r.Context(), service.SubmitOrderRequest{
 		Type:           domain.OrderType(req.Type),
 		BrokerID:       req.BrokerID,
 		DocumentNumber: req.DocumentNumber,
@@ -119,24 +175,74 @@ func (h *OrderHandler) SubmitOrder(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusCreated, buildOrderResponse(order))
 }
 
-// GetOrder handles GET /orders/{order_id}.
+// GetOrder handles GET /orders/{order_id}. ?include=history adds the
+// order's lifecycle events.
 func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "order_id")
 
+	var withHistory bool
+	if v := r.URL.Query().Get("include"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			if part != "history" {
+				WriteError(w, http.StatusBadRequest, "validation_error", "include must be history")
+				return
+			}
+			withHistory = true
+		}
+	}
+
 	order, err := h.orderSvc.GetOrder(orderID)
 	if err != nil {
 		mapOrderError(w, err)
 		return
 	}
+	if !authorizeOrder(w, r, order) {
+		return
+	}
 
-	WriteJSON(w, http.StatusOK, buildOrderResponse(order))
+	var history []orderEventResponse
+	if withHistory {
+		history = buildOrderEventResponses(order.History.Events())
+	}
+
+	resp := buildOrderResponse(order)
+	switch orderResp := resp.(type) {
+	case limitOrderResponse:
+		lookup := limitOrderLookupResponse{limitOrderResponse: orderResp, History: history}
+		if pos := h.orderSvc.GetQueuePosition(order); pos != nil {
+			lookup.QueuePosition = &queuePositionResponse{
+				OrdersAhead:   pos.OrdersAhead,
+				QuantityAhead: pos.QuantityAhead,
+			}
+		}
+		resp = lookup
+	case marketOrderResponse:
+		resp = marketOrderLookupResponse{marketOrderResponse: orderResp, History: history}
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
 }
 
 // CancelOrder handles DELETE /orders/{order_id}.
 func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "order_id")
 
-	order, err := h.orderSvc.CancelOrder(orderID)
+	existing, err := h.orderSvc.GetOrder(orderID)
+	if err != nil {
+		mapOrderError(w, err)
+		return
+	}
+	if !authorizeOrder(w, r, existing) {
+		return
+	}
+
+	// The audit trail records who cancelled the order.
+	ctx := r.Context()
+	if principalFrom(r).Role == domain.APIKeyRoleAdmin {
+		ctx = service.WithCancelInitiator(ctx, service.CancelByAdmin)
+	}
+
+	order, err := h.orderSvc.CancelOrder(ctx, orderID)
 	if err != nil {
 		mapOrderError(w, err)
 		return
@@ -145,6 +251,38 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, buildOrderResponse(order))
 }
 
+// HaltSymbol handles POST /stocks/{symbol}/halt.
+func (h *OrderHandler) HaltSymbol(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+
+	var req haltRequest
+	if r.ContentLength != 0 {
+		if err := ParseJSON(r, &req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	}
+
+	if err := h.orderSvc.HaltSymbol(r.Context(), symbol, req.Reason); err != nil {
+		mapOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, tradingStatusResponse{Symbol: symbol, Halted: true})
+}
+
+// ResumeSymbol handles POST /stocks/{symbol}/resume.
+func (h *OrderHandler) ResumeSymbol(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+
+	if err := h.orderSvc.ResumeSymbol(r.Context(), symbol); err != nil {
+		mapOrderError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, tradingStatusResponse{Symbol: symbol, Halted: false})
+}
+
 // buildOrderResponse constructs the appropriate response type based on order type.
 // Market orders omit price, expires_at, cancelled_at, expired_at.
 // Limit orders always include them (null when not set).
@@ -222,6 +360,27 @@ func buildTradeResponses(trades []*domain.Trade) []tradeResponse {
 	return result
 }
 
+// buildOrderEventResponses converts an order's history to response events.
+func buildOrderEventResponses(events []domain.OrderEvent) []orderEventResponse {
+	result := make([]orderEventResponse, len(events))
+	for i, e := range events {
+		result[i] = orderEventResponse{
+			Type:               string(e.Type),
+			Time:               e.Time.UTC().Format("2006-01-02T15:04:05Z"),
+			Quantity:           e.Quantity,
+			TradeID:            e.TradeID,
+			ReplacementOrderID: e.ReplacementOrderID,
+			FilledQuantity:     e.FilledQuantity,
+			RemainingQuantity:  e.RemainingQuantity,
+		}
+		if e.Price != 0 {
+			p := domain.CentsToDollars(e.Price)
+			result[i].Price = &p
+		}
+	}
+	return result
+}
+
 // mapOrderError maps domain errors to HTTP responses for order endpoints.
 func mapOrderError(w http.ResponseWriter, err error) {
 	var validationErr *domain.ValidationError
@@ -243,6 +402,10 @@ func mapOrderError(w http.ResponseWriter, err error) {
 		WriteError(w, http.StatusConflict, "insufficient_holdings", err.Error())
 	case errors.Is(err, domain.ErrNoLiquidity):
 		WriteError(w, http.StatusConflict, "no_liquidity", err.Error())
+	case errors.Is(err, domain.ErrSymbolHalted):
+		WriteError(w, http.StatusConflict, "symbol_halted", err.Error())
+	case errors.Is(err, domain.ErrSymbolNotFound):
+		WriteError(w, http.StatusNotFound, "symbol_not_found", err.Error())
 	default:
 		WriteError(w, http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
 	}
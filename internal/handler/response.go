@@ -34,6 +34,9 @@ func WriteError(w http.ResponseWriter, status int, errorCode, message string) {
 // It validates that the Content-Type header is application/json and
 // returns an error for missing/incorrect content type or malformed JSON.
 func ParseJSON(r *http.Request, v any) error {
+	_, span := tracer().Start(r.Context(), "handler.parse_json")
+	defer span.End()
+
 	ct := r.Header.Get("Content-Type")
 	if ct == "" || !strings.HasPrefix(ct, "application/json") {
 		return fmt.Errorf("Request body must be valid JSON with Content-Type: application/json")
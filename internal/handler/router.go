@@ -1,28 +1,51 @@
 package handler
 
 import (
+	"bufio"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/metrics"
 	"github.com/efreitasn/miniexchange/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
-// NewRouter creates a chi router with all routes registered, request logging,
-// and Content-Type validation middleware.
+// NewRouter creates a chi router with all routes registered, request
+// tracing, logging and metrics, Content-Type validation and API key
+// authentication middleware. Market data, the health check and /metrics are public; every
+// other route requires an API key, and brokers may only act on their own
+// resources. m may be nil, in which case /metrics is not served, and
+// auditSvc may be nil, in which case the audit trail is not served.
 func NewRouter(
 	brokerSvc *service.BrokerService,
 	orderSvc *service.OrderService,
 	stockSvc *service.StockService,
 	webhookSvc *service.WebhookService,
+	marketDataSvc *service.MarketDataService,
+	eventStreamSvc *service.EventStreamService,
+	candleSvc *service.CandleService,
+	tickerSvc *service.TickerService,
+	apiKeySvc *service.APIKeyService,
+	auditSvc *service.AuditService,
+	m *metrics.Metrics,
 	logger *slog.Logger,
 ) chi.Router {
 	r := chi.NewRouter()
 
 	// Global middleware.
+	r.Use(requestTracing)
 	r.Use(requestLogging(logger))
+	r.Use(requestMetrics(m))
 	r.Use(contentTypeJSON)
 
 	// Create handlers.
@@ -30,49 +53,158 @@ func NewRouter(
 	orderH := NewOrderHandler(orderSvc)
 	stockH := NewStockHandler(stockSvc)
 	webhookH := NewWebhookHandler(webhookSvc)
+	marketDataH := NewMarketDataHandler(marketDataSvc)
+	eventStreamH := NewEventStreamHandler(eventStreamSvc)
+	candleH := NewCandleHandler(candleSvc)
+	tickerH := NewTickerHandler(tickerSvc)
+	apiKeyH := NewAPIKeyHandler(apiKeySvc)
+	auditH := NewAuditHandler(auditSvc)
 
 	// Health check.
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 
-	// Broker routes.
-	r.Post("/brokers", brokerH.Register)
-	r.Get("/brokers/{broker_id}/balance", brokerH.GetBalance)
-	r.Get("/brokers/{broker_id}/orders", brokerH.ListOrders)
-
-	// Order routes.
-	r.Post("/orders", orderH.SubmitOrder)
-	r.Get("/orders/{order_id}", orderH.GetOrder)
-	r.Delete("/orders/{order_id}", orderH.CancelOrder)
+	// Prometheus metrics.
+	if m != nil {
+		r.Method(http.MethodGet, "/metrics", m.Handler())
+	}
 
 	// Stock routes.
 	r.Get("/stocks/{symbol}/price", stockH.GetPrice)
 	r.Get("/stocks/{symbol}/book", stockH.GetBook)
+	r.Get("/stocks/{symbol}/book/l3", stockH.GetL3Book)
 	r.Get("/stocks/{symbol}/quote", stockH.GetQuote)
+	r.Get("/stocks/{symbol}/trades", stockH.GetTrades)
+	r.Get("/stocks/{symbol}/candles", candleH.GetCandles)
+	r.Get("/stocks/{symbol}/ticker", tickerH.GetTicker)
+	r.Get("/stocks/ticker", tickerH.ListTickers)
+
+	// Market data stream.
+	r.Get("/ws/market-data", marketDataH.Stream)
+
+	// Authenticated routes.
+	r.Group(func(r chi.Router) {
+		r.Use(authenticate(apiKeySvc))
+
+		// Broker routes. Registration is operational and admin-only;
+		// everything under a broker is limited to that broker and admins.
+		r.With(requireAdmin).Post("/brokers", brokerH.Register)
+		r.Route("/brokers/{broker_id}", func(r chi.Router) {
+			r.Use(requireBrokerParam)
+			r.Get("/balance", brokerH.GetBalance)
+			r.Get("/orders", brokerH.ListOrders)
+			r.Get("/events", eventStreamH.Stream)
 
-	// Webhook routes.
-	r.Post("/webhooks", webhookH.Upsert)
-	r.Get("/webhooks", webhookH.List)
-	r.Delete("/webhooks/{webhook_id}", webhookH.Delete)
+			// API key routes.
+			r.Post("/api-keys", apiKeyH.Create)
+			r.Get("/api-keys", apiKeyH.List)
+			r.Post("/api-keys/{key_id}/rotate", apiKeyH.Rotate)
+			r.Delete("/api-keys/{key_id}", apiKeyH.Revoke)
+		})
+
+		// Order routes.
+		r.Post("/orders", orderH.SubmitOrder)
+		r.Get("/orders/{order_id}", orderH.GetOrder)
+		r.Delete("/orders/{order_id}", orderH.CancelOrder)
+
+		// Trading halts are operational and admin-only.
+		r.With(requireAdmin).Post("/stocks/{symbol}/halt", orderH.HaltSymbol)
+		r.With(requireAdmin).Post("/stocks/{symbol}/resume", orderH.ResumeSymbol)
+
+		// The audit trail is for compliance and admin-only.
+		if auditSvc != nil {
+			r.With(requireAdmin).Get("/audit/orders/{order_id}", auditH.ListOrderRecords)
+		}
+
+		// Webhook routes.
+		r.Post("/webhooks", webhookH.Upsert)
+		r.Get("/webhooks", webhookH.List)
+		r.Patch("/webhooks/{webhook_id}", webhookH.Update)
+		r.Delete("/webhooks/{webhook_id}", webhookH.Delete)
+		r.Post("/webhooks/{webhook_id}/rotate-secret", webhookH.RotateSecret)
+		r.Get("/webhooks/{webhook_id}/deliveries", webhookH.ListDeliveries)
+		r.Post("/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", webhookH.Redeliver)
+		r.Post("/webhooks/{webhook_id}/ping", webhookH.Ping)
+	})
 
 	return r
 }
 
+// tracer returns the handler package's tracer from the global provider.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/efreitasn/miniexchange/internal/handler")
+}
+
+// requestTracing is middleware that traces each request as a server span,
+// continuing the caller's trace if the request carries a W3C traceparent
+// header. The span is named after the route pattern once routing is done.
+func requestTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.status))
+		if ww.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(ww.status))
+		}
+	})
+}
+
 // requestLogging returns middleware that logs each request's method, path,
-// status code, and duration using slog.
+// status code, and duration using slog, and its trace ID if it has one.
 func requestLogging(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			ww := &statusWriter{ResponseWriter: w, status: http.StatusOK}
 			next.ServeHTTP(ww, r)
-			logger.Info("request",
+			attrs := []any{
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.Int("status", ww.status),
 				slog.Duration("duration", time.Since(start)),
-			)
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+			}
+			logger.Info("request", attrs...)
+		})
+	}
+}
+
+// requestMetrics returns middleware that records each request's status code
+// and duration against its route pattern, so that /orders/{order_id} is one
+// series however many orders are looked up. Streaming routes are recorded
+// when the stream ends.
+func requestMetrics(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(ww, r)
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			m.ObserveHTTPRequest(r.Method, route, ww.status, time.Since(start))
 		})
 	}
 }
@@ -92,12 +224,32 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController, which
+// streaming handlers use to flush and extend write deadlines.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection through the
+// logging middleware. The status is recorded as 101 Switching Protocols.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking connection: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	w.status = http.StatusSwitchingProtocols
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
 // contentTypeJSON is middleware that validates Content-Type for POST, PUT, and
-// PATCH requests. If the Content-Type header doesn't start with
+// PATCH requests with a body. If the Content-Type header doesn't start with
 // "application/json", it returns 400 Bad Request before the handler runs.
+// Requests without a body, such as key rotation, need no Content-Type.
 func contentTypeJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		hasBody := r.ContentLength != 0
+		if hasBody && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) {
 			ct := r.Header.Get("Content-Type")
 			if ct == "" || !strings.HasPrefix(ct, "application/json") {
 				WriteError(w, http.StatusBadRequest, "invalid_request",
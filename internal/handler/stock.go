@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/service"
@@ -45,6 +46,29 @@ type bookResponse struct {
 	SnapshotAt string              `json:"snapshot_at"`
 }
 
+// l3OrderResponse is a single resting order in the L3 book response.
+type l3OrderResponse struct {
+	OrderRef  string `json:"order_ref"`
+	Quantity  int64  `json:"quantity"`
+	CreatedAt string `json:"created_at"`
+}
+
+// l3LevelResponse is a single price level in the L3 book response, with its
+// orders in time priority.
+type l3LevelResponse struct {
+	Price         float64           `json:"price"`
+	TotalQuantity int64             `json:"total_quantity"`
+	Orders        []l3OrderResponse `json:"orders"`
+}
+
+// l3BookResponse is the JSON response for GET /stocks/{symbol}/book/l3.
+type l3BookResponse struct {
+	Symbol     string            `json:"symbol"`
+	Bids       []l3LevelResponse `json:"bids"`
+	Asks       []l3LevelResponse `json:"asks"`
+	SnapshotAt string            `json:"snapshot_at"`
+}
+
 // quoteLevelResponse is a single price level in the quote response.
 type quoteLevelResponse struct {
 	Price    float64 `json:"price"`
@@ -64,6 +88,13 @@ type quoteResponse struct {
 	QuotedAt          string               `json:"quoted_at"`
 }
 
+// tradeTapeResponse is the JSON response for GET /stocks/{symbol}/trades.
+type tradeTapeResponse struct {
+	Symbol     string                `json:"symbol"`
+	Trades     []marketTradeResponse `json:"trades"`
+	NextCursor *string               `json:"next_cursor"`
+}
+
 // GetPrice handles GET /stocks/{symbol}/price.
 func (h *StockHandler) GetPrice(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
@@ -146,6 +177,56 @@ func (h *StockHandler) GetBook(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, resp)
 }
 
+// GetL3Book handles GET /stocks/{symbol}/book/l3.
+func (h *StockHandler) GetL3Book(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+
+	// Parse depth query param (default 10, max 50).
+	depth := 10
+	if d := r.URL.Query().Get("depth"); d != "" {
+		var err error
+		depth, err = strconv.Atoi(d)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "validation_error", "depth must be a valid integer")
+			return
+		}
+	}
+
+	book, err := h.stockSvc.GetL3Book(symbol, depth)
+	if err != nil {
+		mapStockError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, l3BookResponse{
+		Symbol:     book.Symbol,
+		Bids:       buildL3Levels(book.Bids),
+		Asks:       buildL3Levels(book.Asks),
+		SnapshotAt: book.SnapshotAt.UTC().Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+// buildL3Levels converts service L3 levels to their JSON representation.
+func buildL3Levels(levels []service.L3PriceLevel) []l3LevelResponse {
+	result := make([]l3LevelResponse, len(levels))
+	for i, lvl := range levels {
+		orders := make([]l3OrderResponse, len(lvl.Orders))
+		for j, o := range lvl.Orders {
+			orders[j] = l3OrderResponse{
+				OrderRef:  o.OrderRef,
+				Quantity:  o.Quantity,
+				CreatedAt: o.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			}
+		}
+		result[i] = l3LevelResponse{
+			Price:         domain.CentsToDollars(lvl.Price),
+			TotalQuantity: lvl.TotalQuantity,
+			Orders:        orders,
+		}
+	}
+	return result
+}
+
 // GetQuote handles GET /stocks/{symbol}/quote.
 func (h *StockHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
@@ -196,6 +277,71 @@ func (h *StockHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, resp)
 }
 
+// GetTrades handles GET /stocks/{symbol}/trades.
+func (h *StockHandler) GetTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := service.TradeTapeRequest{
+		Symbol: chi.URLParam(r, "symbol"),
+		Limit:  100,
+		Cursor: q.Get("cursor"),
+	}
+
+	var ok bool
+	if req.From, ok = parseOptionalTime(w, r, "from"); !ok {
+		return
+	}
+	if req.To, ok = parseOptionalTime(w, r, "to"); !ok {
+		return
+	}
+
+	if l := q.Get("limit"); l != "" {
+		var err error
+		req.Limit, err = strconv.Atoi(l)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "validation_error", "limit must be a valid integer")
+			return
+		}
+	}
+
+	tape, err := h.stockSvc.GetTrades(req)
+	if err != nil {
+		mapStockError(w, err)
+		return
+	}
+
+	trades := make([]marketTradeResponse, len(tape.Trades))
+	for i, t := range tape.Trades {
+		trades[i] = marketTradeResponse{
+			TradeID:       t.TradeID,
+			Price:         domain.CentsToDollars(t.Price),
+			Quantity:      t.Quantity,
+			AggressorSide: string(t.AggressorSide),
+			ExecutedAt:    t.ExecutedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, tradeTapeResponse{
+		Symbol:     tape.Symbol,
+		Trades:     trades,
+		NextCursor: tape.NextCursor,
+	})
+}
+
+// parseOptionalTime parses an optional RFC 3339 query parameter. On a
+// malformed value it writes a 400 response and returns false.
+func parseOptionalTime(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	ts, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "validation_error", name+" must be an RFC 3339 timestamp")
+		return nil, false
+	}
+	return &ts, true
+}
+
 // mapStockError maps domain errors to HTTP responses for stock endpoints.
 func mapStockError(w http.ResponseWriter, err error) {
 	var validationErr *domain.ValidationError
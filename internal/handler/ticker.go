@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// TickerHandler handles HTTP requests for 24-hour ticker endpoints.
+type TickerHandler struct {
+	tickerSvc *service.TickerService
+}
+
+// NewTickerHandler creates a new TickerHandler.
+func NewTickerHandler(tickerSvc *service.TickerService) *TickerHandler {
+	return &TickerHandler{tickerSvc: tickerSvc}
+}
+
+// tickerResponse is the JSON response for GET /stocks/{symbol}/ticker and a
+// single entry of GET /stocks/ticker.
+type tickerResponse struct {
+	Symbol        string             `json:"symbol"`
+	Open          *float64           `json:"open"`
+	High          *float64           `json:"high"`
+	Low           *float64           `json:"low"`
+	Last          *float64           `json:"last"`
+	Change        *float64           `json:"change"`
+	ChangePercent *float64           `json:"change_percent"`
+	Volume        int64              `json:"volume"`
+	Notional      float64            `json:"notional"`
+	TradeCount    int                `json:"trade_count"`
+	BestBid       *bookLevelResponse `json:"best_bid"`
+	BestAsk       *bookLevelResponse `json:"best_ask"`
+	WindowStart   string             `json:"window_start"`
+	WindowEnd     string             `json:"window_end"`
+}
+
+// tickerListResponse is the JSON response for GET /stocks/ticker.
+type tickerListResponse struct {
+	Tickers []tickerResponse `json:"tickers"`
+}
+
+// GetTicker handles GET /stocks/{symbol}/ticker.
+func (h *TickerHandler) GetTicker(w http.ResponseWriter, r *http.Request) {
+	symbol := chi.URLParam(r, "symbol")
+
+	ticker, err := h.tickerSvc.GetTicker(symbol)
+	if err != nil {
+		mapStockError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, buildTickerResponse(ticker))
+}
+
+// ListTickers handles GET /stocks/ticker.
+func (h *TickerHandler) ListTickers(w http.ResponseWriter, r *http.Request) {
+	tickers := h.tickerSvc.ListTickers()
+
+	resp := tickerListResponse{Tickers: make([]tickerResponse, len(tickers))}
+	for i, t := range tickers {
+		resp.Tickers[i] = buildTickerResponse(t)
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+func buildTickerResponse(t *service.Ticker) tickerResponse {
+	resp := tickerResponse{
+		Symbol:      t.Symbol,
+		Open:        optionalDollars(t.Open),
+		High:        optionalDollars(t.High),
+		Low:         optionalDollars(t.Low),
+		Last:        optionalDollars(t.Last),
+		Change:      optionalDollars(t.Change),
+		Volume:      t.Volume,
+		Notional:    domain.CentsToDollars(t.Notional),
+		TradeCount:  t.TradeCount,
+		BestBid:     buildOptionalLevel(t.BestBid),
+		BestAsk:     buildOptionalLevel(t.BestAsk),
+		WindowStart: t.WindowStart.UTC().Format("2006-01-02T15:04:05Z"),
+		WindowEnd:   t.WindowEnd.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if pct, ok := t.ChangePercent(); ok {
+		rounded := math.Round(pct*100) / 100
+		resp.ChangePercent = &rounded
+	}
+	return resp
+}
+
+// optionalDollars converts an optional cents amount to dollars.
+func optionalDollars(cents *int64) *float64 {
+	if cents == nil {
+		return nil
+	}
+	v := domain.CentsToDollars(*cents)
+	return &v
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 
@@ -21,19 +22,51 @@ func NewWebhookHandler(webhookSvc *service.WebhookService) *WebhookHandler {
 
 // upsertWebhookRequest is the JSON request body for POST /webhooks.
 type upsertWebhookRequest struct {
-	BrokerID string   `json:"broker_id"`
-	URL      string   `json:"url"`
-	Events   []string `json:"events"`
+	BrokerID string                 `json:"broker_id"`
+	URL      string                 `json:"url"`
+	Events   []string               `json:"events"`
+	Filters  *webhookFiltersRequest `json:"filters"`
+	Version  int                    `json:"version"`
 }
 
-// webhookResponse is a single webhook in the response.
+// updateWebhookRequest is the JSON request body for PATCH
+// /webhooks/{webhook_id}. Omitted fields are left unchanged.
+type updateWebhookRequest struct {
+	Status  *string                `json:"status"`
+	Filters *webhookFiltersRequest `json:"filters"`
+	Version *int                   `json:"version"`
+}
+
+// webhookFiltersRequest is the JSON representation of a subscription's
+// filters in requests.
+type webhookFiltersRequest struct {
+	Symbols     []string `json:"symbols"`
+	Side        string   `json:"side"`
+	MinNotional *float64 `json:"min_notional"`
+}
+
+// webhookResponse is a single webhook in the response. SigningSecret is
+// present only when the secret was just generated: on creation and on
+// rotation.
 type webhookResponse struct {
-	WebhookID string `json:"webhook_id"`
-	BrokerID  string `json:"broker_id"`
-	Event     string `json:"event"`
-	URL       string `json:"url"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	WebhookID     string                 `json:"webhook_id"`
+	BrokerID      string                 `json:"broker_id"`
+	Event         string                 `json:"event"`
+	URL           string                 `json:"url"`
+	Status        string                 `json:"status"`
+	Filters       webhookFiltersResponse `json:"filters"`
+	Version       int                    `json:"version"`
+	CreatedAt     string                 `json:"created_at"`
+	UpdatedAt     string                 `json:"updated_at"`
+	SigningSecret string                 `json:"signing_secret,omitempty"`
+}
+
+// webhookFiltersResponse is the JSON representation of a subscription's
+// filters. Filters that are not set are empty or null.
+type webhookFiltersResponse struct {
+	Symbols     []string `json:"symbols"`
+	Side        *string  `json:"side"`
+	MinNotional *float64 `json:"min_notional"`
 }
 
 // webhookListResponse is the JSON response for POST and GET /webhooks.
@@ -41,6 +74,38 @@ type webhookListResponse struct {
 	Webhooks []webhookResponse `json:"webhooks"`
 }
 
+// deliveryResponse is a single webhook delivery in the response.
+type deliveryResponse struct {
+	DeliveryID     string                    `json:"delivery_id"`
+	WebhookID      string                    `json:"webhook_id"`
+	Event          string                    `json:"event"`
+	Sequence       uint64                    `json:"sequence"`
+	Status         string                    `json:"status"`
+	Attempts       int                       `json:"attempts"`
+	CreatedAt      string                    `json:"created_at"`
+	NextAttemptAt  *string                   `json:"next_attempt_at"`
+	LastAttemptAt  *string                   `json:"last_attempt_at"`
+	LastStatusCode *int                      `json:"last_status_code"`
+	LastError      *string                   `json:"last_error"`
+	Payload        json.RawMessage           `json:"payload"`
+	History        []deliveryAttemptResponse `json:"history"`
+}
+
+// deliveryAttemptResponse is the JSON representation of one delivery attempt.
+type deliveryAttemptResponse struct {
+	AttemptedAt     string  `json:"attempted_at"`
+	URL             string  `json:"url"`
+	StatusCode      *int    `json:"status_code"`
+	LatencyMs       float64 `json:"latency_ms"`
+	Error           *string `json:"error"`
+	ResponseSnippet string  `json:"response_snippet"`
+}
+
+// deliveryListResponse is the JSON response for GET /webhooks/{webhook_id}/deliveries.
+type deliveryListResponse struct {
+	Deliveries []deliveryResponse `json:"deliveries"`
+}
+
 // Upsert handles POST /webhooks.
 func (h *WebhookHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	var req upsertWebhookRequest
@@ -48,11 +113,16 @@ func (h *WebhookHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
+	if !authorize(w, r, req.BrokerID) {
+		return
+	}
 
 	webhooks, anyCreated, err := h.webhookSvc.Upsert(service.UpsertWebhookRequest{
 		BrokerID: req.BrokerID,
 		URL:      req.URL,
 		Events:   req.Events,
+		Filters:  toFiltersInput(req.Filters),
+		Version:  req.Version,
 	})
 	if err != nil {
 		mapWebhookError(w, err)
@@ -76,6 +146,9 @@ func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "validation_error", "broker_id query parameter is required")
 		return
 	}
+	if !authorize(w, r, brokerID) {
+		return
+	}
 
 	webhooks, err := h.webhookSvc.List(brokerID)
 	if err != nil {
@@ -88,10 +161,148 @@ func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Update handles PATCH /webhooks/{webhook_id}.
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "webhook_id")
+
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
+	var req updateWebhookRequest
+	if err := ParseJSON(r, &req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	updateReq := service.UpdateWebhookRequest{
+		WebhookID: webhookID,
+		Status:    req.Status,
+		Version:   req.Version,
+	}
+	if req.Filters != nil {
+		filters := toFiltersInput(req.Filters)
+		updateReq.Filters = &filters
+	}
+	updated, err := h.webhookSvc.Update(updateReq)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, buildWebhookResponse(updated))
+}
+
+// RotateSecret handles POST /webhooks/{webhook_id}/rotate-secret.
+func (h *WebhookHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "webhook_id")
+
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
+	rotated, err := h.webhookSvc.RotateSecret(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, buildWebhookResponse(rotated))
+}
+
+// ListDeliveries handles GET /webhooks/{webhook_id}/deliveries.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "webhook_id")
+
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
+	deliveries, err := h.webhookSvc.ListDeliveries(webhookID, r.URL.Query().Get("status"))
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+
+	result := make([]deliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = buildDeliveryResponse(d)
+	}
+	WriteJSON(w, http.StatusOK, deliveryListResponse{Deliveries: result})
+}
+
+// Redeliver handles POST /webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "webhook_id")
+
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
+	d, err := h.webhookSvc.Redeliver(webhookID, chi.URLParam(r, "delivery_id"))
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusAccepted, buildDeliveryResponse(d))
+}
+
+// Ping handles POST /webhooks/{webhook_id}/ping.
+func (h *WebhookHandler) Ping(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "webhook_id")
+
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
+	d, err := h.webhookSvc.Ping(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, buildDeliveryResponse(d))
+}
+
 // Delete handles DELETE /webhooks/{webhook_id}.
 func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	webhookID := chi.URLParam(r, "webhook_id")
 
+	wh, err := h.webhookSvc.Get(webhookID)
+	if err != nil {
+		mapWebhookError(w, err)
+		return
+	}
+	if !authorize(w, r, wh.BrokerID) {
+		return
+	}
+
 	if err := h.webhookSvc.Delete(webhookID); err != nil {
 		mapWebhookError(w, err)
 		return
@@ -100,22 +311,112 @@ func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// toFiltersInput converts request filters to service input. Omitted filters
+// filter nothing.
+func toFiltersInput(f *webhookFiltersRequest) service.WebhookFiltersInput {
+	if f == nil {
+		return service.WebhookFiltersInput{}
+	}
+	return service.WebhookFiltersInput{
+		Symbols:     f.Symbols,
+		Side:        f.Side,
+		MinNotional: f.MinNotional,
+	}
+}
+
 // buildWebhookResponses converts domain webhooks to response webhooks.
 func buildWebhookResponses(webhooks []*domain.Webhook) []webhookResponse {
 	result := make([]webhookResponse, len(webhooks))
 	for i, wh := range webhooks {
-		result[i] = webhookResponse{
-			WebhookID: wh.WebhookID,
-			BrokerID:  wh.BrokerID,
-			Event:     wh.Event,
-			URL:       wh.URL,
-			CreatedAt: wh.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: wh.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
-		}
+		result[i] = buildWebhookResponse(wh)
 	}
 	return result
 }
 
+// buildWebhookResponse converts a domain webhook to its response form. The
+// service leaves Secret empty except where it may be shown.
+func buildWebhookResponse(wh *domain.Webhook) webhookResponse {
+	resp := webhookResponse{
+		WebhookID: wh.WebhookID,
+		BrokerID:  wh.BrokerID,
+		Event:     wh.Event,
+		URL:       wh.URL,
+		Status:    string(wh.Status),
+		Filters: webhookFiltersResponse{
+			Symbols: make([]string, len(wh.Filters.Symbols)),
+		},
+		Version:       wh.Version,
+		CreatedAt:     wh.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:     wh.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		SigningSecret: wh.Secret,
+	}
+	copy(resp.Filters.Symbols, wh.Filters.Symbols)
+	if wh.Filters.Side != "" {
+		side := string(wh.Filters.Side)
+		resp.Filters.Side = &side
+	}
+	if wh.Filters.MinNotional != 0 {
+		minNotional := domain.CentsToDollars(wh.Filters.MinNotional)
+		resp.Filters.MinNotional = &minNotional
+	}
+	return resp
+}
+
+// buildDeliveryResponse converts a domain delivery to its response form.
+func buildDeliveryResponse(d *domain.WebhookDelivery) deliveryResponse {
+	resp := deliveryResponse{
+		DeliveryID: d.DeliveryID,
+		WebhookID:  d.WebhookID,
+		Event:      d.Event,
+		Sequence:   d.Sequence,
+		Status:     string(d.Status),
+		Attempts:   d.Attempts,
+		CreatedAt:  d.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		Payload:    json.RawMessage(d.Payload),
+		History:    make([]deliveryAttemptResponse, len(d.History)),
+	}
+	if !d.NextAttemptAt.IsZero() {
+		s := d.NextAttemptAt.UTC().Format("2006-01-02T15:04:05Z")
+		resp.NextAttemptAt = &s
+	}
+	if d.LastAttemptAt != nil {
+		s := d.LastAttemptAt.UTC().Format("2006-01-02T15:04:05Z")
+		resp.LastAttemptAt = &s
+	}
+	if d.LastStatusCode != 0 {
+		code := d.LastStatusCode
+		resp.LastStatusCode = &code
+	}
+	if d.LastError != "" {
+		e := d.LastError
+		resp.LastError = &e
+	}
+	for i, a := range d.History {
+		resp.History[i] = buildDeliveryAttemptResponse(a)
+	}
+	return resp
+}
+
+// buildDeliveryAttemptResponse converts a domain delivery attempt to its
+// response form.
+func buildDeliveryAttemptResponse(a domain.DeliveryAttempt) deliveryAttemptResponse {
+	resp := deliveryAttemptResponse{
+		AttemptedAt:     a.AttemptedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		URL:             a.URL,
+		LatencyMs:       float64(a.Latency.Microseconds()) / 1000,
+		ResponseSnippet: a.ResponseSnippet,
+	}
+	if a.StatusCode != 0 {
+		code := a.StatusCode
+		resp.StatusCode = &code
+	}
+	if a.Error != "" {
+		e := a.Error
+		resp.Error = &e
+	}
+	return resp
+}
+
 // mapWebhookError maps domain errors to HTTP responses for webhook endpoints.
 func mapWebhookError(w http.ResponseWriter, err error) {
 	var validationErr *domain.ValidationError
@@ -129,6 +430,10 @@ func mapWebhookError(w http.ResponseWriter, err error) {
 		WriteError(w, http.StatusNotFound, "broker_not_found", err.Error())
 	case errors.Is(err, domain.ErrWebhookNotFound):
 		WriteError(w, http.StatusNotFound, "webhook_not_found", err.Error())
+	case errors.Is(err, domain.ErrDeliveryNotFound):
+		WriteError(w, http.StatusNotFound, "delivery_not_found", err.Error())
+	case errors.Is(err, domain.ErrDeliveryNotFailed):
+		WriteError(w, http.StatusConflict, "delivery_not_failed", err.Error())
 	default:
 		WriteError(w, http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
 	}
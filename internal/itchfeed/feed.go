@@ -0,0 +1,245 @@
+// Package itchfeed publishes the binary market data feed defined in
+// pkg/itch. The feed is an engine.BookListener: it turns the order-level
+// changes of every BookUpdate into ITCH-style messages, numbers them with a
+// single sequence across all symbols, and keeps the most recent ones in a
+// ring from which the UDP publisher, the TCP replay service and the
+// retransmission service read.
+package itchfeed
+
+import (
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/pkg/itch"
+)
+
+// bookOrder is a resting order as the feed has described it.
+type bookOrder struct {
+	ref  uint64
+	side byte
+	open int64 // shares not yet executed
+}
+
+// Feed is the market data feed of one exchange process. Its session name
+// is the process start time in Unix seconds, so sequence numbers restart at
+// 1 under a new session after a restart.
+type Feed struct {
+	session string
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	ring    ring
+	closed  bool
+	notify  chan struct{}         // closed and replaced on every append and on Close
+	locates map[string]uint16     // symbol → locate code
+	orders  map[string]*bookOrder // exchange order ID → resting order
+	nextRef uint64
+
+	// Serving state.
+	lns []net.Listener
+	pcs []net.PacketConn
+	wg  sync.WaitGroup
+}
+
+// NewFeed creates a feed that retains the last ringSize messages for
+// replay and retransmission. The session starts with a start-of-messages
+// system event.
+func NewFeed(ringSize int, logger *slog.Logger) *Feed {
+	now := time.Now()
+	f := &Feed{
+		session: strconv.FormatInt(now.Unix(), 10),
+		logger:  logger,
+		ring:    newRing(ringSize),
+		notify:  make(chan struct{}),
+		locates: make(map[string]uint16),
+		orders:  make(map[string]*bookOrder),
+	}
+	f.append(&itch.SystemEvent{Timestamp: now, EventCode: itch.EventStartOfMessages})
+	return f
+}
+
+// Session returns the feed's session name.
+func (f *Feed) Session() string {
+	return f.session
+}
+
+// OnBookUpdate implements engine.BookListener. It only encodes messages
+// into the ring; publishing happens on the readers' goroutines.
+func (f *Feed) OnBookUpdate(u *engine.BookUpdate) {
+	if len(u.Orders) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+
+	locate := f.locate(u.Symbol, u.Timestamp)
+	for _, ev := range u.Orders {
+		switch ev.Type {
+		case engine.OrderAdded:
+			f.nextRef++
+			o := &bookOrder{ref: f.nextRef, side: side(ev.Side), open: ev.Quantity}
+			f.orders[ev.OrderID] = o
+			f.append(&itch.AddOrder{
+				Locate:    locate,
+				Timestamp: u.Timestamp,
+				OrderRef:  o.ref,
+				Side:      o.side,
+				Shares:    uint32(ev.Quantity),
+				Symbol:    u.Symbol,
+				Price:     uint64(ev.Price),
+			})
+
+		case engine.OrderExecuted:
+			o, ok := f.orders[ev.OrderID]
+			if !ok {
+				continue
+			}
+			o.open -= ev.Quantity
+			f.append(&itch.OrderExecuted{
+				Locate:         locate,
+				Timestamp:      u.Timestamp,
+				OrderRef:       o.ref,
+				ExecutedShares: uint32(ev.Quantity),
+				ExecutionPrice: uint64(ev.Price),
+				MatchNumber:    ev.TradeID,
+			})
+			aggressor := itch.SideBuy
+			if o.side == itch.SideBuy {
+				aggressor = itch.SideSell
+			}
+			f.append(&itch.Trade{
+				Locate:      locate,
+				Timestamp:   u.Timestamp,
+				Side:        aggressor,
+				Shares:      uint32(ev.Quantity),
+				Symbol:      u.Symbol,
+				Price:       uint64(ev.Price),
+				MatchNumber: ev.TradeID,
+			})
+
+		case engine.OrderRemoved:
+			o, ok := f.orders[ev.OrderID]
+			if !ok {
+				continue
+			}
+			delete(f.orders, ev.OrderID)
+			// A fully executed order needs no message: its last
+			// OrderExecuted already took it to zero.
+			if o.open > 0 {
+				f.append(&itch.OrderCancel{
+					Locate:         locate,
+					Timestamp:      u.Timestamp,
+					OrderRef:       o.ref,
+					CanceledShares: uint32(o.open),
+				})
+			}
+		}
+	}
+}
+
+// locate returns the symbol's locate code, assigning one and publishing a
+// symbol directory message the first time the symbol is seen.
+func (f *Feed) locate(symbol string, ts time.Time) uint16 {
+	if l, ok := f.locates[symbol]; ok {
+		return l
+	}
+	l := uint16(len(f.locates) + 1)
+	f.locates[symbol] = l
+	f.append(&itch.SymbolDirectory{Locate: l, Timestamp: ts, Symbol: symbol})
+	return l
+}
+
+// append encodes m, stores it under the next sequence number and wakes the
+// readers. The caller must hold f.mu.
+func (f *Feed) append(m itch.Message) {
+	frame, err := itch.Append(nil, m)
+	if err != nil {
+		f.logger.Error("itch message not published", slog.String("error", err.Error()))
+		return
+	}
+	f.ring.append(frame)
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+// batch is a run of consecutive messages read from the ring.
+type batch struct {
+	start  uint64   // sequence number of frames[0], or of the next message if there are none
+	frames [][]byte // length-prefixed messages
+	wait   <-chan struct{}
+	closed bool // the feed has closed and every message has been read
+}
+
+// read returns up to maxCount messages starting at sequence number from,
+// limited to what fits in one packet. If from is no longer retained, the
+// batch starts at the oldest message that is. With no messages to return,
+// wait is closed when there are.
+func (f *Feed) read(from uint64, maxCount int) batch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if from < f.ring.first {
+		from = f.ring.first
+	}
+	b := batch{start: from, wait: f.notify}
+	size := itch.PacketHeaderSize
+	for seq := from; seq < f.ring.next && len(b.frames) < maxCount; seq++ {
+		frame := f.ring.get(seq)
+		if size+len(frame) > itch.MaxPacketSize {
+			break
+		}
+		size += len(frame)
+		b.frames = append(b.frames, frame)
+	}
+	if from >= f.ring.next {
+		b.start = f.ring.next
+		b.closed = f.closed
+	}
+	return b
+}
+
+// bounds returns the oldest retained sequence number and the next one to be
+// assigned.
+func (f *Feed) bounds() (first, next uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ring.first, f.ring.next
+}
+
+func side(s domain.OrderSide) byte {
+	if s == domain.OrderSideBid {
+		return itch.SideBuy
+	}
+	return itch.SideSell
+}
+
+// ring holds the most recent messages by sequence number. Sequence numbers
+// start at 1.
+type ring struct {
+	frames [][]byte
+	first  uint64 // oldest retained
+	next   uint64 // assigned to the next append
+}
+
+func newRing(size int) ring {
+	return ring{frames: make([][]byte, size), first: 1, next: 1}
+}
+
+func (r *ring) append(frame []byte) {
+	if r.next-r.first == uint64(len(r.frames)) {
+		r.first++
+	}
+	r.frames[(r.next-1)%uint64(len(r.frames))] = frame
+	r.next++
+}
+
+func (r *ring) get(seq uint64) []byte {
+	return r.frames[(seq-1)%uint64(len(r.frames))]
+}
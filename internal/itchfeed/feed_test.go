@@ -0,0 +1,158 @@
+package itchfeed
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/pkg/itch"
+)
+
+var testTime = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+func newTestFeed(ringSize int) *Feed {
+	return NewFeed(ringSize, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// messages decodes every retained message from sequence number from on.
+func messages(t *testing.T, f *Feed, from uint64) []itch.Message {
+	t.Helper()
+	var msgs []itch.Message
+	for {
+		b := f.read(from, 1000)
+		if len(b.frames) == 0 {
+			return msgs
+		}
+		for _, frame := range b.frames {
+			m, err := itch.Decode(frame[2:])
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			msgs = append(msgs, m)
+		}
+		from = b.start + uint64(len(b.frames))
+	}
+}
+
+func TestFeed_OrderLifecycle(t *testing.T) {
+	f := newTestFeed(100)
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: []engine.OrderEvent{
+		{Type: engine.OrderAdded, OrderID: "ask-1", Side: domain.OrderSideAsk, Price: 15000, Quantity: 5},
+		{Type: engine.OrderAdded, OrderID: "ask-2", Side: domain.OrderSideAsk, Price: 15100, Quantity: 5},
+	}})
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: []engine.OrderEvent{
+		{Type: engine.OrderExecuted, OrderID: "ask-1", Side: domain.OrderSideAsk, Price: 15000, Quantity: 5, TradeID: "t1"},
+		{Type: engine.OrderRemoved, OrderID: "ask-1", Side: domain.OrderSideAsk, Price: 15000},
+		{Type: engine.OrderExecuted, OrderID: "ask-2", Side: domain.OrderSideAsk, Price: 15100, Quantity: 2, TradeID: "t2"},
+	}})
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "MSFT", Timestamp: testTime, Orders: []engine.OrderEvent{
+		{Type: engine.OrderAdded, OrderID: "bid-1", Side: domain.OrderSideBid, Price: 30000, Quantity: 1},
+	}})
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: []engine.OrderEvent{
+		{Type: engine.OrderRemoved, OrderID: "ask-2", Side: domain.OrderSideAsk, Price: 15100},
+	}})
+	// Updates with only level or trade changes produce nothing.
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime})
+
+	want := []itch.Message{
+		&itch.SymbolDirectory{Locate: 1, Timestamp: testTime, Symbol: "AAPL"},
+		&itch.AddOrder{Locate: 1, Timestamp: testTime, OrderRef: 1, Side: itch.SideSell, Shares: 5, Symbol: "AAPL", Price: 15000},
+		&itch.AddOrder{Locate: 1, Timestamp: testTime, OrderRef: 2, Side: itch.SideSell, Shares: 5, Symbol: "AAPL", Price: 15100},
+		&itch.OrderExecuted{Locate: 1, Timestamp: testTime, OrderRef: 1, ExecutedShares: 5, ExecutionPrice: 15000, MatchNumber: "t1"},
+		&itch.Trade{Locate: 1, Timestamp: testTime, Side: itch.SideBuy, Shares: 5, Symbol: "AAPL", Price: 15000, MatchNumber: "t1"},
+		&itch.OrderExecuted{Locate: 1, Timestamp: testTime, OrderRef: 2, ExecutedShares: 2, ExecutionPrice: 15100, MatchNumber: "t2"},
+		&itch.Trade{Locate: 1, Timestamp: testTime, Side: itch.SideBuy, Shares: 2, Symbol: "AAPL", Price: 15100, MatchNumber: "t2"},
+		&itch.SymbolDirectory{Locate: 2, Timestamp: testTime, Symbol: "MSFT"},
+		&itch.AddOrder{Locate: 2, Timestamp: testTime, OrderRef: 3, Side: itch.SideBuy, Shares: 1, Symbol: "MSFT", Price: 30000},
+		&itch.OrderCancel{Locate: 1, Timestamp: testTime, OrderRef: 2, CanceledShares: 3},
+	}
+
+	got := messages(t, f, 1)
+	if len(got) != 1+len(want) {
+		t.Fatalf("got %d messages, want %d", len(got), 1+len(want))
+	}
+	if ev, ok := got[0].(*itch.SystemEvent); !ok || ev.EventCode != itch.EventStartOfMessages {
+		t.Errorf("first message = %+v, want a start-of-messages event", got[0])
+	}
+	for i, w := range want {
+		if !reflect.DeepEqual(got[i+1], w) {
+			t.Errorf("message %d: got %+v, want %+v", i+2, got[i+1], w)
+		}
+	}
+}
+
+func TestFeed_FromEngine(t *testing.T) {
+	f := newTestFeed(100)
+	books := engine.NewBookManager(nil)
+	books.AddListener(f)
+	bs := store.NewBrokerStore()
+	m := engine.NewMatcher(books, bs, store.NewOrderStore(), store.NewTradeStore(), domain.NewSymbolRegistry(), nil, nil, nil, nil)
+	bs.Create(&domain.Broker{BrokerID: "buyer", CashBalance: 1_000_000, Holdings: map[string]*domain.Holding{}})
+
+	exp := time.Now().Add(time.Hour)
+	order := &domain.Order{Type: domain.OrderTypeLimit, BrokerID: "buyer", Side: domain.OrderSideBid, Symbol: "AAPL", Price: 15000, Quantity: 10, ExpiresAt: &exp}
+	if _, err := m.MatchLimitOrder(context.Background(), order); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.CancelOrder(context.Background(), order.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := messages(t, f, 2)
+	if len(got) != 3 {
+		t.Fatalf("got %d messages, want 3", len(got))
+	}
+	add, ok := got[1].(*itch.AddOrder)
+	if !ok || add.Side != itch.SideBuy || add.Shares != 10 || add.Price != 15000 || add.Symbol != "AAPL" {
+		t.Errorf("got %+v, want an AddOrder for the bid", got[1])
+	}
+	cancel, ok := got[2].(*itch.OrderCancel)
+	if !ok || cancel.OrderRef != add.OrderRef || cancel.CanceledShares != 10 {
+		t.Errorf("got %+v, want an OrderCancel for the bid", got[2])
+	}
+}
+
+func TestFeed_RingEvictsOldest(t *testing.T) {
+	f := newTestFeed(3)
+	for i := 0; i < 4; i++ {
+		f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: []engine.OrderEvent{
+			{Type: engine.OrderAdded, OrderID: string(rune('a' + i)), Side: domain.OrderSideBid, Price: 100, Quantity: 1},
+		}})
+	}
+	// 1 start of messages, 2 directory, 3–6 adds: only 4–6 are retained.
+	first, next := f.bounds()
+	if first != 4 || next != 7 {
+		t.Fatalf("bounds = %d, %d, want 4, 7", first, next)
+	}
+	b := f.read(1, 10)
+	if b.start != 4 || len(b.frames) != 3 {
+		t.Errorf("read from 1 = start %d with %d messages, want start 4 with 3", b.start, len(b.frames))
+	}
+}
+
+func TestFeed_ReadLimitsToOnePacket(t *testing.T) {
+	f := newTestFeed(1000)
+	var orders []engine.OrderEvent
+	for i := 0; i < 100; i++ {
+		orders = append(orders, engine.OrderEvent{Type: engine.OrderAdded, OrderID: string(rune('a' + i)), Side: domain.OrderSideBid, Price: 100, Quantity: 1})
+	}
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: orders})
+
+	b := f.read(1, 1000)
+	size := itch.PacketHeaderSize
+	for _, frame := range b.frames {
+		size += len(frame)
+	}
+	if size > itch.MaxPacketSize {
+		t.Errorf("batch of %d bytes exceeds MaxPacketSize", size)
+	}
+	if len(b.frames) == 0 || len(b.frames) >= 102 {
+		t.Errorf("got %d messages, want a partial batch", len(b.frames))
+	}
+}
@@ -0,0 +1,266 @@
+package itchfeed
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"time"
+
+	"github.com/efreitasn/miniexchange/pkg/itch"
+)
+
+const (
+	// requestTimeout bounds how long a replay connection may take to send
+	// its request.
+	requestTimeout = 10 * time.Second
+
+	// writeTimeout bounds a single write to a replay client. A client that
+	// cannot keep up is disconnected and may reconnect from where it was.
+	writeTimeout = 10 * time.Second
+)
+
+// errClosed stops a stream after the end-of-session packet has been sent.
+var errClosed = errors.New("feed closed")
+
+// Publish sends every message, from the start of the session, to conn as
+// UDP packets, with a heartbeat after each second without messages. It
+// returns immediately; publishing runs until Close. Send errors are logged
+// and otherwise ignored, since receivers recover lost packets through the
+// retransmission service.
+func (f *Feed) Publish(conn net.Conn) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		conn.Close()
+		return
+	}
+	f.wg.Add(1)
+	f.mu.Unlock()
+
+	go func() {
+		defer f.wg.Done()
+		defer conn.Close()
+		f.stream(1, func(packet []byte) error {
+			if _, err := conn.Write(packet); err != nil {
+				f.logger.Debug("itch packet not sent", slog.String("error", err.Error()))
+			}
+			return nil
+		})
+	}()
+}
+
+// ServeReplay accepts replay connections on ln until Close is called. Each
+// connection sends one request header naming the session and the first
+// sequence number it wants, and then receives packets from there on. It
+// always returns a non-nil error, except after Close.
+func (f *Feed) ServeReplay(ln net.Listener) error {
+	if !f.track(ln, nil) {
+		ln.Close()
+		return nil
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if f.isClosed() {
+				return nil
+			}
+			return err
+		}
+
+		f.mu.Lock()
+		if f.closed {
+			f.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		f.wg.Add(1)
+		f.mu.Unlock()
+
+		go func() {
+			defer f.wg.Done()
+			f.handleReplay(conn)
+		}()
+	}
+}
+
+func (f *Feed) handleReplay(conn net.Conn) {
+	defer conn.Close()
+
+	var buf [itch.PacketHeaderSize]byte
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	if _, err := io.ReadFull(conn, buf[:]); err != nil {
+		return
+	}
+	req, _ := itch.ParseHeader(buf[:])
+
+	send := func(packet []byte) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := conn.Write(packet)
+		return err
+	}
+	if req.Session != "" && req.Session != f.session {
+		_, next := f.bounds()
+		send(f.header(next, itch.EndOfSession))
+		return
+	}
+	from := req.Sequence
+	if from == 0 {
+		_, from = f.bounds()
+	}
+	f.logger.Debug("itch replay started",
+		slog.String("remote_addr", conn.RemoteAddr().String()),
+		slog.Uint64("from", from),
+	)
+	if err := f.stream(from, send); err != nil && err != errClosed {
+		f.logger.Info("itch replay ended", slog.String("reason", err.Error()))
+	}
+}
+
+// ServeRetransmit answers retransmission requests on pc until Close is
+// called. A request is a packet header naming the session, the first
+// sequence number wanted and how many; the answer is one packet with as
+// many of them as fit. It always returns a non-nil error, except after
+// Close.
+func (f *Feed) ServeRetransmit(pc net.PacketConn) error {
+	if !f.track(nil, pc) {
+		pc.Close()
+		return nil
+	}
+	buf := make([]byte, itch.MaxPacketSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if f.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if n != itch.PacketHeaderSize {
+			continue
+		}
+		req, _ := itch.ParseHeader(buf[:n])
+		if _, err := pc.WriteTo(f.retransmission(req), addr); err != nil {
+			f.logger.Debug("itch retransmission not sent", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// retransmission builds the answer to a retransmission request. A request
+// for another session is answered with an end-of-session packet naming
+// this one; a request for messages no longer retained, with an empty
+// packet whose sequence number is the oldest one that is.
+func (f *Feed) retransmission(req itch.PacketHeader) []byte {
+	if req.Session != "" && req.Session != f.session {
+		_, next := f.bounds()
+		return f.header(next, itch.EndOfSession)
+	}
+	first, _ := f.bounds()
+	if req.Sequence < first || req.Count == 0 || req.Count == itch.EndOfSession {
+		return f.header(max(req.Sequence, first), 0)
+	}
+	b := f.read(req.Sequence, int(req.Count))
+	return f.packet(b.start, b.frames)
+}
+
+// Close ends the session with an end-of-messages system event, stops
+// accepting requests, and waits for the publisher and replay connections
+// to send their end-of-session packets.
+func (f *Feed) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.append(&itch.SystemEvent{Timestamp: time.Now(), EventCode: itch.EventEndOfMessages})
+	f.closed = true
+	close(f.notify)
+	f.notify = make(chan struct{})
+	for _, ln := range f.lns {
+		ln.Close()
+	}
+	for _, pc := range f.pcs {
+		pc.Close()
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return nil
+}
+
+// stream sends packets of consecutive messages starting at from until the
+// session ends, then an end-of-session packet. It returns errClosed once
+// that has been sent, or the first send error.
+func (f *Feed) stream(from uint64, send func([]byte) error) error {
+	ticker := time.NewTicker(itch.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		b := f.read(from, math.MaxUint16-1)
+		if len(b.frames) > 0 {
+			if err := send(f.packet(b.start, b.frames)); err != nil {
+				return err
+			}
+			from = b.start + uint64(len(b.frames))
+			ticker.Reset(itch.HeartbeatInterval)
+			continue
+		}
+		if b.closed {
+			if err := send(f.header(b.start, itch.EndOfSession)); err != nil {
+				return err
+			}
+			return errClosed
+		}
+		select {
+		case <-b.wait:
+		case <-ticker.C:
+			if err := send(f.header(b.start, 0)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// packet builds a packet of frames, the first of which has sequence number
+// seq.
+func (f *Feed) packet(seq uint64, frames [][]byte) []byte {
+	b := f.header(seq, uint16(len(frames)))
+	for _, frame := range frames {
+		b = append(b, frame...)
+	}
+	return b
+}
+
+// header builds a packet header with no messages after it.
+func (f *Feed) header(seq uint64, count uint16) []byte {
+	// The session is digits only and fits its width, so this cannot fail.
+	b, _ := itch.AppendHeader(make([]byte, 0, itch.MaxPacketSize), itch.PacketHeader{
+		Session:  f.session,
+		Sequence: seq,
+		Count:    count,
+	})
+	return b
+}
+
+// track records a listener or packet conn to be closed by Close. It
+// reports false if the feed is already closed.
+func (f *Feed) track(ln net.Listener, pc net.PacketConn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	if ln != nil {
+		f.lns = append(f.lns, ln)
+	}
+	if pc != nil {
+		f.pcs = append(f.pcs, pc)
+	}
+	return true
+}
+
+func (f *Feed) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
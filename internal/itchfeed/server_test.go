@@ -0,0 +1,278 @@
+package itchfeed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/pkg/itch"
+)
+
+func addOrder(f *Feed, orderID string) {
+	f.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", Timestamp: testTime, Orders: []engine.OrderEvent{
+		{Type: engine.OrderAdded, OrderID: orderID, Side: domain.OrderSideBid, Price: 100, Quantity: 1},
+	}})
+}
+
+// serveReplay starts the replay service on a loopback port.
+func serveReplay(t *testing.T, f *Feed) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go f.ServeReplay(ln)
+	return ln.Addr().String()
+}
+
+// serveRetransmit starts the retransmission service on a loopback port.
+func serveRetransmit(t *testing.T, f *Feed) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go f.ServeRetransmit(pc)
+	return pc.LocalAddr().String()
+}
+
+func recv(t *testing.T, c *itch.ReplayClient) (uint64, itch.Message) {
+	t.Helper()
+	seq, m, err := c.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	return seq, m
+}
+
+func TestReplay_HistoryThenLive(t *testing.T) {
+	f := newTestFeed(100)
+	defer f.Close()
+	addOrder(f, "o1")
+	addr := serveReplay(t, f)
+
+	c, err := itch.DialReplay(addr, "", 1)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	for _, want := range []byte{itch.TypeSystemEvent, itch.TypeSymbolDirectory, itch.TypeAddOrder} {
+		seq, m := recv(t, c)
+		if m.Type() != want {
+			t.Errorf("seq %d: got %c, want %c", seq, m.Type(), want)
+		}
+	}
+	if c.Session() != f.Session() {
+		t.Errorf("session = %q, want %q", c.Session(), f.Session())
+	}
+
+	addOrder(f, "o2")
+	seq, m := recv(t, c)
+	if seq != 4 || m.Type() != itch.TypeAddOrder {
+		t.Errorf("got %d %c, want 4 A", seq, m.Type())
+	}
+}
+
+func TestReplay_FromSequence(t *testing.T) {
+	f := newTestFeed(100)
+	defer f.Close()
+	addOrder(f, "o1")
+	addOrder(f, "o2")
+	addr := serveReplay(t, f)
+
+	c, err := itch.DialReplay(addr, f.Session(), 4)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if seq, m := recv(t, c); seq != 4 || m.(*itch.AddOrder).OrderRef != 2 {
+		t.Errorf("got %d %+v, want the second AddOrder at 4", seq, m)
+	}
+
+	// From zero, only what is published after the request.
+	live, err := itch.DialReplay(addr, "", 0)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer live.Close()
+	// The request may be read after any number of these.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				addOrder(f, fmt.Sprintf("live-%d", i))
+			}
+		}
+	}()
+	if seq, _ := recv(t, live); seq < 5 {
+		t.Errorf("got seq %d, want only messages after 4", seq)
+	}
+}
+
+func TestReplay_EvictedStartShowsGap(t *testing.T) {
+	f := newTestFeed(2)
+	defer f.Close()
+	addOrder(f, "o1")
+	addOrder(f, "o2")
+	addr := serveReplay(t, f)
+
+	c, err := itch.DialReplay(addr, "", 1)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if seq, _ := recv(t, c); seq != 3 {
+		t.Errorf("got seq %d, want 3, the oldest retained", seq)
+	}
+}
+
+func TestReplay_OtherSessionEnds(t *testing.T) {
+	f := newTestFeed(100)
+	defer f.Close()
+	addr := serveReplay(t, f)
+
+	c, err := itch.DialReplay(addr, "1", 1)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if _, _, err := c.Recv(); !errors.Is(err, itch.ErrEndOfSession) {
+		t.Fatalf("got %v, want ErrEndOfSession", err)
+	}
+	if c.Session() != f.Session() {
+		t.Errorf("session = %q, want the current one, %q", c.Session(), f.Session())
+	}
+}
+
+func TestReplay_CloseEndsSession(t *testing.T) {
+	f := newTestFeed(100)
+	addr := serveReplay(t, f)
+
+	c, err := itch.DialReplay(addr, "", 1)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	recv(t, c)
+
+	done := make(chan struct{})
+	go func() {
+		f.Close()
+		close(done)
+	}()
+	seq, m := recv(t, c)
+	if ev, ok := m.(*itch.SystemEvent); !ok || ev.EventCode != itch.EventEndOfMessages || seq != 2 {
+		t.Errorf("got %d %+v, want an end-of-messages event at 2", seq, m)
+	}
+	if _, _, err := c.Recv(); !errors.Is(err, itch.ErrEndOfSession) {
+		t.Errorf("got %v, want ErrEndOfSession", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	// Messages after Close are dropped.
+	addOrder(f, "late")
+	if _, next := f.bounds(); next != 3 {
+		t.Errorf("next = %d, want 3", next)
+	}
+}
+
+func TestRetransmit(t *testing.T) {
+	f := newTestFeed(4)
+	defer f.Close()
+	for _, id := range []string{"o1", "o2", "o3"} {
+		addOrder(f, id)
+	}
+	addr := serveRetransmit(t, f)
+
+	// 1 start of messages, 2 directory, 3–5 adds: 2–5 are retained.
+	h, msgs, err := itch.Retransmit(addr, f.Session(), 3, 2, 5*time.Second)
+	if err != nil {
+		t.Fatalf("retransmit: %v", err)
+	}
+	if h.Sequence != 3 || len(msgs) != 2 || msgs[1].(*itch.AddOrder).OrderRef != 2 {
+		t.Errorf("got %+v with %+v", h, msgs)
+	}
+
+	h, msgs, err = itch.Retransmit(addr, "", 1, 10, 5*time.Second)
+	if err != nil {
+		t.Fatalf("retransmit: %v", err)
+	}
+	if h.Sequence != 2 || len(msgs) != 0 {
+		t.Errorf("evicted request: got %+v with %d messages, want an empty packet at 2", h, len(msgs))
+	}
+
+	h, msgs, err = itch.Retransmit(addr, "", 9, 10, 5*time.Second)
+	if err != nil {
+		t.Fatalf("retransmit: %v", err)
+	}
+	if h.Sequence != 6 || len(msgs) != 0 {
+		t.Errorf("future request: got %+v with %d messages, want an empty packet at 6", h, len(msgs))
+	}
+
+	if _, _, err := itch.Retransmit(addr, "1", 1, 10, 5*time.Second); err == nil {
+		t.Error("expected an error for another session")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	f := newTestFeed(100)
+	f.Publish(conn)
+	addOrder(f, "o1")
+
+	var next uint64 = 1
+	buf := make([]byte, itch.MaxPacketSize)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for next < 4 {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		h, msgs, err := itch.ParsePacket(buf[:n])
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if h.Sequence != next {
+			t.Fatalf("packet at %d, want %d", h.Sequence, next)
+		}
+		next += uint64(len(msgs))
+	}
+
+	f.Close()
+	for {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		h, msgs, _ := itch.ParsePacket(buf[:n])
+		if h.Count == itch.EndOfSession {
+			if h.Sequence != 5 {
+				t.Errorf("end of session at %d, want 5", h.Sequence)
+			}
+			return
+		}
+		next += uint64(len(msgs))
+	}
+}
@@ -0,0 +1,146 @@
+package loadgen
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Buckets of a Histogram: values below subBucketCount nanoseconds have a
+// bucket each, and every power of two above that is split into
+// subBucketHalf buckets of equal width, so a bucket is never wider than
+// 1/subBucketHalf of the values in it.
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram is a high dynamic range histogram of latencies. Like an
+// HdrHistogram it keeps a fixed relative precision over any range instead
+// of a fixed bucket width: every recorded value is reported within 1.6% of
+// itself, from nanoseconds to hours, in a few kilobytes. It is not safe for
+// concurrent use.
+type Histogram struct {
+	counts   []int64 // by bucket index, grown as larger values arrive
+	total    int64
+	sum      int64
+	min, max int64
+}
+
+// Bucket is one non-empty bucket of a Histogram: Count values up to Value,
+// which make the recorded values up to Percentile percent of all of them.
+type Bucket struct {
+	Value      time.Duration
+	Count      int64
+	Percentile float64
+}
+
+// bucketIndex returns the bucket of v nanoseconds.
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return shift*subBucketHalf + int(v>>shift)
+}
+
+// bucketHighest returns the largest value in bucket i.
+func bucketHighest(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+	shift := (i - subBucketHalf) / subBucketHalf
+	sub := int64(i - shift*subBucketHalf)
+	return (sub+1)<<shift - 1
+}
+
+// Record adds a latency. Negative latencies are recorded as zero.
+func (h *Histogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	i := bucketIndex(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	h.max = max(h.max, v)
+	h.total++
+	h.sum += v
+}
+
+// Merge adds every value recorded in o.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.total == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]int64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	h.max = max(h.max, o.max)
+	h.total += o.total
+	h.sum += o.sum
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int64 { return h.total }
+
+// Min returns the smallest recorded value, exactly.
+func (h *Histogram) Min() time.Duration { return time.Duration(h.min) }
+
+// Max returns the largest recorded value, exactly.
+func (h *Histogram) Max() time.Duration { return time.Duration(h.max) }
+
+// Mean returns the mean of the recorded values, exactly.
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / h.total)
+}
+
+// ValueAt returns the value at percentile p, from 0 to 100: the largest
+// value of the bucket that holds the value p percent of the recorded values
+// are at or below. It is 0 when nothing was recorded.
+func (h *Histogram) ValueAt(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(p/100*float64(h.total))), 1)
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(min(max(bucketHighest(i), h.min), h.max))
+		}
+	}
+	return time.Duration(h.max)
+}
+
+// Buckets returns the non-empty buckets in ascending order, each with the
+// share of recorded values at or below it: the histogram's percentile
+// distribution.
+func (h *Histogram) Buckets() []Bucket {
+	var buckets []Bucket
+	var seen int64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		seen += c
+		buckets = append(buckets, Bucket{
+			Value:      time.Duration(min(bucketHighest(i), h.max)),
+			Count:      c,
+			Percentile: 100 * float64(seen) / float64(h.total),
+		})
+	}
+	return buckets
+}
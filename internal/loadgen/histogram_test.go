@@ -0,0 +1,120 @@
+package loadgen
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBucketIndex_Contiguous(t *testing.T) {
+	// Every bucket starts right after the previous one ends.
+	for i := 1; i < bucketIndex(1<<40); i++ {
+		lowest := bucketHighest(i-1) + 1
+		if got := bucketIndex(lowest); got != i {
+			t.Fatalf("value %d: expected bucket %d, got %d", lowest, i, got)
+		}
+		if got := bucketIndex(bucketHighest(i)); got != i {
+			t.Fatalf("value %d: expected bucket %d, got %d", bucketHighest(i), i, got)
+		}
+	}
+}
+
+func TestBucketIndex_RelativePrecision(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 10000 {
+		v := rng.Int64N(int64(time.Hour))
+		highest := bucketHighest(bucketIndex(v))
+		if highest < v {
+			t.Fatalf("value %d: bucket ends below it at %d", v, highest)
+		}
+		if err := float64(highest-v) / float64(max(v, 1)); err > 1.0/subBucketHalf {
+			t.Fatalf("value %d: bucket end %d is %.4f away", v, highest, err)
+		}
+	}
+}
+
+func TestHistogram_Percentiles(t *testing.T) {
+	var h Histogram
+	for v := 1; v <= 1000; v++ {
+		h.Record(time.Duration(v) * time.Microsecond)
+	}
+
+	if h.Count() != 1000 {
+		t.Errorf("expected 1000 values, got %d", h.Count())
+	}
+	if h.Min() != time.Microsecond || h.Max() != time.Millisecond {
+		t.Errorf("expected min 1µs and max 1ms, got %s and %s", h.Min(), h.Max())
+	}
+	if h.Mean() != 500500*time.Nanosecond {
+		t.Errorf("expected mean 500.5µs, got %s", h.Mean())
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Microsecond},
+		{50, 500 * time.Microsecond},
+		{99, 990 * time.Microsecond},
+		{99.9, 999 * time.Microsecond},
+		{100, time.Millisecond},
+	} {
+		got := h.ValueAt(tt.p)
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/subBucketHalf {
+			t.Errorf("p%v: expected about %s, got %s", tt.p, tt.want, got)
+		}
+	}
+}
+
+func TestHistogram_Empty(t *testing.T) {
+	var h Histogram
+	if h.ValueAt(99) != 0 || h.Mean() != 0 || h.Buckets() != nil {
+		t.Errorf("expected zero values from an empty histogram")
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	var a, b, all Histogram
+	rng := rand.New(rand.NewPCG(3, 4))
+	for i := range 5000 {
+		d := time.Duration(rng.Int64N(int64(time.Second)))
+		if i%3 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+		all.Record(d)
+	}
+	var merged Histogram
+	merged.Merge(&a)
+	merged.Merge(&b)
+
+	if merged.Count() != all.Count() || merged.Min() != all.Min() || merged.Max() != all.Max() || merged.Mean() != all.Mean() {
+		t.Errorf("expected the merge to match recording everything in one histogram")
+	}
+	if !slices.Equal(merged.Buckets(), all.Buckets()) {
+		t.Errorf("expected the same buckets after the merge")
+	}
+}
+
+func TestHistogram_Buckets(t *testing.T) {
+	var h Histogram
+	h.Record(-time.Second)
+	h.Record(10 * time.Millisecond)
+	h.Record(10 * time.Millisecond)
+	h.Record(time.Second)
+
+	buckets := h.Buckets()
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %+v", buckets)
+	}
+	if buckets[0].Value != 0 || buckets[0].Count != 1 || buckets[0].Percentile != 25 {
+		t.Errorf("unexpected first bucket %+v", buckets[0])
+	}
+	if buckets[1].Count != 2 || buckets[1].Percentile != 75 {
+		t.Errorf("unexpected second bucket %+v", buckets[1])
+	}
+	if buckets[2].Value != time.Second || buckets[2].Percentile != 100 {
+		t.Errorf("expected the last bucket to end at the max, got %+v", buckets[2])
+	}
+}
@@ -0,0 +1,149 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// HTTPTransport sends requests to the HTTP API with the admin key, which may
+// act for every broker.
+type HTTPTransport struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewHTTPTransport creates a transport for the API at baseURL that keeps up
+// to conns connections open.
+func NewHTTPTransport(baseURL, apiKey string, conns int) *HTTPTransport {
+	return &HTTPTransport{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConns:        conns,
+				MaxIdleConnsPerHost: conns,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// Endpoint implements Transport.
+func (h *HTTPTransport) Endpoint(op Op) string {
+	switch op {
+	case OpLimit:
+		return "POST /orders (limit)"
+	case OpMarket:
+		return "POST /orders (market)"
+	}
+	return "DELETE /orders/{order_id}"
+}
+
+// Submit implements Transport. The reference is the order ID.
+func (h *HTTPTransport) Submit(ctx context.Context, o Order) (string, error) {
+	body := map[string]any{
+		"type":            o.Type,
+		"broker_id":       o.BrokerID,
+		"document_number": o.DocumentNumber,
+		"side":            o.Side,
+		"symbol":          o.Symbol,
+		"quantity":        o.Quantity,
+	}
+	if o.Type == domain.OrderTypeLimit {
+		body["price"] = domain.CentsToDollars(o.Price)
+		body["expires_at"] = time.Now().Add(o.Lifetime).UTC().Format(time.RFC3339Nano)
+	}
+	var resp struct {
+		OrderID string `json:"order_id"`
+	}
+	if err := h.do(ctx, http.MethodPost, "/orders", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.OrderID, nil
+}
+
+// Cancel implements Transport.
+func (h *HTTPTransport) Cancel(ctx context.Context, _, ref string) error {
+	return h.do(ctx, http.MethodDelete, "/orders/"+url.PathEscape(ref), nil, nil)
+}
+
+// RegisterBroker registers a broker with cash dollars and shares of every
+// symbol.
+func (h *HTTPTransport) RegisterBroker(ctx context.Context, brokerID string, cash float64, symbols []string, shares int64) error {
+	holdings := make([]map[string]any, len(symbols))
+	for i, symbol := range symbols {
+		holdings[i] = map[string]any{"symbol": symbol, "quantity": shares}
+	}
+	return h.do(ctx, http.MethodPost, "/brokers", map[string]any{
+		"broker_id":        brokerID,
+		"initial_cash":     cash,
+		"initial_holdings": holdings,
+	}, nil)
+}
+
+// Close implements Transport.
+func (h *HTTPTransport) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// do sends a request and decodes a successful response into out, if it is
+// not nil. An error response is returned as a RejectedError whose reason is
+// the status code and the error code.
+func (h *HTTPTransport) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Drain the body so the connection can be reused.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		reason := fmt.Sprint(resp.StatusCode)
+		if e.Error != "" {
+			reason += " " + e.Error
+		}
+		return &RejectedError{Reason: reason}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
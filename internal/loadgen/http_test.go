@@ -0,0 +1,109 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	var gotOrder map[string]any
+	var gotBroker map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /brokers", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBroker)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin-key" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","message":"no"}`))
+			return
+		}
+		gotOrder = nil
+		json.NewDecoder(r.Body).Decode(&gotOrder)
+		if gotOrder["type"] == "market" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"no_liquidity","message":"No matching orders"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"order_id":"o-1","status":"pending"}`))
+	})
+	mux.HandleFunc("DELETE /orders/{order_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("order_id") != "o-1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"order_not_found","message":"Order not found"}`))
+			return
+		}
+		w.Write([]byte(`{"order_id":"o-1","status":"cancelled"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	h := NewHTTPTransport(srv.URL+"/", "admin-key", 4)
+	defer h.Close()
+	ctx := context.Background()
+
+	if err := h.RegisterBroker(ctx, "lg-1", 1000, []string{"AAPL"}, 50); err != nil {
+		t.Fatalf("RegisterBroker: %v", err)
+	}
+	holdings, _ := gotBroker["initial_holdings"].([]any)
+	if gotBroker["broker_id"] != "lg-1" || gotBroker["initial_cash"] != 1000.0 || len(holdings) != 1 {
+		t.Errorf("unexpected registration %v", gotBroker)
+	}
+
+	ref, err := h.Submit(ctx, Order{
+		Type: domain.OrderTypeLimit, BrokerID: "lg-1", DocumentNumber: "LG1", Side: domain.OrderSideBid,
+		Symbol: "AAPL", Price: 10025, Quantity: 10, Lifetime: time.Minute,
+	})
+	if err != nil || ref != "o-1" {
+		t.Fatalf("expected order o-1, got %q, %v", ref, err)
+	}
+	if gotOrder["price"] != 100.25 || gotOrder["side"] != "bid" || gotOrder["document_number"] != "LG1" {
+		t.Errorf("unexpected order body %v", gotOrder)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, gotOrder["expires_at"].(string))
+	if err != nil || time.Until(expiresAt) < 50*time.Second {
+		t.Errorf("expected expires_at a minute ahead, got %v", gotOrder["expires_at"])
+	}
+
+	_, err = h.Submit(ctx, Order{Type: domain.OrderTypeMarket, BrokerID: "lg-1", Side: domain.OrderSideAsk, Symbol: "AAPL", Quantity: 10})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != "409 no_liquidity" {
+		t.Errorf("expected a 409 no_liquidity rejection, got %v", err)
+	}
+	if _, ok := gotOrder["price"]; ok {
+		t.Errorf("expected no price on a market order, got %v", gotOrder)
+	}
+
+	if err := h.Cancel(ctx, "lg-1", "o-1"); err != nil {
+		t.Errorf("Cancel: %v", err)
+	}
+	if err := h.Cancel(ctx, "lg-1", "o-2"); !errors.As(err, &rejected) || rejected.Reason != "404 order_not_found" {
+		t.Errorf("expected a 404 order_not_found rejection, got %v", err)
+	}
+}
+
+func TestHTTPTransport_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	h := NewHTTPTransport(url, "", 1)
+	err := h.Cancel(context.Background(), "lg-1", "o-1")
+	var rejected *RejectedError
+	if err == nil || errors.As(err, &rejected) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+	if reason := failureReason(err); reason != "connection" {
+		t.Errorf("expected a connection failure, got %s", reason)
+	}
+}
@@ -0,0 +1,435 @@
+// Package loadgen drives a running exchange with a configurable order flow
+// and measures how it copes: throughput, errors and latency histograms for
+// every endpoint. Orders are sent through a Transport, so the same flow can
+// be sent over the HTTP API or the binary order entry protocol.
+//
+// With an arrival rate the load is open loop: requests are scheduled at
+// their arrival times whether or not earlier ones have been answered, and
+// latency is measured from the scheduled time, so a server that falls
+// behind is charged for the queueing it causes instead of slowing the load
+// down.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// Op is a kind of request in the order mix.
+type Op int
+
+const (
+	OpLimit Op = iota
+	OpMarket
+	OpCancel
+	numOps
+)
+
+// Arrival processes.
+const (
+	ArrivalPoisson = "poisson"
+	ArrivalUniform = "uniform"
+)
+
+// Price distributions.
+const (
+	PriceUniform = "uniform"
+	PriceNormal  = "normal"
+)
+
+// maxOpenOrders bounds the accepted limit orders kept as cancel targets.
+const maxOpenOrders = 100_000
+
+// Order is an order to send.
+type Order struct {
+	Type           domain.OrderType
+	BrokerID       string
+	DocumentNumber string
+	Side           domain.OrderSide
+	Symbol         string
+	Price          int64 // in cents; limit orders only
+	Quantity       int64
+	Lifetime       time.Duration // limit orders only
+}
+
+// Transport sends requests to the exchange over one protocol.
+type Transport interface {
+	// Submit enters an order and returns the reference Cancel takes for it.
+	Submit(ctx context.Context, o Order) (string, error)
+	// Cancel cancels the order with the reference Submit returned.
+	Cancel(ctx context.Context, brokerID, ref string) error
+	// Endpoint names the endpoint an Op is sent to, for the report.
+	Endpoint(op Op) string
+	Close() error
+}
+
+// RejectedError is a request the exchange answered with a refusal or an
+// error, as opposed to one that got no answer.
+type RejectedError struct {
+	Reason string // the error code, prefixed with the HTTP status or OUCH message
+}
+
+func (e *RejectedError) Error() string { return "rejected: " + e.Reason }
+
+// Config describes the load.
+type Config struct {
+	Seed        uint64
+	Duration    time.Duration
+	Rate        float64       // requests per second; 0 sends back to back (closed loop)
+	Arrival     string        // ArrivalPoisson or ArrivalUniform
+	Concurrency int           // requests in flight at most
+	Timeout     time.Duration // per request
+
+	Brokers      int
+	BrokerPrefix string  // broker IDs are BrokerPrefix-1 to BrokerPrefix-Brokers
+	Cash         float64 // initial cash of every broker, in dollars
+	Shares       int64   // initial holding of every broker in every symbol
+
+	LimitWeight  float64 // relative frequency of limit orders
+	MarketWeight float64 // relative frequency of market orders
+	CancelWeight float64 // relative frequency of cancels of accepted limit orders
+
+	Symbols           []string
+	Price             float64 // centre of limit prices, in dollars
+	PriceDistribution string  // PriceUniform or PriceNormal
+	PriceSpread       float64 // half-width (uniform) or standard deviation (normal) of limit prices, in dollars
+	MaxQuantity       int64   // orders are for 1 to MaxQuantity shares
+	Lifetime          time.Duration
+}
+
+// DefaultConfig returns thirty seconds of 500 requests per second, mostly
+// limit orders on two symbols.
+func DefaultConfig() Config {
+	return Config{
+		Seed:              1,
+		Duration:          30 * time.Second,
+		Rate:              500,
+		Arrival:           ArrivalPoisson,
+		Concurrency:       64,
+		Timeout:           5 * time.Second,
+		Brokers:           10,
+		BrokerPrefix:      "loadgen",
+		Cash:              1_000_000_000,
+		Shares:            1_000_000,
+		LimitWeight:       0.7,
+		MarketWeight:      0.1,
+		CancelWeight:      0.2,
+		Symbols:           []string{"AAPL", "MSFT"},
+		Price:             100,
+		PriceDistribution: PriceNormal,
+		PriceSpread:       1,
+		MaxQuantity:       100,
+		Lifetime:          time.Minute,
+	}
+}
+
+// validate reports the first setting the load cannot run with.
+func (c Config) validate() error {
+	switch {
+	case c.Duration <= 0:
+		return errors.New("duration must be positive")
+	case c.Rate < 0:
+		return errors.New("rate must not be negative")
+	case c.Arrival != ArrivalPoisson && c.Arrival != ArrivalUniform:
+		return fmt.Errorf("arrival must be %s or %s", ArrivalPoisson, ArrivalUniform)
+	case c.Concurrency < 1:
+		return errors.New("concurrency must be at least 1")
+	case c.Timeout <= 0:
+		return errors.New("timeout must be positive")
+	case c.Brokers < 1:
+		return errors.New("brokers must be at least 1")
+	case c.Cash < 0 || c.Shares < 0:
+		return errors.New("cash and shares must not be negative")
+	case c.LimitWeight < 0 || c.MarketWeight < 0 || c.CancelWeight < 0:
+		return errors.New("order mix weights must not be negative")
+	case c.LimitWeight+c.MarketWeight == 0:
+		return errors.New("the order mix must include limit or market orders")
+	case len(c.Symbols) == 0:
+		return errors.New("at least one symbol is required")
+	case c.Price <= 0:
+		return errors.New("price must be positive")
+	case c.PriceDistribution != PriceUniform && c.PriceDistribution != PriceNormal:
+		return fmt.Errorf("price distribution must be %s or %s", PriceUniform, PriceNormal)
+	case c.PriceSpread < 0:
+		return errors.New("price spread must not be negative")
+	case c.MaxQuantity < 1:
+		return errors.New("max quantity must be at least 1")
+	case c.Lifetime < time.Second:
+		return errors.New("order lifetime must be at least a second")
+	}
+	return nil
+}
+
+// BrokerIDs returns the IDs of the brokers the load trades as.
+func (c Config) BrokerIDs() []string {
+	ids := make([]string, c.Brokers)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%d", c.BrokerPrefix, i+1)
+	}
+	return ids
+}
+
+// job is one scheduled request.
+type job struct {
+	op       Op
+	order    Order
+	pick     uint64    // chooses the order a cancel targets
+	intended time.Time // when the request was due; zero in a closed loop
+}
+
+// openOrder is an accepted limit order a cancel may target.
+type openOrder struct {
+	brokerID string
+	ref      string
+}
+
+// openOrders is the pool of cancel targets.
+type openOrders struct {
+	mu     sync.Mutex
+	orders []openOrder
+}
+
+// add keeps an accepted order, replacing the one pick chooses once the pool
+// is full.
+func (p *openOrders) add(o openOrder, pick uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.orders) < maxOpenOrders {
+		p.orders = append(p.orders, o)
+		return
+	}
+	p.orders[pick%maxOpenOrders] = o
+}
+
+// take removes and returns the order pick chooses.
+func (p *openOrders) take(pick uint64) (openOrder, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.orders) == 0 {
+		return openOrder{}, false
+	}
+	i := int(pick % uint64(len(p.orders)))
+	o := p.orders[i]
+	last := len(p.orders) - 1
+	p.orders[i] = p.orders[last]
+	p.orders = p.orders[:last]
+	return o, true
+}
+
+// endpointStats accumulates the outcomes of one endpoint's requests.
+type endpointStats struct {
+	mu        sync.Mutex
+	requests  int64
+	succeeded int64
+	rejected  int64
+	failed    int64
+	skipped   int64
+	errors    map[string]int64
+	latency   Histogram
+}
+
+// record adds a request's outcome. Answered requests, accepted or not, are
+// added to the latency histogram; requests that got no answer are not.
+func (s *endpointStats) record(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	var rejected *RejectedError
+	switch {
+	case err == nil:
+		s.succeeded++
+	case errors.As(err, &rejected):
+		s.rejected++
+		s.errors[rejected.Reason]++
+	default:
+		s.failed++
+		s.errors[failureReason(err)]++
+		return
+	}
+	s.latency.Record(latency)
+}
+
+// failureReason classifies a request that got no answer.
+func failureReason(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+	return "connection"
+}
+
+// Run sends the load cfg describes through t, as the brokers
+// cfg.BrokerIDs(), which must already exist, and reports the outcome.
+// Cancelling ctx stops scheduling requests; those in flight are waited for
+// and included in the report.
+func Run(ctx context.Context, cfg Config, t Transport) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	var stats [numOps]*endpointStats
+	for i := range stats {
+		stats[i] = &endpointStats{errors: make(map[string]int64)}
+	}
+	pool := &openOrders{}
+
+	// Requests in flight finish even after ctx is cancelled.
+	reqCtx := context.WithoutCancel(ctx)
+	jobs := make(chan job, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				send(reqCtx, cfg, t, pool, stats[j.op], j)
+			}
+		}()
+	}
+
+	start := time.Now()
+	sent := schedule(ctx, cfg, jobs, start)
+	close(jobs)
+	wg.Wait()
+
+	return newReport(cfg, t, stats, sent, time.Since(start)), nil
+}
+
+// schedule generates the requests and queues them for the workers until
+// cfg.Duration has passed or ctx is cancelled, and returns how many it
+// queued. With a rate, requests are due at arrival times drawn from the
+// arrival process; without one, a request is queued whenever a worker is
+// free.
+func schedule(ctx context.Context, cfg Config, jobs chan<- job, start time.Time) int64 {
+	rng := newRand(cfg.Seed)
+	brokers := cfg.BrokerIDs()
+	deadline := start.Add(cfg.Duration)
+
+	var sent int64
+	next := start
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		j := newJob(cfg, rng, brokers)
+		if cfg.Rate > 0 {
+			next = next.Add(interarrival(cfg, rng))
+			if next.After(deadline) {
+				return sent
+			}
+			timer.Reset(time.Until(next))
+			select {
+			case <-ctx.Done():
+				return sent
+			case <-timer.C:
+			}
+			j.intended = next
+		} else if !time.Now().Before(deadline) {
+			return sent
+		}
+		select {
+		case <-ctx.Done():
+			return sent
+		case jobs <- j:
+			sent++
+		}
+	}
+}
+
+func newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// interarrival returns the time to the next arrival.
+func interarrival(cfg Config, rng *rand.Rand) time.Duration {
+	mean := float64(time.Second) / cfg.Rate
+	if cfg.Arrival == ArrivalUniform {
+		return time.Duration(mean)
+	}
+	return time.Duration(rng.ExpFloat64() * mean)
+}
+
+// newJob draws the next request from the order mix.
+func newJob(cfg Config, rng *rand.Rand, brokers []string) job {
+	n := rng.IntN(len(brokers))
+	j := job{
+		order: Order{
+			BrokerID:       brokers[n],
+			DocumentNumber: fmt.Sprintf("LG%d", n+1),
+			Side:           domain.OrderSideBid,
+			Symbol:         cfg.Symbols[rng.IntN(len(cfg.Symbols))],
+			Quantity:       1 + rng.Int64N(cfg.MaxQuantity),
+		},
+		pick: rng.Uint64(),
+	}
+	if rng.IntN(2) == 1 {
+		j.order.Side = domain.OrderSideAsk
+	}
+
+	r := rng.Float64() * (cfg.LimitWeight + cfg.MarketWeight + cfg.CancelWeight)
+	switch {
+	case r < cfg.LimitWeight:
+		j.op = OpLimit
+		j.order.Type = domain.OrderTypeLimit
+		j.order.Price = limitPrice(cfg, rng)
+		j.order.Lifetime = cfg.Lifetime
+	case r < cfg.LimitWeight+cfg.MarketWeight:
+		j.op = OpMarket
+		j.order.Type = domain.OrderTypeMarket
+	default:
+		j.op = OpCancel
+	}
+	return j
+}
+
+// limitPrice draws a limit price, in cents, from the price distribution.
+func limitPrice(cfg Config, rng *rand.Rand) int64 {
+	offset := cfg.PriceSpread * rng.NormFloat64()
+	if cfg.PriceDistribution == PriceUniform {
+		offset = cfg.PriceSpread * (2*rng.Float64() - 1)
+	}
+	return max(int64(math.Round((cfg.Price+offset)*100)), 1)
+}
+
+// send makes one request and records its outcome. A cancel with no
+// accepted limit order to target is skipped.
+func send(ctx context.Context, cfg Config, t Transport, pool *openOrders, stats *endpointStats, j job) {
+	var target openOrder
+	if j.op == OpCancel {
+		var ok bool
+		if target, ok = pool.take(j.pick); !ok {
+			stats.mu.Lock()
+			stats.skipped++
+			stats.mu.Unlock()
+			return
+		}
+	}
+
+	start := j.intended
+	if start.IsZero() {
+		start = time.Now()
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	var (
+		ref string
+		err error
+	)
+	if j.op == OpCancel {
+		err = t.Cancel(reqCtx, target.brokerID, target.ref)
+	} else {
+		ref, err = t.Submit(reqCtx, j.order)
+	}
+	stats.record(time.Since(start), err)
+	if err == nil && j.op == OpLimit {
+		pool.add(openOrder{brokerID: j.order.BrokerID, ref: ref}, j.pick)
+	}
+}
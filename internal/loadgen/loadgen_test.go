@@ -0,0 +1,290 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// fakeTransport accepts every order and rejects cancels of orders it has
+// already cancelled.
+type fakeTransport struct {
+	mu        sync.Mutex
+	orders    []Order
+	cancelled map[string]bool
+	inFlight  int
+	maxFlight int
+	delay     time.Duration
+}
+
+func newFakeTransport(delay time.Duration) *fakeTransport {
+	return &fakeTransport{cancelled: make(map[string]bool), delay: delay}
+}
+
+func (f *fakeTransport) enter() {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxFlight = max(f.maxFlight, f.inFlight)
+	f.mu.Unlock()
+	time.Sleep(f.delay)
+}
+
+func (f *fakeTransport) leave() {
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+}
+
+func (f *fakeTransport) Submit(_ context.Context, o Order) (string, error) {
+	f.enter()
+	defer f.leave()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.orders = append(f.orders, o)
+	if o.Type == domain.OrderTypeMarket && o.Quantity > 90 {
+		return "", &RejectedError{Reason: "409 no_liquidity"}
+	}
+	return fmt.Sprintf("%s/%d", o.BrokerID, len(f.orders)), nil
+}
+
+func (f *fakeTransport) Cancel(_ context.Context, brokerID, ref string) error {
+	f.enter()
+	defer f.leave()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(ref, brokerID+"/") {
+		return fmt.Errorf("order %s is not %s's", ref, brokerID)
+	}
+	if f.cancelled[ref] {
+		return &RejectedError{Reason: "409 order_not_cancellable"}
+	}
+	f.cancelled[ref] = true
+	return nil
+}
+
+func (f *fakeTransport) Endpoint(op Op) string { return fmt.Sprintf("op%d", op) }
+func (f *fakeTransport) Close() error          { return nil }
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Duration = 300 * time.Millisecond
+	cfg.Rate = 1000
+	cfg.Concurrency = 8
+	cfg.Brokers = 3
+	return cfg
+}
+
+func TestRun_OpenLoop(t *testing.T) {
+	cfg := testConfig()
+	ft := newFakeTransport(0)
+	report, err := Run(context.Background(), cfg, ft)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// About Rate × Duration arrivals; Poisson arrivals vary.
+	if report.Sent < 200 || report.Sent > 400 {
+		t.Errorf("expected about 300 requests, got %d", report.Sent)
+	}
+	if len(report.Endpoints) != 3 {
+		t.Fatalf("expected 3 endpoints, got %d", len(report.Endpoints))
+	}
+	limit, market, cancel := report.Endpoints[OpLimit], report.Endpoints[OpMarket], report.Endpoints[OpCancel]
+	if limit.Endpoint != "op0" || market.Endpoint != "op1" || cancel.Endpoint != "op2" {
+		t.Errorf("unexpected endpoint names %q, %q, %q", limit.Endpoint, market.Endpoint, cancel.Endpoint)
+	}
+
+	var total int64
+	for _, e := range report.Endpoints {
+		total += e.Requests + e.Skipped
+		if e.Requests != e.Succeeded+e.Rejected+e.Failed {
+			t.Errorf("%s: requests %d do not add up", e.Endpoint, e.Requests)
+		}
+		if e.Latency.Count() != e.Succeeded+e.Rejected {
+			t.Errorf("%s: expected a latency for every answer, got %d", e.Endpoint, e.Latency.Count())
+		}
+	}
+	if total != report.Sent {
+		t.Errorf("expected every request to be accounted for, got %d of %d", total, report.Sent)
+	}
+
+	// Roughly the configured mix.
+	if share := float64(limit.Requests) / float64(report.Sent); share < 0.55 || share > 0.85 {
+		t.Errorf("expected about 70%% limit orders, got %.2f", share)
+	}
+	if market.Rejected != market.Errors["409 no_liquidity"] || market.Failed != 0 {
+		t.Errorf("expected rejections by reason, got %+v", market.Errors)
+	}
+	if cancel.Succeeded == 0 || cancel.Failed != 0 {
+		t.Errorf("expected cancels of accepted orders by their broker, got %+v", cancel)
+	}
+}
+
+func TestRun_ClosedLoopConcurrency(t *testing.T) {
+	cfg := testConfig()
+	cfg.Rate = 0
+	cfg.Concurrency = 4
+	ft := newFakeTransport(2 * time.Millisecond)
+	report, err := Run(context.Background(), cfg, ft)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ft.maxFlight > cfg.Concurrency {
+		t.Errorf("expected at most %d requests in flight, got %d", cfg.Concurrency, ft.maxFlight)
+	}
+	if report.Answered == 0 || report.TargetRate != 0 {
+		t.Errorf("expected a closed loop to send requests, got %+v", report)
+	}
+}
+
+func TestRun_OpenLoopChargesQueueing(t *testing.T) {
+	// One worker answering in 5ms cannot keep up with 1000 arrivals a
+	// second, so requests wait for it and their latency includes the wait.
+	cfg := testConfig()
+	cfg.Duration = 200 * time.Millisecond
+	cfg.Concurrency = 1
+	cfg.Arrival = ArrivalUniform
+	report, err := Run(context.Background(), cfg, newFakeTransport(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if p99 := report.Endpoints[OpLimit].Latency.ValueAt(99); p99 < 20*time.Millisecond {
+		t.Errorf("expected queueing in the latency, got p99 %s", p99)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Duration = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	report, err := Run(ctx, cfg, newFakeTransport(0))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancelling to stop the load, ran for %s", elapsed)
+	}
+	if report.Sent == 0 {
+		t.Errorf("expected a report of the requests sent before cancelling")
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	cfg := testConfig()
+	cfg.Rate = 0
+	cfg.Concurrency = 1
+	cfg.Duration = 50 * time.Millisecond
+
+	first, second := newFakeTransport(0), newFakeTransport(0)
+	if _, err := Run(context.Background(), cfg, first); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, err := Run(context.Background(), cfg, second); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	n := min(len(first.orders), len(second.orders))
+	if n == 0 {
+		t.Fatal("expected orders")
+	}
+	for i := range n {
+		a, b := first.orders[i], second.orders[i]
+		if a != b {
+			t.Fatalf("order %d: expected the same flow from the same seed, got %+v and %+v", i, a, b)
+		}
+	}
+}
+
+func TestNewJob_PriceDistribution(t *testing.T) {
+	for _, dist := range []string{PriceUniform, PriceNormal} {
+		cfg := DefaultConfig()
+		cfg.PriceDistribution = dist
+		cfg.LimitWeight, cfg.MarketWeight, cfg.CancelWeight = 1, 0, 0
+		rng := newRand(1)
+
+		var sum, below, above int64
+		for range 10000 {
+			j := newJob(cfg, rng, cfg.BrokerIDs())
+			if j.op != OpLimit || j.order.Lifetime != cfg.Lifetime {
+				t.Fatalf("%s: expected limit orders only, got %+v", dist, j)
+			}
+			p := j.order.Price
+			sum += p
+			if p < 9900 {
+				below++
+			}
+			if p > 10100 {
+				above++
+			}
+		}
+		if mean := sum / 10000; mean < 9990 || mean > 10010 {
+			t.Errorf("%s: expected prices centred on 100.00, got mean %d cents", dist, mean)
+		}
+		switch dist {
+		case PriceUniform:
+			if below != 0 || above != 0 {
+				t.Errorf("uniform: expected prices within the spread, got %d below and %d above", below, above)
+			}
+		case PriceNormal:
+			// About 32% of a normal distribution is over a standard
+			// deviation away.
+			if out := below + above; out < 2800 || out > 3600 {
+				t.Errorf("normal: expected about 3200 prices outside a standard deviation, got %d", out)
+			}
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"duration", func(c *Config) { c.Duration = 0 }, "duration"},
+		{"arrival", func(c *Config) { c.Arrival = "bursty" }, "arrival"},
+		{"concurrency", func(c *Config) { c.Concurrency = 0 }, "concurrency"},
+		{"only cancels", func(c *Config) { c.LimitWeight, c.MarketWeight = 0, 0 }, "limit or market"},
+		{"negative weight", func(c *Config) { c.CancelWeight = -1 }, "weights"},
+		{"price distribution", func(c *Config) { c.PriceDistribution = "pareto" }, "price distribution"},
+		{"lifetime", func(c *Config) { c.Lifetime = time.Millisecond }, "lifetime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg)
+			_, err := Run(context.Background(), cfg, newFakeTransport(0))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOpenOrders(t *testing.T) {
+	var p openOrders
+	if _, ok := p.take(0); ok {
+		t.Fatal("expected nothing to take from an empty pool")
+	}
+	p.add(openOrder{brokerID: "a", ref: "1"}, 0)
+	p.add(openOrder{brokerID: "b", ref: "2"}, 0)
+
+	o, ok := p.take(1)
+	if !ok || o.ref != "2" {
+		t.Errorf("expected to take order 2, got %+v", o)
+	}
+	o, ok = p.take(7)
+	if !ok || o.ref != "1" {
+		t.Errorf("expected to take order 1, got %+v", o)
+	}
+	if _, ok := p.take(0); ok {
+		t.Error("expected every order to be taken once")
+	}
+}
@@ -0,0 +1,248 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+// errSessionClosed is returned for requests pending when a session ends.
+var errSessionClosed = errors.New("ouch session closed")
+
+// OUCHTransport sends requests over the binary order entry protocol, on one
+// session per broker.
+type OUCHTransport struct {
+	sessions map[string]*ouchSession
+}
+
+// ouchSession is one broker's connection. Responses arrive on it in any
+// order, so requests wait for the response naming their token.
+type ouchSession struct {
+	client *ouch.Client
+
+	mu      sync.Mutex
+	tokens  uint64                       // last token issued
+	pending map[string]chan ouch.Message // by token, with a "cancel " prefix for cancels
+	err     error                        // why the session ended, once it has
+	done    chan struct{}
+}
+
+// DialOUCH logs on to the gateway at addr as every one of brokers, with an
+// API key that may act for all of them.
+func DialOUCH(addr, apiKey string, brokers []string) (*OUCHTransport, error) {
+	t := &OUCHTransport{sessions: make(map[string]*ouchSession, len(brokers))}
+	for _, brokerID := range brokers {
+		c, err := ouch.Dial(addr, brokerID, apiKey)
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("log on as %s: %w", brokerID, err)
+		}
+		s := &ouchSession{
+			client:  c,
+			pending: make(map[string]chan ouch.Message),
+			done:    make(chan struct{}),
+		}
+		t.sessions[brokerID] = s
+		go s.receive()
+	}
+	return t, nil
+}
+
+// Endpoint implements Transport.
+func (t *OUCHTransport) Endpoint(op Op) string {
+	switch op {
+	case OpLimit:
+		return "OUCH EnterOrder (limit)"
+	case OpMarket:
+		return "OUCH EnterOrder (market)"
+	}
+	return "OUCH CancelOrder"
+}
+
+// Submit implements Transport. The reference is the order's token, and the
+// order is answered by its Accepted or Rejected message.
+func (t *OUCHTransport) Submit(ctx context.Context, o Order) (string, error) {
+	s, err := t.session(o.BrokerID)
+	if err != nil {
+		return "", err
+	}
+	m := &ouch.EnterOrder{
+		OrderType: ouch.OrderTypeMarket,
+		Side:      ouch.SideBuy,
+		Shares:    uint32(o.Quantity),
+		Symbol:    o.Symbol,
+		Account:   o.DocumentNumber,
+	}
+	if o.Side == domain.OrderSideAsk {
+		m.Side = ouch.SideSell
+	}
+	if o.Type == domain.OrderTypeLimit {
+		m.OrderType = ouch.OrderTypeLimit
+		m.Price = uint64(o.Price)
+		m.TimeInForce = uint32((o.Lifetime + time.Second - 1) / time.Second)
+	}
+
+	s.mu.Lock()
+	s.tokens++
+	m.Token = strconv.FormatUint(s.tokens, 10)
+	s.mu.Unlock()
+
+	resp, err := s.request(ctx, m.Token, func() error { return s.client.EnterOrder(m) })
+	if err != nil {
+		return "", err
+	}
+	if r, ok := resp.(*ouch.Rejected); ok {
+		return "", &RejectedError{Reason: "Rejected " + rejectedReason(r.Reason)}
+	}
+	return m.Token, nil
+}
+
+// Cancel implements Transport. The cancel is answered by a Canceled
+// message or a CancelRejected one.
+func (t *OUCHTransport) Cancel(ctx context.Context, brokerID, ref string) error {
+	s, err := t.session(brokerID)
+	if err != nil {
+		return err
+	}
+	resp, err := s.request(ctx, "cancel "+ref, func() error { return s.client.CancelOrder(&ouch.CancelOrder{Token: ref}) })
+	if err != nil {
+		return err
+	}
+	if r, ok := resp.(*ouch.CancelRejected); ok {
+		return &RejectedError{Reason: "CancelRejected " + cancelRejectedReason(r.Reason)}
+	}
+	return nil
+}
+
+// Close implements Transport.
+func (t *OUCHTransport) Close() error {
+	var errs []error
+	for _, s := range t.sessions {
+		errs = append(errs, s.client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (t *OUCHTransport) session(brokerID string) (*ouchSession, error) {
+	s, ok := t.sessions[brokerID]
+	if !ok {
+		return nil, fmt.Errorf("no ouch session for broker %s", brokerID)
+	}
+	return s, nil
+}
+
+// request sends a message with send and waits for the response to key.
+func (s *ouchSession) request(ctx context.Context, key string, send func() error) (ouch.Message, error) {
+	ch := make(chan ouch.Message, 1)
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	s.pending[key] = ch
+	s.mu.Unlock()
+
+	if err := send(); err != nil {
+		s.forget(key)
+		return nil, err
+	}
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-s.done:
+		s.forget(key)
+		return nil, s.err
+	case <-ctx.Done():
+		s.forget(key)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *ouchSession) forget(key string) {
+	s.mu.Lock()
+	delete(s.pending, key)
+	s.mu.Unlock()
+}
+
+// receive hands every response to the request waiting for it until the
+// session ends. Executions, and cancellations nobody asked for, are not
+// responses and are dropped.
+func (s *ouchSession) receive() {
+	var err error
+	for {
+		var m ouch.Message
+		if m, err = s.client.Recv(); err != nil {
+			break
+		}
+		var key string
+		switch m := m.(type) {
+		case *ouch.Accepted:
+			key = m.Token
+		case *ouch.Rejected:
+			key = m.Token
+		case *ouch.Canceled:
+			if m.Reason == ouch.CancelReasonUser {
+				key = "cancel " + m.Token
+			}
+		case *ouch.CancelRejected:
+			key = "cancel " + m.Token
+		case *ouch.EndOfSession:
+			err = errSessionClosed
+		}
+		if err != nil {
+			break
+		}
+		if key == "" {
+			continue
+		}
+		s.mu.Lock()
+		ch, ok := s.pending[key]
+		delete(s.pending, key)
+		s.mu.Unlock()
+		if ok {
+			ch <- m
+		}
+	}
+
+	if !errors.Is(err, errSessionClosed) {
+		err = fmt.Errorf("%w: %v", errSessionClosed, err)
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	close(s.done)
+}
+
+func rejectedReason(reason byte) string {
+	switch reason {
+	case ouch.RejectInvalid:
+		return "invalid"
+	case ouch.RejectDuplicateToken:
+		return "duplicate_token"
+	case ouch.RejectInsufficientBalance:
+		return "insufficient_balance"
+	case ouch.RejectInsufficientHoldings:
+		return "insufficient_holdings"
+	case ouch.RejectNoLiquidity:
+		return "no_liquidity"
+	}
+	return "other"
+}
+
+func cancelRejectedReason(reason byte) string {
+	switch reason {
+	case ouch.CancelRejectUnknownToken:
+		return "unknown_token"
+	case ouch.CancelRejectTooLate:
+		return "too_late"
+	case ouch.CancelRejectInvalid:
+		return "invalid"
+	}
+	return "other"
+}
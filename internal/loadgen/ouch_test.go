@@ -0,0 +1,210 @@
+package loadgen
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+// fakeGateway serves the order entry protocol for brokers it knows. It
+// accepts limit orders, fills market orders for up to 90 shares and
+// cancels the rest, and cancels open orders once.
+func fakeGateway(t *testing.T, brokers ...string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+	known := make(map[string]bool)
+	for _, b := range brokers {
+		known[b] = true
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				serveFakeSession(conn, known)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeSession(conn net.Conn, known map[string]bool) {
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	m, err := ouch.ReadClientMessage(r)
+	if err != nil {
+		return
+	}
+	if login := m.(*ouch.LoginRequest); !known[login.BrokerID] || login.APIKey != "test-admin-key" {
+		ouch.Write(conn, &ouch.LoginRejected{Reason: ouch.LoginRejectNotAuthorized})
+		return
+	}
+	ouch.Write(conn, &ouch.LoginAccepted{BrokerID: m.(*ouch.LoginRequest).BrokerID})
+
+	open := make(map[string]bool)
+	for {
+		m, err := ouch.ReadClientMessage(r)
+		if err != nil {
+			return
+		}
+		switch m := m.(type) {
+		case *ouch.EnterOrder:
+			if m.OrderType == ouch.OrderTypeMarket && m.Shares > 90 {
+				ouch.Write(conn, &ouch.Rejected{Token: m.Token, Reason: ouch.RejectNoLiquidity})
+				continue
+			}
+			ouch.Write(conn, &ouch.Accepted{Token: m.Token, OrderType: m.OrderType, Shares: m.Shares, Symbol: m.Symbol, Price: m.Price, TimeInForce: m.TimeInForce})
+			if m.OrderType == ouch.OrderTypeMarket {
+				ouch.Write(conn, &ouch.Executed{Token: m.Token, ExecutedShares: 1, ExecutionPrice: 10000})
+				ouch.Write(conn, &ouch.Canceled{Token: m.Token, DecrementShares: m.Shares - 1, Reason: ouch.CancelReasonIOC})
+				continue
+			}
+			if m.TimeInForce != 60 || m.Price != 10025 {
+				ouch.Write(conn, &ouch.Canceled{Token: m.Token, Reason: ouch.CancelReasonTimeout})
+				continue
+			}
+			open[m.Token] = true
+		case *ouch.CancelOrder:
+			if !open[m.Token] {
+				ouch.Write(conn, &ouch.CancelRejected{Token: m.Token, Reason: ouch.CancelRejectTooLate})
+				continue
+			}
+			delete(open, m.Token)
+			ouch.Write(conn, &ouch.Canceled{Token: m.Token, Reason: ouch.CancelReasonUser})
+		case *ouch.LogoutRequest:
+			ouch.Write(conn, &ouch.EndOfSession{})
+			return
+		}
+	}
+}
+
+func TestOUCHTransport(t *testing.T) {
+	addr := fakeGateway(t, "lg-1", "lg-2")
+	ot, err := DialOUCH(addr, "test-admin-key", []string{"lg-1", "lg-2"})
+	if err != nil {
+		t.Fatalf("DialOUCH: %v", err)
+	}
+	defer ot.Close()
+	ctx := context.Background()
+
+	limit := Order{
+		Type: domain.OrderTypeLimit, BrokerID: "lg-1", DocumentNumber: "LG1", Side: domain.OrderSideBid,
+		Symbol: "AAPL", Price: 10025, Quantity: 10, Lifetime: 59500 * time.Millisecond,
+	}
+	ref, err := ot.Submit(ctx, limit)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// A market order's fill and IOC cancellation are not answers to a cancel.
+	if _, err := ot.Submit(ctx, Order{Type: domain.OrderTypeMarket, BrokerID: "lg-1", Side: domain.OrderSideAsk, Symbol: "AAPL", Quantity: 10}); err != nil {
+		t.Fatalf("Submit market: %v", err)
+	}
+	_, err = ot.Submit(ctx, Order{Type: domain.OrderTypeMarket, BrokerID: "lg-1", Side: domain.OrderSideAsk, Symbol: "AAPL", Quantity: 95})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != "Rejected no_liquidity" {
+		t.Errorf("expected a no_liquidity rejection, got %v", err)
+	}
+
+	if err := ot.Cancel(ctx, "lg-1", ref); err != nil {
+		t.Errorf("Cancel: %v", err)
+	}
+	if err := ot.Cancel(ctx, "lg-1", ref); !errors.As(err, &rejected) || rejected.Reason != "CancelRejected too_late" {
+		t.Errorf("expected a too_late rejection, got %v", err)
+	}
+	if err := ot.Cancel(ctx, "lg-9", ref); err == nil {
+		t.Error("expected an error for a broker without a session")
+	}
+}
+
+func TestOUCHTransport_Concurrent(t *testing.T) {
+	addr := fakeGateway(t, "lg-1")
+	ot, err := DialOUCH(addr, "test-admin-key", []string{"lg-1"})
+	if err != nil {
+		t.Fatalf("DialOUCH: %v", err)
+	}
+	defer ot.Close()
+
+	var wg sync.WaitGroup
+	refs := make(chan string, 50)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref, err := ot.Submit(context.Background(), Order{
+				Type: domain.OrderTypeLimit, BrokerID: "lg-1", Side: domain.OrderSideBid,
+				Symbol: "AAPL", Price: 10025, Quantity: int64(i + 1), Lifetime: time.Minute,
+			})
+			if err != nil {
+				t.Errorf("Submit: %v", err)
+				return
+			}
+			refs <- ref
+		}()
+	}
+	wg.Wait()
+	close(refs)
+
+	seen := make(map[string]bool)
+	for ref := range refs {
+		if seen[ref] {
+			t.Errorf("token %s issued twice", ref)
+		}
+		seen[ref] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 accepted orders, got %d", len(seen))
+	}
+}
+
+func TestOUCHTransport_SessionClosed(t *testing.T) {
+	addr := fakeGateway(t, "lg-1")
+	ot, err := DialOUCH(addr, "test-admin-key", []string{"lg-1"})
+	if err != nil {
+		t.Fatalf("DialOUCH: %v", err)
+	}
+	ot.Close()
+
+	_, err = ot.Submit(context.Background(), Order{Type: domain.OrderTypeMarket, BrokerID: "lg-1", Side: domain.OrderSideBid, Symbol: "AAPL", Quantity: 1})
+	if err == nil {
+		t.Fatal("expected an error on a closed session")
+	}
+	if reason := failureReason(err); reason != "connection" {
+		t.Errorf("expected a connection failure, got %s", reason)
+	}
+}
+
+func TestDialOUCH_LoginRejected(t *testing.T) {
+	addr := fakeGateway(t, "lg-1")
+	_, err := DialOUCH(addr, "test-admin-key", []string{"lg-1", "lg-2"})
+	var loginErr *ouch.LoginError
+	if !errors.As(err, &loginErr) {
+		t.Fatalf("expected a login error, got %v", err)
+	}
+	if want := fmt.Sprintf("log on as lg-2: %v", loginErr); err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
@@ -0,0 +1,232 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// reportPercentiles are the percentiles a report lists for every endpoint.
+var reportPercentiles = []float64{50, 90, 99, 99.9, 99.99}
+
+// Report is the outcome of a load run.
+type Report struct {
+	Elapsed    time.Duration
+	TargetRate float64 // requests per second; 0 for a closed loop
+	Sent       int64   // requests scheduled, including skipped cancels
+	Answered   int64   // requests answered, accepted or not
+	Throughput float64 // answered requests per second
+	Endpoints  []EndpointReport
+}
+
+// EndpointReport is the outcome of one endpoint's requests.
+type EndpointReport struct {
+	Endpoint   string
+	Requests   int64
+	Succeeded  int64
+	Rejected   int64            // answered with a refusal or an error
+	Failed     int64            // not answered: timeouts and connection errors
+	Skipped    int64            // cancels with no accepted order to target
+	Errors     map[string]int64 // rejection and failure counts by reason
+	Throughput float64          // answered requests per second
+	Latency    *Histogram       // of answered requests
+}
+
+func newReport(cfg Config, t Transport, stats [numOps]*endpointStats, sent int64, elapsed time.Duration) *Report {
+	r := &Report{Elapsed: elapsed, TargetRate: cfg.Rate, Sent: sent}
+	for op, s := range stats {
+		e := EndpointReport{
+			Endpoint:  t.Endpoint(Op(op)),
+			Requests:  s.requests,
+			Succeeded: s.succeeded,
+			Rejected:  s.rejected,
+			Failed:    s.failed,
+			Skipped:   s.skipped,
+			Errors:    s.errors,
+			Latency:   &s.latency,
+		}
+		answered := s.succeeded + s.rejected
+		e.Throughput = float64(answered) / elapsed.Seconds()
+		r.Answered += answered
+		r.Endpoints = append(r.Endpoints, e)
+	}
+	r.Throughput = float64(r.Answered) / elapsed.Seconds()
+	return r
+}
+
+// ErrorReasons returns the endpoint's error reasons, most frequent first.
+func (e EndpointReport) ErrorReasons() []string {
+	reasons := make([]string, 0, len(e.Errors))
+	for reason := range e.Errors {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if e.Errors[reasons[i]] != e.Errors[reasons[j]] {
+			return e.Errors[reasons[i]] > e.Errors[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	return reasons
+}
+
+// WriteText writes the report as tables: throughput and errors, then
+// latency percentiles, for every endpoint. With distribution, every
+// endpoint's full latency histogram follows, as a percentile distribution.
+func (r *Report) WriteText(out io.Writer, distribution bool) error {
+	rate := "closed loop"
+	if r.TargetRate > 0 {
+		rate = fmt.Sprintf("target %.0f req/s", r.TargetRate)
+	}
+	fmt.Fprintf(out, "%d requests in %s (%s): %d answered, %.1f req/s\n\n",
+		r.Sent, r.Elapsed.Round(time.Millisecond), rate, r.Answered, r.Throughput)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENDPOINT\tREQUESTS\tOK\tREJECTED\tFAILED\tSKIPPED\tREQ/S\tERRORS")
+	for _, e := range r.Endpoints {
+		reasons := e.ErrorReasons()
+		for i, reason := range reasons {
+			reasons[i] = fmt.Sprintf("%s ×%d", reason, e.Errors[reason])
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\n",
+			e.Endpoint, e.Requests, e.Succeeded, e.Rejected, e.Failed, e.Skipped, e.Throughput, strings.Join(reasons, ", "))
+	}
+
+	fmt.Fprint(w, "\nENDPOINT\tMIN\tMEAN")
+	for _, p := range reportPercentiles {
+		fmt.Fprintf(w, "\tP%v", p)
+	}
+	fmt.Fprintln(w, "\tMAX")
+	for _, e := range r.Endpoints {
+		h := e.Latency
+		fmt.Fprintf(w, "%s\t%s\t%s", e.Endpoint, latency(h.Min()), latency(h.Mean()))
+		for _, p := range reportPercentiles {
+			fmt.Fprintf(w, "\t%s", latency(h.ValueAt(p)))
+		}
+		fmt.Fprintf(w, "\t%s\n", latency(h.Max()))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !distribution {
+		return nil
+	}
+	for _, e := range r.Endpoints {
+		fmt.Fprintf(out, "\n%s latency distribution\n", e.Endpoint)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "VALUE\tPERCENTILE\tCOUNT\t")
+		for _, b := range e.Latency.Buckets() {
+			fmt.Fprintf(w, "%s\t%.4f%%\t%d\t\n", latency(b.Value), b.Percentile, b.Count)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latency formats a latency with three significant digits.
+func latency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	case d >= time.Microsecond:
+		return d.Round(10 * time.Nanosecond).String()
+	}
+	return d.String()
+}
+
+// jsonReport is the JSON form of a Report. Latencies are in microseconds.
+type jsonReport struct {
+	ElapsedSeconds float64        `json:"elapsed_seconds"`
+	TargetRate     float64        `json:"target_rate"`
+	Sent           int64          `json:"sent"`
+	Answered       int64          `json:"answered"`
+	Throughput     float64        `json:"throughput"`
+	Endpoints      []jsonEndpoint `json:"endpoints"`
+}
+
+type jsonEndpoint struct {
+	Endpoint   string           `json:"endpoint"`
+	Requests   int64            `json:"requests"`
+	Succeeded  int64            `json:"succeeded"`
+	Rejected   int64            `json:"rejected"`
+	Failed     int64            `json:"failed"`
+	Skipped    int64            `json:"skipped"`
+	Errors     map[string]int64 `json:"errors"`
+	Throughput float64          `json:"throughput"`
+	Latency    jsonLatency      `json:"latency"`
+}
+
+type jsonLatency struct {
+	Count       int64            `json:"count"`
+	MinUs       float64          `json:"min_us"`
+	MeanUs      float64          `json:"mean_us"`
+	MaxUs       float64          `json:"max_us"`
+	Percentiles []jsonPercentile `json:"percentiles"`
+	Histogram   []jsonBucket     `json:"histogram"`
+}
+
+type jsonPercentile struct {
+	Percentile float64 `json:"percentile"`
+	ValueUs    float64 `json:"value_us"`
+}
+
+type jsonBucket struct {
+	ValueUs    float64 `json:"value_us"`
+	Count      int64   `json:"count"`
+	Percentile float64 `json:"percentile"`
+}
+
+// WriteJSON writes the report as JSON, with every endpoint's full latency
+// histogram.
+func (r *Report) WriteJSON(out io.Writer) error {
+	jr := jsonReport{
+		ElapsedSeconds: r.Elapsed.Seconds(),
+		TargetRate:     r.TargetRate,
+		Sent:           r.Sent,
+		Answered:       r.Answered,
+		Throughput:     r.Throughput,
+	}
+	for _, e := range r.Endpoints {
+		h := e.Latency
+		je := jsonEndpoint{
+			Endpoint:   e.Endpoint,
+			Requests:   e.Requests,
+			Succeeded:  e.Succeeded,
+			Rejected:   e.Rejected,
+			Failed:     e.Failed,
+			Skipped:    e.Skipped,
+			Errors:     e.Errors,
+			Throughput: e.Throughput,
+			Latency: jsonLatency{
+				Count:       h.Count(),
+				MinUs:       micros(h.Min()),
+				MeanUs:      micros(h.Mean()),
+				MaxUs:       micros(h.Max()),
+				Percentiles: []jsonPercentile{},
+				Histogram:   []jsonBucket{},
+			},
+		}
+		for _, p := range reportPercentiles {
+			je.Latency.Percentiles = append(je.Latency.Percentiles, jsonPercentile{Percentile: p, ValueUs: micros(h.ValueAt(p))})
+		}
+		for _, b := range h.Buckets() {
+			je.Latency.Histogram = append(je.Latency.Histogram, jsonBucket{ValueUs: micros(b.Value), Count: b.Count, Percentile: b.Percentile})
+		}
+		jr.Endpoints = append(jr.Endpoints, je)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jr)
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
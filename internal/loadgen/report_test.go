@@ -0,0 +1,99 @@
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	var limit, cancel Histogram
+	for v := 1; v <= 100; v++ {
+		limit.Record(time.Duration(v) * time.Millisecond)
+	}
+	cancel.Record(500 * time.Microsecond)
+	return &Report{
+		Elapsed:    2 * time.Second,
+		TargetRate: 60,
+		Sent:       121,
+		Answered:   101,
+		Throughput: 50.5,
+		Endpoints: []EndpointReport{
+			{Endpoint: "POST /orders (limit)", Requests: 100, Succeeded: 100, Errors: map[string]int64{}, Throughput: 50, Latency: &limit},
+			{
+				Endpoint: "DELETE /orders/{order_id}", Requests: 20, Succeeded: 0, Rejected: 1, Failed: 19, Skipped: 1,
+				Errors: map[string]int64{"timeout": 4, "connection": 15, "409 order_not_cancellable": 1}, Throughput: 0.5, Latency: &cancel,
+			},
+		},
+	}
+}
+
+func TestEndpointReport_ErrorReasons(t *testing.T) {
+	got := testReport().Endpoints[1].ErrorReasons()
+	want := []string{"connection", "timeout", "409 order_not_cancellable"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestReport_WriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteText(&buf, false); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"121 requests in 2s (target 60 req/s): 101 answered, 50.5 req/s",
+		"connection ×15, timeout ×4, 409 order_not_cancellable ×1",
+		"P99.9",
+		"50.33ms", // the limit orders' median, to the histogram's precision
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "latency distribution") {
+		t.Errorf("expected no distribution unless asked for")
+	}
+
+	buf.Reset()
+	if err := testReport().WriteText(&buf, true); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	if !strings.Contains(buf.String(), "POST /orders (limit) latency distribution") || !strings.Contains(buf.String(), "100.0000%") {
+		t.Errorf("expected the distribution, got:\n%s", buf.String())
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.ElapsedSeconds != 2 || got.Sent != 121 || len(got.Endpoints) != 2 {
+		t.Fatalf("unexpected report %+v", got)
+	}
+	limit := got.Endpoints[0].Latency
+	if limit.Count != 100 || limit.MinUs != 1000 || limit.MaxUs != 100000 {
+		t.Errorf("unexpected latency summary %+v", limit)
+	}
+	if len(limit.Percentiles) != len(reportPercentiles) {
+		t.Errorf("expected %d percentiles, got %d", len(reportPercentiles), len(limit.Percentiles))
+	}
+	var count int64
+	for _, b := range limit.Histogram {
+		count += b.Count
+	}
+	if count != 100 || limit.Histogram[len(limit.Histogram)-1].Percentile != 100 {
+		t.Errorf("expected the histogram to hold every value, got %+v", limit.Histogram)
+	}
+	if got.Endpoints[1].Errors["timeout"] != 4 {
+		t.Errorf("expected error counts by reason, got %v", got.Endpoints[1].Errors)
+	}
+}
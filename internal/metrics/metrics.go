@@ -0,0 +1,289 @@
+// Package metrics exposes the exchange's Prometheus metrics: HTTP requests,
+// matching, order outcomes, trades, book depth, order expiration and webhook
+// delivery. Metrics is an engine.BookListener, from which it derives the
+// trade and book metrics, and an engine.ExpiryObserver. The services and the
+// router record the rest through its Observe methods.
+//
+// Every method is safe to call on a nil *Metrics, so components built
+// without metrics, as in most tests, need no checks.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+const namespace = "miniexchange"
+
+// latencyBuckets are the histogram buckets, in seconds, for in-process work
+// such as a matching pass or an expiration tick: 10µs to 100ms.
+var latencyBuckets = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1,
+}
+
+// bookSide is the depth of one side of a symbol's book, as the book
+// updates have described it.
+type bookSide struct {
+	levels map[int64]int // price → resting orders at that price
+	orders int
+}
+
+// Metrics holds the exchange's collectors and the registry that serves
+// them.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	matchDuration       *prometheus.HistogramVec
+	ordersAccepted      *prometheus.CounterVec
+	ordersRejected      *prometheus.CounterVec
+	trades              *prometheus.CounterVec
+	tradeNotional       *prometheus.CounterVec
+	restingOrders       *prometheus.GaugeVec
+	priceLevels         *prometheus.GaugeVec
+	expiryTickDuration  prometheus.Histogram
+	webhookAttempts     *prometheus.CounterVec
+	webhookDuration     *prometheus.HistogramVec
+	webhookDeliveries   *prometheus.CounterVec
+
+	mu    sync.Mutex
+	books map[string]map[domain.OrderSide]*bookSide // symbol → side → depth
+}
+
+// New creates a Metrics with its collectors registered on a fresh registry,
+// along with the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		matchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "matcher_duration_seconds",
+			Help:      "Time to match an incoming order, by symbol and order type.",
+			Buckets:   latencyBuckets,
+		}, []string{"symbol", "type"}),
+		ordersAccepted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_accepted_total",
+			Help:      "Orders accepted, by order type.",
+		}, []string{"type"}),
+		ordersRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_rejected_total",
+			Help:      "Order submissions rejected, by reason.",
+		}, []string{"reason"}),
+		trades: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "trades_total",
+			Help:      "Trades executed, by symbol.",
+		}, []string{"symbol"}),
+		tradeNotional: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "trade_notional_dollars_total",
+			Help:      "Notional value traded (price × quantity), in dollars, by symbol.",
+		}, []string{"symbol"}),
+		restingOrders: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "book_resting_orders",
+			Help:      "Orders resting on the book, by symbol and side.",
+		}, []string{"symbol", "side"}),
+		priceLevels: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "book_price_levels",
+			Help:      "Price levels on the book, by symbol and side.",
+		}, []string{"symbol", "side"}),
+		expiryTickDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "expiry_tick_duration_seconds",
+			Help:      "Time taken by each order expiration pass.",
+			Buckets:   latencyBuckets,
+		}),
+		webhookAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_attempts_total",
+			Help:      "Webhook delivery attempts, by event type and outcome (success or failure).",
+		}, []string{"event", "outcome"}),
+		webhookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_attempt_duration_seconds",
+			Help:      "Webhook delivery attempt latency, by event type.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"event"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Webhook deliveries completed, by event type and final status (succeeded or failed).",
+		}, []string{"event", "status"}),
+		books: make(map[string]map[domain.OrderSide]*bookSide),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.matchDuration,
+		m.ordersAccepted,
+		m.ordersRejected,
+		m.trades,
+		m.tradeNotional,
+		m.restingOrders,
+		m.priceLevels,
+		m.expiryTickDuration,
+		m.webhookAttempts,
+		m.webhookDuration,
+		m.webhookDeliveries,
+	)
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// WatchExpiryQueue reports length, sampled at every scrape, as the number
+// of orders waiting to expire.
+func (m *Metrics) WatchExpiryQueue(length func() int) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "expiry_queue_length",
+		Help:      "Resting orders tracked for expiration.",
+	}, func() float64 {
+		return float64(length())
+	}))
+}
+
+// ObserveHTTPRequest records a handled HTTP request. route is the matched
+// route pattern, such as /orders/{order_id}, so that the label set stays
+// bounded; requests that matched no route are recorded as "unmatched".
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = "unmatched"
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpRequestDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveMatch records how long the matcher took to process an incoming
+// order.
+func (m *Metrics) ObserveMatch(symbol string, orderType domain.OrderType, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.matchDuration.WithLabelValues(symbol, string(orderType)).Observe(d.Seconds())
+}
+
+// OrderAccepted counts an accepted order.
+func (m *Metrics) OrderAccepted(orderType domain.OrderType) {
+	if m == nil {
+		return
+	}
+	m.ordersAccepted.WithLabelValues(string(orderType)).Inc()
+}
+
+// OrderRejected counts a rejected order submission. reason is an error
+// code such as insufficient_balance or validation_error.
+func (m *Metrics) OrderRejected(reason string) {
+	if m == nil {
+		return
+	}
+	m.ordersRejected.WithLabelValues(reason).Inc()
+}
+
+// ObserveExpiryTick implements engine.ExpiryObserver.
+func (m *Metrics) ObserveExpiryTick(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.expiryTickDuration.Observe(d.Seconds())
+}
+
+// ObserveWebhookAttempt records one attempt to deliver an event.
+func (m *Metrics) ObserveWebhookAttempt(event string, ok bool, d time.Duration) {
+	if m == nil {
+		return
+	}
+	outcome := "success"
+	if !ok {
+		outcome = "failure"
+	}
+	m.webhookAttempts.WithLabelValues(event, outcome).Inc()
+	m.webhookDuration.WithLabelValues(event).Observe(d.Seconds())
+}
+
+// WebhookDeliveryCompleted counts a delivery that has succeeded or failed
+// for good.
+func (m *Metrics) WebhookDeliveryCompleted(event string, status domain.DeliveryStatus) {
+	if m == nil {
+		return
+	}
+	m.webhookDeliveries.WithLabelValues(event, string(status)).Inc()
+}
+
+// OnBookUpdate implements engine.BookListener. It counts the update's
+// trades and their notional, and applies its level changes to the book
+// depth gauges.
+func (m *Metrics) OnBookUpdate(u *engine.BookUpdate) {
+	if m == nil {
+		return
+	}
+	for _, t := range u.Trades {
+		m.trades.WithLabelValues(u.Symbol).Inc()
+		m.tradeNotional.WithLabelValues(u.Symbol).Add(float64(t.Price*t.Quantity) / 100)
+	}
+	if len(u.Levels) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sides := m.books[u.Symbol]
+	if sides == nil {
+		sides = map[domain.OrderSide]*bookSide{
+			domain.OrderSideBid: {levels: make(map[int64]int)},
+			domain.OrderSideAsk: {levels: make(map[int64]int)},
+		}
+		m.books[u.Symbol] = sides
+	}
+	for _, l := range u.Levels {
+		s := sides[l.Side]
+		s.orders -= s.levels[l.Price]
+		if l.TotalQuantity == 0 {
+			delete(s.levels, l.Price)
+			continue
+		}
+		s.levels[l.Price] = l.OrderCount
+		s.orders += l.OrderCount
+	}
+	for side, s := range sides {
+		m.restingOrders.WithLabelValues(u.Symbol, string(side)).Set(float64(s.orders))
+		m.priceLevels.WithLabelValues(u.Symbol, string(side)).Set(float64(len(s.levels)))
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+func TestNilMetrics_NoOp(t *testing.T) {
+	var m *Metrics
+	m.WatchExpiryQueue(func() int { return 1 })
+	m.ObserveHTTPRequest("GET", "/healthz", 200, time.Millisecond)
+	m.ObserveMatch("AAPL", domain.OrderTypeLimit, time.Millisecond)
+	m.OrderAccepted(domain.OrderTypeLimit)
+	m.OrderRejected("validation_error")
+	m.ObserveExpiryTick(time.Millisecond)
+	m.ObserveWebhookAttempt("trade.executed", true, time.Millisecond)
+	m.WebhookDeliveryCompleted("trade.executed", domain.DeliveryStatusSucceeded)
+	m.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL"})
+}
+
+func TestOnBookUpdate_TradesAndDepth(t *testing.T) {
+	m := New()
+
+	// Two asks at 100.00, one at 101.00, one bid at 99.00.
+	m.OnBookUpdate(&engine.BookUpdate{
+		Symbol: "AAPL",
+		Levels: []engine.LevelUpdate{
+			{Side: domain.OrderSideAsk, Price: 10000, TotalQuantity: 10, OrderCount: 2},
+			{Side: domain.OrderSideAsk, Price: 10100, TotalQuantity: 5, OrderCount: 1},
+			{Side: domain.OrderSideBid, Price: 9900, TotalQuantity: 5, OrderCount: 1},
+		},
+	})
+	// A bid takes both orders at 100.00.
+	m.OnBookUpdate(&engine.BookUpdate{
+		Symbol: "AAPL",
+		Trades: []engine.TradeEvent{
+			{Price: 10000, Quantity: 4},
+			{Price: 10000, Quantity: 6},
+		},
+		Levels: []engine.LevelUpdate{
+			{Side: domain.OrderSideAsk, Price: 10000, TotalQuantity: 0, OrderCount: 0},
+		},
+	})
+
+	if got := testutil.ToFloat64(m.trades.WithLabelValues("AAPL")); got != 2 {
+		t.Errorf("trades: expected 2, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.tradeNotional.WithLabelValues("AAPL")); got != 1000 {
+		t.Errorf("notional: expected 1000, got %v", got)
+	}
+	for _, tc := range []struct {
+		side           string
+		orders, levels float64
+	}{
+		{"ask", 1, 1},
+		{"bid", 1, 1},
+	} {
+		if got := testutil.ToFloat64(m.restingOrders.WithLabelValues("AAPL", tc.side)); got != tc.orders {
+			t.Errorf("%s resting orders: expected %v, got %v", tc.side, tc.orders, got)
+		}
+		if got := testutil.ToFloat64(m.priceLevels.WithLabelValues("AAPL", tc.side)); got != tc.levels {
+			t.Errorf("%s price levels: expected %v, got %v", tc.side, tc.levels, got)
+		}
+	}
+
+	// A level whose order count changes replaces its previous count.
+	m.OnBookUpdate(&engine.BookUpdate{
+		Symbol: "AAPL",
+		Levels: []engine.LevelUpdate{
+			{Side: domain.OrderSideBid, Price: 9900, TotalQuantity: 15, OrderCount: 3},
+		},
+	})
+	if got := testutil.ToFloat64(m.restingOrders.WithLabelValues("AAPL", "bid")); got != 3 {
+		t.Errorf("bid resting orders: expected 3, got %v", got)
+	}
+}
+
+func TestObserve_LabelsAndHandler(t *testing.T) {
+	m := New()
+	queued := 7
+	m.WatchExpiryQueue(func() int { return queued })
+	m.ObserveHTTPRequest("GET", "", 404, time.Millisecond)
+	m.ObserveWebhookAttempt("trade.executed", false, 20*time.Millisecond)
+	m.ObserveWebhookAttempt("trade.executed", true, 10*time.Millisecond)
+	m.WebhookDeliveryCompleted("trade.executed", domain.DeliveryStatusSucceeded)
+	m.OrderRejected("symbol_halted")
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched requests: expected 1, got %v", got)
+	}
+	for _, outcome := range []string{"success", "failure"} {
+		if got := testutil.ToFloat64(m.webhookAttempts.WithLabelValues("trade.executed", outcome)); got != 1 {
+			t.Errorf("%s attempts: expected 1, got %v", outcome, got)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, line := range []string{
+		`miniexchange_expiry_queue_length 7`,
+		`miniexchange_orders_rejected_total{reason="symbol_halted"} 1`,
+		`miniexchange_webhook_deliveries_total{event="trade.executed",status="succeeded"} 1`,
+		`miniexchange_webhook_delivery_attempt_duration_seconds_count{event="trade.executed"} 2`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package ouchgw serves the binary order entry protocol defined in pkg/ouch.
+// It is a transport like the HTTP handlers and the FIX acceptor: orders go
+// to the matcher through the order service, and reports are driven by the
+// broker events the services publish.
+package ouchgw
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+// loginTimeout bounds how long a new connection may take to send its
+// LoginRequest.
+const loginTimeout = 10 * time.Second
+
+// Gateway accepts order entry connections. A session logs on as a
+// registered broker, with an API key that may act for it, and enters orders
+// on its behalf. At most one connection
+// per broker may be logged on at a time; tokens and open orders survive
+// reconnects for the life of the process.
+type Gateway struct {
+	orderSvc  *service.OrderService
+	brokerSvc *service.BrokerService
+	apiKeySvc *service.APIKeyService
+	events    *service.EventStreamService
+	logger    *slog.Logger
+
+	mu     sync.Mutex
+	ln     net.Listener
+	closed bool
+	states map[string]*sessionState // broker ID → state, kept across reconnects
+	conns  map[net.Conn]*session    // nil until the connection has logged on
+	wg     sync.WaitGroup
+}
+
+// NewGateway creates a Gateway. Order reports are driven by the broker
+// events published to events.
+func NewGateway(
+	orderSvc *service.OrderService,
+	brokerSvc *service.BrokerService,
+	apiKeySvc *service.APIKeyService,
+	events *service.EventStreamService,
+	logger *slog.Logger,
+) *Gateway {
+	return &Gateway{
+		orderSvc:  orderSvc,
+		brokerSvc: brokerSvc,
+		apiKeySvc: apiKeySvc,
+		events:    events,
+		logger:    logger,
+		states:    make(map[string]*sessionState),
+		conns:     make(map[net.Conn]*session),
+	}
+}
+
+// Serve accepts connections on ln until Close is called. It always returns
+// a non-nil error, except after Close.
+func (g *Gateway) Serve(ln net.Listener) error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	g.ln = ln
+	g.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			g.mu.Lock()
+			closed := g.closed
+			g.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		g.mu.Lock()
+		if g.closed {
+			g.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		g.conns[conn] = nil
+		g.wg.Add(1)
+		g.mu.Unlock()
+
+		go func() {
+			defer g.wg.Done()
+			g.handleConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections, ends every active session with an
+// EndOfSession, and waits for their connections to close.
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	g.closed = true
+	if g.ln != nil {
+		g.ln.Close()
+	}
+	for conn, s := range g.conns {
+		if s != nil {
+			s.stop()
+		} else {
+			conn.Close()
+		}
+	}
+	g.mu.Unlock()
+
+	g.wg.Wait()
+	return nil
+}
+
+// handleConn waits for a valid LoginRequest and then runs the session.
+func (g *Gateway) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		g.mu.Lock()
+		delete(g.conns, conn)
+		g.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(loginTimeout))
+	m, err := ouch.ReadClientMessage(r)
+	if err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	login, ok := m.(*ouch.LoginRequest)
+	if !ok {
+		g.logger.Warn("ouch connection dropped: first message is not a login",
+			slog.String("remote_addr", conn.RemoteAddr().String()))
+		return
+	}
+	if !g.brokerSvc.Exists(login.BrokerID) {
+		g.logger.Warn("ouch login rejected: unknown broker", slog.String("broker_id", login.BrokerID))
+		rejectLogin(conn, ouch.LoginRejectNotAuthorized)
+		return
+	}
+	if p, err := g.apiKeySvc.Authenticate(login.APIKey); err != nil || !p.CanActFor(login.BrokerID) {
+		g.logger.Warn("ouch login rejected: invalid api key", slog.String("broker_id", login.BrokerID))
+		rejectLogin(conn, ouch.LoginRejectNotAuthorized)
+		return
+	}
+
+	st, err := g.acquire(login.BrokerID)
+	if err != nil {
+		g.logger.Warn("ouch login rejected", slog.String("broker_id", login.BrokerID), slog.String("error", err.Error()))
+		rejectLogin(conn, ouch.LoginRejectSessionActive)
+		return
+	}
+	defer g.release(st)
+
+	s := newSession(g, st, conn, r)
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return
+	}
+	g.conns[conn] = s
+	g.mu.Unlock()
+
+	s.run()
+}
+
+func rejectLogin(conn net.Conn, reason byte) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	ouch.Write(conn, &ouch.LoginRejected{Reason: reason})
+}
+
+var errSessionActive = errors.New("session is already logged on")
+
+// acquire returns the broker's session state, creating it on first use,
+// and marks it active.
+func (g *Gateway) acquire(brokerID string) (*sessionState, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	st, ok := g.states[brokerID]
+	if !ok {
+		st = newSessionState(brokerID)
+		g.states[brokerID] = st
+	}
+	if st.active {
+		return nil, errSessionActive
+	}
+	st.active = true
+	return st, nil
+}
+
+func (g *Gateway) release(st *sessionState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st.active = false
+}
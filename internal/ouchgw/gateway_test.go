@@ -0,0 +1,443 @@
+package ouchgw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+// testAdminKey authenticates test sessions; it may act for every broker.
+const testAdminKey = "test-admin-key-0123456789"
+
+type testEnv struct {
+	gateway   *Gateway
+	addr      string
+	orderSvc  *service.OrderService
+	events    *service.EventStreamService
+	expiryMgr *engine.ExpiryManager
+	apiKeySvc *service.APIKeyService
+}
+
+// newTestEnv starts a gateway on a loopback port and registers broker1
+// (cash) and broker2 (AAPL shares).
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	bs := store.NewBrokerStore()
+	os := store.NewOrderStore()
+	ts := store.NewTradeStore()
+	ws := store.NewWebhookStore()
+	sr := domain.NewSymbolRegistry()
+	bm := engine.NewBookManager(nil)
+	eventStreamSvc := service.NewEventStreamService(bs, 64, nil)
+	webhookSvc := service.NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, service.DeliveryPolicy{}, eventStreamSvc, nil, nil)
+	t.Cleanup(webhookSvc.Close)
+	m := engine.NewMatcher(bm, bs, os, ts, sr, webhookSvc, nil, nil, nil)
+	e := engine.NewExpiryManager(20*time.Millisecond, bm, os, bs, webhookSvc, nil, nil, nil)
+	brokerSvc := service.NewBrokerService(bs, sr, nil)
+	orderSvc := service.NewOrderService(m, e, bs, os, ts, webhookSvc, sr, nil, nil, nil, nil)
+	apiKeySvc := service.NewAPIKeyService(store.NewAPIKeyStore(), bs, testAdminKey)
+
+	for _, req := range []service.RegisterBrokerRequest{
+		{BrokerID: "broker1", InitialCash: 100_000},
+		{BrokerID: "broker2", InitialHoldings: []service.HoldingInput{{Symbol: "AAPL", Quantity: 1000}}},
+	} {
+		if _, err := brokerSvc.Register(req); err != nil {
+			t.Fatalf("register %s: %v", req.BrokerID, err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	g := NewGateway(orderSvc, brokerSvc, apiKeySvc, eventStreamSvc, logger)
+	go g.Serve(ln)
+	t.Cleanup(func() { g.Close() })
+
+	return &testEnv{
+		gateway:   g,
+		addr:      ln.Addr().String(),
+		orderSvc:  orderSvc,
+		events:    eventStreamSvc,
+		expiryMgr: e,
+		apiKeySvc: apiKeySvc,
+	}
+}
+
+func (env *testEnv) dial(t *testing.T, brokerID string) *ouch.Client {
+	t.Helper()
+	c, err := ouch.Dial(env.addr, brokerID, testAdminKey)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// waitReleased waits for the broker's session to end after its client
+// disconnected.
+func (env *testEnv) waitReleased(t *testing.T, brokerID string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		env.gateway.mu.Lock()
+		active := env.gateway.states[brokerID].active
+		env.gateway.mu.Unlock()
+		if !active {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session for %s still active", brokerID)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// recv reads the next message and checks its type.
+func recv[M ouch.Message](t *testing.T, c *ouch.Client) M {
+	t.Helper()
+	m, err := c.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	got, ok := m.(M)
+	if !ok {
+		var want M
+		t.Fatalf("got %T %+v, want %T", m, m, want)
+	}
+	return got
+}
+
+func limitOrder(token string, side byte, cents uint64, shares uint32) *ouch.EnterOrder {
+	return &ouch.EnterOrder{
+		Token:       token,
+		OrderType:   ouch.OrderTypeLimit,
+		Side:        side,
+		Shares:      shares,
+		Symbol:      "AAPL",
+		Price:       cents,
+		TimeInForce: 3600,
+		Account:     "12345678901",
+	}
+}
+
+// restAsk rests a broker2 ask through the order service.
+func (env *testEnv) restAsk(t *testing.T, price float64, qty int64) *domain.Order {
+	t.Helper()
+	expiresAt := time.Now().Add(time.Hour)
+	order, err := env.orderSvc.SubmitOrder(context.Background(), service.SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "broker2",
+		DocumentNumber: "98765432100",
+		Side:           domain.OrderSideAsk,
+		Symbol:         "AAPL",
+		Price:          &price,
+		Quantity:       qty,
+		ExpiresAt:      &expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("rest ask: %v", err)
+	}
+	return order
+}
+
+func TestGateway_Login_UnknownBroker(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := ouch.Dial(env.addr, "nobody", testAdminKey)
+	var le *ouch.LoginError
+	if !errors.As(err, &le) || le.Reason != ouch.LoginRejectNotAuthorized {
+		t.Fatalf("got %v, want a not authorized login error", err)
+	}
+}
+
+func TestGateway_Login_BrokerKey(t *testing.T) {
+	env := newTestEnv(t)
+	_, secret, err := env.apiKeySvc.Create("broker1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := ouch.Dial(env.addr, "broker1", secret)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c.Close()
+}
+
+func TestGateway_Login_InvalidAPIKey(t *testing.T) {
+	env := newTestEnv(t)
+	_, otherSecret, err := env.apiKeySvc.Create("broker2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, key := range map[string]string{
+		"missing":        "",
+		"unknown key":    "mx_not-a-key",
+		"another broker": otherSecret,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ouch.Dial(env.addr, "broker1", key)
+			var le *ouch.LoginError
+			if !errors.As(err, &le) || le.Reason != ouch.LoginRejectNotAuthorized {
+				t.Fatalf("got %v, want a not authorized login error", err)
+			}
+		})
+	}
+}
+
+func TestGateway_Login_AlreadyLoggedOn(t *testing.T) {
+	env := newTestEnv(t)
+	env.dial(t, "broker1")
+
+	_, err := ouch.Dial(env.addr, "broker1", testAdminKey)
+	var le *ouch.LoginError
+	if !errors.As(err, &le) || le.Reason != ouch.LoginRejectSessionActive {
+		t.Fatalf("got %v, want a session active login error", err)
+	}
+}
+
+func TestGateway_Logout(t *testing.T) {
+	env := newTestEnv(t)
+	c, err := ouch.Dial(env.addr, "broker1", testAdminKey)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c.Close()
+
+	// The broker can log on again once the session is released.
+	env.waitReleased(t, "broker1")
+	env.dial(t, "broker1")
+}
+
+func TestGateway_EnterOrder_AcceptAndExecute(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(limitOrder("t1", ouch.SideBuy, 1000, 10))
+	ack := recv[*ouch.Accepted](t, c)
+	if ack.Token != "t1" || ack.Shares != 10 || ack.Price != 1000 || ack.Symbol != "AAPL" || ack.OrderID == "" {
+		t.Errorf("accepted = %+v", ack)
+	}
+	order, err := env.orderSvc.GetOrder(ack.OrderID)
+	if err != nil || order.Price != 1000 || order.BrokerID != "broker1" {
+		t.Fatalf("exchange order = %+v, %v", order, err)
+	}
+
+	trade := env.restAsk(t, 10, 4).Trades[0]
+	exec := recv[*ouch.Executed](t, c)
+	if exec.Token != "t1" || exec.ExecutedShares != 4 || exec.ExecutionPrice != 1000 || exec.MatchNumber != trade.TradeID {
+		t.Errorf("executed = %+v, want 4 @ 1000 match %s", exec, trade.TradeID)
+	}
+}
+
+func TestGateway_EnterOrder_MarketRemainderCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	env.restAsk(t, 10, 5)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(&ouch.EnterOrder{
+		Token:     "m1",
+		OrderType: ouch.OrderTypeMarket,
+		Side:      ouch.SideBuy,
+		Shares:    8,
+		Symbol:    "AAPL",
+		Account:   "12345678901",
+	})
+	recv[*ouch.Accepted](t, c)
+	if exec := recv[*ouch.Executed](t, c); exec.ExecutedShares != 5 {
+		t.Errorf("executed %d, want 5", exec.ExecutedShares)
+	}
+	canceled := recv[*ouch.Canceled](t, c)
+	if canceled.DecrementShares != 3 || canceled.Reason != ouch.CancelReasonIOC {
+		t.Errorf("canceled = %+v, want 3 shares IOC", canceled)
+	}
+}
+
+func TestGateway_EnterOrder_Rejects(t *testing.T) {
+	market := limitOrder("r6", ouch.SideBuy, 0, 1)
+	market.OrderType = ouch.OrderTypeMarket
+	market.TimeInForce = 0
+	noTIF := limitOrder("r3", ouch.SideBuy, 1000, 1)
+	noTIF.TimeInForce = 0
+
+	tests := []struct {
+		name   string
+		msg    *ouch.EnterOrder
+		reason byte
+	}{
+		{"bad side", limitOrder("r1", 'X', 1000, 1), ouch.RejectInvalid},
+		{"zero price", limitOrder("r2", ouch.SideBuy, 0, 1), ouch.RejectInvalid},
+		{"no time in force", noTIF, ouch.RejectInvalid},
+		{"zero shares", limitOrder("r4", ouch.SideBuy, 1000, 0), ouch.RejectInvalid},
+		{"insufficient cash", limitOrder("r5", ouch.SideBuy, 1000, 1_000_000), ouch.RejectInsufficientBalance},
+		{"no liquidity", market, ouch.RejectNoLiquidity},
+		{"insufficient holdings", limitOrder("r7", ouch.SideSell, 1000, 1), ouch.RejectInsufficientHoldings},
+	}
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.EnterOrder(tc.msg)
+			rej := recv[*ouch.Rejected](t, c)
+			if rej.Token != tc.msg.Token || rej.Reason != tc.reason {
+				t.Errorf("rejected = %+v, want %q", rej, tc.reason)
+			}
+		})
+	}
+}
+
+func TestGateway_EnterOrder_DuplicateToken(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(limitOrder("dup", ouch.SideBuy, 1000, 1))
+	recv[*ouch.Accepted](t, c)
+	c.EnterOrder(limitOrder("dup", ouch.SideBuy, 1000, 1))
+	if rej := recv[*ouch.Rejected](t, c); rej.Reason != ouch.RejectDuplicateToken {
+		t.Errorf("reason = %q, want duplicate token", rej.Reason)
+	}
+}
+
+func TestGateway_CancelOrder(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(limitOrder("t1", ouch.SideBuy, 1000, 10))
+	recv[*ouch.Accepted](t, c)
+	env.restAsk(t, 10, 3)
+	recv[*ouch.Executed](t, c)
+
+	c.CancelOrder(&ouch.CancelOrder{Token: "t1"})
+	canceled := recv[*ouch.Canceled](t, c)
+	if canceled.Token != "t1" || canceled.DecrementShares != 7 || canceled.Reason != ouch.CancelReasonUser {
+		t.Errorf("canceled = %+v, want 7 shares by user", canceled)
+	}
+
+	// The order is no longer open.
+	c.CancelOrder(&ouch.CancelOrder{Token: "t1"})
+	if rej := recv[*ouch.CancelRejected](t, c); rej.Reason != ouch.CancelRejectTooLate {
+		t.Errorf("reason = %q, want too late", rej.Reason)
+	}
+	c.CancelOrder(&ouch.CancelOrder{Token: "nope"})
+	if rej := recv[*ouch.CancelRejected](t, c); rej.Reason != ouch.CancelRejectUnknownToken {
+		t.Errorf("reason = %q, want unknown token", rej.Reason)
+	}
+}
+
+func TestGateway_ReplaceOrder(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(limitOrder("t1", ouch.SideBuy, 1000, 10))
+	ack := recv[*ouch.Accepted](t, c)
+	env.restAsk(t, 10, 3)
+	recv[*ouch.Executed](t, c)
+
+	c.ReplaceOrder(&ouch.ReplaceOrder{ExistingToken: "t1", ReplacementToken: "t2", Shares: 12, Price: 950})
+	replaced := recv[*ouch.Replaced](t, c)
+	if replaced.ReplacementToken != "t2" || replaced.PreviousToken != "t1" || replaced.Shares != 9 || replaced.Price != 950 {
+		t.Errorf("replaced = %+v, want t1 → t2 with 9 open @ 950", replaced)
+	}
+	if replaced.OrderID == ack.OrderID {
+		t.Error("expected the replacement to be a new exchange order")
+	}
+
+	// The previous token no longer refers to an open order.
+	c.CancelOrder(&ouch.CancelOrder{Token: "t1"})
+	if rej := recv[*ouch.CancelRejected](t, c); rej.Reason != ouch.CancelRejectTooLate {
+		t.Errorf("reason = %q, want too late", rej.Reason)
+	}
+
+	env.restAsk(t, 9.5, 9)
+	if exec := recv[*ouch.Executed](t, c); exec.Token != "t2" || exec.ExecutedShares != 9 || exec.ExecutionPrice != 950 {
+		t.Errorf("executed = %+v", exec)
+	}
+}
+
+func TestGateway_ReplaceOrder_MarketOrderRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.restAsk(t, 10, 1)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(&ouch.EnterOrder{Token: "m1", OrderType: ouch.OrderTypeMarket, Side: ouch.SideBuy, Shares: 1, Symbol: "AAPL", Account: "1"})
+	recv[*ouch.Accepted](t, c)
+	recv[*ouch.Executed](t, c)
+	c.ReplaceOrder(&ouch.ReplaceOrder{ExistingToken: "m1", ReplacementToken: "m2", Shares: 2, Price: 1000})
+	if rej := recv[*ouch.CancelRejected](t, c); rej.Token != "m1" {
+		t.Errorf("cancel rejected = %+v", rej)
+	}
+}
+
+func TestGateway_Expiry(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	c.EnterOrder(limitOrder("t1", ouch.SideBuy, 1000, 10))
+	ack := recv[*ouch.Accepted](t, c)
+
+	// Subscribing synchronises with the acceptance event's publication, so
+	// the expiry goroutine is ordered after the order service is done with
+	// the order.
+	sub, _, err := env.events.Subscribe("broker1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env.events.Unsubscribe("broker1", sub)
+
+	// Time in force is in whole seconds; move the expiry closer instead of
+	// waiting one out.
+	order, _ := env.orderSvc.GetOrder(ack.OrderID)
+	soon := time.Now().Add(20 * time.Millisecond)
+	order.ExpiresAt = &soon
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.expiryMgr.Start(ctx)
+
+	canceled := recv[*ouch.Canceled](t, c)
+	if canceled.Reason != ouch.CancelReasonTimeout || canceled.DecrementShares != 10 {
+		t.Errorf("canceled = %+v, want 10 shares timed out", canceled)
+	}
+}
+
+func TestGateway_ReportsSurviveReconnect(t *testing.T) {
+	env := newTestEnv(t)
+	c, err := ouch.Dial(env.addr, "broker1", testAdminKey)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c.EnterOrder(limitOrder("t1", ouch.SideBuy, 1000, 10))
+	recv[*ouch.Accepted](t, c)
+	c.Close()
+	env.waitReleased(t, "broker1")
+
+	// Executed while disconnected.
+	env.restAsk(t, 10, 10)
+
+	c = env.dial(t, "broker1")
+	if exec := recv[*ouch.Executed](t, c); exec.Token != "t1" || exec.ExecutedShares != 10 {
+		t.Errorf("executed = %+v", exec)
+	}
+}
+
+func TestGateway_CloseEndsSessions(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.dial(t, "broker1")
+
+	go env.gateway.Close()
+	recv[*ouch.EndOfSession](t, c)
+	if _, err := c.Recv(); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
@@ -0,0 +1,398 @@
+package ouchgw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+// ouchOrder is an open order entered over the protocol, tracked so reports
+// can carry the client's token. After a replace, the replacement is a new
+// exchange order under the replacement token.
+type ouchOrder struct {
+	orderID       string // exchange order ID
+	token         string
+	previousToken string // token of the order it replaced, if any
+	orderType     byte
+	side          byte
+	symbol        string
+	account       string
+	price         uint64 // cents; 0 for market orders
+	shares        uint32 // as entered, or the replacement's open shares
+	timeInForce   uint32
+
+	exchangeQty    int64 // quantity of the current exchange order
+	exchangeFilled int64
+	baseQty        int64 // executed by the orders this one replaced
+
+	replacement  bool // acknowledged with Replaced rather than Accepted
+	acknowledged bool // its Accepted or Replaced has been sent
+	replaced     bool // cancelled as part of a replace; not reported
+}
+
+func (o *ouchOrder) leavesQty() int64 {
+	return o.exchangeQty - o.exchangeFilled
+}
+
+// brokerEvent is the subset of a broker event payload the gateway needs.
+type brokerEvent struct {
+	Data struct {
+		OrderID       string  `json:"order_id"`
+		TradeID       string  `json:"trade_id"`
+		TradePrice    float64 `json:"trade_price"`
+		TradeQuantity int64   `json:"trade_quantity"`
+	} `json:"data"`
+}
+
+// onEvent turns a broker event about an order entered on this session into
+// a report. Events for orders entered through other channels are skipped.
+func (s *session) onEvent(ev service.BrokerEvent) error {
+	id := ev.ID
+	s.st.lastEventID = &id
+
+	var payload brokerEvent
+	if err := json.Unmarshal(ev.Data, &payload); err != nil {
+		return fmt.Errorf("decode %s event: %w", ev.Event, err)
+	}
+	o, ok := s.st.orders[payload.Data.OrderID]
+	if !ok {
+		return nil
+	}
+	if err := s.acknowledge(o); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch ev.Event {
+	case "trade.executed":
+		price, err := domain.DollarsToCents(payload.Data.TradePrice)
+		if err != nil {
+			return fmt.Errorf("decode trade price: %w", err)
+		}
+		qty := payload.Data.TradeQuantity
+		o.exchangeFilled += qty
+		if o.leavesQty() == 0 {
+			delete(s.st.orders, o.orderID)
+		}
+		return s.send(&ouch.Executed{
+			Timestamp:      now,
+			Token:          o.token,
+			ExecutedShares: uint32(qty),
+			ExecutionPrice: uint64(price),
+			MatchNumber:    payload.Data.TradeID,
+		})
+	case "order.cancelled":
+		delete(s.st.orders, o.orderID)
+		if o.replaced {
+			return nil
+		}
+		return s.sendCanceled(o, ouch.CancelReasonUser)
+	case "order.expired":
+		delete(s.st.orders, o.orderID)
+		return s.sendCanceled(o, ouch.CancelReasonTimeout)
+	}
+	return nil
+}
+
+// acknowledge sends an order's Accepted, or Replaced for a replacement,
+// unless it has been sent already. order.accepted only fires for an order
+// that rests, so the acknowledgement goes out ahead of whichever report
+// comes first.
+func (s *session) acknowledge(o *ouchOrder) error {
+	if o.acknowledged {
+		return nil
+	}
+	o.acknowledged = true
+	if o.replacement {
+		return s.send(&ouch.Replaced{
+			Timestamp:        time.Now(),
+			ReplacementToken: o.token,
+			Shares:           o.shares,
+			Price:            o.price,
+			TimeInForce:      o.timeInForce,
+			OrderID:          o.orderID,
+			PreviousToken:    o.previousToken,
+		})
+	}
+	return s.send(&ouch.Accepted{
+		Timestamp:   time.Now(),
+		Token:       o.token,
+		OrderType:   o.orderType,
+		Side:        o.side,
+		Shares:      o.shares,
+		Symbol:      o.symbol,
+		Price:       o.price,
+		TimeInForce: o.timeInForce,
+		Account:     o.account,
+		OrderID:     o.orderID,
+	})
+}
+
+// onEnterOrder submits an EnterOrder. The acknowledgement and executions
+// are reported from the events the order service publishes.
+func (s *session) onEnterOrder(m *ouch.EnterOrder) error {
+	if m.Token == "" {
+		return s.reject(m.Token, ouch.RejectInvalid)
+	}
+	if _, ok := s.st.tokens[m.Token]; ok {
+		return s.reject(m.Token, ouch.RejectDuplicateToken)
+	}
+
+	req, err := s.parseOrder(m)
+	if err != nil {
+		return s.reject(m.Token, ouch.RejectInvalid)
+	}
+	order, err := s.g.orderSvc.SubmitOrder(context.Background(), *req)
+	if err != nil {
+		s.log.Debug("ouch order rejected", slog.String("token", m.Token), slog.String("error", err.Error()))
+		return s.reject(m.Token, rejectReason(err))
+	}
+
+	o := &ouchOrder{
+		orderID:     order.OrderID,
+		token:       m.Token,
+		orderType:   m.OrderType,
+		side:        m.Side,
+		symbol:      m.Symbol,
+		account:     m.Account,
+		price:       m.Price,
+		shares:      m.Shares,
+		timeInForce: m.TimeInForce,
+		exchangeQty: order.Quantity,
+	}
+	s.st.orders[order.OrderID] = o
+	s.st.tokens[m.Token] = order.OrderID
+
+	if err := s.drainEvents(); err != nil {
+		return err
+	}
+
+	// A market order's unfilled remainder is cancelled immediately (IOC)
+	// without an event of its own. Market orders never rest, so their
+	// status is final once SubmitOrder returns; one that found nothing to
+	// match has had no event at all.
+	if order.Type == domain.OrderTypeMarket && order.Status == domain.OrderStatusCancelled {
+		if err := s.acknowledge(o); err != nil {
+			return err
+		}
+		delete(s.st.orders, o.orderID)
+		return s.sendCanceled(o, ouch.CancelReasonIOC)
+	}
+	return nil
+}
+
+// parseOrder converts an EnterOrder to an order service request. Prices
+// arrive in cents; the service takes dollars.
+func (s *session) parseOrder(m *ouch.EnterOrder) (*service.SubmitOrderRequest, error) {
+	req := &service.SubmitOrderRequest{
+		BrokerID:       s.st.brokerID,
+		DocumentNumber: m.Account,
+		Symbol:         m.Symbol,
+		Quantity:       int64(m.Shares),
+	}
+
+	switch m.Side {
+	case ouch.SideBuy:
+		req.Side = domain.OrderSideBid
+	case ouch.SideSell:
+		req.Side = domain.OrderSideAsk
+	default:
+		return nil, fmt.Errorf("unsupported side %q", m.Side)
+	}
+
+	switch m.OrderType {
+	case ouch.OrderTypeMarket:
+		if m.Price != 0 || m.TimeInForce != 0 {
+			return nil, errors.New("market orders take no price or time in force")
+		}
+		req.Type = domain.OrderTypeMarket
+	case ouch.OrderTypeLimit:
+		price, err := dollars(m.Price)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt, err := expiry(m.TimeInForce)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = domain.OrderTypeLimit
+		req.Price = &price
+		req.ExpiresAt = &expiresAt
+	default:
+		return nil, fmt.Errorf("unsupported order type %q", m.OrderType)
+	}
+	return req, nil
+}
+
+// onCancelOrder cancels an open order. The cancellation is reported from
+// the order.cancelled event.
+func (s *session) onCancelOrder(m *ouch.CancelOrder) error {
+	o, reason := s.lookup(m.Token)
+	if o == nil {
+		return s.cancelReject(m.Token, reason)
+	}
+	if _, err := s.g.orderSvc.CancelOrder(context.Background(), o.orderID); err != nil {
+		return s.cancelReject(m.Token, cancelRejectReason(err))
+	}
+	return s.drainEvents()
+}
+
+// onReplaceOrder replaces an open limit order with a new price, quantity,
+// and optionally time in force. The exchange cancels the order and enters
+// a new one, reported as a single Replaced message.
+func (s *session) onReplaceOrder(m *ouch.ReplaceOrder) error {
+	o, reason := s.lookup(m.ExistingToken)
+	if o == nil {
+		return s.cancelReject(m.ExistingToken, reason)
+	}
+	if m.ReplacementToken == "" || o.orderType != ouch.OrderTypeLimit {
+		return s.cancelReject(m.ExistingToken, ouch.CancelRejectInvalid)
+	}
+	if _, ok := s.st.tokens[m.ReplacementToken]; ok {
+		return s.cancelReject(m.ExistingToken, ouch.CancelRejectInvalid)
+	}
+	price, err := dollars(m.Price)
+	if err != nil {
+		return s.cancelReject(m.ExistingToken, ouch.CancelRejectInvalid)
+	}
+
+	req := service.ReplaceOrderRequest{
+		OrderID: o.orderID,
+		Price:   price,
+		// Shares covers the whole chain of replaced orders; the exchange
+		// order only needs to cover what this one has not executed.
+		Quantity: int64(m.Shares) - o.baseQty,
+	}
+	if m.TimeInForce != 0 {
+		expiresAt, _ := expiry(m.TimeInForce)
+		req.ExpiresAt = &expiresAt
+	}
+
+	cancelled, replacement, err := s.g.orderSvc.ReplaceOrder(context.Background(), req)
+	if err != nil {
+		// If the original was cancelled before the replacement failed, the
+		// cancellation is still reported.
+		if err := s.cancelReject(m.ExistingToken, cancelRejectReason(err)); err != nil {
+			return err
+		}
+		return s.drainEvents()
+	}
+
+	s.st.tokens[m.ReplacementToken] = o.orderID
+	if replacement == nil {
+		// Executed up to the new quantity while being cancelled: only the
+		// cancellation is reported.
+		return s.drainEvents()
+	}
+
+	baseQty := o.baseQty
+	for _, t := range cancelled.Trades {
+		baseQty += t.Quantity
+	}
+
+	o.replaced = true
+	next := &ouchOrder{
+		orderID:       replacement.OrderID,
+		token:         m.ReplacementToken,
+		previousToken: o.token,
+		orderType:     o.orderType,
+		side:          o.side,
+		symbol:        o.symbol,
+		account:       o.account,
+		price:         m.Price,
+		shares:        uint32(replacement.Quantity),
+		timeInForce:   m.TimeInForce,
+		exchangeQty:   replacement.Quantity,
+		baseQty:       baseQty,
+		replacement:   true,
+	}
+	s.st.orders[replacement.OrderID] = next
+	s.st.tokens[m.ReplacementToken] = replacement.OrderID
+	return s.drainEvents()
+}
+
+// lookup finds the open order a token refers to. It returns nil with a
+// CancelRejected reason if there is none.
+func (s *session) lookup(token string) (*ouchOrder, byte) {
+	orderID, ok := s.st.tokens[token]
+	if !ok {
+		return nil, ouch.CancelRejectUnknownToken
+	}
+	o, ok := s.st.orders[orderID]
+	if !ok || o.token != token {
+		return nil, ouch.CancelRejectTooLate
+	}
+	return o, 0
+}
+
+func rejectReason(err error) byte {
+	var ve *domain.ValidationError
+	switch {
+	case errors.As(err, &ve), errors.Is(err, domain.ErrSymbolNotFound):
+		return ouch.RejectInvalid
+	case errors.Is(err, domain.ErrInsufficientBalance):
+		return ouch.RejectInsufficientBalance
+	case errors.Is(err, domain.ErrInsufficientHoldings):
+		return ouch.RejectInsufficientHoldings
+	case errors.Is(err, domain.ErrNoLiquidity):
+		return ouch.RejectNoLiquidity
+	}
+	return ouch.RejectOther
+}
+
+func cancelRejectReason(err error) byte {
+	var ve *domain.ValidationError
+	switch {
+	case errors.Is(err, domain.ErrOrderNotCancellable):
+		return ouch.CancelRejectTooLate
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return ouch.CancelRejectUnknownToken
+	case errors.As(err, &ve):
+		return ouch.CancelRejectInvalid
+	}
+	return ouch.CancelRejectOther
+}
+
+func (s *session) sendCanceled(o *ouchOrder, reason byte) error {
+	leaves := o.leavesQty()
+	o.exchangeFilled = o.exchangeQty
+	return s.send(&ouch.Canceled{
+		Timestamp:       time.Now(),
+		Token:           o.token,
+		DecrementShares: uint32(leaves),
+		Reason:          reason,
+	})
+}
+
+func (s *session) reject(token string, reason byte) error {
+	return s.send(&ouch.Rejected{Timestamp: time.Now(), Token: token, Reason: reason})
+}
+
+func (s *session) cancelReject(token string, reason byte) error {
+	return s.send(&ouch.CancelRejected{Timestamp: time.Now(), Token: token, Reason: reason})
+}
+
+// dollars converts a price in cents to the dollars the order service
+// takes. The conversion is exact for every valid price.
+func dollars(cents uint64) (float64, error) {
+	if cents == 0 || cents > math.MaxInt64 {
+		return 0, errors.New("price must be greater than 0")
+	}
+	return domain.CentsToDollars(int64(cents)), nil
+}
+
+// expiry derives a limit order's expiry from its time in force in seconds.
+func expiry(seconds uint32) (time.Time, error) {
+	if seconds == 0 {
+		return time.Time{}, errors.New("limit orders require a time in force")
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second), nil
+}
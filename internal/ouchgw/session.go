@@ -0,0 +1,237 @@
+package ouchgw
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/pkg/ouch"
+)
+
+const (
+	// writeTimeout bounds a single write to the client.
+	writeTimeout = 10 * time.Second
+
+	// timerInterval is how often heartbeat and idle deadlines are checked.
+	timerInterval = 250 * time.Millisecond
+)
+
+// sessionState is the state of a broker's session that outlives a single
+// connection: the orders entered over the protocol and the position in the
+// broker's event stream.
+type sessionState struct {
+	brokerID string
+	active   bool // guarded by Gateway.mu
+
+	// Accessed only by the active session's goroutine.
+	orders      map[string]*ouchOrder // exchange order ID → open order
+	tokens      map[string]string     // every token seen → exchange order ID
+	lastEventID *uint64
+}
+
+func newSessionState(brokerID string) *sessionState {
+	return &sessionState{
+		brokerID: brokerID,
+		orders:   make(map[string]*ouchOrder),
+		tokens:   make(map[string]string),
+	}
+}
+
+// inbound is a message or read error from the reader goroutine.
+type inbound struct {
+	msg ouch.Message
+	err error
+}
+
+// session runs one logged-on connection. All protocol state is handled on
+// the goroutine that calls run; a separate goroutine only reads from the
+// connection.
+type session struct {
+	g    *Gateway
+	st   *sessionState
+	conn net.Conn
+	r    *bufio.Reader
+	log  *slog.Logger
+
+	quit     chan struct{}
+	stopOnce sync.Once
+
+	sub      *service.EventSubscription
+	lastSent time.Time
+	lastRecv time.Time
+}
+
+func newSession(g *Gateway, st *sessionState, conn net.Conn, r *bufio.Reader) *session {
+	return &session{
+		g:    g,
+		st:   st,
+		conn: conn,
+		r:    r,
+		log:  g.logger.With(slog.String("broker_id", st.brokerID)),
+		quit: make(chan struct{}),
+	}
+}
+
+// stop asks the session to end the connection.
+func (s *session) stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+}
+
+// errLogout ends the session after an EndOfSession has been sent.
+var errLogout = errors.New("logout")
+
+// run accepts the login and processes requests and broker events until the
+// connection ends.
+func (s *session) run() {
+	if err := s.send(&ouch.LoginAccepted{BrokerID: s.st.brokerID}); err != nil {
+		return
+	}
+	s.lastRecv = time.Now()
+	s.log.Info("ouch session logged on")
+
+	if err := s.subscribe(); err != nil {
+		s.log.Error("ouch event subscription failed", slog.String("error", err.Error()))
+		return
+	}
+	defer func() { s.g.events.Unsubscribe(s.st.brokerID, s.sub) }()
+
+	in := make(chan inbound)
+	go func() {
+		for {
+			msg, err := ouch.ReadClientMessage(s.r)
+			select {
+			case in <- inbound{msg, err}:
+			case <-s.quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	// Unblock the reader goroutine when the session ends for any reason.
+	defer s.stop()
+
+	ticker := time.NewTicker(timerInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case msg := <-in:
+			if msg.err != nil {
+				s.log.Info("ouch connection closed", slog.String("reason", msg.err.Error()))
+				return
+			}
+			s.lastRecv = time.Now()
+			err = s.onMessage(msg.msg)
+		case ev := <-s.sub.Events():
+			err = s.onEvent(ev)
+		case <-s.sub.Done():
+			// Dropped as a slow consumer: resume from the last event handled.
+			err = s.subscribe()
+		case now := <-ticker.C:
+			err = s.onTimer(now)
+		case <-s.quit:
+			s.sendEndOfSession()
+			return
+		}
+		if err == errLogout {
+			s.log.Info("ouch session logged out")
+			return
+		}
+		if err != nil {
+			s.log.Warn("ouch session ended", slog.String("error", err.Error()))
+			return
+		}
+	}
+}
+
+func (s *session) onMessage(m ouch.Message) error {
+	switch m := m.(type) {
+	case *ouch.Heartbeat:
+		return nil
+	case *ouch.LogoutRequest:
+		s.sendEndOfSession()
+		return errLogout
+	case *ouch.EnterOrder:
+		return s.onEnterOrder(m)
+	case *ouch.ReplaceOrder:
+		return s.onReplaceOrder(m)
+	case *ouch.CancelOrder:
+		return s.onCancelOrder(m)
+	case *ouch.LoginRequest:
+		// Already logged on; the stream is out of step with the protocol.
+		s.sendEndOfSession()
+		return errors.New("login on a logged-on session")
+	}
+	return nil
+}
+
+// onTimer sends heartbeats and detects a silent client.
+func (s *session) onTimer(now time.Time) error {
+	if now.Sub(s.lastRecv) >= ouch.IdleTimeout {
+		s.sendEndOfSession()
+		return errors.New("client idle timeout")
+	}
+	if now.Sub(s.lastSent) >= ouch.HeartbeatInterval {
+		return s.send(&ouch.Heartbeat{})
+	}
+	return nil
+}
+
+// subscribe (re)opens the broker event subscription, resuming after the
+// last event handled, and processes the backlog.
+func (s *session) subscribe() error {
+	if s.sub != nil {
+		s.g.events.Unsubscribe(s.st.brokerID, s.sub)
+	}
+	sub, backlog, err := s.g.events.Subscribe(s.st.brokerID, s.st.lastEventID)
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	for _, ev := range backlog {
+		if err := s.onEvent(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainEvents handles every event already queued. Called after a request
+// to the order service, whose events are published before it returns, so
+// reports for the request go out before anything that depends on them.
+func (s *session) drainEvents() error {
+	for {
+		select {
+		case ev := <-s.sub.Events():
+			if err := s.onEvent(ev); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *session) send(m ouch.Message) error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := ouch.Write(s.conn, m); err != nil {
+		return err
+	}
+	s.lastSent = time.Now()
+	return nil
+}
+
+// sendEndOfSession sends an EndOfSession, ignoring write errors since the
+// connection is about to close anyway.
+func (s *session) sendEndOfSession() {
+	if err := s.send(&ouch.EndOfSession{}); err != nil {
+		s.log.Debug("ouch end of session not sent", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,392 @@
+// Package replay runs a recorded stream of broker registrations, order
+// submissions and cancellations through a fresh exchange, for debugging and
+// backtesting. The exchange runs on a virtual clock set from each command's
+// time and issues sequential IDs, so the same input produces byte-identical
+// output on every run.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// Command operations.
+const (
+	OpRegisterBroker = "register_broker"
+	OpSubmit         = "submit"
+	OpCancel         = "cancel"
+)
+
+// maxLineSize bounds one line of input.
+const maxLineSize = 1 << 20
+
+// Command is one line of replay input. Time is when the command reached the
+// exchange; it must not go backwards, and orders expiring up to it expire
+// before it runs. A command without a time runs at the previous one's.
+//
+// A submit's OrderID is the ID the order had when it was recorded. Cancels
+// naming it cancel the order that submit created, so recorded cancels work
+// although replayed orders get new IDs.
+type Command struct {
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+
+	// register_broker
+	Cash     float64   `json:"cash"`
+	Holdings []Holding `json:"holdings"`
+
+	// register_broker, submit
+	BrokerID string `json:"broker_id"`
+
+	// submit
+	Type           domain.OrderType `json:"type"`
+	DocumentNumber string           `json:"document_number"`
+	Side           domain.OrderSide `json:"side"`
+	Symbol         string           `json:"symbol"`
+	Price          *float64         `json:"price"`
+	Quantity       int64            `json:"quantity"`
+	ExpiresAt      *time.Time       `json:"expires_at"`
+
+	// submit, cancel
+	OrderID string `json:"order_id"`
+}
+
+// Holding is a broker's initial position in a symbol.
+type Holding struct {
+	Symbol   string `json:"symbol"`
+	Quantity int64  `json:"quantity"`
+}
+
+// Result counts what a replay did.
+type Result struct {
+	Commands int `json:"commands"`
+	Trades   int `json:"trades"`
+	Rejected int `json:"rejected"`
+	Orders   int `json:"orders"`
+	Brokers  int `json:"brokers"`
+}
+
+// tradeRecord is an output line for an executed trade.
+type tradeRecord struct {
+	Kind          string  `json:"kind"`
+	Line          int     `json:"line"`
+	TradeID       string  `json:"trade_id"`
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	Quantity      int64   `json:"quantity"`
+	AggressorSide string  `json:"aggressor_side"`
+	BuyOrderID    string  `json:"buy_order_id"`
+	SellOrderID   string  `json:"sell_order_id"`
+	ExecutedAt    string  `json:"executed_at"`
+}
+
+// rejectionRecord is an output line for a command the exchange refused.
+type rejectionRecord struct {
+	Kind    string `json:"kind"`
+	Line    int    `json:"line"`
+	Op      string `json:"op"`
+	OrderID string `json:"order_id,omitempty"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// orderRecord is an output line for an order's final state.
+type orderRecord struct {
+	Kind              string   `json:"kind"`
+	OrderID           string   `json:"order_id"`
+	RecordedOrderID   string   `json:"recorded_order_id,omitempty"`
+	BrokerID          string   `json:"broker_id"`
+	Symbol            string   `json:"symbol"`
+	Side              string   `json:"side"`
+	Type              string   `json:"type"`
+	Price             *float64 `json:"price,omitempty"`
+	Quantity          int64    `json:"quantity"`
+	FilledQuantity    int64    `json:"filled_quantity"`
+	RemainingQuantity int64    `json:"remaining_quantity"`
+	CancelledQuantity int64    `json:"cancelled_quantity"`
+	Status            string   `json:"status"`
+	AveragePrice      *float64 `json:"average_price"`
+}
+
+// brokerRecord is an output line for a broker's final balance.
+type brokerRecord struct {
+	Kind         string          `json:"kind"`
+	BrokerID     string          `json:"broker_id"`
+	Cash         float64         `json:"cash"`
+	ReservedCash float64         `json:"reserved_cash"`
+	Holdings     []holdingRecord `json:"holdings"`
+}
+
+type holdingRecord struct {
+	Symbol           string `json:"symbol"`
+	Quantity         int64  `json:"quantity"`
+	ReservedQuantity int64  `json:"reserved_quantity"`
+}
+
+// summaryRecord is the last output line.
+type summaryRecord struct {
+	Kind string `json:"kind"`
+	Result
+	FinalTime string `json:"final_time"`
+}
+
+// exchange is a fresh exchange on a virtual clock, wired as the server wires
+// it but without webhooks, metrics or an audit log.
+type exchange struct {
+	clock    *clock.Virtual
+	expiry   *engine.ExpiryManager
+	brokers  *service.BrokerService
+	orders   *service.OrderService
+	trades   []engine.TradeEvent // trades of the current command
+	brokerID []string            // registered brokers, for the summary
+}
+
+func newExchange() *exchange {
+	clk := clock.NewVirtual(time.Unix(0, 0).UTC())
+	ids := clock.NewSequence()
+
+	brokerStore := store.NewBrokerStore()
+	orderStore := store.NewOrderStore()
+	tradeStore := store.NewTradeStore()
+	symbols := domain.NewSymbolRegistry()
+
+	books := engine.NewBookManager(clk)
+	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, clk, ids)
+	expiry := engine.NewExpiryManager(time.Second, books, orderStore, brokerStore, nil, nil, nil, clk)
+
+	x := &exchange{
+		clock:   clk,
+		expiry:  expiry,
+		brokers: service.NewBrokerService(brokerStore, symbols, clk),
+		orders:  service.NewOrderService(matcher, expiry, brokerStore, orderStore, tradeStore, nil, symbols, nil, nil, clk, ids),
+	}
+	books.AddListener(x)
+	return x
+}
+
+// OnBookUpdate collects the trades of the command being run. Book updates
+// are published on the goroutine that made them, so this needs no lock.
+func (x *exchange) OnBookUpdate(u *engine.BookUpdate) {
+	x.trades = append(x.trades, u.Trades...)
+}
+
+// Run replays the JSON Lines commands read from r and writes the outcome to
+// w, also as JSON Lines: a trade line for every trade and a rejected line
+// for every refused command as they happen, then an order line for every
+// order in submission order, a broker line for every broker in registration
+// order, and a summary line. Input that cannot be replayed, such as a
+// malformed line or a time that goes backwards, stops the replay with an
+// error naming the line.
+func Run(r io.Reader, w io.Writer) (Result, error) {
+	x := newExchange()
+	enc := json.NewEncoder(w)
+
+	var (
+		res      Result
+		orders   []*domain.Order
+		recorded = make(map[string]string) // recorded order ID → replayed order ID
+		lastTime time.Time
+	)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var cmd Command
+		if err := json.Unmarshal(sc.Bytes(), &cmd); err != nil {
+			return res, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !cmd.Time.IsZero() {
+			if cmd.Time.Before(lastTime) {
+				return res, fmt.Errorf("line %d: time %s is before the previous command's", line, cmd.Time.Format(time.RFC3339Nano))
+			}
+			lastTime = cmd.Time
+			x.clock.Set(cmd.Time)
+		}
+		x.expiry.ExpireDue()
+		res.Commands++
+
+		var err error
+		x.trades = x.trades[:0]
+		switch cmd.Op {
+		case OpRegisterBroker:
+			err = x.registerBroker(cmd)
+			if err == nil {
+				res.Brokers++
+			}
+		case OpSubmit:
+			var order *domain.Order
+			order, err = x.submit(cmd)
+			if err == nil {
+				orders = append(orders, order)
+				if cmd.OrderID != "" {
+					recorded[cmd.OrderID] = order.OrderID
+				}
+				for _, t := range x.trades {
+					if err := enc.Encode(newTradeRecord(line, order, t)); err != nil {
+						return res, err
+					}
+					res.Trades++
+				}
+			}
+		case OpCancel:
+			orderID := cmd.OrderID
+			if id, ok := recorded[orderID]; ok {
+				orderID = id
+			}
+			_, err = x.orders.CancelOrder(context.Background(), orderID)
+		default:
+			return res, fmt.Errorf("line %d: unknown op %q", line, cmd.Op)
+		}
+		if err != nil {
+			res.Rejected++
+			if err := enc.Encode(newRejectionRecord(line, cmd, err)); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return res, fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	inverse := make(map[string]string, len(recorded))
+	for from, to := range recorded {
+		inverse[to] = from
+	}
+	for _, o := range orders {
+		if err := enc.Encode(newOrderRecord(o, inverse[o.OrderID])); err != nil {
+			return res, err
+		}
+	}
+	res.Orders = len(orders)
+	for _, id := range x.brokerID {
+		balance, err := x.brokers.GetBalance(id)
+		if err != nil {
+			return res, err
+		}
+		if err := enc.Encode(newBrokerRecord(balance)); err != nil {
+			return res, err
+		}
+	}
+	summary := summaryRecord{Kind: "summary", Result: res, FinalTime: x.clock.Now().UTC().Format(time.RFC3339Nano)}
+	return res, enc.Encode(summary)
+}
+
+func (x *exchange) registerBroker(cmd Command) error {
+	holdings := make([]service.HoldingInput, len(cmd.Holdings))
+	for i, h := range cmd.Holdings {
+		holdings[i] = service.HoldingInput{Symbol: h.Symbol, Quantity: h.Quantity}
+	}
+	_, err := x.brokers.Register(service.RegisterBrokerRequest{
+		BrokerID:        cmd.BrokerID,
+		InitialCash:     cmd.Cash,
+		InitialHoldings: holdings,
+	})
+	if err == nil {
+		x.brokerID = append(x.brokerID, cmd.BrokerID)
+	}
+	return err
+}
+
+func (x *exchange) submit(cmd Command) (*domain.Order, error) {
+	return x.orders.SubmitOrder(context.Background(), service.SubmitOrderRequest{
+		Type:           cmd.Type,
+		BrokerID:       cmd.BrokerID,
+		DocumentNumber: cmd.DocumentNumber,
+		Side:           cmd.Side,
+		Symbol:         cmd.Symbol,
+		Price:          cmd.Price,
+		Quantity:       cmd.Quantity,
+		ExpiresAt:      cmd.ExpiresAt,
+	})
+}
+
+func newTradeRecord(line int, incoming *domain.Order, t engine.TradeEvent) tradeRecord {
+	rec := tradeRecord{
+		Kind:          "trade",
+		Line:          line,
+		TradeID:       t.TradeID,
+		Symbol:        incoming.Symbol,
+		Price:         domain.CentsToDollars(t.Price),
+		Quantity:      t.Quantity,
+		AggressorSide: string(t.AggressorSide),
+		ExecutedAt:    t.ExecutedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if incoming.Side == domain.OrderSideBid {
+		rec.BuyOrderID, rec.SellOrderID = incoming.OrderID, t.RestingOrderID
+	} else {
+		rec.BuyOrderID, rec.SellOrderID = t.RestingOrderID, incoming.OrderID
+	}
+	return rec
+}
+
+func newRejectionRecord(line int, cmd Command, err error) rejectionRecord {
+	return rejectionRecord{
+		Kind:    "rejected",
+		Line:    line,
+		Op:      cmd.Op,
+		OrderID: cmd.OrderID,
+		Reason:  service.RejectReason(err),
+		Message: err.Error(),
+	}
+}
+
+func newOrderRecord(o *domain.Order, recordedID string) orderRecord {
+	rec := orderRecord{
+		Kind:              "order",
+		OrderID:           o.OrderID,
+		RecordedOrderID:   recordedID,
+		BrokerID:          o.BrokerID,
+		Symbol:            o.Symbol,
+		Side:              string(o.Side),
+		Type:              string(o.Type),
+		Quantity:          o.Quantity,
+		FilledQuantity:    o.FilledQuantity,
+		RemainingQuantity: o.RemainingQuantity,
+		CancelledQuantity: o.CancelledQuantity,
+		Status:            string(o.Status),
+	}
+	if o.Type == domain.OrderTypeLimit {
+		price := domain.CentsToDollars(o.Price)
+		rec.Price = &price
+	}
+	if avg, ok := o.AveragePrice(); ok {
+		v := domain.CentsToDollars(avg)
+		rec.AveragePrice = &v
+	}
+	return rec
+}
+
+func newBrokerRecord(b *service.BalanceResponse) brokerRecord {
+	holdings := make([]holdingRecord, 0, len(b.Holdings))
+	for _, h := range b.Holdings {
+		holdings = append(holdings, holdingRecord{
+			Symbol:           h.Symbol,
+			Quantity:         h.Quantity,
+			ReservedQuantity: h.ReservedQuantity,
+		})
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Symbol < holdings[j].Symbol })
+	return brokerRecord{
+		Kind:         "broker",
+		BrokerID:     b.BrokerID,
+		Cash:         domain.CentsToDollars(b.CashBalance),
+		ReservedCash: domain.CentsToDollars(b.ReservedCash),
+		Holdings:     holdings,
+	}
+}
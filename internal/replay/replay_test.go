@@ -0,0 +1,127 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runFile replays a file from testdata, failing the test on error.
+func runFile(t *testing.T, name string) (Result, []byte) {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	res, err := Run(f, &out)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	return res, out.Bytes()
+}
+
+// decodeLines decodes each output line into a map.
+func decodeLines(t *testing.T, out []byte) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatalf("decode %q: %v", l, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRun_Session(t *testing.T) {
+	res, out := runFile(t, "session.jsonl")
+
+	want := Result{Commands: 10, Trades: 2, Rejected: 3, Orders: 4, Brokers: 2}
+	if res != want {
+		t.Errorf("expected %+v, got %+v", want, res)
+	}
+
+	lines := decodeLines(t, out)
+	byKind := make(map[string][]map[string]any)
+	for _, l := range lines {
+		byKind[l["kind"].(string)] = append(byKind[l["kind"].(string)], l)
+	}
+
+	// The bid takes 100 @ 148 and 50 @ 150; IDs and times are reproducible.
+	trades := byKind["trade"]
+	if len(trades) != 2 {
+		t.Fatalf("expected 2 trades, got %v", trades)
+	}
+	if trades[0]["price"] != 148.0 || trades[0]["quantity"] != 100.0 || trades[1]["price"] != 150.0 || trades[1]["quantity"] != 50.0 {
+		t.Errorf("unexpected trades: %v", trades)
+	}
+	if trades[0]["executed_at"] != "2026-01-15T14:30:03Z" || trades[0]["buy_order_id"] != "00000000-0000-4000-8000-000000000003" ||
+		trades[0]["sell_order_id"] != "00000000-0000-4000-8000-000000000001" {
+		t.Errorf("unexpected trade identity: %v", trades[0])
+	}
+
+	// The market bid finds no MSFT asks, the oversized bid is refused, and
+	// the second ask has expired by the time it is cancelled.
+	var reasons []string
+	for _, r := range byKind["rejected"] {
+		reasons = append(reasons, r["reason"].(string))
+	}
+	if got := strings.Join(reasons, ","); got != "no_liquidity,insufficient_balance,order_not_cancellable" {
+		t.Errorf("unexpected rejections: %s", got)
+	}
+
+	var statuses []string
+	for _, o := range byKind["order"] {
+		statuses = append(statuses, o["recorded_order_id"].(string)+"="+o["status"].(string))
+	}
+	// Rejected submissions leave no order behind.
+	if got := strings.Join(statuses, ","); got != "rec-ask-1=filled,rec-ask-2=expired,rec-bid-1=filled,rec-bid-2=cancelled" {
+		t.Errorf("unexpected order states: %s", got)
+	}
+
+	brokers := byKind["broker"]
+	if len(brokers) != 2 || brokers[1]["broker_id"] != "buyer" || brokers[1]["cash"] != 100000.0-14800-7500 ||
+		brokers[1]["reserved_cash"] != 0.0 {
+		t.Errorf("unexpected broker balances: %v", brokers)
+	}
+
+	summary := lines[len(lines)-1]
+	if summary["kind"] != "summary" || summary["final_time"] != "2026-01-15T14:32:00Z" {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	_, first := runFile(t, "session.jsonl")
+	for i := 0; i < 3; i++ {
+		if _, out := runFile(t, "session.jsonl"); !bytes.Equal(out, first) {
+			t.Fatalf("run %d differs:\n%s\nvs\n%s", i+2, out, first)
+		}
+	}
+}
+
+func TestRun_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"malformed", `{"op":`, "line 1:"},
+		{"unknown op", `{"op":"halt"}`, `line 1: unknown op "halt"`},
+		{"time goes backwards", `{"time":"2026-01-15T14:30:01Z","op":"cancel","order_id":"x"}` + "\n" +
+			`{"time":"2026-01-15T14:30:00Z","op":"cancel","order_id":"x"}`, "line 2: time"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Run(strings.NewReader(tc.input), &bytes.Buffer{})
+			if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+				t.Errorf("expected an error starting %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+// publicMethods are the calls that need no API key, matching the REST
+// API's public market data routes.
+var publicMethods = map[string]bool{
+	pb.Exchange_GetBook_FullMethodName:          true,
+	pb.Exchange_GetQuote_FullMethodName:         true,
+	pb.Exchange_GetPrice_FullMethodName:         true,
+	pb.Exchange_StreamMarketData_FullMethodName: true,
+}
+
+// adminMethods are the calls that require the admin role.
+var adminMethods = map[string]bool{
+	pb.Exchange_RegisterBroker_FullMethodName: true,
+}
+
+// principalKey is the context key under which the auth interceptors store
+// the caller's domain.Principal.
+type principalKey struct{}
+
+// unaryAuth resolves the API key in the "authorization" metadata
+// ("Bearer <key>") of every non-public call, like the REST authenticate
+// middleware. Calls without a valid key fail with Unauthenticated, and
+// admin-only calls made with a broker key with PermissionDenied.
+func unaryAuth(apiKeySvc *service.APIKeyService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, apiKeySvc, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuth is unaryAuth for streaming calls.
+func streamAuth(apiKeySvc *service.APIKeyService) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), apiKeySvc, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a ServerStream whose context carries the caller's
+// principal.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
+
+// authenticate returns ctx with the principal of the call's API key, or the
+// status the call fails with.
+func authenticate(ctx context.Context, apiKeySvc *service.APIKeyService, method string) (context.Context, error) {
+	if publicMethods[method] {
+		return ctx, nil
+	}
+	p, err := apiKeySvc.Authenticate(bearerToken(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "a valid API key is required in the authorization metadata")
+	}
+	if adminMethods[method] && p.Role != domain.APIKeyRoleAdmin {
+		return nil, status.Error(codes.PermissionDenied, "this method requires the admin role")
+	}
+	return context.WithValue(ctx, principalKey{}, p), nil
+}
+
+// authorize returns PermissionDenied unless the caller may act for
+// brokerID, like the REST handlers' authorize.
+func authorize(ctx context.Context, brokerID string) error {
+	if principalFrom(ctx).CanActFor(brokerID) {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "API key may not act for broker "+brokerID)
+}
+
+// authorizeOrder returns NotFound, as for an unknown order, unless the
+// caller may act for order's broker, so that callers cannot learn which
+// order IDs other brokers hold.
+func authorizeOrder(ctx context.Context, order *domain.Order) error {
+	if principalFrom(ctx).CanActFor(order.BrokerID) {
+		return nil
+	}
+	return toStatus(domain.ErrOrderNotFound)
+}
+
+// principalFrom returns the principal stored by the auth interceptors, or
+// the zero Principal, which may act for no broker.
+func principalFrom(ctx context.Context) domain.Principal {
+	p, _ := ctx.Value(principalKey{}).(domain.Principal)
+	return p
+}
+
+// bearerToken returns the token of an "authorization: Bearer <token>"
+// metadata entry, or "" if there is none.
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
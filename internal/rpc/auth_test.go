@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+)
+
+func TestAuth_KeyRequired(t *testing.T) {
+	x := newTestExchange(t)
+	ctx := context.Background()
+
+	for name, c := range map[string]pb.ExchangeClient{
+		"missing": x.client(t, ""),
+		"unknown": x.client(t, "mx_not-a-key"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.GetBalance(ctx, &pb.GetBalanceRequest{BrokerId: "broker1"})
+			assertCode(t, err, codes.Unauthenticated)
+			_, err = c.SubmitOrder(ctx, limitOrder("broker1", pb.Side_SIDE_BID, 100, 1))
+			assertCode(t, err, codes.Unauthenticated)
+
+			stream, err := c.StreamOrderUpdates(ctx, &pb.StreamOrderUpdatesRequest{BrokerId: "broker1"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = stream.Recv()
+			assertCode(t, err, codes.Unauthenticated)
+
+			// Market data is public.
+			if _, err := c.GetBook(ctx, &pb.GetBookRequest{Symbol: "AAPL"}); err != nil {
+				t.Errorf("GetBook: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuth_BrokerKeyScopedToBroker(t *testing.T) {
+	x := newTestExchange(t)
+	ctx := context.Background()
+	_, secret, err := x.apiKeySvc.Create("broker1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	admin := x.client(t, testAdminKey)
+	c := x.client(t, secret)
+
+	_, err = c.RegisterBroker(ctx, &pb.RegisterBrokerRequest{BrokerId: "broker3"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	if _, err := c.GetBalance(ctx, &pb.GetBalanceRequest{BrokerId: "broker1"}); err != nil {
+		t.Errorf("GetBalance of own broker: %v", err)
+	}
+	_, err = c.GetBalance(ctx, &pb.GetBalanceRequest{BrokerId: "broker2"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	own := submit(t, c, limitOrder("broker1", pb.Side_SIDE_BID, 100, 1))
+	_, err = c.SubmitOrder(ctx, limitOrder("broker2", pb.Side_SIDE_ASK, 110, 1))
+	assertCode(t, err, codes.PermissionDenied)
+
+	other := submit(t, admin, limitOrder("broker2", pb.Side_SIDE_ASK, 110, 1))
+	_, err = c.GetOrder(ctx, &pb.GetOrderRequest{OrderId: other.OrderId})
+	assertCode(t, err, codes.NotFound)
+	_, err = c.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: other.OrderId})
+	assertCode(t, err, codes.NotFound)
+	if _, err := c.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: own.OrderId}); err != nil {
+		t.Errorf("CancelOrder of own order: %v", err)
+	}
+
+	stream, err := c.StreamOrderUpdates(ctx, &pb.StreamOrderUpdatesRequest{BrokerId: "broker2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = stream.Recv()
+	assertCode(t, err, codes.PermissionDenied)
+}
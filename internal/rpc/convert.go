@@ -0,0 +1,299 @@
+package rpc
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+func sideFromPB(s pb.Side) domain.OrderSide {
+	switch s {
+	case pb.Side_SIDE_BID:
+		return domain.OrderSideBid
+	case pb.Side_SIDE_ASK:
+		return domain.OrderSideAsk
+	}
+	return "" // rejected by service validation
+}
+
+func sideToPB(s domain.OrderSide) pb.Side {
+	switch s {
+	case domain.OrderSideBid:
+		return pb.Side_SIDE_BID
+	case domain.OrderSideAsk:
+		return pb.Side_SIDE_ASK
+	}
+	return pb.Side_SIDE_UNSPECIFIED
+}
+
+func orderTypeFromPB(t pb.OrderType) domain.OrderType {
+	switch t {
+	case pb.OrderType_ORDER_TYPE_LIMIT:
+		return domain.OrderTypeLimit
+	case pb.OrderType_ORDER_TYPE_MARKET:
+		return domain.OrderTypeMarket
+	}
+	return "" // rejected by service validation
+}
+
+func orderTypeToPB(t domain.OrderType) pb.OrderType {
+	switch t {
+	case domain.OrderTypeLimit:
+		return pb.OrderType_ORDER_TYPE_LIMIT
+	case domain.OrderTypeMarket:
+		return pb.OrderType_ORDER_TYPE_MARKET
+	}
+	return pb.OrderType_ORDER_TYPE_UNSPECIFIED
+}
+
+func orderStatusToPB(s domain.OrderStatus) pb.OrderStatus {
+	switch s {
+	case domain.OrderStatusPending:
+		return pb.OrderStatus_ORDER_STATUS_PENDING
+	case domain.OrderStatusPartiallyFilled:
+		return pb.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED
+	case domain.OrderStatusFilled:
+		return pb.OrderStatus_ORDER_STATUS_FILLED
+	case domain.OrderStatusCancelled:
+		return pb.OrderStatus_ORDER_STATUS_CANCELLED
+	case domain.OrderStatusExpired:
+		return pb.OrderStatus_ORDER_STATUS_EXPIRED
+	}
+	return pb.OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func channelFromPB(c pb.MarketDataChannel) service.MarketDataChannel {
+	switch c {
+	case pb.MarketDataChannel_MARKET_DATA_CHANNEL_TRADES:
+		return service.ChannelTrades
+	case pb.MarketDataChannel_MARKET_DATA_CHANNEL_TOP_OF_BOOK:
+		return service.ChannelTopOfBook
+	case pb.MarketDataChannel_MARKET_DATA_CHANNEL_DEPTH:
+		return service.ChannelDepth
+	}
+	return "" // rejected by service validation
+}
+
+func channelToPB(c service.MarketDataChannel) pb.MarketDataChannel {
+	switch c {
+	case service.ChannelTrades:
+		return pb.MarketDataChannel_MARKET_DATA_CHANNEL_TRADES
+	case service.ChannelTopOfBook:
+		return pb.MarketDataChannel_MARKET_DATA_CHANNEL_TOP_OF_BOOK
+	case service.ChannelDepth:
+		return pb.MarketDataChannel_MARKET_DATA_CHANNEL_DEPTH
+	}
+	return pb.MarketDataChannel_MARKET_DATA_CHANNEL_UNSPECIFIED
+}
+
+// dollars converts an optional amount in cents to optional dollars.
+func dollars(cents *int64) *float64 {
+	if cents == nil {
+		return nil
+	}
+	v := domain.CentsToDollars(*cents)
+	return &v
+}
+
+// timestamp converts an optional time, returning nil (unset) for nil.
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func buildBroker(b *domain.Broker) *pb.Broker {
+	holdings := make([]*pb.Holding, 0, len(b.Holdings))
+	for symbol, h := range b.Holdings {
+		holdings = append(holdings, &pb.Holding{Symbol: symbol, Quantity: h.Quantity})
+	}
+	slices.SortFunc(holdings, func(a, b *pb.Holding) int { return cmp.Compare(a.Symbol, b.Symbol) })
+
+	return &pb.Broker{
+		BrokerId:    b.BrokerID,
+		CashBalance: domain.CentsToDollars(b.CashBalance),
+		Holdings:    holdings,
+		CreatedAt:   timestamppb.New(b.CreatedAt),
+	}
+}
+
+func buildBalance(b *service.BalanceResponse) *pb.Balance {
+	holdings := make([]*pb.HoldingBalance, len(b.Holdings))
+	for i, h := range b.Holdings {
+		holdings[i] = &pb.HoldingBalance{
+			Symbol:            h.Symbol,
+			Quantity:          h.Quantity,
+			ReservedQuantity:  h.ReservedQuantity,
+			AvailableQuantity: h.AvailableQuantity,
+		}
+	}
+
+	return &pb.Balance{
+		BrokerId:      b.BrokerID,
+		CashBalance:   domain.CentsToDollars(b.CashBalance),
+		ReservedCash:  domain.CentsToDollars(b.ReservedCash),
+		AvailableCash: domain.CentsToDollars(b.AvailableCash),
+		Holdings:      holdings,
+		UpdatedAt:     timestamppb.New(b.UpdatedAt),
+	}
+}
+
+// buildOrder converts a domain order. Market orders leave price, expires_at,
+// cancelled_at and expired_at unset, as the REST API omits them.
+func buildOrder(o *domain.Order) *pb.Order {
+	trades := make([]*pb.Trade, len(o.Trades))
+	for i, t := range o.Trades {
+		trades[i] = &pb.Trade{
+			TradeId:    t.TradeID,
+			Price:      domain.CentsToDollars(t.Price),
+			Quantity:   t.Quantity,
+			ExecutedAt: timestamppb.New(t.ExecutedAt),
+		}
+	}
+
+	resp := &pb.Order{
+		OrderId:           o.OrderID,
+		Type:              orderTypeToPB(o.Type),
+		BrokerId:          o.BrokerID,
+		DocumentNumber:    o.DocumentNumber,
+		Side:              sideToPB(o.Side),
+		Symbol:            o.Symbol,
+		Quantity:          o.Quantity,
+		FilledQuantity:    o.FilledQuantity,
+		RemainingQuantity: o.RemainingQuantity,
+		CancelledQuantity: o.CancelledQuantity,
+		Status:            orderStatusToPB(o.Status),
+		CreatedAt:         timestamppb.New(o.CreatedAt),
+		Trades:            trades,
+	}
+	if avg, ok := o.AveragePrice(); ok {
+		resp.AveragePrice = dollars(&avg)
+	}
+
+	if o.Type == domain.OrderTypeLimit {
+		resp.Price = dollars(&o.Price)
+		resp.ExpiresAt = timestamp(o.ExpiresAt)
+		resp.CancelledAt = timestamp(o.CancelledAt)
+		resp.ExpiredAt = timestamp(o.ExpiredAt)
+	}
+	return resp
+}
+
+func buildBookLevels(levels []service.BookPriceLevel) []*pb.BookLevel {
+	result := make([]*pb.BookLevel, len(levels))
+	for i := range levels {
+		result[i] = buildBookLevel(&levels[i])
+	}
+	return result
+}
+
+// buildBookLevel converts an optional level, returning nil for nil.
+func buildBookLevel(l *service.BookPriceLevel) *pb.BookLevel {
+	if l == nil {
+		return nil
+	}
+	return &pb.BookLevel{
+		Price:         domain.CentsToDollars(l.Price),
+		TotalQuantity: l.TotalQuantity,
+		OrderCount:    int32(l.OrderCount),
+	}
+}
+
+func buildBook(b *service.BookResponse) *pb.Book {
+	return &pb.Book{
+		Symbol:     b.Symbol,
+		Bids:       buildBookLevels(b.Bids),
+		Asks:       buildBookLevels(b.Asks),
+		Spread:     dollars(b.Spread),
+		SnapshotAt: timestamppb.New(b.SnapshotAt),
+	}
+}
+
+func buildQuote(q *service.QuoteResponse) *pb.Quote {
+	levels := make([]*pb.QuoteLevel, len(q.PriceLevels))
+	for i, l := range q.PriceLevels {
+		levels[i] = &pb.QuoteLevel{
+			Price:    domain.CentsToDollars(l.Price),
+			Quantity: l.Quantity,
+		}
+	}
+
+	return &pb.Quote{
+		Symbol:                q.Symbol,
+		Side:                  sideToPB(q.Side),
+		QuantityRequested:     q.QuantityRequested,
+		QuantityAvailable:     q.QuantityAvailable,
+		FullyFillable:         q.FullyFillable,
+		EstimatedAveragePrice: dollars(q.EstimatedAvgPrice),
+		EstimatedTotal:        dollars(q.EstimatedTotal),
+		PriceLevels:           levels,
+		QuotedAt:              timestamppb.New(q.QuotedAt),
+	}
+}
+
+func buildPrice(p *service.PriceResponse) *pb.Price {
+	return &pb.Price{
+		Symbol:         p.Symbol,
+		CurrentPrice:   dollars(p.CurrentPrice),
+		Window:         p.Window,
+		TradesInWindow: int32(p.TradesInWindow),
+		LastTradeAt:    timestamp(p.LastTradeAt),
+	}
+}
+
+func buildOrderUpdate(ev service.BrokerEvent) *pb.OrderUpdate {
+	return &pb.OrderUpdate{
+		Id:        ev.ID,
+		Event:     ev.Event,
+		Data:      string(ev.Data),
+		CreatedAt: timestamppb.New(ev.CreatedAt),
+	}
+}
+
+// buildMarketDataUpdate converts a service message; which data variant is
+// set follows the channel.
+func buildMarketDataUpdate(msg *service.MarketDataMessage) *pb.MarketDataUpdate {
+	update := &pb.MarketDataUpdate{
+		Type:      pb.MarketDataUpdate_TYPE_UPDATE,
+		Channel:   channelToPB(msg.Channel),
+		Symbol:    msg.Symbol,
+		Seq:       msg.Seq,
+		Timestamp: timestamppb.New(msg.Timestamp),
+	}
+	if msg.Type == service.MessageTypeSnapshot {
+		update.Type = pb.MarketDataUpdate_TYPE_SNAPSHOT
+	}
+
+	switch msg.Channel {
+	case service.ChannelTrades:
+		trades := make([]*pb.MarketTrade, len(msg.Trades))
+		for i, t := range msg.Trades {
+			trades[i] = &pb.MarketTrade{
+				TradeId:       t.TradeID,
+				Price:         domain.CentsToDollars(t.Price),
+				Quantity:      t.Quantity,
+				AggressorSide: sideToPB(t.AggressorSide),
+				ExecutedAt:    timestamppb.New(t.ExecutedAt),
+			}
+		}
+		update.Data = &pb.MarketDataUpdate_Trades{Trades: &pb.MarketTrades{Trades: trades}}
+	case service.ChannelTopOfBook:
+		update.Data = &pb.MarketDataUpdate_TopOfBook{TopOfBook: &pb.TopOfBook{
+			BestBid: buildBookLevel(msg.BestBid),
+			BestAsk: buildBookLevel(msg.BestAsk),
+		}}
+	case service.ChannelDepth:
+		update.Data = &pb.MarketDataUpdate_Depth{Depth: &pb.Depth{
+			Bids: buildBookLevels(msg.Bids),
+			Asks: buildBookLevels(msg.Asks),
+		}}
+	}
+	return update
+}
@@ -0,0 +1,2640 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: miniexchange/v1/exchange.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Side int32
+
+const (
+	Side_SIDE_UNSPECIFIED Side = 0
+	Side_SIDE_BID         Side = 1
+	Side_SIDE_ASK         Side = 2
+)
+
+// Enum value maps for Side.
+var (
+	Side_name = map[int32]string{
+		0: "SIDE_UNSPECIFIED",
+		1: "SIDE_BID",
+		2: "SIDE_ASK",
+	}
+	Side_value = map[string]int32{
+		"SIDE_UNSPECIFIED": 0,
+		"SIDE_BID":         1,
+		"SIDE_ASK":         2,
+	}
+)
+
+func (x Side) Enum() *Side {
+	p := new(Side)
+	*p = x
+	return p
+}
+
+func (x Side) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Side) Descriptor() protoreflect.EnumDescriptor {
+	return file_miniexchange_v1_exchange_proto_enumTypes[0].Descriptor()
+}
+
+func (Side) Type() protoreflect.EnumType {
+	return &file_miniexchange_v1_exchange_proto_enumTypes[0]
+}
+
+func (x Side) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Side.Descriptor instead.
+func (Side) EnumDescriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+type OrderType int32
+
+const (
+	OrderType_ORDER_TYPE_UNSPECIFIED OrderType = 0
+	OrderType_ORDER_TYPE_LIMIT       OrderType = 1
+	OrderType_ORDER_TYPE_MARKET      OrderType = 2
+)
+
+// Enum value maps for OrderType.
+var (
+	OrderType_name = map[int32]string{
+		0: "ORDER_TYPE_UNSPECIFIED",
+		1: "ORDER_TYPE_LIMIT",
+		2: "ORDER_TYPE_MARKET",
+	}
+	OrderType_value = map[string]int32{
+		"ORDER_TYPE_UNSPECIFIED": 0,
+		"ORDER_TYPE_LIMIT":       1,
+		"ORDER_TYPE_MARKET":      2,
+	}
+)
+
+func (x OrderType) Enum() *OrderType {
+	p := new(OrderType)
+	*p = x
+	return p
+}
+
+func (x OrderType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderType) Descriptor() protoreflect.EnumDescriptor {
+	return file_miniexchange_v1_exchange_proto_enumTypes[1].Descriptor()
+}
+
+func (OrderType) Type() protoreflect.EnumType {
+	return &file_miniexchange_v1_exchange_proto_enumTypes[1]
+}
+
+func (x OrderType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderType.Descriptor instead.
+func (OrderType) EnumDescriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{1}
+}
+
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_UNSPECIFIED      OrderStatus = 0
+	OrderStatus_ORDER_STATUS_PENDING          OrderStatus = 1
+	OrderStatus_ORDER_STATUS_PARTIALLY_FILLED OrderStatus = 2
+	OrderStatus_ORDER_STATUS_FILLED           OrderStatus = 3
+	OrderStatus_ORDER_STATUS_CANCELLED        OrderStatus = 4
+	OrderStatus_ORDER_STATUS_EXPIRED          OrderStatus = 5
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "ORDER_STATUS_UNSPECIFIED",
+		1: "ORDER_STATUS_PENDING",
+		2: "ORDER_STATUS_PARTIALLY_FILLED",
+		3: "ORDER_STATUS_FILLED",
+		4: "ORDER_STATUS_CANCELLED",
+		5: "ORDER_STATUS_EXPIRED",
+	}
+	OrderStatus_value = map[string]int32{
+		"ORDER_STATUS_UNSPECIFIED":      0,
+		"ORDER_STATUS_PENDING":          1,
+		"ORDER_STATUS_PARTIALLY_FILLED": 2,
+		"ORDER_STATUS_FILLED":           3,
+		"ORDER_STATUS_CANCELLED":        4,
+		"ORDER_STATUS_EXPIRED":          5,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_miniexchange_v1_exchange_proto_enumTypes[2].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_miniexchange_v1_exchange_proto_enumTypes[2]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{2}
+}
+
+type MarketDataChannel int32
+
+const (
+	MarketDataChannel_MARKET_DATA_CHANNEL_UNSPECIFIED MarketDataChannel = 0
+	MarketDataChannel_MARKET_DATA_CHANNEL_TRADES      MarketDataChannel = 1
+	MarketDataChannel_MARKET_DATA_CHANNEL_TOP_OF_BOOK MarketDataChannel = 2
+	MarketDataChannel_MARKET_DATA_CHANNEL_DEPTH       MarketDataChannel = 3
+)
+
+// Enum value maps for MarketDataChannel.
+var (
+	MarketDataChannel_name = map[int32]string{
+		0: "MARKET_DATA_CHANNEL_UNSPECIFIED",
+		1: "MARKET_DATA_CHANNEL_TRADES",
+		2: "MARKET_DATA_CHANNEL_TOP_OF_BOOK",
+		3: "MARKET_DATA_CHANNEL_DEPTH",
+	}
+	MarketDataChannel_value = map[string]int32{
+		"MARKET_DATA_CHANNEL_UNSPECIFIED": 0,
+		"MARKET_DATA_CHANNEL_TRADES":      1,
+		"MARKET_DATA_CHANNEL_TOP_OF_BOOK": 2,
+		"MARKET_DATA_CHANNEL_DEPTH":       3,
+	}
+)
+
+func (x MarketDataChannel) Enum() *MarketDataChannel {
+	p := new(MarketDataChannel)
+	*p = x
+	return p
+}
+
+func (x MarketDataChannel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MarketDataChannel) Descriptor() protoreflect.EnumDescriptor {
+	return file_miniexchange_v1_exchange_proto_enumTypes[3].Descriptor()
+}
+
+func (MarketDataChannel) Type() protoreflect.EnumType {
+	return &file_miniexchange_v1_exchange_proto_enumTypes[3]
+}
+
+func (x MarketDataChannel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MarketDataChannel.Descriptor instead.
+func (MarketDataChannel) EnumDescriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+type MarketDataUpdate_Type int32
+
+const (
+	MarketDataUpdate_TYPE_UNSPECIFIED MarketDataUpdate_Type = 0
+	MarketDataUpdate_TYPE_SNAPSHOT    MarketDataUpdate_Type = 1
+	MarketDataUpdate_TYPE_UPDATE      MarketDataUpdate_Type = 2
+)
+
+// Enum value maps for MarketDataUpdate_Type.
+var (
+	MarketDataUpdate_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_SNAPSHOT",
+		2: "TYPE_UPDATE",
+	}
+	MarketDataUpdate_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_SNAPSHOT":    1,
+		"TYPE_UPDATE":      2,
+	}
+)
+
+func (x MarketDataUpdate_Type) Enum() *MarketDataUpdate_Type {
+	p := new(MarketDataUpdate_Type)
+	*p = x
+	return p
+}
+
+func (x MarketDataUpdate_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MarketDataUpdate_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_miniexchange_v1_exchange_proto_enumTypes[4].Descriptor()
+}
+
+func (MarketDataUpdate_Type) Type() protoreflect.EnumType {
+	return &file_miniexchange_v1_exchange_proto_enumTypes[4]
+}
+
+func (x MarketDataUpdate_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MarketDataUpdate_Type.Descriptor instead.
+func (MarketDataUpdate_Type) EnumDescriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{28, 0}
+}
+
+type Holding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Holding) Reset() {
+	*x = Holding{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Holding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Holding) ProtoMessage() {}
+
+func (x *Holding) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Holding.ProtoReflect.Descriptor instead.
+func (*Holding) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Holding) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Holding) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type RegisterBrokerRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId        string                 `protobuf:"bytes,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	InitialCash     float64                `protobuf:"fixed64,2,opt,name=initial_cash,json=initialCash,proto3" json:"initial_cash,omitempty"`
+	InitialHoldings []*Holding             `protobuf:"bytes,3,rep,name=initial_holdings,json=initialHoldings,proto3" json:"initial_holdings,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegisterBrokerRequest) Reset() {
+	*x = RegisterBrokerRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterBrokerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBrokerRequest) ProtoMessage() {}
+
+func (x *RegisterBrokerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBrokerRequest.ProtoReflect.Descriptor instead.
+func (*RegisterBrokerRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RegisterBrokerRequest) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *RegisterBrokerRequest) GetInitialCash() float64 {
+	if x != nil {
+		return x.InitialCash
+	}
+	return 0
+}
+
+func (x *RegisterBrokerRequest) GetInitialHoldings() []*Holding {
+	if x != nil {
+		return x.InitialHoldings
+	}
+	return nil
+}
+
+type Broker struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      string                 `protobuf:"bytes,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	CashBalance   float64                `protobuf:"fixed64,2,opt,name=cash_balance,json=cashBalance,proto3" json:"cash_balance,omitempty"`
+	Holdings      []*Holding             `protobuf:"bytes,3,rep,name=holdings,proto3" json:"holdings,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Broker) Reset() {
+	*x = Broker{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Broker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Broker) ProtoMessage() {}
+
+func (x *Broker) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Broker.ProtoReflect.Descriptor instead.
+func (*Broker) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Broker) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *Broker) GetCashBalance() float64 {
+	if x != nil {
+		return x.CashBalance
+	}
+	return 0
+}
+
+func (x *Broker) GetHoldings() []*Holding {
+	if x != nil {
+		return x.Holdings
+	}
+	return nil
+}
+
+func (x *Broker) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type GetBalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      string                 `protobuf:"bytes,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceRequest) Reset() {
+	*x = GetBalanceRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceRequest) ProtoMessage() {}
+
+func (x *GetBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetBalanceRequest) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+type HoldingBalance struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Symbol            string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Quantity          int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ReservedQuantity  int64                  `protobuf:"varint,3,opt,name=reserved_quantity,json=reservedQuantity,proto3" json:"reserved_quantity,omitempty"`
+	AvailableQuantity int64                  `protobuf:"varint,4,opt,name=available_quantity,json=availableQuantity,proto3" json:"available_quantity,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *HoldingBalance) Reset() {
+	*x = HoldingBalance{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HoldingBalance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldingBalance) ProtoMessage() {}
+
+func (x *HoldingBalance) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldingBalance.ProtoReflect.Descriptor instead.
+func (*HoldingBalance) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HoldingBalance) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *HoldingBalance) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *HoldingBalance) GetReservedQuantity() int64 {
+	if x != nil {
+		return x.ReservedQuantity
+	}
+	return 0
+}
+
+func (x *HoldingBalance) GetAvailableQuantity() int64 {
+	if x != nil {
+		return x.AvailableQuantity
+	}
+	return 0
+}
+
+type Balance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      string                 `protobuf:"bytes,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	CashBalance   float64                `protobuf:"fixed64,2,opt,name=cash_balance,json=cashBalance,proto3" json:"cash_balance,omitempty"`
+	ReservedCash  float64                `protobuf:"fixed64,3,opt,name=reserved_cash,json=reservedCash,proto3" json:"reserved_cash,omitempty"`
+	AvailableCash float64                `protobuf:"fixed64,4,opt,name=available_cash,json=availableCash,proto3" json:"available_cash,omitempty"`
+	Holdings      []*HoldingBalance      `protobuf:"bytes,5,rep,name=holdings,proto3" json:"holdings,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Balance) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *Balance) GetCashBalance() float64 {
+	if x != nil {
+		return x.CashBalance
+	}
+	return 0
+}
+
+func (x *Balance) GetReservedCash() float64 {
+	if x != nil {
+		return x.ReservedCash
+	}
+	return 0
+}
+
+func (x *Balance) GetAvailableCash() float64 {
+	if x != nil {
+		return x.AvailableCash
+	}
+	return 0
+}
+
+func (x *Balance) GetHoldings() []*HoldingBalance {
+	if x != nil {
+		return x.Holdings
+	}
+	return nil
+}
+
+func (x *Balance) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type SubmitOrderRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Type           OrderType              `protobuf:"varint,1,opt,name=type,proto3,enum=miniexchange.v1.OrderType" json:"type,omitempty"`
+	BrokerId       string                 `protobuf:"bytes,2,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	DocumentNumber string                 `protobuf:"bytes,3,opt,name=document_number,json=documentNumber,proto3" json:"document_number,omitempty"`
+	Side           Side                   `protobuf:"varint,4,opt,name=side,proto3,enum=miniexchange.v1.Side" json:"side,omitempty"`
+	Symbol         string                 `protobuf:"bytes,5,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// Required for limit orders, must be unset for market orders.
+	Price    *float64 `protobuf:"fixed64,6,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	Quantity int64    `protobuf:"varint,7,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Required for limit orders, must be unset for market orders.
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitOrderRequest) Reset() {
+	*x = SubmitOrderRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitOrderRequest) ProtoMessage() {}
+
+func (x *SubmitOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitOrderRequest.ProtoReflect.Descriptor instead.
+func (*SubmitOrderRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SubmitOrderRequest) GetType() OrderType {
+	if x != nil {
+		return x.Type
+	}
+	return OrderType_ORDER_TYPE_UNSPECIFIED
+}
+
+func (x *SubmitOrderRequest) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *SubmitOrderRequest) GetDocumentNumber() string {
+	if x != nil {
+		return x.DocumentNumber
+	}
+	return ""
+}
+
+func (x *SubmitOrderRequest) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *SubmitOrderRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *SubmitOrderRequest) GetPrice() float64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *SubmitOrderRequest) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *SubmitOrderRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+type CancelOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderRequest) Reset() {
+	*x = CancelOrderRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderRequest) ProtoMessage() {}
+
+func (x *CancelOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderRequest.ProtoReflect.Descriptor instead.
+func (*CancelOrderRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CancelOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+type Trade struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TradeId       string                 `protobuf:"bytes,1,opt,name=trade_id,json=tradeId,proto3" json:"trade_id,omitempty"`
+	Price         float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ExecutedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=executed_at,json=executedAt,proto3" json:"executed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trade) Reset() {
+	*x = Trade{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trade) ProtoMessage() {}
+
+func (x *Trade) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trade.ProtoReflect.Descriptor instead.
+func (*Trade) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Trade) GetTradeId() string {
+	if x != nil {
+		return x.TradeId
+	}
+	return ""
+}
+
+func (x *Trade) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Trade) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Trade) GetExecutedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExecutedAt
+	}
+	return nil
+}
+
+type QueuePosition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrdersAhead   int32                  `protobuf:"varint,1,opt,name=orders_ahead,json=ordersAhead,proto3" json:"orders_ahead,omitempty"`
+	QuantityAhead int64                  `protobuf:"varint,2,opt,name=quantity_ahead,json=quantityAhead,proto3" json:"quantity_ahead,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueuePosition) Reset() {
+	*x = QueuePosition{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueuePosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueuePosition) ProtoMessage() {}
+
+func (x *QueuePosition) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueuePosition.ProtoReflect.Descriptor instead.
+func (*QueuePosition) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QueuePosition) GetOrdersAhead() int32 {
+	if x != nil {
+		return x.OrdersAhead
+	}
+	return 0
+}
+
+func (x *QueuePosition) GetQuantityAhead() int64 {
+	if x != nil {
+		return x.QuantityAhead
+	}
+	return 0
+}
+
+// Order is a limit or market order. Market orders never set price,
+// expires_at, cancelled_at or expired_at.
+type Order struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	OrderId           string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Type              OrderType              `protobuf:"varint,2,opt,name=type,proto3,enum=miniexchange.v1.OrderType" json:"type,omitempty"`
+	BrokerId          string                 `protobuf:"bytes,3,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	DocumentNumber    string                 `protobuf:"bytes,4,opt,name=document_number,json=documentNumber,proto3" json:"document_number,omitempty"`
+	Side              Side                   `protobuf:"varint,5,opt,name=side,proto3,enum=miniexchange.v1.Side" json:"side,omitempty"`
+	Symbol            string                 `protobuf:"bytes,6,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Price             *float64               `protobuf:"fixed64,7,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	Quantity          int64                  `protobuf:"varint,8,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	FilledQuantity    int64                  `protobuf:"varint,9,opt,name=filled_quantity,json=filledQuantity,proto3" json:"filled_quantity,omitempty"`
+	RemainingQuantity int64                  `protobuf:"varint,10,opt,name=remaining_quantity,json=remainingQuantity,proto3" json:"remaining_quantity,omitempty"`
+	CancelledQuantity int64                  `protobuf:"varint,11,opt,name=cancelled_quantity,json=cancelledQuantity,proto3" json:"cancelled_quantity,omitempty"`
+	Status            OrderStatus            `protobuf:"varint,12,opt,name=status,proto3,enum=miniexchange.v1.OrderStatus" json:"status,omitempty"`
+	ExpiresAt         *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CancelledAt       *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=cancelled_at,json=cancelledAt,proto3" json:"cancelled_at,omitempty"`
+	ExpiredAt         *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=expired_at,json=expiredAt,proto3" json:"expired_at,omitempty"`
+	AveragePrice      *float64               `protobuf:"fixed64,17,opt,name=average_price,json=averagePrice,proto3,oneof" json:"average_price,omitempty"`
+	Trades            []*Trade               `protobuf:"bytes,18,rep,name=trades,proto3" json:"trades,omitempty"`
+	// Set only by GetOrder, and only while the order rests on the book.
+	QueuePosition *QueuePosition `protobuf:"bytes,19,opt,name=queue_position,json=queuePosition,proto3" json:"queue_position,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Order) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *Order) GetType() OrderType {
+	if x != nil {
+		return x.Type
+	}
+	return OrderType_ORDER_TYPE_UNSPECIFIED
+}
+
+func (x *Order) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *Order) GetDocumentNumber() string {
+	if x != nil {
+		return x.DocumentNumber
+	}
+	return ""
+}
+
+func (x *Order) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *Order) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Order) GetPrice() float64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *Order) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Order) GetFilledQuantity() int64 {
+	if x != nil {
+		return x.FilledQuantity
+	}
+	return 0
+}
+
+func (x *Order) GetRemainingQuantity() int64 {
+	if x != nil {
+		return x.RemainingQuantity
+	}
+	return 0
+}
+
+func (x *Order) GetCancelledQuantity() int64 {
+	if x != nil {
+		return x.CancelledQuantity
+	}
+	return 0
+}
+
+func (x *Order) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *Order) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetCancelledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CancelledAt
+	}
+	return nil
+}
+
+func (x *Order) GetExpiredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiredAt
+	}
+	return nil
+}
+
+func (x *Order) GetAveragePrice() float64 {
+	if x != nil && x.AveragePrice != nil {
+		return *x.AveragePrice
+	}
+	return 0
+}
+
+func (x *Order) GetTrades() []*Trade {
+	if x != nil {
+		return x.Trades
+	}
+	return nil
+}
+
+func (x *Order) GetQueuePosition() *QueuePosition {
+	if x != nil {
+		return x.QueuePosition
+	}
+	return nil
+}
+
+type GetBookRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Symbol string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// Levels per side, between 1 and 50. Zero means the default of 10.
+	Depth         int32 `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookRequest) Reset() {
+	*x = GetBookRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookRequest) ProtoMessage() {}
+
+func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookRequest.ProtoReflect.Descriptor instead.
+func (*GetBookRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetBookRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetBookRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+type BookLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	TotalQuantity int64                  `protobuf:"varint,2,opt,name=total_quantity,json=totalQuantity,proto3" json:"total_quantity,omitempty"`
+	OrderCount    int32                  `protobuf:"varint,3,opt,name=order_count,json=orderCount,proto3" json:"order_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookLevel) Reset() {
+	*x = BookLevel{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookLevel) ProtoMessage() {}
+
+func (x *BookLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookLevel.ProtoReflect.Descriptor instead.
+func (*BookLevel) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BookLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *BookLevel) GetTotalQuantity() int64 {
+	if x != nil {
+		return x.TotalQuantity
+	}
+	return 0
+}
+
+func (x *BookLevel) GetOrderCount() int32 {
+	if x != nil {
+		return x.OrderCount
+	}
+	return 0
+}
+
+type Book struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Symbol string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Bids   []*BookLevel           `protobuf:"bytes,2,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks   []*BookLevel           `protobuf:"bytes,3,rep,name=asks,proto3" json:"asks,omitempty"`
+	// Unset when either side is empty.
+	Spread        *float64               `protobuf:"fixed64,4,opt,name=spread,proto3,oneof" json:"spread,omitempty"`
+	SnapshotAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=snapshot_at,json=snapshotAt,proto3" json:"snapshot_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Book) Reset() {
+	*x = Book{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Book) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Book) ProtoMessage() {}
+
+func (x *Book) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Book.ProtoReflect.Descriptor instead.
+func (*Book) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Book) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Book) GetBids() []*BookLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *Book) GetAsks() []*BookLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+func (x *Book) GetSpread() float64 {
+	if x != nil && x.Spread != nil {
+		return *x.Spread
+	}
+	return 0
+}
+
+func (x *Book) GetSnapshotAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SnapshotAt
+	}
+	return nil
+}
+
+type GetQuoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Side          Side                   `protobuf:"varint,2,opt,name=side,proto3,enum=miniexchange.v1.Side" json:"side,omitempty"`
+	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuoteRequest) Reset() {
+	*x = GetQuoteRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuoteRequest) ProtoMessage() {}
+
+func (x *GetQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuoteRequest.ProtoReflect.Descriptor instead.
+func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetQuoteRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetQuoteRequest) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *GetQuoteRequest) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type QuoteLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuoteLevel) Reset() {
+	*x = QuoteLevel{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteLevel) ProtoMessage() {}
+
+func (x *QuoteLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteLevel.ProtoReflect.Descriptor instead.
+func (*QuoteLevel) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *QuoteLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *QuoteLevel) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type Quote struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Symbol            string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Side              Side                   `protobuf:"varint,2,opt,name=side,proto3,enum=miniexchange.v1.Side" json:"side,omitempty"`
+	QuantityRequested int64                  `protobuf:"varint,3,opt,name=quantity_requested,json=quantityRequested,proto3" json:"quantity_requested,omitempty"`
+	QuantityAvailable int64                  `protobuf:"varint,4,opt,name=quantity_available,json=quantityAvailable,proto3" json:"quantity_available,omitempty"`
+	FullyFillable     bool                   `protobuf:"varint,5,opt,name=fully_fillable,json=fullyFillable,proto3" json:"fully_fillable,omitempty"`
+	// Unset when there is no liquidity.
+	EstimatedAveragePrice *float64               `protobuf:"fixed64,6,opt,name=estimated_average_price,json=estimatedAveragePrice,proto3,oneof" json:"estimated_average_price,omitempty"`
+	EstimatedTotal        *float64               `protobuf:"fixed64,7,opt,name=estimated_total,json=estimatedTotal,proto3,oneof" json:"estimated_total,omitempty"`
+	PriceLevels           []*QuoteLevel          `protobuf:"bytes,8,rep,name=price_levels,json=priceLevels,proto3" json:"price_levels,omitempty"`
+	QuotedAt              *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=quoted_at,json=quotedAt,proto3" json:"quoted_at,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Quote) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Quote) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *Quote) GetQuantityRequested() int64 {
+	if x != nil {
+		return x.QuantityRequested
+	}
+	return 0
+}
+
+func (x *Quote) GetQuantityAvailable() int64 {
+	if x != nil {
+		return x.QuantityAvailable
+	}
+	return 0
+}
+
+func (x *Quote) GetFullyFillable() bool {
+	if x != nil {
+		return x.FullyFillable
+	}
+	return false
+}
+
+func (x *Quote) GetEstimatedAveragePrice() float64 {
+	if x != nil && x.EstimatedAveragePrice != nil {
+		return *x.EstimatedAveragePrice
+	}
+	return 0
+}
+
+func (x *Quote) GetEstimatedTotal() float64 {
+	if x != nil && x.EstimatedTotal != nil {
+		return *x.EstimatedTotal
+	}
+	return 0
+}
+
+func (x *Quote) GetPriceLevels() []*QuoteLevel {
+	if x != nil {
+		return x.PriceLevels
+	}
+	return nil
+}
+
+func (x *Quote) GetQuotedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.QuotedAt
+	}
+	return nil
+}
+
+type GetPriceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPriceRequest) Reset() {
+	*x = GetPriceRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPriceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPriceRequest) ProtoMessage() {}
+
+func (x *GetPriceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPriceRequest.ProtoReflect.Descriptor instead.
+func (*GetPriceRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetPriceRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type Price struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Symbol string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// Unset when the symbol has never traded.
+	CurrentPrice   *float64               `protobuf:"fixed64,2,opt,name=current_price,json=currentPrice,proto3,oneof" json:"current_price,omitempty"`
+	Window         string                 `protobuf:"bytes,3,opt,name=window,proto3" json:"window,omitempty"`
+	TradesInWindow int32                  `protobuf:"varint,4,opt,name=trades_in_window,json=tradesInWindow,proto3" json:"trades_in_window,omitempty"`
+	LastTradeAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=last_trade_at,json=lastTradeAt,proto3" json:"last_trade_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Price) Reset() {
+	*x = Price{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Price) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Price) ProtoMessage() {}
+
+func (x *Price) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Price.ProtoReflect.Descriptor instead.
+func (*Price) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Price) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Price) GetCurrentPrice() float64 {
+	if x != nil && x.CurrentPrice != nil {
+		return *x.CurrentPrice
+	}
+	return 0
+}
+
+func (x *Price) GetWindow() string {
+	if x != nil {
+		return x.Window
+	}
+	return ""
+}
+
+func (x *Price) GetTradesInWindow() int32 {
+	if x != nil {
+		return x.TradesInWindow
+	}
+	return 0
+}
+
+func (x *Price) GetLastTradeAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTradeAt
+	}
+	return nil
+}
+
+type StreamOrderUpdatesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      string                 `protobuf:"bytes,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	LastEventId   *uint64                `protobuf:"varint,2,opt,name=last_event_id,json=lastEventId,proto3,oneof" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamOrderUpdatesRequest) Reset() {
+	*x = StreamOrderUpdatesRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamOrderUpdatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamOrderUpdatesRequest) ProtoMessage() {}
+
+func (x *StreamOrderUpdatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamOrderUpdatesRequest.ProtoReflect.Descriptor instead.
+func (*StreamOrderUpdatesRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *StreamOrderUpdatesRequest) GetBrokerId() string {
+	if x != nil {
+		return x.BrokerId
+	}
+	return ""
+}
+
+func (x *StreamOrderUpdatesRequest) GetLastEventId() uint64 {
+	if x != nil && x.LastEventId != nil {
+		return *x.LastEventId
+	}
+	return 0
+}
+
+// OrderUpdate is one event in the broker's stream. IDs increase by exactly
+// one per event, so a jump means events fell out of the retained buffer.
+type OrderUpdate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The webhook event type, e.g. "trade.executed".
+	Event string `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+	// The JSON payload, identical to the webhook body.
+	Data          string                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderUpdate) Reset() {
+	*x = OrderUpdate{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderUpdate) ProtoMessage() {}
+
+func (x *OrderUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderUpdate.ProtoReflect.Descriptor instead.
+func (*OrderUpdate) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *OrderUpdate) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *OrderUpdate) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *OrderUpdate) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type MarketDataSubscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Channel       MarketDataChannel      `protobuf:"varint,2,opt,name=channel,proto3,enum=miniexchange.v1.MarketDataChannel" json:"channel,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketDataSubscription) Reset() {
+	*x = MarketDataSubscription{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketDataSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketDataSubscription) ProtoMessage() {}
+
+func (x *MarketDataSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketDataSubscription.ProtoReflect.Descriptor instead.
+func (*MarketDataSubscription) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *MarketDataSubscription) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *MarketDataSubscription) GetChannel() MarketDataChannel {
+	if x != nil {
+		return x.Channel
+	}
+	return MarketDataChannel_MARKET_DATA_CHANNEL_UNSPECIFIED
+}
+
+type StreamMarketDataRequest struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Subscriptions []*MarketDataSubscription `protobuf:"bytes,1,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMarketDataRequest) Reset() {
+	*x = StreamMarketDataRequest{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMarketDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMarketDataRequest) ProtoMessage() {}
+
+func (x *StreamMarketDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMarketDataRequest.ProtoReflect.Descriptor instead.
+func (*StreamMarketDataRequest) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *StreamMarketDataRequest) GetSubscriptions() []*MarketDataSubscription {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+type MarketTrade struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TradeId       string                 `protobuf:"bytes,1,opt,name=trade_id,json=tradeId,proto3" json:"trade_id,omitempty"`
+	Price         float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	AggressorSide Side                   `protobuf:"varint,4,opt,name=aggressor_side,json=aggressorSide,proto3,enum=miniexchange.v1.Side" json:"aggressor_side,omitempty"`
+	ExecutedAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=executed_at,json=executedAt,proto3" json:"executed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketTrade) Reset() {
+	*x = MarketTrade{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketTrade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketTrade) ProtoMessage() {}
+
+func (x *MarketTrade) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketTrade.ProtoReflect.Descriptor instead.
+func (*MarketTrade) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *MarketTrade) GetTradeId() string {
+	if x != nil {
+		return x.TradeId
+	}
+	return ""
+}
+
+func (x *MarketTrade) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *MarketTrade) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *MarketTrade) GetAggressorSide() Side {
+	if x != nil {
+		return x.AggressorSide
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *MarketTrade) GetExecutedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExecutedAt
+	}
+	return nil
+}
+
+type MarketTrades struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trades        []*MarketTrade         `protobuf:"bytes,1,rep,name=trades,proto3" json:"trades,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketTrades) Reset() {
+	*x = MarketTrades{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketTrades) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketTrades) ProtoMessage() {}
+
+func (x *MarketTrades) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketTrades.ProtoReflect.Descriptor instead.
+func (*MarketTrades) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *MarketTrades) GetTrades() []*MarketTrade {
+	if x != nil {
+		return x.Trades
+	}
+	return nil
+}
+
+type TopOfBook struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Unset when that side is empty.
+	BestBid       *BookLevel `protobuf:"bytes,1,opt,name=best_bid,json=bestBid,proto3" json:"best_bid,omitempty"`
+	BestAsk       *BookLevel `protobuf:"bytes,2,opt,name=best_ask,json=bestAsk,proto3" json:"best_ask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopOfBook) Reset() {
+	*x = TopOfBook{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopOfBook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopOfBook) ProtoMessage() {}
+
+func (x *TopOfBook) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopOfBook.ProtoReflect.Descriptor instead.
+func (*TopOfBook) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TopOfBook) GetBestBid() *BookLevel {
+	if x != nil {
+		return x.BestBid
+	}
+	return nil
+}
+
+func (x *TopOfBook) GetBestAsk() *BookLevel {
+	if x != nil {
+		return x.BestAsk
+	}
+	return nil
+}
+
+// Depth holds every level in a snapshot and only the changed levels in an
+// update, where a total_quantity of zero means the level was removed.
+type Depth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bids          []*BookLevel           `protobuf:"bytes,1,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks          []*BookLevel           `protobuf:"bytes,2,rep,name=asks,proto3" json:"asks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Depth) Reset() {
+	*x = Depth{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Depth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Depth) ProtoMessage() {}
+
+func (x *Depth) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Depth.ProtoReflect.Descriptor instead.
+func (*Depth) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Depth) GetBids() []*BookLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *Depth) GetAsks() []*BookLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+// MarketDataUpdate is a snapshot or incremental update for one (symbol,
+// channel) pair. seq increases by exactly one per update after the
+// snapshot; a jump means data was missed and the client should reconnect.
+type MarketDataUpdate struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      MarketDataUpdate_Type  `protobuf:"varint,1,opt,name=type,proto3,enum=miniexchange.v1.MarketDataUpdate_Type" json:"type,omitempty"`
+	Channel   MarketDataChannel      `protobuf:"varint,2,opt,name=channel,proto3,enum=miniexchange.v1.MarketDataChannel" json:"channel,omitempty"`
+	Symbol    string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Seq       uint64                 `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*MarketDataUpdate_Trades
+	//	*MarketDataUpdate_TopOfBook
+	//	*MarketDataUpdate_Depth
+	Data          isMarketDataUpdate_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketDataUpdate) Reset() {
+	*x = MarketDataUpdate{}
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketDataUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketDataUpdate) ProtoMessage() {}
+
+func (x *MarketDataUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_miniexchange_v1_exchange_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketDataUpdate.ProtoReflect.Descriptor instead.
+func (*MarketDataUpdate) Descriptor() ([]byte, []int) {
+	return file_miniexchange_v1_exchange_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *MarketDataUpdate) GetType() MarketDataUpdate_Type {
+	if x != nil {
+		return x.Type
+	}
+	return MarketDataUpdate_TYPE_UNSPECIFIED
+}
+
+func (x *MarketDataUpdate) GetChannel() MarketDataChannel {
+	if x != nil {
+		return x.Channel
+	}
+	return MarketDataChannel_MARKET_DATA_CHANNEL_UNSPECIFIED
+}
+
+func (x *MarketDataUpdate) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *MarketDataUpdate) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *MarketDataUpdate) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *MarketDataUpdate) GetData() isMarketDataUpdate_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *MarketDataUpdate) GetTrades() *MarketTrades {
+	if x != nil {
+		if x, ok := x.Data.(*MarketDataUpdate_Trades); ok {
+			return x.Trades
+		}
+	}
+	return nil
+}
+
+func (x *MarketDataUpdate) GetTopOfBook() *TopOfBook {
+	if x != nil {
+		if x, ok := x.Data.(*MarketDataUpdate_TopOfBook); ok {
+			return x.TopOfBook
+		}
+	}
+	return nil
+}
+
+func (x *MarketDataUpdate) GetDepth() *Depth {
+	if x != nil {
+		if x, ok := x.Data.(*MarketDataUpdate_Depth); ok {
+			return x.Depth
+		}
+	}
+	return nil
+}
+
+type isMarketDataUpdate_Data interface {
+	isMarketDataUpdate_Data()
+}
+
+type MarketDataUpdate_Trades struct {
+	Trades *MarketTrades `protobuf:"bytes,6,opt,name=trades,proto3,oneof"`
+}
+
+type MarketDataUpdate_TopOfBook struct {
+	TopOfBook *TopOfBook `protobuf:"bytes,7,opt,name=top_of_book,json=topOfBook,proto3,oneof"`
+}
+
+type MarketDataUpdate_Depth struct {
+	Depth *Depth `protobuf:"bytes,8,opt,name=depth,proto3,oneof"`
+}
+
+func (*MarketDataUpdate_Trades) isMarketDataUpdate_Data() {}
+
+func (*MarketDataUpdate_TopOfBook) isMarketDataUpdate_Data() {}
+
+func (*MarketDataUpdate_Depth) isMarketDataUpdate_Data() {}
+
+var File_miniexchange_v1_exchange_proto protoreflect.FileDescriptor
+
+const file_miniexchange_v1_exchange_proto_rawDesc = "" +
+	"\n" +
+	"\x1eminiexchange/v1/exchange.proto\x12\x0fminiexchange.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"=\n" +
+	"\aHolding\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\"\x9c\x01\n" +
+	"\x15RegisterBrokerRequest\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\tR\bbrokerId\x12!\n" +
+	"\finitial_cash\x18\x02 \x01(\x01R\vinitialCash\x12C\n" +
+	"\x10initial_holdings\x18\x03 \x03(\v2\x18.miniexchange.v1.HoldingR\x0finitialHoldings\"\xb9\x01\n" +
+	"\x06Broker\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\tR\bbrokerId\x12!\n" +
+	"\fcash_balance\x18\x02 \x01(\x01R\vcashBalance\x124\n" +
+	"\bholdings\x18\x03 \x03(\v2\x18.miniexchange.v1.HoldingR\bholdings\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"0\n" +
+	"\x11GetBalanceRequest\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\tR\bbrokerId\"\xa0\x01\n" +
+	"\x0eHoldingBalance\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\x12+\n" +
+	"\x11reserved_quantity\x18\x03 \x01(\x03R\x10reservedQuantity\x12-\n" +
+	"\x12available_quantity\x18\x04 \x01(\x03R\x11availableQuantity\"\x8d\x02\n" +
+	"\aBalance\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\tR\bbrokerId\x12!\n" +
+	"\fcash_balance\x18\x02 \x01(\x01R\vcashBalance\x12#\n" +
+	"\rreserved_cash\x18\x03 \x01(\x01R\freservedCash\x12%\n" +
+	"\x0eavailable_cash\x18\x04 \x01(\x01R\ravailableCash\x12;\n" +
+	"\bholdings\x18\x05 \x03(\v2\x1f.miniexchange.v1.HoldingBalanceR\bholdings\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xc9\x02\n" +
+	"\x12SubmitOrderRequest\x12.\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1a.miniexchange.v1.OrderTypeR\x04type\x12\x1b\n" +
+	"\tbroker_id\x18\x02 \x01(\tR\bbrokerId\x12'\n" +
+	"\x0fdocument_number\x18\x03 \x01(\tR\x0edocumentNumber\x12)\n" +
+	"\x04side\x18\x04 \x01(\x0e2\x15.miniexchange.v1.SideR\x04side\x12\x16\n" +
+	"\x06symbol\x18\x05 \x01(\tR\x06symbol\x12\x19\n" +
+	"\x05price\x18\x06 \x01(\x01H\x00R\x05price\x88\x01\x01\x12\x1a\n" +
+	"\bquantity\x18\a \x01(\x03R\bquantity\x129\n" +
+	"\n" +
+	"expires_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAtB\b\n" +
+	"\x06_price\",\n" +
+	"\x0fGetOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"/\n" +
+	"\x12CancelOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"\x91\x01\n" +
+	"\x05Trade\x12\x19\n" +
+	"\btrade_id\x18\x01 \x01(\tR\atradeId\x12\x14\n" +
+	"\x05price\x18\x02 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x03R\bquantity\x12;\n" +
+	"\vexecuted_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"executedAt\"Y\n" +
+	"\rQueuePosition\x12!\n" +
+	"\forders_ahead\x18\x01 \x01(\x05R\vordersAhead\x12%\n" +
+	"\x0equantity_ahead\x18\x02 \x01(\x03R\rquantityAhead\"\xfc\x06\n" +
+	"\x05Order\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12.\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x1a.miniexchange.v1.OrderTypeR\x04type\x12\x1b\n" +
+	"\tbroker_id\x18\x03 \x01(\tR\bbrokerId\x12'\n" +
+	"\x0fdocument_number\x18\x04 \x01(\tR\x0edocumentNumber\x12)\n" +
+	"\x04side\x18\x05 \x01(\x0e2\x15.miniexchange.v1.SideR\x04side\x12\x16\n" +
+	"\x06symbol\x18\x06 \x01(\tR\x06symbol\x12\x19\n" +
+	"\x05price\x18\a \x01(\x01H\x00R\x05price\x88\x01\x01\x12\x1a\n" +
+	"\bquantity\x18\b \x01(\x03R\bquantity\x12'\n" +
+	"\x0ffilled_quantity\x18\t \x01(\x03R\x0efilledQuantity\x12-\n" +
+	"\x12remaining_quantity\x18\n" +
+	" \x01(\x03R\x11remainingQuantity\x12-\n" +
+	"\x12cancelled_quantity\x18\v \x01(\x03R\x11cancelledQuantity\x124\n" +
+	"\x06status\x18\f \x01(\x0e2\x1c.miniexchange.v1.OrderStatusR\x06status\x129\n" +
+	"\n" +
+	"expires_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x129\n" +
+	"\n" +
+	"created_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12=\n" +
+	"\fcancelled_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\vcancelledAt\x129\n" +
+	"\n" +
+	"expired_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\texpiredAt\x12(\n" +
+	"\raverage_price\x18\x11 \x01(\x01H\x01R\faveragePrice\x88\x01\x01\x12.\n" +
+	"\x06trades\x18\x12 \x03(\v2\x16.miniexchange.v1.TradeR\x06trades\x12E\n" +
+	"\x0equeue_position\x18\x13 \x01(\v2\x1e.miniexchange.v1.QueuePositionR\rqueuePositionB\b\n" +
+	"\x06_priceB\x10\n" +
+	"\x0e_average_price\">\n" +
+	"\x0eGetBookRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\x05R\x05depth\"i\n" +
+	"\tBookLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12%\n" +
+	"\x0etotal_quantity\x18\x02 \x01(\x03R\rtotalQuantity\x12\x1f\n" +
+	"\vorder_count\x18\x03 \x01(\x05R\n" +
+	"orderCount\"\xe3\x01\n" +
+	"\x04Book\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12.\n" +
+	"\x04bids\x18\x02 \x03(\v2\x1a.miniexchange.v1.BookLevelR\x04bids\x12.\n" +
+	"\x04asks\x18\x03 \x03(\v2\x1a.miniexchange.v1.BookLevelR\x04asks\x12\x1b\n" +
+	"\x06spread\x18\x04 \x01(\x01H\x00R\x06spread\x88\x01\x01\x12;\n" +
+	"\vsnapshot_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"snapshotAtB\t\n" +
+	"\a_spread\"p\n" +
+	"\x0fGetQuoteRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12)\n" +
+	"\x04side\x18\x02 \x01(\x0e2\x15.miniexchange.v1.SideR\x04side\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x03R\bquantity\">\n" +
+	"\n" +
+	"QuoteLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\"\xe3\x03\n" +
+	"\x05Quote\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12)\n" +
+	"\x04side\x18\x02 \x01(\x0e2\x15.miniexchange.v1.SideR\x04side\x12-\n" +
+	"\x12quantity_requested\x18\x03 \x01(\x03R\x11quantityRequested\x12-\n" +
+	"\x12quantity_available\x18\x04 \x01(\x03R\x11quantityAvailable\x12%\n" +
+	"\x0efully_fillable\x18\x05 \x01(\bR\rfullyFillable\x12;\n" +
+	"\x17estimated_average_price\x18\x06 \x01(\x01H\x00R\x15estimatedAveragePrice\x88\x01\x01\x12,\n" +
+	"\x0festimated_total\x18\a \x01(\x01H\x01R\x0eestimatedTotal\x88\x01\x01\x12>\n" +
+	"\fprice_levels\x18\b \x03(\v2\x1b.miniexchange.v1.QuoteLevelR\vpriceLevels\x127\n" +
+	"\tquoted_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\bquotedAtB\x1a\n" +
+	"\x18_estimated_average_priceB\x12\n" +
+	"\x10_estimated_total\")\n" +
+	"\x0fGetPriceRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\"\xdd\x01\n" +
+	"\x05Price\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12(\n" +
+	"\rcurrent_price\x18\x02 \x01(\x01H\x00R\fcurrentPrice\x88\x01\x01\x12\x16\n" +
+	"\x06window\x18\x03 \x01(\tR\x06window\x12(\n" +
+	"\x10trades_in_window\x18\x04 \x01(\x05R\x0etradesInWindow\x12>\n" +
+	"\rlast_trade_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vlastTradeAtB\x10\n" +
+	"\x0e_current_price\"s\n" +
+	"\x19StreamOrderUpdatesRequest\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\tR\bbrokerId\x12'\n" +
+	"\rlast_event_id\x18\x02 \x01(\x04H\x00R\vlastEventId\x88\x01\x01B\x10\n" +
+	"\x0e_last_event_id\"\x82\x01\n" +
+	"\vOrderUpdate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05event\x18\x02 \x01(\tR\x05event\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\tR\x04data\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"n\n" +
+	"\x16MarketDataSubscription\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12<\n" +
+	"\achannel\x18\x02 \x01(\x0e2\".miniexchange.v1.MarketDataChannelR\achannel\"h\n" +
+	"\x17StreamMarketDataRequest\x12M\n" +
+	"\rsubscriptions\x18\x01 \x03(\v2'.miniexchange.v1.MarketDataSubscriptionR\rsubscriptions\"\xd5\x01\n" +
+	"\vMarketTrade\x12\x19\n" +
+	"\btrade_id\x18\x01 \x01(\tR\atradeId\x12\x14\n" +
+	"\x05price\x18\x02 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x03R\bquantity\x12<\n" +
+	"\x0eaggressor_side\x18\x04 \x01(\x0e2\x15.miniexchange.v1.SideR\raggressorSide\x12;\n" +
+	"\vexecuted_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"executedAt\"D\n" +
+	"\fMarketTrades\x124\n" +
+	"\x06trades\x18\x01 \x03(\v2\x1c.miniexchange.v1.MarketTradeR\x06trades\"y\n" +
+	"\tTopOfBook\x125\n" +
+	"\bbest_bid\x18\x01 \x01(\v2\x1a.miniexchange.v1.BookLevelR\abestBid\x125\n" +
+	"\bbest_ask\x18\x02 \x01(\v2\x1a.miniexchange.v1.BookLevelR\abestAsk\"g\n" +
+	"\x05Depth\x12.\n" +
+	"\x04bids\x18\x01 \x03(\v2\x1a.miniexchange.v1.BookLevelR\x04bids\x12.\n" +
+	"\x04asks\x18\x02 \x03(\v2\x1a.miniexchange.v1.BookLevelR\x04asks\"\xe1\x03\n" +
+	"\x10MarketDataUpdate\x12:\n" +
+	"\x04type\x18\x01 \x01(\x0e2&.miniexchange.v1.MarketDataUpdate.TypeR\x04type\x12<\n" +
+	"\achannel\x18\x02 \x01(\x0e2\".miniexchange.v1.MarketDataChannelR\achannel\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\x04R\x03seq\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x127\n" +
+	"\x06trades\x18\x06 \x01(\v2\x1d.miniexchange.v1.MarketTradesH\x00R\x06trades\x12<\n" +
+	"\vtop_of_book\x18\a \x01(\v2\x1a.miniexchange.v1.TopOfBookH\x00R\ttopOfBook\x12.\n" +
+	"\x05depth\x18\b \x01(\v2\x16.miniexchange.v1.DepthH\x00R\x05depth\"@\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rTYPE_SNAPSHOT\x10\x01\x12\x0f\n" +
+	"\vTYPE_UPDATE\x10\x02B\x06\n" +
+	"\x04data*8\n" +
+	"\x04Side\x12\x14\n" +
+	"\x10SIDE_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bSIDE_BID\x10\x01\x12\f\n" +
+	"\bSIDE_ASK\x10\x02*T\n" +
+	"\tOrderType\x12\x1a\n" +
+	"\x16ORDER_TYPE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10ORDER_TYPE_LIMIT\x10\x01\x12\x15\n" +
+	"\x11ORDER_TYPE_MARKET\x10\x02*\xb7\x01\n" +
+	"\vOrderStatus\x12\x1c\n" +
+	"\x18ORDER_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14ORDER_STATUS_PENDING\x10\x01\x12!\n" +
+	"\x1dORDER_STATUS_PARTIALLY_FILLED\x10\x02\x12\x17\n" +
+	"\x13ORDER_STATUS_FILLED\x10\x03\x12\x1a\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\x04\x12\x18\n" +
+	"\x14ORDER_STATUS_EXPIRED\x10\x05*\x9c\x01\n" +
+	"\x11MarketDataChannel\x12#\n" +
+	"\x1fMARKET_DATA_CHANNEL_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aMARKET_DATA_CHANNEL_TRADES\x10\x01\x12#\n" +
+	"\x1fMARKET_DATA_CHANNEL_TOP_OF_BOOK\x10\x02\x12\x1d\n" +
+	"\x19MARKET_DATA_CHANNEL_DEPTH\x10\x032\x9b\x06\n" +
+	"\bExchange\x12Q\n" +
+	"\x0eRegisterBroker\x12&.miniexchange.v1.RegisterBrokerRequest\x1a\x17.miniexchange.v1.Broker\x12J\n" +
+	"\n" +
+	"GetBalance\x12\".miniexchange.v1.GetBalanceRequest\x1a\x18.miniexchange.v1.Balance\x12J\n" +
+	"\vSubmitOrder\x12#.miniexchange.v1.SubmitOrderRequest\x1a\x16.miniexchange.v1.Order\x12D\n" +
+	"\bGetOrder\x12 .miniexchange.v1.GetOrderRequest\x1a\x16.miniexchange.v1.Order\x12J\n" +
+	"\vCancelOrder\x12#.miniexchange.v1.CancelOrderRequest\x1a\x16.miniexchange.v1.Order\x12A\n" +
+	"\aGetBook\x12\x1f.miniexchange.v1.GetBookRequest\x1a\x15.miniexchange.v1.Book\x12D\n" +
+	"\bGetQuote\x12 .miniexchange.v1.GetQuoteRequest\x1a\x16.miniexchange.v1.Quote\x12D\n" +
+	"\bGetPrice\x12 .miniexchange.v1.GetPriceRequest\x1a\x16.miniexchange.v1.Price\x12`\n" +
+	"\x12StreamOrderUpdates\x12*.miniexchange.v1.StreamOrderUpdatesRequest\x1a\x1c.miniexchange.v1.OrderUpdate0\x01\x12a\n" +
+	"\x10StreamMarketData\x12(.miniexchange.v1.StreamMarketDataRequest\x1a!.miniexchange.v1.MarketDataUpdate0\x01Bf\n" +
+	"\x1dcom.efreitasn.miniexchange.v1B\rExchangeProtoP\x01Z4github.com/efreitasn/miniexchange/internal/rpc/pb;pbb\x06proto3"
+
+var (
+	file_miniexchange_v1_exchange_proto_rawDescOnce sync.Once
+	file_miniexchange_v1_exchange_proto_rawDescData []byte
+)
+
+func file_miniexchange_v1_exchange_proto_rawDescGZIP() []byte {
+	file_miniexchange_v1_exchange_proto_rawDescOnce.Do(func() {
+		file_miniexchange_v1_exchange_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_miniexchange_v1_exchange_proto_rawDesc), len(file_miniexchange_v1_exchange_proto_rawDesc)))
+	})
+	return file_miniexchange_v1_exchange_proto_rawDescData
+}
+
+var file_miniexchange_v1_exchange_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_miniexchange_v1_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
+var file_miniexchange_v1_exchange_proto_goTypes = []any{
+	(Side)(0),                         // 0: miniexchange.v1.Side
+	(OrderType)(0),                    // 1: miniexchange.v1.OrderType
+	(OrderStatus)(0),                  // 2: miniexchange.v1.OrderStatus
+	(MarketDataChannel)(0),            // 3: miniexchange.v1.MarketDataChannel
+	(MarketDataUpdate_Type)(0),        // 4: miniexchange.v1.MarketDataUpdate.Type
+	(*Holding)(nil),                   // 5: miniexchange.v1.Holding
+	(*RegisterBrokerRequest)(nil),     // 6: miniexchange.v1.RegisterBrokerRequest
+	(*Broker)(nil),                    // 7: miniexchange.v1.Broker
+	(*GetBalanceRequest)(nil),         // 8: miniexchange.v1.GetBalanceRequest
+	(*HoldingBalance)(nil),            // 9: miniexchange.v1.HoldingBalance
+	(*Balance)(nil),                   // 10: miniexchange.v1.Balance
+	(*SubmitOrderRequest)(nil),        // 11: miniexchange.v1.SubmitOrderRequest
+	(*GetOrderRequest)(nil),           // 12: miniexchange.v1.GetOrderRequest
+	(*CancelOrderRequest)(nil),        // 13: miniexchange.v1.CancelOrderRequest
+	(*Trade)(nil),                     // 14: miniexchange.v1.Trade
+	(*QueuePosition)(nil),             // 15: miniexchange.v1.QueuePosition
+	(*Order)(nil),                     // 16: miniexchange.v1.Order
+	(*GetBookRequest)(nil),            // 17: miniexchange.v1.GetBookRequest
+	(*BookLevel)(nil),                 // 18: miniexchange.v1.BookLevel
+	(*Book)(nil),                      // 19: miniexchange.v1.Book
+	(*GetQuoteRequest)(nil),           // 20: miniexchange.v1.GetQuoteRequest
+	(*QuoteLevel)(nil),                // 21: miniexchange.v1.QuoteLevel
+	(*Quote)(nil),                     // 22: miniexchange.v1.Quote
+	(*GetPriceRequest)(nil),           // 23: miniexchange.v1.GetPriceRequest
+	(*Price)(nil),                     // 24: miniexchange.v1.Price
+	(*StreamOrderUpdatesRequest)(nil), // 25: miniexchange.v1.StreamOrderUpdatesRequest
+	(*OrderUpdate)(nil),               // 26: miniexchange.v1.OrderUpdate
+	(*MarketDataSubscription)(nil),    // 27: miniexchange.v1.MarketDataSubscription
+	(*StreamMarketDataRequest)(nil),   // 28: miniexchange.v1.StreamMarketDataRequest
+	(*MarketTrade)(nil),               // 29: miniexchange.v1.MarketTrade
+	(*MarketTrades)(nil),              // 30: miniexchange.v1.MarketTrades
+	(*TopOfBook)(nil),                 // 31: miniexchange.v1.TopOfBook
+	(*Depth)(nil),                     // 32: miniexchange.v1.Depth
+	(*MarketDataUpdate)(nil),          // 33: miniexchange.v1.MarketDataUpdate
+	(*timestamppb.Timestamp)(nil),     // 34: google.protobuf.Timestamp
+}
+var file_miniexchange_v1_exchange_proto_depIdxs = []int32{
+	5,  // 0: miniexchange.v1.RegisterBrokerRequest.initial_holdings:type_name -> miniexchange.v1.Holding
+	5,  // 1: miniexchange.v1.Broker.holdings:type_name -> miniexchange.v1.Holding
+	34, // 2: miniexchange.v1.Broker.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 3: miniexchange.v1.Balance.holdings:type_name -> miniexchange.v1.HoldingBalance
+	34, // 4: miniexchange.v1.Balance.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 5: miniexchange.v1.SubmitOrderRequest.type:type_name -> miniexchange.v1.OrderType
+	0,  // 6: miniexchange.v1.SubmitOrderRequest.side:type_name -> miniexchange.v1.Side
+	34, // 7: miniexchange.v1.SubmitOrderRequest.expires_at:type_name -> google.protobuf.Timestamp
+	34, // 8: miniexchange.v1.Trade.executed_at:type_name -> google.protobuf.Timestamp
+	1,  // 9: miniexchange.v1.Order.type:type_name -> miniexchange.v1.OrderType
+	0,  // 10: miniexchange.v1.Order.side:type_name -> miniexchange.v1.Side
+	2,  // 11: miniexchange.v1.Order.status:type_name -> miniexchange.v1.OrderStatus
+	34, // 12: miniexchange.v1.Order.expires_at:type_name -> google.protobuf.Timestamp
+	34, // 13: miniexchange.v1.Order.created_at:type_name -> google.protobuf.Timestamp
+	34, // 14: miniexchange.v1.Order.cancelled_at:type_name -> google.protobuf.Timestamp
+	34, // 15: miniexchange.v1.Order.expired_at:type_name -> google.protobuf.Timestamp
+	14, // 16: miniexchange.v1.Order.trades:type_name -> miniexchange.v1.Trade
+	15, // 17: miniexchange.v1.Order.queue_position:type_name -> miniexchange.v1.QueuePosition
+	18, // 18: miniexchange.v1.Book.bids:type_name -> miniexchange.v1.BookLevel
+	18, // 19: miniexchange.v1.Book.asks:type_name -> miniexchange.v1.BookLevel
+	34, // 20: miniexchange.v1.Book.snapshot_at:type_name -> google.protobuf.Timestamp
+	0,  // 21: miniexchange.v1.GetQuoteRequest.side:type_name -> miniexchange.v1.Side
+	0,  // 22: miniexchange.v1.Quote.side:type_name -> miniexchange.v1.Side
+	21, // 23: miniexchange.v1.Quote.price_levels:type_name -> miniexchange.v1.QuoteLevel
+	34, // 24: miniexchange.v1.Quote.quoted_at:type_name -> google.protobuf.Timestamp
+	34, // 25: miniexchange.v1.Price.last_trade_at:type_name -> google.protobuf.Timestamp
+	34, // 26: miniexchange.v1.OrderUpdate.created_at:type_name -> google.protobuf.Timestamp
+	3,  // 27: miniexchange.v1.MarketDataSubscription.channel:type_name -> miniexchange.v1.MarketDataChannel
+	27, // 28: miniexchange.v1.StreamMarketDataRequest.subscriptions:type_name -> miniexchange.v1.MarketDataSubscription
+	0,  // 29: miniexchange.v1.MarketTrade.aggressor_side:type_name -> miniexchange.v1.Side
+	34, // 30: miniexchange.v1.MarketTrade.executed_at:type_name -> google.protobuf.Timestamp
+	29, // 31: miniexchange.v1.MarketTrades.trades:type_name -> miniexchange.v1.MarketTrade
+	18, // 32: miniexchange.v1.TopOfBook.best_bid:type_name -> miniexchange.v1.BookLevel
+	18, // 33: miniexchange.v1.TopOfBook.best_ask:type_name -> miniexchange.v1.BookLevel
+	18, // 34: miniexchange.v1.Depth.bids:type_name -> miniexchange.v1.BookLevel
+	18, // 35: miniexchange.v1.Depth.asks:type_name -> miniexchange.v1.BookLevel
+	4,  // 36: miniexchange.v1.MarketDataUpdate.type:type_name -> miniexchange.v1.MarketDataUpdate.Type
+	3,  // 37: miniexchange.v1.MarketDataUpdate.channel:type_name -> miniexchange.v1.MarketDataChannel
+	34, // 38: miniexchange.v1.MarketDataUpdate.timestamp:type_name -> google.protobuf.Timestamp
+	30, // 39: miniexchange.v1.MarketDataUpdate.trades:type_name -> miniexchange.v1.MarketTrades
+	31, // 40: miniexchange.v1.MarketDataUpdate.top_of_book:type_name -> miniexchange.v1.TopOfBook
+	32, // 41: miniexchange.v1.MarketDataUpdate.depth:type_name -> miniexchange.v1.Depth
+	6,  // 42: miniexchange.v1.Exchange.RegisterBroker:input_type -> miniexchange.v1.RegisterBrokerRequest
+	8,  // 43: miniexchange.v1.Exchange.GetBalance:input_type -> miniexchange.v1.GetBalanceRequest
+	11, // 44: miniexchange.v1.Exchange.SubmitOrder:input_type -> miniexchange.v1.SubmitOrderRequest
+	12, // 45: miniexchange.v1.Exchange.GetOrder:input_type -> miniexchange.v1.GetOrderRequest
+	13, // 46: miniexchange.v1.Exchange.CancelOrder:input_type -> miniexchange.v1.CancelOrderRequest
+	17, // 47: miniexchange.v1.Exchange.GetBook:input_type -> miniexchange.v1.GetBookRequest
+	20, // 48: miniexchange.v1.Exchange.GetQuote:input_type -> miniexchange.v1.GetQuoteRequest
+	23, // 49: miniexchange.v1.Exchange.GetPrice:input_type -> miniexchange.v1.GetPriceRequest
+	25, // 50: miniexchange.v1.Exchange.StreamOrderUpdates:input_type -> miniexchange.v1.StreamOrderUpdatesRequest
+	28, // 51: miniexchange.v1.Exchange.StreamMarketData:input_type -> miniexchange.v1.StreamMarketDataRequest
+	7,  // 52: miniexchange.v1.Exchange.RegisterBroker:output_type -> miniexchange.v1.Broker
+	10, // 53: miniexchange.v1.Exchange.GetBalance:output_type -> miniexchange.v1.Balance
+	16, // 54: miniexchange.v1.Exchange.SubmitOrder:output_type -> miniexchange.v1.Order
+	16, // 55: miniexchange.v1.Exchange.GetOrder:output_type -> miniexchange.v1.Order
+	16, // 56: miniexchange.v1.Exchange.CancelOrder:output_type -> miniexchange.v1.Order
+	19, // 57: miniexchange.v1.Exchange.GetBook:output_type -> miniexchange.v1.Book
+	22, // 58: miniexchange.v1.Exchange.GetQuote:output_type -> miniexchange.v1.Quote
+	24, // 59: miniexchange.v1.Exchange.GetPrice:output_type -> miniexchange.v1.Price
+	26, // 60: miniexchange.v1.Exchange.StreamOrderUpdates:output_type -> miniexchange.v1.OrderUpdate
+	33, // 61: miniexchange.v1.Exchange.StreamMarketData:output_type -> miniexchange.v1.MarketDataUpdate
+	52, // [52:62] is the sub-list for method output_type
+	42, // [42:52] is the sub-list for method input_type
+	42, // [42:42] is the sub-list for extension type_name
+	42, // [42:42] is the sub-list for extension extendee
+	0,  // [0:42] is the sub-list for field type_name
+}
+
+func init() { file_miniexchange_v1_exchange_proto_init() }
+func file_miniexchange_v1_exchange_proto_init() {
+	if File_miniexchange_v1_exchange_proto != nil {
+		return
+	}
+	file_miniexchange_v1_exchange_proto_msgTypes[6].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[11].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[14].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[17].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[19].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[20].OneofWrappers = []any{}
+	file_miniexchange_v1_exchange_proto_msgTypes[28].OneofWrappers = []any{
+		(*MarketDataUpdate_Trades)(nil),
+		(*MarketDataUpdate_TopOfBook)(nil),
+		(*MarketDataUpdate_Depth)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_miniexchange_v1_exchange_proto_rawDesc), len(file_miniexchange_v1_exchange_proto_rawDesc)),
+			NumEnums:      5,
+			NumMessages:   29,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_miniexchange_v1_exchange_proto_goTypes,
+		DependencyIndexes: file_miniexchange_v1_exchange_proto_depIdxs,
+		EnumInfos:         file_miniexchange_v1_exchange_proto_enumTypes,
+		MessageInfos:      file_miniexchange_v1_exchange_proto_msgTypes,
+	}.Build()
+	File_miniexchange_v1_exchange_proto = out.File
+	file_miniexchange_v1_exchange_proto_goTypes = nil
+	file_miniexchange_v1_exchange_proto_depIdxs = nil
+}
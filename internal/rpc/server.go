@@ -0,0 +1,256 @@
+// Package rpc implements the gRPC transport. Like the handler package it
+// only talks to services, so both APIs share validation, matching and
+// webhook dispatch.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+	"github.com/efreitasn/miniexchange/internal/service"
+)
+
+// defaultBookDepth is the depth GetBook uses when the request leaves it
+// unset, matching the REST default.
+const defaultBookDepth = 10
+
+// Server implements pb.ExchangeServer on top of the service layer.
+type Server struct {
+	pb.UnimplementedExchangeServer
+
+	brokerSvc      *service.BrokerService
+	orderSvc       *service.OrderService
+	stockSvc       *service.StockService
+	marketDataSvc  *service.MarketDataService
+	eventStreamSvc *service.EventStreamService
+}
+
+// NewServer creates a gRPC server with the Exchange service registered,
+// request logging, and the same API key authentication and broker-scoped
+// authorization as the REST API.
+func NewServer(
+	apiKeySvc *service.APIKeyService,
+	brokerSvc *service.BrokerService,
+	orderSvc *service.OrderService,
+	stockSvc *service.StockService,
+	marketDataSvc *service.MarketDataService,
+	eventStreamSvc *service.EventStreamService,
+	logger *slog.Logger,
+) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogging(logger), unaryAuth(apiKeySvc)),
+		grpc.ChainStreamInterceptor(streamLogging(logger), streamAuth(apiKeySvc)),
+	)
+	pb.RegisterExchangeServer(srv, &Server{
+		brokerSvc:      brokerSvc,
+		orderSvc:       orderSvc,
+		stockSvc:       stockSvc,
+		marketDataSvc:  marketDataSvc,
+		eventStreamSvc: eventStreamSvc,
+	})
+	return srv
+}
+
+// RegisterBroker implements pb.ExchangeServer.
+func (s *Server) RegisterBroker(_ context.Context, req *pb.RegisterBrokerRequest) (*pb.Broker, error) {
+	holdings := make([]service.HoldingInput, len(req.InitialHoldings))
+	for i, h := range req.InitialHoldings {
+		holdings[i] = service.HoldingInput{
+			Symbol:   h.Symbol,
+			Quantity: h.Quantity,
+		}
+	}
+
+	broker, err := s.brokerSvc.Register(service.RegisterBrokerRequest{
+		BrokerID:        req.BrokerId,
+		InitialCash:     req.InitialCash,
+		InitialHoldings: holdings,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildBroker(broker), nil
+}
+
+// GetBalance implements pb.ExchangeServer.
+func (s *Server) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.Balance, error) {
+	if err := authorize(ctx, req.BrokerId); err != nil {
+		return nil, err
+	}
+	balance, err := s.brokerSvc.GetBalance(req.BrokerId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildBalance(balance), nil
+}
+
+// SubmitOrder implements pb.ExchangeServer.
+func (s *Server) SubmitOrder(ctx context.Context, req *pb.SubmitOrderRequest) (*pb.Order, error) {
+	if err := authorize(ctx, req.BrokerId); err != nil {
+		return nil, err
+	}
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		if err := req.ExpiresAt.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be a valid timestamp")
+		}
+		t := req.ExpiresAt.AsTime()
+		expiresAt = &t
+	}
+
+	order, err := s.orderSvc.SubmitOrder(ctx, service.SubmitOrderRequest{
+		Type:           orderTypeFromPB(req.Type),
+		BrokerID:       req.BrokerId,
+		DocumentNumber: req.DocumentNumber,
+		Side:           sideFromPB(req.Side),
+		Symbol:         req.Symbol,
+		Price:          req.Price,
+		Quantity:       req.Quantity,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildOrder(order), nil
+}
+
+// GetOrder implements pb.ExchangeServer.
+func (s *Server) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {
+	order, err := s.orderSvc.GetOrder(req.OrderId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := authorizeOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	resp := buildOrder(order)
+	if order.Type == domain.OrderTypeLimit {
+		if pos := s.orderSvc.GetQueuePosition(order); pos != nil {
+			resp.QueuePosition = &pb.QueuePosition{
+				OrdersAhead:   int32(pos.OrdersAhead),
+				QuantityAhead: pos.QuantityAhead,
+			}
+		}
+	}
+	return resp, nil
+}
+
+// CancelOrder implements pb.ExchangeServer.
+func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.Order, error) {
+	existing, err := s.orderSvc.GetOrder(req.OrderId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := authorizeOrder(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	// The audit trail records who cancelled the order.
+	if principalFrom(ctx).Role == domain.APIKeyRoleAdmin {
+		ctx = service.WithCancelInitiator(ctx, service.CancelByAdmin)
+	}
+
+	order, err := s.orderSvc.CancelOrder(ctx, req.OrderId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildOrder(order), nil
+}
+
+// GetBook implements pb.ExchangeServer.
+func (s *Server) GetBook(_ context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	depth := int(req.Depth)
+	if depth == 0 {
+		depth = defaultBookDepth
+	}
+
+	book, err := s.stockSvc.GetBook(req.Symbol, depth)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildBook(book), nil
+}
+
+// GetQuote implements pb.ExchangeServer.
+func (s *Server) GetQuote(_ context.Context, req *pb.GetQuoteRequest) (*pb.Quote, error) {
+	quote, err := s.stockSvc.GetQuote(req.Symbol, sideFromPB(req.Side), req.Quantity)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildQuote(quote), nil
+}
+
+// GetPrice implements pb.ExchangeServer.
+func (s *Server) GetPrice(_ context.Context, req *pb.GetPriceRequest) (*pb.Price, error) {
+	price, err := s.stockSvc.GetPrice(req.Symbol)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return buildPrice(price), nil
+}
+
+// toStatus maps service errors to gRPC statuses, using the codes that
+// correspond to the REST API's HTTP statuses.
+func toStatus(err error) error {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return status.Error(codes.InvalidArgument, validationErr.Message)
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrBrokerNotFound),
+		errors.Is(err, domain.ErrOrderNotFound),
+		errors.Is(err, domain.ErrSymbolNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrBrokerAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, domain.ErrOrderNotCancellable),
+		errors.Is(err, domain.ErrInsufficientBalance),
+		errors.Is(err, domain.ErrInsufficientHoldings),
+		errors.Is(err, domain.ErrNoLiquidity),
+		errors.Is(err, domain.ErrSymbolHalted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrSlowConsumer):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, "An unexpected error occurred")
+	}
+}
+
+// unaryLogging logs each unary call's method, status code, and duration,
+// like the REST request logging middleware.
+func unaryLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Info("rpc",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+		return resp, err
+	}
+}
+
+// streamLogging logs each streaming call when it ends.
+func streamLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logger.Info("rpc",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+		return err
+	}
+}
@@ -0,0 +1,314 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// testAdminKey is the admin key of the test server.
+const testAdminKey = "test-admin-key-0123456789"
+
+// testExchange is the Exchange service served over an in-memory listener.
+type testExchange struct {
+	ln        *bufconn.Listener
+	apiKeySvc *service.APIKeyService
+}
+
+// newTestExchange serves the Exchange service over an in-memory listener
+// and registers broker1 (cash) and broker2 (AAPL shares).
+func newTestExchange(t *testing.T) *testExchange {
+	t.Helper()
+	bs := store.NewBrokerStore()
+	os := store.NewOrderStore()
+	ts := store.NewTradeStore()
+	ws := store.NewWebhookStore()
+	sr := domain.NewSymbolRegistry()
+	bm := engine.NewBookManager(nil)
+	eventStreamSvc := service.NewEventStreamService(bs, 64, nil)
+	webhookSvc := service.NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, service.DeliveryPolicy{}, eventStreamSvc, nil, nil)
+	t.Cleanup(webhookSvc.Close)
+	m := engine.NewMatcher(bm, bs, os, ts, sr, webhookSvc, nil, nil, nil)
+	e := engine.NewExpiryManager(time.Hour, bm, os, bs, webhookSvc, nil, nil, nil)
+	brokerSvc := service.NewBrokerService(bs, sr, nil)
+	orderSvc := service.NewOrderService(m, e, bs, os, ts, webhookSvc, sr, nil, nil, nil, nil)
+	stockSvc := service.NewStockService(ts, bm, m, 5*time.Minute, sr)
+	marketDataSvc := service.NewMarketDataService(sr, 64)
+	bm.AddListener(marketDataSvc)
+	apiKeySvc := service.NewAPIKeyService(store.NewAPIKeyStore(), bs, testAdminKey)
+
+	for _, req := range []service.RegisterBrokerRequest{
+		{BrokerID: "broker1", InitialCash: 100_000},
+		{BrokerID: "broker2", InitialHoldings: []service.HoldingInput{{Symbol: "AAPL", Quantity: 1000}}},
+	} {
+		if _, err := brokerSvc.Register(req); err != nil {
+			t.Fatalf("register %s: %v", req.BrokerID, err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := NewServer(apiKeySvc, brokerSvc, orderSvc, stockSvc, marketDataSvc, eventStreamSvc, logger)
+	ln := bufconn.Listen(1 << 20)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	return &testExchange{ln: ln, apiKeySvc: apiKeySvc}
+}
+
+// client returns a client that sends apiKey with every call, or no key if
+// it is empty.
+func (x *testExchange) client(t *testing.T, apiKey string) pb.ExchangeClient {
+	t.Helper()
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return x.ln.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if apiKey != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerCredentials(apiKey)))
+	}
+	conn, err := grpc.NewClient("passthrough:///bufconn", opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewExchangeClient(conn)
+}
+
+// newTestClient serves a test exchange and returns a client using the
+// admin key.
+func newTestClient(t *testing.T) pb.ExchangeClient {
+	t.Helper()
+	return newTestExchange(t).client(t, testAdminKey)
+}
+
+// bearerCredentials sends an API key as "authorization: Bearer <key>".
+type bearerCredentials string
+
+func (c bearerCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+func (bearerCredentials) RequireTransportSecurity() bool { return false }
+
+func limitOrder(brokerID string, side pb.Side, price float64, qty int64) *pb.SubmitOrderRequest {
+	return &pb.SubmitOrderRequest{
+		Type:           pb.OrderType_ORDER_TYPE_LIMIT,
+		BrokerId:       brokerID,
+		DocumentNumber: "12345678901",
+		Side:           side,
+		Symbol:         "AAPL",
+		Price:          proto.Float64(price),
+		Quantity:       qty,
+		ExpiresAt:      timestamppb.New(time.Now().Add(time.Hour)),
+	}
+}
+
+// submit places an order and fails the test on error.
+func submit(t *testing.T, c pb.ExchangeClient, req *pb.SubmitOrderRequest) *pb.Order {
+	t.Helper()
+	order, err := c.SubmitOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	return order
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %s, want %s (err: %v)", got, want, err)
+	}
+}
+
+func TestRegisterBrokerAndBalance(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	broker, err := c.RegisterBroker(ctx, &pb.RegisterBrokerRequest{
+		BrokerId:    "broker3",
+		InitialCash: 1500.50,
+		InitialHoldings: []*pb.Holding{
+			{Symbol: "MSFT", Quantity: 20},
+			{Symbol: "AAPL", Quantity: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if broker.CashBalance != 1500.50 || len(broker.Holdings) != 2 || broker.Holdings[0].Symbol != "AAPL" {
+		t.Errorf("unexpected broker: %v", broker)
+	}
+
+	_, err = c.RegisterBroker(ctx, &pb.RegisterBrokerRequest{BrokerId: "broker3"})
+	assertCode(t, err, codes.AlreadyExists)
+	_, err = c.RegisterBroker(ctx, &pb.RegisterBrokerRequest{BrokerId: ""})
+	assertCode(t, err, codes.InvalidArgument)
+
+	submit(t, c, limitOrder("broker3", pb.Side_SIDE_BID, 100, 5))
+	balance, err := c.GetBalance(ctx, &pb.GetBalanceRequest{BrokerId: "broker3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if balance.ReservedCash != 500 || balance.AvailableCash != 1000.50 {
+		t.Errorf("reserved/available = %v/%v, want 500/1000.50", balance.ReservedCash, balance.AvailableCash)
+	}
+
+	_, err = c.GetBalance(ctx, &pb.GetBalanceRequest{BrokerId: "nobody"})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestSubmitGetCancelOrder(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	ask := submit(t, c, limitOrder("broker2", pb.Side_SIDE_ASK, 10, 100))
+	if ask.Status != pb.OrderStatus_ORDER_STATUS_PENDING || ask.GetPrice() != 10 || ask.ExpiresAt == nil {
+		t.Errorf("unexpected ask: %v", ask)
+	}
+
+	// A market bid fills part of the resting ask.
+	bid := submit(t, c, &pb.SubmitOrderRequest{
+		Type:           pb.OrderType_ORDER_TYPE_MARKET,
+		BrokerId:       "broker1",
+		DocumentNumber: "12345678901",
+		Side:           pb.Side_SIDE_BID,
+		Symbol:         "AAPL",
+		Quantity:       40,
+	})
+	if bid.Status != pb.OrderStatus_ORDER_STATUS_FILLED || len(bid.Trades) != 1 || bid.GetAveragePrice() != 10 {
+		t.Errorf("unexpected market order: %v", bid)
+	}
+	if bid.Price != nil || bid.ExpiresAt != nil {
+		t.Errorf("market order should not set price or expires_at: %v", bid)
+	}
+
+	got, err := c.GetOrder(ctx, &pb.GetOrderRequest{OrderId: ask.OrderId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != pb.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED || got.RemainingQuantity != 60 {
+		t.Errorf("unexpected order: %v", got)
+	}
+	if got.QueuePosition == nil || got.QueuePosition.OrdersAhead != 0 {
+		t.Errorf("queue position = %v, want first in queue", got.QueuePosition)
+	}
+
+	cancelled, err := c.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: ask.OrderId})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != pb.OrderStatus_ORDER_STATUS_CANCELLED || cancelled.CancelledAt == nil || cancelled.CancelledQuantity != 60 {
+		t.Errorf("unexpected cancelled order: %v", cancelled)
+	}
+
+	got, _ = c.GetOrder(ctx, &pb.GetOrderRequest{OrderId: ask.OrderId})
+	if got.QueuePosition != nil {
+		t.Errorf("cancelled order should have no queue position, got %v", got.QueuePosition)
+	}
+
+	_, err = c.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: ask.OrderId})
+	assertCode(t, err, codes.FailedPrecondition)
+	_, err = c.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "missing"})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestSubmitOrder_Errors(t *testing.T) {
+	c := newTestClient(t)
+
+	noType := limitOrder("broker1", pb.Side_SIDE_BID, 10, 1)
+	noType.Type = pb.OrderType_ORDER_TYPE_UNSPECIFIED
+	noPrice := limitOrder("broker1", pb.Side_SIDE_BID, 10, 1)
+	noPrice.Price = nil
+	noLiquidity := &pb.SubmitOrderRequest{
+		Type:           pb.OrderType_ORDER_TYPE_MARKET,
+		BrokerId:       "broker1",
+		DocumentNumber: "12345678901",
+		Side:           pb.Side_SIDE_BID,
+		Symbol:         "AAPL",
+		Quantity:       1,
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.SubmitOrderRequest
+		want codes.Code
+	}{
+		{"unspecified type", noType, codes.InvalidArgument},
+		{"limit without price", noPrice, codes.InvalidArgument},
+		{"unknown broker", limitOrder("nobody", pb.Side_SIDE_BID, 10, 1), codes.NotFound},
+		{"insufficient balance", limitOrder("broker1", pb.Side_SIDE_BID, 10, 1_000_000), codes.FailedPrecondition},
+		{"no liquidity", noLiquidity, codes.FailedPrecondition},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := c.SubmitOrder(context.Background(), tc.req)
+			assertCode(t, err, tc.want)
+		})
+	}
+}
+
+func TestBookQuoteAndPrice(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	price, err := c.GetPrice(ctx, &pb.GetPriceRequest{Symbol: "AAPL"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if price.CurrentPrice != nil || price.LastTradeAt != nil {
+		t.Errorf("untraded symbol should have no price: %v", price)
+	}
+
+	submit(t, c, limitOrder("broker2", pb.Side_SIDE_ASK, 10, 50))
+	submit(t, c, limitOrder("broker2", pb.Side_SIDE_ASK, 11, 50))
+	submit(t, c, limitOrder("broker1", pb.Side_SIDE_BID, 9.50, 20))
+
+	book, err := c.GetBook(ctx, &pb.GetBookRequest{Symbol: "AAPL"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(book.Bids) != 1 || len(book.Asks) != 2 || book.GetSpread() != 0.50 {
+		t.Errorf("unexpected book: %v", book)
+	}
+	book, _ = c.GetBook(ctx, &pb.GetBookRequest{Symbol: "AAPL", Depth: 1})
+	if len(book.Asks) != 1 || book.Asks[0].Price != 10 {
+		t.Errorf("depth 1 asks = %v, want the best level only", book.Asks)
+	}
+	_, err = c.GetBook(ctx, &pb.GetBookRequest{Symbol: "AAPL", Depth: 51})
+	assertCode(t, err, codes.InvalidArgument)
+	_, err = c.GetBook(ctx, &pb.GetBookRequest{Symbol: "ZZZZ"})
+	assertCode(t, err, codes.NotFound)
+
+	quote, err := c.GetQuote(ctx, &pb.GetQuoteRequest{Symbol: "AAPL", Side: pb.Side_SIDE_BID, Quantity: 75})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !quote.FullyFillable || quote.GetEstimatedTotal() != 775 || len(quote.PriceLevels) != 2 {
+		t.Errorf("unexpected quote: %v", quote)
+	}
+	_, err = c.GetQuote(ctx, &pb.GetQuoteRequest{Symbol: "AAPL", Quantity: 1})
+	assertCode(t, err, codes.InvalidArgument)
+
+	submit(t, c, limitOrder("broker1", pb.Side_SIDE_BID, 10, 10))
+	price, _ = c.GetPrice(ctx, &pb.GetPriceRequest{Symbol: "AAPL"})
+	if price.GetCurrentPrice() != 10 || price.TradesInWindow == 0 || price.LastTradeAt == nil {
+		t.Errorf("unexpected price: %v", price)
+	}
+}
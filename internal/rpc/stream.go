@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+)
+
+// StreamOrderUpdates implements pb.ExchangeServer. A client that falls
+// behind is dropped with ResourceExhausted and resumes by reconnecting
+// with the last ID it saw.
+func (s *Server) StreamOrderUpdates(req *pb.StreamOrderUpdatesRequest, stream grpc.ServerStreamingServer[pb.OrderUpdate]) error {
+	if err := authorize(stream.Context(), req.BrokerId); err != nil {
+		return err
+	}
+	sub, backlog, err := s.eventStreamSvc.Subscribe(req.BrokerId, req.LastEventId)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer s.eventStreamSvc.Unsubscribe(req.BrokerId, sub)
+
+	for _, ev := range backlog {
+		if err := stream.Send(buildOrderUpdate(ev)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case ev := <-sub.Events():
+			if err := stream.Send(buildOrderUpdate(ev)); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return doneStatus(sub.Err())
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// StreamMarketData implements pb.ExchangeServer. Every subscription is
+// validated before anything is sent; a client that falls behind is dropped
+// with ResourceExhausted and resyncs by reconnecting for fresh snapshots.
+func (s *Server) StreamMarketData(req *pb.StreamMarketDataRequest, stream grpc.ServerStreamingServer[pb.MarketDataUpdate]) error {
+	if len(req.Subscriptions) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one subscription is required")
+	}
+
+	sub := s.marketDataSvc.NewSubscriber()
+	defer s.marketDataSvc.Close(sub)
+
+	for _, ms := range req.Subscriptions {
+		if err := s.marketDataSvc.Subscribe(sub, ms.Symbol, channelFromPB(ms.Channel)); err != nil {
+			return toStatus(err)
+		}
+	}
+
+	for {
+		select {
+		case msg := <-sub.Messages():
+			if err := stream.Send(buildMarketDataUpdate(msg)); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return doneStatus(sub.Err())
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// doneStatus is the status a stream ends with when its subscription is
+// closed by the service rather than by the client.
+func doneStatus(err error) error {
+	if errors.Is(err, domain.ErrSlowConsumer) {
+		return toStatus(err)
+	}
+	return status.Error(codes.Unavailable, "subscription closed")
+}
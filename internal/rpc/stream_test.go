@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/efreitasn/miniexchange/internal/rpc/pb"
+)
+
+func TestStreamOrderUpdates(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Resuming from 0 replays everything, so events published before the
+	// server has subscribed are not missed.
+	stream, err := c.StreamOrderUpdates(ctx, &pb.StreamOrderUpdatesRequest{
+		BrokerId:    "broker1",
+		LastEventId: proto.Uint64(0),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	submit(t, c, limitOrder("broker2", pb.Side_SIDE_ASK, 10, 5))
+	bid := submit(t, c, limitOrder("broker1", pb.Side_SIDE_BID, 10, 5))
+
+	for i, want := range []string{"trade.executed", "balance.changed", "order.filled"} {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if ev.Id != uint64(i+1) || ev.Event != want {
+			t.Errorf("event %d = %d %s, want %d %s", i, ev.Id, ev.Event, i+1, want)
+		}
+		var payload struct {
+			Data struct {
+				OrderID string `json:"order_id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &payload); err != nil {
+			t.Fatalf("payload is not JSON: %v", err)
+		}
+		if payload.Data.OrderID != bid.OrderId {
+			t.Errorf("payload order_id = %q, want %q", payload.Data.OrderID, bid.OrderId)
+		}
+	}
+
+	// Resuming after the first event skips it.
+	resumed, err := c.StreamOrderUpdates(ctx, &pb.StreamOrderUpdatesRequest{
+		BrokerId:    "broker1",
+		LastEventId: proto.Uint64(1),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ev, err := resumed.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if ev.Id != 2 {
+		t.Errorf("resumed at event %d, want 2", ev.Id)
+	}
+}
+
+func TestStreamOrderUpdates_UnknownBroker(t *testing.T) {
+	c := newTestClient(t)
+	stream, err := c.StreamOrderUpdates(context.Background(), &pb.StreamOrderUpdatesRequest{BrokerId: "nobody"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = stream.Recv()
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestStreamMarketData(t *testing.T) {
+	c := newTestClient(t)
+	submit(t, c, limitOrder("broker2", pb.Side_SIDE_ASK, 10, 50))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.StreamMarketData(ctx, &pb.StreamMarketDataRequest{
+		Subscriptions: []*pb.MarketDataSubscription{
+			{Symbol: "AAPL", Channel: pb.MarketDataChannel_MARKET_DATA_CHANNEL_DEPTH},
+			{Symbol: "AAPL", Channel: pb.MarketDataChannel_MARKET_DATA_CHANNEL_TRADES},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	depth, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if depth.Type != pb.MarketDataUpdate_TYPE_SNAPSHOT || depth.Channel != pb.MarketDataChannel_MARKET_DATA_CHANNEL_DEPTH {
+		t.Fatalf("first message = %s %s, want depth snapshot", depth.Type, depth.Channel)
+	}
+	if asks := depth.GetDepth().Asks; len(asks) != 1 || asks[0].Price != 10 || asks[0].TotalQuantity != 50 {
+		t.Errorf("snapshot asks = %v", asks)
+	}
+	trades, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if trades.Type != pb.MarketDataUpdate_TYPE_SNAPSHOT || len(trades.GetTrades().GetTrades()) != 0 {
+		t.Fatalf("second message = %v, want empty trades snapshot", trades)
+	}
+
+	submit(t, c, limitOrder("broker1", pb.Side_SIDE_BID, 10, 20))
+
+	var sawTrade, sawDepth bool
+	for !sawTrade || !sawDepth {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if msg.Type != pb.MarketDataUpdate_TYPE_UPDATE {
+			t.Fatalf("unexpected %s", msg.Type)
+		}
+		switch data := msg.Data.(type) {
+		case *pb.MarketDataUpdate_Trades:
+			sawTrade = true
+			tr := data.Trades.Trades
+			if len(tr) != 1 || tr[0].Quantity != 20 || tr[0].AggressorSide != pb.Side_SIDE_BID {
+				t.Errorf("trade update = %v", tr)
+			}
+			if msg.Seq != trades.Seq+1 {
+				t.Errorf("trades seq = %d, want %d", msg.Seq, trades.Seq+1)
+			}
+		case *pb.MarketDataUpdate_Depth:
+			sawDepth = true
+			if asks := data.Depth.Asks; len(asks) != 1 || asks[0].TotalQuantity != 30 {
+				t.Errorf("depth update asks = %v", asks)
+			}
+		}
+	}
+}
+
+func TestStreamMarketData_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *pb.StreamMarketDataRequest
+		want codes.Code
+	}{
+		{"no subscriptions", &pb.StreamMarketDataRequest{}, codes.InvalidArgument},
+		{"unspecified channel", &pb.StreamMarketDataRequest{Subscriptions: []*pb.MarketDataSubscription{
+			{Symbol: "AAPL"},
+		}}, codes.InvalidArgument},
+		{"unknown symbol", &pb.StreamMarketDataRequest{Subscriptions: []*pb.MarketDataSubscription{
+			{Symbol: "ZZZZ", Channel: pb.MarketDataChannel_MARKET_DATA_CHANNEL_TRADES},
+		}}, codes.NotFound},
+	}
+	c := newTestClient(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := c.StreamMarketData(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = stream.Recv()
+			assertCode(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefix starts every issued secret, so leaked keys are easy to
+	// recognise in logs and by secret scanners.
+	apiKeyPrefix = "mx_"
+
+	// apiKeyDisplayLength is how many leading characters of a secret are
+	// kept in the clear to tell keys apart.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+
+	// maxAPIKeysPerBroker bounds how many keys a broker may hold at once.
+	maxAPIKeysPerBroker = 10
+)
+
+// APIKeyService issues, rotates and revokes broker API keys, and resolves
+// the caller of a request from the key it presents.
+type APIKeyService struct {
+	store       *store.APIKeyStore
+	brokerStore *store.BrokerStore
+	adminHash   []byte // SHA-256 of the admin key; nil if there is none
+}
+
+// NewAPIKeyService creates a new APIKeyService. adminKey is the secret that
+// authenticates as an admin; if it is empty, no caller can act as admin.
+func NewAPIKeyService(apiKeyStore *store.APIKeyStore, brokerStore *store.BrokerStore, adminKey string) *APIKeyService {
+	s := &APIKeyService{
+		store:       apiKeyStore,
+		brokerStore: brokerStore,
+	}
+	if adminKey != "" {
+		sum := sha256.Sum256([]byte(adminKey))
+		s.adminHash = sum[:]
+	}
+	return s
+}
+
+// Create issues a new key for a broker. It returns the key and its secret,
+// which is not stored and cannot be retrieved again.
+func (s *APIKeyService) Create(brokerID string) (*domain.APIKey, string, error) {
+	if !s.brokerStore.Exists(brokerID) {
+		return nil, "", domain.ErrBrokerNotFound
+	}
+	if s.store.CountByBroker(brokerID) >= maxAPIKeysPerBroker {
+		return nil, "", &domain.ValidationError{
+			Message: fmt.Sprintf("a broker may hold at most %d api keys", maxAPIKeysPerBroker),
+		}
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+	k := &domain.APIKey{
+		KeyID:     uuid.New().String(),
+		BrokerID:  brokerID,
+		Hash:      hashAPIKey(secret),
+		Prefix:    secret[:apiKeyDisplayLength],
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	s.store.Create(k)
+	return k, secret, nil
+}
+
+// List returns a broker's keys, oldest first.
+func (s *APIKeyService) List(brokerID string) ([]*domain.APIKey, error) {
+	if !s.brokerStore.Exists(brokerID) {
+		return nil, domain.ErrBrokerNotFound
+	}
+	return s.store.ListByBroker(brokerID), nil
+}
+
+// Rotate replaces the secret of one of a broker's keys, keeping its ID. The
+// old secret stops working immediately. It returns the key and its new
+// secret.
+func (s *APIKeyService) Rotate(brokerID, keyID string) (*domain.APIKey, string, error) {
+	if _, err := s.brokerKey(brokerID, keyID); err != nil {
+		return nil, "", err
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+	k, err := s.store.Rotate(keyID, hashAPIKey(secret), secret[:apiKeyDisplayLength], time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return nil, "", err
+	}
+	return k, secret, nil
+}
+
+// Revoke deletes one of a broker's keys. Requests using it are rejected
+// from then on.
+func (s *APIKeyService) Revoke(brokerID, keyID string) error {
+	if _, err := s.brokerKey(brokerID, keyID); err != nil {
+		return err
+	}
+	return s.store.Delete(keyID)
+}
+
+// Authenticate resolves a presented secret to the principal it belongs to.
+// It returns domain.ErrUnauthorized if the secret is not a live key.
+func (s *APIKeyService) Authenticate(secret string) (domain.Principal, error) {
+	if secret == "" {
+		return domain.Principal{}, domain.ErrUnauthorized
+	}
+	sum := sha256.Sum256([]byte(secret))
+	if s.adminHash != nil && subtle.ConstantTimeCompare(sum[:], s.adminHash) == 1 {
+		return domain.Principal{Role: domain.APIKeyRoleAdmin}, nil
+	}
+	k, err := s.store.GetByHash(hex.EncodeToString(sum[:]))
+	if err != nil {
+		return domain.Principal{}, domain.ErrUnauthorized
+	}
+	return domain.Principal{
+		Role:     domain.APIKeyRoleBroker,
+		BrokerID: k.BrokerID,
+		KeyID:    k.KeyID,
+	}, nil
+}
+
+// brokerKey returns keyID if it belongs to brokerID. A key of another
+// broker is reported as not found.
+func (s *APIKeyService) brokerKey(brokerID, keyID string) (*domain.APIKey, error) {
+	if !s.brokerStore.Exists(brokerID) {
+		return nil, domain.ErrBrokerNotFound
+	}
+	k, err := s.store.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if k.BrokerID != brokerID {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return k, nil
+}
+
+// newAPIKeySecret returns a new secret: the prefix followed by 256 random
+// bits in unpadded base64url.
+func newAPIKeySecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating api key: %w", err)
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// hashAPIKey returns the hex-encoded SHA-256 of a secret. Secrets carry 256
+// random bits, so a fast unsalted hash is enough to make a stolen store
+// useless.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+const testAdminKey = "admin-secret-for-tests"
+
+func newTestAPIKeyService() (*APIKeyService, *store.BrokerStore) {
+	bs := store.NewBrokerStore()
+	svc := NewAPIKeyService(store.NewAPIKeyStore(), bs, testAdminKey)
+	return svc, bs
+}
+
+func TestAPIKey_Create_Authenticate(t *testing.T) {
+	svc, bs := newTestAPIKeyService()
+	registerBroker(t, bs, "b1")
+
+	k, secret, err := svc.Create("b1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(secret, "mx_") {
+		t.Errorf("secret %q should start with mx_", secret)
+	}
+	if !strings.HasPrefix(secret, k.Prefix) || len(k.Prefix) != 11 {
+		t.Errorf("prefix %q should be the first 11 characters of the secret", k.Prefix)
+	}
+	if k.Hash == secret || strings.Contains(k.Hash, secret) {
+		t.Error("the secret must not be stored")
+	}
+
+	p, err := svc.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate: unexpected error: %v", err)
+	}
+	want := domain.Principal{Role: domain.APIKeyRoleBroker, BrokerID: "b1", KeyID: k.KeyID}
+	if p != want {
+		t.Errorf("principal = %+v, want %+v", p, want)
+	}
+}
+
+func TestAPIKey_Create_UnknownBroker(t *testing.T) {
+	svc, _ := newTestAPIKeyService()
+	if _, _, err := svc.Create("ghost"); !errors.Is(err, domain.ErrBrokerNotFound) {
+		t.Errorf("expected ErrBrokerNotFound, got %v", err)
+	}
+}
+
+func TestAPIKey_Create_Limit(t *testing.T) {
+	svc, bs := newTestAPIKeyService()
+	registerBroker(t, bs, "b1")
+	for i := 0; i < maxAPIKeysPerBroker; i++ {
+		if _, _, err := svc.Create("b1"); err != nil {
+			t.Fatalf("key %d: unexpected error: %v", i, err)
+		}
+	}
+	_, _, err := svc.Create("b1")
+	var ve *domain.ValidationError
+	if !errors.As(err, &ve) {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+}
+
+func TestAPIKey_Authenticate_Admin(t *testing.T) {
+	svc, _ := newTestAPIKeyService()
+	p, err := svc.Authenticate(testAdminKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Role != domain.APIKeyRoleAdmin {
+		t.Errorf("role = %q, want admin", p.Role)
+	}
+}
+
+func TestAPIKey_Authenticate_Rejects(t *testing.T) {
+	svc, _ := newTestAPIKeyService()
+	noAdmin := NewAPIKeyService(store.NewAPIKeyStore(), store.NewBrokerStore(), "")
+
+	for name, tc := range map[string]struct {
+		svc    *APIKeyService
+		secret string
+	}{
+		"empty":          {svc, ""},
+		"unknown":        {svc, "mx_unknown"},
+		"empty no admin": {noAdmin, ""},
+		"admin not set":  {noAdmin, testAdminKey},
+	} {
+		if _, err := tc.svc.Authenticate(tc.secret); !errors.Is(err, domain.ErrUnauthorized) {
+			t.Errorf("%s: expected ErrUnauthorized, got %v", name, err)
+		}
+	}
+}
+
+func TestAPIKey_Rotate(t *testing.T) {
+	svc, bs := newTestAPIKeyService()
+	registerBroker(t, bs, "b1")
+	k, oldSecret, _ := svc.Create("b1")
+
+	rotated, newSecret, err := svc.Rotate("b1", k.KeyID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated.KeyID != k.KeyID {
+		t.Errorf("key ID changed from %s to %s", k.KeyID, rotated.KeyID)
+	}
+	if rotated.RotatedAt == nil {
+		t.Error("RotatedAt should be set")
+	}
+	if newSecret == oldSecret {
+		t.Error("rotation should issue a new secret")
+	}
+	if _, err := svc.Authenticate(oldSecret); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("old secret: expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := svc.Authenticate(newSecret); err != nil {
+		t.Errorf("new secret: unexpected error: %v", err)
+	}
+}
+
+func TestAPIKey_OtherBrokersKey(t *testing.T) {
+	svc, bs := newTestAPIKeyService()
+	registerBroker(t, bs, "b1")
+	registerBroker(t, bs, "b2")
+	k, _, _ := svc.Create("b1")
+
+	if _, _, err := svc.Rotate("b2", k.KeyID); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("Rotate: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if err := svc.Revoke("b2", k.KeyID); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("Revoke: expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKey_Revoke(t *testing.T) {
+	svc, bs := newTestAPIKeyService()
+	registerBroker(t, bs, "b1")
+	k, secret, _ := svc.Create("b1")
+
+	if err := svc.Revoke("b1", k.KeyID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Authenticate(secret); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized after revoke, got %v", err)
+	}
+	keys, _ := svc.List("b1")
+	if len(keys) != 0 {
+		t.Errorf("expected no keys after revoke, got %d", len(keys))
+	}
+	if err := svc.Revoke("b1", k.KeyID); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("second revoke: expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
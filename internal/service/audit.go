@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/audit"
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// CancelInitiator says who cancelled an order, for the audit log.
+type CancelInitiator string
+
+const (
+	// CancelByBroker is a cancellation the order's broker asked for. It is
+	// assumed when the context names no initiator.
+	CancelByBroker CancelInitiator = "broker"
+	// CancelByAdmin is a cancellation an admin asked for.
+	CancelByAdmin CancelInitiator = "admin"
+	// CancelByReplace is the cancellation of an order being replaced.
+	CancelByReplace CancelInitiator = "replace"
+	// CancelByIOC is the cancellation of the unfilled remainder of a market
+	// order.
+	CancelByIOC CancelInitiator = "ioc"
+)
+
+// cancelInitiatorKey is the context key for WithCancelInitiator.
+type cancelInitiatorKey struct{}
+
+// WithCancelInitiator returns a context under which OrderService.CancelOrder
+// records initiator as the cancellation's initiator.
+func WithCancelInitiator(ctx context.Context, initiator CancelInitiator) context.Context {
+	return context.WithValue(ctx, cancelInitiatorKey{}, initiator)
+}
+
+// cancelInitiatorFrom returns the initiator stored by WithCancelInitiator,
+// or CancelByBroker.
+func cancelInitiatorFrom(ctx context.Context) CancelInitiator {
+	if i, ok := ctx.Value(cancelInitiatorKey{}).(CancelInitiator); ok {
+		return i
+	}
+	return CancelByBroker
+}
+
+// AuditService records order lifecycle events to the audit log and reads
+// them back. A failure to write a record is logged; it does not fail the
+// operation being recorded. Records of events that happen on the book are
+// stamped with the event's time; it is an engine.OrderAuditor and an
+// engine.ExpiryAuditor.
+//
+// Every Record method is safe to call on a nil *AuditService.
+type AuditService struct {
+	log    *audit.Log
+	logger *slog.Logger
+}
+
+// NewAuditService creates a new AuditService writing to log.
+func NewAuditService(log *audit.Log, logger *slog.Logger) *AuditService {
+	return &AuditService{log: log, logger: logger}
+}
+
+// ListOrderRecords returns every audit record about an order, oldest first.
+// It returns domain.ErrOrderNotFound if there are none.
+func (s *AuditService) ListOrderRecords(orderID string) ([]audit.Record, error) {
+	records, err := s.log.Query(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+	return records, nil
+}
+
+// RecordOrderReceived records an order submission, before it is validated,
+// under the ID it will have if it is accepted.
+func (s *AuditService) RecordOrderReceived(orderID string, req SubmitOrderRequest) {
+	s.append(requestRecord("order.received", orderID, req))
+}
+
+// RecordOrderRejected records the rejection of an order submission.
+func (s *AuditService) RecordOrderRejected(orderID string, req SubmitOrderRequest, err error) {
+	rec := requestRecord("order.rejected", orderID, req)
+	rec.Reason = RejectReason(err)
+	rec.Message = err.Error()
+	s.append(rec)
+}
+
+// RecordOrderAccepted records a newly accepted order as it stood before it
+// matched, with the cash or shares reserved for it.
+func (s *AuditService) RecordOrderAccepted(order *domain.Order) {
+	rec := orderRecord("order.accepted", order)
+	rec.Time = order.CreatedAt.UTC()
+	rec.After = &audit.State{
+		Status:            string(domain.OrderStatusPending),
+		RemainingQuantity: order.Quantity,
+	}
+	delta := audit.BalanceDelta{BrokerID: order.BrokerID}
+	switch {
+	case order.Side == domain.OrderSideAsk:
+		delta.ReservedHolding = order.Quantity
+	case order.Type == domain.OrderTypeLimit:
+		delta.ReservedCash = domain.CentsToDollars(order.Price * order.Quantity)
+	}
+	rec.BalanceDeltas = balanceDeltas(delta)
+	s.append(rec)
+}
+
+// RecordOrderMatched records trade, one of order's executions, made when
+// filledBefore of the order had already filled, with the settlement of the
+// order's broker. Only order's terms are read, not its current state.
+func (s *AuditService) RecordOrderMatched(order *domain.Order, trade *domain.Trade, filledBefore int64, counterpartyOrderID string) {
+	filledAfter := filledBefore + trade.Quantity
+
+	rec := orderRecord("order.matched", order)
+	rec.Time = trade.ExecutedAt.UTC()
+	rec.Trade = &audit.Trade{
+		TradeID:             trade.TradeID,
+		Price:               domain.CentsToDollars(trade.Price),
+		Quantity:            trade.Quantity,
+		Aggressor:           trade.Aggressor,
+		CounterpartyOrderID: counterpartyOrderID,
+	}
+	rec.Before = &audit.State{
+		Status:            string(fillStatus(filledBefore)),
+		FilledQuantity:    filledBefore,
+		RemainingQuantity: order.Quantity - filledBefore,
+	}
+	rec.After = &audit.State{
+		Status:            string(fillStatus(filledAfter)),
+		FilledQuantity:    filledAfter,
+		RemainingQuantity: order.Quantity - filledAfter,
+	}
+	if filledAfter == order.Quantity {
+		rec.After.Status = string(domain.OrderStatusFilled)
+	}
+
+	notional := trade.Price * trade.Quantity
+	delta := audit.BalanceDelta{BrokerID: order.BrokerID}
+	if order.Side == domain.OrderSideBid {
+		delta.Cash = -domain.CentsToDollars(notional)
+		delta.Holding = trade.Quantity
+		if order.Type == domain.OrderTypeLimit {
+			delta.ReservedCash = -domain.CentsToDollars(order.Price * trade.Quantity)
+		}
+	} else {
+		delta.Cash = domain.CentsToDollars(notional)
+		delta.Holding = -trade.Quantity
+		delta.ReservedHolding = -trade.Quantity
+	}
+	rec.BalanceDeltas = balanceDeltas(delta)
+	s.append(rec)
+}
+
+// fillStatus returns the status of a resting order that has filled the
+// given number of shares.
+func fillStatus(filled int64) domain.OrderStatus {
+	if filled == 0 {
+		return domain.OrderStatusPending
+	}
+	return domain.OrderStatusPartiallyFilled
+}
+
+// RecordOrderAmended records the replacement of original, which has been
+// cancelled, by replacement. The replacement is described as it was
+// accepted; its own records follow what became of it.
+func (s *AuditService) RecordOrderAmended(original, replacement *domain.Order) {
+	rec := orderRecord("order.amended", original)
+	rec.Time = replacement.CreatedAt.UTC()
+	rec.ReplacementOrderID = replacement.OrderID
+	rec.Before = terminalState(original)
+	rec.Before.Price = rec.Price
+	price := domain.CentsToDollars(replacement.Price)
+	rec.After = &audit.State{
+		Status:            string(domain.OrderStatusPending),
+		Price:             &price,
+		RemainingQuantity: replacement.Quantity,
+	}
+	s.append(rec)
+}
+
+// RecordOrderCancelled records the cancellation of order's unfilled
+// quantity and the release of what was reserved for it, with the initiator
+// ctx names (see WithCancelInitiator).
+func (s *AuditService) RecordOrderCancelled(ctx context.Context, order *domain.Order) {
+	rec := releaseRecord("order.cancelled", order, *order.CancelledAt)
+	rec.Initiator = string(cancelInitiatorFrom(ctx))
+	s.append(rec)
+}
+
+// RecordOrderUnfilled records the cancellation, at, of a market order's
+// unfilled quantity and the release of the shares reserved for it.
+func (s *AuditService) RecordOrderUnfilled(order *domain.Order, at time.Time) {
+	rec := releaseRecord("order.cancelled", order, at)
+	rec.Initiator = string(CancelByIOC)
+	s.append(rec)
+}
+
+// RecordOrderExpired records the expiration of order's unfilled quantity
+// and the release of what was reserved for it.
+func (s *AuditService) RecordOrderExpired(order *domain.Order) {
+	s.append(releaseRecord("order.expired", order, *order.ExpiredAt))
+}
+
+// releaseRecord builds the record of an order leaving the book at at, whose
+// remaining quantity has just moved to its cancelled quantity.
+func releaseRecord(event string, order *domain.Order, at time.Time) *audit.Record {
+	rec := orderRecord(event, order)
+	rec.Time = at.UTC()
+	rec.Before = &audit.State{
+		Status:            string(fillStatus(order.FilledQuantity)),
+		FilledQuantity:    order.FilledQuantity,
+		RemainingQuantity: order.CancelledQuantity,
+	}
+	rec.After = terminalState(order)
+
+	delta := audit.BalanceDelta{BrokerID: order.BrokerID}
+	switch {
+	case order.Side == domain.OrderSideAsk:
+		delta.ReservedHolding = -order.CancelledQuantity
+	case order.Type == domain.OrderTypeLimit:
+		delta.ReservedCash = -domain.CentsToDollars(order.Price * order.CancelledQuantity)
+	}
+	rec.BalanceDeltas = balanceDeltas(delta)
+	return rec
+}
+
+// balanceDeltas returns the deltas worth recording: none if d changes
+// nothing, as for a market bid, which reserves no cash.
+func balanceDeltas(d audit.BalanceDelta) []audit.BalanceDelta {
+	if d == (audit.BalanceDelta{BrokerID: d.BrokerID}) {
+		return nil
+	}
+	return []audit.BalanceDelta{d}
+}
+
+// terminalState returns the state of an order that is off the book.
+func terminalState(order *domain.Order) *audit.State {
+	return &audit.State{
+		Status:            string(order.Status),
+		FilledQuantity:    order.FilledQuantity,
+		RemainingQuantity: order.RemainingQuantity,
+		CancelledQuantity: order.CancelledQuantity,
+	}
+}
+
+// orderRecord builds a record with the terms of an accepted order.
+func orderRecord(event string, order *domain.Order) *audit.Record {
+	rec := &audit.Record{
+		Event:    event,
+		OrderID:  order.OrderID,
+		BrokerID: order.BrokerID,
+		Symbol:   order.Symbol,
+		Side:     string(order.Side),
+		Type:     string(order.Type),
+		Quantity: order.Quantity,
+	}
+	if order.Type == domain.OrderTypeLimit {
+		price := domain.CentsToDollars(order.Price)
+		rec.Price = &price
+	}
+	if order.ExpiresAt != nil {
+		expiresAt := order.ExpiresAt.UTC().Format(time.RFC3339)
+		rec.ExpiresAt = &expiresAt
+	}
+	return rec
+}
+
+// requestRecord builds a record with the terms of an order submission.
+func requestRecord(event, orderID string, req SubmitOrderRequest) *audit.Record {
+	rec := &audit.Record{
+		Event:    event,
+		OrderID:  orderID,
+		BrokerID: req.BrokerID,
+		Symbol:   req.Symbol,
+		Side:     string(req.Side),
+		Type:     string(req.Type),
+		Price:    req.Price,
+		Quantity: req.Quantity,
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC().Format(time.RFC3339)
+		rec.ExpiresAt = &expiresAt
+	}
+	return rec
+}
+
+// append writes rec to the audit log, logging a failure.
+func (s *AuditService) append(rec *audit.Record) {
+	if s == nil {
+		return
+	}
+	if err := s.log.Append(rec); err != nil {
+		s.logger.Error("failed to write audit record",
+			slog.String("event", rec.Event),
+			slog.String("order_id", rec.OrderID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
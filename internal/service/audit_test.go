@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/audit"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+// newAuditEnv returns an order env whose order service and expiry manager
+// record to an audit log in dir, with "seller" holding 500 AAPL and "buyer"
+// holding $100,000.
+func newAuditEnv(t *testing.T, dir string) (*testOrderEnv, *AuditService) {
+	t.Helper()
+	log, err := audit.Open(dir, 1<<20, 0)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	auditSvc := NewAuditService(log, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	env := newTestOrderEnv()
+	env.matcher = engine.NewMatcher(env.books, env.brokerStore, env.orderStore, env.tradeStore, env.symbols, nil, auditSvc, nil, nil)
+	env.expiry = engine.NewExpiryManager(10*time.Millisecond, env.books, env.orderStore, env.brokerStore, nil, nil, auditSvc, nil)
+	env.svc = NewOrderService(env.matcher, env.expiry, env.brokerStore, env.orderStore, env.tradeStore, nil, env.symbols, nil, auditSvc, nil, nil)
+
+	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 500}})
+	env.registerBroker(t, "buyer", 100000.00, nil)
+	return env, auditSvc
+}
+
+// auditEvents returns the event of each of an order's audit records.
+func auditEvents(t *testing.T, auditSvc *AuditService, orderID string) ([]audit.Record, []string) {
+	t.Helper()
+	records, err := auditSvc.ListOrderRecords(orderID)
+	if err != nil {
+		t.Fatalf("list audit records for %s: %v", orderID, err)
+	}
+	events := make([]string, len(records))
+	for i, rec := range records {
+		events[i] = rec.Event
+	}
+	return records, events
+}
+
+func TestAudit_MatchedOrders(t *testing.T) {
+	env, auditSvc := newAuditEnv(t, t.TempDir())
+	ctx := context.Background()
+
+	ask, err := env.svc.SubmitOrder(ctx, limitOrder("seller", domain.OrderSideAsk, 150.00, 10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bid, err := env.svc.SubmitOrder(ctx, limitOrder("buyer", domain.OrderSideBid, 151.00, 4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, events := auditEvents(t, auditSvc, bid.OrderID)
+	if want := []string{"order.received", "order.accepted", "order.matched"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("bid events: expected %v, got %v", want, events)
+	}
+	accepted := records[1]
+	if want := []audit.BalanceDelta{{BrokerID: "buyer", ReservedCash: 604.00}}; !reflect.DeepEqual(accepted.BalanceDeltas, want) {
+		t.Errorf("accepted deltas: expected %+v, got %+v", want, accepted.BalanceDeltas)
+	}
+	matched := records[2]
+	if matched.Trade == nil || matched.Trade.Price != 150.00 || matched.Trade.Quantity != 4 ||
+		!matched.Trade.Aggressor || matched.Trade.CounterpartyOrderID != ask.OrderID {
+		t.Errorf("unexpected trade: %+v", matched.Trade)
+	}
+	if want := (audit.State{Status: "pending", RemainingQuantity: 4}); *matched.Before != want {
+		t.Errorf("before: expected %+v, got %+v", want, *matched.Before)
+	}
+	if want := (audit.State{Status: "filled", FilledQuantity: 4}); *matched.After != want {
+		t.Errorf("after: expected %+v, got %+v", want, *matched.After)
+	}
+	// Records are stamped with the time of the event, not of the append.
+	if !accepted.Time.Equal(bid.CreatedAt) || !matched.Time.Equal(bid.Trades[0].ExecutedAt) {
+		t.Errorf("got times %v and %v, want %v and %v", accepted.Time, matched.Time, bid.CreatedAt, bid.Trades[0].ExecutedAt)
+	}
+	// Bought at the ask's price, releasing the reservation at the bid's.
+	if want := []audit.BalanceDelta{{BrokerID: "buyer", Cash: -600.00, ReservedCash: -604.00, Holding: 4}}; !reflect.DeepEqual(matched.BalanceDeltas, want) {
+		t.Errorf("matched deltas: expected %+v, got %+v", want, matched.BalanceDeltas)
+	}
+
+	records, events = auditEvents(t, auditSvc, ask.OrderID)
+	if want := []string{"order.received", "order.accepted", "order.matched"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("ask events: expected %v, got %v", want, events)
+	}
+	matched = records[2]
+	if matched.Trade.Aggressor || matched.Trade.CounterpartyOrderID != bid.OrderID {
+		t.Errorf("unexpected trade: %+v", matched.Trade)
+	}
+	if want := (audit.State{Status: "partially_filled", FilledQuantity: 4, RemainingQuantity: 6}); *matched.After != want {
+		t.Errorf("after: expected %+v, got %+v", want, *matched.After)
+	}
+	if want := []audit.BalanceDelta{{BrokerID: "seller", Cash: 600.00, Holding: -4, ReservedHolding: -4}}; !reflect.DeepEqual(matched.BalanceDeltas, want) {
+		t.Errorf("matched deltas: expected %+v, got %+v", want, matched.BalanceDeltas)
+	}
+	if records[0].Seq >= records[1].Seq || records[1].Seq >= records[2].Seq {
+		t.Errorf("expected increasing sequence numbers, got %d, %d, %d", records[0].Seq, records[1].Seq, records[2].Seq)
+	}
+}
+
+func TestAudit_Rejected(t *testing.T) {
+	dir := t.TempDir()
+	env, auditSvc := newAuditEnv(t, dir)
+
+	req := limitOrder("buyer", domain.OrderSideBid, 150.00, 1000)
+	if _, err := env.svc.SubmitOrder(context.Background(), req); err != domain.ErrInsufficientBalance {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+
+	// The rejected order is never stored, so take its ID from the log.
+	data, err := os.ReadFile(filepath.Join(dir, "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	var received audit.Record
+	if err := json.Unmarshal(bytes.SplitN(data, []byte("\n"), 2)[0], &received); err != nil {
+		t.Fatalf("decode first record: %v", err)
+	}
+	if received.Event != "order.received" || received.OrderID == "" {
+		t.Fatalf("expected order.received with an order ID, got %+v", received)
+	}
+
+	records, events := auditEvents(t, auditSvc, received.OrderID)
+	if want := []string{"order.received", "order.rejected"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+	rejected := records[1]
+	if rejected.Reason != "insufficient_balance" || rejected.Quantity != req.Quantity || *rejected.Price != *req.Price {
+		t.Errorf("unexpected rejection: %+v", rejected)
+	}
+	if rejected.Before != nil || rejected.After != nil || rejected.BalanceDeltas != nil {
+		t.Errorf("expected a rejection to change nothing, got %+v", rejected)
+	}
+	if _, err := env.orderStore.Get(received.OrderID); err == nil {
+		t.Error("expected the rejected order not to be stored")
+	}
+}
+
+func TestAudit_CancelInitiator(t *testing.T) {
+	env, auditSvc := newAuditEnv(t, t.TempDir())
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		ctx  context.Context
+		want CancelInitiator
+	}{
+		{ctx, CancelByBroker},
+		{WithCancelInitiator(ctx, CancelByAdmin), CancelByAdmin},
+	} {
+		order, err := env.svc.SubmitOrder(ctx, limitOrder("seller", domain.OrderSideAsk, 150.00, 10))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := env.svc.CancelOrder(tc.ctx, order.OrderID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		records, events := auditEvents(t, auditSvc, order.OrderID)
+		if want := []string{"order.received", "order.accepted", "order.cancelled"}; !reflect.DeepEqual(events, want) {
+			t.Fatalf("expected %v, got %v", want, events)
+		}
+		cancelled := records[2]
+		if cancelled.Initiator != string(tc.want) {
+			t.Errorf("initiator: expected %s, got %s", tc.want, cancelled.Initiator)
+		}
+		if want := (audit.State{Status: "pending", RemainingQuantity: 10}); *cancelled.Before != want {
+			t.Errorf("before: expected %+v, got %+v", want, *cancelled.Before)
+		}
+		if want := (audit.State{Status: "cancelled", CancelledQuantity: 10}); *cancelled.After != want {
+			t.Errorf("after: expected %+v, got %+v", want, *cancelled.After)
+		}
+		if want := []audit.BalanceDelta{{BrokerID: "seller", ReservedHolding: -10}}; !reflect.DeepEqual(cancelled.BalanceDeltas, want) {
+			t.Errorf("deltas: expected %+v, got %+v", want, cancelled.BalanceDeltas)
+		}
+	}
+}
+
+func TestAudit_Amended(t *testing.T) {
+	env, auditSvc := newAuditEnv(t, t.TempDir())
+	ctx := context.Background()
+
+	original, err := env.svc.SubmitOrder(ctx, limitOrder("buyer", domain.OrderSideBid, 150.00, 10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, replacement, err := env.svc.ReplaceOrder(ctx, ReplaceOrderRequest{OrderID: original.OrderID, Price: 149.50, Quantity: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, events := auditEvents(t, auditSvc, original.OrderID)
+	if want := []string{"order.received", "order.accepted", "order.cancelled", "order.amended"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+	if records[2].Initiator != string(CancelByReplace) {
+		t.Errorf("initiator: expected replace, got %s", records[2].Initiator)
+	}
+	amended := records[3]
+	if amended.ReplacementOrderID != replacement.OrderID {
+		t.Errorf("replacement: expected %s, got %s", replacement.OrderID, amended.ReplacementOrderID)
+	}
+	if *amended.Before.Price != 150.00 || amended.Before.CancelledQuantity != 10 {
+		t.Errorf("unexpected before: %+v", amended.Before)
+	}
+	if *amended.After.Price != 149.50 || amended.After.RemainingQuantity != 20 {
+		t.Errorf("unexpected after: %+v", amended.After)
+	}
+
+	// The amendment is also part of the replacement's trail.
+	_, events = auditEvents(t, auditSvc, replacement.OrderID)
+	if want := []string{"order.received", "order.accepted", "order.amended"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("replacement events: expected %v, got %v", want, events)
+	}
+}
+
+func TestAudit_MarketOrderRemainderCancelled(t *testing.T) {
+	env, auditSvc := newAuditEnv(t, t.TempDir())
+	ctx := context.Background()
+
+	if _, err := env.svc.SubmitOrder(ctx, limitOrder("buyer", domain.OrderSideBid, 150.00, 3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order, err := env.svc.SubmitOrder(ctx, SubmitOrderRequest{
+		Type:           domain.OrderTypeMarket,
+		BrokerID:       "seller",
+		DocumentNumber: "DOC001",
+		Side:           domain.OrderSideAsk,
+		Symbol:         "AAPL",
+		Quantity:       5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, events := auditEvents(t, auditSvc, order.OrderID)
+	if want := []string{"order.received", "order.accepted", "order.matched", "order.cancelled"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+	cancelled := records[3]
+	if cancelled.Initiator != string(CancelByIOC) {
+		t.Errorf("initiator: expected ioc, got %s", cancelled.Initiator)
+	}
+	if want := (audit.State{Status: "partially_filled", FilledQuantity: 3, RemainingQuantity: 2}); *cancelled.Before != want {
+		t.Errorf("before: expected %+v, got %+v", want, *cancelled.Before)
+	}
+	if want := []audit.BalanceDelta{{BrokerID: "seller", ReservedHolding: -2}}; !reflect.DeepEqual(cancelled.BalanceDeltas, want) {
+		t.Errorf("deltas: expected %+v, got %+v", want, cancelled.BalanceDeltas)
+	}
+}
+
+func TestAudit_Expired(t *testing.T) {
+	env, auditSvc := newAuditEnv(t, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := limitOrder("buyer", domain.OrderSideBid, 150.00, 10)
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	req.ExpiresAt = &expiresAt
+	order, err := env.svc.SubmitOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env.expiry.Start(ctx)
+
+	var records []audit.Record
+	waitFor(t, "order.expired", func() bool {
+		records, _ = auditSvc.ListOrderRecords(order.OrderID)
+		return len(records) == 3
+	})
+	expired := records[2]
+	if expired.Event != "order.expired" {
+		t.Fatalf("expected order.expired, got %s", expired.Event)
+	}
+	if want := (audit.State{Status: "expired", CancelledQuantity: 10}); *expired.After != want {
+		t.Errorf("after: expected %+v, got %+v", want, *expired.After)
+	}
+	if want := []audit.BalanceDelta{{BrokerID: "buyer", ReservedCash: -1500.00}}; !reflect.DeepEqual(expired.BalanceDeltas, want) {
+		t.Errorf("deltas: expected %+v, got %+v", want, expired.BalanceDeltas)
+	}
+}
+
+func TestAudit_ListOrderRecords_NotFound(t *testing.T) {
+	_, auditSvc := newAuditEnv(t, t.TempDir())
+
+	if _, err := auditSvc.ListOrderRecords("no-such-order"); err != domain.ErrOrderNotFound {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
@@ -49,13 +50,19 @@ type HoldingBalance struct {
 type BrokerService struct {
 	store   *store.BrokerStore
 	symbols *domain.SymbolRegistry
+	clock   clock.Clock
 }
 
-// NewBrokerService creates a new BrokerService.
-func NewBrokerService(store *store.BrokerStore, symbols *domain.SymbolRegistry) *BrokerService {
+// NewBrokerService creates a new BrokerService. Brokers are stamped with
+// clk's time; a nil clk is the wall clock.
+func NewBrokerService(store *store.BrokerStore, symbols *domain.SymbolRegistry, clk clock.Clock) *BrokerService {
+	if clk == nil {
+		clk = clock.System
+	}
 	return &BrokerService{
 		store:   store,
 		symbols: symbols,
+		clock:   clk,
 	}
 }
 
@@ -118,7 +125,7 @@ func (s *BrokerService) Register(req RegisterBrokerRequest) (*domain.Broker, err
 		CashBalance:  cashCents,
 		ReservedCash: 0,
 		Holdings:     holdings,
-		CreatedAt:    time.Now(),
+		CreatedAt:    s.clock.Now(),
 	}
 
 	// Attempt to create (returns ErrBrokerAlreadyExists if duplicate)
@@ -163,3 +170,8 @@ func (s *BrokerService) GetBalance(brokerID string) (*BalanceResponse, error) {
 		UpdatedAt:     broker.CreatedAt,
 	}, nil
 }
+
+// Exists reports whether a broker with the given ID is registered.
+func (s *BrokerService) Exists(brokerID string) bool {
+	return s.store.Exists(brokerID)
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func newTestBrokerService() *BrokerService {
-	return NewBrokerService(store.NewBrokerStore(), domain.NewSymbolRegistry())
+	return NewBrokerService(store.NewBrokerStore(), domain.NewSymbolRegistry(), nil)
 }
 
 func TestRegister_Success_CashOnly(t *testing.T) {
@@ -78,7 +78,7 @@ func TestRegister_Success_ZeroCash(t *testing.T) {
 func TestRegister_Success_SymbolsRegistered(t *testing.T) {
 	bs := store.NewBrokerStore()
 	sr := domain.NewSymbolRegistry()
-	svc := NewBrokerService(bs, sr)
+	svc := NewBrokerService(bs, sr, nil)
 
 	_, err := svc.Register(RegisterBrokerRequest{
 		BrokerID:    "broker-sym",
@@ -341,3 +341,17 @@ func TestGetBalance_EmptyHoldings(t *testing.T) {
 		t.Errorf("got cash_balance %d, want %d", bal.CashBalance, 50000)
 	}
 }
+
+func TestExists(t *testing.T) {
+	svc := newTestBrokerService()
+	if _, err := svc.Register(RegisterBrokerRequest{BrokerID: "broker-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !svc.Exists("broker-1") {
+		t.Error("expected broker-1 to exist")
+	}
+	if svc.Exists("broker-2") {
+		t.Error("expected broker-2 not to exist")
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// maxFilledCandles bounds the number of buckets a request with empty-bucket
+// filling may span, since filled output is not bounded by trade history.
+const maxFilledCandles = 5000
+
+// CandleInterval is a supported candle resolution.
+type CandleInterval string
+
+// Candle intervals.
+const (
+	CandleInterval1m CandleInterval = "1m"
+	CandleInterval5m CandleInterval = "5m"
+	CandleInterval1h CandleInterval = "1h"
+	CandleInterval1d CandleInterval = "1d"
+)
+
+// candleIntervals maps each supported interval to its bucket width, in the
+// order they are listed in validation messages.
+var candleIntervals = []struct {
+	interval CandleInterval
+	width    time.Duration
+}{
+	{CandleInterval1m, time.Minute},
+	{CandleInterval5m, 5 * time.Minute},
+	{CandleInterval1h, time.Hour},
+	{CandleInterval1d, 24 * time.Hour},
+}
+
+// Candle is one OHLCV bucket. Prices are in cents. Buckets are aligned to
+// UTC: daily candles start at midnight UTC.
+type Candle struct {
+	Start      time.Time
+	End        time.Time // exclusive
+	Open       int64
+	High       int64
+	Low        int64
+	Close      int64
+	Volume     int64
+	Notional   int64 // sum(price × quantity), in cents
+	TradeCount int
+	Partial    bool // the bucket has not closed yet
+}
+
+// VWAP returns the bucket's volume-weighted average price, or false for a
+// bucket without trades.
+func (c *Candle) VWAP() (int64, bool) {
+	if c.Volume == 0 {
+		return 0, false
+	}
+	return c.Notional / c.Volume, true
+}
+
+// CandleRequest represents the input for GET /stocks/{symbol}/candles.
+type CandleRequest struct {
+	Symbol    string
+	Interval  CandleInterval
+	From      *time.Time // inclusive; nil for the first candle
+	To        *time.Time // exclusive; nil for now
+	FillEmpty bool       // include empty buckets, carrying the previous close forward
+}
+
+// CandleResponse represents the response for GET /stocks/{symbol}/candles.
+type CandleResponse struct {
+	Symbol   string
+	Interval CandleInterval
+	Candles  []Candle
+}
+
+// candleSeries holds one symbol's candles at one interval, oldest first.
+type candleSeries struct {
+	width   time.Duration
+	candles []Candle
+}
+
+// CandleService maintains OHLCV candles for every symbol and interval. It is
+// seeded from the trade store once and then updated from book updates as
+// trades execute, so queries never rescan trade history.
+type CandleService struct {
+	symbols *domain.SymbolRegistry
+	clock   clock.Clock
+
+	mu     sync.RWMutex
+	series map[string]map[CandleInterval]*candleSeries // symbol → interval → series
+}
+
+// NewCandleService creates a CandleService seeded with the trades already in
+// tradeStore; see seedExecutions for when to register it as a book listener.
+// Queries without an end run up to clk's time; a nil clk is the wall clock.
+func NewCandleService(tradeStore *store.TradeStore, symbols *domain.SymbolRegistry, clk clock.Clock) *CandleService {
+	if clk == nil {
+		clk = clock.System
+	}
+	s := &CandleService{
+		symbols: symbols,
+		clock:   clk,
+		series:  make(map[string]map[CandleInterval]*candleSeries),
+	}
+	seedExecutions(tradeStore, s.applyLocked)
+	return s
+}
+
+// OnBookUpdate folds the update's trades into every interval. It implements
+// engine.BookListener.
+func (s *CandleService) OnBookUpdate(u *engine.BookUpdate) {
+	if len(u.Trades) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range u.Trades {
+		s.applyLocked(u.Symbol, t)
+	}
+}
+
+// applyLocked adds one trade to the symbol's candles. The caller must hold
+// s.mu for writing or be the constructor.
+func (s *CandleService) applyLocked(symbol string, t engine.TradeEvent) {
+	bySymbol, ok := s.series[symbol]
+	if !ok {
+		bySymbol = make(map[CandleInterval]*candleSeries, len(candleIntervals))
+		for _, ci := range candleIntervals {
+			bySymbol[ci.interval] = &candleSeries{width: ci.width}
+		}
+		s.series[symbol] = bySymbol
+	}
+	for _, cs := range bySymbol {
+		cs.add(t.Price, t.Quantity, t.ExecutedAt)
+	}
+}
+
+// add updates the bucket containing at, creating it if needed. Trades arrive
+// in execution order, so this is almost always the last bucket.
+func (cs *candleSeries) add(price, qty int64, at time.Time) {
+	start := at.UTC().Truncate(cs.width)
+
+	n := len(cs.candles)
+	i := n
+	if n == 0 || cs.candles[n-1].Start.Before(start) {
+		cs.candles = append(cs.candles, Candle{})
+	} else {
+		i = sort.Search(n, func(j int) bool { return !cs.candles[j].Start.Before(start) })
+		if !cs.candles[i].Start.Equal(start) {
+			cs.candles = append(cs.candles, Candle{})
+			copy(cs.candles[i+1:], cs.candles[i:])
+			cs.candles[i] = Candle{}
+		}
+	}
+
+	c := &cs.candles[i]
+	if c.TradeCount == 0 {
+		*c = Candle{
+			Start: start,
+			End:   start.Add(cs.width),
+			Open:  price,
+			High:  price,
+			Low:   price,
+		}
+	}
+	c.High = max(c.High, price)
+	c.Low = min(c.Low, price)
+	c.Close = price
+	c.Volume += qty
+	c.Notional += price * qty
+	c.TradeCount++
+}
+
+// GetCandles returns the symbol's candles at the requested interval, oldest
+// first. The bucket containing the current time is flagged as partial.
+func (s *CandleService) GetCandles(req CandleRequest) (*CandleResponse, error) {
+	if !s.symbols.Exists(req.Symbol) {
+		return nil, domain.ErrSymbolNotFound
+	}
+
+	var width time.Duration
+	for _, ci := range candleIntervals {
+		if ci.interval == req.Interval {
+			width = ci.width
+		}
+	}
+	if width == 0 {
+		return nil, &domain.ValidationError{
+			Message: fmt.Sprintf("Unknown interval: %s. Must be one of: 1m, 5m, 1h, 1d", req.Interval),
+		}
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, &domain.ValidationError{Message: "from must be before to"}
+	}
+
+	now := s.clock.Now().UTC()
+	// Buckets are selected by start time: the one containing from is
+	// included, and buckets starting at or after to are not.
+	end := now
+	if req.To != nil {
+		end = req.To.UTC()
+	}
+
+	s.mu.RLock()
+	var stored []Candle
+	if bySymbol, ok := s.series[req.Symbol]; ok {
+		stored = bySymbol[req.Interval].candles
+	}
+
+	first := 0
+	if req.From != nil {
+		from := req.From.UTC().Truncate(width)
+		first = sort.Search(len(stored), func(i int) bool { return !stored[i].Start.Before(from) })
+	}
+	last := sort.Search(len(stored), func(i int) bool { return !stored[i].Start.Before(end) })
+
+	candles := make([]Candle, 0, max(last-first, 0))
+	if req.FillEmpty {
+		candles = fillCandles(stored, first, last, req.From, end, width)
+	} else if first < last {
+		candles = append(candles, stored[first:last]...)
+	}
+	s.mu.RUnlock()
+
+	// fillCandles stops shortly after the limit, so this also bounds its work.
+	if req.FillEmpty && len(candles) > maxFilledCandles {
+		return nil, &domain.ValidationError{
+			Message: fmt.Sprintf("from/to span more than %d candles; narrow the range or disable fill_empty", maxFilledCandles),
+		}
+	}
+
+	for i := range candles {
+		candles[i].Partial = !now.Before(candles[i].Start) && now.Before(candles[i].End)
+	}
+
+	return &CandleResponse{
+		Symbol:   req.Symbol,
+		Interval: req.Interval,
+		Candles:  candles,
+	}, nil
+}
+
+// fillCandles returns stored[first:last] with a bucket for every interval
+// between them and up to end, each empty bucket carrying the previous close.
+// Buckets before the first known price are omitted since they have no close
+// to carry.
+func fillCandles(stored []Candle, first, last int, from *time.Time, end time.Time, width time.Duration) []Candle {
+	var prev *Candle
+	if first > 0 {
+		prev = &stored[first-1]
+	}
+
+	next := time.Time{}
+	if from != nil {
+		next = from.UTC().Truncate(width)
+	}
+
+	result := make([]Candle, 0, last-first)
+	appendEmptyUntil := func(limit time.Time) {
+		if prev == nil {
+			return
+		}
+		if next.IsZero() || next.Before(prev.End) {
+			next = prev.End
+		}
+		for ; next.Before(limit) && len(result) <= maxFilledCandles; next = next.Add(width) {
+			result = append(result, Candle{
+				Start: next,
+				End:   next.Add(width),
+				Open:  prev.Close,
+				High:  prev.Close,
+				Low:   prev.Close,
+				Close: prev.Close,
+			})
+		}
+	}
+
+	for i := first; i < last; i++ {
+		appendEmptyUntil(stored[i].Start)
+		result = append(result, stored[i])
+		prev = &stored[i]
+		next = prev.End
+	}
+	appendEmptyUntil(end)
+
+	return result
+}
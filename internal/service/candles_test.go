@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+func newTestCandleService() (*CandleService, *store.TradeStore) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	ts := store.NewTradeStore()
+	return NewCandleService(ts, sr, nil), ts
+}
+
+// tradeUpdate builds a book update carrying one trade.
+func tradeUpdate(price, qty int64, at time.Time) *engine.BookUpdate {
+	return &engine.BookUpdate{
+		Symbol: "AAPL",
+		Trades: []engine.TradeEvent{{TradeID: "t", Price: price, Quantity: qty, AggressorSide: domain.OrderSideBid, ExecutedAt: at}},
+	}
+}
+
+var candleBase = time.Date(2026, 2, 16, 16, 0, 0, 0, time.UTC)
+
+func TestCandles_OHLCVPerBucket(t *testing.T) {
+	svc, _ := newTestCandleService()
+	svc.OnBookUpdate(tradeUpdate(15000, 10, candleBase.Add(5*time.Second)))
+	svc.OnBookUpdate(tradeUpdate(15200, 5, candleBase.Add(20*time.Second)))
+	svc.OnBookUpdate(tradeUpdate(14900, 5, candleBase.Add(40*time.Second)))
+	svc.OnBookUpdate(tradeUpdate(15100, 20, candleBase.Add(90*time.Second)))
+
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 2 {
+		t.Fatalf("expected 2 candles, got %d", len(resp.Candles))
+	}
+	c := resp.Candles[0]
+	if !c.Start.Equal(candleBase) || !c.End.Equal(candleBase.Add(time.Minute)) {
+		t.Errorf("unexpected bounds %v-%v", c.Start, c.End)
+	}
+	if c.Open != 15000 || c.High != 15200 || c.Low != 14900 || c.Close != 14900 {
+		t.Errorf("unexpected OHLC %d/%d/%d/%d", c.Open, c.High, c.Low, c.Close)
+	}
+	if c.Volume != 20 || c.TradeCount != 3 {
+		t.Errorf("got volume=%d trades=%d, want 20/3", c.Volume, c.TradeCount)
+	}
+	// (15000*10 + 15200*5 + 14900*5) / 20 = 15025
+	if vwap, ok := c.VWAP(); !ok || vwap != 15025 {
+		t.Errorf("got vwap %d, want 15025", vwap)
+	}
+	if c.Partial {
+		t.Error("closed bucket must not be partial")
+	}
+
+	hourly, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1h})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hourly.Candles) != 1 || hourly.Candles[0].Volume != 40 || hourly.Candles[0].Close != 15100 {
+		t.Errorf("unexpected hourly candles %+v", hourly.Candles)
+	}
+}
+
+func TestCandles_SeededFromTradeStoreOncePerTrade(t *testing.T) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	ts := store.NewTradeStore()
+	at := candleBase.Add(time.Second)
+	ts.Append("AAPL", &domain.Trade{TradeID: "t1", OrderID: "in", Side: domain.OrderSideBid, Aggressor: true, Price: 15000, Quantity: 10, ExecutedAt: at})
+	ts.Append("AAPL", &domain.Trade{TradeID: "t1", OrderID: "rest", Side: domain.OrderSideAsk, Price: 15000, Quantity: 10, ExecutedAt: at})
+
+	svc := NewCandleService(ts, sr, nil)
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1d})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 1 || resp.Candles[0].Volume != 10 || resp.Candles[0].TradeCount != 1 {
+		t.Fatalf("unexpected candles %+v", resp.Candles)
+	}
+	if !resp.Candles[0].Start.Equal(time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily candle should start at midnight UTC, got %v", resp.Candles[0].Start)
+	}
+}
+
+func TestCandles_FromToSelectsBuckets(t *testing.T) {
+	svc, _ := newTestCandleService()
+	for i := 0; i < 5; i++ {
+		svc.OnBookUpdate(tradeUpdate(15000+int64(i), 1, candleBase.Add(time.Duration(i)*time.Minute+time.Second)))
+	}
+
+	from := candleBase.Add(90 * time.Second) // inside the second bucket
+	to := candleBase.Add(3 * time.Minute)
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 2 || resp.Candles[0].Open != 15001 || resp.Candles[1].Open != 15002 {
+		t.Fatalf("unexpected candles %+v", resp.Candles)
+	}
+}
+
+func TestCandles_FillEmptyCarriesCloseForward(t *testing.T) {
+	svc, _ := newTestCandleService()
+	svc.OnBookUpdate(tradeUpdate(15000, 1, candleBase.Add(time.Second)))
+	svc.OnBookUpdate(tradeUpdate(15500, 1, candleBase.Add(3*time.Minute+time.Second)))
+
+	to := candleBase.Add(5 * time.Minute)
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, To: &to, FillEmpty: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 5 {
+		t.Fatalf("expected 5 candles, got %d", len(resp.Candles))
+	}
+	for _, i := range []int{1, 2} {
+		c := resp.Candles[i]
+		if c.TradeCount != 0 || c.Volume != 0 || c.Open != 15000 || c.Close != 15000 {
+			t.Errorf("candle %d: expected empty bucket carrying 150.00, got %+v", i, c)
+		}
+		if _, ok := c.VWAP(); ok {
+			t.Errorf("candle %d: empty bucket must have no vwap", i)
+		}
+	}
+	if resp.Candles[3].Close != 15500 || resp.Candles[4].Close != 15500 || resp.Candles[4].TradeCount != 0 {
+		t.Errorf("unexpected tail %+v", resp.Candles[3:])
+	}
+
+	without, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, To: &to})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(without.Candles) != 2 {
+		t.Errorf("expected only 2 candles without fill, got %d", len(without.Candles))
+	}
+}
+
+func TestCandles_CurrentBucketIsPartial(t *testing.T) {
+	svc, _ := newTestCandleService()
+	svc.OnBookUpdate(tradeUpdate(15000, 1, time.Now()))
+
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1h})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 1 || !resp.Candles[0].Partial {
+		t.Fatalf("expected one partial candle, got %+v", resp.Candles)
+	}
+}
+
+func TestCandles_OutOfOrderTradeLandsInItsBucket(t *testing.T) {
+	svc, _ := newTestCandleService()
+	svc.OnBookUpdate(tradeUpdate(15000, 1, candleBase.Add(2*time.Minute)))
+	svc.OnBookUpdate(tradeUpdate(14000, 1, candleBase))
+
+	resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Candles) != 2 || resp.Candles[0].Open != 14000 || resp.Candles[1].Open != 15000 {
+		t.Fatalf("unexpected candles %+v", resp.Candles)
+	}
+}
+
+func TestCandles_ValidationErrors(t *testing.T) {
+	svc, _ := newTestCandleService()
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	tests := []struct {
+		name string
+		req  CandleRequest
+	}{
+		{"unknown interval", CandleRequest{Symbol: "AAPL", Interval: "2m"}},
+		{"from after to", CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, From: &now, To: &earlier}},
+		{"fill spans too many buckets", CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, From: &longAgo, FillEmpty: true}},
+	}
+	svc.OnBookUpdate(tradeUpdate(15000, 1, longAgo))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GetCandles(tt.req)
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("expected ValidationError, got %v", err)
+			}
+		})
+	}
+
+	if _, err := svc.GetCandles(CandleRequest{Symbol: "MSFT", Interval: CandleInterval1m}); !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+}
+
+func TestCandles_FillEndsAtClock(t *testing.T) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	clk := clock.NewVirtual(candleBase.Add(3*time.Minute + 30*time.Second))
+	svc := NewCandleService(store.NewTradeStore(), sr, clk)
+	svc.OnBookUpdate(tradeUpdate(15000, 10, candleBase.Add(5*time.Second)))
+
+	for _, want := range []int{4, 6} {
+		resp, err := svc.GetCandles(CandleRequest{Symbol: "AAPL", Interval: CandleInterval1m, FillEmpty: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resp.Candles) != want {
+			t.Errorf("got %d candles, want %d", len(resp.Candles), want)
+		}
+		clk.Advance(2 * time.Minute)
+	}
+}
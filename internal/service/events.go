@@ -0,0 +1,197 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// eventSubscriberBuffer is the number of undelivered events a stream
+// subscriber may hold before it is dropped as a slow consumer. Dropped
+// clients resume from the retained buffer with Last-Event-ID.
+const eventSubscriberBuffer = 256
+
+// BrokerEvent is a single event in a broker's stream. IDs are assigned per
+// broker starting at 1 and increase by exactly one per event, so a client
+// can detect events that fell out of the retained buffer.
+type BrokerEvent struct {
+	ID        uint64
+	Event     string
+	Data      []byte // JSON payload, identical to the webhook body
+	CreatedAt time.Time
+}
+
+// EventSubscription delivers live events for one broker.
+type EventSubscription struct {
+	c    chan BrokerEvent
+	done chan struct{}
+	once sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+// Events returns the channel on which live events are delivered.
+func (s *EventSubscription) Events() <-chan BrokerEvent {
+	return s.c
+}
+
+// Done is closed when the subscription has been dropped or closed.
+func (s *EventSubscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns domain.ErrSlowConsumer if the subscription was dropped for
+// falling behind, or nil otherwise.
+func (s *EventSubscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *EventSubscription) send(ev BrokerEvent) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.c <- ev:
+		return true
+	default:
+		s.close(domain.ErrSlowConsumer)
+		return false
+	}
+}
+
+func (s *EventSubscription) close(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// brokerStream holds the retained events and live subscribers of one broker.
+type brokerStream struct {
+	lastID uint64
+	events []BrokerEvent // oldest first, at most bufferSize entries
+	subs   map[*EventSubscription]bool
+}
+
+// EventStreamService keeps a bounded, per-broker history of the events that
+// are also delivered through webhooks and fans them out to live streams.
+type EventStreamService struct {
+	brokerStore *store.BrokerStore
+	bufferSize  int
+	clock       clock.Clock
+
+	mu      sync.Mutex
+	streams map[string]*brokerStream
+}
+
+// NewEventStreamService creates an EventStreamService that retains up to
+// bufferSize events per broker for Last-Event-ID resumption. Events are
+// stamped with clk's time; a nil clk is the wall clock.
+func NewEventStreamService(brokerStore *store.BrokerStore, bufferSize int, clk clock.Clock) *EventStreamService {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &EventStreamService{
+		brokerStore: brokerStore,
+		bufferSize:  bufferSize,
+		clock:       clk,
+		streams:     make(map[string]*brokerStream),
+	}
+}
+
+// Publish appends an event to the broker's stream and delivers it to live
+// subscribers. It never blocks on a subscriber.
+func (s *EventStreamService) Publish(brokerID, event string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bs := s.stream(brokerID)
+	bs.lastID++
+	ev := BrokerEvent{
+		ID:        bs.lastID,
+		Event:     event,
+		Data:      data,
+		CreatedAt: s.clock.Now(),
+	}
+
+	bs.events = append(bs.events, ev)
+	if len(bs.events) > s.bufferSize {
+		// Copy rather than reslice so dropped events can be collected.
+		bs.events = append([]BrokerEvent(nil), bs.events[len(bs.events)-s.bufferSize:]...)
+	}
+
+	for sub := range bs.subs {
+		if !sub.send(ev) {
+			delete(bs.subs, sub)
+		}
+	}
+}
+
+// Subscribe opens a live subscription for the broker. If lastEventID is
+// non-nil, the retained events after it are returned as a backlog to send
+// before any live event; live events never duplicate the backlog. Events
+// already evicted from the buffer are not replayed, which the client sees
+// as a gap in IDs.
+func (s *EventStreamService) Subscribe(brokerID string, lastEventID *uint64) (*EventSubscription, []BrokerEvent, error) {
+	if !s.brokerStore.Exists(brokerID) {
+		return nil, nil, domain.ErrBrokerNotFound
+	}
+
+	sub := &EventSubscription{
+		c:    make(chan BrokerEvent, eventSubscriberBuffer),
+		done: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bs := s.stream(brokerID)
+	backlog := make([]BrokerEvent, 0)
+	if lastEventID != nil {
+		for _, ev := range bs.events {
+			if ev.ID > *lastEventID {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	bs.subs[sub] = true
+
+	return sub, backlog, nil
+}
+
+// Unsubscribe removes and closes a subscription.
+func (s *EventStreamService) Unsubscribe(brokerID string, sub *EventSubscription) {
+	s.mu.Lock()
+	if bs, ok := s.streams[brokerID]; ok {
+		delete(bs.subs, sub)
+	}
+	s.mu.Unlock()
+	sub.close(nil)
+}
+
+// stream returns the broker's stream, creating it if necessary. The caller
+// must hold s.mu.
+func (s *EventStreamService) stream(brokerID string) *brokerStream {
+	bs, ok := s.streams[brokerID]
+	if !ok {
+		bs = &brokerStream{subs: make(map[*EventSubscription]bool)}
+		s.streams[brokerID] = bs
+	}
+	return bs
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+func newTestEventStreamService(bufferSize int) *EventStreamService {
+	bs := store.NewBrokerStore()
+	_ = bs.Create(&domain.Broker{BrokerID: "b1", Holdings: map[string]*domain.Holding{}})
+	return NewEventStreamService(bs, bufferSize, nil)
+}
+
+func nextEvent(t *testing.T, sub *EventSubscription) BrokerEvent {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		return ev
+	default:
+		t.Fatal("expected a queued event")
+		return BrokerEvent{}
+	}
+}
+
+func eventIDs(events []BrokerEvent) []uint64 {
+	ids := make([]uint64, len(events))
+	for i, ev := range events {
+		ids[i] = ev.ID
+	}
+	return ids
+}
+
+func TestEventStream_LiveEventsCarryPayload(t *testing.T) {
+	svc := newTestEventStreamService(10)
+	sub, backlog, err := svc.Subscribe("b1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backlog) != 0 {
+		t.Fatalf("expected no backlog without Last-Event-ID, got %v", eventIDs(backlog))
+	}
+
+	svc.Publish("b1", "order.cancelled", map[string]string{"event": "order.cancelled"})
+	svc.Publish("other", "order.cancelled", map[string]string{"event": "order.cancelled"})
+
+	ev := nextEvent(t, sub)
+	if ev.ID != 1 || ev.Event != "order.cancelled" {
+		t.Fatalf("got id=%d event=%s, want 1/order.cancelled", ev.ID, ev.Event)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(ev.Data, &payload); err != nil || payload["event"] != "order.cancelled" {
+		t.Fatalf("unexpected payload %s (err %v)", ev.Data, err)
+	}
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("received another broker's event %+v", ev)
+	default:
+	}
+}
+
+func TestEventStream_ResumeReplaysAfterLastEventID(t *testing.T) {
+	svc := newTestEventStreamService(10)
+	for i := 0; i < 5; i++ {
+		svc.Publish("b1", "trade.executed", struct{}{})
+	}
+
+	last := uint64(3)
+	_, backlog, err := svc.Subscribe("b1", &last)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := eventIDs(backlog); len(ids) != 2 || ids[0] != 4 || ids[1] != 5 {
+		t.Fatalf("got backlog %v, want [4 5]", ids)
+	}
+}
+
+func TestEventStream_BufferIsBounded(t *testing.T) {
+	svc := newTestEventStreamService(3)
+	for i := 0; i < 10; i++ {
+		svc.Publish("b1", "trade.executed", struct{}{})
+	}
+
+	last := uint64(0)
+	_, backlog, err := svc.Subscribe("b1", &last)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Evicted events leave a gap the client can see: IDs start at 8, not 1.
+	if ids := eventIDs(backlog); len(ids) != 3 || ids[0] != 8 || ids[2] != 10 {
+		t.Fatalf("got backlog %v, want [8 9 10]", ids)
+	}
+}
+
+func TestEventStream_SlowConsumerIsDropped(t *testing.T) {
+	svc := newTestEventStreamService(eventSubscriberBuffer * 2)
+	sub, _, err := svc.Subscribe("b1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < eventSubscriberBuffer+1; i++ {
+		svc.Publish("b1", "trade.executed", struct{}{})
+	}
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("expected slow subscriber to be dropped")
+	}
+	if !errors.Is(sub.Err(), domain.ErrSlowConsumer) {
+		t.Errorf("got err %v, want ErrSlowConsumer", sub.Err())
+	}
+	if n := len(svc.stream("b1").subs); n != 0 {
+		t.Errorf("expected dropped subscriber to be removed, got %d", n)
+	}
+}
+
+func TestEventStream_Unsubscribe(t *testing.T) {
+	svc := newTestEventStreamService(10)
+	sub, _, err := svc.Subscribe("b1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.Unsubscribe("b1", sub)
+
+	svc.Publish("b1", "trade.executed", struct{}{})
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("unexpected event after unsubscribe %+v", ev)
+	default:
+	}
+	if sub.Err() != nil {
+		t.Errorf("expected nil error after normal close, got %v", sub.Err())
+	}
+}
+
+func TestEventStream_UnknownBroker(t *testing.T) {
+	svc := newTestEventStreamService(10)
+	if _, _, err := svc.Subscribe("ghost", nil); !errors.Is(err, domain.ErrBrokerNotFound) {
+		t.Errorf("expected ErrBrokerNotFound, got %v", err)
+	}
+}
+
+func TestWebhookService_PublishesToEventStreamWithoutSubscription(t *testing.T) {
+	events := newTestEventStreamService(10)
+	svc := NewWebhookService(store.NewWebhookStore(), store.NewDeliveryStore(100, 100), events.brokerStore, 0, DeliveryPolicy{}, events, nil, nil)
+	defer svc.Close()
+	sub, _, err := events.Subscribe("b1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order := &domain.Order{OrderID: "o1", BrokerID: "b1", Symbol: "AAPL", Side: domain.OrderSideBid, Status: domain.OrderStatusPending}
+	svc.DispatchOrderAccepted(context.Background(), order)
+	svc.DispatchOrderCancelled(context.Background(), order)
+
+	for _, want := range []string{"order.accepted", "order.cancelled"} {
+		ev := nextEvent(t, sub)
+		if ev.Event != want {
+			t.Fatalf("got event %s, want %s", ev.Event, want)
+		}
+		var payload orderEventPayload
+		if err := json.Unmarshal(ev.Data, &payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		if payload.Event != want || payload.Data.OrderID != "o1" {
+			t.Errorf("unexpected payload %+v", payload)
+		}
+	}
+}
+
+func TestWebhookService_StampsEventsWithClock(t *testing.T) {
+	start := time.Date(2030, 1, 2, 9, 30, 0, 0, time.UTC)
+	clk := clock.NewVirtual(start)
+	bs := store.NewBrokerStore()
+	_ = bs.Create(&domain.Broker{BrokerID: "b1", Holdings: map[string]*domain.Holding{}})
+	events := NewEventStreamService(bs, 10, clk)
+	svc := NewWebhookService(store.NewWebhookStore(), store.NewDeliveryStore(100, 100), bs, 0, DeliveryPolicy{}, events, nil, clk)
+	defer svc.Close()
+	sub, _, err := events.Subscribe("b1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clk.Advance(time.Minute)
+	order := &domain.Order{OrderID: "o1", BrokerID: "b1", Symbol: "AAPL", Side: domain.OrderSideBid, Status: domain.OrderStatusPending}
+	svc.DispatchOrderAccepted(context.Background(), order)
+
+	want := start.Add(time.Minute)
+	ev := nextEvent(t, sub)
+	if !ev.CreatedAt.Equal(want) {
+		t.Errorf("got created_at %v, want %v", ev.CreatedAt, want)
+	}
+	var payload orderEventPayload
+	if err := json.Unmarshal(ev.Data, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Timestamp != want.Format(time.RFC3339) {
+		t.Errorf("got timestamp %s, want %s", payload.Timestamp, want.Format(time.RFC3339))
+	}
+}
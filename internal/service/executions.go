@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// seedExecutions calls fn for every execution already in tradeStore, oldest
+// first per symbol, in the form book updates carry them. The store keeps a
+// record for each side of an execution while a BookUpdate carries it once,
+// so only the aggressor's record is passed on. A service seeded this way and
+// then fed the Trades of every BookUpdate must be registered as a book
+// listener before orders are accepted, so no execution is missed or counted
+// twice.
+func seedExecutions(tradeStore *store.TradeStore, fn func(symbol string, t engine.TradeEvent)) {
+	for _, symbol := range tradeStore.Symbols() {
+		for _, t := range tradeStore.GetBySymbol(symbol) {
+			if !t.Aggressor {
+				continue
+			}
+			fn(symbol, engine.TradeEvent{
+				TradeID:       t.TradeID,
+				Price:         t.Price,
+				Quantity:      t.Quantity,
+				AggressorSide: t.Side,
+				ExecutedAt:    t.ExecutedAt,
+			})
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+// MarketDataChannel identifies a per-symbol market data stream.
+type MarketDataChannel string
+
+const (
+	ChannelTrades     MarketDataChannel = "trades"
+	ChannelTopOfBook  MarketDataChannel = "top_of_book"
+	ChannelDepth      MarketDataChannel = "depth"
+	recentTradesLimit                   = 50
+)
+
+// ValidMarketDataChannels lists all channels a client can subscribe to.
+var ValidMarketDataChannels = map[MarketDataChannel]bool{
+	ChannelTrades:    true,
+	ChannelTopOfBook: true,
+	ChannelDepth:     true,
+}
+
+// Market data message types.
+const (
+	MessageTypeSnapshot = "snapshot"
+	MessageTypeUpdate   = "update"
+)
+
+// MarketTrade is a single anonymised execution on the trades channel.
+type MarketTrade struct {
+	TradeID       string
+	Price         int64
+	Quantity      int64
+	AggressorSide domain.OrderSide
+	ExecutedAt    time.Time
+}
+
+// MarketDataMessage is a snapshot or incremental update for one
+// (symbol, channel) pair. Seq starts at the snapshot's value and increases
+// by exactly one per update, so a client that sees a jump has missed data
+// and should resubscribe to get a fresh snapshot.
+//
+// Which fields are populated depends on Channel:
+//   - trades: Trades (the most recent trades in a snapshot, new ones in an update)
+//   - top_of_book: BestBid and BestAsk (nil when that side is empty)
+//   - depth: Bids and Asks (every level in a snapshot, changed levels in an
+//     update, where TotalQuantity == 0 means the level was removed)
+type MarketDataMessage struct {
+	Type      string
+	Channel   MarketDataChannel
+	Symbol    string
+	Seq       uint64
+	Trades    []MarketTrade
+	BestBid   *BookPriceLevel
+	BestAsk   *BookPriceLevel
+	Bids      []BookPriceLevel
+	Asks      []BookPriceLevel
+	Timestamp time.Time
+}
+
+// Subscriber is a single consumer of market data, typically one client
+// connection. Messages for all of its subscriptions are delivered through
+// one bounded channel; if the consumer falls behind and the channel fills
+// up, the subscriber is dropped with domain.ErrSlowConsumer rather than
+// blocking the matching engine.
+type Subscriber struct {
+	c    chan *MarketDataMessage
+	done chan struct{}
+	once sync.Once
+
+	mu   sync.Mutex
+	err  error
+	subs map[subscriptionKey]bool
+}
+
+type subscriptionKey struct {
+	symbol  string
+	channel MarketDataChannel
+}
+
+// Messages returns the channel on which snapshots and updates are delivered.
+func (s *Subscriber) Messages() <-chan *MarketDataMessage {
+	return s.c
+}
+
+// Done is closed when the subscriber has been dropped or closed.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the subscriber was dropped, or nil if it is still
+// active or was closed normally.
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// send delivers msg without blocking. It returns false if the subscriber is
+// closed or was dropped because its buffer is full.
+func (s *Subscriber) send(msg *MarketDataMessage) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.c <- msg:
+		return true
+	default:
+		s.close(domain.ErrSlowConsumer)
+		return false
+	}
+}
+
+func (s *Subscriber) close(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// symbolFeed mirrors the published state of one symbol's book and tracks
+// the subscribers of each of its channels. The mirror is built purely from
+// engine.BookUpdate events, so snapshots taken from it are always
+// consistent with the sequence numbers of the updates that follow.
+type symbolFeed struct {
+	mu           sync.Mutex
+	bids         map[int64]BookPriceLevel
+	asks         map[int64]BookPriceLevel
+	bestBid      *BookPriceLevel
+	bestAsk      *BookPriceLevel
+	recentTrades []MarketTrade
+	seq          map[MarketDataChannel]uint64
+	subs         map[MarketDataChannel]map[*Subscriber]bool
+}
+
+// MarketDataService fans out real-time trades, top-of-book, and L2 depth
+// updates to subscribers. It implements engine.BookListener.
+type MarketDataService struct {
+	symbols    *domain.SymbolRegistry
+	bufferSize int
+
+	mu    sync.Mutex
+	feeds map[string]*symbolFeed
+}
+
+// NewMarketDataService creates a MarketDataService whose subscribers buffer
+// at most bufferSize undelivered messages.
+func NewMarketDataService(symbols *domain.SymbolRegistry, bufferSize int) *MarketDataService {
+	return &MarketDataService{
+		symbols:    symbols,
+		bufferSize: bufferSize,
+		feeds:      make(map[string]*symbolFeed),
+	}
+}
+
+// NewSubscriber creates a subscriber with no subscriptions.
+func (s *MarketDataService) NewSubscriber() *Subscriber {
+	return &Subscriber{
+		c:    make(chan *MarketDataMessage, s.bufferSize),
+		done: make(chan struct{}),
+		subs: make(map[subscriptionKey]bool),
+	}
+}
+
+// Subscribe registers sub for updates on the given symbol and channel and
+// enqueues a snapshot ahead of them. Subscribing again to the same pair
+// enqueues a fresh snapshot, which is how clients resync after a gap.
+func (s *MarketDataService) Subscribe(sub *Subscriber, symbol string, channel MarketDataChannel) error {
+	if !ValidMarketDataChannels[channel] {
+		return &domain.ValidationError{
+			Message: fmt.Sprintf("Unknown channel: %s. Must be one of: trades, top_of_book, depth", channel),
+		}
+	}
+	if !s.symbols.Exists(symbol) {
+		return domain.ErrSymbolNotFound
+	}
+
+	feed := s.feed(symbol)
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if !sub.send(feed.snapshot(symbol, channel)) {
+		return sub.Err()
+	}
+	feed.subs[channel][sub] = true
+
+	sub.mu.Lock()
+	sub.subs[subscriptionKey{symbol: symbol, channel: channel}] = true
+	sub.mu.Unlock()
+	return nil
+}
+
+// Unsubscribe stops updates for the given symbol and channel. It is a
+// no-op if sub is not subscribed.
+func (s *MarketDataService) Unsubscribe(sub *Subscriber, symbol string, channel MarketDataChannel) {
+	s.mu.Lock()
+	feed, ok := s.feeds[symbol]
+	s.mu.Unlock()
+	if ok && feed.subs[channel] != nil {
+		feed.mu.Lock()
+		delete(feed.subs[channel], sub)
+		feed.mu.Unlock()
+	}
+
+	sub.mu.Lock()
+	delete(sub.subs, subscriptionKey{symbol: symbol, channel: channel})
+	sub.mu.Unlock()
+}
+
+// Close removes all of sub's subscriptions and closes it.
+func (s *MarketDataService) Close(sub *Subscriber) {
+	sub.mu.Lock()
+	keys := make([]subscriptionKey, 0, len(sub.subs))
+	for k := range sub.subs {
+		keys = append(keys, k)
+	}
+	sub.mu.Unlock()
+
+	for _, k := range keys {
+		s.Unsubscribe(sub, k.symbol, k.channel)
+	}
+	sub.close(nil)
+}
+
+// OnBookUpdate applies an engine update to the symbol's mirror and sends
+// the resulting messages to subscribers. It never blocks: subscribers whose
+// buffers are full are dropped.
+func (s *MarketDataService) OnBookUpdate(u *engine.BookUpdate) {
+	feed := s.feed(u.Symbol)
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if len(u.Trades) > 0 {
+		trades := make([]MarketTrade, len(u.Trades))
+		for i, t := range u.Trades {
+			trades[i] = MarketTrade{
+				TradeID:       t.TradeID,
+				Price:         t.Price,
+				Quantity:      t.Quantity,
+				AggressorSide: t.AggressorSide,
+				ExecutedAt:    t.ExecutedAt,
+			}
+		}
+		feed.recentTrades = append(feed.recentTrades, trades...)
+		if n := len(feed.recentTrades); n > recentTradesLimit {
+			feed.recentTrades = append([]MarketTrade(nil), feed.recentTrades[n-recentTradesLimit:]...)
+		}
+		feed.publish(&MarketDataMessage{
+			Type:      MessageTypeUpdate,
+			Channel:   ChannelTrades,
+			Symbol:    u.Symbol,
+			Trades:    trades,
+			Timestamp: u.Timestamp,
+		})
+	}
+
+	if len(u.Levels) > 0 {
+		bids := make([]BookPriceLevel, 0)
+		asks := make([]BookPriceLevel, 0)
+		for _, lu := range u.Levels {
+			side := feed.asks
+			if lu.Side == domain.OrderSideBid {
+				side = feed.bids
+			}
+			level := BookPriceLevel{
+				Price:         lu.Price,
+				TotalQuantity: lu.TotalQuantity,
+				OrderCount:    lu.OrderCount,
+			}
+			if lu.TotalQuantity == 0 {
+				delete(side, lu.Price)
+			} else {
+				side[lu.Price] = level
+			}
+			if lu.Side == domain.OrderSideBid {
+				bids = append(bids, level)
+			} else {
+				asks = append(asks, level)
+			}
+		}
+		feed.publish(&MarketDataMessage{
+			Type:      MessageTypeUpdate,
+			Channel:   ChannelDepth,
+			Symbol:    u.Symbol,
+			Bids:      bids,
+			Asks:      asks,
+			Timestamp: u.Timestamp,
+		})
+	}
+
+	bestBid := toBookPriceLevel(u.BestBid)
+	bestAsk := toBookPriceLevel(u.BestAsk)
+	if !samePriceLevel(bestBid, feed.bestBid) || !samePriceLevel(bestAsk, feed.bestAsk) {
+		feed.bestBid = bestBid
+		feed.bestAsk = bestAsk
+		feed.publish(&MarketDataMessage{
+			Type:      MessageTypeUpdate,
+			Channel:   ChannelTopOfBook,
+			Symbol:    u.Symbol,
+			BestBid:   bestBid,
+			BestAsk:   bestAsk,
+			Timestamp: u.Timestamp,
+		})
+	}
+}
+
+// feed returns the symbolFeed for symbol, creating it if necessary.
+func (s *MarketDataService) feed(symbol string) *symbolFeed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feed, ok := s.feeds[symbol]
+	if !ok {
+		feed = &symbolFeed{
+			bids: make(map[int64]BookPriceLevel),
+			asks: make(map[int64]BookPriceLevel),
+			seq:  make(map[MarketDataChannel]uint64),
+			subs: make(map[MarketDataChannel]map[*Subscriber]bool),
+		}
+		for ch := range ValidMarketDataChannels {
+			feed.subs[ch] = make(map[*Subscriber]bool)
+		}
+		s.feeds[symbol] = feed
+	}
+	return feed
+}
+
+// publish assigns the next sequence number for msg's channel and delivers
+// it to every subscriber of that channel. The caller must hold f.mu.
+func (f *symbolFeed) publish(msg *MarketDataMessage) {
+	f.seq[msg.Channel]++
+	msg.Seq = f.seq[msg.Channel]
+	for sub := range f.subs[msg.Channel] {
+		if !sub.send(msg) {
+			delete(f.subs[msg.Channel], sub)
+		}
+	}
+}
+
+// snapshot builds the current state of a channel. The caller must hold f.mu.
+func (f *symbolFeed) snapshot(symbol string, channel MarketDataChannel) *MarketDataMessage {
+	msg := &MarketDataMessage{
+		Type:      MessageTypeSnapshot,
+		Channel:   channel,
+		Symbol:    symbol,
+		Seq:       f.seq[channel],
+		Timestamp: time.Now(),
+	}
+
+	switch channel {
+	case ChannelTrades:
+		msg.Trades = append([]MarketTrade{}, f.recentTrades...)
+	case ChannelTopOfBook:
+		msg.BestBid = f.bestBid
+		msg.BestAsk = f.bestAsk
+	case ChannelDepth:
+		msg.Bids = sortedLevels(f.bids, true)
+		msg.Asks = sortedLevels(f.asks, false)
+	}
+	return msg
+}
+
+// sortedLevels returns the levels ordered best first: price descending for
+// bids, ascending for asks.
+func sortedLevels(levels map[int64]BookPriceLevel, descending bool) []BookPriceLevel {
+	result := make([]BookPriceLevel, 0, len(levels))
+	for _, l := range levels {
+		result = append(result, l)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if descending {
+			return result[i].Price > result[j].Price
+		}
+		return result[i].Price < result[j].Price
+	})
+	return result
+}
+
+func toBookPriceLevel(pl *engine.PriceLevel) *BookPriceLevel {
+	if pl == nil {
+		return nil
+	}
+	return &BookPriceLevel{
+		Price:         pl.Price,
+		TotalQuantity: pl.TotalQuantity,
+		OrderCount:    pl.OrderCount,
+	}
+}
+
+func samePriceLevel(a, b *BookPriceLevel) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
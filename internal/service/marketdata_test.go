@@ -0,0 +1,250 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+func newTestMarketDataService(bufferSize int) (*MarketDataService, *domain.SymbolRegistry) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	return NewMarketDataService(sr, bufferSize), sr
+}
+
+// nextMessage reads one message or fails if none is queued.
+func nextMessage(t *testing.T, sub *Subscriber) *MarketDataMessage {
+	t.Helper()
+	select {
+	case msg := <-sub.Messages():
+		return msg
+	default:
+		t.Fatal("expected a queued message")
+		return nil
+	}
+}
+
+func expectNoMessage(t *testing.T, sub *Subscriber) {
+	t.Helper()
+	select {
+	case msg := <-sub.Messages():
+		t.Fatalf("unexpected message %+v", msg)
+	default:
+	}
+}
+
+func bidLevelUpdate(seq uint64, price, qty int64, count int) *engine.BookUpdate {
+	u := &engine.BookUpdate{
+		Symbol:    "AAPL",
+		Seq:       seq,
+		Levels:    []engine.LevelUpdate{{Side: domain.OrderSideBid, Price: price, TotalQuantity: qty, OrderCount: count}},
+		Timestamp: time.Now(),
+	}
+	if qty > 0 {
+		u.BestBid = &engine.PriceLevel{Price: price, TotalQuantity: qty, OrderCount: count}
+	}
+	return u
+}
+
+func TestMarketData_Subscribe_SnapshotThenUpdates(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	svc.OnBookUpdate(bidLevelUpdate(1, 15000, 10, 1))
+
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snap := nextMessage(t, sub)
+	if snap.Type != MessageTypeSnapshot || snap.Seq != 1 {
+		t.Fatalf("got type=%s seq=%d, want snapshot/1", snap.Type, snap.Seq)
+	}
+	if len(snap.Bids) != 1 || snap.Bids[0].TotalQuantity != 10 {
+		t.Fatalf("unexpected snapshot bids %+v", snap.Bids)
+	}
+	if len(snap.Asks) != 0 {
+		t.Fatalf("expected no asks, got %+v", snap.Asks)
+	}
+
+	svc.OnBookUpdate(bidLevelUpdate(2, 15000, 25, 2))
+	upd := nextMessage(t, sub)
+	if upd.Type != MessageTypeUpdate || upd.Seq != 2 {
+		t.Fatalf("got type=%s seq=%d, want update/2", upd.Type, upd.Seq)
+	}
+	if len(upd.Bids) != 1 || upd.Bids[0].TotalQuantity != 25 || upd.Bids[0].OrderCount != 2 {
+		t.Fatalf("unexpected update bids %+v", upd.Bids)
+	}
+}
+
+func TestMarketData_DepthSnapshot_SortedAndRemovesEmptyLevels(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	svc.OnBookUpdate(bidLevelUpdate(1, 14900, 5, 1))
+	svc.OnBookUpdate(bidLevelUpdate(2, 15000, 5, 1))
+	svc.OnBookUpdate(bidLevelUpdate(3, 15100, 5, 1))
+	svc.OnBookUpdate(bidLevelUpdate(4, 15000, 0, 0))
+
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := nextMessage(t, sub)
+	if len(snap.Bids) != 2 || snap.Bids[0].Price != 15100 || snap.Bids[1].Price != 14900 {
+		t.Fatalf("unexpected snapshot bids %+v", snap.Bids)
+	}
+}
+
+func TestMarketData_TopOfBook_OnlyPublishesOnChange(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelTopOfBook); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := nextMessage(t, sub)
+	if snap.BestBid != nil || snap.BestAsk != nil || snap.Seq != 0 {
+		t.Fatalf("expected empty snapshot at seq 0, got %+v", snap)
+	}
+
+	svc.OnBookUpdate(bidLevelUpdate(1, 15000, 10, 1))
+	upd := nextMessage(t, sub)
+	if upd.Seq != 1 || upd.BestBid == nil || upd.BestBid.Price != 15000 {
+		t.Fatalf("unexpected top of book update %+v", upd)
+	}
+
+	// A deeper level changes but the best bid does not.
+	svc.OnBookUpdate(&engine.BookUpdate{
+		Symbol:  "AAPL",
+		Seq:     2,
+		Levels:  []engine.LevelUpdate{{Side: domain.OrderSideBid, Price: 14000, TotalQuantity: 1, OrderCount: 1}},
+		BestBid: &engine.PriceLevel{Price: 15000, TotalQuantity: 10, OrderCount: 1},
+	})
+	expectNoMessage(t, sub)
+}
+
+func TestMarketData_Trades_SnapshotHasRecentTrades(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	for i := 0; i < recentTradesLimit+5; i++ {
+		svc.OnBookUpdate(&engine.BookUpdate{
+			Symbol: "AAPL",
+			Seq:    uint64(i + 1),
+			Trades: []engine.TradeEvent{{TradeID: "t", Price: 15000, Quantity: int64(i + 1), AggressorSide: domain.OrderSideBid}},
+		})
+	}
+
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelTrades); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := nextMessage(t, sub)
+	if snap.Seq != uint64(recentTradesLimit+5) {
+		t.Errorf("got seq %d, want %d", snap.Seq, recentTradesLimit+5)
+	}
+	if len(snap.Trades) != recentTradesLimit {
+		t.Fatalf("got %d trades, want %d", len(snap.Trades), recentTradesLimit)
+	}
+	if snap.Trades[len(snap.Trades)-1].Quantity != int64(recentTradesLimit+5) {
+		t.Errorf("expected the most recent trade last, got %+v", snap.Trades[len(snap.Trades)-1])
+	}
+}
+
+func TestMarketData_Resubscribe_SendsFreshSnapshot(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nextMessage(t, sub)
+	svc.OnBookUpdate(bidLevelUpdate(1, 15000, 10, 1))
+	nextMessage(t, sub)
+
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := nextMessage(t, sub)
+	if snap.Type != MessageTypeSnapshot || snap.Seq != 1 {
+		t.Fatalf("got type=%s seq=%d, want snapshot/1", snap.Type, snap.Seq)
+	}
+
+	// Still a single registration: one update yields one message.
+	svc.OnBookUpdate(bidLevelUpdate(2, 15000, 20, 2))
+	nextMessage(t, sub)
+	expectNoMessage(t, sub)
+}
+
+func TestMarketData_Unsubscribe_StopsUpdates(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nextMessage(t, sub)
+
+	svc.Unsubscribe(sub, "AAPL", ChannelDepth)
+	svc.OnBookUpdate(bidLevelUpdate(1, 15000, 10, 1))
+	expectNoMessage(t, sub)
+}
+
+func TestMarketData_SlowConsumerIsDropped(t *testing.T) {
+	svc, _ := newTestMarketDataService(2)
+	slow := svc.NewSubscriber()
+	fast := svc.NewSubscriber()
+	for _, sub := range []*Subscriber{slow, fast} {
+		if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	nextMessage(t, fast)
+
+	// slow never reads: snapshot + 1 update fill its buffer, the next one drops it.
+	for i := 1; i <= 3; i++ {
+		svc.OnBookUpdate(bidLevelUpdate(uint64(i), 15000, int64(i), 1))
+		nextMessage(t, fast)
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("expected slow subscriber to be dropped")
+	}
+	if !errors.Is(slow.Err(), domain.ErrSlowConsumer) {
+		t.Errorf("got err %v, want ErrSlowConsumer", slow.Err())
+	}
+	if fast.Err() != nil {
+		t.Errorf("fast subscriber should still be active, got %v", fast.Err())
+	}
+}
+
+func TestMarketData_Subscribe_ValidationErrors(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	sub := svc.NewSubscriber()
+
+	err := svc.Subscribe(sub, "AAPL", "candles")
+	var validationErr *domain.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError for unknown channel, got %v", err)
+	}
+
+	err = svc.Subscribe(sub, "MSFT", ChannelTrades)
+	if !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+	expectNoMessage(t, sub)
+}
+
+func TestMarketData_Close_RemovesSubscriptions(t *testing.T) {
+	svc, _ := newTestMarketDataService(16)
+	sub := svc.NewSubscriber()
+	if err := svc.Subscribe(sub, "AAPL", ChannelDepth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.Close(sub)
+
+	if sub.Err() != nil {
+		t.Errorf("expected nil error after normal close, got %v", sub.Err())
+	}
+	if n := len(svc.feed("AAPL").subs[ChannelDepth]); n != 0 {
+		t.Errorf("expected no depth subscribers after close, got %d", n)
+	}
+}
@@ -1,12 +1,20 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/metrics"
 	"github.com/efreitasn/miniexchange/internal/store"
 )
 
@@ -36,6 +44,15 @@ type SubmitOrderRequest struct {
 	ExpiresAt      *time.Time // required for limit, must be nil for market
 }
 
+// ReplaceOrderRequest represents the new terms for a cancel/replace of a
+// resting limit order.
+type ReplaceOrderRequest struct {
+	OrderID   string
+	Price     float64
+	Quantity  int64      // new total quantity, including what has already filled
+	ExpiresAt *time.Time // nil keeps the original expiry
+}
+
 // OrderService handles order submission, retrieval, cancellation, and listing.
 type OrderService struct {
 	matcher     *engine.Matcher
@@ -45,9 +62,16 @@ type OrderService struct {
 	tradeStore  *store.TradeStore
 	webhookSvc  *WebhookService
 	symbols     *domain.SymbolRegistry
+	metrics     *metrics.Metrics
+	audit       *AuditService
+	clock       clock.Clock
+	ids         clock.IDGenerator
 }
 
 // NewOrderService creates a new OrderService with the given dependencies.
+// metrics and audit may be nil. Expiry times are checked against clk, and
+// order IDs come from ids; nil means the wall clock and random UUIDs, and
+// they should be the matcher's.
 func NewOrderService(
 	matcher *engine.Matcher,
 	expiry *engine.ExpiryManager,
@@ -56,7 +80,17 @@ func NewOrderService(
 	tradeStore *store.TradeStore,
 	webhookSvc *WebhookService,
 	symbols *domain.SymbolRegistry,
+	metrics *metrics.Metrics,
+	audit *AuditService,
+	clk clock.Clock,
+	ids clock.IDGenerator,
 ) *OrderService {
+	if clk == nil {
+		clk = clock.System
+	}
+	if ids == nil {
+		ids = clock.UUIDs
+	}
 	return &OrderService{
 		matcher:     matcher,
 		expiry:      expiry,
@@ -65,12 +99,62 @@ func NewOrderService(
 		tradeStore:  tradeStore,
 		webhookSvc:  webhookSvc,
 		symbols:     symbols,
+		metrics:     metrics,
+		audit:       audit,
+		clock:       clk,
+		ids:         ids,
+	}
+}
+
+// SubmitOrder validates the request, creates the order and runs the
+// matching engine, which dispatches webhooks for the order and any trades
+// executed. A rejected submission from a known broker dispatches
+// order.rejected. The
+// submission is traced under ctx, and the webhooks it dispatches carry the
+// trace. The order is given its ID on receipt, so every audit record of the
+// submission, accepted or not, carries it.
+func (s *OrderService) SubmitOrder(ctx context.Context, req SubmitOrderRequest) (*domain.Order, error) {
+	ctx, span := tracer().Start(ctx, "service.submit_order", trace.WithAttributes(
+		attribute.String("order.broker_id", req.BrokerID),
+		attribute.String("order.symbol", req.Symbol),
+		attribute.String("order.side", string(req.Side)),
+		attribute.String("order.type", string(req.Type)),
+	))
+	defer span.End()
+
+	orderID := s.ids.NewID()
+	s.audit.RecordOrderReceived(orderID, req)
+
+	order, err := s.submitOrder(ctx, orderID, req)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("order.reject_reason", RejectReason(err)))
+		s.metrics.OrderRejected(RejectReason(err))
+		s.audit.RecordOrderRejected(orderID, req, err)
+		if s.webhookSvc != nil && s.brokerStore.Exists(req.BrokerID) {
+			s.webhookSvc.DispatchOrderRejected(ctx, req, err)
+		}
+		return order, err
+	}
+	span.SetAttributes(
+		attribute.String("order.id", order.OrderID),
+		attribute.String("order.status", string(order.Status)),
+	)
+	s.metrics.OrderAccepted(order.Type)
+	return order, nil
+}
+
+// RejectReason returns the reason an order submission was rejected: the
+// error's code, or validation_error for a validation failure.
+func RejectReason(err error) string {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return "validation_error"
 	}
+	return err.Error()
 }
 
-// SubmitOrder validates the request, creates the order, runs the matching
-// engine, and dispatches webhooks for any trades executed.
-func (s *OrderService) SubmitOrder(req SubmitOrderRequest) (*domain.Order, error) {
+func (s *OrderService) submitOrder(ctx context.Context, orderID string, req SubmitOrderRequest) (*domain.Order, error) {
 	// Validate order type.
 	if req.Type != domain.OrderTypeLimit && req.Type != domain.OrderTypeMarket {
 		return nil, &domain.ValidationError{
@@ -107,12 +191,12 @@ func (s *OrderService) SubmitOrder(req SubmitOrderRequest) (*domain.Order, error
 
 	// Type-specific validation.
 	if req.Type == domain.OrderTypeLimit {
-		return s.submitLimitOrder(req)
+		return s.submitLimitOrder(ctx, orderID, req)
 	}
-	return s.submitMarketOrder(req)
+	return s.submitMarketOrder(ctx, orderID, req)
 }
 
-func (s *OrderService) submitLimitOrder(req SubmitOrderRequest) (*domain.Order, error) {
+func (s *OrderService) submitLimitOrder(ctx context.Context, orderID string, req SubmitOrderRequest) (*domain.Order, error) {
 	// Validate price.
 	if req.Price == nil {
 		return nil, &domain.ValidationError{
@@ -137,7 +221,7 @@ func (s *OrderService) submitLimitOrder(req SubmitOrderRequest) (*domain.Order,
 			Message: "expires_at is required for limit orders",
 		}
 	}
-	if !req.ExpiresAt.After(time.Now()) {
+	if !req.ExpiresAt.After(s.clock.Now()) {
 		return nil, &domain.ValidationError{
 			Message: "expires_at must be a future timestamp",
 		}
@@ -149,6 +233,7 @@ func (s *OrderService) submitLimitOrder(req SubmitOrderRequest) (*domain.Order,
 	}
 
 	order := &domain.Order{
+		OrderID:        orderID,
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       req.BrokerID,
 		DocumentNumber: req.DocumentNumber,
@@ -159,7 +244,9 @@ func (s *OrderService) submitLimitOrder(req SubmitOrderRequest) (*domain.Order,
 		ExpiresAt:      req.ExpiresAt,
 	}
 
-	trades, err := s.matcher.MatchLimitOrder(order)
+	start := time.Now()
+	_, err = s.matcher.MatchLimitOrder(ctx, order)
+	s.metrics.ObserveMatch(order.Symbol, order.Type, time.Since(start))
 	if err != nil {
 		return nil, err
 	}
@@ -169,13 +256,10 @@ func (s *OrderService) submitLimitOrder(req SubmitOrderRequest) (*domain.Order,
 		s.expiry.Add(order)
 	}
 
-	// Dispatch webhooks for trades (outside the lock, fire-and-forget).
-	s.dispatchTradeWebhooks(trades, order)
-
 	return order, nil
 }
 
-func (s *OrderService) submitMarketOrder(req SubmitOrderRequest) (*domain.Order, error) {
+func (s *OrderService) submitMarketOrder(ctx context.Context, orderID string, req SubmitOrderRequest) (*domain.Order, error) {
 	// Market orders must NOT include price or expires_at.
 	if req.Price != nil {
 		return nil, &domain.ValidationError{
@@ -194,6 +278,7 @@ func (s *OrderService) submitMarketOrder(req SubmitOrderRequest) (*domain.Order,
 	}
 
 	order := &domain.Order{
+		OrderID:        orderID,
 		Type:           domain.OrderTypeMarket,
 		BrokerID:       req.BrokerID,
 		DocumentNumber: req.DocumentNumber,
@@ -202,76 +287,193 @@ func (s *OrderService) submitMarketOrder(req SubmitOrderRequest) (*domain.Order,
 		Quantity:       req.Quantity,
 	}
 
-	trades, err := s.matcher.MatchMarketOrder(order)
+	start := time.Now()
+	_, err := s.matcher.MatchMarketOrder(ctx, order)
+	s.metrics.ObserveMatch(order.Symbol, order.Type, time.Since(start))
 	if err != nil {
 		return nil, err
 	}
 
-	// Dispatch webhooks for trades.
-	s.dispatchTradeWebhooks(trades, order)
-
 	return order, nil
 }
 
-// dispatchTradeWebhooks dispatches trade.executed webhooks for each trade
-// to both the buyer and seller brokers. Skips dispatch if webhookSvc is nil.
+// GetOrder retrieves an order by ID with all its trades.
+func (s *OrderService) GetOrder(orderID string) (*domain.Order, error) {
+	return s.orderStore.Get(orderID)
+}
+
+// ReplaceOrder cancels a resting limit order and submits a replacement with
+// the same broker, document number, side, and symbol. The replacement's
+// quantity is req.Quantity less whatever the original had filled by the time
+// it was cancelled, and it takes a new place in the queue.
 //
-// The trades slice contains the incoming order's trade records. For each
-// trade, we also need to notify the resting order's broker. We find the
-// resting order by looking up the counterpart trade (same TradeID, different
-// OrderID) in the trade store.
-func (s *OrderService) dispatchTradeWebhooks(trades []*domain.Trade, incomingOrder *domain.Order) {
-	if s.webhookSvc == nil || len(trades) == 0 {
-		return
-	}
-
-	// Build a set of TradeIDs to find counterpart orders.
-	// The trade store has both sides appended per symbol.
-	allTrades := s.tradeStore.GetBySymbol(incomingOrder.Symbol)
-
-	// Index counterpart trades by TradeID for O(1) lookup.
-	counterparts := make(map[string]*domain.Trade, len(trades))
-	for _, t := range allTrades {
-		if t.OrderID != incomingOrder.OrderID {
-			counterparts[t.TradeID] = t
-		}
+// The replacement is not atomic: the original is cancelled first. If it
+// filled up to req.Quantity in the meantime, only the cancellation takes
+// effect and the replacement is nil. If the replacement is rejected, the
+// cancelled original is returned together with the error.
+func (s *OrderService) ReplaceOrder(ctx context.Context, req ReplaceOrderRequest) (*domain.Order, *domain.Order, error) {
+	ctx, span := tracer().Start(ctx, "service.replace_order", trace.WithAttributes(
+		attribute.String("order.id", req.OrderID),
+	))
+	defer span.End()
+
+	cancelled, replacement, err := s.replaceOrder(ctx, req)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 	}
+	return cancelled, replacement, err
+}
 
-	for _, trade := range trades {
-		// Dispatch to the incoming order's broker.
-		s.webhookSvc.DispatchTradeExecuted(incomingOrder.BrokerID, trade, incomingOrder)
-
-		// Dispatch to the resting order's broker.
-		if ct, ok := counterparts[trade.TradeID]; ok {
-			restingOrder, err := s.orderStore.Get(ct.OrderID)
-			if err == nil {
-				s.webhookSvc.DispatchTradeExecuted(restingOrder.BrokerID, ct, restingOrder)
+func (s *OrderService) replaceOrder(ctx context.Context, req ReplaceOrderRequest) (*domain.Order, *domain.Order, error) {
+	original, err := s.orderStore.Get(req.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if original.Type != domain.OrderTypeLimit {
+		return nil, nil, &domain.ValidationError{
+			Message: "only limit orders can be replaced",
+		}
+	}
+	if req.Quantity <= 0 {
+		return nil, nil, &domain.ValidationError{
+			Message: "quantity must be a positive integer",
+		}
+	}
+	if req.Price <= 0 {
+		return nil, nil, &domain.ValidationError{
+			Message: "price must be greater than 0",
+		}
+	}
+	if _, err := domain.DollarsToCents(req.Price); err != nil {
+		return nil, nil, &domain.ValidationError{
+			Message: "price must have at most 2 decimal places",
+		}
+	}
+	expiresAt := original.ExpiresAt
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(s.clock.Now()) {
+			return nil, nil, &domain.ValidationError{
+				Message: "expires_at must be a future timestamp",
 			}
 		}
+		expiresAt = req.ExpiresAt
+	}
+	// Refuse up front rather than cancel an order that cannot be replaced.
+	if s.matcher.Halted(original.Symbol) {
+		return nil, nil, domain.ErrSymbolHalted
+	}
+
+	cancelled, err := s.CancelOrder(WithCancelInitiator(ctx, CancelByReplace), req.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// FilledQuantity is final once the order is off the book.
+	quantity := req.Quantity - cancelled.FilledQuantity
+	if quantity <= 0 {
+		return cancelled, nil, nil
+	}
+
+	price := req.Price
+	replacement, err := s.SubmitOrder(ctx, SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       cancelled.BrokerID,
+		DocumentNumber: cancelled.DocumentNumber,
+		Side:           cancelled.Side,
+		Symbol:         cancelled.Symbol,
+		Price:          &price,
+		Quantity:       quantity,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return cancelled, nil, err
 	}
+	s.audit.RecordOrderAmended(cancelled, replacement)
+	cancelled.Record(domain.OrderEvent{
+		Type:               domain.OrderEventAmended,
+		Time:               replacement.CreatedAt,
+		Quantity:           replacement.Quantity,
+		Price:              replacement.Price,
+		ReplacementOrderID: replacement.OrderID,
+	})
+	if s.webhookSvc != nil {
+		s.webhookSvc.DispatchOrderAmended(ctx, cancelled, replacement)
+	}
+	return cancelled, replacement, nil
 }
 
-// GetOrder retrieves an order by ID with all its trades.
-func (s *OrderService) GetOrder(orderID string) (*domain.Order, error) {
-	return s.orderStore.Get(orderID)
+// QueuePosition describes the resting quantity ahead of an order at its
+// price level.
+type QueuePosition struct {
+	OrdersAhead   int
+	QuantityAhead int64
+}
+
+// GetQueuePosition returns the order's position in its price level's queue,
+// or nil if the order is not resting on the book.
+func (s *OrderService) GetQueuePosition(order *domain.Order) *QueuePosition {
+	pos, ok := s.matcher.QueuePosition(order.Symbol, order.OrderID)
+	if !ok {
+		return nil
+	}
+	return &QueuePosition{
+		OrdersAhead:   pos.OrdersAhead,
+		QuantityAhead: pos.QuantityAhead,
+	}
 }
 
-// CancelOrder cancels a pending or partially filled order.
-func (s *OrderService) CancelOrder(orderID string) (*domain.Order, error) {
-	order, err := s.matcher.CancelOrder(orderID)
+// CancelOrder cancels a pending or partially filled order. The
+// cancellation is traced under ctx, and audited with the initiator ctx
+// names (see WithCancelInitiator).
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	ctx, span := tracer().Start(ctx, "service.cancel_order", trace.WithAttributes(
+		attribute.String("order.id", orderID),
+	))
+	defer span.End()
+
+	order, err := s.matcher.CancelOrder(ctx, orderID)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 
 	// Remove from expiry manager.
 	s.expiry.Remove(orderID)
 
-	// Dispatch order.cancelled webhook.
-	if s.webhookSvc != nil {
-		s.webhookSvc.DispatchOrderCancelled(order)
+	return order, nil
+}
+
+// HaltSymbol halts trading in a symbol: new orders for it are rejected with
+// domain.ErrSymbolHalted until ResumeSymbol, while resting orders can still
+// be cancelled or expire. Halting a symbol that is already halted does
+// nothing; otherwise every broker is sent symbol.halted. It returns
+// domain.ErrSymbolNotFound for a symbol the exchange has never seen.
+func (s *OrderService) HaltSymbol(ctx context.Context, symbol, reason string) error {
+	if len(reason) > 256 {
+		return &domain.ValidationError{
+			Message: "reason must be at most 256 characters",
+		}
+	}
+	if !s.symbols.Exists(symbol) {
+		return domain.ErrSymbolNotFound
+	}
+	if s.matcher.SetHalted(symbol, true) && s.webhookSvc != nil {
+		s.webhookSvc.DispatchSymbolHalted(ctx, symbol, reason)
 	}
+	return nil
+}
 
-	return order, nil
+// ResumeSymbol resumes trading in a halted symbol, sending every broker
+// symbol.resumed. Resuming a symbol that is not halted does nothing. It
+// returns domain.ErrSymbolNotFound for a symbol the exchange has never seen.
+func (s *OrderService) ResumeSymbol(ctx context.Context, symbol string) error {
+	if !s.symbols.Exists(symbol) {
+		return domain.ErrSymbolNotFound
+	}
+	if s.matcher.SetHalted(symbol, false) && s.webhookSvc != nil {
+		s.webhookSvc.DispatchSymbolResumed(ctx, symbol)
+	}
+	return nil
 }
 
 // ListOrders returns a paginated list of orders for a broker with optional
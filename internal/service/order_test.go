@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -27,11 +29,11 @@ func newTestOrderEnv() *testOrderEnv {
 	os := store.NewOrderStore()
 	ts := store.NewTradeStore()
 	sr := domain.NewSymbolRegistry()
-	bm := engine.NewBookManager()
-	m := engine.NewMatcher(bm, bs, os, ts, sr)
-	e := engine.NewExpiryManager(time.Second, bm, os, bs, nil)
-	svc := NewOrderService(m, e, bs, os, ts, nil, sr)
-	bsvc := NewBrokerService(bs, sr)
+	bm := engine.NewBookManager(nil)
+	m := engine.NewMatcher(bm, bs, os, ts, sr, nil, nil, nil, nil)
+	e := engine.NewExpiryManager(time.Second, bm, os, bs, nil, nil, nil, nil)
+	svc := NewOrderService(m, e, bs, os, ts, nil, sr, nil, nil, nil, nil)
+	bsvc := NewBrokerService(bs, sr, nil)
 	return &testOrderEnv{
 		brokerStore: bs,
 		orderStore:  os,
@@ -73,7 +75,7 @@ func TestSubmitOrder_LimitBid_Pending(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
-	order, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	order, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "DOC001",
@@ -121,7 +123,7 @@ func TestSubmitOrder_LimitAsk_Pending(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 500}})
 
-	order, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	order, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "DOC002",
@@ -148,7 +150,7 @@ func TestSubmitOrder_LimitBid_FullyFilled(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place a resting ask.
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -163,7 +165,7 @@ func TestSubmitOrder_LimitBid_FullyFilled(t *testing.T) {
 	}
 
 	// Place a matching bid.
-	order, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	order, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID001",
@@ -208,7 +210,7 @@ func TestSubmitOrder_LimitBid_PartiallyFilled(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place a small resting ask.
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -223,7 +225,7 @@ func TestSubmitOrder_LimitBid_PartiallyFilled(t *testing.T) {
 	}
 
 	// Place a larger bid.
-	order, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	order, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID001",
@@ -262,7 +264,7 @@ func TestSubmitOrder_MarketBid_FullyFilled(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place a resting ask.
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -277,7 +279,7 @@ func TestSubmitOrder_MarketBid_FullyFilled(t *testing.T) {
 	}
 
 	// Place a market bid.
-	order, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	order, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeMarket,
 		BrokerID:       "buyer",
 		DocumentNumber: "MKT001",
@@ -303,7 +305,7 @@ func TestSubmitOrder_MarketBid_NoLiquidity(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeMarket,
 		BrokerID:       "buyer",
 		DocumentNumber: "MKT001",
@@ -320,7 +322,7 @@ func TestSubmitOrder_MarketOrder_WithPrice_Rejected(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeMarket,
 		BrokerID:       "buyer",
 		DocumentNumber: "MKT001",
@@ -341,7 +343,7 @@ func TestSubmitOrder_MarketOrder_WithExpiresAt_Rejected(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeMarket,
 		BrokerID:       "buyer",
 		DocumentNumber: "MKT001",
@@ -364,7 +366,7 @@ func TestSubmitOrder_InvalidOrderType(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           "stop_loss",
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -387,7 +389,7 @@ func TestSubmitOrder_InvalidOrderType(t *testing.T) {
 func TestSubmitOrder_InvalidBrokerID(t *testing.T) {
 	env := newTestOrderEnv()
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "invalid broker!",
 		DocumentNumber: "DOC001",
@@ -409,7 +411,7 @@ func TestSubmitOrder_InvalidDocumentNumber(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC 001!", // invalid chars
@@ -431,7 +433,7 @@ func TestSubmitOrder_InvalidSide(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -453,7 +455,7 @@ func TestSubmitOrder_InvalidSymbol(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -475,7 +477,7 @@ func TestSubmitOrder_ZeroQuantity(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -497,7 +499,7 @@ func TestSubmitOrder_NegativeQuantity(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -519,7 +521,7 @@ func TestSubmitOrder_LimitOrder_ZeroPrice(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -541,7 +543,7 @@ func TestSubmitOrder_LimitOrder_PriceTooManyDecimals(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -563,7 +565,7 @@ func TestSubmitOrder_LimitOrder_NoPrice(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -584,7 +586,7 @@ func TestSubmitOrder_LimitOrder_NoExpiresAt(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -606,7 +608,7 @@ func TestSubmitOrder_LimitOrder_PastExpiresAt(t *testing.T) {
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
 	past := time.Now().Add(-1 * time.Hour)
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -627,7 +629,7 @@ func TestSubmitOrder_LimitOrder_PastExpiresAt(t *testing.T) {
 func TestSubmitOrder_BrokerNotFound(t *testing.T) {
 	env := newTestOrderEnv()
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "nonexistent",
 		DocumentNumber: "DOC001",
@@ -646,7 +648,7 @@ func TestSubmitOrder_InsufficientBalance(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100.00, nil) // only $100
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -665,7 +667,7 @@ func TestSubmitOrder_InsufficientHoldings(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 10}})
 
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "DOC001",
@@ -686,7 +688,7 @@ func TestGetOrder_Success(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	submitted, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	submitted, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -721,13 +723,54 @@ func TestGetOrder_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetQueuePosition(t *testing.T) {
+	env := newTestOrderEnv()
+	env.registerBroker(t, "broker1", 100000.00, nil)
+
+	submit := func(qty int64) *domain.Order {
+		t.Helper()
+		o, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+			Type:           domain.OrderTypeLimit,
+			BrokerID:       "broker1",
+			DocumentNumber: "DOC001",
+			Side:           domain.OrderSideBid,
+			Symbol:         "AAPL",
+			Price:          floatPtr(150.00),
+			Quantity:       qty,
+			ExpiresAt:      futureTime(),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return o
+	}
+	first := submit(100)
+	second := submit(50)
+
+	pos := env.svc.GetQueuePosition(second)
+	if pos == nil || pos.OrdersAhead != 1 || pos.QuantityAhead != 100 {
+		t.Fatalf("expected 1 order / 100 ahead, got %+v", pos)
+	}
+
+	if _, err := env.svc.CancelOrder(context.Background(), first.OrderID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pos := env.svc.GetQueuePosition(first); pos != nil {
+		t.Errorf("expected nil for cancelled order, got %+v", pos)
+	}
+	pos = env.svc.GetQueuePosition(second)
+	if pos == nil || pos.OrdersAhead != 0 || pos.QuantityAhead != 0 {
+		t.Errorf("expected front of queue, got %+v", pos)
+	}
+}
+
 // --- CancelOrder Tests ---
 
 func TestCancelOrder_PendingOrder(t *testing.T) {
 	env := newTestOrderEnv()
 	env.registerBroker(t, "broker1", 100000.00, nil)
 
-	submitted, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	submitted, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "broker1",
 		DocumentNumber: "DOC001",
@@ -741,7 +784,7 @@ func TestCancelOrder_PendingOrder(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	cancelled, err := env.svc.CancelOrder(submitted.OrderID)
+	cancelled, err := env.svc.CancelOrder(context.Background(), submitted.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -770,7 +813,7 @@ func TestCancelOrder_PartiallyFilledOrder(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place a small ask.
-	_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -785,7 +828,7 @@ func TestCancelOrder_PartiallyFilledOrder(t *testing.T) {
 	}
 
 	// Place a larger bid that partially fills.
-	bid, err := env.svc.SubmitOrder(SubmitOrderRequest{
+	bid, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID001",
@@ -803,7 +846,7 @@ func TestCancelOrder_PartiallyFilledOrder(t *testing.T) {
 	}
 
 	// Cancel the partially filled order.
-	cancelled, err := env.svc.CancelOrder(bid.OrderID)
+	cancelled, err := env.svc.CancelOrder(context.Background(), bid.OrderID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -831,7 +874,7 @@ func TestCancelOrder_AlreadyFilled(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place ask and matching bid.
-	_, _ = env.svc.SubmitOrder(SubmitOrderRequest{
+	_, _ = env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -841,7 +884,7 @@ func TestCancelOrder_AlreadyFilled(t *testing.T) {
 		Quantity:       100,
 		ExpiresAt:      futureTime(),
 	})
-	bid, _ := env.svc.SubmitOrder(SubmitOrderRequest{
+	bid, _ := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID001",
@@ -852,7 +895,7 @@ func TestCancelOrder_AlreadyFilled(t *testing.T) {
 		ExpiresAt:      futureTime(),
 	})
 
-	_, err := env.svc.CancelOrder(bid.OrderID)
+	_, err := env.svc.CancelOrder(context.Background(), bid.OrderID)
 	if err != domain.ErrOrderNotCancellable {
 		t.Errorf("got error %v, want ErrOrderNotCancellable", err)
 	}
@@ -861,7 +904,179 @@ func TestCancelOrder_AlreadyFilled(t *testing.T) {
 func TestCancelOrder_NotFound(t *testing.T) {
 	env := newTestOrderEnv()
 
-	_, err := env.svc.CancelOrder("nonexistent")
+	_, err := env.svc.CancelOrder(context.Background(), "nonexistent")
+	if err != domain.ErrOrderNotFound {
+		t.Errorf("got error %v, want ErrOrderNotFound", err)
+	}
+}
+
+// --- ReplaceOrder Tests ---
+
+func TestReplaceOrder_PartiallyFilled(t *testing.T) {
+	env := newTestOrderEnv()
+	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 500}})
+	env.registerBroker(t, "buyer", 100000.00, nil)
+
+	_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "seller",
+		DocumentNumber: "ASK001",
+		Side:           domain.OrderSideAsk,
+		Symbol:         "AAPL",
+		Price:          floatPtr(148.00),
+		Quantity:       30,
+		ExpiresAt:      futureTime(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bid, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "buyer",
+		DocumentNumber: "BID001",
+		Side:           domain.OrderSideBid,
+		Symbol:         "AAPL",
+		Price:          floatPtr(150.00),
+		Quantity:       100,
+		ExpiresAt:      futureTime(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// New total of 80 with 30 already filled leaves 50 for the replacement.
+	cancelled, replacement, err := env.svc.ReplaceOrder(context.Background(), ReplaceOrderRequest{
+		OrderID:  bid.OrderID,
+		Price:    145.00,
+		Quantity: 80,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Errorf("got original status %q, want %q", cancelled.Status, domain.OrderStatusCancelled)
+	}
+	if replacement == nil {
+		t.Fatal("expected a replacement order")
+	}
+	if replacement.OrderID == bid.OrderID {
+		t.Error("expected the replacement to have a new order ID")
+	}
+	if replacement.Quantity != 50 || replacement.Price != 14500 {
+		t.Errorf("got replacement quantity %d price %d, want 50 at 14500", replacement.Quantity, replacement.Price)
+	}
+	if replacement.DocumentNumber != "BID001" || replacement.Side != domain.OrderSideBid {
+		t.Errorf("replacement did not inherit document number and side: %+v", replacement)
+	}
+	if replacement.ExpiresAt == nil || !replacement.ExpiresAt.Equal(*bid.ExpiresAt) {
+		t.Errorf("expected the original expiry to be kept, got %v", replacement.ExpiresAt)
+	}
+
+	// The original's history ends with the amendment.
+	events := cancelled.History.Events()
+	last := events[len(events)-1]
+	if last.Type != domain.OrderEventAmended || last.ReplacementOrderID != replacement.OrderID ||
+		last.Quantity != 50 || last.Price != 14500 || last.FilledQuantity != 30 {
+		t.Errorf("expected an amended event naming the replacement, got %+v", last)
+	}
+	if prev := events[len(events)-2]; prev.Type != domain.OrderEventCancelled || prev.Quantity != 70 {
+		t.Errorf("expected the original's cancellation before the amendment, got %+v", prev)
+	}
+
+	// Reserved cash moves from the original's remainder to the replacement.
+	broker, _ := env.brokerStore.Get("buyer")
+	if broker.ReservedCash != 50*14500 {
+		t.Errorf("got reserved_cash %d, want %d", broker.ReservedCash, 50*14500)
+	}
+}
+
+func TestReplaceOrder_AlreadyFilledUpToNewQuantity(t *testing.T) {
+	env := newTestOrderEnv()
+	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 500}})
+	env.registerBroker(t, "buyer", 100000.00, nil)
+
+	_, _ = env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "seller",
+		DocumentNumber: "ASK001",
+		Side:           domain.OrderSideAsk,
+		Symbol:         "AAPL",
+		Price:          floatPtr(148.00),
+		Quantity:       60,
+		ExpiresAt:      futureTime(),
+	})
+	bid, _ := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "buyer",
+		DocumentNumber: "BID001",
+		Side:           domain.OrderSideBid,
+		Symbol:         "AAPL",
+		Price:          floatPtr(150.00),
+		Quantity:       100,
+		ExpiresAt:      futureTime(),
+	})
+
+	cancelled, replacement, err := env.svc.ReplaceOrder(context.Background(), ReplaceOrderRequest{
+		OrderID:  bid.OrderID,
+		Price:    150.00,
+		Quantity: 50,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Errorf("got original status %q, want %q", cancelled.Status, domain.OrderStatusCancelled)
+	}
+	if replacement != nil {
+		t.Errorf("expected no replacement, got %+v", replacement)
+	}
+}
+
+func TestReplaceOrder_ValidationErrors(t *testing.T) {
+	env := newTestOrderEnv()
+	env.registerBroker(t, "buyer", 100000.00, nil)
+	bid, _ := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       "buyer",
+		DocumentNumber: "BID001",
+		Side:           domain.OrderSideBid,
+		Symbol:         "AAPL",
+		Price:          floatPtr(150.00),
+		Quantity:       100,
+		ExpiresAt:      futureTime(),
+	})
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		req  ReplaceOrderRequest
+	}{
+		{"zero quantity", ReplaceOrderRequest{OrderID: bid.OrderID, Price: 150.00, Quantity: 0}},
+		{"zero price", ReplaceOrderRequest{OrderID: bid.OrderID, Price: 0, Quantity: 10}},
+		{"too many decimals", ReplaceOrderRequest{OrderID: bid.OrderID, Price: 150.001, Quantity: 10}},
+		{"past expiry", ReplaceOrderRequest{OrderID: bid.OrderID, Price: 150.00, Quantity: 10, ExpiresAt: &past}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := env.svc.ReplaceOrder(context.Background(), tc.req)
+			var ve *domain.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+		})
+	}
+
+	// A rejected replace leaves the original untouched.
+	order, _ := env.svc.GetOrder(bid.OrderID)
+	if order.Status != domain.OrderStatusPending {
+		t.Errorf("got status %q, want %q", order.Status, domain.OrderStatusPending)
+	}
+}
+
+func TestReplaceOrder_NotFound(t *testing.T) {
+	env := newTestOrderEnv()
+
+	_, _, err := env.svc.ReplaceOrder(context.Background(), ReplaceOrderRequest{OrderID: "nonexistent", Price: 1, Quantity: 1})
 	if err != domain.ErrOrderNotFound {
 		t.Errorf("got error %v, want ErrOrderNotFound", err)
 	}
@@ -875,7 +1090,7 @@ func TestListOrders_Success(t *testing.T) {
 
 	// Submit a few orders.
 	for i := 0; i < 3; i++ {
-		_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+		_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 			Type:           domain.OrderTypeLimit,
 			BrokerID:       "broker1",
 			DocumentNumber: "DOC001",
@@ -908,7 +1123,7 @@ func TestListOrders_WithStatusFilter(t *testing.T) {
 	env.registerBroker(t, "buyer", 100000.00, nil)
 
 	// Place a resting ask.
-	_, _ = env.svc.SubmitOrder(SubmitOrderRequest{
+	_, _ = env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "seller",
 		DocumentNumber: "ASK001",
@@ -920,7 +1135,7 @@ func TestListOrders_WithStatusFilter(t *testing.T) {
 	})
 
 	// Place a matching bid (fills both).
-	_, _ = env.svc.SubmitOrder(SubmitOrderRequest{
+	_, _ = env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID001",
@@ -932,7 +1147,7 @@ func TestListOrders_WithStatusFilter(t *testing.T) {
 	})
 
 	// Place another pending bid.
-	_, _ = env.svc.SubmitOrder(SubmitOrderRequest{
+	_, _ = env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 		Type:           domain.OrderTypeLimit,
 		BrokerID:       "buyer",
 		DocumentNumber: "BID002",
@@ -976,7 +1191,7 @@ func TestListOrders_Pagination(t *testing.T) {
 
 	// Submit 5 orders.
 	for i := 0; i < 5; i++ {
-		_, err := env.svc.SubmitOrder(SubmitOrderRequest{
+		_, err := env.svc.SubmitOrder(context.Background(), SubmitOrderRequest{
 			Type:           domain.OrderTypeLimit,
 			BrokerID:       "broker1",
 			DocumentNumber: "DOC001",
@@ -1,7 +1,14 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
@@ -34,6 +41,30 @@ type BookResponse struct {
 	SnapshotAt time.Time
 }
 
+// L3Order represents a single resting order in the L3 book response. The
+// order reference is stable for the order's lifetime but cannot be mapped
+// back to the order ID or broker.
+type L3Order struct {
+	OrderRef  string
+	Quantity  int64
+	CreatedAt time.Time
+}
+
+// L3PriceLevel represents a price level with its orders in time priority.
+type L3PriceLevel struct {
+	Price         int64
+	TotalQuantity int64
+	Orders        []L3Order
+}
+
+// L3BookResponse represents the response for GET /stocks/{symbol}/book/l3.
+type L3BookResponse struct {
+	Symbol     string
+	Bids       []L3PriceLevel
+	Asks       []L3PriceLevel
+	SnapshotAt time.Time
+}
+
 // QuotePriceLevel represents a single price level in the quote response.
 type QuotePriceLevel struct {
 	Price    int64
@@ -53,6 +84,34 @@ type QuoteResponse struct {
 	QuotedAt          time.Time
 }
 
+// maxTradeTapeLimit is the largest page GET /stocks/{symbol}/trades returns.
+const maxTradeTapeLimit = 1000
+
+// TapeTrade is a single anonymised trade on the public tape.
+type TapeTrade struct {
+	TradeID       string
+	Price         int64
+	Quantity      int64
+	AggressorSide domain.OrderSide
+	ExecutedAt    time.Time
+}
+
+// TradeTapeRequest represents the input for GET /stocks/{symbol}/trades.
+type TradeTapeRequest struct {
+	Symbol string
+	From   *time.Time // inclusive; nil for no lower bound
+	To     *time.Time // exclusive; nil for no upper bound
+	Limit  int
+	Cursor string     // next_cursor from the previous page, or empty
+}
+
+// TradeTapeResponse represents the response for GET /stocks/{symbol}/trades.
+type TradeTapeResponse struct {
+	Symbol     string
+	Trades     []TapeTrade
+	NextCursor *string // nil on the last page
+}
+
 // StockService handles stock price, book, and quote queries.
 type StockService struct {
 	tradeStore *store.TradeStore
@@ -60,6 +119,7 @@ type StockService struct {
 	matcher    *engine.Matcher
 	vwapWindow time.Duration
 	symbols    *domain.SymbolRegistry
+	refKey     []byte // per-process key for anonymised L3 order references
 }
 
 // NewStockService creates a new StockService with the given dependencies.
@@ -70,12 +130,17 @@ func NewStockService(
 	vwapWindow time.Duration,
 	symbols *domain.SymbolRegistry,
 ) *StockService {
+	refKey := make([]byte, 32)
+	// crypto/rand.Read never returns an error on supported platforms.
+	_, _ = rand.Read(refKey)
+
 	return &StockService{
 		tradeStore: tradeStore,
 		books:      books,
 		matcher:    matcher,
 		vwapWindow: vwapWindow,
 		symbols:    symbols,
+		refKey:     refKey,
 	}
 }
 
@@ -190,6 +255,64 @@ func (s *StockService) GetBook(symbol string, depth int) (*BookResponse, error)
 	return resp, nil
 }
 
+// GetL3Book returns every resting order in the top depth price levels of
+// each side, best price first and in time priority within each level.
+func (s *StockService) GetL3Book(symbol string, depth int) (*L3BookResponse, error) {
+	if !s.symbols.Exists(symbol) {
+		return nil, domain.ErrSymbolNotFound
+	}
+
+	if depth < 1 || depth > 50 {
+		return nil, &domain.ValidationError{
+			Message: "depth must be between 1 and 50",
+		}
+	}
+
+	book := s.books.GetOrCreate(symbol)
+
+	book.RLock()
+	defer book.RUnlock()
+
+	return &L3BookResponse{
+		Symbol:     symbol,
+		Bids:       s.toL3Levels(book.TopBidOrders(depth)),
+		Asks:       s.toL3Levels(book.TopAskOrders(depth)),
+		SnapshotAt: time.Now(),
+	}, nil
+}
+
+// toL3Levels converts engine levels to L3 price levels. The caller must hold
+// the book's read lock, since it reads each order's remaining quantity.
+func (s *StockService) toL3Levels(levels []engine.LevelOrders) []L3PriceLevel {
+	result := make([]L3PriceLevel, len(levels))
+	for i, lvl := range levels {
+		orders := make([]L3Order, len(lvl.Entries))
+		var total int64
+		for j, e := range lvl.Entries {
+			orders[j] = L3Order{
+				OrderRef:  s.orderRef(e.OrderID),
+				Quantity:  e.Order.RemainingQuantity,
+				CreatedAt: e.CreatedAt,
+			}
+			total += e.Order.RemainingQuantity
+		}
+		result[i] = L3PriceLevel{
+			Price:         lvl.Price,
+			TotalQuantity: total,
+			Orders:        orders,
+		}
+	}
+	return result
+}
+
+// orderRef derives an anonymised reference for an order: a truncated
+// HMAC-SHA256 of the order ID under a key that never leaves the process.
+func (s *StockService) orderRef(orderID string) string {
+	mac := hmac.New(sha256.New, s.refKey)
+	mac.Write([]byte(orderID))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
 // GetQuote simulates a market order against the current book and returns
 // the estimated result without placing an order.
 func (s *StockService) GetQuote(symbol string, side domain.OrderSide, quantity int64) (*QuoteResponse, error) {
@@ -232,6 +355,98 @@ func (s *StockService) GetQuote(symbol string, side domain.OrderSide, quantity i
 	}, nil
 }
 
+// GetTrades returns a page of the symbol's public trade tape in chronological
+// order. Each execution appears once, taken from the aggressor's trade record,
+// without broker or order identifiers.
+//
+// The cursor is an opaque position in the symbol's append-only trade history,
+// so pages stay stable while new trades arrive. Clients pass the same from/to
+// on every page.
+func (s *StockService) GetTrades(req TradeTapeRequest) (*TradeTapeResponse, error) {
+	if !s.symbols.Exists(req.Symbol) {
+		return nil, domain.ErrSymbolNotFound
+	}
+
+	limit := req.Limit
+	if limit < 1 || limit > maxTradeTapeLimit {
+		return nil, &domain.ValidationError{
+			Message: fmt.Sprintf("limit must be between 1 and %d", maxTradeTapeLimit),
+		}
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, &domain.ValidationError{
+			Message: "from must be before to",
+		}
+	}
+
+	trades := s.tradeStore.GetBySymbol(req.Symbol)
+
+	start := 0
+	if req.Cursor != "" {
+		pos, ok := decodeTradeCursor(req.Cursor, len(trades))
+		if !ok {
+			return nil, &domain.ValidationError{Message: "cursor is invalid"}
+		}
+		start = pos
+	}
+	if req.From != nil {
+		// Trades are appended in execution order, so the history is sorted.
+		first := sort.Search(len(trades), func(i int) bool {
+			return !trades[i].ExecutedAt.Before(*req.From)
+		})
+		start = max(start, first)
+	}
+
+	resp := &TradeTapeResponse{
+		Symbol: req.Symbol,
+		Trades: make([]TapeTrade, 0),
+	}
+
+	for i := start; i < len(trades); i++ {
+		t := trades[i]
+		if req.To != nil && !t.ExecutedAt.Before(*req.To) {
+			break
+		}
+		if !t.Aggressor {
+			continue
+		}
+		if len(resp.Trades) == limit {
+			cursor := encodeTradeCursor(i)
+			resp.NextCursor = &cursor
+			break
+		}
+		resp.Trades = append(resp.Trades, TapeTrade{
+			TradeID:       t.TradeID,
+			Price:         t.Price,
+			Quantity:      t.Quantity,
+			AggressorSide: t.Side,
+			ExecutedAt:    t.ExecutedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// encodeTradeCursor returns an opaque cursor for a position in a symbol's
+// trade history.
+func encodeTradeCursor(pos int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(pos)))
+}
+
+// decodeTradeCursor parses a cursor produced by encodeTradeCursor and checks
+// it against the current history length.
+func decodeTradeCursor(cursor string, historyLen int) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(string(raw))
+	if err != nil || pos < 0 || pos > historyLen {
+		return 0, false
+	}
+	return pos, true
+}
+
 // formatDuration converts a time.Duration to a human-readable string
 // like "5m" for the window field.
 func formatDuration(d time.Duration) string {
@@ -27,10 +27,10 @@ func TestProperty_VWAPComputation(t *testing.T) {
 		tradeStore := store.NewTradeStore()
 		symbols := domain.NewSymbolRegistry()
 		symbols.Register("TEST")
-		books := engine.NewBookManager()
+		books := engine.NewBookManager(nil)
 		brokerStore := store.NewBrokerStore()
 		orderStore := store.NewOrderStore()
-		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 		svc := NewStockService(tradeStore, books, matcher, vwapWindow, symbols)
 
 		// Also add some trades outside the window to ensure they're excluded.
@@ -97,10 +97,10 @@ func TestProperty_VWAPFallbackToLastTrade(t *testing.T) {
 		tradeStore := store.NewTradeStore()
 		symbols := domain.NewSymbolRegistry()
 		symbols.Register("TEST")
-		books := engine.NewBookManager()
+		books := engine.NewBookManager(nil)
 		brokerStore := store.NewBrokerStore()
 		orderStore := store.NewOrderStore()
-		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 		svc := NewStockService(tradeStore, books, matcher, vwapWindow, symbols)
 
 		// Generate trades all outside the window.
@@ -158,10 +158,10 @@ func TestProperty_VWAPNullWhenNoTrades(t *testing.T) {
 		symbolName := fmt.Sprintf("SYM%d", rapid.IntRange(1, 999).Draw(t, "symbolSuffix"))
 		symbols.Register(symbolName)
 
-		books := engine.NewBookManager()
+		books := engine.NewBookManager(nil)
 		brokerStore := store.NewBrokerStore()
 		orderStore := store.NewOrderStore()
-		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 		svc := NewStockService(tradeStore, books, matcher, vwapWindow, symbols)
 
 		resp, err := svc.GetPrice(symbolName)
@@ -196,10 +196,10 @@ func TestProperty_BookSnapshotAggregation(t *testing.T) {
 		tradeStore := store.NewTradeStore()
 		symbols := domain.NewSymbolRegistry()
 		symbols.Register("TEST")
-		books := engine.NewBookManager()
+		books := engine.NewBookManager(nil)
 		brokerStore := store.NewBrokerStore()
 		orderStore := store.NewOrderStore()
-		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+		matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 		svc := NewStockService(tradeStore, books, matcher, 5*time.Minute, symbols)
 
 		book := books.GetOrCreate("TEST")
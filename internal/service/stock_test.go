@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -15,8 +18,8 @@ func newTestStockService(vwapWindow time.Duration) (*StockService, *store.TradeS
 	brokerStore := store.NewBrokerStore()
 	orderStore := store.NewOrderStore()
 	symbols := domain.NewSymbolRegistry()
-	books := engine.NewBookManager()
-	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols)
+	books := engine.NewBookManager(nil)
+	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, nil, nil)
 
 	svc := NewStockService(tradeStore, books, matcher, vwapWindow, symbols)
 	return svc, tradeStore, books, matcher, symbols, brokerStore, orderStore
@@ -248,7 +251,7 @@ func TestGetBook_WithOrders(t *testing.T) {
 		Quantity: 100,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(bidOrder)
+	svc.matcher.MatchLimitOrder(context.Background(), bidOrder)
 
 	askOrder := &domain.Order{
 		Type:     domain.OrderTypeLimit,
@@ -259,7 +262,7 @@ func TestGetBook_WithOrders(t *testing.T) {
 		Quantity: 200,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(askOrder)
+	svc.matcher.MatchLimitOrder(context.Background(), askOrder)
 
 	resp, err := svc.GetBook("AAPL", 10)
 	if err != nil {
@@ -312,7 +315,7 @@ func TestGetBook_DepthLimitsResults(t *testing.T) {
 			Quantity:  100,
 			ExpiresAt: &expires,
 		}
-		svc.matcher.MatchLimitOrder(order)
+		svc.matcher.MatchLimitOrder(context.Background(), order)
 	}
 
 	// Request depth=2 — should only get 2 levels.
@@ -331,6 +334,98 @@ func TestGetBook_DepthLimitsResults(t *testing.T) {
 	}
 }
 
+// --- GetL3Book tests ---
+
+func TestGetL3Book_SymbolNotFound(t *testing.T) {
+	svc, _, _, _, _, _, _ := newTestStockService(5 * time.Minute)
+
+	_, err := svc.GetL3Book("AAPL", 10)
+	if err != domain.ErrSymbolNotFound {
+		t.Fatalf("expected ErrSymbolNotFound, got %v", err)
+	}
+}
+
+func TestGetL3Book_InvalidDepth(t *testing.T) {
+	svc, _, _, _, symbols, _, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	for _, depth := range []int{0, 51} {
+		_, err := svc.GetL3Book("AAPL", depth)
+		if _, ok := err.(*domain.ValidationError); !ok {
+			t.Fatalf("depth %d: expected ValidationError, got %v", depth, err)
+		}
+	}
+}
+
+func TestGetL3Book_OrdersInPriority(t *testing.T) {
+	svc, _, _, _, symbols, brokerStore, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	brokerStore.Create(&domain.Broker{
+		BrokerID:    "seller",
+		CashBalance: 0,
+		Holdings: map[string]*domain.Holding{
+			"AAPL": {Quantity: 1000},
+		},
+		CreatedAt: time.Now(),
+	})
+
+	expires := time.Now().Add(time.Hour)
+	var orders []*domain.Order
+	for _, spec := range []struct{ price, qty int64 }{{10100, 30}, {10000, 10}, {10000, 20}} {
+		o := &domain.Order{
+			Type:      domain.OrderTypeLimit,
+			BrokerID:  "seller",
+			Side:      domain.OrderSideAsk,
+			Symbol:    "AAPL",
+			Price:     spec.price,
+			Quantity:  spec.qty,
+			ExpiresAt: &expires,
+		}
+		svc.matcher.MatchLimitOrder(context.Background(), o)
+		orders = append(orders, o)
+	}
+
+	resp, err := svc.GetL3Book("AAPL", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Bids) != 0 {
+		t.Fatalf("expected 0 bid levels, got %d", len(resp.Bids))
+	}
+	if len(resp.Asks) != 2 {
+		t.Fatalf("expected 2 ask levels, got %d", len(resp.Asks))
+	}
+
+	best := resp.Asks[0]
+	if best.Price != 10000 || best.TotalQuantity != 30 || len(best.Orders) != 2 {
+		t.Fatalf("unexpected best level: %+v", best)
+	}
+	if best.Orders[0].Quantity != 10 || best.Orders[1].Quantity != 20 {
+		t.Errorf("expected quantities [10, 20] in time priority, got [%d, %d]", best.Orders[0].Quantity, best.Orders[1].Quantity)
+	}
+
+	// References are stable, distinct, and do not expose the order ID.
+	if best.Orders[0].OrderRef != svc.orderRef(orders[1].OrderID) {
+		t.Errorf("expected stable order ref for the first order at the level")
+	}
+	if best.Orders[0].OrderRef == best.Orders[1].OrderRef {
+		t.Errorf("expected distinct order refs, got %q twice", best.Orders[0].OrderRef)
+	}
+	for _, o := range orders {
+		for _, l3 := range best.Orders {
+			if l3.OrderRef == o.OrderID {
+				t.Errorf("order ref leaks order ID %s", o.OrderID)
+			}
+		}
+	}
+
+	resp, _ = svc.GetL3Book("AAPL", 1)
+	if len(resp.Asks) != 1 {
+		t.Errorf("expected depth 1 to return 1 ask level, got %d", len(resp.Asks))
+	}
+}
+
 // --- GetQuote tests ---
 
 func TestGetQuote_SymbolNotFound(t *testing.T) {
@@ -423,7 +518,7 @@ func TestGetQuote_BidWithLiquidity(t *testing.T) {
 		Quantity:  700,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(ask1)
+	svc.matcher.MatchLimitOrder(context.Background(), ask1)
 
 	ask2 := &domain.Order{
 		Type:      domain.OrderTypeLimit,
@@ -434,7 +529,7 @@ func TestGetQuote_BidWithLiquidity(t *testing.T) {
 		Quantity:  300,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(ask2)
+	svc.matcher.MatchLimitOrder(context.Background(), ask2)
 
 	// Bid quote for 1000 shares — should sweep both levels.
 	resp, err := svc.GetQuote("AAPL", domain.OrderSideBid, 1000)
@@ -495,7 +590,7 @@ func TestGetQuote_PartialLiquidity(t *testing.T) {
 		Quantity:  400,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(ask)
+	svc.matcher.MatchLimitOrder(context.Background(), ask)
 
 	// Request 1000 but only 400 available.
 	resp, err := svc.GetQuote("AAPL", domain.OrderSideBid, 1000)
@@ -535,7 +630,7 @@ func TestGetQuote_AskSide(t *testing.T) {
 		Quantity:  500,
 		ExpiresAt: &expires,
 	}
-	svc.matcher.MatchLimitOrder(bid)
+	svc.matcher.MatchLimitOrder(context.Background(), bid)
 
 	// Ask quote — walks bid side.
 	resp, err := svc.GetQuote("AAPL", domain.OrderSideAsk, 500)
@@ -575,3 +670,127 @@ func TestFormatDuration(t *testing.T) {
 		}
 	}
 }
+
+// --- GetTrades tests ---
+
+// appendTradePair appends both records of one execution, as the matcher does.
+func appendTradePair(ts *store.TradeStore, tradeID string, price, qty int64, aggressor domain.OrderSide, at time.Time) {
+	passive := domain.OrderSideAsk
+	if aggressor == domain.OrderSideAsk {
+		passive = domain.OrderSideBid
+	}
+	ts.Append("AAPL", &domain.Trade{TradeID: tradeID, OrderID: tradeID + "-in", Side: aggressor, Aggressor: true, Price: price, Quantity: qty, ExecutedAt: at})
+	ts.Append("AAPL", &domain.Trade{TradeID: tradeID, OrderID: tradeID + "-rest", Side: passive, Price: price, Quantity: qty, ExecutedAt: at})
+}
+
+func TestGetTrades_OncePerTradeWithAggressorSide(t *testing.T) {
+	svc, tradeStore, _, _, symbols, _, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	now := time.Now()
+	appendTradePair(tradeStore, "t1", 15000, 10, domain.OrderSideBid, now.Add(-2*time.Minute))
+	appendTradePair(tradeStore, "t2", 14900, 5, domain.OrderSideAsk, now.Add(-time.Minute))
+
+	resp, err := svc.GetTrades(TradeTapeRequest{Symbol: "AAPL", Limit: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Trades) != 2 {
+		t.Fatalf("expected 2 trades, got %d", len(resp.Trades))
+	}
+	if resp.Trades[0].TradeID != "t1" || resp.Trades[0].AggressorSide != domain.OrderSideBid {
+		t.Errorf("unexpected first trade %+v", resp.Trades[0])
+	}
+	if resp.Trades[1].TradeID != "t2" || resp.Trades[1].AggressorSide != domain.OrderSideAsk {
+		t.Errorf("unexpected second trade %+v", resp.Trades[1])
+	}
+	if resp.NextCursor != nil {
+		t.Errorf("expected no next cursor, got %q", *resp.NextCursor)
+	}
+}
+
+func TestGetTrades_TimeRange(t *testing.T) {
+	svc, tradeStore, _, _, symbols, _, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	base := time.Date(2026, 2, 16, 16, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		appendTradePair(tradeStore, fmt.Sprintf("t%d", i), 15000, 1, domain.OrderSideBid, base.Add(time.Duration(i)*time.Minute))
+	}
+
+	from := base.Add(time.Minute)
+	to := base.Add(3 * time.Minute)
+	resp, err := svc.GetTrades(TradeTapeRequest{Symbol: "AAPL", From: &from, To: &to, Limit: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// from is inclusive, to is exclusive.
+	if len(resp.Trades) != 2 || resp.Trades[0].TradeID != "t1" || resp.Trades[1].TradeID != "t2" {
+		t.Fatalf("unexpected trades %+v", resp.Trades)
+	}
+}
+
+func TestGetTrades_CursorPagination(t *testing.T) {
+	svc, tradeStore, _, _, symbols, _, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		appendTradePair(tradeStore, fmt.Sprintf("t%d", i), 15000, 1, domain.OrderSideBid, now.Add(time.Duration(i)*time.Second))
+	}
+
+	var got []string
+	req := TradeTapeRequest{Symbol: "AAPL", Limit: 2}
+	for page := 0; ; page++ {
+		if page > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		resp, err := svc.GetTrades(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, tr := range resp.Trades {
+			got = append(got, tr.TradeID)
+		}
+		if resp.NextCursor == nil {
+			break
+		}
+		req.Cursor = *resp.NextCursor
+	}
+
+	want := []string{"t0", "t1", "t2", "t3", "t4"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetTrades_ValidationErrors(t *testing.T) {
+	svc, _, _, _, symbols, _, _ := newTestStockService(5 * time.Minute)
+	symbols.Register("AAPL")
+
+	now := time.Now()
+	later := now.Add(time.Minute)
+	tests := []struct {
+		name string
+		req  TradeTapeRequest
+	}{
+		{"limit zero", TradeTapeRequest{Symbol: "AAPL", Limit: 0}},
+		{"limit too large", TradeTapeRequest{Symbol: "AAPL", Limit: 1001}},
+		{"from after to", TradeTapeRequest{Symbol: "AAPL", Limit: 10, From: &later, To: &now}},
+		{"bad cursor", TradeTapeRequest{Symbol: "AAPL", Limit: 10, Cursor: "!!"}},
+		{"cursor past end", TradeTapeRequest{Symbol: "AAPL", Limit: 10, Cursor: encodeTradeCursor(5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GetTrades(tt.req)
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("expected ValidationError, got %v", err)
+			}
+		})
+	}
+
+	if _, err := svc.GetTrades(TradeTapeRequest{Symbol: "MSFT", Limit: 10}); !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// tickerWindow is the length of the rolling ticker window.
+const tickerWindow = 24 * time.Hour
+
+// Ticker holds rolling 24-hour statistics for a symbol. Prices are in cents.
+type Ticker struct {
+	Symbol      string
+	Open        *int64 // first trade in the window; nil when the window is empty
+	High        *int64
+	Low         *int64
+	Last        *int64 // most recent trade, even if older than the window
+	Change      *int64 // Last - Open; nil when the window is empty
+	Volume      int64
+	Notional    int64 // sum(price × quantity), in cents
+	TradeCount  int
+	BestBid     *BookPriceLevel
+	BestAsk     *BookPriceLevel
+	WindowStart time.Time
+	WindowEnd   time.Time
+}
+
+// ChangePercent returns Change as a percentage of Open, or false when the
+// window is empty.
+func (t *Ticker) ChangePercent() (float64, bool) {
+	if t.Change == nil || *t.Open == 0 {
+		return 0, false
+	}
+	return float64(*t.Change) / float64(*t.Open) * 100, true
+}
+
+// tickerTrade is one execution retained in a symbol's rolling window.
+type tickerTrade struct {
+	price      int64
+	quantity   int64
+	executedAt time.Time
+}
+
+// symbolTicker keeps a symbol's trades in the rolling window with running
+// totals. High and low are tracked with monotonic deques over the window
+// (indices into trades), so evicting old trades and reading the extremes
+// are amortised O(1).
+type symbolTicker struct {
+	trades   []tickerTrade // oldest first; trades[head:] are in the window
+	head     int
+	maxDeque []int // decreasing prices
+	minDeque []int // increasing prices
+	volume   int64
+	notional int64
+	last     *int64 // most recent trade, kept after it leaves the window
+	bestBid  *BookPriceLevel
+	bestAsk  *BookPriceLevel
+}
+
+// add appends a trade. Trades arrive in execution order per symbol.
+func (st *symbolTicker) add(price, qty int64, at time.Time) {
+	st.trades = append(st.trades, tickerTrade{price: price, quantity: qty, executedAt: at})
+	i := len(st.trades) - 1
+	for len(st.maxDeque) > 0 && st.trades[st.maxDeque[len(st.maxDeque)-1]].price <= price {
+		st.maxDeque = st.maxDeque[:len(st.maxDeque)-1]
+	}
+	st.maxDeque = append(st.maxDeque, i)
+	for len(st.minDeque) > 0 && st.trades[st.minDeque[len(st.minDeque)-1]].price >= price {
+		st.minDeque = st.minDeque[:len(st.minDeque)-1]
+	}
+	st.minDeque = append(st.minDeque, i)
+
+	st.volume += qty
+	st.notional += price * qty
+	st.last = &price
+}
+
+// evict drops trades executed before windowStart.
+func (st *symbolTicker) evict(windowStart time.Time) {
+	for st.head < len(st.trades) && st.trades[st.head].executedAt.Before(windowStart) {
+		t := st.trades[st.head]
+		st.volume -= t.quantity
+		st.notional -= t.price * t.quantity
+		if st.maxDeque[0] == st.head {
+			st.maxDeque = st.maxDeque[1:]
+		}
+		if st.minDeque[0] == st.head {
+			st.minDeque = st.minDeque[1:]
+		}
+		st.head++
+	}
+
+	// Compact once at least half the backing slice is dead, rebasing the
+	// deque indices.
+	if st.head > 0 && st.head*2 >= len(st.trades) {
+		st.trades = append([]tickerTrade(nil), st.trades[st.head:]...)
+		for i := range st.maxDeque {
+			st.maxDeque[i] -= st.head
+		}
+		for i := range st.minDeque {
+			st.minDeque[i] -= st.head
+		}
+		st.head = 0
+	}
+}
+
+// TickerService maintains rolling 24-hour statistics and the best bid/ask
+// for every symbol from book updates, without scanning trade history.
+type TickerService struct {
+	symbols *domain.SymbolRegistry
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	tickers map[string]*symbolTicker
+}
+
+// NewTickerService creates a TickerService seeded with the last 24 hours of
+// trades in tradeStore; see seedExecutions for when to register it as a book
+// listener. The window ends at clk's time; a nil clk is the wall clock.
+func NewTickerService(tradeStore *store.TradeStore, symbols *domain.SymbolRegistry, clk clock.Clock) *TickerService {
+	if clk == nil {
+		clk = clock.System
+	}
+	s := &TickerService{
+		symbols: symbols,
+		clock:   clk,
+		tickers: make(map[string]*symbolTicker),
+	}
+	seedExecutions(tradeStore, func(symbol string, t engine.TradeEvent) {
+		s.ticker(symbol).add(t.Price, t.Quantity, t.ExecutedAt)
+	})
+	windowStart := clk.Now().Add(-tickerWindow)
+	for _, st := range s.tickers {
+		st.evict(windowStart)
+	}
+	return s
+}
+
+// OnBookUpdate records the update's trades and best bid/ask. It implements
+// engine.BookListener.
+func (s *TickerService) OnBookUpdate(u *engine.BookUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.ticker(u.Symbol)
+	for _, t := range u.Trades {
+		st.add(t.Price, t.Quantity, t.ExecutedAt)
+	}
+	// Evict here too so symbols that are never queried stay bounded.
+	st.evict(s.clock.Now().Add(-tickerWindow))
+	st.bestBid = toBookPriceLevel(u.BestBid)
+	st.bestAsk = toBookPriceLevel(u.BestAsk)
+}
+
+// GetTicker returns the rolling 24-hour ticker for a symbol.
+func (s *TickerService) GetTicker(symbol string) (*Ticker, error) {
+	if !s.symbols.Exists(symbol) {
+		return nil, domain.ErrSymbolNotFound
+	}
+
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot(symbol, now), nil
+}
+
+// ListTickers returns the rolling 24-hour ticker for every registered symbol,
+// sorted by symbol.
+func (s *TickerService) ListTickers() []*Ticker {
+	symbols := s.symbols.List()
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*Ticker, len(symbols))
+	for i, symbol := range symbols {
+		result[i] = s.snapshot(symbol, now)
+	}
+	return result
+}
+
+// snapshot evicts expired trades and builds the symbol's ticker. The caller
+// must hold s.mu.
+func (s *TickerService) snapshot(symbol string, now time.Time) *Ticker {
+	windowStart := now.Add(-tickerWindow)
+	t := &Ticker{
+		Symbol:      symbol,
+		WindowStart: windowStart,
+		WindowEnd:   now,
+	}
+
+	st, ok := s.tickers[symbol]
+	if !ok {
+		return t
+	}
+	st.evict(windowStart)
+
+	if st.last != nil {
+		last := *st.last
+		t.Last = &last
+	}
+	t.BestBid = st.bestBid
+	t.BestAsk = st.bestAsk
+	t.Volume = st.volume
+	t.Notional = st.notional
+	t.TradeCount = len(st.trades) - st.head
+	if t.TradeCount > 0 {
+		open := st.trades[st.head].price
+		high := st.trades[st.maxDeque[0]].price
+		low := st.trades[st.minDeque[0]].price
+		change := *t.Last - open
+		t.Open, t.High, t.Low, t.Change = &open, &high, &low, &change
+	}
+	return t
+}
+
+// ticker returns the symbol's ticker state, creating it if necessary. The
+// caller must hold s.mu or be the constructor.
+func (s *TickerService) ticker(symbol string) *symbolTicker {
+	st, ok := s.tickers[symbol]
+	if !ok {
+		st = &symbolTicker{}
+		s.tickers[symbol] = st
+	}
+	return st
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+func newTestTickerService() (*TickerService, *domain.SymbolRegistry) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	return NewTickerService(store.NewTradeStore(), sr, nil), sr
+}
+
+func TestTicker_RollingStats(t *testing.T) {
+	svc, _ := newTestTickerService()
+	now := time.Now()
+	svc.OnBookUpdate(tradeUpdate(10000, 5, now.Add(-25*time.Hour))) // outside the window
+	svc.OnBookUpdate(tradeUpdate(15000, 10, now.Add(-3*time.Hour)))
+	svc.OnBookUpdate(tradeUpdate(16000, 2, now.Add(-2*time.Hour)))
+	svc.OnBookUpdate(tradeUpdate(14000, 4, now.Add(-time.Hour)))
+	svc.OnBookUpdate(tradeUpdate(15300, 4, now.Add(-time.Minute)))
+
+	ticker, err := svc.GetTicker("AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *ticker.Open != 15000 || *ticker.High != 16000 || *ticker.Low != 14000 || *ticker.Last != 15300 {
+		t.Errorf("got open=%d high=%d low=%d last=%d", *ticker.Open, *ticker.High, *ticker.Low, *ticker.Last)
+	}
+	if *ticker.Change != 300 {
+		t.Errorf("got change %d, want 300", *ticker.Change)
+	}
+	if pct, ok := ticker.ChangePercent(); !ok || pct != 2 {
+		t.Errorf("got change percent %v, want 2", pct)
+	}
+	if ticker.Volume != 20 || ticker.TradeCount != 4 {
+		t.Errorf("got volume=%d trades=%d, want 20/4", ticker.Volume, ticker.TradeCount)
+	}
+	wantNotional := int64(15000*10 + 16000*2 + 14000*4 + 15300*4)
+	if ticker.Notional != wantNotional {
+		t.Errorf("got notional %d, want %d", ticker.Notional, wantNotional)
+	}
+}
+
+func TestTicker_HighLowAfterEviction(t *testing.T) {
+	svc, _ := newTestTickerService()
+	now := time.Now()
+	// The window's high and low both sit on trades that age out first.
+	svc.OnBookUpdate(tradeUpdate(20000, 1, now.Add(-24*time.Hour+time.Minute)))
+	svc.OnBookUpdate(tradeUpdate(10000, 1, now.Add(-24*time.Hour+2*time.Minute)))
+	svc.OnBookUpdate(tradeUpdate(15000, 1, now.Add(-time.Hour)))
+	svc.OnBookUpdate(tradeUpdate(15500, 1, now.Add(-time.Minute)))
+
+	svc.mu.Lock()
+	ticker := svc.snapshot("AAPL", now.Add(5*time.Minute))
+	svc.mu.Unlock()
+
+	if ticker.TradeCount != 2 || *ticker.Open != 15000 || *ticker.High != 15500 || *ticker.Low != 15000 {
+		t.Errorf("unexpected ticker after eviction: trades=%d open=%d high=%d low=%d",
+			ticker.TradeCount, *ticker.Open, *ticker.High, *ticker.Low)
+	}
+	if ticker.Volume != 2 || ticker.Notional != 30500 {
+		t.Errorf("got volume=%d notional=%d, want 2/30500", ticker.Volume, ticker.Notional)
+	}
+}
+
+func TestTicker_EmptyWindowKeepsLastPrice(t *testing.T) {
+	svc, _ := newTestTickerService()
+	svc.OnBookUpdate(tradeUpdate(15000, 1, time.Now().Add(-48*time.Hour)))
+
+	ticker, err := svc.GetTicker("AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ticker.Open != nil || ticker.High != nil || ticker.Change != nil || ticker.TradeCount != 0 {
+		t.Errorf("expected empty window, got %+v", ticker)
+	}
+	if ticker.Last == nil || *ticker.Last != 15000 {
+		t.Errorf("expected last 15000, got %v", ticker.Last)
+	}
+	if _, ok := ticker.ChangePercent(); ok {
+		t.Error("expected no change percent for an empty window")
+	}
+}
+
+func TestTicker_BestBidAskFromBookUpdates(t *testing.T) {
+	svc, _ := newTestTickerService()
+	svc.OnBookUpdate(&engine.BookUpdate{
+		Symbol:  "AAPL",
+		BestBid: &engine.PriceLevel{Price: 14900, TotalQuantity: 10, OrderCount: 1},
+		BestAsk: &engine.PriceLevel{Price: 15100, TotalQuantity: 3, OrderCount: 2},
+	})
+
+	ticker, err := svc.GetTicker("AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ticker.BestBid == nil || ticker.BestBid.Price != 14900 || ticker.BestAsk == nil || ticker.BestAsk.OrderCount != 2 {
+		t.Errorf("unexpected best bid/ask %+v / %+v", ticker.BestBid, ticker.BestAsk)
+	}
+
+	svc.OnBookUpdate(&engine.BookUpdate{Symbol: "AAPL", BestAsk: &engine.PriceLevel{Price: 15100, TotalQuantity: 3, OrderCount: 2}})
+	if ticker, _ := svc.GetTicker("AAPL"); ticker.BestBid != nil {
+		t.Errorf("expected empty bid side, got %+v", ticker.BestBid)
+	}
+}
+
+func TestTicker_SeededFromTradeStore(t *testing.T) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	ts := store.NewTradeStore()
+	at := time.Now().Add(-time.Hour)
+	ts.Append("AAPL", &domain.Trade{TradeID: "t1", OrderID: "in", Side: domain.OrderSideBid, Aggressor: true, Price: 15000, Quantity: 10, ExecutedAt: at})
+	ts.Append("AAPL", &domain.Trade{TradeID: "t1", OrderID: "rest", Side: domain.OrderSideAsk, Price: 15000, Quantity: 10, ExecutedAt: at})
+
+	ticker, err := NewTickerService(ts, sr, nil).GetTicker("AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ticker.TradeCount != 1 || ticker.Volume != 10 {
+		t.Errorf("got trades=%d volume=%d, want 1/10", ticker.TradeCount, ticker.Volume)
+	}
+}
+
+func TestTicker_ListAndUnknownSymbol(t *testing.T) {
+	svc, sr := newTestTickerService()
+	sr.Register("GOOG")
+	svc.OnBookUpdate(tradeUpdate(15000, 1, time.Now()))
+
+	tickers := svc.ListTickers()
+	if len(tickers) != 2 || tickers[0].Symbol != "AAPL" || tickers[1].Symbol != "GOOG" {
+		t.Fatalf("unexpected tickers %+v", tickers)
+	}
+	if tickers[1].Last != nil || tickers[1].TradeCount != 0 {
+		t.Errorf("expected an empty GOOG ticker, got %+v", tickers[1])
+	}
+
+	if _, err := svc.GetTicker("MSFT"); !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+}
+
+func TestTicker_WindowFollowsClock(t *testing.T) {
+	sr := domain.NewSymbolRegistry()
+	sr.Register("AAPL")
+	clk := clock.NewVirtual(candleBase)
+	svc := NewTickerService(store.NewTradeStore(), sr, clk)
+	svc.OnBookUpdate(tradeUpdate(15000, 10, candleBase.Add(-time.Hour)))
+
+	if ticker, _ := svc.GetTicker("AAPL"); ticker.TradeCount != 1 {
+		t.Fatalf("got %d trades, want 1", ticker.TradeCount)
+	}
+	clk.Advance(23*time.Hour + time.Minute)
+	ticker, _ := svc.GetTicker("AAPL")
+	if ticker.TradeCount != 0 || ticker.Volume != 0 {
+		t.Errorf("expected the trade to leave the window, got %+v", ticker)
+	}
+	if !ticker.WindowEnd.Equal(clk.Now()) {
+		t.Errorf("got window end %v, want %v", ticker.WindowEnd, clk.Now())
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// tracer returns the service layer's tracer from the global provider,
+// looked up on each use so spans follow whichever provider is installed.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/efreitasn/miniexchange/internal/service")
+}
+
+// startDeliverySpan starts the span for an attempt to deliver d to wh, in
+// the trace of the dispatch that queued it, if any. The returned context
+// is cancelled when the service closes.
+func (s *WebhookService) startDeliverySpan(name string, wh *domain.Webhook, d *domain.WebhookDelivery) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(s.ctx, propagation.MapCarrier(d.TraceContext))
+	return tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.id", wh.WebhookID),
+			attribute.String("webhook.event", d.Event),
+			attribute.String("webhook.delivery_id", d.DeliveryID),
+			attribute.Int("webhook.attempt", d.Attempts+1),
+			attribute.String("url.full", wh.URL),
+		),
+	)
+}
+
+// endDeliverySpan records the outcome of a delivery attempt and ends its
+// span.
+func endDeliverySpan(span trace.Span, a domain.DeliveryAttempt, err error) {
+	if a.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", a.StatusCode))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
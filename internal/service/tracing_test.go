@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/pkg/webhook"
+)
+
+// recordSpans installs a tracer provider that records every span, and the
+// W3C trace context propagator, for the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp, prop := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(prop)
+	})
+	return rec
+}
+
+// endedSpans returns the ended spans with the given name.
+func endedSpans(rec *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestDeliver_PropagatesTraceContext(t *testing.T) {
+	rec := recordSpans(t)
+
+	var mu sync.Mutex
+	var headers []http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers = append(headers, r.Header.Clone())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), fastRetries)
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled"},
+	})
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	svc.DispatchOrderCancelled(ctx, cancelledOrder())
+	root.End()
+
+	waitFor(t, "delivery span", func() bool {
+		return len(endedSpans(rec, "webhook.deliver")) == 1
+	})
+
+	dispatch := endedSpans(rec, "webhook.dispatch")
+	if len(dispatch) != 1 || dispatch[0].Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Fatalf("expected one webhook.dispatch span under the root, got %v", dispatch)
+	}
+	deliver := endedSpans(rec, "webhook.deliver")[0]
+	if deliver.SpanKind() != trace.SpanKindClient {
+		t.Errorf("deliver span kind: expected client, got %v", deliver.SpanKind())
+	}
+	if deliver.SpanContext().TraceID() != root.SpanContext().TraceID() ||
+		deliver.Parent().SpanID() != dispatch[0].SpanContext().SpanID() {
+		t.Error("expected the delivery in the dispatch's trace, under the dispatch span")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(headers) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(headers))
+	}
+	want := "00-" + deliver.SpanContext().TraceID().String() + "-" + deliver.SpanContext().SpanID().String() + "-01"
+	if got := headers[0].Get("Traceparent"); got != want {
+		t.Errorf("traceparent: expected %q, got %q", want, got)
+	}
+	if headers[0].Get(webhook.HeaderDeliveryID) == "" {
+		t.Error("expected the usual delivery headers alongside the trace context")
+	}
+
+	deliveries, _ := svc.ListDeliveries(webhooks[0].WebhookID, "succeeded")
+	if len(deliveries) != 1 || deliveries[0].TraceContext["traceparent"] == "" {
+		t.Errorf("expected the delivery to keep its trace context, got %+v", deliveries)
+	}
+}
+
+func TestDeliver_UntracedDispatchStartsTrace(t *testing.T) {
+	rec := recordSpans(t)
+	svc, _, requests := newDeliveryTestEnv(t, fastRetries, func(int64) int { return http.StatusInternalServerError })
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "failed attempts", func() bool {
+		return len(endedSpans(rec, "webhook.deliver")) == fastRetries.MaxAttempts
+	})
+
+	dispatch := endedSpans(rec, "webhook.dispatch")[0]
+	for i, s := range endedSpans(rec, "webhook.deliver") {
+		if s.Parent().SpanID() != dispatch.SpanContext().SpanID() {
+			t.Errorf("attempt %d: expected parent webhook.dispatch", i+1)
+		}
+		if s.Status().Code != codes.Error {
+			t.Errorf("attempt %d: expected error status, got %+v", i+1, s.Status())
+		}
+	}
+	if requests.Load() != int64(fastRetries.MaxAttempts) {
+		t.Errorf("expected %d requests, got %d", fastRetries.MaxAttempts, requests.Load())
+	}
+}
+
+func TestSubmitOrder_Traced(t *testing.T) {
+	env, _ := newEventCatalogueEnv(t)
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("seller", domain.OrderSideAsk, 150.00, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := recordSpans(t)
+	order, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	submit := endedSpans(rec, "service.submit_order")
+	if len(submit) != 1 {
+		t.Fatalf("expected 1 service.submit_order span, got %d", len(submit))
+	}
+	parent := submit[0].SpanContext().SpanID()
+	match := endedSpans(rec, "engine.match_limit_order")
+	if len(match) != 1 || match[0].Parent().SpanID() != parent {
+		t.Fatal("expected the matching pass under the submission")
+	}
+	held := endedSpans(rec, "engine.lock_held")
+	if len(held) != 1 || held[0].Parent().SpanID() != match[0].SpanContext().SpanID() {
+		t.Fatal("expected the book lock held under the matching pass")
+	}
+	// trade.executed, balance.changed and order.filled for each side, all
+	// numbered and queued with the book locked.
+	dispatch := endedSpans(rec, "webhook.dispatch")
+	if len(dispatch) != 6 {
+		t.Errorf("expected 6 webhook.dispatch spans, got %d", len(dispatch))
+	}
+	for _, s := range dispatch {
+		if s.Parent().SpanID() != held[0].SpanContext().SpanID() {
+			t.Errorf("expected %v under the book lock", s.Attributes())
+		}
+	}
+
+	found := false
+	for _, kv := range submit[0].Attributes() {
+		if kv.Key == "order.id" && kv.Value.AsString() == order.OrderID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected order.id=%s on the submission span, got %v", order.OrderID, submit[0].Attributes())
+	}
+}
+
+func TestSubmitOrder_TracedRejection(t *testing.T) {
+	env, _ := newEventCatalogueEnv(t)
+	rec := recordSpans(t)
+
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 0)); err == nil {
+		t.Fatal("expected a validation error")
+	}
+
+	submit := endedSpans(rec, "service.submit_order")
+	if len(submit) != 1 || submit[0].Status().Code != codes.Error {
+		t.Fatalf("expected an errored service.submit_order span, got %v", submit)
+	}
+	if len(endedSpans(rec, "engine.match_limit_order")) != 0 {
+		t.Error("expected no matching pass for an invalid order")
+	}
+	if rejected := endedSpans(rec, "webhook.dispatch"); len(rejected) != 1 || rejected[0].Parent().SpanID() != submit[0].SpanContext().SpanID() {
+		t.Error("expected order.rejected dispatched under the submission")
+	}
+}
@@ -1,55 +1,144 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
 	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/metrics"
 	"github.com/efreitasn/miniexchange/internal/store"
 	"github.com/google/uuid"
 )
 
-// Valid webhook event types.
-var validWebhookEvents = map[string]bool{
-	"trade.executed":  true,
-	"order.expired":   true,
-	"order.cancelled": true,
-}
+const (
+	// webhookSecretPrefix starts every signing secret.
+	webhookSecretPrefix = "whsec_"
+
+	// secretRotationGrace is how long a rotated-out signing secret keeps
+	// signing deliveries alongside the new one.
+	secretRotationGrace = 24 * time.Hour
+)
 
 // UpsertWebhookRequest represents the input for webhook registration.
+// Version 0 subscribes to each event's latest payload version.
 type UpsertWebhookRequest struct {
 	BrokerID string
 	URL      string
 	Events   []string
+	Filters  WebhookFiltersInput
+	Version  int
 }
 
-// WebhookService handles webhook CRUD and event dispatch.
+// WebhookFiltersInput represents the input for a subscription's filters.
+// Zero values do not filter.
+type WebhookFiltersInput struct {
+	Symbols     []string
+	Side        string
+	MinNotional *float64
+}
+
+// UpdateWebhookRequest represents the input for updating a webhook
+// subscription. Nil fields are left unchanged.
+type UpdateWebhookRequest struct {
+	WebhookID string
+	Status    *string
+	Filters   *WebhookFiltersInput
+	Version   *int
+}
+
+// maxFilterSymbols is the most symbols a subscription's filters may list.
+const maxFilterSymbols = 100
+
+// WebhookService handles webhook CRUD and event dispatch. Deliveries are
+// kept in the delivery store and attempted by a fixed pool of workers,
+// retried under the DeliveryPolicy until they succeed or fail.
 type WebhookService struct {
 	store       *store.WebhookStore
+	deliveries  *store.DeliveryStore
 	brokerStore *store.BrokerStore
 	client      *http.Client
+	events      *EventStreamService // optional; receives every event regardless of subscriptions
+	policy      DeliveryPolicy
+	metrics     *metrics.Metrics // optional
+	clock       clock.Clock      // stamps events and subscription changes
+
+	// mu guards the fields below. It also orders event dispatch, so that
+	// sequence numbers, the event stream and the delivery queues agree, and
+	// orders delivery updates with webhook deletion.
+	mu             sync.Mutex
+	sequences      map[string]uint64 // broker_id → last event sequence number
+	endpointQueues map[endpointKey]*endpointQueue
+	queue          deliveryQueue
+	circuits       map[string]*circuit // endpoint URL → circuit
+	wake           chan struct{}
+	due            chan scheduledDelivery // handed from the scheduler to the workers
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-// NewWebhookService creates a new WebhookService with the given dependencies.
+// NewWebhookService creates a new WebhookService with the given dependencies
+// and starts delivering. Call Close to stop.
+// events may be nil, in which case events are only delivered through webhooks.
+// metrics may be nil. Event payloads and subscriptions are stamped with clk's
+// time; a nil clk is the wall clock. Delivery attempts, retries and secret
+// rotation always run on the wall clock.
 func NewWebhookService(
 	webhookStore *store.WebhookStore,
+	deliveryStore *store.DeliveryStore,
 	brokerStore *store.BrokerStore,
 	webhookTimeout time.Duration,
+	policy DeliveryPolicy,
+	events *EventStreamService,
+	metrics *metrics.Metrics,
+	clk clock.Clock,
 ) *WebhookService {
-	return &WebhookService{
+	if clk == nil {
+		clk = clock.System
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &WebhookService{
 		store:       webhookStore,
+		deliveries:  deliveryStore,
 		brokerStore: brokerStore,
+		events:      events,
+		metrics:     metrics,
+		clock:       clk,
 		client: &http.Client{
 			Timeout: webhookTimeout,
 		},
+		policy:         policy.withDefaults(),
+		sequences:      make(map[string]uint64),
+		endpointQueues: make(map[endpointKey]*endpointQueue),
+		circuits:       make(map[string]*circuit),
+		wake:           make(chan struct{}, 1),
+		due:            make(chan scheduledDelivery),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
+	s.start()
+	return s
 }
 
-// Upsert validates the request and creates or updates webhook subscriptions.
+// Upsert validates the request and creates or updates webhook subscriptions,
+// one per event, keyed by (broker_id, event, url): a broker may subscribe
+// several URLs to the same event. Registering an existing subscription again
+// replaces its filters and version.
 // Returns the resulting webhooks, whether any new subscriptions were created, and any error.
+// Each new subscription gets its own signing secret, which is returned only
+// here: webhooks that already existed are returned without theirs.
 func (s *WebhookService) Upsert(req UpsertWebhookRequest) ([]*domain.Webhook, bool, error) {
 	// Validate broker exists.
 	if !s.brokerStore.Exists(req.BrokerID) {
@@ -80,9 +169,9 @@ func (s *WebhookService) Upsert(req UpsertWebhookRequest) ([]*domain.Webhook, bo
 	seen := make(map[string]bool, len(req.Events))
 	dedupedEvents := make([]string, 0, len(req.Events))
 	for _, event := range req.Events {
-		if !validWebhookEvents[event] {
+		if _, ok := webhookEvents[event]; !ok {
 			return nil, false, &domain.ValidationError{
-				Message: "Unknown event type: " + event + ". Must be one of: trade.executed, order.expired, order.cancelled",
+				Message: "Unknown event type: " + event + ". Must be one of: " + webhookEventNames(),
 			}
 		}
 		if !seen[event] {
@@ -90,58 +179,228 @@ func (s *WebhookService) Upsert(req UpsertWebhookRequest) ([]*domain.Webhook, bo
 			dedupedEvents = append(dedupedEvents, event)
 		}
 	}
+	for _, event := range dedupedEvents {
+		if err := validateWebhookVersion(req.Version, event); err != nil {
+			return nil, false, err
+		}
+	}
 
-	// Upsert each (broker_id, event) pair.
-	now := time.Now().UTC().Truncate(time.Second)
+	filters, err := parseWebhookFilters(req.Filters)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Upsert each (broker_id, event, url) subscription.
+	now := s.clock.Now().UTC().Truncate(time.Second)
 	anyCreated := false
 	webhooks := make([]*domain.Webhook, 0, len(dedupedEvents))
 
 	for _, event := range dedupedEvents {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, false, err
+		}
+		version := req.Version
+		if version == 0 {
+			version = webhookEvents[event]
+		}
 		w := &domain.Webhook{
 			WebhookID: uuid.New().String(),
 			BrokerID:  req.BrokerID,
 			Event:     event,
 			URL:       req.URL,
+			Status:    domain.WebhookStatusActive,
+			Filters:   filters,
+			Version:   version,
 			CreatedAt: now,
 			UpdatedAt: now,
+			Secret:    secret,
 		}
 
-		created := s.store.Upsert(w)
+		stored, created := s.store.Upsert(w)
 		if created {
 			anyCreated = true
-			webhooks = append(webhooks, w)
 		} else {
-			// Fetch the existing webhook to return it.
-			existing := s.store.GetByBrokerEvent(req.BrokerID, event)
-			if existing != nil {
-				webhooks = append(webhooks, existing)
-			}
+			stored.Secret = ""
+			stored.PreviousSecret = ""
 		}
+		webhooks = append(webhooks, stored)
 	}
 
 	return webhooks, anyCreated, nil
 }
 
-// List validates the broker exists and returns all webhook subscriptions.
+// List validates the broker exists and returns all webhook subscriptions,
+// without their signing secrets.
 func (s *WebhookService) List(brokerID string) ([]*domain.Webhook, error) {
 	if !s.brokerStore.Exists(brokerID) {
 		return nil, domain.ErrBrokerNotFound
 	}
-	return s.store.ListByBroker(brokerID), nil
+	webhooks := s.store.ListByBroker(brokerID)
+	for _, w := range webhooks {
+		w.Secret = ""
+		w.PreviousSecret = ""
+	}
+	return webhooks, nil
 }
 
-// Delete removes a webhook subscription by ID.
+// Update changes a webhook subscription's status, filters or version, and
+// returns it without its signing secret. Pausing a subscription stops
+// deliveries for events that occur until it is resumed; deliveries already
+// queued are still made.
+func (s *WebhookService) Update(req UpdateWebhookRequest) (*domain.Webhook, error) {
+	wh, err := s.store.Get(req.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == nil && req.Filters == nil && req.Version == nil {
+		return nil, &domain.ValidationError{Message: "at least one of status, filters or version is required"}
+	}
+
+	updated := *wh
+	if req.Status != nil {
+		switch status := domain.WebhookStatus(*req.Status); status {
+		case domain.WebhookStatusActive, domain.WebhookStatusPaused:
+			updated.Status = status
+		default:
+			return nil, &domain.ValidationError{
+				Message: fmt.Sprintf("Invalid status: '%s'. Must be one of: active, paused", *req.Status),
+			}
+		}
+	}
+	if req.Filters != nil {
+		if updated.Filters, err = parseWebhookFilters(*req.Filters); err != nil {
+			return nil, err
+		}
+	}
+	if req.Version != nil {
+		if *req.Version == 0 {
+			return nil, &domain.ValidationError{Message: "version must be a positive integer"}
+		}
+		if err := validateWebhookVersion(*req.Version, wh.Event); err != nil {
+			return nil, err
+		}
+		updated.Version = *req.Version
+	}
+
+	if updated.Status != wh.Status || updated.Version != wh.Version || !updated.Filters.Equal(wh.Filters) {
+		updated.UpdatedAt = s.clock.Now().UTC().Truncate(time.Second)
+	}
+	result, err := s.store.Update(&updated)
+	if err != nil {
+		return nil, err
+	}
+	result.Secret = ""
+	result.PreviousSecret = ""
+	return result, nil
+}
+
+// Get returns a webhook subscription by ID.
+func (s *WebhookService) Get(webhookID string) (*domain.Webhook, error) {
+	return s.store.Get(webhookID)
+}
+
+// RotateSecret replaces a webhook's signing secret and returns the webhook
+// with the new one. The old secret keeps signing deliveries, next to the new
+// one, for secretRotationGrace, so receivers can switch without rejecting
+// deliveries in between.
+func (s *WebhookService) RotateSecret(webhookID string) (*domain.Webhook, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	// Deliveries are signed on the wall clock, so the grace period runs on it
+	// too.
+	now := time.Now().UTC().Truncate(time.Second)
+	return s.store.RotateSecret(webhookID, secret, now.Add(secretRotationGrace), now)
+}
+
+// Delete removes a webhook subscription by ID, along with its deliveries.
 func (s *WebhookService) Delete(webhookID string) error {
-	return s.store.Delete(webhookID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wh, err := s.store.Get(webhookID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(webhookID); err != nil {
+		return err
+	}
+	s.deliveries.DeleteByWebhook(webhookID)
+	// The webhook's queued deliveries are dropped as their turn comes.
+	s.unblock(endpointKey{wh.BrokerID, wh.URL})
+	return nil
+}
+
+// eventPayload is an event payload. It carries the broker's event sequence
+// number, which dispatch assigns, and describes what the event is about so
+// that dispatch can apply subscription filters.
+type eventPayload interface {
+	setSequence(seq uint64)
+	subject() eventSubject
+}
+
+// versionedPayload is the payload of an event with more than one payload
+// version. It is built at the latest version, which the event stream gets,
+// and atVersion renders it at the version a subscription asked for.
+type versionedPayload interface {
+	eventPayload
+	atVersion(version int) any
+}
+
+// payloadAt returns payload as rendered for a subscription to version.
+func payloadAt(payload eventPayload, version int) any {
+	if vp, ok := payload.(versionedPayload); ok {
+		return vp.atVersion(version)
+	}
+	return payload
+}
+
+// eventSubject is what an event is about, as far as subscription filters
+// are concerned. Fields the event does not have are left empty.
+type eventSubject struct {
+	symbol   string
+	side     domain.OrderSide
+	notional int64 // value traded, in cents; 0 for events that are not trades
+}
+
+// matches reports whether a subscription with filters f receives the event.
+// A filter on a field the event does not have lets it through.
+func (e eventSubject) matches(f domain.WebhookFilters) bool {
+	if len(f.Symbols) > 0 && e.symbol != "" && !slices.Contains(f.Symbols, e.symbol) {
+		return false
+	}
+	if f.Side != "" && e.side != "" && e.side != f.Side {
+		return false
+	}
+	if f.MinNotional > 0 && e.notional > 0 && e.notional < f.MinNotional {
+		return false
+	}
+	return true
 }
 
 // tradeExecutedPayload is the JSON payload for trade.executed webhooks.
 type tradeExecutedPayload struct {
 	Event     string                 `json:"event"`
+	Version   int                    `json:"version"`
+	Sequence  uint64                 `json:"sequence"`
 	Timestamp string                 `json:"timestamp"`
 	Data      tradeExecutedData      `json:"data"`
 }
 
+func (p *tradeExecutedPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *tradeExecutedPayload) subject() eventSubject {
+	// The price was converted from cents, so converting back is exact.
+	price, _ := domain.DollarsToCents(p.Data.TradePrice)
+	return eventSubject{
+		symbol:   p.Data.Symbol,
+		side:     domain.OrderSide(p.Data.Side),
+		notional: price * p.Data.TradeQuantity,
+	}
+}
+
 type tradeExecutedData struct {
 	TradeID               string  `json:"trade_id"`
 	BrokerID              string  `json:"broker_id"`
@@ -155,13 +414,22 @@ type tradeExecutedData struct {
 	OrderRemainingQuantity int64  `json:"order_remaining_quantity"`
 }
 
-// orderEventPayload is the JSON payload for order.expired and order.cancelled webhooks.
+// orderEventPayload is the JSON payload for order.accepted, order.expired,
+// and order.cancelled events.
 type orderEventPayload struct {
 	Event     string         `json:"event"`
+	Version   int            `json:"version"`
+	Sequence  uint64         `json:"sequence"`
 	Timestamp string         `json:"timestamp"`
 	Data      orderEventData `json:"data"`
 }
 
+func (p *orderEventPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *orderEventPayload) subject() eventSubject {
+	return eventSubject{symbol: p.Data.Symbol, side: domain.OrderSide(p.Data.Side)}
+}
+
 type orderEventData struct {
 	BrokerID          string  `json:"broker_id"`
 	OrderID           string  `json:"order_id"`
@@ -176,15 +444,11 @@ type orderEventData struct {
 }
 
 // DispatchTradeExecuted dispatches a trade.executed webhook notification
-// to the specified broker. Fire-and-forget — errors are silently ignored.
-func (s *WebhookService) DispatchTradeExecuted(brokerID string, trade *domain.Trade, order *domain.Order) {
-	wh := s.store.GetByBrokerEvent(brokerID, "trade.executed")
-	if wh == nil {
-		return
-	}
-
+// to the specified broker. The delivery happens in the background.
+func (s *WebhookService) DispatchTradeExecuted(ctx context.Context, brokerID string, trade *domain.Trade, order *domain.Order) {
 	payload := tradeExecutedPayload{
 		Event:     "trade.executed",
+		Version:   webhookEvents["trade.executed"],
 		Timestamp: trade.ExecutedAt.UTC().Truncate(time.Second).Format(time.RFC3339),
 		Data: tradeExecutedData{
 			TradeID:                trade.TradeID,
@@ -200,38 +464,69 @@ func (s *WebhookService) DispatchTradeExecuted(brokerID string, trade *domain.Tr
 		},
 	}
 
-	go s.deliver(wh, "trade.executed", payload)
+	s.dispatch(ctx, brokerID, "trade.executed", &payload)
 }
 
 // DispatchOrderExpired dispatches an order.expired webhook notification
-// to the order's broker. Fire-and-forget.
-func (s *WebhookService) DispatchOrderExpired(order *domain.Order) {
-	wh := s.store.GetByBrokerEvent(order.BrokerID, "order.expired")
-	if wh == nil {
-		return
-	}
-
-	payload := s.buildOrderEventPayload("order.expired", order)
-	go s.deliver(wh, "order.expired", payload)
+// to the order's broker. The delivery happens in the background.
+func (s *WebhookService) DispatchOrderExpired(ctx context.Context, order *domain.Order) {
+	s.dispatch(ctx, order.BrokerID, "order.expired", s.buildOrderEventPayload("order.expired", order))
 }
 
 // DispatchOrderCancelled dispatches an order.cancelled webhook notification
-// to the order's broker. Fire-and-forget.
-func (s *WebhookService) DispatchOrderCancelled(order *domain.Order) {
-	wh := s.store.GetByBrokerEvent(order.BrokerID, "order.cancelled")
-	if wh == nil {
-		return
+// to the order's broker. The delivery happens in the background.
+func (s *WebhookService) DispatchOrderCancelled(ctx context.Context, order *domain.Order) {
+	s.dispatch(ctx, order.BrokerID, "order.cancelled", s.buildOrderEventPayload("order.cancelled", order))
+}
+
+// DispatchOrderAccepted dispatches an order.accepted notification to the
+// order's broker once a new order has been matched and rests on the book.
+// The delivery happens in the background.
+func (s *WebhookService) DispatchOrderAccepted(ctx context.Context, order *domain.Order) {
+	s.dispatch(ctx, order.BrokerID, "order.accepted", s.buildOrderEventPayload("order.accepted", order))
+}
+
+// dispatch assigns the event the broker's next sequence number, publishes
+// it to the broker's event stream, and queues a delivery to each matching
+// subscription, rendered at the subscription's payload version. The
+// fan-out is traced under ctx.
+func (s *WebhookService) dispatch(ctx context.Context, brokerID, event string, payload eventPayload) {
+	ctx, span := tracer().Start(ctx, "webhook.dispatch", trace.WithAttributes(
+		attribute.String("webhook.event", event),
+		attribute.String("webhook.broker_id", brokerID),
+	))
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequences[brokerID]++
+	seq := s.sequences[brokerID]
+	payload.setSequence(seq)
+	span.SetAttributes(attribute.Int64("webhook.sequence", int64(seq)))
+
+	if s.events != nil {
+		s.events.Publish(brokerID, event, payload)
 	}
 
-	payload := s.buildOrderEventPayload("order.cancelled", order)
-	go s.deliver(wh, "order.cancelled", payload)
+	subject := payload.subject()
+	queued := 0
+	for _, wh := range s.store.ListByBrokerEvent(brokerID, event) {
+		if wh.Status == domain.WebhookStatusPaused || !subject.matches(wh.Filters) {
+			continue
+		}
+		s.enqueue(ctx, wh, event, seq, payloadAt(payload, wh.Version))
+		queued++
+	}
+	span.SetAttributes(attribute.Int("webhook.deliveries", queued))
 }
 
-// buildOrderEventPayload creates the JSON payload for order.expired and order.cancelled events.
-func (s *WebhookService) buildOrderEventPayload(event string, order *domain.Order) orderEventPayload {
-	return orderEventPayload{
+// buildOrderEventPayload creates the JSON payload for order lifecycle events.
+func (s *WebhookService) buildOrderEventPayload(event string, order *domain.Order) *orderEventPayload {
+	return &orderEventPayload{
 		Event:     event,
-		Timestamp: time.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		Version:   webhookEvents[event],
+		Timestamp: s.clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
 		Data: orderEventData{
 			BrokerID:          order.BrokerID,
 			OrderID:           order.OrderID,
@@ -247,27 +542,68 @@ func (s *WebhookService) buildOrderEventPayload(event string, order *domain.Orde
 	}
 }
 
-// deliver sends the webhook payload via HTTP POST with the required headers.
-// Errors are silently ignored (fire-and-forget).
-func (s *WebhookService) deliver(wh *domain.Webhook, eventType string, payload interface{}) {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return
+// parseWebhookFilters validates subscription filters and converts them to
+// their domain form, with symbols deduplicated and sorted.
+func parseWebhookFilters(in WebhookFiltersInput) (domain.WebhookFilters, error) {
+	var f domain.WebhookFilters
+
+	if len(in.Symbols) > maxFilterSymbols {
+		return f, &domain.ValidationError{
+			Message: fmt.Sprintf("filters.symbols must have at most %d symbols", maxFilterSymbols),
+		}
+	}
+	for _, symbol := range in.Symbols {
+		if !orderSymbolRegex.MatchString(symbol) {
+			return f, &domain.ValidationError{
+				Message: "filters.symbols entries must match ^[A-Z]{1,10}$",
+			}
+		}
+		if !slices.Contains(f.Symbols, symbol) {
+			f.Symbols = append(f.Symbols, symbol)
+		}
+	}
+	slices.Sort(f.Symbols)
+
+	switch side := domain.OrderSide(in.Side); side {
+	case "", domain.OrderSideBid, domain.OrderSideAsk:
+		f.Side = side
+	default:
+		return f, &domain.ValidationError{
+			Message: "filters.side must be 'bid' or 'ask'",
+		}
 	}
 
-	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
-	if err != nil {
-		return
+	if in.MinNotional != nil {
+		if *in.MinNotional <= 0 {
+			return f, &domain.ValidationError{Message: "filters.min_notional must be greater than 0"}
+		}
+		cents, err := domain.DollarsToCents(*in.MinNotional)
+		if err != nil {
+			return f, &domain.ValidationError{Message: "filters.min_notional must have at most 2 decimal places"}
+		}
+		f.MinNotional = cents
 	}
+	return f, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Delivery-Id", uuid.New().String())
-	req.Header.Set("X-Webhook-Id", wh.WebhookID)
-	req.Header.Set("X-Event-Type", eventType)
+// validateWebhookVersion checks that a subscription to event may receive
+// payload version, where 0 means the latest.
+func validateWebhookVersion(version int, event string) error {
+	latest := webhookEvents[event]
+	if version < 0 || version > latest {
+		return &domain.ValidationError{
+			Message: fmt.Sprintf("Unsupported version %d for event %s. Must be between 1 and %d", version, event, latest),
+		}
+	}
+	return nil
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return
+// newWebhookSecret returns a new signing secret: the prefix followed by 256
+// random bits in unpadded base64url.
+func newWebhookSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating webhook secret: %w", err)
 	}
-	resp.Body.Close()
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b[:]), nil
 }
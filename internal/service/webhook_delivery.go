@@ -0,0 +1,632 @@
+package service
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/pkg/webhook"
+	"github.com/google/uuid"
+)
+
+// DeliveryPolicy controls how webhook deliveries are attempted and retried.
+// Zero fields take the defaults below.
+type DeliveryPolicy struct {
+	MaxAttempts int           // attempts before a delivery fails
+	MaxAge      time.Duration // time after queueing before a delivery fails
+	Workers     int           // attempts in flight at once, across all webhooks
+	BaseDelay   time.Duration // wait before the second attempt; doubles after each failure
+	MaxDelay    time.Duration // longest wait between attempts
+
+	// An endpoint's circuit opens after BreakerThreshold consecutive failed
+	// attempts. While it is open, deliveries to the endpoint wait instead of
+	// being attempted, and one attempt per BreakerCooldown probes whether it
+	// has recovered.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Default delivery policy values.
+const (
+	DefaultDeliveryMaxAttempts = 8
+	DefaultDeliveryMaxAge      = 24 * time.Hour
+	DefaultDeliveryWorkers     = 16
+	defaultDeliveryBaseDelay   = time.Second
+	defaultDeliveryMaxDelay    = 10 * time.Minute
+	defaultBreakerThreshold    = 5
+	defaultBreakerCooldown     = 30 * time.Second
+)
+
+const (
+	// deliveryHistoryLimit is how many of a delivery's attempts its history
+	// keeps.
+	deliveryHistoryLimit = 20
+
+	// responseSnippetLimit is how much of a response body an attempt records.
+	responseSnippetLimit = 512
+)
+
+// withDefaults returns p with its zero fields set to the defaults.
+func (p DeliveryPolicy) withDefaults() DeliveryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultDeliveryMaxAttempts
+	}
+	if p.MaxAge <= 0 {
+		p.MaxAge = DefaultDeliveryMaxAge
+	}
+	if p.Workers <= 0 {
+		p.Workers = DefaultDeliveryWorkers
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultDeliveryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultDeliveryMaxDelay
+	}
+	if p.BreakerThreshold <= 0 {
+		p.BreakerThreshold = defaultBreakerThreshold
+	}
+	if p.BreakerCooldown <= 0 {
+		p.BreakerCooldown = defaultBreakerCooldown
+	}
+	return p
+}
+
+// backoff returns the wait after the nth failed attempt: BaseDelay doubled
+// n-1 times and capped at MaxDelay, of which a random half is dropped so
+// that deliveries which failed together do not retry together.
+func (p DeliveryPolicy) backoff(n int) time.Duration {
+	d := p.MaxDelay
+	if n-1 < 32 {
+		if b := p.BaseDelay << (n - 1); b > 0 && b < d {
+			d = b
+		}
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// circuit tracks the recent failures of one webhook endpoint.
+type circuit struct {
+	failures  int       // consecutive failed attempts
+	openUntil time.Time // once failures reaches the threshold, attempts wait until then
+}
+
+// endpointKey identifies a delivery queue: a broker's subscriptions to the
+// same URL share one, whatever events they cover.
+type endpointKey struct {
+	brokerID string
+	url      string
+}
+
+// scheduledDelivery is the delivery at the head of an endpoint's queue,
+// waiting for its next attempt.
+type scheduledDelivery struct {
+	key        endpointKey
+	deliveryID string
+	at         time.Time
+}
+
+// endpointQueue holds a broker's pending deliveries to one URL in the order
+// their events occurred. Only the head, ids[0], is scheduled or attempted;
+// the rest wait until it succeeds or fails.
+type endpointQueue struct {
+	ids []string
+}
+
+// deliveryQueue is a min-heap of scheduled deliveries, earliest first. It
+// holds at most one delivery per endpoint queue, except for a stale entry
+// left by unblock, which is dropped when it falls due.
+type deliveryQueue []scheduledDelivery
+
+func (q deliveryQueue) Len() int           { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q deliveryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x any)        { *q = append(*q, x.(scheduledDelivery)) }
+func (q *deliveryQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Valid values for the status filter of ListDeliveries.
+var validDeliveryStatuses = map[domain.DeliveryStatus]bool{
+	domain.DeliveryStatusPending:   true,
+	domain.DeliveryStatusSucceeded: true,
+	domain.DeliveryStatusFailed:    true,
+}
+
+// ListDeliveries returns a webhook's pending deliveries and its most recent
+// succeeded and failed ones, oldest first, each with the history of its
+// attempts. A non-empty status returns only the deliveries with that status.
+func (s *WebhookService) ListDeliveries(webhookID, status string) ([]*domain.WebhookDelivery, error) {
+	if status != "" && !validDeliveryStatuses[domain.DeliveryStatus(status)] {
+		return nil, &domain.ValidationError{Message: "status must be one of: pending, succeeded, failed"}
+	}
+	if _, err := s.store.Get(webhookID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.ListByWebhook(webhookID, domain.DeliveryStatus(status)), nil
+}
+
+// Redeliver queues a failed delivery again, with a fresh retry budget: its
+// attempts and age are counted again from now. It joins the back of the
+// endpoint's queue and keeps its sequence number, so it arrives after events
+// that came later. It returns
+// domain.ErrDeliveryNotFound if the webhook has no such delivery and
+// domain.ErrDeliveryNotFailed if the delivery has not failed.
+func (s *WebhookService) Redeliver(webhookID, deliveryID string) (*domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	wh, err := s.store.Get(webhookID)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	d, err := s.deliveries.Get(deliveryID)
+	if err != nil || d.WebhookID != webhookID {
+		s.mu.Unlock()
+		return nil, domain.ErrDeliveryNotFound
+	}
+	if d.Status != domain.DeliveryStatusFailed {
+		s.mu.Unlock()
+		return nil, domain.ErrDeliveryNotFailed
+	}
+
+	now := time.Now().UTC()
+	d.Status = domain.DeliveryStatusPending
+	d.QueuedAt = now
+	d.Attempts = 0
+	d.NextAttemptAt = time.Time{}
+	d.CompletedAt = nil
+	if s.push(endpointKey{wh.BrokerID, wh.URL}, d.DeliveryID, now) {
+		d.NextAttemptAt = now
+	}
+	s.deliveries.Save(d)
+	s.mu.Unlock()
+
+	s.wakeScheduler()
+	return d, nil
+}
+
+// pingPayload is the JSON payload for webhook.ping deliveries.
+type pingPayload struct {
+	Event     string   `json:"event"`
+	Timestamp string   `json:"timestamp"`
+	Data      pingData `json:"data"`
+}
+
+type pingData struct {
+	WebhookID       string `json:"webhook_id"`
+	BrokerID        string `json:"broker_id"`
+	SubscribedEvent string `json:"subscribed_event"`
+}
+
+// Ping sends a webhook.ping event to the webhook straight away, signed like
+// any other delivery, and returns the delivery with the outcome. The ping is
+// made once, outside the endpoint's queue and circuit breaker,
+// and is kept in the webhook's deliveries like any other.
+func (s *WebhookService) Ping(webhookID string) (*domain.WebhookDelivery, error) {
+	wh, err := s.store.Get(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	body, err := json.Marshal(pingPayload{
+		Event:     "webhook.ping",
+		Timestamp: now.Truncate(time.Second).Format(time.RFC3339),
+		Data: pingData{
+			WebhookID:       wh.WebhookID,
+			BrokerID:        wh.BrokerID,
+			SubscribedEvent: wh.Event,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	d := &domain.WebhookDelivery{
+		DeliveryID: uuid.New().String(),
+		WebhookID:  wh.WebhookID,
+		BrokerID:   wh.BrokerID,
+		Event:      "webhook.ping",
+		Payload:    body,
+		CreatedAt:  now,
+		QueuedAt:   now,
+	}
+
+	ctx, span := s.startDeliverySpan("webhook.ping", wh, d)
+	a, err := s.send(ctx, wh, d)
+	endDeliverySpan(span, a, err)
+	s.metrics.ObserveWebhookAttempt(d.Event, err == nil, a.Latency)
+	recordAttempt(d, a)
+	completed := time.Now().UTC()
+	d.CompletedAt = &completed
+	if err == nil {
+		d.Status = domain.DeliveryStatusSucceeded
+	} else {
+		d.Status = domain.DeliveryStatusFailed
+		d.LastError = err.Error()
+	}
+	s.metrics.WebhookDeliveryCompleted(d.Event, d.Status)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.store.Get(wh.WebhookID); err == nil {
+		s.deliveries.Save(d)
+	}
+	return d, nil
+}
+
+// Close stops delivering webhooks and waits for the delivery goroutines to
+// exit. Attempts in flight are cancelled and not counted. Pending deliveries
+// stay in the store but are no longer attempted.
+func (s *WebhookService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// start launches the scheduler and the delivery workers.
+func (s *WebhookService) start() {
+	s.wg.Add(1 + s.policy.Workers)
+	go s.runScheduler()
+	for i := 0; i < s.policy.Workers; i++ {
+		go s.runWorker()
+	}
+}
+
+// enqueue stores a new delivery of payload to wh, carrying the trace context
+// of ctx, and adds it to the back of the queue for wh's broker and URL. The
+// caller must hold s.mu.
+func (s *WebhookService) enqueue(ctx context.Context, wh *domain.Webhook, event string, seq uint64, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	now := time.Now().UTC()
+	d := &domain.WebhookDelivery{
+		DeliveryID: uuid.New().String(),
+		WebhookID:  wh.WebhookID,
+		BrokerID:   wh.BrokerID,
+		Event:      event,
+		Sequence:   seq,
+		Payload:    body,
+		Status:     domain.DeliveryStatusPending,
+		CreatedAt:  now,
+		QueuedAt:   now,
+	}
+	if len(carrier) > 0 {
+		d.TraceContext = carrier
+	}
+	if s.push(endpointKey{wh.BrokerID, wh.URL}, d.DeliveryID, now) {
+		d.NextAttemptAt = now
+	}
+	s.deliveries.Save(d)
+	s.wakeScheduler()
+}
+
+// push adds a delivery to the back of the endpoint's queue and reports
+// whether it is the head, in which case it is scheduled for at. The caller
+// must hold s.mu.
+func (s *WebhookService) push(key endpointKey, deliveryID string, at time.Time) bool {
+	q := s.endpointQueues[key]
+	if q == nil {
+		q = &endpointQueue{}
+		s.endpointQueues[key] = q
+	}
+	q.ids = append(q.ids, deliveryID)
+	if len(q.ids) > 1 {
+		return false
+	}
+	heap.Push(&s.queue, scheduledDelivery{key: key, deliveryID: deliveryID, at: at})
+	return true
+}
+
+// advance removes deliveryID from the head of the endpoint's queue, once it
+// has succeeded, failed or gone, and schedules the next delivery straight
+// away. It does nothing if deliveryID is no longer the head. The caller must
+// hold s.mu.
+func (s *WebhookService) advance(key endpointKey, deliveryID string) {
+	q := s.endpointQueues[key]
+	if q == nil || q.ids[0] != deliveryID {
+		return
+	}
+	q.ids = q.ids[1:]
+	if len(q.ids) == 0 {
+		delete(s.endpointQueues, key)
+		return
+	}
+	heap.Push(&s.queue, scheduledDelivery{key: key, deliveryID: q.ids[0], at: time.Now()})
+	s.wakeScheduler()
+}
+
+// unblock reschedules the head of the endpoint's queue for now if its
+// delivery has been deleted, so that a deleted webhook's delivery waiting to
+// be retried does not hold up the broker's other subscriptions to the URL.
+// The caller must hold s.mu.
+func (s *WebhookService) unblock(key endpointKey) {
+	q := s.endpointQueues[key]
+	if q == nil {
+		return
+	}
+	if _, err := s.deliveries.Get(q.ids[0]); err == nil {
+		return
+	}
+	heap.Push(&s.queue, scheduledDelivery{key: key, deliveryID: q.ids[0], at: time.Now()})
+	s.wakeScheduler()
+}
+
+// wakeScheduler tells the scheduler the queue has changed.
+func (s *WebhookService) wakeScheduler() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runScheduler hands deliveries to the workers as they fall due. When all
+// workers are busy it waits for one, so attempts never exceed
+// DeliveryPolicy.Workers however many deliveries are waiting. Since only the
+// head of each endpoint's queue is scheduled, a broker's deliveries to a URL
+// are attempted one at a time, in order, across all of its subscriptions to
+// it, while other brokers' and other URLs' run in parallel. A failing head
+// holds up its queue until it fails, once its retry budget is spent.
+func (s *WebhookService) runScheduler() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		var due []scheduledDelivery
+		for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+			due = append(due, heap.Pop(&s.queue).(scheduledDelivery))
+		}
+		wait := time.Hour
+		if len(s.queue) > 0 {
+			wait = s.queue[0].at.Sub(now)
+		}
+		s.mu.Unlock()
+
+		if len(due) > 0 {
+			for _, sd := range due {
+				select {
+				case s.due <- sd:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			// Handing them over may have taken a while; look again.
+			continue
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// runWorker attempts deliveries handed over by the scheduler.
+func (s *WebhookService) runWorker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case sd := <-s.due:
+			s.attempt(sd)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt makes one attempt of the delivery at the head of an endpoint's queue
+// and records the outcome: the delivery succeeds, is scheduled for another
+// attempt, or fails.
+func (s *WebhookService) attempt(sd scheduledDelivery) {
+	d, err := s.deliveries.Get(sd.deliveryID)
+	if err != nil || d.Status != domain.DeliveryStatusPending {
+		s.finishAttempt(sd, nil, time.Time{})
+		return
+	}
+	wh, err := s.store.Get(d.WebhookID)
+	if err != nil {
+		s.finishAttempt(sd, nil, time.Time{})
+		return
+	}
+
+	if ok, retryAt := s.allow(wh.URL, time.Now()); !ok {
+		if d.LastError == "" {
+			d.LastError = "circuit open: endpoint has been failing"
+		}
+		s.finishAttempt(sd, d, retryAt)
+		return
+	}
+
+	ctx, span := s.startDeliverySpan("webhook.deliver", wh, d)
+	a, err := s.send(ctx, wh, d)
+	endDeliverySpan(span, a, err)
+	if s.ctx.Err() != nil {
+		// Shutting down: the attempt was cut short, so it does not count.
+		return
+	}
+	now := time.Now().UTC()
+	s.record(wh.URL, err == nil, now)
+	s.metrics.ObserveWebhookAttempt(d.Event, err == nil, a.Latency)
+
+	recordAttempt(d, a)
+	if err == nil {
+		d.Status = domain.DeliveryStatusSucceeded
+		d.LastError = ""
+		d.CompletedAt = &now
+		s.finishAttempt(sd, d, time.Time{})
+		return
+	}
+	d.LastError = err.Error()
+	s.finishAttempt(sd, d, now.Add(s.policy.backoff(d.Attempts)))
+}
+
+// recordAttempt adds an attempt to a delivery's count, last attempt fields
+// and history.
+func recordAttempt(d *domain.WebhookDelivery, a domain.DeliveryAttempt) {
+	d.Attempts++
+	d.LastAttemptAt = &a.AttemptedAt
+	d.LastStatusCode = a.StatusCode
+	d.History = append(d.History, a)
+	if n := len(d.History); n > deliveryHistoryLimit {
+		d.History = d.History[n-deliveryHistoryLimit:]
+	}
+}
+
+// finishAttempt saves the head of an endpoint's queue after an attempt. A
+// succeeded delivery is kept as a record. A pending one is scheduled again for
+// retryAt, unless it has used up its attempts or retryAt is past its age
+// limit, in which case it fails. A nil delivery, or one whose webhook was
+// deleted in the meantime, is dropped. Unless the delivery is scheduled
+// again, the endpoint's next delivery moves up.
+func (s *WebhookService) finishAttempt(sd scheduledDelivery, d *domain.WebhookDelivery, retryAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d == nil {
+		s.advance(sd.key, sd.deliveryID)
+		return
+	}
+	if _, err := s.store.Get(d.WebhookID); err != nil {
+		s.advance(sd.key, sd.deliveryID)
+		return
+	}
+	if d.Status == domain.DeliveryStatusSucceeded {
+		s.deliveries.Save(d)
+		s.metrics.WebhookDeliveryCompleted(d.Event, d.Status)
+		s.advance(sd.key, sd.deliveryID)
+		return
+	}
+
+	if d.Attempts >= s.policy.MaxAttempts || retryAt.Sub(d.QueuedAt) > s.policy.MaxAge {
+		now := time.Now().UTC()
+		d.Status = domain.DeliveryStatusFailed
+		d.NextAttemptAt = time.Time{}
+		d.CompletedAt = &now
+		s.deliveries.Save(d)
+		s.metrics.WebhookDeliveryCompleted(d.Event, d.Status)
+		s.advance(sd.key, sd.deliveryID)
+		return
+	}
+
+	d.NextAttemptAt = retryAt.UTC()
+	s.deliveries.Save(d)
+	sd.at = retryAt
+	heap.Push(&s.queue, sd)
+	s.wakeScheduler()
+}
+
+// allow reports whether an attempt to url may go ahead at now. When it may
+// not, it also returns when the endpoint's circuit lets the next attempt
+// through.
+func (s *WebhookService) allow(url string, now time.Time) (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.circuits[url]
+	if c == nil || c.failures < s.policy.BreakerThreshold {
+		return true, time.Time{}
+	}
+	if now.Before(c.openUntil) {
+		return false, c.openUntil
+	}
+	// Let this attempt probe the endpoint, and hold the others back for
+	// another cooldown in case it is still down.
+	c.openUntil = now.Add(s.policy.BreakerCooldown)
+	return true, time.Time{}
+}
+
+// record updates url's circuit with the outcome of an attempt. A success
+// closes it; a failure at or past the threshold (re)opens it.
+func (s *WebhookService) record(url string, ok bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ok {
+		delete(s.circuits, url)
+		return
+	}
+	c := s.circuits[url]
+	if c == nil {
+		c = &circuit{}
+		s.circuits[url] = c
+	}
+	c.failures++
+	if c.failures >= s.policy.BreakerThreshold {
+		c.openUntil = now.Add(s.policy.BreakerCooldown)
+	}
+}
+
+// send POSTs a delivery to the webhook with the required headers, signed
+// with the webhook's secrets as pkg/webhook describes, and the W3C trace
+// context of ctx. It returns a record of the attempt, and an error unless the
+// response status was 2xx.
+func (s *WebhookService) send(ctx context.Context, wh *domain.Webhook, d *domain.WebhookDelivery) (domain.DeliveryAttempt, error) {
+	now := time.Now()
+	a := domain.DeliveryAttempt{AttemptedAt: now.UTC(), URL: wh.URL}
+	fail := func(err error) (domain.DeliveryAttempt, error) {
+		a.Latency = time.Since(now)
+		a.Error = err.Error()
+		return a, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fail(err)
+	}
+
+	sigs := make([]string, 0, 2)
+	for _, secret := range wh.SigningSecrets(now) {
+		sigs = append(sigs, webhook.Sign(secret, d.DeliveryID, now.Unix(), d.Payload))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderDeliveryID, d.DeliveryID)
+	req.Header.Set(webhook.HeaderWebhookID, wh.WebhookID)
+	req.Header.Set(webhook.HeaderEventType, d.Event)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, strings.Join(sigs, ","))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	a.StatusCode = resp.StatusCode
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, responseSnippetLimit))
+	a.ResponseSnippet = strings.ToValidUTF8(string(snippet), "\uFFFD")
+	// Drain a little more of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail(fmt.Errorf("unexpected status %d", resp.StatusCode))
+	}
+	a.Latency = time.Since(now)
+	return a, nil
+}
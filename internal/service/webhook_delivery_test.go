@@ -0,0 +1,719 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/metrics"
+	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/pkg/webhook"
+)
+
+// fastRetries retries almost immediately and never trips the circuit
+// breaker unless a test says otherwise.
+var fastRetries = DeliveryPolicy{
+	MaxAttempts:      3,
+	BaseDelay:        time.Millisecond,
+	MaxDelay:         5 * time.Millisecond,
+	BreakerThreshold: 1000,
+}
+
+// waitFor polls cond until it holds or a few seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newDeliveryTestEnv registers broker-1 with an order.cancelled webhook
+// pointing at a TLS server that answers with the status returned by status.
+// It returns the service, the webhook ID and the number of requests served.
+func newDeliveryTestEnv(t *testing.T, policy DeliveryPolicy, status func(n int64) int) (*WebhookService, string, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status(requests.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), policy)
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc, webhooks[0].WebhookID, &requests
+}
+
+func cancelledOrder() *domain.Order {
+	return &domain.Order{OrderID: "ord-1", BrokerID: "broker-1", Symbol: "AAPL", Status: domain.OrderStatusCancelled}
+}
+
+func failedDeliveries(t *testing.T, svc *WebhookService, webhookID string) []*domain.WebhookDelivery {
+	t.Helper()
+	list, err := svc.ListDeliveries(webhookID, "failed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return list
+}
+
+func TestDeliver_RetriesUntilSuccess(t *testing.T) {
+	var mu sync.Mutex
+	var ids []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, r.Header.Get(webhook.HeaderDeliveryID))
+		if len(ids) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), fastRetries)
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled"},
+	})
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	var succeeded []*domain.WebhookDelivery
+	waitFor(t, "delivery to succeed", func() bool {
+		succeeded, _ = svc.ListDeliveries(webhooks[0].WebhookID, "succeeded")
+		return len(succeeded) == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ids) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(ids))
+	}
+	// Every attempt carries the same delivery ID, so receivers can dedupe.
+	if ids[0] == "" || ids[1] != ids[0] || ids[2] != ids[0] {
+		t.Errorf("delivery IDs differ between attempts: %v", ids)
+	}
+
+	// The succeeded delivery is kept, with every attempt in its history.
+	d := succeeded[0]
+	if d.DeliveryID != ids[0] || d.Attempts != 3 || d.LastStatusCode != http.StatusNoContent || d.CompletedAt == nil {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if len(d.History) != 3 {
+		t.Fatalf("got %d attempts in history, want 3", len(d.History))
+	}
+	for i, want := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusNoContent} {
+		a := d.History[i]
+		if a.StatusCode != want || a.URL != server.URL+"/hooks" || a.AttemptedAt.IsZero() || a.Latency <= 0 {
+			t.Errorf("attempt %d: unexpected %+v", i, a)
+		}
+		if (a.Error == "") != (want == http.StatusNoContent) {
+			t.Errorf("attempt %d: unexpected error %q", i, a.Error)
+		}
+	}
+}
+
+func TestDeliver_RecordsResponseSnippet(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"unknown order"}`)
+		io.WriteString(w, strings.Repeat("x", 2*responseSnippetLimit))
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{MaxAttempts: 1})
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled"},
+	})
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to fail", func() bool {
+		return len(failedDeliveries(t, svc, webhooks[0].WebhookID)) == 1
+	})
+
+	history := failedDeliveries(t, svc, webhooks[0].WebhookID)[0].History
+	if len(history) != 1 {
+		t.Fatalf("got %d attempts in history, want 1", len(history))
+	}
+	a := history[0]
+	if a.StatusCode != http.StatusBadRequest || a.Error != "unexpected status 400" {
+		t.Errorf("unexpected attempt %+v", a)
+	}
+	if len(a.ResponseSnippet) != responseSnippetLimit || !strings.HasPrefix(a.ResponseSnippet, `{"error":"unknown order"}xxx`) {
+		t.Errorf("unexpected response snippet %q", a.ResponseSnippet)
+	}
+}
+
+func TestDeliver_RecordsMetrics(t *testing.T) {
+	svc, webhookID, _ := newDeliveryTestEnv(t, fastRetries, func(n int64) int {
+		if n == 1 {
+			return http.StatusBadGateway
+		}
+		return http.StatusOK
+	})
+	mx := metrics.New()
+	svc.metrics = mx
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to succeed", func() bool {
+		succeeded, _ := svc.ListDeliveries(webhookID, "succeeded")
+		return len(succeeded) == 1
+	})
+
+	rr := httptest.NewRecorder()
+	mx.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, line := range []string{
+		`miniexchange_webhook_delivery_attempts_total{event="order.cancelled",outcome="failure"} 1`,
+		`miniexchange_webhook_delivery_attempts_total{event="order.cancelled",outcome="success"} 1`,
+		`miniexchange_webhook_delivery_attempt_duration_seconds_count{event="order.cancelled"} 2`,
+		`miniexchange_webhook_deliveries_total{event="order.cancelled",status="succeeded"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %q", line)
+		}
+	}
+}
+
+func TestRecordAttempt_BoundsHistory(t *testing.T) {
+	d := &domain.WebhookDelivery{}
+	for i := 1; i <= deliveryHistoryLimit+5; i++ {
+		recordAttempt(d, domain.DeliveryAttempt{StatusCode: i})
+	}
+	if d.Attempts != deliveryHistoryLimit+5 || d.LastStatusCode != deliveryHistoryLimit+5 {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if len(d.History) != deliveryHistoryLimit {
+		t.Fatalf("got %d attempts in history, want %d", len(d.History), deliveryHistoryLimit)
+	}
+	if d.History[0].StatusCode != 6 || d.History[deliveryHistoryLimit-1].StatusCode != deliveryHistoryLimit+5 {
+		t.Errorf("history does not keep the latest attempts: first %d, last %d",
+			d.History[0].StatusCode, d.History[deliveryHistoryLimit-1].StatusCode)
+	}
+}
+
+func TestDeliver_FailsAfterMaxAttempts(t *testing.T) {
+	svc, webhookID, requests := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		return http.StatusInternalServerError
+	})
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to fail", func() bool {
+		return len(failedDeliveries(t, svc, webhookID)) == 1
+	})
+
+	d := failedDeliveries(t, svc, webhookID)[0]
+	if d.Attempts != 3 || requests.Load() != 3 {
+		t.Errorf("got %d attempts and %d requests, want 3", d.Attempts, requests.Load())
+	}
+	if d.LastStatusCode != http.StatusInternalServerError || d.LastError == "" {
+		t.Errorf("unexpected last result: %d %q", d.LastStatusCode, d.LastError)
+	}
+	if d.Event != "order.cancelled" || d.LastAttemptAt == nil || d.CompletedAt == nil || !d.NextAttemptAt.IsZero() {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+}
+
+func TestDeliver_FailsAfterMaxAge(t *testing.T) {
+	policy := fastRetries
+	policy.MaxAttempts = 100
+	policy.MaxAge = 50 * time.Millisecond
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+	svc, webhookID, requests := newDeliveryTestEnv(t, policy, func(int64) int {
+		return http.StatusBadGateway
+	})
+
+	// The first retry would come after the age limit, so the delivery fails
+	// straight away instead of waiting for it.
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to fail", func() bool {
+		return len(failedDeliveries(t, svc, webhookID)) == 1
+	})
+	if got := failedDeliveries(t, svc, webhookID)[0].Attempts; got != 1 || requests.Load() != 1 {
+		t.Errorf("got %d attempts and %d requests, want 1", got, requests.Load())
+	}
+}
+
+func TestDeliver_CircuitBreakerHoldsDeliveries(t *testing.T) {
+	policy := fastRetries
+	policy.MaxAttempts = 10
+	policy.BreakerThreshold = 2
+	policy.BreakerCooldown = time.Hour
+	svc, webhookID, requests := newDeliveryTestEnv(t, policy, func(int64) int {
+		return http.StatusInternalServerError
+	})
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "the circuit to open", func() bool {
+		list, _ := svc.ListDeliveries(webhookID, "pending")
+		return len(list) == 1 && time.Until(list[0].NextAttemptAt) > time.Minute
+	})
+
+	// No attempt is made while the circuit is open.
+	time.Sleep(50 * time.Millisecond)
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+	list, _ := svc.ListDeliveries(webhookID, "pending")
+	if list[0].Attempts != 2 || list[0].LastError == "" {
+		t.Errorf("held delivery: got %d attempts and error %q", list[0].Attempts, list[0].LastError)
+	}
+}
+
+func TestCircuit_ProbesAfterCooldown(t *testing.T) {
+	svc, _ := newTestWebhookService(t)
+	svc.policy.BreakerThreshold = 2
+	svc.policy.BreakerCooldown = time.Minute
+	const url = "https://example.com/hooks"
+	now := time.Now()
+
+	svc.record(url, false, now)
+	if ok, _ := svc.allow(url, now); !ok {
+		t.Fatal("circuit opened below the threshold")
+	}
+	svc.record(url, false, now)
+	if ok, retryAt := svc.allow(url, now); ok || !retryAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("got %v, %v; want circuit open until %v", ok, retryAt, now.Add(time.Minute))
+	}
+
+	// After the cooldown one attempt probes; the rest keep waiting.
+	later := now.Add(time.Minute)
+	if ok, _ := svc.allow(url, later); !ok {
+		t.Fatal("probe not allowed after cooldown")
+	}
+	if ok, _ := svc.allow(url, later); ok {
+		t.Fatal("second attempt allowed while probing")
+	}
+
+	// A successful probe closes the circuit.
+	svc.record(url, true, later)
+	if ok, _ := svc.allow(url, later); !ok {
+		t.Error("circuit still open after a success")
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	var healthy atomic.Bool
+	svc, webhookID, requests := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		if healthy.Load() {
+			return http.StatusOK
+		}
+		return http.StatusInternalServerError
+	})
+
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to fail", func() bool {
+		return len(failedDeliveries(t, svc, webhookID)) == 1
+	})
+	failed := failedDeliveries(t, svc, webhookID)[0]
+
+	healthy.Store(true)
+	d, err := svc.Redeliver(webhookID, failed.DeliveryID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Status != domain.DeliveryStatusPending || d.Attempts != 0 || !d.QueuedAt.After(failed.QueuedAt) {
+		t.Errorf("unexpected redelivery %+v", d)
+	}
+	var succeeded []*domain.WebhookDelivery
+	waitFor(t, "redelivery to succeed", func() bool {
+		succeeded, _ = svc.ListDeliveries(webhookID, "succeeded")
+		return len(succeeded) == 1
+	})
+	if got := requests.Load(); got != 4 {
+		t.Errorf("got %d requests, want 4", got)
+	}
+	// The history spans the redelivery.
+	if h := succeeded[0].History; len(h) != 4 || h[2].StatusCode != http.StatusInternalServerError || h[3].StatusCode != http.StatusOK {
+		t.Errorf("unexpected history %+v", h)
+	}
+}
+
+func TestRedeliver_Errors(t *testing.T) {
+	svc, webhookID, _ := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		return http.StatusOK
+	})
+	svc.deliveries.Save(&domain.WebhookDelivery{
+		DeliveryID: "d-pending",
+		WebhookID:  webhookID,
+		Status:     domain.DeliveryStatusPending,
+	})
+	svc.deliveries.Save(&domain.WebhookDelivery{
+		DeliveryID: "d-other",
+		WebhookID:  "wh-other",
+		Status:     domain.DeliveryStatusFailed,
+	})
+
+	if _, err := svc.Redeliver(webhookID, "missing"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("unknown delivery: expected ErrDeliveryNotFound, got %v", err)
+	}
+	if _, err := svc.Redeliver(webhookID, "d-other"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("another webhook's delivery: expected ErrDeliveryNotFound, got %v", err)
+	}
+	if _, err := svc.Redeliver(webhookID, "d-pending"); !errors.Is(err, domain.ErrDeliveryNotFailed) {
+		t.Errorf("pending delivery: expected ErrDeliveryNotFailed, got %v", err)
+	}
+}
+
+func TestListDeliveries_Errors(t *testing.T) {
+	svc, webhookID, _ := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		return http.StatusOK
+	})
+
+	var validationErr *domain.ValidationError
+	if _, err := svc.ListDeliveries(webhookID, "delivered"); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+	if _, err := svc.ListDeliveries("missing", ""); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+func TestDelete_RemovesDeliveries(t *testing.T) {
+	svc, webhookID, _ := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		return http.StatusInternalServerError
+	})
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	waitFor(t, "delivery to fail", func() bool {
+		return len(failedDeliveries(t, svc, webhookID)) == 1
+	})
+
+	if err := svc.Delete(webhookID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list := svc.deliveries.ListByWebhook(webhookID, ""); len(list) != 0 {
+		t.Errorf("deliveries left after delete: %+v", list)
+	}
+}
+
+func TestDeliveryPolicy_Backoff(t *testing.T) {
+	p := DeliveryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+	for n, full := range map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		4:   8 * time.Second,
+		7:   time.Minute, // 64s, capped
+		100: time.Minute,
+	} {
+		for i := 0; i < 50; i++ {
+			if got := p.backoff(n); got < full/2 || got > full {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", n, got, full/2, full)
+			}
+		}
+	}
+}
+
+func TestDispatch_SequenceNumbersPerBroker(t *testing.T) {
+	events := newTestEventStreamService(10)
+	registerBroker(t, events.brokerStore, "b2")
+	svc := NewWebhookService(store.NewWebhookStore(), store.NewDeliveryStore(100, 100), events.brokerStore, 0, DeliveryPolicy{}, events, nil, nil)
+	defer svc.Close()
+	sub1, _, _ := events.Subscribe("b1", nil)
+	sub2, _, _ := events.Subscribe("b2", nil)
+
+	order1 := &domain.Order{OrderID: "o1", BrokerID: "b1", Symbol: "AAPL", Status: domain.OrderStatusPending}
+	order2 := &domain.Order{OrderID: "o2", BrokerID: "b2", Symbol: "AAPL", Status: domain.OrderStatusPending}
+	svc.DispatchOrderAccepted(context.Background(), order1)
+	svc.DispatchOrderAccepted(context.Background(), order2)
+	svc.DispatchOrderCancelled(context.Background(), order1)
+
+	for _, tc := range []struct {
+		sub  *EventSubscription
+		want []uint64
+	}{{sub1, []uint64{1, 2}}, {sub2, []uint64{1}}} {
+		for _, want := range tc.want {
+			ev := nextEvent(t, tc.sub)
+			var payload orderEventPayload
+			if err := json.Unmarshal(ev.Data, &payload); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+			// The sequence number matches the event stream ID.
+			if payload.Sequence != want || ev.ID != want {
+				t.Errorf("%s: got sequence %d and event ID %d, want %d", payload.Data.BrokerID, payload.Sequence, ev.ID, want)
+			}
+		}
+	}
+}
+
+func TestDeliver_InOrderAcrossSubscriptions(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload struct {
+			Sequence uint64 `json:"sequence"`
+		}
+		json.Unmarshal(body, &payload)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, fmt.Sprintf("%d %s", payload.Sequence, r.Header.Get(webhook.HeaderEventType)))
+		// The first event fails twice before it gets through.
+		if payload.Sequence == 1 && len(received) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), fastRetries)
+	registerBroker(t, bs, "broker-1")
+	// One subscription per event, both to the same URL.
+	svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled", "order.expired"},
+	})
+
+	order := cancelledOrder()
+	svc.DispatchOrderCancelled(context.Background(), order)
+	svc.DispatchOrderExpired(context.Background(), order)
+	svc.DispatchOrderCancelled(context.Background(), order)
+	waitFor(t, "all deliveries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 5
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"1 order.cancelled", "1 order.cancelled", "1 order.cancelled", "2 order.expired", "3 order.cancelled"}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Fatalf("got deliveries %q, want %q", received, want)
+		}
+	}
+}
+
+func TestDeliver_BrokersInParallel(t *testing.T) {
+	release := make(chan struct{})
+	delivered := make(chan string, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		} else {
+			delivered <- r.URL.Path
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), fastRetries)
+	for _, id := range []string{"broker-1", "broker-2"} {
+		registerBroker(t, bs, id)
+	}
+	svc.Upsert(UpsertWebhookRequest{BrokerID: "broker-1", URL: server.URL + "/slow", Events: []string{"order.cancelled"}})
+	svc.Upsert(UpsertWebhookRequest{BrokerID: "broker-2", URL: server.URL + "/fast", Events: []string{"order.cancelled"}})
+
+	// broker-1's endpoint hangs; broker-2's delivery is not held up by it.
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	svc.DispatchOrderCancelled(context.Background(), &domain.Order{OrderID: "ord-2", BrokerID: "broker-2", Symbol: "AAPL", Status: domain.OrderStatusCancelled})
+	select {
+	case path := <-delivered:
+		if path != "/fast" {
+			t.Errorf("got delivery to %s, want /fast", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("broker-2's delivery was held up by broker-1's")
+	}
+}
+
+func TestDeliver_EndpointsIndependent(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delivered <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := fastRetries
+	policy.BaseDelay = time.Hour
+	policy.MaxDelay = time.Hour
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), policy)
+	registerBroker(t, bs, "broker-1")
+	svc.Upsert(UpsertWebhookRequest{BrokerID: "broker-1", URL: server.URL + "/broken", Events: []string{"order.cancelled"}})
+	svc.Upsert(UpsertWebhookRequest{BrokerID: "broker-1", URL: server.URL + "/ok", Events: []string{"order.expired"}})
+
+	// The cancellation waits an hour for its retry; the later expiry goes to
+	// a different URL and is not held up by it.
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	svc.DispatchOrderExpired(context.Background(), cancelledOrder())
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("order.expired held up by the failing endpoint")
+	}
+}
+
+func TestDelete_UnblocksEndpointQueue(t *testing.T) {
+	expired := make(chan struct{}, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webhook.HeaderEventType) == "order.cancelled" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		expired <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := fastRetries
+	policy.BaseDelay = time.Hour
+	policy.MaxDelay = time.Hour
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), policy)
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{BrokerID: "broker-1", URL: server.URL + "/hooks", Events: []string{"order.cancelled", "order.expired"}})
+
+	// The cancellation waits an hour for its retry, and the expiry to the
+	// same URL behind it.
+	svc.DispatchOrderCancelled(context.Background(), cancelledOrder())
+	svc.DispatchOrderExpired(context.Background(), cancelledOrder())
+	waitFor(t, "the first attempt", func() bool {
+		list, _ := svc.ListDeliveries(webhooks[0].WebhookID, "pending")
+		return len(list) == 1 && list[0].Attempts == 1
+	})
+	select {
+	case <-expired:
+		t.Fatal("order.expired delivered ahead of the earlier order.cancelled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := svc.Delete(webhooks[0].WebhookID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-expired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("order.expired still held after the blocking webhook was deleted")
+	}
+}
+
+func TestPing(t *testing.T) {
+	var got struct {
+		header  http.Header
+		payload pingPayload
+	}
+	var mu sync.Mutex
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got.header = r.Header.Clone()
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got.payload)
+		io.WriteString(w, "pong")
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), fastRetries)
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	wh := webhooks[0]
+
+	d, err := svc.Ping(wh.WebhookID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Event != "webhook.ping" || d.Status != domain.DeliveryStatusSucceeded || d.Attempts != 1 || d.Sequence != 0 {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if len(d.History) != 1 || d.History[0].StatusCode != http.StatusOK || d.History[0].ResponseSnippet != "pong" {
+		t.Errorf("unexpected history %+v", d.History)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got.payload.Event != "webhook.ping" || got.payload.Data.WebhookID != wh.WebhookID ||
+		got.payload.Data.BrokerID != "broker-1" || got.payload.Data.SubscribedEvent != "trade.executed" {
+		t.Errorf("unexpected payload %+v", got.payload)
+	}
+	if got.header.Get(webhook.HeaderEventType) != "webhook.ping" || got.header.Get(webhook.HeaderDeliveryID) != d.DeliveryID {
+		t.Errorf("unexpected headers %v", got.header)
+	}
+	// The ping is signed like any delivery.
+	if err := webhook.Verify(wh.Secret, got.header, d.Payload, time.Minute); err != nil {
+		t.Errorf("ping signature does not verify: %v", err)
+	}
+
+	// It is kept with the webhook's deliveries.
+	list, _ := svc.ListDeliveries(wh.WebhookID, "succeeded")
+	if len(list) != 1 || list[0].DeliveryID != d.DeliveryID {
+		t.Errorf("unexpected deliveries %+v", list)
+	}
+}
+
+func TestPing_Failure(t *testing.T) {
+	svc, webhookID, requests := newDeliveryTestEnv(t, fastRetries, func(int64) int {
+		return http.StatusNotFound
+	})
+
+	d, err := svc.Ping(webhookID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Status != domain.DeliveryStatusFailed || d.LastStatusCode != http.StatusNotFound || d.LastError != "unexpected status 404" {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	// A ping is not retried.
+	time.Sleep(20 * time.Millisecond)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+
+	if _, err := svc.Ping("missing"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
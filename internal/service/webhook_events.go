@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+)
+
+// webhookEvents is the catalogue of events a webhook can subscribe to, with
+// the latest version of each event's payload schema. Every payload carries
+// its version; a change that could break a receiver bumps it, and the
+// event's payload implements versionedPayload to render the older ones.
+var webhookEvents = map[string]int{
+	"order.accepted":  1,
+	"order.rejected":  1,
+	"order.amended":   1,
+	"order.filled":    2,
+	"order.expired":   1,
+	"order.cancelled": 1,
+	"trade.executed":  1,
+	"balance.changed": 1,
+	"symbol.halted":   1,
+	"symbol.resumed":  1,
+}
+
+// webhookEventNames returns the catalogue's event types, sorted, for error
+// messages.
+func webhookEventNames() string {
+	names := make([]string, 0, len(webhookEvents))
+	for event := range webhookEvents {
+		names = append(names, event)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// orderFilledPayload is the JSON payload for order.filled events, at
+// version 2.
+type orderFilledPayload struct {
+	Event     string          `json:"event"`
+	Version   int             `json:"version"`
+	Sequence  uint64          `json:"sequence"`
+	Timestamp string          `json:"timestamp"`
+	Data      orderFilledData `json:"data"`
+}
+
+func (p *orderFilledPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *orderFilledPayload) subject() eventSubject {
+	notional, _ := domain.DollarsToCents(p.Data.Notional)
+	return eventSubject{symbol: p.Data.Symbol, side: domain.OrderSide(p.Data.Side), notional: notional}
+}
+
+// atVersion renders version 1, which counted the order's fills in
+// trade_count instead of listing them.
+func (p *orderFilledPayload) atVersion(version int) any {
+	if version != 1 {
+		return p
+	}
+	d := p.Data
+	return &orderFilledPayloadV1{
+		Event:     p.Event,
+		Version:   1,
+		Sequence:  p.Sequence,
+		Timestamp: p.Timestamp,
+		Data: orderFilledDataV1{
+			BrokerID:       d.BrokerID,
+			OrderID:        d.OrderID,
+			Type:           d.Type,
+			Symbol:         d.Symbol,
+			Side:           d.Side,
+			Price:          d.Price,
+			Quantity:       d.Quantity,
+			FilledQuantity: d.FilledQuantity,
+			AveragePrice:   d.AveragePrice,
+			Notional:       d.Notional,
+			TradeCount:     len(d.Fills),
+			FilledAt:       d.FilledAt,
+		},
+	}
+}
+
+type orderFilledData struct {
+	BrokerID       string          `json:"broker_id"`
+	OrderID        string          `json:"order_id"`
+	Type           string          `json:"type"`
+	Symbol         string          `json:"symbol"`
+	Side           string          `json:"side"`
+	Price          float64         `json:"price"`
+	Quantity       int64           `json:"quantity"`
+	FilledQuantity int64           `json:"filled_quantity"`
+	AveragePrice   float64         `json:"average_price"`
+	Notional       float64         `json:"notional"`
+	Fills          []orderFillData `json:"fills"`
+	FilledAt       string          `json:"filled_at"`
+}
+
+// orderFillData is one of the trades that filled an order.
+type orderFillData struct {
+	TradeID    string  `json:"trade_id"`
+	Price      float64 `json:"price"`
+	Quantity   int64   `json:"quantity"`
+	ExecutedAt string  `json:"executed_at"`
+}
+
+// orderFilledPayloadV1 is the JSON payload for order.filled events at
+// version 1.
+type orderFilledPayloadV1 struct {
+	Event     string            `json:"event"`
+	Version   int               `json:"version"`
+	Sequence  uint64            `json:"sequence"`
+	Timestamp string            `json:"timestamp"`
+	Data      orderFilledDataV1 `json:"data"`
+}
+
+type orderFilledDataV1 struct {
+	BrokerID       string  `json:"broker_id"`
+	OrderID        string  `json:"order_id"`
+	Type           string  `json:"type"`
+	Symbol         string  `json:"symbol"`
+	Side           string  `json:"side"`
+	Price          float64 `json:"price"`
+	Quantity       int64   `json:"quantity"`
+	FilledQuantity int64   `json:"filled_quantity"`
+	AveragePrice   float64 `json:"average_price"`
+	Notional       float64 `json:"notional"`
+	TradeCount     int     `json:"trade_count"`
+	FilledAt       string  `json:"filled_at"`
+}
+
+// orderRejectedPayload is the JSON payload for order.rejected events.
+type orderRejectedPayload struct {
+	Event     string            `json:"event"`
+	Version   int               `json:"version"`
+	Sequence  uint64            `json:"sequence"`
+	Timestamp string            `json:"timestamp"`
+	Data      orderRejectedData `json:"data"`
+}
+
+func (p *orderRejectedPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *orderRejectedPayload) subject() eventSubject {
+	return eventSubject{symbol: p.Data.Symbol, side: domain.OrderSide(p.Data.Side)}
+}
+
+type orderRejectedData struct {
+	BrokerID       string   `json:"broker_id"`
+	DocumentNumber string   `json:"document_number"`
+	Type           string   `json:"type"`
+	Symbol         string   `json:"symbol"`
+	Side           string   `json:"side"`
+	Price          *float64 `json:"price"`
+	Quantity       int64    `json:"quantity"`
+	Reason         string   `json:"reason"`
+	Message        string   `json:"message"`
+}
+
+// orderAmendedPayload is the JSON payload for order.amended events.
+type orderAmendedPayload struct {
+	Event     string           `json:"event"`
+	Version   int              `json:"version"`
+	Sequence  uint64           `json:"sequence"`
+	Timestamp string           `json:"timestamp"`
+	Data      orderAmendedData `json:"data"`
+}
+
+func (p *orderAmendedPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *orderAmendedPayload) subject() eventSubject {
+	return eventSubject{symbol: p.Data.Symbol, side: domain.OrderSide(p.Data.Side)}
+}
+
+type orderAmendedData struct {
+	BrokerID         string  `json:"broker_id"`
+	OrderID          string  `json:"order_id"`
+	ReplacedOrderID  string  `json:"replaced_order_id"`
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"`
+	Price            float64 `json:"price"`
+	Quantity         int64   `json:"quantity"`
+	PreviousPrice    float64 `json:"previous_price"`
+	PreviousQuantity int64   `json:"previous_quantity"`
+	PreviousFilled   int64   `json:"previous_filled_quantity"`
+	Status           string  `json:"status"`
+}
+
+// balanceChangedPayload is the JSON payload for balance.changed events.
+type balanceChangedPayload struct {
+	Event     string             `json:"event"`
+	Version   int                `json:"version"`
+	Sequence  uint64             `json:"sequence"`
+	Timestamp string             `json:"timestamp"`
+	Data      balanceChangedData `json:"data"`
+}
+
+func (p *balanceChangedPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+// subject reports the side the broker traded on, bid when its holding grew,
+// and the trade's value.
+func (p *balanceChangedPayload) subject() eventSubject {
+	cash, _ := domain.DollarsToCents(p.Data.CashDelta)
+	side := domain.OrderSideBid
+	if p.Data.HoldingDelta < 0 {
+		side = domain.OrderSideAsk
+	}
+	if cash < 0 {
+		cash = -cash
+	}
+	return eventSubject{symbol: p.Data.Symbol, side: side, notional: cash}
+}
+
+type balanceChangedData struct {
+	BrokerID     string  `json:"broker_id"`
+	TradeID      string  `json:"trade_id"`
+	OrderID      string  `json:"order_id"`
+	Symbol       string  `json:"symbol"`
+	CashDelta    float64 `json:"cash_delta"`
+	HoldingDelta int64   `json:"holding_delta"`
+}
+
+// symbolEventPayload is the JSON payload for symbol.halted and
+// symbol.resumed events.
+type symbolEventPayload struct {
+	Event     string          `json:"event"`
+	Version   int             `json:"version"`
+	Sequence  uint64          `json:"sequence"`
+	Timestamp string          `json:"timestamp"`
+	Data      symbolEventData `json:"data"`
+}
+
+func (p *symbolEventPayload) setSequence(seq uint64) { p.Sequence = seq }
+
+func (p *symbolEventPayload) subject() eventSubject {
+	return eventSubject{symbol: p.Data.Symbol}
+}
+
+type symbolEventData struct {
+	Symbol string  `json:"symbol"`
+	Reason *string `json:"reason"`
+}
+
+// DispatchOrderMatched dispatches the notifications of order's matching
+// pass in the order the events happened: for each fill, trade.executed and
+// balance.changed to both brokers, and order.filled to the resting order's
+// broker if the fill completed it; then order.accepted if order rests on
+// the book, or order.filled if it filled. It is an engine.OrderDispatcher,
+// so each broker's sequence numbers follow the order of the passes on a
+// symbol.
+func (s *WebhookService) DispatchOrderMatched(ctx context.Context, order *domain.Order, fills []engine.Fill) {
+	for _, f := range fills {
+		s.DispatchTradeExecuted(ctx, order.BrokerID, f.Trade, order)
+		s.DispatchBalanceChanged(ctx, order.BrokerID, f.Trade, order)
+		s.DispatchTradeExecuted(ctx, f.Resting.BrokerID, f.RestingTrade, f.Resting)
+		s.DispatchBalanceChanged(ctx, f.Resting.BrokerID, f.RestingTrade, f.Resting)
+		if filledBy(f.Resting, f.RestingTrade) {
+			s.DispatchOrderFilled(ctx, f.Resting)
+		}
+	}
+	switch order.Status {
+	case domain.OrderStatusPending, domain.OrderStatusPartiallyFilled:
+		s.DispatchOrderAccepted(ctx, order)
+	case domain.OrderStatusFilled:
+		s.DispatchOrderFilled(ctx, order)
+	}
+}
+
+// filledBy reports whether trade was the execution that filled order.
+func filledBy(order *domain.Order, trade *domain.Trade) bool {
+	if order.Status != domain.OrderStatusFilled || len(order.Trades) == 0 {
+		return false
+	}
+	return order.Trades[len(order.Trades)-1].TradeID == trade.TradeID
+}
+
+// DispatchOrderFilled dispatches an order.filled notification, summarising
+// the order's fills, to the order's broker once it has filled completely.
+func (s *WebhookService) DispatchOrderFilled(ctx context.Context, order *domain.Order) {
+	avg, _ := order.AveragePrice()
+	var notional int64
+	var filledAt time.Time
+	fills := make([]orderFillData, len(order.Trades))
+	for i, t := range order.Trades {
+		notional += t.Price * t.Quantity
+		if t.ExecutedAt.After(filledAt) {
+			filledAt = t.ExecutedAt
+		}
+		fills[i] = orderFillData{
+			TradeID:    t.TradeID,
+			Price:      domain.CentsToDollars(t.Price),
+			Quantity:   t.Quantity,
+			ExecutedAt: t.ExecutedAt.UTC().Truncate(time.Second).Format(time.RFC3339),
+		}
+	}
+
+	s.dispatch(ctx, order.BrokerID, "order.filled", &orderFilledPayload{
+		Event:     "order.filled",
+		Version:   webhookEvents["order.filled"],
+		Timestamp: s.clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		Data: orderFilledData{
+			BrokerID:       order.BrokerID,
+			OrderID:        order.OrderID,
+			Type:           string(order.Type),
+			Symbol:         order.Symbol,
+			Side:           string(order.Side),
+			Price:          domain.CentsToDollars(order.Price),
+			Quantity:       order.Quantity,
+			FilledQuantity: order.FilledQuantity,
+			AveragePrice:   domain.CentsToDollars(avg),
+			Notional:       domain.CentsToDollars(notional),
+			Fills:          fills,
+			FilledAt:       filledAt.UTC().Truncate(time.Second).Format(time.RFC3339),
+		},
+	})
+}
+
+// DispatchOrderRejected dispatches an order.rejected notification to the
+// broker of an order submission that err rejected. The reason is the
+// error's code, or validation_error for a validation failure.
+func (s *WebhookService) DispatchOrderRejected(ctx context.Context, req SubmitOrderRequest, err error) {
+	s.dispatch(ctx, req.BrokerID, "order.rejected", &orderRejectedPayload{
+		Event:     "order.rejected",
+		Version:   webhookEvents["order.rejected"],
+		Timestamp: s.clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		Data: orderRejectedData{
+			BrokerID:       req.BrokerID,
+			DocumentNumber: req.DocumentNumber,
+			Type:           string(req.Type),
+			Symbol:         req.Symbol,
+			Side:           string(req.Side),
+			Price:          req.Price,
+			Quantity:       req.Quantity,
+			Reason:         RejectReason(err),
+			Message:        err.Error(),
+		},
+	})
+}
+
+// DispatchOrderAmended dispatches an order.amended notification to the
+// broker of an order that was replaced, once the replacement is accepted.
+func (s *WebhookService) DispatchOrderAmended(ctx context.Context, original, replacement *domain.Order) {
+	s.dispatch(ctx, replacement.BrokerID, "order.amended", &orderAmendedPayload{
+		Event:     "order.amended",
+		Version:   webhookEvents["order.amended"],
+		Timestamp: s.clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		Data: orderAmendedData{
+			BrokerID:         replacement.BrokerID,
+			OrderID:          replacement.OrderID,
+			ReplacedOrderID:  original.OrderID,
+			Symbol:           replacement.Symbol,
+			Side:             string(replacement.Side),
+			Price:            domain.CentsToDollars(replacement.Price),
+			Quantity:         replacement.Quantity,
+			PreviousPrice:    domain.CentsToDollars(original.Price),
+			PreviousQuantity: original.Quantity,
+			PreviousFilled:   original.FilledQuantity,
+			Status:           string(replacement.Status),
+		},
+	})
+}
+
+// DispatchBalanceChanged dispatches a balance.changed notification with the
+// cash and holding deltas that settling trade, one of order's executions,
+// applied to the broker.
+func (s *WebhookService) DispatchBalanceChanged(ctx context.Context, brokerID string, trade *domain.Trade, order *domain.Order) {
+	cash := trade.Price * trade.Quantity
+	holding := trade.Quantity
+	if trade.Side == domain.OrderSideBid {
+		cash = -cash
+	} else {
+		holding = -holding
+	}
+
+	s.dispatch(ctx, brokerID, "balance.changed", &balanceChangedPayload{
+		Event:     "balance.changed",
+		Version:   webhookEvents["balance.changed"],
+		Timestamp: trade.ExecutedAt.UTC().Truncate(time.Second).Format(time.RFC3339),
+		Data: balanceChangedData{
+			BrokerID:     brokerID,
+			TradeID:      trade.TradeID,
+			OrderID:      order.OrderID,
+			Symbol:       order.Symbol,
+			CashDelta:    domain.CentsToDollars(cash),
+			HoldingDelta: holding,
+		},
+	})
+}
+
+// DispatchSymbolHalted dispatches a symbol.halted notification to every
+// broker. reason may be empty.
+func (s *WebhookService) DispatchSymbolHalted(ctx context.Context, symbol, reason string) {
+	s.dispatchSymbolEvent(ctx, "symbol.halted", symbol, reason)
+}
+
+// DispatchSymbolResumed dispatches a symbol.resumed notification to every
+// broker.
+func (s *WebhookService) DispatchSymbolResumed(ctx context.Context, symbol string) {
+	s.dispatchSymbolEvent(ctx, "symbol.resumed", symbol, "")
+}
+
+// dispatchSymbolEvent dispatches a market-wide event about a symbol to every
+// broker, each with its own sequence number.
+func (s *WebhookService) dispatchSymbolEvent(ctx context.Context, event, symbol, reason string) {
+	var r *string
+	if reason != "" {
+		r = &reason
+	}
+	timestamp := s.clock.Now().UTC().Truncate(time.Second).Format(time.RFC3339)
+	for _, brokerID := range s.brokerStore.IDs() {
+		s.dispatch(ctx, brokerID, event, &symbolEventPayload{
+			Event:     event,
+			Version:   webhookEvents[event],
+			Timestamp: timestamp,
+			Data:      symbolEventData{Symbol: symbol, Reason: r},
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// newEventCatalogueEnv returns an order env whose order service dispatches
+// through a webhook service to an event stream, with "seller" holding 500
+// AAPL and "buyer" holding $100,000.
+func newEventCatalogueEnv(t *testing.T) (*testOrderEnv, *EventStreamService) {
+	t.Helper()
+	env := newTestOrderEnv()
+	events := NewEventStreamService(env.brokerStore, 100, nil)
+	webhookSvc := NewWebhookService(store.NewWebhookStore(), store.NewDeliveryStore(100, 100), env.brokerStore, time.Second, DeliveryPolicy{}, events, nil, nil)
+	t.Cleanup(webhookSvc.Close)
+	env.matcher = engine.NewMatcher(env.books, env.brokerStore, env.orderStore, env.tradeStore, env.symbols, webhookSvc, nil, nil, nil)
+	env.svc = NewOrderService(env.matcher, env.expiry, env.brokerStore, env.orderStore, env.tradeStore, webhookSvc, env.symbols, nil, nil, nil, nil)
+
+	env.registerBroker(t, "seller", 0, []HoldingInput{{Symbol: "AAPL", Quantity: 500}})
+	env.registerBroker(t, "buyer", 100000.00, nil)
+	return env, events
+}
+
+// catalogueEvent is a broker event with its payload decoded.
+type catalogueEvent struct {
+	Event    string
+	Version  int
+	Sequence uint64
+	Data     map[string]any
+}
+
+// subscribeEvents subscribes to the broker's event stream and returns a
+// function that returns the events queued since the last call.
+func subscribeEvents(t *testing.T, events *EventStreamService, brokerID string) func() []catalogueEvent {
+	t.Helper()
+	sub, _, err := events.Subscribe(brokerID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { events.Unsubscribe(brokerID, sub) })
+
+	return func() []catalogueEvent {
+		t.Helper()
+		var got []catalogueEvent
+		for {
+			select {
+			case ev := <-sub.Events():
+				var payload struct {
+					Event    string         `json:"event"`
+					Version  int            `json:"version"`
+					Sequence uint64         `json:"sequence"`
+					Data     map[string]any `json:"data"`
+				}
+				if err := json.Unmarshal(ev.Data, &payload); err != nil {
+					t.Fatalf("decode %s payload: %v", ev.Event, err)
+				}
+				// The event stream gets every event at its latest version.
+				if payload.Event != ev.Event || payload.Version != webhookEvents[ev.Event] || payload.Sequence != ev.ID {
+					t.Errorf("unexpected envelope for event %d %s: %s", ev.ID, ev.Event, ev.Data)
+				}
+				got = append(got, catalogueEvent{payload.Event, payload.Version, payload.Sequence, payload.Data})
+			default:
+				return got
+			}
+		}
+	}
+}
+
+func eventNames(events []catalogueEvent) string {
+	names := make([]string, len(events))
+	for i, ev := range events {
+		names[i] = ev.Event
+	}
+	return strings.Join(names, " ")
+}
+
+func limitOrder(brokerID string, side domain.OrderSide, price float64, qty int64) SubmitOrderRequest {
+	return SubmitOrderRequest{
+		Type:           domain.OrderTypeLimit,
+		BrokerID:       brokerID,
+		DocumentNumber: "DOC001",
+		Side:           side,
+		Symbol:         "AAPL",
+		Price:          floatPtr(price),
+		Quantity:       qty,
+		ExpiresAt:      futureTime(),
+	}
+}
+
+func TestUpsert_AcceptsEveryCatalogueEvent(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	events := make([]string, 0, len(webhookEvents))
+	for event := range webhookEvents {
+		events = append(events, event)
+	}
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   events,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(webhooks) != len(webhookEvents) {
+		t.Errorf("got %d webhooks, want %d", len(webhooks), len(webhookEvents))
+	}
+}
+
+func TestSubmitOrder_DispatchesFillAndBalanceEvents(t *testing.T) {
+	env, events := newEventCatalogueEnv(t)
+	sellerEvents := subscribeEvents(t, events, "seller")
+	buyerEvents := subscribeEvents(t, events, "buyer")
+
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("seller", domain.OrderSideAsk, 150.00, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 151.00, 4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A partial fill settles balances but does not fill the resting ask.
+	if got := eventNames(sellerEvents()); got != "order.accepted trade.executed balance.changed" {
+		t.Errorf("seller events after partial fill: got %q", got)
+	}
+	buyer := buyerEvents()
+	if got := eventNames(buyer); got != "trade.executed balance.changed order.filled" {
+		t.Fatalf("buyer events: got %q", got)
+	}
+	if d := buyer[1].Data; d["cash_delta"] != -600.0 || d["holding_delta"] != 4.0 || d["symbol"] != "AAPL" {
+		t.Errorf("unexpected buyer balance.changed data: %v", d)
+	}
+	if d := buyer[2].Data; d["average_price"] != 150.0 || d["notional"] != 600.0 ||
+		d["filled_quantity"] != 4.0 || len(d["fills"].([]any)) != 1 || d["price"] != 151.0 {
+		t.Errorf("unexpected buyer order.filled data: %v", d)
+	}
+
+	// Filling the rest of the ask sends the seller its summary.
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 6)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seller := sellerEvents()
+	if got := eventNames(seller); got != "trade.executed balance.changed order.filled" {
+		t.Fatalf("seller events after final fill: got %q", got)
+	}
+	if d := seller[1].Data; d["cash_delta"] != 900.0 || d["holding_delta"] != -6.0 {
+		t.Errorf("unexpected seller balance.changed data: %v", d)
+	}
+	if d := seller[2].Data; d["average_price"] != 150.0 || d["notional"] != 1500.0 ||
+		d["filled_quantity"] != 10.0 || len(d["fills"].([]any)) != 2 || d["side"] != "ask" {
+		t.Errorf("unexpected seller order.filled data: %v", d)
+	}
+}
+
+func TestSubmitOrder_DispatchesOrderAcceptedOnlyWhenResting(t *testing.T) {
+	env, events := newEventCatalogueEnv(t)
+	buyerEvents := subscribeEvents(t, events, "buyer")
+
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("seller", domain.OrderSideAsk, 150.00, 10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A limit order that crosses completely never rests.
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := eventNames(buyerEvents()); got != "trade.executed balance.changed order.filled" {
+		t.Errorf("crossed limit order: got %q", got)
+	}
+
+	// Nor does a market order, filled or not.
+	market := SubmitOrderRequest{Type: domain.OrderTypeMarket, BrokerID: "buyer", DocumentNumber: "DOC001", Side: domain.OrderSideBid, Symbol: "AAPL", Quantity: 8}
+	if _, err := env.svc.SubmitOrder(context.Background(), market); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := eventNames(buyerEvents()); got != "trade.executed balance.changed" {
+		t.Errorf("market order: got %q", got)
+	}
+
+	// A limit order that partially fills rests, and is accepted after its
+	// fills.
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("seller", domain.OrderSideAsk, 150.00, 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	partial, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := buyerEvents()
+	if eventNames(got) != "trade.executed balance.changed order.accepted" {
+		t.Fatalf("resting limit order: got %q", eventNames(got))
+	}
+	if d := got[2].Data; d["order_id"] != partial.OrderID || d["status"] != "partially_filled" {
+		t.Errorf("unexpected order.accepted data: %v", d)
+	}
+}
+
+func TestSubmitOrder_DispatchesOrderRejected(t *testing.T) {
+	env, events := newEventCatalogueEnv(t)
+	buyerEvents := subscribeEvents(t, events, "buyer")
+
+	_, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 1000))
+	if !errors.Is(err, domain.ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+	invalid := limitOrder("buyer", domain.OrderSideBid, 150.00, 0)
+	if _, err := env.svc.SubmitOrder(context.Background(), invalid); err == nil {
+		t.Fatal("expected a validation error")
+	}
+	// Submissions for unknown brokers have nobody to notify.
+	if _, err := env.svc.SubmitOrder(context.Background(), limitOrder("ghost", domain.OrderSideBid, 150.00, 1)); !errors.Is(err, domain.ErrBrokerNotFound) {
+		t.Fatalf("expected ErrBrokerNotFound, got %v", err)
+	}
+
+	got := buyerEvents()
+	if eventNames(got) != "order.rejected order.rejected" {
+		t.Fatalf("got events %q", eventNames(got))
+	}
+	if d := got[0].Data; d["reason"] != "insufficient_balance" || d["document_number"] != "DOC001" ||
+		d["price"] != 150.0 || d["quantity"] != 1000.0 || d["type"] != "limit" {
+		t.Errorf("unexpected order.rejected data: %v", d)
+	}
+	if d := got[1].Data; d["reason"] != "validation_error" || d["message"] != "quantity must be a positive integer" {
+		t.Errorf("unexpected order.rejected data: %v", d)
+	}
+}
+
+func TestReplaceOrder_DispatchesOrderAmended(t *testing.T) {
+	env, events := newEventCatalogueEnv(t)
+	buyerEvents := subscribeEvents(t, events, "buyer")
+
+	bid, err := env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 140.00, 50))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, replacement, err := env.svc.ReplaceOrder(context.Background(), ReplaceOrderRequest{OrderID: bid.OrderID, Price: 145.00, Quantity: 80})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := buyerEvents()
+	if eventNames(got) != "order.accepted order.cancelled order.accepted order.amended" {
+		t.Fatalf("got events %q", eventNames(got))
+	}
+	d := got[3].Data
+	if d["order_id"] != replacement.OrderID || d["replaced_order_id"] != bid.OrderID ||
+		d["price"] != 145.0 || d["quantity"] != 80.0 ||
+		d["previous_price"] != 140.0 || d["previous_quantity"] != 50.0 ||
+		d["previous_filled_quantity"] != 0.0 || d["status"] != "pending" {
+		t.Errorf("unexpected order.amended data: %v", d)
+	}
+}
+
+func TestHaltSymbol(t *testing.T) {
+	env, events := newEventCatalogueEnv(t)
+	ask, err := env.svc.SubmitOrder(context.Background(), limitOrder("seller", domain.OrderSideAsk, 150.00, 10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sellerEvents := subscribeEvents(t, events, "seller")
+	buyerEvents := subscribeEvents(t, events, "buyer")
+
+	if err := env.svc.HaltSymbol(context.Background(), "AAPL", "pending news"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := env.svc.HaltSymbol(context.Background(), "AAPL", "again"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = env.svc.SubmitOrder(context.Background(), limitOrder("buyer", domain.OrderSideBid, 150.00, 1))
+	if !errors.Is(err, domain.ErrSymbolHalted) {
+		t.Fatalf("expected ErrSymbolHalted, got %v", err)
+	}
+	if _, _, err := env.svc.ReplaceOrder(context.Background(), ReplaceOrderRequest{OrderID: ask.OrderID, Price: 151.00, Quantity: 10}); !errors.Is(err, domain.ErrSymbolHalted) {
+		t.Fatalf("replace: expected ErrSymbolHalted, got %v", err)
+	}
+	if got, _ := env.orderStore.Get(ask.OrderID); got.Status != domain.OrderStatusPending {
+		t.Errorf("a rejected replacement must leave the order resting, got status %s", got.Status)
+	}
+	if err := env.svc.ResumeSymbol(context.Background(), "AAPL"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every broker hears about the halt once, whether or not it trades the
+	// symbol, and each numbers it in its own sequence.
+	seller := sellerEvents()
+	if eventNames(seller) != "symbol.halted symbol.resumed" {
+		t.Fatalf("seller events: got %q", eventNames(seller))
+	}
+	if d := seller[0].Data; d["symbol"] != "AAPL" || d["reason"] != "pending news" {
+		t.Errorf("unexpected symbol.halted data: %v", d)
+	}
+	if d := seller[1].Data; d["symbol"] != "AAPL" || d["reason"] != nil {
+		t.Errorf("unexpected symbol.resumed data: %v", d)
+	}
+	buyer := buyerEvents()
+	if eventNames(buyer) != "symbol.halted order.rejected symbol.resumed" {
+		t.Fatalf("buyer events: got %q", eventNames(buyer))
+	}
+	if buyer[1].Data["reason"] != "symbol_halted" {
+		t.Errorf("unexpected order.rejected data: %v", buyer[1].Data)
+	}
+
+	if err := env.svc.HaltSymbol(context.Background(), "MSFT", ""); !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("unknown symbol: expected ErrSymbolNotFound, got %v", err)
+	}
+	if err := env.svc.ResumeSymbol(context.Background(), "MSFT"); !errors.Is(err, domain.ErrSymbolNotFound) {
+		t.Errorf("unknown symbol: expected ErrSymbolNotFound, got %v", err)
+	}
+	var validationErr *domain.ValidationError
+	if err := env.svc.HaltSymbol(context.Background(), "AAPL", strings.Repeat("x", 257)); !errors.As(err, &validationErr) {
+		t.Errorf("long reason: expected ValidationError, got %v", err)
+	}
+}
+
+func TestEventSubject_Matches(t *testing.T) {
+	sell := (&balanceChangedPayload{Data: balanceChangedData{Symbol: "AAPL", CashDelta: 900.00, HoldingDelta: -6}}).subject()
+	if sell != (eventSubject{symbol: "AAPL", side: domain.OrderSideAsk, notional: 90000}) {
+		t.Fatalf("unexpected balance.changed subject: %+v", sell)
+	}
+	halt := (&symbolEventPayload{Data: symbolEventData{Symbol: "AAPL"}}).subject()
+
+	for _, tc := range []struct {
+		name    string
+		subject eventSubject
+		filters domain.WebhookFilters
+		want    bool
+	}{
+		{"no filters", sell, domain.WebhookFilters{}, true},
+		{"symbol listed", sell, domain.WebhookFilters{Symbols: []string{"AAPL", "MSFT"}}, true},
+		{"symbol not listed", sell, domain.WebhookFilters{Symbols: []string{"MSFT"}}, false},
+		{"same side", sell, domain.WebhookFilters{Side: domain.OrderSideAsk}, true},
+		{"other side", sell, domain.WebhookFilters{Side: domain.OrderSideBid}, false},
+		{"notional at minimum", sell, domain.WebhookFilters{MinNotional: 90000}, true},
+		{"notional below minimum", sell, domain.WebhookFilters{MinNotional: 90001}, false},
+		{"side filter without a side", halt, domain.WebhookFilters{Side: domain.OrderSideBid}, true},
+		{"notional filter without a trade", halt, domain.WebhookFilters{MinNotional: 1}, true},
+	} {
+		if got := tc.subject.matches(tc.filters); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
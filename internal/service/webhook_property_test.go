@@ -15,13 +15,14 @@ import (
 
 // TestProperty_WebhookUpsertIdempotency verifies that for any webhook registration
 // with the same (broker_id, event) pair and same URL, re-registering is idempotent —
-// the webhook_id remains stable and the subscription is unchanged. Changing the URL
-// updates the subscription without changing the webhook_id.
+// the webhook_id remains stable and the subscription is unchanged. A different URL
+// for the same event creates a separate subscription.
 func TestProperty_WebhookUpsertIdempotency(t *testing.T) {
 	rapid.Check(t, func(t *rapid.T) {
 		bs := store.NewBrokerStore()
 		ws := store.NewWebhookStore()
-		svc := NewWebhookService(ws, bs, 5*time.Second)
+		svc := NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, DeliveryPolicy{}, nil, nil, nil)
+		defer svc.Close()
 
 		// Register a broker.
 		brokerID := fmt.Sprintf("broker-%d", rapid.IntRange(1, 9999).Draw(t, "brokerSuffix"))
@@ -94,27 +95,28 @@ func TestProperty_WebhookUpsertIdempotency(t *testing.T) {
 			}
 		}
 
-		// --- Step 3: Re-register with same (broker_id, event) but different URL ---
-		// webhook_id should remain stable, URL should be updated.
+		// --- Step 3: Register a different URL for the same (broker_id, event) ---
+		// This creates a second subscription and leaves the first unchanged.
 		webhooks3, created3, err := svc.Upsert(UpsertWebhookRequest{
 			BrokerID: brokerID,
 			URL:      url2,
 			Events:   []string{event},
 		})
 		if err != nil {
-			t.Fatalf("URL update upsert failed: %v", err)
+			t.Fatalf("second URL upsert failed: %v", err)
 		}
-		if created3 {
-			t.Fatal("expected created=false when updating URL")
+		if !created3 {
+			t.Fatal("expected created=true for a second URL")
 		}
 		if len(webhooks3) != 1 {
 			t.Fatalf("expected 1 webhook, got %d", len(webhooks3))
 		}
-		if webhooks3[0].WebhookID != originalID {
-			t.Fatalf("webhook_id changed after URL update: %q -> %q", originalID, webhooks3[0].WebhookID)
+		secondID := webhooks3[0].WebhookID
+		if secondID == originalID {
+			t.Fatalf("second URL reused webhook_id %q", originalID)
 		}
-		if webhooks3[0].URL != url2 {
-			t.Fatalf("expected updated URL %q, got %q", url2, webhooks3[0].URL)
+		if original, err := svc.Get(originalID); err != nil || original.URL != url1 {
+			t.Fatalf("first subscription changed: %+v, %v", original, err)
 		}
 
 		// --- Step 4: Verify idempotency with the new URL ---
@@ -124,17 +126,16 @@ func TestProperty_WebhookUpsertIdempotency(t *testing.T) {
 			Events:   []string{event},
 		})
 		if err != nil {
-			t.Fatalf("post-update idempotent upsert failed: %v", err)
+			t.Fatalf("second URL idempotent upsert failed: %v", err)
 		}
 		if created4 {
-			t.Fatal("expected created=false for idempotent re-registration after URL update")
+			t.Fatal("expected created=false for idempotent re-registration of the second URL")
 		}
-		if webhooks4[0].WebhookID != originalID {
-			t.Fatalf("webhook_id not stable after URL update idempotent check: %q -> %q",
-				originalID, webhooks4[0].WebhookID)
+		if webhooks4[0].WebhookID != secondID {
+			t.Fatalf("webhook_id not stable for the second URL: %q -> %q", secondID, webhooks4[0].WebhookID)
 		}
-		if webhooks4[0].URL != url2 {
-			t.Fatalf("URL should remain %q, got %q", url2, webhooks4[0].URL)
+		if list, _ := svc.List(brokerID); len(list) != 2 {
+			t.Fatalf("expected 2 subscriptions, got %d", len(list))
 		}
 	})
 }
@@ -1,25 +1,39 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
 	"github.com/efreitasn/miniexchange/internal/store"
+	"github.com/efreitasn/miniexchange/pkg/webhook"
 )
 
-func newTestWebhookService() (*WebhookService, *store.BrokerStore) {
+func newTestWebhookService(t *testing.T) (*WebhookService, *store.BrokerStore) {
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := NewWebhookService(ws, bs, 5*time.Second)
+	svc := NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, DeliveryPolicy{}, nil, nil, nil)
+	t.Cleanup(svc.Close)
 	return svc, bs
 }
 
+// newDeliveringWebhookService returns a WebhookService that delivers with
+// client, for a TLS test server, under policy.
+func newDeliveringWebhookService(t *testing.T, ws *store.WebhookStore, bs *store.BrokerStore, client *http.Client, policy DeliveryPolicy) *WebhookService {
+	svc := NewWebhookService(ws, store.NewDeliveryStore(100, 100), bs, 5*time.Second, policy, nil, nil, nil)
+	svc.client = client
+	t.Cleanup(svc.Close)
+	return svc
+}
+
 func registerBroker(t *testing.T, bs *store.BrokerStore, id string) {
 	t.Helper()
 	err := bs.Create(&domain.Broker{
@@ -36,7 +50,7 @@ func registerBroker(t *testing.T, bs *store.BrokerStore, id string) {
 // --- Upsert tests ---
 
 func TestUpsert_Success_NewSubscriptions(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	webhooks, created, err := svc.Upsert(UpsertWebhookRequest{
@@ -64,12 +78,12 @@ func TestUpsert_Success_NewSubscriptions(t *testing.T) {
 	}
 }
 
-func TestUpsert_Success_UpdateExistingURL(t *testing.T) {
-	svc, bs := newTestWebhookService()
+func TestUpsert_Success_SecondURLForSameEvent(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	// Create initial subscription.
-	_, _, err := svc.Upsert(UpsertWebhookRequest{
+	first, _, err := svc.Upsert(UpsertWebhookRequest{
 		BrokerID: "broker-1",
 		URL:      "https://example.com/old",
 		Events:   []string{"trade.executed"},
@@ -78,7 +92,7 @@ func TestUpsert_Success_UpdateExistingURL(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// Update URL.
+	// A second URL for the same event is a second subscription.
 	webhooks, created, err := svc.Upsert(UpsertWebhookRequest{
 		BrokerID: "broker-1",
 		URL:      "https://example.com/new",
@@ -87,19 +101,21 @@ func TestUpsert_Success_UpdateExistingURL(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if created {
-		t.Error("expected created=false for URL update")
+	if !created {
+		t.Error("expected created=true for a second URL")
 	}
-	if len(webhooks) != 1 {
-		t.Fatalf("got %d webhooks, want 1", len(webhooks))
+	if len(webhooks) != 1 || webhooks[0].WebhookID == first[0].WebhookID {
+		t.Fatalf("expected a new subscription, got %+v", webhooks)
 	}
-	if webhooks[0].URL != "https://example.com/new" {
-		t.Errorf("got URL %q, want %q", webhooks[0].URL, "https://example.com/new")
+
+	list, _ := svc.List("broker-1")
+	if len(list) != 2 || list[0].URL != "https://example.com/old" || list[1].URL != "https://example.com/new" {
+		t.Errorf("expected both subscriptions listed, got %+v", list)
 	}
 }
 
 func TestUpsert_Success_IdempotentSameURL(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	webhooks1, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -128,7 +144,7 @@ func TestUpsert_Success_IdempotentSameURL(t *testing.T) {
 }
 
 func TestUpsert_Success_MixNewAndExisting(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	// Create one subscription.
@@ -159,7 +175,7 @@ func TestUpsert_Success_MixNewAndExisting(t *testing.T) {
 }
 
 func TestUpsert_Success_DeduplicateEvents(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -176,7 +192,7 @@ func TestUpsert_Success_DeduplicateEvents(t *testing.T) {
 }
 
 func TestUpsert_BrokerNotFound(t *testing.T) {
-	svc, _ := newTestWebhookService()
+	svc, _ := newTestWebhookService(t)
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
 		BrokerID: "nonexistent",
@@ -189,7 +205,7 @@ func TestUpsert_BrokerNotFound(t *testing.T) {
 }
 
 func TestUpsert_EmptyURL(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -206,7 +222,7 @@ func TestUpsert_EmptyURL(t *testing.T) {
 }
 
 func TestUpsert_HTTPSchemeRejected(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -227,7 +243,7 @@ func TestUpsert_HTTPSchemeRejected(t *testing.T) {
 }
 
 func TestUpsert_URLTooLong(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	longURL := "https://example.com/" + string(make([]byte, 2049))
@@ -245,7 +261,7 @@ func TestUpsert_URLTooLong(t *testing.T) {
 }
 
 func TestUpsert_InvalidURL(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -262,7 +278,7 @@ func TestUpsert_InvalidURL(t *testing.T) {
 }
 
 func TestUpsert_EmptyEvents(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -283,7 +299,7 @@ func TestUpsert_EmptyEvents(t *testing.T) {
 }
 
 func TestUpsert_InvalidEventType(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -298,16 +314,176 @@ func TestUpsert_InvalidEventType(t *testing.T) {
 	if !ok {
 		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
 	}
-	expected := "Unknown event type: trade.matched. Must be one of: trade.executed, order.expired, order.cancelled"
+	expected := "Unknown event type: trade.matched. Must be one of: balance.changed, order.accepted, order.amended, order.cancelled, order.expired, order.filled, order.rejected, symbol.halted, symbol.resumed, trade.executed"
 	if ve.Message != expected {
 		t.Errorf("got message %q, want %q", ve.Message, expected)
 	}
 }
 
+func TestUpsert_FiltersAndVersion(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	minNotional := 1000.50
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+		Filters: WebhookFiltersInput{
+			Symbols:     []string{"MSFT", "AAPL", "MSFT"},
+			Side:        "bid",
+			MinNotional: &minNotional,
+		},
+		Version: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wh := webhooks[0]
+	want := domain.WebhookFilters{Symbols: []string{"AAPL", "MSFT"}, Side: domain.OrderSideBid, MinNotional: 100050}
+	if !wh.Filters.Equal(want) || wh.Version != 1 || wh.Status != domain.WebhookStatusActive {
+		t.Errorf("got filters %+v, version %d, status %q", wh.Filters, wh.Version, wh.Status)
+	}
+
+	// Registering the same URL again replaces the filters.
+	webhooks, created, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created || webhooks[0].WebhookID != wh.WebhookID || !webhooks[0].Filters.IsZero() {
+		t.Errorf("expected the subscription's filters cleared, got created=%v %+v", created, webhooks[0])
+	}
+	// Version 0 selects each event's latest version.
+	if webhooks[0].Version != webhookEvents["trade.executed"] {
+		t.Errorf("got version %d, want the latest", webhooks[0].Version)
+	}
+}
+
+func TestUpsert_InvalidFiltersAndVersion(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	zero, fractional := 0.0, 10.001
+	tooMany := make([]string, maxFilterSymbols+1)
+	for i := range tooMany {
+		tooMany[i] = "AAPL"
+	}
+	for name, tc := range map[string]struct {
+		filters WebhookFiltersInput
+		version int
+		want    string
+	}{
+		"bad symbol":         {filters: WebhookFiltersInput{Symbols: []string{"aapl"}}, want: "filters.symbols entries must match ^[A-Z]{1,10}$"},
+		"too many symbols":   {filters: WebhookFiltersInput{Symbols: tooMany}, want: "filters.symbols must have at most 100 symbols"},
+		"bad side":           {filters: WebhookFiltersInput{Side: "buy"}, want: "filters.side must be 'bid' or 'ask'"},
+		"zero min notional":  {filters: WebhookFiltersInput{MinNotional: &zero}, want: "filters.min_notional must be greater than 0"},
+		"fractional cents":   {filters: WebhookFiltersInput{MinNotional: &fractional}, want: "filters.min_notional must have at most 2 decimal places"},
+		"unreleased version": {version: 2, want: "Unsupported version 2 for event trade.executed. Must be between 1 and 1"},
+		"negative version":   {version: -1, want: "Unsupported version -1 for event trade.executed. Must be between 1 and 1"},
+	} {
+		_, _, err := svc.Upsert(UpsertWebhookRequest{
+			BrokerID: "broker-1",
+			URL:      "https://example.com/hooks",
+			Events:   []string{"trade.executed"},
+			Filters:  tc.filters,
+			Version:  tc.version,
+		})
+		var ve *domain.ValidationError
+		if !errors.As(err, &ve) || ve.Message != tc.want {
+			t.Errorf("%s: got error %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+// --- Update tests ---
+
+func TestUpdate_PauseResumeAndFilters(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := webhooks[0].WebhookID
+
+	paused := "paused"
+	got, err := svc.Update(UpdateWebhookRequest{WebhookID: id, Status: &paused})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != domain.WebhookStatusPaused || got.Secret != "" {
+		t.Errorf("expected a paused webhook without its secret, got %+v", got)
+	}
+
+	// Filters are replaced as a whole; the status is left alone.
+	got, err = svc.Update(UpdateWebhookRequest{WebhookID: id, Filters: &WebhookFiltersInput{Side: "ask"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != domain.WebhookStatusPaused || got.Filters.Side != domain.OrderSideAsk {
+		t.Errorf("expected paused with an ask filter, got %+v", got)
+	}
+
+	active, version := "active", 1
+	got, err = svc.Update(UpdateWebhookRequest{WebhookID: id, Status: &active, Version: &version})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != domain.WebhookStatusActive || got.Filters.Side != domain.OrderSideAsk || got.Version != 1 {
+		t.Errorf("expected active with the ask filter kept, got %+v", got)
+	}
+	if stored, _ := svc.Get(id); stored.Status != domain.WebhookStatusActive || stored.Secret == "" {
+		t.Errorf("expected the stored webhook active with its secret, got %+v", stored)
+	}
+}
+
+func TestUpdate_Errors(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	id := webhooks[0].WebhookID
+
+	status := "paused"
+	if _, err := svc.Update(UpdateWebhookRequest{WebhookID: "nonexistent", Status: &status}); err != domain.ErrWebhookNotFound {
+		t.Errorf("unknown webhook: got error %v, want ErrWebhookNotFound", err)
+	}
+
+	disabled, zero, two := "disabled", 0, 2
+	for name, tc := range map[string]struct {
+		req  UpdateWebhookRequest
+		want string
+	}{
+		"empty":       {UpdateWebhookRequest{WebhookID: id}, "at least one of status, filters or version is required"},
+		"bad status":  {UpdateWebhookRequest{WebhookID: id, Status: &disabled}, "Invalid status: 'disabled'. Must be one of: active, paused"},
+		"bad filters": {UpdateWebhookRequest{WebhookID: id, Filters: &WebhookFiltersInput{Side: "sell"}}, "filters.side must be 'bid' or 'ask'"},
+		"zero":        {UpdateWebhookRequest{WebhookID: id, Version: &zero}, "version must be a positive integer"},
+		"unreleased":  {UpdateWebhookRequest{WebhookID: id, Version: &two}, "Unsupported version 2 for event trade.executed. Must be between 1 and 1"},
+	} {
+		_, err := svc.Update(tc.req)
+		var ve *domain.ValidationError
+		if !errors.As(err, &ve) || ve.Message != tc.want {
+			t.Errorf("%s: got error %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
 // --- List tests ---
 
 func TestList_Success(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	_, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -329,7 +505,7 @@ func TestList_Success(t *testing.T) {
 }
 
 func TestList_EmptyResult(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	webhooks, err := svc.List("broker-1")
@@ -342,7 +518,7 @@ func TestList_EmptyResult(t *testing.T) {
 }
 
 func TestList_BrokerNotFound(t *testing.T) {
-	svc, _ := newTestWebhookService()
+	svc, _ := newTestWebhookService(t)
 
 	_, err := svc.List("nonexistent")
 	if err != domain.ErrBrokerNotFound {
@@ -353,7 +529,7 @@ func TestList_BrokerNotFound(t *testing.T) {
 // --- Delete tests ---
 
 func TestDelete_Success(t *testing.T) {
-	svc, bs := newTestWebhookService()
+	svc, bs := newTestWebhookService(t)
 	registerBroker(t, bs, "broker-1")
 
 	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
@@ -381,7 +557,7 @@ func TestDelete_Success(t *testing.T) {
 }
 
 func TestDelete_NotFound(t *testing.T) {
-	svc, _ := newTestWebhookService()
+	svc, _ := newTestWebhookService(t)
 
 	err := svc.Delete("nonexistent-id")
 	if err != domain.ErrWebhookNotFound {
@@ -389,6 +565,159 @@ func TestDelete_NotFound(t *testing.T) {
 	}
 }
 
+func TestGet(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := svc.Get(webhooks[0].WebhookID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.BrokerID != "broker-1" {
+		t.Errorf("got broker %q, want broker-1", got.BrokerID)
+	}
+
+	if _, err := svc.Get("nonexistent-id"); err != domain.ErrWebhookNotFound {
+		t.Errorf("got error %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestUpsert_SecretReturnedOnlyOnCreation(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed", "order.expired"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(webhooks[0].Secret, "whsec_") {
+		t.Errorf("expected a whsec_ secret, got %q", webhooks[0].Secret)
+	}
+	if webhooks[0].Secret == webhooks[1].Secret {
+		t.Error("each webhook should get its own secret")
+	}
+
+	again, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed", "order.cancelled"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again[0].Secret != "" {
+		t.Error("an existing webhook's secret must not be returned again")
+	}
+	if again[1].Secret == "" {
+		t.Error("a webhook created by this call should have its secret")
+	}
+
+	// The stored secret is unchanged by the second upsert.
+	stored, _ := svc.Get(webhooks[0].WebhookID)
+	if stored.Secret != webhooks[0].Secret {
+		t.Error("upserting an existing webhook must not change its secret")
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	svc, bs := newTestWebhookService(t)
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, _ := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      "https://example.com/hooks",
+		Events:   []string{"trade.executed"},
+	})
+	old := webhooks[0].Secret
+
+	rotated, err := svc.RotateSecret(webhooks[0].WebhookID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated.Secret == old || !strings.HasPrefix(rotated.Secret, "whsec_") {
+		t.Errorf("expected a new secret, got %q", rotated.Secret)
+	}
+	if rotated.SecretRotatedAt == nil {
+		t.Error("SecretRotatedAt should be set")
+	}
+	secrets := rotated.SigningSecrets(time.Now())
+	if len(secrets) != 2 || secrets[0] != rotated.Secret || secrets[1] != old {
+		t.Errorf("expected both secrets during the grace period, got %v", secrets)
+	}
+	if got := rotated.SigningSecrets(time.Now().Add(secretRotationGrace + time.Second)); len(got) != 1 {
+		t.Errorf("expected only the new secret after the grace period, got %v", got)
+	}
+
+	if _, err := svc.RotateSecret("nonexistent-id"); err != domain.ErrWebhookNotFound {
+		t.Errorf("got error %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestDeliver_Signed(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan delivery, 2)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{r.Header.Clone(), body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	ws := store.NewWebhookStore()
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
+	registerBroker(t, bs, "broker-1")
+
+	webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+		BrokerID: "broker-1",
+		URL:      server.URL + "/hooks",
+		Events:   []string{"order.cancelled"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	oldSecret := webhooks[0].Secret
+	order := &domain.Order{OrderID: "ord-1", BrokerID: "broker-1", Symbol: "AAPL", Status: domain.OrderStatusCancelled}
+
+	svc.DispatchOrderCancelled(context.Background(), order)
+	d := <-deliveries
+	if err := webhook.Verify(oldSecret, d.header, d.body, 0); err != nil {
+		t.Fatalf("delivery does not verify: %v", err)
+	}
+	if err := webhook.Verify("whsec_wrong", d.header, d.body, 0); err == nil {
+		t.Error("delivery verified with the wrong secret")
+	}
+
+	// After a rotation, deliveries verify with either secret.
+	rotated, err := svc.RotateSecret(webhooks[0].WebhookID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.DispatchOrderCancelled(context.Background(), order)
+	d = <-deliveries
+	for _, secret := range []string{rotated.Secret, oldSecret} {
+		if err := webhook.Verify(secret, d.header, d.body, 0); err != nil {
+			t.Errorf("delivery does not verify with %s: %v", secret, err)
+		}
+	}
+}
+
 // --- Dispatch tests ---
 
 func TestDispatchTradeExecuted_SendsCorrectPayload(t *testing.T) {
@@ -410,11 +739,7 @@ func TestDispatchTradeExecuted_SendsCorrectPayload(t *testing.T) {
 
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := &WebhookService{
-		store:       ws,
-		brokerStore: bs,
-		client:      server.Client(),
-	}
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
 
 	registerBroker(t, bs, "broker-1")
 
@@ -445,7 +770,7 @@ func TestDispatchTradeExecuted_SendsCorrectPayload(t *testing.T) {
 		RemainingQuantity: 500,
 	}
 
-	svc.DispatchTradeExecuted("broker-1", trade, order)
+	svc.DispatchTradeExecuted(context.Background(), "broker-1", trade, order)
 
 	// Wait for goroutine to complete.
 	time.Sleep(100 * time.Millisecond)
@@ -511,11 +836,7 @@ func TestDispatchOrderExpired_SendsCorrectPayload(t *testing.T) {
 
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := &WebhookService{
-		store:       ws,
-		brokerStore: bs,
-		client:      server.Client(),
-	}
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
 
 	registerBroker(t, bs, "broker-1")
 
@@ -541,7 +862,7 @@ func TestDispatchOrderExpired_SendsCorrectPayload(t *testing.T) {
 		Status:            domain.OrderStatusExpired,
 	}
 
-	svc.DispatchOrderExpired(order)
+	svc.DispatchOrderExpired(context.Background(), order)
 	time.Sleep(100 * time.Millisecond)
 
 	mu.Lock()
@@ -585,11 +906,7 @@ func TestDispatchOrderCancelled_SendsCorrectPayload(t *testing.T) {
 
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := &WebhookService{
-		store:       ws,
-		brokerStore: bs,
-		client:      server.Client(),
-	}
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
 
 	registerBroker(t, bs, "broker-1")
 
@@ -615,7 +932,7 @@ func TestDispatchOrderCancelled_SendsCorrectPayload(t *testing.T) {
 		Status:            domain.OrderStatusCancelled,
 	}
 
-	svc.DispatchOrderCancelled(order)
+	svc.DispatchOrderCancelled(context.Background(), order)
 	time.Sleep(100 * time.Millisecond)
 
 	mu.Lock()
@@ -649,11 +966,7 @@ func TestDispatch_NoSubscription_NoRequest(t *testing.T) {
 
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := &WebhookService{
-		store:       ws,
-		brokerStore: bs,
-		client:      server.Client(),
-	}
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
 
 	registerBroker(t, bs, "broker-1")
 
@@ -673,9 +986,9 @@ func TestDispatch_NoSubscription_NoRequest(t *testing.T) {
 		Status:   domain.OrderStatusFilled,
 	}
 
-	svc.DispatchTradeExecuted("broker-1", trade, order)
-	svc.DispatchOrderExpired(order)
-	svc.DispatchOrderCancelled(order)
+	svc.DispatchTradeExecuted(context.Background(), "broker-1", trade, order)
+	svc.DispatchOrderExpired(context.Background(), order)
+	svc.DispatchOrderCancelled(context.Background(), order)
 
 	time.Sleep(100 * time.Millisecond)
 
@@ -692,11 +1005,7 @@ func TestDispatch_ServerError_SilentlyIgnored(t *testing.T) {
 
 	bs := store.NewBrokerStore()
 	ws := store.NewWebhookStore()
-	svc := &WebhookService{
-		store:       ws,
-		brokerStore: bs,
-		client:      server.Client(),
-	}
+	svc := newDeliveringWebhookService(t, ws, bs, server.Client(), DeliveryPolicy{})
 
 	registerBroker(t, bs, "broker-1")
 
@@ -724,7 +1033,145 @@ func TestDispatch_ServerError_SilentlyIgnored(t *testing.T) {
 		Status:   domain.OrderStatusFilled,
 	}
 
-	// Should not panic or return error — fire-and-forget.
-	svc.DispatchTradeExecuted("broker-1", trade, order)
+	// Should not panic or block; the failure is retried in the background.
+	svc.DispatchTradeExecuted(context.Background(), "broker-1", trade, order)
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestDispatch_FiltersAndPausedSubscriptions(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]string) // path → event types, in delivery order
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], r.Header.Get("X-Event-Type"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	svc := newDeliveringWebhookService(t, store.NewWebhookStore(), bs, server.Client(), DeliveryPolicy{})
+	registerBroker(t, bs, "broker-1")
+
+	subscribe := func(path, event string, filters WebhookFiltersInput) string {
+		webhooks, _, err := svc.Upsert(UpsertWebhookRequest{
+			BrokerID: "broker-1",
+			URL:      server.URL + path,
+			Events:   []string{event},
+			Filters:  filters,
+		})
+		if err != nil {
+			t.Fatalf("subscribe %s: %v", path, err)
+		}
+		return webhooks[0].WebhookID
+	}
+	minNotional := 1000.00
+	subscribe("/all", "trade.executed", WebhookFiltersInput{})
+	subscribe("/aapl-large", "trade.executed", WebhookFiltersInput{Symbols: []string{"AAPL"}, MinNotional: &minNotional})
+	subscribe("/asks", "trade.executed", WebhookFiltersInput{Side: "ask"})
+	paused := subscribe("/paused", "trade.executed", WebhookFiltersInput{})
+	subscribe("/msft-halts", "symbol.halted", WebhookFiltersInput{Symbols: []string{"MSFT"}})
+	subscribe("/bid-halts", "symbol.halted", WebhookFiltersInput{Side: "bid"})
+
+	status := "paused"
+	if _, err := svc.Update(UpdateWebhookRequest{WebhookID: paused, Status: &status}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trade := func(symbol string, side domain.OrderSide, price, qty int64) {
+		svc.DispatchTradeExecuted(context.Background(), "broker-1",
+			&domain.Trade{TradeID: "trd", Price: price, Quantity: qty, ExecutedAt: time.Now()},
+			&domain.Order{OrderID: "ord", BrokerID: "broker-1", Symbol: symbol, Side: side, Status: domain.OrderStatusFilled})
+	}
+	trade("AAPL", domain.OrderSideBid, 14800, 500) // $74,000
+	trade("AAPL", domain.OrderSideBid, 500, 1)     // $5
+	trade("MSFT", domain.OrderSideAsk, 10000, 10)  // $1,000
+	// A side filter does not apply to an event without a side.
+	svc.DispatchSymbolHalted(context.Background(), "AAPL", "")
+
+	want := map[string]int{"/all": 3, "/aapl-large": 1, "/asks": 1, "/paused": 0, "/msft-halts": 0, "/bid-halts": 1}
+	waitFor(t, "the matching deliveries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, events := range received {
+			n += len(events)
+		}
+		return n == 6
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for path, want := range want {
+		if got := len(received[path]); got != want {
+			t.Errorf("%s: got %d deliveries, want %d", path, got, want)
+		}
+	}
+}
+
+func TestDispatch_PayloadVersionPerSubscription(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]map[string]any) // path → payload
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		received[r.URL.Path] = payload
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	bs := store.NewBrokerStore()
+	svc := newDeliveringWebhookService(t, store.NewWebhookStore(), bs, server.Client(), DeliveryPolicy{})
+	registerBroker(t, bs, "broker-1")
+	for path, version := range map[string]int{"/v1": 1, "/latest": 0} {
+		if _, _, err := svc.Upsert(UpsertWebhookRequest{
+			BrokerID: "broker-1",
+			URL:      server.URL + path,
+			Events:   []string{"order.filled"},
+			Version:  version,
+		}); err != nil {
+			t.Fatalf("subscribe %s: %v", path, err)
+		}
+	}
+
+	executedAt := time.Date(2026, 2, 16, 16, 29, 0, 0, time.UTC)
+	svc.DispatchOrderFilled(context.Background(), &domain.Order{
+		OrderID: "ord-1", BrokerID: "broker-1", Type: domain.OrderTypeLimit, Symbol: "AAPL", Side: domain.OrderSideBid,
+		Price: 15000, Quantity: 10, FilledQuantity: 10, Status: domain.OrderStatusFilled,
+		Trades: []*domain.Trade{
+			{TradeID: "trd-1", Price: 14900, Quantity: 4, ExecutedAt: executedAt},
+			{TradeID: "trd-2", Price: 15000, Quantity: 6, ExecutedAt: executedAt},
+		},
+	})
+	waitFor(t, "both deliveries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	v1, latest := received["/v1"], received["/latest"]
+	if v1["version"] != 1.0 || latest["version"] != 2.0 || v1["sequence"] != latest["sequence"] {
+		t.Errorf("got versions %v and %v, sequences %v and %v", v1["version"], latest["version"], v1["sequence"], latest["sequence"])
+	}
+	if d := v1["data"].(map[string]any); d["trade_count"] != 2.0 || d["fills"] != nil {
+		t.Errorf("unexpected version 1 data: %v", d)
+	}
+	d := latest["data"].(map[string]any)
+	fills, _ := d["fills"].([]any)
+	if d["trade_count"] != nil || len(fills) != 2 {
+		t.Fatalf("unexpected version 2 data: %v", d)
+	}
+	want := map[string]any{"trade_id": "trd-1", "price": 149.0, "quantity": 4.0, "executed_at": "2026-02-16T16:29:00Z"}
+	for k, v := range want {
+		if fills[0].(map[string]any)[k] != v {
+			t.Errorf("first fill: got %v, want %v", fills[0], want)
+			break
+		}
+	}
+}
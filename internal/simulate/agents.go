@@ -0,0 +1,153 @@
+package simulate
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// agent is a participant that acts once in every step.
+type agent interface {
+	act(s *sim)
+	acct() *account
+}
+
+// account is an agent's broker and what it has done.
+type account struct {
+	population     string
+	brokerID       string
+	documentNumber string
+	orders         []*domain.Order // accepted orders, in submission order
+	rejected       int
+	cancelled      int
+}
+
+func newAccount(population string, n int) account {
+	return account{
+		population:     population,
+		brokerID:       fmt.Sprintf("%s-%d", population, n),
+		documentNumber: fmt.Sprintf("%s%d", population, n),
+	}
+}
+
+func (a *account) acct() *account { return a }
+
+// noiseTrader sends random orders around the reference price.
+type noiseTrader struct {
+	account
+}
+
+func (t *noiseTrader) act(s *sim) {
+	cfg := s.cfg.Noise
+	if s.rng.Float64() >= cfg.Rate {
+		return
+	}
+	m := s.markets[s.rng.IntN(len(s.markets))]
+	side := domain.OrderSideBid
+	if s.rng.IntN(2) == 1 {
+		side = domain.OrderSideAsk
+	}
+	quantity := 1 + s.rng.Int64N(cfg.MaxQuantity)
+	if s.rng.Float64() < cfg.MarketRatio {
+		s.submitMarket(&t.account, m, side, quantity)
+		return
+	}
+	price := m.reference * (1 + cfg.PriceSigma*s.rng.NormFloat64())
+	s.submitLimit(&t.account, m, side, max(int64(math.Round(price)), 1), quantity, cfg.Lifetime)
+}
+
+// marketMaker keeps a quote on both sides of every market.
+type marketMaker struct {
+	account
+	quotes [][]*domain.Order // open quotes, by market
+}
+
+func (mm *marketMaker) act(s *sim) {
+	cfg := s.cfg.Maker
+	balance, err := s.x.brokers.GetBalance(mm.brokerID)
+	if err != nil {
+		return
+	}
+	halfSpread := int64(math.Round(cfg.HalfSpread * 100))
+	for i, m := range s.markets {
+		for _, q := range mm.quotes[i] {
+			s.cancel(&mm.account, q)
+		}
+		mm.quotes[i] = mm.quotes[i][:0]
+
+		var inventory int64
+		for _, h := range balance.Holdings {
+			if h.Symbol == m.symbol {
+				inventory = h.Quantity - s.cfg.Shares
+			}
+		}
+		bid, ask := quote(m.referenceCents(), halfSpread, inventory, cfg.Quantity)
+
+		// Quotes outlive the step, so the book is never empty between a
+		// maker's turns, and expire if the maker stops requoting.
+		lifetime := 2 * s.cfg.StepInterval
+		if bid > 0 {
+			if o := s.submitLimit(&mm.account, m, domain.OrderSideBid, bid, cfg.Quantity, lifetime); o != nil {
+				mm.quotes[i] = append(mm.quotes[i], o)
+			}
+		}
+		if o := s.submitLimit(&mm.account, m, domain.OrderSideAsk, ask, cfg.Quantity, lifetime); o != nil {
+			mm.quotes[i] = append(mm.quotes[i], o)
+		}
+	}
+}
+
+// quote returns a market maker's bid and ask around reference, both moved a
+// cent against inventory for every quantity shares of it. A bid that would
+// not be a valid price is returned as 0, and not sent.
+func quote(reference, halfSpread, inventory, quantity int64) (bid, ask int64) {
+	skew := inventory / quantity
+	bid = reference - halfSpread - skew
+	ask = max(reference+halfSpread-skew, bid+1, 1)
+	return max(bid, 0), ask
+}
+
+// momentumTaker follows moves of the last trade price with market orders.
+type momentumTaker struct {
+	account
+	acted []int // step of the last order, by market
+}
+
+func (t *momentumTaker) act(s *sim) {
+	cfg := s.cfg.Momentum
+	for i, m := range s.markets {
+		if t.acted[i] > 0 && s.step-t.acted[i] < cfg.Lookback {
+			continue
+		}
+		side, ok := momentum(m.history, cfg.Lookback, cfg.Threshold)
+		if !ok {
+			continue
+		}
+		s.submitMarket(&t.account, m, side, cfg.Quantity)
+		t.acted[i] = s.step
+	}
+}
+
+// momentum returns the side to trade after the last price in history moved
+// by at least threshold over lookback steps: bid after a rise and ask after
+// a fall. It reports false when the move is smaller or the market had not
+// traded lookback steps ago.
+func momentum(history []int64, lookback int, threshold float64) (domain.OrderSide, bool) {
+	n := len(history)
+	if n <= lookback {
+		return "", false
+	}
+	from, to := history[n-1-lookback], history[n-1]
+	if from == 0 {
+		return "", false
+	}
+	change := float64(to-from) / float64(from)
+	switch {
+	case change >= threshold:
+		return domain.OrderSideBid, true
+	case change <= -threshold:
+		return domain.OrderSideAsk, true
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package simulate
+
+import (
+	"testing"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name                                       string
+		reference, halfSpread, inventory, quantity int64
+		wantBid, wantAsk                           int64
+	}{
+		{"flat", 10000, 5, 0, 100, 9995, 10005},
+		{"partial lot", 10000, 5, 99, 100, 9995, 10005},
+		{"long", 10000, 5, 250, 100, 9993, 10003},
+		{"short", 10000, 5, -300, 100, 9998, 10008},
+		{"bid below a cent", 3, 5, 0, 100, 0, 8},
+		{"ask floored", 3, 5, 2000, 100, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bid, ask := quote(tt.reference, tt.halfSpread, tt.inventory, tt.quantity)
+			if bid != tt.wantBid || ask != tt.wantAsk {
+				t.Errorf("expected %d/%d, got %d/%d", tt.wantBid, tt.wantAsk, bid, ask)
+			}
+		})
+	}
+}
+
+func TestMomentum(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []int64
+		wantSide domain.OrderSide
+		wantOK   bool
+	}{
+		{"rise", []int64{10000, 10010, 10020}, domain.OrderSideBid, true},
+		{"fall", []int64{10000, 9990, 9980}, domain.OrderSideAsk, true},
+		{"small move", []int64{10000, 10010, 10019}, "", false},
+		{"short history", []int64{10000, 10100}, "", false},
+		{"not yet traded", []int64{0, 10000, 10100}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			side, ok := momentum(tt.history, 2, 0.002)
+			if side != tt.wantSide || ok != tt.wantOK {
+				t.Errorf("expected %q/%v, got %q/%v", tt.wantSide, tt.wantOK, side, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+// Package simulate runs populations of trading agents against a fresh
+// exchange: noise traders placing random orders, market makers quoting
+// around a reference price and momentum takers chasing price moves. Every
+// agent registers a broker through the broker service and trades through
+// the order service, as a client of the API would.
+//
+// The exchange runs on a virtual clock that advances one step at a time and
+// issues sequential IDs, and all randomness comes from one generator seeded
+// from the configuration, so the same configuration produces the same
+// summary on every run.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/clock"
+	"github.com/efreitasn/miniexchange/internal/domain"
+	"github.com/efreitasn/miniexchange/internal/engine"
+	"github.com/efreitasn/miniexchange/internal/service"
+	"github.com/efreitasn/miniexchange/internal/store"
+)
+
+// Population names, as reported in the summary.
+const (
+	PopulationNoise    = "noise"
+	PopulationMaker    = "maker"
+	PopulationMomentum = "momentum"
+)
+
+// start is the virtual time the simulation starts at.
+var start = time.Unix(0, 0).UTC()
+
+// Config describes a simulation.
+type Config struct {
+	Seed         uint64
+	Steps        int
+	StepInterval time.Duration // virtual time between steps
+
+	Symbols        []string
+	ReferencePrice float64 // initial reference price of every symbol, in dollars
+	Volatility     float64 // standard deviation of the reference's relative change per step
+
+	Cash   float64 // initial cash of every agent, in dollars
+	Shares int64   // initial holding of every agent in every symbol
+
+	PathPoints int // price path samples per symbol in the summary
+
+	Noise    NoiseConfig
+	Maker    MakerConfig
+	Momentum MomentumConfig
+}
+
+// NoiseConfig describes the noise traders. In a step each one acts with
+// probability Rate, sending a market order or a limit order priced around
+// the reference for a random symbol, side and quantity.
+type NoiseConfig struct {
+	Count       int
+	Rate        float64       // probability of acting in a step
+	MarketRatio float64       // share of orders that are market orders
+	PriceSigma  float64       // standard deviation of a limit price's distance from the reference, relative to it
+	MaxQuantity int64         // orders are for 1 to MaxQuantity shares
+	Lifetime    time.Duration // how long limit orders rest before expiring
+}
+
+// MakerConfig describes the market makers. In every step each one cancels
+// its open quotes and quotes both sides of every symbol HalfSpread away from
+// the reference, skewing both quotes down a cent for every Quantity shares
+// of inventory it has accumulated, and up for inventory it has sold.
+type MakerConfig struct {
+	Count      int
+	HalfSpread float64 // distance of each quote from the reference, in dollars
+	Quantity   int64   // size of each quote
+}
+
+// MomentumConfig describes the momentum takers. In every step each one
+// sends a market order in the direction of the last trade price's move over
+// Lookback steps, when the move is at least Threshold, and then waits
+// Lookback steps before trading that symbol again.
+type MomentumConfig struct {
+	Count     int
+	Lookback  int     // steps over which the move is measured
+	Threshold float64 // relative move that triggers an order
+	Quantity  int64
+}
+
+// DefaultConfig returns a small market: two symbols, twenty noise traders,
+// two market makers and five momentum takers trading for a thousand
+// one-second steps.
+func DefaultConfig() Config {
+	return Config{
+		Seed:           1,
+		Steps:          1000,
+		StepInterval:   time.Second,
+		Symbols:        []string{"AAPL", "MSFT"},
+		ReferencePrice: 100,
+		Volatility:     0.001,
+		Cash:           1_000_000,
+		Shares:         10_000,
+		PathPoints:     20,
+		Noise: NoiseConfig{
+			Count:       20,
+			Rate:        0.2,
+			MarketRatio: 0.2,
+			PriceSigma:  0.002,
+			MaxQuantity: 100,
+			Lifetime:    30 * time.Second,
+		},
+		Maker: MakerConfig{
+			Count:      2,
+			HalfSpread: 0.05,
+			Quantity:   100,
+		},
+		Momentum: MomentumConfig{
+			Count:     5,
+			Lookback:  10,
+			Threshold: 0.002,
+			Quantity:  50,
+		},
+	}
+}
+
+// validate reports the first setting a simulation cannot run with.
+func (c Config) validate() error {
+	switch {
+	case c.Steps < 1:
+		return errors.New("steps must be at least 1")
+	case c.StepInterval <= 0:
+		return errors.New("step interval must be positive")
+	case len(c.Symbols) == 0:
+		return errors.New("at least one symbol is required")
+	case c.ReferencePrice <= 0:
+		return errors.New("reference price must be positive")
+	case c.Volatility < 0:
+		return errors.New("volatility must not be negative")
+	case c.PathPoints < 1:
+		return errors.New("path points must be at least 1")
+	case c.Noise.Count < 0 || c.Maker.Count < 0 || c.Momentum.Count < 0:
+		return errors.New("agent counts must not be negative")
+	case c.Noise.Count+c.Maker.Count+c.Momentum.Count == 0:
+		return errors.New("at least one agent is required")
+	case c.Noise.Count > 0 && (c.Noise.Rate < 0 || c.Noise.Rate > 1):
+		return errors.New("noise rate must be between 0 and 1")
+	case c.Noise.Count > 0 && (c.Noise.MarketRatio < 0 || c.Noise.MarketRatio > 1):
+		return errors.New("noise market ratio must be between 0 and 1")
+	case c.Noise.Count > 0 && c.Noise.PriceSigma < 0:
+		return errors.New("noise price sigma must not be negative")
+	case c.Noise.Count > 0 && c.Noise.MaxQuantity < 1:
+		return errors.New("noise max quantity must be at least 1")
+	case c.Noise.Count > 0 && c.Noise.Lifetime <= 0:
+		return errors.New("noise order lifetime must be positive")
+	case c.Maker.Count > 0 && c.Maker.HalfSpread <= 0:
+		return errors.New("maker half spread must be positive")
+	case c.Maker.Count > 0 && c.Maker.Quantity < 1:
+		return errors.New("maker quantity must be at least 1")
+	case c.Momentum.Count > 0 && c.Momentum.Lookback < 1:
+		return errors.New("momentum lookback must be at least 1")
+	case c.Momentum.Count > 0 && c.Momentum.Threshold <= 0:
+		return errors.New("momentum threshold must be positive")
+	case c.Momentum.Count > 0 && c.Momentum.Quantity < 1:
+		return errors.New("momentum quantity must be at least 1")
+	}
+	return nil
+}
+
+// Summary is what a simulation did, per symbol and per population. Prices
+// and amounts are in dollars.
+type Summary struct {
+	Seed        uint64              `json:"seed"`
+	Steps       int                 `json:"steps"`
+	Duration    string              `json:"duration"`
+	Symbols     []SymbolSummary     `json:"symbols"`
+	Populations []PopulationSummary `json:"populations"`
+}
+
+// SymbolSummary is the trading in one symbol. Prices are nil when the
+// symbol never traded, and the average spread is nil when the book never
+// had both sides at the end of a step.
+type SymbolSummary struct {
+	Symbol         string       `json:"symbol"`
+	Trades         int          `json:"trades"`
+	Volume         int64        `json:"volume"`
+	Notional       float64      `json:"notional"`
+	VWAP           *float64     `json:"vwap"`
+	Open           *float64     `json:"open"`
+	High           *float64     `json:"high"`
+	Low            *float64     `json:"low"`
+	Close          *float64     `json:"close"`
+	ReferencePrice float64      `json:"reference_price"` // final reference price
+	AverageSpread  *float64     `json:"average_spread"`
+	SpreadSamples  int          `json:"spread_samples"`
+	Path           []PricePoint `json:"path"`
+}
+
+// PricePoint is the last trade price at the end of a step.
+type PricePoint struct {
+	Step  int     `json:"step"`
+	Price float64 `json:"price"`
+}
+
+// PopulationSummary is the activity of one population. Bought and Sold are
+// shares filled across all symbols. PnL marks both the initial and final
+// holdings at each symbol's close, or its initial reference price if it
+// never traded, so the populations' PnL sums to zero.
+type PopulationSummary struct {
+	Population string  `json:"population"`
+	Agents     int     `json:"agents"`
+	Orders     int     `json:"orders"`
+	Rejected   int     `json:"rejected"`
+	Cancelled  int     `json:"cancelled"`
+	Bought     int64   `json:"bought"`
+	Sold       int64   `json:"sold"`
+	PnL        float64 `json:"pnl"`
+}
+
+// market is the state of one symbol as the agents see it.
+type market struct {
+	symbol    string
+	reference float64 // in cents, unrounded so the random walk does not drift
+	bestBid   int64   // 0 when the bid side is empty
+	bestAsk   int64   // 0 when the ask side is empty
+	last      int64   // last trade price, 0 before the first trade
+	history   []int64 // last trade price at the end of each step
+
+	trades           int
+	volume, notional int64
+	open, high, low  int64
+	spreadSum        int64
+	spreadSamples    int
+}
+
+// referenceCents returns the reference rounded to a valid price.
+func (m *market) referenceCents() int64 {
+	return max(int64(math.Round(m.reference)), 1)
+}
+
+// exchange is a fresh exchange on a virtual clock, wired as the server wires
+// it but without webhooks, metrics or an audit log.
+type exchange struct {
+	clock   *clock.Virtual
+	expiry  *engine.ExpiryManager
+	brokers *service.BrokerService
+	orders  *service.OrderService
+	markets map[string]*market
+}
+
+func newExchange() *exchange {
+	clk := clock.NewVirtual(start)
+	ids := clock.NewSequence()
+
+	brokerStore := store.NewBrokerStore()
+	orderStore := store.NewOrderStore()
+	tradeStore := store.NewTradeStore()
+	symbols := domain.NewSymbolRegistry()
+
+	books := engine.NewBookManager(clk)
+	matcher := engine.NewMatcher(books, brokerStore, orderStore, tradeStore, symbols, nil, nil, clk, ids)
+	expiry := engine.NewExpiryManager(time.Second, books, orderStore, brokerStore, nil, nil, nil, clk)
+
+	x := &exchange{
+		clock:   clk,
+		expiry:  expiry,
+		brokers: service.NewBrokerService(brokerStore, symbols, clk),
+		orders:  service.NewOrderService(matcher, expiry, brokerStore, orderStore, tradeStore, nil, symbols, nil, nil, clk, ids),
+		markets: make(map[string]*market),
+	}
+	books.AddListener(x)
+	return x
+}
+
+// OnBookUpdate tracks the top of the book and the trades of every market.
+// Book updates are published on the goroutine that made them, so this needs
+// no lock.
+func (x *exchange) OnBookUpdate(u *engine.BookUpdate) {
+	m, ok := x.markets[u.Symbol]
+	if !ok {
+		return
+	}
+	m.bestBid, m.bestAsk = 0, 0
+	if u.BestBid != nil {
+		m.bestBid = u.BestBid.Price
+	}
+	if u.BestAsk != nil {
+		m.bestAsk = u.BestAsk.Price
+	}
+	for _, t := range u.Trades {
+		if m.trades == 0 {
+			m.open, m.high, m.low = t.Price, t.Price, t.Price
+		}
+		m.trades++
+		m.volume += t.Quantity
+		m.notional += t.Price * t.Quantity
+		m.high = max(m.high, t.Price)
+		m.low = min(m.low, t.Price)
+		m.last = t.Price
+	}
+}
+
+// sim is a running simulation.
+type sim struct {
+	cfg     Config
+	rng     *rand.Rand
+	x       *exchange
+	markets []*market // in configuration order
+	step    int
+}
+
+// Run runs the simulation cfg describes and summarises it.
+func Run(cfg Config) (*Summary, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	s := &sim{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		x:   newExchange(),
+	}
+	for _, symbol := range cfg.Symbols {
+		if _, ok := s.x.markets[symbol]; ok {
+			return nil, fmt.Errorf("symbol %s is listed twice", symbol)
+		}
+		m := &market{symbol: symbol, reference: cfg.ReferencePrice * 100}
+		s.x.markets[symbol] = m
+		s.markets = append(s.markets, m)
+	}
+
+	agents, err := s.register()
+	if err != nil {
+		return nil, err
+	}
+
+	for s.step = 1; s.step <= cfg.Steps; s.step++ {
+		s.x.clock.Advance(cfg.StepInterval)
+		s.x.expiry.ExpireDue()
+		for _, m := range s.markets {
+			m.reference *= math.Exp(cfg.Volatility * s.rng.NormFloat64())
+		}
+		s.rng.Shuffle(len(agents), func(i, j int) { agents[i], agents[j] = agents[j], agents[i] })
+		for _, a := range agents {
+			a.act(s)
+		}
+		for _, m := range s.markets {
+			m.history = append(m.history, m.last)
+			if m.bestBid > 0 && m.bestAsk > 0 {
+				m.spreadSum += m.bestAsk - m.bestBid
+				m.spreadSamples++
+			}
+		}
+	}
+
+	return s.summarize(agents)
+}
+
+// register creates every agent and registers its broker.
+func (s *sim) register() ([]agent, error) {
+	var agents []agent
+	for i := range s.cfg.Noise.Count {
+		agents = append(agents, &noiseTrader{account: newAccount(PopulationNoise, i+1)})
+	}
+	for i := range s.cfg.Maker.Count {
+		agents = append(agents, &marketMaker{
+			account: newAccount(PopulationMaker, i+1),
+			quotes:  make([][]*domain.Order, len(s.markets)),
+		})
+	}
+	for i := range s.cfg.Momentum.Count {
+		agents = append(agents, &momentumTaker{
+			account: newAccount(PopulationMomentum, i+1),
+			acted:   make([]int, len(s.markets)),
+		})
+	}
+
+	holdings := make([]service.HoldingInput, len(s.cfg.Symbols))
+	for i, symbol := range s.cfg.Symbols {
+		holdings[i] = service.HoldingInput{Symbol: symbol, Quantity: s.cfg.Shares}
+	}
+	for _, a := range agents {
+		_, err := s.x.brokers.Register(service.RegisterBrokerRequest{
+			BrokerID:        a.acct().brokerID,
+			InitialCash:     s.cfg.Cash,
+			InitialHoldings: holdings,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("register %s: %w", a.acct().brokerID, err)
+		}
+	}
+	return agents, nil
+}
+
+// submitLimit sends a limit order that rests for lifetime, and returns it
+// when the exchange accepts it.
+func (s *sim) submitLimit(a *account, m *market, side domain.OrderSide, price, quantity int64, lifetime time.Duration) *domain.Order {
+	dollars := domain.CentsToDollars(price)
+	expiresAt := s.x.clock.Now().Add(lifetime)
+	return s.submit(a, service.SubmitOrderRequest{
+		Type:      domain.OrderTypeLimit,
+		Side:      side,
+		Symbol:    m.symbol,
+		Price:     &dollars,
+		Quantity:  quantity,
+		ExpiresAt: &expiresAt,
+	})
+}
+
+// submitMarket sends a market order and returns it when the exchange
+// accepts it.
+func (s *sim) submitMarket(a *account, m *market, side domain.OrderSide, quantity int64) *domain.Order {
+	return s.submit(a, service.SubmitOrderRequest{
+		Type:     domain.OrderTypeMarket,
+		Side:     side,
+		Symbol:   m.symbol,
+		Quantity: quantity,
+	})
+}
+
+func (s *sim) submit(a *account, req service.SubmitOrderRequest) *domain.Order {
+	req.BrokerID = a.brokerID
+	req.DocumentNumber = a.documentNumber
+	order, err := s.x.orders.SubmitOrder(context.Background(), req)
+	if err != nil {
+		a.rejected++
+		return nil
+	}
+	a.orders = append(a.orders, order)
+	return order
+}
+
+// cancel cancels an order that is still open.
+func (s *sim) cancel(a *account, order *domain.Order) {
+	if order.Status != domain.OrderStatusPending && order.Status != domain.OrderStatusPartiallyFilled {
+		return
+	}
+	if _, err := s.x.orders.CancelOrder(context.Background(), order.OrderID); err == nil {
+		a.cancelled++
+	}
+}
+
+func (s *sim) summarize(agents []agent) (*Summary, error) {
+	summary := &Summary{
+		Seed:     s.cfg.Seed,
+		Steps:    s.cfg.Steps,
+		Duration: (time.Duration(s.cfg.Steps) * s.cfg.StepInterval).String(),
+	}
+
+	marks := make(map[string]int64, len(s.markets))
+	every := max(s.cfg.Steps/s.cfg.PathPoints, 1)
+	for _, m := range s.markets {
+		sym := SymbolSummary{
+			Symbol:         m.symbol,
+			Trades:         m.trades,
+			Volume:         m.volume,
+			Notional:       domain.CentsToDollars(m.notional),
+			ReferencePrice: domain.CentsToDollars(m.referenceCents()),
+			SpreadSamples:  m.spreadSamples,
+			Path:           []PricePoint{},
+		}
+		marks[m.symbol] = int64(math.Round(s.cfg.ReferencePrice * 100))
+		if m.trades > 0 {
+			sym.VWAP = dollars(float64(m.notional) / float64(m.volume))
+			sym.Open = dollars(float64(m.open))
+			sym.High = dollars(float64(m.high))
+			sym.Low = dollars(float64(m.low))
+			sym.Close = dollars(float64(m.last))
+			marks[m.symbol] = m.last
+		}
+		if m.spreadSamples > 0 {
+			sym.AverageSpread = dollars(float64(m.spreadSum) / float64(m.spreadSamples))
+		}
+		for step := every; step <= s.cfg.Steps; step += every {
+			if step+every > s.cfg.Steps {
+				step = s.cfg.Steps
+			}
+			if price := m.history[step-1]; price > 0 {
+				sym.Path = append(sym.Path, PricePoint{Step: step, Price: domain.CentsToDollars(price)})
+			}
+		}
+		summary.Symbols = append(summary.Symbols, sym)
+	}
+
+	initialCash := int64(math.Round(s.cfg.Cash * 100))
+	populations := make(map[string]*PopulationSummary)
+	pnl := make(map[string]int64)
+	for _, name := range []string{PopulationNoise, PopulationMaker, PopulationMomentum} {
+		populations[name] = &PopulationSummary{Population: name}
+	}
+	for _, a := range agents {
+		acct := a.acct()
+		pop := populations[acct.population]
+		pop.Agents++
+		pop.Orders += len(acct.orders)
+		pop.Rejected += acct.rejected
+		pop.Cancelled += acct.cancelled
+		for _, o := range acct.orders {
+			if o.Side == domain.OrderSideBid {
+				pop.Bought += o.FilledQuantity
+			} else {
+				pop.Sold += o.FilledQuantity
+			}
+		}
+
+		balance, err := s.x.brokers.GetBalance(acct.brokerID)
+		if err != nil {
+			return nil, err
+		}
+		held := make(map[string]int64, len(balance.Holdings))
+		for _, h := range balance.Holdings {
+			held[h.Symbol] = h.Quantity
+		}
+		pnl[acct.population] += balance.CashBalance - initialCash
+		for _, symbol := range s.cfg.Symbols {
+			pnl[acct.population] += (held[symbol] - s.cfg.Shares) * marks[symbol]
+		}
+	}
+	for _, name := range []string{PopulationNoise, PopulationMaker, PopulationMomentum} {
+		if pop := populations[name]; pop.Agents > 0 {
+			pop.PnL = domain.CentsToDollars(pnl[name])
+			summary.Populations = append(summary.Populations, *pop)
+		}
+	}
+	return summary, nil
+}
+
+// dollars converts a price in cents to dollars, rounded to the cent.
+func dollars(cents float64) *float64 {
+	v := domain.CentsToDollars(int64(math.Round(cents)))
+	return &v
+}
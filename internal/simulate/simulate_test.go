@@ -0,0 +1,151 @@
+package simulate
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun_Default(t *testing.T) {
+	cfg := DefaultConfig()
+	summary, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(summary.Symbols) != len(cfg.Symbols) {
+		t.Fatalf("expected %d symbols, got %d", len(cfg.Symbols), len(summary.Symbols))
+	}
+	var volume int64
+	for _, sym := range summary.Symbols {
+		if sym.Trades == 0 || sym.Close == nil {
+			t.Fatalf("%s: expected trades, got %+v", sym.Symbol, sym)
+		}
+		if *sym.Low > *sym.Close || *sym.Close > *sym.High {
+			t.Errorf("%s: close %v outside [%v, %v]", sym.Symbol, *sym.Close, *sym.Low, *sym.High)
+		}
+		if sym.AverageSpread == nil || *sym.AverageSpread <= 0 {
+			t.Errorf("%s: expected a positive average spread, got %v", sym.Symbol, sym.AverageSpread)
+		}
+		if len(sym.Path) != cfg.PathPoints {
+			t.Errorf("%s: expected %d path points, got %d", sym.Symbol, cfg.PathPoints, len(sym.Path))
+		} else if last := sym.Path[len(sym.Path)-1]; last.Step != cfg.Steps || last.Price != *sym.Close {
+			t.Errorf("%s: expected the path to end at step %d at the close, got %+v", sym.Symbol, cfg.Steps, last)
+		}
+		volume += sym.Volume
+	}
+
+	// Every share bought was sold by another agent, and the populations
+	// only traded with each other.
+	var bought, sold int64
+	var pnl float64
+	for _, pop := range summary.Populations {
+		bought += pop.Bought
+		sold += pop.Sold
+		pnl += pop.PnL
+	}
+	if bought != volume || sold != volume {
+		t.Errorf("expected bought and sold to equal the volume %d, got %d and %d", volume, bought, sold)
+	}
+	if math.Abs(pnl) > 0.005 {
+		t.Errorf("expected the populations' PnL to sum to zero, got %v", pnl)
+	}
+
+	want := []string{PopulationNoise, PopulationMaker, PopulationMomentum}
+	for i, pop := range summary.Populations {
+		if pop.Population != want[i] {
+			t.Errorf("population %d: expected %s, got %s", i, want[i], pop.Population)
+		}
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Steps = 200
+
+	first, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	second, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same seed to produce the same summary")
+	}
+
+	cfg.Seed++
+	third, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if reflect.DeepEqual(first.Symbols, third.Symbols) {
+		t.Errorf("expected a different seed to produce a different market")
+	}
+}
+
+func TestRun_MakersOnly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Steps = 50
+	cfg.Volatility = 0
+	cfg.Noise.Count = 0
+	cfg.Momentum.Count = 0
+
+	summary, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, sym := range summary.Symbols {
+		if sym.Trades != 0 || sym.Close != nil || len(sym.Path) != 0 {
+			t.Errorf("%s: expected makers not to trade with each other, got %+v", sym.Symbol, sym)
+		}
+		if sym.AverageSpread == nil || *sym.AverageSpread != 2*cfg.Maker.HalfSpread {
+			t.Errorf("%s: expected the makers' spread %v, got %v", sym.Symbol, 2*cfg.Maker.HalfSpread, sym.AverageSpread)
+		}
+	}
+	if len(summary.Populations) != 1 || summary.Populations[0].Population != PopulationMaker {
+		t.Fatalf("expected only the maker population, got %+v", summary.Populations)
+	}
+	maker := summary.Populations[0]
+	quotes := cfg.Steps * len(cfg.Symbols) * 2 * cfg.Maker.Count
+	if maker.Orders != quotes || maker.Rejected != 0 || maker.PnL != 0 {
+		t.Errorf("expected %d quotes and no rejections or PnL, got %+v", quotes, maker)
+	}
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"no steps", func(c *Config) { c.Steps = 0 }, "steps"},
+		{"no symbols", func(c *Config) { c.Symbols = nil }, "symbol"},
+		{"duplicate symbol", func(c *Config) { c.Symbols = []string{"AAPL", "AAPL"} }, "listed twice"},
+		{"invalid symbol", func(c *Config) { c.Symbols = []string{"aapl"} }, "register"},
+		{"no agents", func(c *Config) { c.Noise.Count, c.Maker.Count, c.Momentum.Count = 0, 0, 0 }, "agent"},
+		{"noise rate", func(c *Config) { c.Noise.Rate = 1.5 }, "noise rate"},
+		{"maker spread", func(c *Config) { c.Maker.HalfSpread = 0 }, "half spread"},
+		{"momentum lookback", func(c *Config) { c.Momentum.Lookback = 0 }, "lookback"},
+		{"unused population", func(c *Config) { c.Maker.Count, c.Maker.HalfSpread = 0, 0 }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Steps = 10
+			tt.modify(&cfg)
+			_, err := Run(cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
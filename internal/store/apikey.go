@@ -0,0 +1,141 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// APIKeyStore is a thread-safe in-memory store for API keys.
+// Primary index: key_id → key.
+// Secondary indexes: secret hash → key, and broker_id → key_id → key.
+// Keys are returned as copies, so callers never share state with the store.
+type APIKeyStore struct {
+	mu       sync.RWMutex
+	keys     map[string]*domain.APIKey            // key_id → key
+	byHash   map[string]*domain.APIKey            // hash → key
+	byBroker map[string]map[string]*domain.APIKey // broker_id → key_id → key
+}
+
+// NewAPIKeyStore creates an empty APIKeyStore.
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		keys:     make(map[string]*domain.APIKey),
+		byHash:   make(map[string]*domain.APIKey),
+		byBroker: make(map[string]map[string]*domain.APIKey),
+	}
+}
+
+// Create adds a key to all indexes.
+func (s *APIKeyStore) Create(k *domain.APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *k
+	s.keys[k.KeyID] = &stored
+	s.byHash[k.Hash] = &stored
+	if s.byBroker[k.BrokerID] == nil {
+		s.byBroker[k.BrokerID] = make(map[string]*domain.APIKey)
+	}
+	s.byBroker[k.BrokerID][k.KeyID] = &stored
+}
+
+// Get retrieves a key by ID. It returns domain.ErrAPIKeyNotFound if the key
+// does not exist.
+func (s *APIKeyStore) Get(keyID string) (*domain.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	c := *k
+	return &c, nil
+}
+
+// GetByHash retrieves the key whose secret hashes to hash. It returns
+// domain.ErrAPIKeyNotFound if there is none.
+func (s *APIKeyStore) GetByHash(hash string) (*domain.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.byHash[hash]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	c := *k
+	return &c, nil
+}
+
+// ListByBroker returns a broker's keys, oldest first.
+// Returns an empty slice if the broker has no keys.
+func (s *APIKeyStore) ListByBroker(brokerID string) []*domain.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := s.byBroker[brokerID]
+	result := make([]*domain.APIKey, 0, len(keys))
+	for _, k := range keys {
+		c := *k
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].KeyID < result[j].KeyID
+	})
+	return result
+}
+
+// CountByBroker returns how many keys a broker has.
+func (s *APIKeyStore) CountByBroker(brokerID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.byBroker[brokerID])
+}
+
+// Rotate replaces a key's secret hash and prefix. The old secret stops
+// working immediately. It returns domain.ErrAPIKeyNotFound if the key does
+// not exist.
+func (s *APIKeyStore) Rotate(keyID, hash, prefix string, at time.Time) (*domain.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	delete(s.byHash, k.Hash)
+	k.Hash = hash
+	k.Prefix = prefix
+	k.RotatedAt = &at
+	s.byHash[hash] = k
+	c := *k
+	return &c, nil
+}
+
+// Delete removes a key from all indexes. It returns
+// domain.ErrAPIKeyNotFound if the key does not exist.
+func (s *APIKeyStore) Delete(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	delete(s.keys, keyID)
+	delete(s.byHash, k.Hash)
+	if keys, ok := s.byBroker[k.BrokerID]; ok {
+		delete(keys, keyID)
+		if len(keys) == 0 {
+			delete(s.byBroker, k.BrokerID)
+		}
+	}
+	return nil
+}
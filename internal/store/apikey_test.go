@@ -0,0 +1,155 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+func newTestAPIKey(id, brokerID, hash string, createdAt time.Time) *domain.APIKey {
+	return &domain.APIKey{
+		KeyID:     id,
+		BrokerID:  brokerID,
+		Hash:      hash,
+		Prefix:    "mx_" + hash,
+		CreatedAt: createdAt,
+	}
+}
+
+func TestAPIKeyStore_CreateAndGet(t *testing.T) {
+	s := NewAPIKeyStore()
+	s.Create(newTestAPIKey("k1", "b1", "h1", time.Now()))
+
+	got, err := s.Get("k1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.BrokerID != "b1" {
+		t.Errorf("BrokerID = %q, want b1", got.BrokerID)
+	}
+
+	got, err = s.GetByHash("h1")
+	if err != nil {
+		t.Fatalf("GetByHash: unexpected error: %v", err)
+	}
+	if got.KeyID != "k1" {
+		t.Errorf("KeyID = %q, want k1", got.KeyID)
+	}
+}
+
+func TestAPIKeyStore_Get_NotFound(t *testing.T) {
+	s := NewAPIKeyStore()
+	if _, err := s.Get("missing"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("Get: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, err := s.GetByHash("missing"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("GetByHash: expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKeyStore_ReturnsCopies(t *testing.T) {
+	s := NewAPIKeyStore()
+	k := newTestAPIKey("k1", "b1", "h1", time.Now())
+	s.Create(k)
+	k.BrokerID = "changed"
+
+	got, _ := s.Get("k1")
+	got.BrokerID = "changed"
+
+	got, _ = s.Get("k1")
+	if got.BrokerID != "b1" {
+		t.Errorf("stored key was modified through a returned pointer: BrokerID = %q", got.BrokerID)
+	}
+}
+
+func TestAPIKeyStore_ListByBroker_OldestFirst(t *testing.T) {
+	s := NewAPIKeyStore()
+	now := time.Now()
+	s.Create(newTestAPIKey("k2", "b1", "h2", now.Add(time.Second)))
+	s.Create(newTestAPIKey("k1", "b1", "h1", now))
+	s.Create(newTestAPIKey("k3", "b2", "h3", now))
+
+	keys := s.ListByBroker("b1")
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].KeyID != "k1" || keys[1].KeyID != "k2" {
+		t.Errorf("order = [%s %s], want [k1 k2]", keys[0].KeyID, keys[1].KeyID)
+	}
+	if s.CountByBroker("b1") != 2 {
+		t.Errorf("CountByBroker = %d, want 2", s.CountByBroker("b1"))
+	}
+	if got := s.ListByBroker("nobody"); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", got)
+	}
+}
+
+func TestAPIKeyStore_Rotate(t *testing.T) {
+	s := NewAPIKeyStore()
+	s.Create(newTestAPIKey("k1", "b1", "old", time.Now()))
+
+	at := time.Now()
+	got, err := s.Rotate("k1", "new", "mx_new", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Hash != "new" || got.RotatedAt == nil || !got.RotatedAt.Equal(at) {
+		t.Errorf("unexpected rotated key: %+v", got)
+	}
+	if _, err := s.GetByHash("old"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("old hash should no longer resolve, got %v", err)
+	}
+	if k, err := s.GetByHash("new"); err != nil || k.KeyID != "k1" {
+		t.Errorf("new hash should resolve to k1, got %v, %v", k, err)
+	}
+
+	if _, err := s.Rotate("missing", "x", "x", at); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKeyStore_Delete(t *testing.T) {
+	s := NewAPIKeyStore()
+	s.Create(newTestAPIKey("k1", "b1", "h1", time.Now()))
+
+	if err := s.Delete("k1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get("k1"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("Get after delete: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, err := s.GetByHash("h1"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("GetByHash after delete: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if s.CountByBroker("b1") != 0 {
+		t.Errorf("expected no keys for b1")
+	}
+	if err := s.Delete("k1"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("second delete: expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKeyStore_ConcurrentAccess(t *testing.T) {
+	s := NewAPIKeyStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("k%d", i)
+			s.Create(newTestAPIKey(id, "b1", "h"+id, time.Now()))
+			s.GetByHash("h" + id)
+			s.Rotate(id, "r"+id, "mx_r", time.Now())
+			s.ListByBroker("b1")
+		}(i)
+	}
+	wg.Wait()
+
+	if got := s.CountByBroker("b1"); got != 50 {
+		t.Errorf("CountByBroker = %d, want 50", got)
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
@@ -55,3 +56,16 @@ func (s *BrokerStore) Exists(id string) bool {
 	_, ok := s.brokers[id]
 	return ok
 }
+
+// IDs returns the IDs of all brokers in sorted order.
+func (s *BrokerStore) IDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.brokers))
+	for id := range s.brokers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -68,6 +68,19 @@ func TestBrokerStore_Exists(t *testing.T) {
 	}
 }
 
+func TestBrokerStore_IDs(t *testing.T) {
+	s := NewBrokerStore()
+	if ids := s.IDs(); ids == nil || len(ids) != 0 {
+		t.Fatalf("expected empty non-nil slice, got %v", ids)
+	}
+	for _, id := range []string{"broker-2", "broker-3", "broker-1"} {
+		_ = s.Create(newTestBroker(id))
+	}
+	if ids := s.IDs(); fmt.Sprint(ids) != "[broker-1 broker-2 broker-3]" {
+		t.Errorf("got %v, want [broker-1 broker-2 broker-3]", ids)
+	}
+}
+
 func TestBrokerStore_ConcurrentAccess(t *testing.T) {
 	s := NewBrokerStore()
 	var wg sync.WaitGroup
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+// DeliveryStore is a thread-safe in-memory store for webhook deliveries.
+// Primary index: delivery_id → delivery.
+// Secondary index: webhook_id → delivery_ids, oldest first.
+// Each webhook keeps at most failedLimit failed and succeededLimit succeeded
+// deliveries; saving one more drops the oldest with the same status. Pending
+// deliveries are never dropped. Deliveries are stored and returned as copies.
+type DeliveryStore struct {
+	mu             sync.RWMutex
+	deliveries     map[string]*domain.WebhookDelivery // delivery_id → delivery
+	byWebhook      map[string][]string                // webhook_id → delivery_ids
+	failedLimit    int
+	succeededLimit int
+}
+
+// NewDeliveryStore creates an empty DeliveryStore that keeps up to
+// failedLimit failed and succeededLimit succeeded deliveries per webhook.
+func NewDeliveryStore(failedLimit, succeededLimit int) *DeliveryStore {
+	return &DeliveryStore{
+		deliveries:     make(map[string]*domain.WebhookDelivery),
+		byWebhook:      make(map[string][]string),
+		failedLimit:    failedLimit,
+		succeededLimit: succeededLimit,
+	}
+}
+
+// Save inserts a delivery or replaces the stored one with the same ID.
+func (s *DeliveryStore) Save(d *domain.WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[d.DeliveryID]; !ok {
+		s.byWebhook[d.WebhookID] = append(s.byWebhook[d.WebhookID], d.DeliveryID)
+	}
+	s.deliveries[d.DeliveryID] = copyDelivery(d)
+
+	switch d.Status {
+	case domain.DeliveryStatusFailed:
+		s.trim(d.WebhookID, d.Status, s.failedLimit)
+	case domain.DeliveryStatusSucceeded:
+		s.trim(d.WebhookID, d.Status, s.succeededLimit)
+	}
+}
+
+// trim drops a webhook's oldest deliveries with the given status beyond
+// limit. Callers must hold s.mu.
+func (s *DeliveryStore) trim(webhookID string, status domain.DeliveryStatus, limit int) {
+	ids := s.byWebhook[webhookID]
+	n := 0
+	for _, id := range ids {
+		if s.deliveries[id].Status == status {
+			n++
+		}
+	}
+
+	kept := ids[:0]
+	for _, id := range ids {
+		if n > limit && s.deliveries[id].Status == status {
+			delete(s.deliveries, id)
+			n--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.byWebhook[webhookID] = kept
+}
+
+// Get retrieves a delivery by ID. It returns domain.ErrDeliveryNotFound if
+// the delivery does not exist.
+func (s *DeliveryStore) Get(id string) (*domain.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	return copyDelivery(d), nil
+}
+
+// ListByWebhook returns a webhook's deliveries with the given status, or all
+// of them if status is empty, oldest first.
+func (s *DeliveryStore) ListByWebhook(webhookID string, status domain.DeliveryStatus) []*domain.WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*domain.WebhookDelivery{}
+	for _, id := range s.byWebhook[webhookID] {
+		d := s.deliveries[id]
+		if status != "" && d.Status != status {
+			continue
+		}
+		result = append(result, copyDelivery(d))
+	}
+	return result
+}
+
+// Delete removes a delivery by ID. Deleting an unknown delivery is a no-op.
+func (s *DeliveryStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return
+	}
+	delete(s.deliveries, id)
+
+	ids := s.byWebhook[d.WebhookID]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(s.byWebhook, d.WebhookID)
+	} else {
+		s.byWebhook[d.WebhookID] = ids
+	}
+}
+
+// DeleteByWebhook removes every delivery for a webhook.
+func (s *DeliveryStore) DeleteByWebhook(webhookID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.byWebhook[webhookID] {
+		delete(s.deliveries, id)
+	}
+	delete(s.byWebhook, webhookID)
+}
+
+// copyDelivery returns a copy of d that shares no state with it.
+func copyDelivery(d *domain.WebhookDelivery) *domain.WebhookDelivery {
+	c := *d
+	c.History = slices.Clone(d.History)
+	return &c
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efreitasn/miniexchange/internal/domain"
+)
+
+func newTestDelivery(id, webhookID string, status domain.DeliveryStatus) *domain.WebhookDelivery {
+	now := time.Now()
+	return &domain.WebhookDelivery{
+		DeliveryID: id,
+		WebhookID:  webhookID,
+		BrokerID:   "broker-1",
+		Event:      "trade.executed",
+		Payload:    []byte(`{}`),
+		Status:     status,
+		CreatedAt:  now,
+		QueuedAt:   now,
+	}
+}
+
+func TestDeliveryStore_SaveAndGet(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusPending))
+
+	got, err := s.Get("d-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.WebhookID != "wh-1" || got.Status != domain.DeliveryStatusPending {
+		t.Errorf("unexpected delivery %+v", got)
+	}
+
+	// Saving again replaces the delivery without duplicating it.
+	got.Status = domain.DeliveryStatusFailed
+	got.Attempts = 3
+	s.Save(got)
+	list := s.ListByWebhook("wh-1", "")
+	if len(list) != 1 || list[0].Attempts != 3 || list[0].Status != domain.DeliveryStatusFailed {
+		t.Errorf("unexpected list after replace: %+v", list)
+	}
+}
+
+func TestDeliveryStore_Get_NotFound(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	if _, err := s.Get("missing"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("expected ErrDeliveryNotFound, got %v", err)
+	}
+}
+
+func TestDeliveryStore_ReturnsCopies(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	d := newTestDelivery("d-1", "wh-1", domain.DeliveryStatusPending)
+	s.Save(d)
+	d.Attempts = 5
+
+	got, _ := s.Get("d-1")
+	if got.Attempts != 0 {
+		t.Error("store shares state with the saved delivery")
+	}
+	got.Attempts = 7
+	again, _ := s.Get("d-1")
+	if again.Attempts != 0 {
+		t.Error("store shares state with a returned delivery")
+	}
+}
+
+func TestDeliveryStore_ListByWebhook_FilterAndOrder(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-2", "wh-1", domain.DeliveryStatusPending))
+	s.Save(newTestDelivery("d-3", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-4", "wh-2", domain.DeliveryStatusFailed))
+
+	all := s.ListByWebhook("wh-1", "")
+	if len(all) != 3 || all[0].DeliveryID != "d-1" || all[2].DeliveryID != "d-3" {
+		t.Errorf("unexpected deliveries: %+v", all)
+	}
+	failed := s.ListByWebhook("wh-1", domain.DeliveryStatusFailed)
+	if len(failed) != 2 || failed[0].DeliveryID != "d-1" || failed[1].DeliveryID != "d-3" {
+		t.Errorf("unexpected failed deliveries: %+v", failed)
+	}
+	if got := s.ListByWebhook("wh-3", ""); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", got)
+	}
+}
+
+func TestDeliveryStore_FailedLimit(t *testing.T) {
+	s := NewDeliveryStore(2, 100)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-2", "wh-1", domain.DeliveryStatusPending))
+	s.Save(newTestDelivery("d-3", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-4", "wh-1", domain.DeliveryStatusFailed))
+
+	// The oldest failed delivery is dropped; pending ones are never dropped.
+	list := s.ListByWebhook("wh-1", "")
+	var ids []string
+	for _, d := range list {
+		ids = append(ids, d.DeliveryID)
+	}
+	if fmt.Sprint(ids) != "[d-2 d-3 d-4]" {
+		t.Errorf("got %v, want [d-2 d-3 d-4]", ids)
+	}
+	if _, err := s.Get("d-1"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("expected d-1 to be dropped, got %v", err)
+	}
+}
+
+func TestDeliveryStore_SucceededLimit(t *testing.T) {
+	s := NewDeliveryStore(1, 2)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusSucceeded))
+	s.Save(newTestDelivery("d-2", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-3", "wh-1", domain.DeliveryStatusSucceeded))
+	s.Save(newTestDelivery("d-4", "wh-1", domain.DeliveryStatusSucceeded))
+
+	// Succeeded deliveries have their own limit; failed ones are not dropped
+	// to make room for them.
+	var ids []string
+	for _, d := range s.ListByWebhook("wh-1", "") {
+		ids = append(ids, d.DeliveryID)
+	}
+	if fmt.Sprint(ids) != "[d-2 d-3 d-4]" {
+		t.Errorf("got %v, want [d-2 d-3 d-4]", ids)
+	}
+}
+
+func TestDeliveryStore_CopiesHistory(t *testing.T) {
+	s := NewDeliveryStore(10, 10)
+	d := newTestDelivery("d-1", "wh-1", domain.DeliveryStatusPending)
+	d.History = append(make([]domain.DeliveryAttempt, 0, 4), domain.DeliveryAttempt{StatusCode: 500})
+	s.Save(d)
+	d.History[0].StatusCode = 200
+
+	got, _ := s.Get("d-1")
+	if got.History[0].StatusCode != 500 {
+		t.Error("store shares history with the saved delivery")
+	}
+	got.History[0].StatusCode = 503
+	got.History = append(got.History, domain.DeliveryAttempt{StatusCode: 200})
+	again, _ := s.Get("d-1")
+	if len(again.History) != 1 || again.History[0].StatusCode != 500 {
+		t.Errorf("store shares history with a returned delivery: %+v", again.History)
+	}
+}
+
+func TestDeliveryStore_Delete(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusPending))
+	s.Save(newTestDelivery("d-2", "wh-1", domain.DeliveryStatusPending))
+
+	s.Delete("d-1")
+	s.Delete("missing")
+	if _, err := s.Get("d-1"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("expected ErrDeliveryNotFound, got %v", err)
+	}
+	if list := s.ListByWebhook("wh-1", ""); len(list) != 1 || list[0].DeliveryID != "d-2" {
+		t.Errorf("unexpected deliveries: %+v", list)
+	}
+}
+
+func TestDeliveryStore_DeleteByWebhook(t *testing.T) {
+	s := NewDeliveryStore(10, 100)
+	s.Save(newTestDelivery("d-1", "wh-1", domain.DeliveryStatusPending))
+	s.Save(newTestDelivery("d-2", "wh-1", domain.DeliveryStatusFailed))
+	s.Save(newTestDelivery("d-3", "wh-2", domain.DeliveryStatusFailed))
+
+	s.DeleteByWebhook("wh-1")
+	if list := s.ListByWebhook("wh-1", ""); len(list) != 0 {
+		t.Errorf("expected no deliveries, got %+v", list)
+	}
+	if _, err := s.Get("d-2"); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("expected ErrDeliveryNotFound, got %v", err)
+	}
+	if _, err := s.Get("d-3"); err != nil {
+		t.Errorf("other webhook's delivery removed: %v", err)
+	}
+}
+
+func TestDeliveryStore_ConcurrentAccess(t *testing.T) {
+	s := NewDeliveryStore(5, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("d-%d", i)
+			status := domain.DeliveryStatusPending
+			if i%2 == 0 {
+				status = domain.DeliveryStatusFailed
+			}
+			s.Save(newTestDelivery(id, "wh-1", status))
+			s.Get(id)
+			s.ListByWebhook("wh-1", domain.DeliveryStatusFailed)
+			if i%5 == 0 {
+				s.Delete(id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(s.ListByWebhook("wh-1", domain.DeliveryStatusFailed)); got > 5 {
+		t.Errorf("got %d failed deliveries, want at most 5", got)
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
@@ -44,3 +45,16 @@ func (s *TradeStore) GetBySymbol(symbol string) []*domain.Trade {
 	copy(result, trades)
 	return result
 }
+
+// Symbols returns every symbol with at least one trade, sorted.
+func (s *TradeStore) Symbols() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	symbols := make([]string, 0, len(s.trades))
+	for symbol := range s.trades {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
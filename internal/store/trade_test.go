@@ -88,6 +88,23 @@ func TestTradeStore_MultipleSymbols(t *testing.T) {
 	}
 }
 
+func TestTradeStore_Symbols(t *testing.T) {
+	s := NewTradeStore()
+	if got := s.Symbols(); len(got) != 0 {
+		t.Fatalf("expected no symbols, got %v", got)
+	}
+
+	now := time.Now()
+	s.Append("GOOG", newTestTrade("t1", now))
+	s.Append("AAPL", newTestTrade("t2", now))
+	s.Append("GOOG", newTestTrade("t3", now))
+
+	got := s.Symbols()
+	if len(got) != 2 || got[0] != "AAPL" || got[1] != "GOOG" {
+		t.Fatalf("expected [AAPL GOOG], got %v", got)
+	}
+}
+
 func TestTradeStore_ConcurrentAccess(t *testing.T) {
 	s := NewTradeStore()
 	var wg sync.WaitGroup
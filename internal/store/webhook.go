@@ -1,57 +1,58 @@
 package store
 
 import (
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/efreitasn/miniexchange/internal/domain"
 )
 
 // WebhookStore is a thread-safe in-memory store for webhooks.
 // Primary index: webhook_id → webhook.
-// Secondary index: broker_id → event → webhook.
+// Secondary index: broker_id → webhooks, oldest first.
+// Webhooks are stored and returned as copies, so callers never share state
+// with the store.
 type WebhookStore struct {
 	mu       sync.RWMutex
-	webhooks map[string]*domain.Webhook            // webhook_id → webhook
-	byBroker map[string]map[string]*domain.Webhook // broker_id → event → webhook
+	webhooks map[string]*domain.Webhook   // webhook_id → webhook
+	byBroker map[string][]*domain.Webhook // broker_id → webhooks, oldest first
 }
 
 // NewWebhookStore creates an empty WebhookStore.
 func NewWebhookStore() *WebhookStore {
 	return &WebhookStore{
 		webhooks: make(map[string]*domain.Webhook),
-		byBroker: make(map[string]map[string]*domain.Webhook),
+		byBroker: make(map[string][]*domain.Webhook),
 	}
 }
 
-// Upsert inserts or updates a webhook subscription keyed by (broker_id, event).
-// If a subscription already exists for that broker+event pair, the URL and
-// UpdatedAt are updated (the webhook_id remains stable). If the existing URL
-// matches, it is a no-op. Returns true if a new subscription was created.
-func (s *WebhookStore) Upsert(w *domain.Webhook) bool {
+// Upsert inserts or updates a webhook subscription keyed by (broker_id,
+// event, url). If a subscription already exists for that key, its filters
+// and version are updated, and UpdatedAt with them if they changed; its
+// webhook_id, status and secret remain. It returns the stored webhook and
+// true if a new subscription was created.
+func (s *WebhookStore) Upsert(w *domain.Webhook) (*domain.Webhook, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Check if a subscription already exists for this broker+event.
-	if events, ok := s.byBroker[w.BrokerID]; ok {
-		if existing, ok := events[w.Event]; ok {
-			// Update URL and UpdatedAt if the URL changed.
-			if existing.URL != w.URL {
-				existing.URL = w.URL
-				existing.UpdatedAt = w.UpdatedAt
-			}
-			return false
+	for _, existing := range s.byBroker[w.BrokerID] {
+		if existing.Event != w.Event || existing.URL != w.URL {
+			continue
 		}
+		if existing.Version != w.Version || !existing.Filters.Equal(w.Filters) {
+			existing.Filters = copyFilters(w.Filters)
+			existing.Version = w.Version
+			existing.UpdatedAt = w.UpdatedAt
+		}
+		return copyWebhook(existing), false
 	}
 
 	// New subscription — add to both indexes.
-	s.webhooks[w.WebhookID] = w
-
-	if s.byBroker[w.BrokerID] == nil {
-		s.byBroker[w.BrokerID] = make(map[string]*domain.Webhook)
-	}
-	s.byBroker[w.BrokerID][w.Event] = w
-
-	return true
+	stored := copyWebhook(w)
+	s.webhooks[w.WebhookID] = stored
+	s.byBroker[w.BrokerID] = append(s.byBroker[w.BrokerID], stored)
+	return copyWebhook(stored), true
 }
 
 // Get retrieves a webhook by ID. It returns
@@ -64,27 +65,56 @@ func (s *WebhookStore) Get(id string) (*domain.Webhook, error) {
 	if !ok {
 		return nil, domain.ErrWebhookNotFound
 	}
-	return w, nil
+	return copyWebhook(w), nil
 }
 
-// ListByBroker returns all webhooks for a broker.
+// ListByBroker returns all webhooks for a broker, oldest first.
 // Returns an empty slice if the broker has no subscriptions.
 func (s *WebhookStore) ListByBroker(brokerID string) []*domain.Webhook {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	events := s.byBroker[brokerID]
-	if len(events) == 0 {
-		return []*domain.Webhook{}
+	webhooks := s.byBroker[brokerID]
+	result := make([]*domain.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, copyWebhook(w))
 	}
+	return result
+}
+
+// ListByBrokerEvent returns a broker's webhooks for an event, oldest first,
+// or an empty slice if there are none.
+func (s *WebhookStore) ListByBrokerEvent(brokerID, event string) []*domain.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	result := make([]*domain.Webhook, 0, len(events))
-	for _, w := range events {
-		result = append(result, w)
+	result := []*domain.Webhook{}
+	for _, w := range s.byBroker[brokerID] {
+		if w.Event == event {
+			result = append(result, copyWebhook(w))
+		}
 	}
 	return result
 }
 
+// Update replaces the status, filters, version and UpdatedAt of the stored
+// webhook with w's. It returns the updated webhook, or
+// domain.ErrWebhookNotFound if the webhook does not exist.
+func (s *WebhookStore) Update(w *domain.Webhook) (*domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.webhooks[w.WebhookID]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	stored.Status = w.Status
+	stored.Filters = copyFilters(w.Filters)
+	stored.Version = w.Version
+	stored.UpdatedAt = w.UpdatedAt
+	return copyWebhook(stored), nil
+}
+
 // Delete removes a webhook by ID. It returns
 // domain.ErrWebhookNotFound if the webhook does not exist.
 // Both the primary and secondary indexes are cleaned up.
@@ -101,25 +131,44 @@ func (s *WebhookStore) Delete(id string) error {
 	delete(s.webhooks, id)
 
 	// Remove from secondary index.
-	if events, ok := s.byBroker[w.BrokerID]; ok {
-		delete(events, w.Event)
-		if len(events) == 0 {
-			delete(s.byBroker, w.BrokerID)
-		}
+	webhooks := slices.DeleteFunc(s.byBroker[w.BrokerID], func(c *domain.Webhook) bool {
+		return c.WebhookID == id
+	})
+	if len(webhooks) == 0 {
+		delete(s.byBroker, w.BrokerID)
+	} else {
+		s.byBroker[w.BrokerID] = webhooks
 	}
 
 	return nil
 }
 
-// GetByBrokerEvent returns the webhook for a specific broker+event pair,
-// or nil if no subscription exists.
-func (s *WebhookStore) GetByBrokerEvent(brokerID, event string) *domain.Webhook {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RotateSecret replaces a webhook's signing secret. The old secret stays
+// in PreviousSecret until previousExpiresAt. It returns
+// domain.ErrWebhookNotFound if the webhook does not exist.
+func (s *WebhookStore) RotateSecret(id, secret string, previousExpiresAt, at time.Time) (*domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	events := s.byBroker[brokerID]
-	if events == nil {
-		return nil
+	w, ok := s.webhooks[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
 	}
-	return events[event]
+	w.PreviousSecret = w.Secret
+	w.PreviousSecretExpiresAt = previousExpiresAt
+	w.Secret = secret
+	w.SecretRotatedAt = &at
+	return copyWebhook(w), nil
+}
+
+// copyWebhook returns a copy of w that shares no state with it.
+func copyWebhook(w *domain.Webhook) *domain.Webhook {
+	c := *w
+	c.Filters = copyFilters(w.Filters)
+	return &c
+}
+
+func copyFilters(f domain.WebhookFilters) domain.WebhookFilters {
+	f.Symbols = slices.Clone(f.Symbols)
+	return f
 }
@@ -25,10 +25,13 @@ func TestWebhookStore_Upsert_NewSubscription(t *testing.T) {
 	s := NewWebhookStore()
 	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
 
-	created := s.Upsert(w)
+	stored, created := s.Upsert(w)
 	if !created {
 		t.Fatal("expected Upsert to return true for new subscription")
 	}
+	if stored.WebhookID != "wh-1" {
+		t.Fatalf("expected the stored webhook wh-1, got %s", stored.WebhookID)
+	}
 
 	got, err := s.Get("wh-1")
 	if err != nil {
@@ -39,32 +42,27 @@ func TestWebhookStore_Upsert_NewSubscription(t *testing.T) {
 	}
 }
 
-func TestWebhookStore_Upsert_UpdateURL(t *testing.T) {
+func TestWebhookStore_Upsert_DifferentURLs(t *testing.T) {
 	s := NewWebhookStore()
 	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/old")
 	s.Upsert(w)
 
-	// Upsert with same broker+event but different URL.
+	// Upsert with same broker+event but a different URL adds a subscription.
 	w2 := newTestWebhook("wh-2", "broker-1", "trade.executed", "https://example.com/new")
-	w2.UpdatedAt = time.Now().Add(time.Second)
-	created := s.Upsert(w2)
-	if created {
-		t.Fatal("expected Upsert to return false when updating existing subscription")
+	if _, created := s.Upsert(w2); !created {
+		t.Fatal("expected Upsert to return true for a second URL")
 	}
 
-	// The original webhook_id should be stable.
 	got, err := s.Get("wh-1")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if got.URL != "https://example.com/new" {
-		t.Fatalf("expected URL to be updated, got %s", got.URL)
+	if got.URL != "https://example.com/old" {
+		t.Fatalf("expected the first subscription unchanged, got URL %s", got.URL)
 	}
-
-	// The new webhook_id should NOT be in the store.
-	_, err = s.Get("wh-2")
-	if err != domain.ErrWebhookNotFound {
-		t.Fatalf("expected ErrWebhookNotFound for wh-2, got %v", err)
+	list := s.ListByBrokerEvent("broker-1", "trade.executed")
+	if len(list) != 2 || list[0].WebhookID != "wh-1" || list[1].WebhookID != "wh-2" {
+		t.Fatalf("expected wh-1 and wh-2, oldest first, got %+v", list)
 	}
 }
 
@@ -75,10 +73,14 @@ func TestWebhookStore_Upsert_SameURL_Idempotent(t *testing.T) {
 
 	// Re-register with same URL — should be a no-op.
 	w2 := newTestWebhook("wh-2", "broker-1", "trade.executed", "https://example.com/hook")
-	created := s.Upsert(w2)
+	w2.UpdatedAt = w.UpdatedAt.Add(time.Second)
+	stored, created := s.Upsert(w2)
 	if created {
 		t.Fatal("expected Upsert to return false for idempotent re-registration")
 	}
+	if stored.WebhookID != "wh-1" || !stored.UpdatedAt.Equal(w.UpdatedAt) {
+		t.Fatalf("expected wh-1 unchanged, got %+v", stored)
+	}
 
 	got, _ := s.Get("wh-1")
 	if got.URL != "https://example.com/hook" {
@@ -86,13 +88,37 @@ func TestWebhookStore_Upsert_SameURL_Idempotent(t *testing.T) {
 	}
 }
 
+func TestWebhookStore_Upsert_UpdatesFiltersAndVersion(t *testing.T) {
+	s := NewWebhookStore()
+	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
+	w.Status = domain.WebhookStatusPaused
+	w.Secret = "secret"
+	s.Upsert(w)
+
+	w2 := newTestWebhook("wh-2", "broker-1", "trade.executed", "https://example.com/hook")
+	w2.Filters = domain.WebhookFilters{Symbols: []string{"AAPL"}, MinNotional: 100000}
+	w2.Version = 1
+	w2.UpdatedAt = w.UpdatedAt.Add(time.Second)
+	stored, created := s.Upsert(w2)
+	if created {
+		t.Fatal("expected Upsert to return false when updating filters")
+	}
+	if stored.WebhookID != "wh-1" || !stored.Filters.Equal(w2.Filters) || stored.Version != 1 ||
+		!stored.UpdatedAt.Equal(w2.UpdatedAt) {
+		t.Errorf("expected filters, version and updated_at to change, got %+v", stored)
+	}
+	if stored.Status != domain.WebhookStatusPaused || stored.Secret != "secret" {
+		t.Errorf("expected status and secret kept, got %+v", stored)
+	}
+}
+
 func TestWebhookStore_Upsert_DifferentEvents(t *testing.T) {
 	s := NewWebhookStore()
 	w1 := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/trades")
 	w2 := newTestWebhook("wh-2", "broker-1", "order.expired", "https://example.com/expired")
 
-	c1 := s.Upsert(w1)
-	c2 := s.Upsert(w2)
+	_, c1 := s.Upsert(w1)
+	_, c2 := s.Upsert(w2)
 	if !c1 || !c2 {
 		t.Fatal("expected both to be new subscriptions")
 	}
@@ -141,9 +167,8 @@ func TestWebhookStore_Delete(t *testing.T) {
 	}
 
 	// Secondary index should be cleaned up.
-	got := s.GetByBrokerEvent("broker-1", "trade.executed")
-	if got != nil {
-		t.Fatal("expected nil from GetByBrokerEvent after delete")
+	if got := s.ListByBrokerEvent("broker-1", "trade.executed"); len(got) != 0 {
+		t.Fatalf("expected no webhooks from ListByBrokerEvent after delete, got %d", len(got))
 	}
 
 	// ListByBroker should return empty.
@@ -181,35 +206,61 @@ func TestWebhookStore_Delete_PartialCleanup(t *testing.T) {
 	}
 }
 
-func TestWebhookStore_GetByBrokerEvent(t *testing.T) {
+func TestWebhookStore_ListByBrokerEvent(t *testing.T) {
 	s := NewWebhookStore()
-	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
-	s.Upsert(w)
+	s.Upsert(newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/a"))
+	s.Upsert(newTestWebhook("wh-2", "broker-1", "order.expired", "https://example.com/a"))
+	s.Upsert(newTestWebhook("wh-3", "broker-1", "trade.executed", "https://example.com/b"))
 
-	got := s.GetByBrokerEvent("broker-1", "trade.executed")
-	if got == nil {
-		t.Fatal("expected webhook, got nil")
-	}
-	if got.WebhookID != "wh-1" {
-		t.Fatalf("expected wh-1, got %s", got.WebhookID)
+	got := s.ListByBrokerEvent("broker-1", "trade.executed")
+	if len(got) != 2 || got[0].WebhookID != "wh-1" || got[1].WebhookID != "wh-3" {
+		t.Fatalf("expected wh-1 and wh-3, oldest first, got %+v", got)
 	}
 }
 
-func TestWebhookStore_GetByBrokerEvent_NotFound(t *testing.T) {
+func TestWebhookStore_ListByBrokerEvent_NotFound(t *testing.T) {
 	s := NewWebhookStore()
 
-	got := s.GetByBrokerEvent("broker-1", "trade.executed")
-	if got != nil {
-		t.Fatal("expected nil for nonexistent broker+event")
+	got := s.ListByBrokerEvent("broker-1", "trade.executed")
+	if got == nil || len(got) != 0 {
+		t.Fatalf("expected non-nil empty slice for nonexistent broker+event, got %v", got)
 	}
 
 	// Add a webhook for a different event.
 	w := newTestWebhook("wh-1", "broker-1", "order.expired", "https://example.com/hook")
 	s.Upsert(w)
 
-	got = s.GetByBrokerEvent("broker-1", "trade.executed")
-	if got != nil {
-		t.Fatal("expected nil for different event")
+	if got := s.ListByBrokerEvent("broker-1", "trade.executed"); len(got) != 0 {
+		t.Fatal("expected no webhooks for different event")
+	}
+}
+
+func TestWebhookStore_Update(t *testing.T) {
+	s := NewWebhookStore()
+	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
+	w.Secret = "secret"
+	s.Upsert(w)
+
+	update := newTestWebhook("wh-1", "other", "order.expired", "https://example.com/other")
+	update.Status = domain.WebhookStatusPaused
+	update.Filters = domain.WebhookFilters{Side: domain.OrderSideAsk}
+	update.Version = 1
+	got, err := s.Update(update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != domain.WebhookStatusPaused || got.Filters.Side != domain.OrderSideAsk || got.Version != 1 {
+		t.Errorf("expected status, filters and version updated, got %+v", got)
+	}
+	if got.BrokerID != "broker-1" || got.Event != "trade.executed" || got.URL != "https://example.com/hook" || got.Secret != "secret" {
+		t.Errorf("expected the rest of the webhook kept, got %+v", got)
+	}
+	if listed := s.ListByBroker("broker-1"); listed[0].Status != domain.WebhookStatusPaused {
+		t.Errorf("ListByBroker: status = %q, want paused", listed[0].Status)
+	}
+
+	if _, err := s.Update(newTestWebhook("missing", "broker-1", "trade.executed", "https://example.com/hook")); err != domain.ErrWebhookNotFound {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
 	}
 }
 
@@ -247,3 +298,57 @@ func TestWebhookStore_ConcurrentAccess(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestWebhookStore_RotateSecret(t *testing.T) {
+	s := NewWebhookStore()
+	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
+	w.Secret = "old"
+	s.Upsert(w)
+
+	at := time.Now()
+	got, err := s.RotateSecret("wh-1", "new", at.Add(time.Hour), at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Secret != "new" || got.PreviousSecret != "old" || !got.PreviousSecretExpiresAt.Equal(at.Add(time.Hour)) {
+		t.Errorf("unexpected rotated webhook: %+v", got)
+	}
+	if got.SecretRotatedAt == nil || !got.SecretRotatedAt.Equal(at) {
+		t.Errorf("SecretRotatedAt = %v, want %v", got.SecretRotatedAt, at)
+	}
+
+	// Both indexes see the new secret.
+	if stored, _ := s.Get("wh-1"); stored.Secret != "new" {
+		t.Errorf("Get: secret = %q, want new", stored.Secret)
+	}
+	if stored := s.ListByBrokerEvent("broker-1", "trade.executed")[0]; stored.Secret != "new" {
+		t.Errorf("ListByBrokerEvent: secret = %q, want new", stored.Secret)
+	}
+
+	if _, err := s.RotateSecret("missing", "x", at, at); err != domain.ErrWebhookNotFound {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+func TestWebhookStore_ReturnsCopies(t *testing.T) {
+	s := NewWebhookStore()
+	w := newTestWebhook("wh-1", "broker-1", "trade.executed", "https://example.com/hook")
+	w.Filters.Symbols = []string{"AAPL"}
+	s.Upsert(w)
+	w.URL = "https://example.com/changed"
+	w.Filters.Symbols[0] = "MSFT"
+
+	got, _ := s.Get("wh-1")
+	got.URL = "https://example.com/changed"
+	got.Filters.Symbols[0] = "MSFT"
+	s.ListByBroker("broker-1")[0].URL = "https://example.com/changed"
+	s.ListByBrokerEvent("broker-1", "trade.executed")[0].Filters.Symbols[0] = "MSFT"
+
+	got, _ = s.Get("wh-1")
+	if got.URL != "https://example.com/hook" {
+		t.Errorf("stored webhook was modified through a pointer: URL = %q", got.URL)
+	}
+	if got.Filters.Symbols[0] != "AAPL" {
+		t.Errorf("stored filters were modified through a pointer: symbols = %v", got.Filters.Symbols)
+	}
+}
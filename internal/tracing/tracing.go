@@ -0,0 +1,74 @@
+// Package tracing sets up OpenTelemetry tracing for the exchange.
+//
+// Packages that record spans get their tracer from the global provider, so
+// nothing is exported until Setup installs one. The W3C trace context
+// propagator is installed regardless of the exporter: a traceparent a client
+// sends is carried through to outgoing webhook deliveries even when the
+// exchange itself records nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ServiceName is the service.name resource attribute on exported spans.
+const ServiceName = "miniexchange"
+
+// Setup installs the global tracer provider and propagator. exporter is
+// "none", "otlp" (OTLP over HTTP to endpoint, a host:port without TLS) or
+// "stdout" (one JSON object per span, written to w). sampleRatio is the
+// fraction of new traces recorded; traces a caller started follow the
+// caller's sampling decision.
+//
+// The returned function flushes buffered spans and stops the exporter. It
+// is a no-op for "none".
+func Setup(ctx context.Context, exporter, endpoint string, sampleRatio float64, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpoint(endpoint),
+			otlptracehttp.WithInsecure(),
+		)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator that
+// Setup replaces.
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	tp, prop := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(prop)
+	})
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	restoreGlobals(t)
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), "stdout", "", 1, &buf)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	var got struct {
+		Name     string
+		Resource []struct {
+			Key   string
+			Value struct{ Value any }
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("decode exported span %q: %v", buf.String(), err)
+	}
+	if got.Name != "work" {
+		t.Errorf("span name: expected work, got %q", got.Name)
+	}
+	found := false
+	for _, kv := range got.Resource {
+		if kv.Key == "service.name" && kv.Value.Value == ServiceName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected service.name=%s in resource %+v", ServiceName, got.Resource)
+	}
+}
+
+func TestSetup_NoneStillPropagates(t *testing.T) {
+	restoreGlobals(t)
+
+	shutdown, err := Setup(context.Background(), "none", "", 1, nil)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	in := http.Header{"Traceparent": {traceparent}}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(in))
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("expected the incoming trace context to be extracted")
+	}
+
+	// A span from the default no-op provider carries its parent's context.
+	ctx, span := otel.Tracer("test").Start(ctx, "work")
+	span.End()
+	out := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(out))
+	if got := out.Get("Traceparent"); got != traceparent {
+		t.Errorf("traceparent: expected %q, got %q", traceparent, got)
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	restoreGlobals(t)
+
+	if _, err := Setup(context.Background(), "jaeger", "", 1, nil); err == nil {
+		t.Fatal("expected error for unknown exporter")
+	}
+}
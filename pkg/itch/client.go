@@ -0,0 +1,131 @@
+package itch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// HeartbeatInterval is how long the exchange goes without sending
+	// messages before it sends a heartbeat packet.
+	HeartbeatInterval = time.Second
+
+	// IdleTimeout is how long a ReplayClient waits without receiving
+	// anything before it considers the connection dead.
+	IdleTimeout = 15 * time.Second
+
+	// dialTimeout bounds connecting and sending the replay request.
+	dialTimeout = 10 * time.Second
+)
+
+// ErrEndOfSession is returned by Recv after the exchange has ended the
+// session. No more messages will be sent under it.
+var ErrEndOfSession = errors.New("itch: end of session")
+
+// ReplayClient reads the feed from the exchange's TCP replay service. It
+// must be used from one goroutine at a time.
+type ReplayClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	session string
+	next    uint64    // sequence number of pending[0]
+	pending []Message // messages of the current packet not yet returned
+}
+
+// DialReplay connects to the replay service at addr and asks for the
+// messages of session starting at sequence number from. An empty session
+// means the current one; a from of zero means only messages published
+// after the request.
+func DialReplay(addr, session string, from uint64) (*ReplayClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	req, err := AppendHeader(nil, PacketHeader{Session: session, Sequence: from})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return &ReplayClient{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// Recv returns the next message and its sequence number, skipping
+// heartbeats. Messages the exchange no longer retains show up as a jump in
+// the sequence number.
+func (c *ReplayClient) Recv() (uint64, Message, error) {
+	for len(c.pending) == 0 {
+		c.conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		h, msgs, err := ReadPacket(c.r)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.session = h.Session
+		if h.Count == EndOfSession {
+			return 0, nil, ErrEndOfSession
+		}
+		c.next = h.Sequence
+		c.pending = msgs
+	}
+	m := c.pending[0]
+	c.pending = c.pending[1:]
+	seq := c.next
+	c.next++
+	return seq, m, nil
+}
+
+// Session returns the session named by the last packet received, or "" if
+// none has been received yet.
+func (c *ReplayClient) Session() string {
+	return c.session
+}
+
+// Close closes the connection.
+func (c *ReplayClient) Close() error {
+	return c.conn.Close()
+}
+
+// Retransmit asks the retransmission service at addr for count messages of
+// session starting at sequence number seq and waits up to timeout for the
+// answer. The answer may hold fewer messages than asked for, to keep it
+// within MaxPacketSize; ask again for the rest. If the first message asked
+// for is no longer retained, the answer holds no messages and its Sequence
+// is the oldest one that is.
+func Retransmit(addr, session string, seq uint64, count uint16, timeout time.Duration) (PacketHeader, []Message, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return PacketHeader{}, nil, err
+	}
+	defer conn.Close()
+
+	req, err := AppendHeader(nil, PacketHeader{Session: session, Sequence: seq, Count: count})
+	if err != nil {
+		return PacketHeader{}, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(req); err != nil {
+		return PacketHeader{}, nil, err
+	}
+	buf := make([]byte, MaxPacketSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return PacketHeader{}, nil, err
+	}
+	h, msgs, err := ParsePacket(buf[:n])
+	if err != nil {
+		return h, nil, err
+	}
+	if h.Count == EndOfSession {
+		return h, nil, fmt.Errorf("itch: session %q has ended", h.Session)
+	}
+	return h, msgs, nil
+}
@@ -0,0 +1,105 @@
+package itch
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDialReplay(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	reqs := make(chan PacketHeader, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var buf [PacketHeaderSize]byte
+		if _, err := io.ReadFull(conn, buf[:]); err != nil {
+			return
+		}
+		h, _ := ParseHeader(buf[:])
+		reqs <- h
+
+		var b []byte
+		b, _ = AppendHeader(b, PacketHeader{Session: "1700000000", Sequence: 5, Count: 2})
+		b, _ = Append(b, &SymbolDirectory{Locate: 1, Symbol: "AAPL"})
+		b, _ = Append(b, &OrderCancel{Locate: 1, OrderRef: 1, CanceledShares: 3})
+		b, _ = AppendHeader(b, PacketHeader{Session: "1700000000", Sequence: 7})
+		b, _ = AppendHeader(b, PacketHeader{Session: "1700000000", Sequence: 9, Count: 1})
+		b, _ = Append(b, &SystemEvent{EventCode: EventEndOfMessages})
+		b, _ = AppendHeader(b, PacketHeader{Session: "1700000000", Sequence: 10, Count: EndOfSession})
+		conn.Write(b)
+	}()
+
+	c, err := DialReplay(ln.Addr().String(), "", 3)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case req := <-reqs:
+		if req != (PacketHeader{Sequence: 3}) {
+			t.Errorf("got request %+v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received")
+	}
+
+	// The heartbeat is skipped and the jump from 7 to 9 is visible.
+	for _, want := range []struct {
+		seq uint64
+		typ byte
+	}{{5, TypeSymbolDirectory}, {6, TypeOrderCancel}, {9, TypeSystemEvent}} {
+		seq, m, err := c.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if seq != want.seq || m.Type() != want.typ {
+			t.Errorf("got %d %c, want %d %c", seq, m.Type(), want.seq, want.typ)
+		}
+	}
+	if _, _, err := c.Recv(); !errors.Is(err, ErrEndOfSession) {
+		t.Errorf("got %v, want ErrEndOfSession", err)
+	}
+	if c.Session() != "1700000000" {
+		t.Errorf("session = %q", c.Session())
+	}
+}
+
+func TestRetransmit(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+
+	go func() {
+		buf := make([]byte, MaxPacketSize)
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, _ := ParseHeader(buf[:n])
+		b, _ := AppendHeader(nil, PacketHeader{Session: req.Session, Sequence: req.Sequence, Count: 1})
+		b, _ = Append(b, &SystemEvent{EventCode: EventStartOfMessages})
+		pc.WriteTo(b, addr)
+	}()
+
+	h, msgs, err := Retransmit(pc.LocalAddr().String(), "s1", 1, 10, 5*time.Second)
+	if err != nil {
+		t.Fatalf("retransmit: %v", err)
+	}
+	if h.Session != "s1" || h.Sequence != 1 || len(msgs) != 1 {
+		t.Errorf("got %+v with %d messages", h, len(msgs))
+	}
+}
@@ -0,0 +1,407 @@
+// Package itch implements the exchange's binary market data feed, an
+// ITCH-style stream of order-level book events carried in MoldUDP64-style
+// packets, and a client for its replay and retransmission services. The
+// wire format is specified in design-documents/itch-protocol.md.
+//
+// Every message has a sequence number, implied by its position in the
+// feed: the first message of a session is 1 and each message increments it
+// by one. Messages are framed by a two-byte big-endian length that counts
+// the type byte and the body that follow it. Alpha fields are ASCII, left
+// justified and padded with spaces; integers are unsigned big-endian.
+// Prices are in cents and timestamps are nanoseconds since the Unix epoch.
+package itch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message types.
+const (
+	TypeSystemEvent     byte = 'S'
+	TypeSymbolDirectory byte = 'R'
+	TypeAddOrder        byte = 'A'
+	TypeOrderExecuted   byte = 'E'
+	TypeOrderCancel     byte = 'X'
+	TypeTrade           byte = 'P'
+)
+
+// Field widths of alpha fields.
+const (
+	SymbolWidth  = 10
+	MatchIDWidth = 36
+)
+
+// SystemEvent codes.
+const (
+	EventStartOfMessages byte = 'O' // first message of a session
+	EventEndOfMessages   byte = 'C' // last message of a session
+)
+
+// Side values.
+const (
+	SideBuy  byte = 'B'
+	SideSell byte = 'S'
+)
+
+// MaxMessageSize is the largest frame payload (type byte and body) any
+// message uses.
+const MaxMessageSize = 1 + 69
+
+// ErrUnknownType is returned when decoding a message whose type byte is not
+// defined.
+var ErrUnknownType = errors.New("itch: unknown message type")
+
+// Message is a single feed message.
+type Message interface {
+	// Type returns the message's type byte.
+	Type() byte
+
+	size() int
+	encode(b []byte) error
+	decode(b []byte)
+}
+
+// SystemEvent marks the start and end of a session.
+type SystemEvent struct {
+	Timestamp time.Time
+	EventCode byte
+}
+
+// SymbolDirectory assigns a locate code to a symbol. It is sent once per
+// session, before the first message that uses the locate code.
+type SymbolDirectory struct {
+	Locate    uint16
+	Timestamp time.Time
+	Symbol    string
+}
+
+// AddOrder reports an order placed on the book. OrderRef identifies the
+// order in later messages for the rest of the session; it is not the
+// exchange order ID.
+type AddOrder struct {
+	Locate    uint16
+	Timestamp time.Time
+	OrderRef  uint64
+	Side      byte
+	Shares    uint32
+	Symbol    string
+	Price     uint64
+}
+
+// OrderExecuted reports a fill against a resting order. The order's open
+// shares go down by ExecutedShares; at zero it has left the book.
+type OrderExecuted struct {
+	Locate         uint16
+	Timestamp      time.Time
+	OrderRef       uint64
+	ExecutedShares uint32
+	ExecutionPrice uint64
+	MatchNumber    string // the trade ID
+}
+
+// OrderCancel reports that a resting order was cancelled or expired.
+// CanceledShares is every share it still had open: the order has left the
+// book.
+type OrderCancel struct {
+	Locate         uint16
+	Timestamp      time.Time
+	OrderRef       uint64
+	CanceledShares uint32
+}
+
+// Trade reports a match from the point of view of the tape. It follows the
+// OrderExecuted for the same match; Side is the incoming order's side.
+type Trade struct {
+	Locate      uint16
+	Timestamp   time.Time
+	Side        byte
+	Shares      uint32
+	Symbol      string
+	Price       uint64
+	MatchNumber string
+}
+
+func (*SystemEvent) Type() byte     { return TypeSystemEvent }
+func (*SymbolDirectory) Type() byte { return TypeSymbolDirectory }
+func (*AddOrder) Type() byte        { return TypeAddOrder }
+func (*OrderExecuted) Type() byte   { return TypeOrderExecuted }
+func (*OrderCancel) Type() byte     { return TypeOrderCancel }
+func (*Trade) Type() byte           { return TypeTrade }
+
+func (*SystemEvent) size() int     { return 2 + 8 + 1 }
+func (*SymbolDirectory) size() int { return 2 + 8 + SymbolWidth }
+func (*AddOrder) size() int        { return 2 + 8 + 8 + 1 + 4 + SymbolWidth + 8 }
+func (*OrderExecuted) size() int   { return 2 + 8 + 8 + 4 + 8 + MatchIDWidth }
+func (*OrderCancel) size() int     { return 2 + 8 + 8 + 4 }
+func (*Trade) size() int           { return 2 + 8 + 1 + 4 + SymbolWidth + 8 + MatchIDWidth }
+
+func (m *SystemEvent) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(0)
+	w.time(m.Timestamp)
+	w.byte(m.EventCode)
+	return w.err
+}
+
+func (m *SystemEvent) decode(b []byte) {
+	r := reader{b: b}
+	r.uint16()
+	m.Timestamp = r.time()
+	m.EventCode = r.byte()
+}
+
+func (m *SymbolDirectory) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(m.Locate)
+	w.time(m.Timestamp)
+	w.alpha(m.Symbol, SymbolWidth)
+	return w.err
+}
+
+func (m *SymbolDirectory) decode(b []byte) {
+	r := reader{b: b}
+	m.Locate = r.uint16()
+	m.Timestamp = r.time()
+	m.Symbol = r.alpha(SymbolWidth)
+}
+
+func (m *AddOrder) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(m.Locate)
+	w.time(m.Timestamp)
+	w.uint64(m.OrderRef)
+	w.byte(m.Side)
+	w.uint32(m.Shares)
+	w.alpha(m.Symbol, SymbolWidth)
+	w.uint64(m.Price)
+	return w.err
+}
+
+func (m *AddOrder) decode(b []byte) {
+	r := reader{b: b}
+	m.Locate = r.uint16()
+	m.Timestamp = r.time()
+	m.OrderRef = r.uint64()
+	m.Side = r.byte()
+	m.Shares = r.uint32()
+	m.Symbol = r.alpha(SymbolWidth)
+	m.Price = r.uint64()
+}
+
+func (m *OrderExecuted) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(m.Locate)
+	w.time(m.Timestamp)
+	w.uint64(m.OrderRef)
+	w.uint32(m.ExecutedShares)
+	w.uint64(m.ExecutionPrice)
+	w.alpha(m.MatchNumber, MatchIDWidth)
+	return w.err
+}
+
+func (m *OrderExecuted) decode(b []byte) {
+	r := reader{b: b}
+	m.Locate = r.uint16()
+	m.Timestamp = r.time()
+	m.OrderRef = r.uint64()
+	m.ExecutedShares = r.uint32()
+	m.ExecutionPrice = r.uint64()
+	m.MatchNumber = r.alpha(MatchIDWidth)
+}
+
+func (m *OrderCancel) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(m.Locate)
+	w.time(m.Timestamp)
+	w.uint64(m.OrderRef)
+	w.uint32(m.CanceledShares)
+	return w.err
+}
+
+func (m *OrderCancel) decode(b []byte) {
+	r := reader{b: b}
+	m.Locate = r.uint16()
+	m.Timestamp = r.time()
+	m.OrderRef = r.uint64()
+	m.CanceledShares = r.uint32()
+}
+
+func (m *Trade) encode(b []byte) error {
+	w := writer{b: b}
+	w.uint16(m.Locate)
+	w.time(m.Timestamp)
+	w.byte(m.Side)
+	w.uint32(m.Shares)
+	w.alpha(m.Symbol, SymbolWidth)
+	w.uint64(m.Price)
+	w.alpha(m.MatchNumber, MatchIDWidth)
+	return w.err
+}
+
+func (m *Trade) decode(b []byte) {
+	r := reader{b: b}
+	m.Locate = r.uint16()
+	m.Timestamp = r.time()
+	m.Side = r.byte()
+	m.Shares = r.uint32()
+	m.Symbol = r.alpha(SymbolWidth)
+	m.Price = r.uint64()
+	m.MatchNumber = r.alpha(MatchIDWidth)
+}
+
+// Append appends m's frame, length prefix included, to dst. It fails if an
+// alpha field does not fit its width or is not printable ASCII.
+func Append(dst []byte, m Message) ([]byte, error) {
+	n := 1 + m.size()
+	start := len(dst)
+	dst = append(dst, make([]byte, 2+n)...)
+	frame := dst[start:]
+	binary.BigEndian.PutUint16(frame, uint16(n))
+	frame[2] = m.Type()
+	if err := m.encode(frame[3:]); err != nil {
+		return dst[:start], fmt.Errorf("itch: encode %c: %w", m.Type(), err)
+	}
+	return dst, nil
+}
+
+// Decode decodes one message from b, which holds the type byte and the
+// body but not the length prefix.
+func Decode(b []byte) (Message, error) {
+	if len(b) == 0 {
+		return nil, errors.New("itch: empty message")
+	}
+	m := newMessage(b[0])
+	if m == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, b[0])
+	}
+	if len(b) != 1+m.size() {
+		return nil, fmt.Errorf("itch: %c message length %d, want %d", b[0], len(b), 1+m.size())
+	}
+	m.decode(b[1:])
+	return m, nil
+}
+
+func newMessage(t byte) Message {
+	switch t {
+	case TypeSystemEvent:
+		return &SystemEvent{}
+	case TypeSymbolDirectory:
+		return &SymbolDirectory{}
+	case TypeAddOrder:
+		return &AddOrder{}
+	case TypeOrderExecuted:
+		return &OrderExecuted{}
+	case TypeOrderCancel:
+		return &OrderCancel{}
+	case TypeTrade:
+		return &Trade{}
+	}
+	return nil
+}
+
+// writer encodes fixed-width fields into b, remembering the first error.
+type writer struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (w *writer) alpha(s string, width int) {
+	if len(s) > width {
+		w.fail(fmt.Errorf("%q is longer than %d bytes", s, width))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			w.fail(fmt.Errorf("%q is not printable ASCII", s))
+			break
+		}
+	}
+	n := copy(w.b[w.off:w.off+width], s)
+	for i := w.off + n; i < w.off+width; i++ {
+		w.b[i] = ' '
+	}
+	w.off += width
+}
+
+func (w *writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *writer) byte(v byte) {
+	w.b[w.off] = v
+	w.off++
+}
+
+func (w *writer) uint16(v uint16) {
+	binary.BigEndian.PutUint16(w.b[w.off:], v)
+	w.off += 2
+}
+
+func (w *writer) uint32(v uint32) {
+	binary.BigEndian.PutUint32(w.b[w.off:], v)
+	w.off += 4
+}
+
+func (w *writer) uint64(v uint64) {
+	binary.BigEndian.PutUint64(w.b[w.off:], v)
+	w.off += 8
+}
+
+func (w *writer) time(t time.Time) {
+	var ns uint64
+	if !t.IsZero() {
+		ns = uint64(t.UnixNano())
+	}
+	w.uint64(ns)
+}
+
+// reader decodes fixed-width fields from b. Lengths are checked before
+// decoding, so it cannot run past the end.
+type reader struct {
+	b   []byte
+	off int
+}
+
+func (r *reader) alpha(width int) string {
+	s := strings.TrimRight(string(r.b[r.off:r.off+width]), " ")
+	r.off += width
+	return s
+}
+
+func (r *reader) byte() byte {
+	v := r.b[r.off]
+	r.off++
+	return v
+}
+
+func (r *reader) uint16() uint16 {
+	v := binary.BigEndian.Uint16(r.b[r.off:])
+	r.off += 2
+	return v
+}
+
+func (r *reader) uint32() uint32 {
+	v := binary.BigEndian.Uint32(r.b[r.off:])
+	r.off += 4
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	v := binary.BigEndian.Uint64(r.b[r.off:])
+	r.off += 8
+	return v
+}
+
+func (r *reader) time() time.Time {
+	ns := r.uint64()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns)).UTC()
+}
@@ -0,0 +1,101 @@
+package itch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC)
+	msgs := []Message{
+		&SystemEvent{Timestamp: ts, EventCode: EventStartOfMessages},
+		&SymbolDirectory{Locate: 1, Timestamp: ts, Symbol: "AAPL"},
+		&AddOrder{Locate: 1, Timestamp: ts, OrderRef: 7, Side: SideSell, Shares: 100, Symbol: "AAPL", Price: 15025},
+		&OrderExecuted{Locate: 1, Timestamp: ts, OrderRef: 7, ExecutedShares: 40, ExecutionPrice: 15025, MatchNumber: "0b4f3c1e-4a1a-4d8e-9d7e-2b1f1c0a9e11"},
+		&Trade{Locate: 1, Timestamp: ts, Side: SideBuy, Shares: 40, Symbol: "AAPL", Price: 15025, MatchNumber: "0b4f3c1e-4a1a-4d8e-9d7e-2b1f1c0a9e11"},
+		&OrderCancel{Locate: 1, Timestamp: ts, OrderRef: 7, CanceledShares: 60},
+		&SystemEvent{EventCode: EventEndOfMessages},
+	}
+	for _, want := range msgs {
+		b, err := Append(nil, want)
+		if err != nil {
+			t.Fatalf("append %T: %v", want, err)
+		}
+		if n := int(binary.BigEndian.Uint16(b)); n != len(b)-2 || n > MaxMessageSize {
+			t.Errorf("%T: length prefix %d for a %d-byte frame", want, n, len(b))
+		}
+		got, err := Decode(b[2:])
+		if err != nil {
+			t.Fatalf("decode %T: %v", want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestAppend_Layout(t *testing.T) {
+	b, err := Append(nil, &OrderCancel{Locate: 0x0102, OrderRef: 3, CanceledShares: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []byte{
+		0, 23, 'X',
+		1, 2, // locate
+		0, 0, 0, 0, 0, 0, 0, 0, // zero timestamp
+		0, 0, 0, 0, 0, 0, 0, 3, // order reference
+		0, 0, 0, 5, // shares
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("got %v, want %v", b, want)
+	}
+}
+
+func TestAppend_InvalidAlpha(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"too long", &SymbolDirectory{Symbol: strings.Repeat("X", SymbolWidth+1)}},
+		{"not printable", &SymbolDirectory{Symbol: "A\tB"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Append([]byte("keep"), tc.msg)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if string(b) != "keep" {
+				t.Errorf("dst = %q, want it unchanged", b)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		b     []byte
+		isErr error
+	}{
+		{"empty", nil, nil},
+		{"unknown type", []byte{'Q'}, ErrUnknownType},
+		{"length mismatch", []byte{'X', 0, 1}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(tc.b)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.isErr != nil && !errors.Is(err, tc.isErr) {
+				t.Errorf("got %v, want %v", err, tc.isErr)
+			}
+		})
+	}
+}
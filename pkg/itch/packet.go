@@ -0,0 +1,136 @@
+package itch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Packet layout. Every packet, whether a UDP datagram, a retransmission
+// answer or a block of the TCP replay stream, starts with a header naming
+// the session, the sequence number of its first message and how many
+// messages follow.
+const (
+	SessionWidth     = 10
+	PacketHeaderSize = SessionWidth + 8 + 2
+
+	// MaxPacketSize bounds the packets the exchange sends, header included,
+	// so that a datagram fits a standard Ethernet MTU.
+	MaxPacketSize = 1400
+
+	// EndOfSession is the Count of the packet that ends a session. No
+	// messages follow it.
+	EndOfSession uint16 = 0xFFFF
+)
+
+// PacketHeader is the header of a packet. A Count of zero is a heartbeat;
+// its Sequence is the sequence number of the next message. The same layout
+// is used for retransmission and replay requests, where Sequence is the
+// first message wanted and Count how many.
+type PacketHeader struct {
+	Session  string
+	Sequence uint64
+	Count    uint16
+}
+
+// AppendHeader appends h to dst.
+func AppendHeader(dst []byte, h PacketHeader) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, make([]byte, PacketHeaderSize)...)
+	w := writer{b: dst[start:]}
+	w.alpha(h.Session, SessionWidth)
+	w.uint64(h.Sequence)
+	w.uint16(h.Count)
+	if w.err != nil {
+		return dst[:start], fmt.Errorf("itch: encode header: %w", w.err)
+	}
+	return dst, nil
+}
+
+// ParseHeader decodes a header from the start of b.
+func ParseHeader(b []byte) (PacketHeader, error) {
+	if len(b) < PacketHeaderSize {
+		return PacketHeader{}, fmt.Errorf("itch: packet of %d bytes is shorter than its header", len(b))
+	}
+	r := reader{b: b}
+	return PacketHeader{
+		Session:  r.alpha(SessionWidth),
+		Sequence: r.uint64(),
+		Count:    r.uint16(),
+	}, nil
+}
+
+// ParsePacket decodes a whole packet, such as a UDP datagram.
+func ParsePacket(b []byte) (PacketHeader, []Message, error) {
+	h, err := ParseHeader(b)
+	if err != nil {
+		return h, nil, err
+	}
+	b = b[PacketHeaderSize:]
+	var msgs []Message
+	for i := 0; i < messageCount(h); i++ {
+		if len(b) < 2 {
+			return h, nil, fmt.Errorf("itch: packet truncated after %d of %d messages", i, h.Count)
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			return h, nil, fmt.Errorf("itch: packet truncated after %d of %d messages", i, h.Count)
+		}
+		m, err := Decode(b[2 : 2+n])
+		if err != nil {
+			return h, nil, err
+		}
+		msgs = append(msgs, m)
+		b = b[2+n:]
+	}
+	if len(b) != 0 {
+		return h, nil, fmt.Errorf("itch: %d bytes after the last message", len(b))
+	}
+	return h, msgs, nil
+}
+
+// ReadPacket reads one packet from a stream of packets, such as the TCP
+// replay service.
+func ReadPacket(r *bufio.Reader) (PacketHeader, []Message, error) {
+	var hdr [PacketHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return PacketHeader{}, nil, err
+	}
+	h, _ := ParseHeader(hdr[:])
+	var msgs []Message
+	var buf [MaxMessageSize]byte
+	for i := 0; i < messageCount(h); i++ {
+		if _, err := io.ReadFull(r, buf[:2]); err != nil {
+			return h, nil, unexpectedEOF(err)
+		}
+		n := int(binary.BigEndian.Uint16(buf[:2]))
+		if n < 1 || n > MaxMessageSize {
+			return h, nil, fmt.Errorf("itch: invalid message length %d", n)
+		}
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return h, nil, unexpectedEOF(err)
+		}
+		m, err := Decode(buf[:n])
+		if err != nil {
+			return h, nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return h, msgs, nil
+}
+
+// messageCount returns the number of messages that follow h.
+func messageCount(h PacketHeader) int {
+	if h.Count == EndOfSession {
+		return 0
+	}
+	return int(h.Count)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package itch
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func appendPacket(t *testing.T, dst []byte, h PacketHeader, msgs ...Message) []byte {
+	t.Helper()
+	dst, err := AppendHeader(dst, h)
+	if err != nil {
+		t.Fatalf("append header: %v", err)
+	}
+	for _, m := range msgs {
+		if dst, err = Append(dst, m); err != nil {
+			t.Fatalf("append %T: %v", m, err)
+		}
+	}
+	return dst
+}
+
+func TestParsePacket(t *testing.T) {
+	msgs := []Message{
+		&SymbolDirectory{Locate: 1, Symbol: "AAPL"},
+		&AddOrder{Locate: 1, OrderRef: 1, Side: SideBuy, Shares: 10, Symbol: "AAPL", Price: 100},
+	}
+	h := PacketHeader{Session: "1700000000", Sequence: 42, Count: 2}
+	b := appendPacket(t, nil, h, msgs...)
+
+	gotH, got, err := ParsePacket(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotH != h {
+		t.Errorf("got header %+v, want %+v", gotH, h)
+	}
+	if !reflect.DeepEqual(got, msgs) {
+		t.Errorf("got %+v, want %+v", got, msgs)
+	}
+
+	for _, bad := range [][]byte{
+		b[:PacketHeaderSize-1],
+		b[:len(b)-1],
+		append(append([]byte(nil), b...), 0),
+	} {
+		if _, _, err := ParsePacket(bad); err == nil {
+			t.Errorf("expected an error for a %d-byte packet", len(bad))
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	var b []byte
+	b = appendPacket(t, b, PacketHeader{Session: "s", Sequence: 1, Count: 1}, &SystemEvent{EventCode: EventStartOfMessages})
+	b = appendPacket(t, b, PacketHeader{Session: "s", Sequence: 2})
+	b = appendPacket(t, b, PacketHeader{Session: "s", Sequence: 2, Count: EndOfSession})
+	r := bufio.NewReader(bytes.NewReader(b))
+
+	for _, want := range []struct {
+		h PacketHeader
+		n int
+	}{
+		{PacketHeader{Session: "s", Sequence: 1, Count: 1}, 1},
+		{PacketHeader{Session: "s", Sequence: 2}, 0},
+		{PacketHeader{Session: "s", Sequence: 2, Count: EndOfSession}, 0},
+	} {
+		h, msgs, err := ReadPacket(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if h != want.h || len(msgs) != want.n {
+			t.Errorf("got %+v with %d messages, want %+v with %d", h, len(msgs), want.h, want.n)
+		}
+	}
+	if _, _, err := ReadPacket(r); err != io.EOF {
+		t.Errorf("expected io.EOF after the last packet, got %v", err)
+	}
+
+	truncated := appendPacket(t, nil, PacketHeader{Count: 2}, &SystemEvent{})
+	if _, _, err := ReadPacket(bufio.NewReader(bytes.NewReader(truncated))); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,179 @@
+package ouch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// HeartbeatInterval is how long either side may go without sending
+	// before it sends a Heartbeat.
+	HeartbeatInterval = time.Second
+
+	// IdleTimeout is how long either side waits without receiving anything
+	// before it considers the connection dead.
+	IdleTimeout = 15 * time.Second
+
+	// loginTimeout bounds the login handshake.
+	loginTimeout = 10 * time.Second
+
+	// writeTimeout bounds a single write.
+	writeTimeout = 10 * time.Second
+)
+
+// ErrClosed is returned when sending on a closed Client.
+var ErrClosed = errors.New("ouch: client closed")
+
+// LoginError is returned by Dial when the exchange rejects the login.
+type LoginError struct {
+	Reason byte
+}
+
+func (e *LoginError) Error() string {
+	switch e.Reason {
+	case LoginRejectNotAuthorized:
+		return "ouch: login rejected: not authorized"
+	case LoginRejectSessionActive:
+		return "ouch: login rejected: session already active"
+	}
+	return fmt.Sprintf("ouch: login rejected: reason %q", e.Reason)
+}
+
+// Client is a logged-on order entry session. Requests may be sent from any
+// goroutine; Recv must be called from one goroutine at a time. Heartbeats
+// are sent and consumed by the client.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu       sync.Mutex // serialises writes
+	lastSent time.Time
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Dial connects to the exchange at addr and logs on as brokerID with an API
+// key that may act for it.
+func Dial(addr, brokerID, apiKey string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, loginTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c, err := login(conn, brokerID, apiKey)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func login(conn net.Conn, brokerID, apiKey string) (*Client, error) {
+	c := &Client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		quit: make(chan struct{}),
+	}
+	if err := c.send(&LoginRequest{BrokerID: brokerID, APIKey: apiKey}); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(loginTimeout))
+	m, err := ReadServerMessage(c.r)
+	if err != nil {
+		return nil, err
+	}
+	switch m := m.(type) {
+	case *LoginAccepted:
+	case *LoginRejected:
+		return nil, &LoginError{Reason: m.Reason}
+	default:
+		return nil, fmt.Errorf("ouch: unexpected %c message during login", m.Type())
+	}
+
+	c.wg.Add(1)
+	go c.heartbeat()
+	return c, nil
+}
+
+// EnterOrder sends an EnterOrder request.
+func (c *Client) EnterOrder(m *EnterOrder) error { return c.send(m) }
+
+// ReplaceOrder sends a ReplaceOrder request.
+func (c *Client) ReplaceOrder(m *ReplaceOrder) error { return c.send(m) }
+
+// CancelOrder sends a CancelOrder request.
+func (c *Client) CancelOrder(m *CancelOrder) error { return c.send(m) }
+
+// Recv returns the next message from the exchange other than a Heartbeat.
+// It fails if the exchange is silent for longer than IdleTimeout. After an
+// EndOfSession the exchange closes the connection.
+func (c *Client) Recv() (Message, error) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		m, err := ReadServerMessage(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m.(*Heartbeat); !ok {
+			return m, nil
+		}
+	}
+}
+
+// Close logs out and closes the connection.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.wg.Wait()
+		c.send(&LogoutRequest{})
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// heartbeat keeps the session alive while the caller has nothing to send.
+func (c *Client) heartbeat() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(HeartbeatInterval / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.mu.Lock()
+			idle := now.Sub(c.lastSent) >= HeartbeatInterval
+			c.mu.Unlock()
+			if idle {
+				if err := c.send(&Heartbeat{}); err != nil {
+					return
+				}
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *Client) send(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.quit:
+		if _, ok := m.(*LogoutRequest); !ok {
+			return ErrClosed
+		}
+	default:
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := Write(c.conn, m); err != nil {
+		return err
+	}
+	c.lastSent = time.Now()
+	return nil
+}
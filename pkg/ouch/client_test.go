@@ -0,0 +1,141 @@
+package ouch
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeExchange accepts one connection and hands it to serve.
+func fakeExchange(t *testing.T, serve func(conn net.Conn, r *bufio.Reader)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		ln.Close()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		serve(conn, bufio.NewReader(conn))
+	}()
+	return ln.Addr().String()
+}
+
+func TestDial(t *testing.T) {
+	got := make(chan Message, 3)
+	addr := fakeExchange(t, func(conn net.Conn, r *bufio.Reader) {
+		login, _ := ReadClientMessage(r)
+		got <- login
+		Write(conn, &LoginAccepted{BrokerID: "broker1"})
+		Write(conn, &Heartbeat{})
+		Write(conn, &Rejected{Token: "t1", Reason: RejectInvalid})
+
+		cancel, _ := ReadClientMessage(r)
+		got <- cancel
+		// The client sends heartbeats while idle and a logout on close.
+		for {
+			m, err := ReadClientMessage(r)
+			if err != nil {
+				return
+			}
+			if _, ok := m.(*LogoutRequest); ok {
+				got <- m
+				return
+			}
+		}
+	})
+
+	c, err := Dial(addr, "broker1", "mx_key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := <-got; m.(*LoginRequest).BrokerID != "broker1" || m.(*LoginRequest).APIKey != "mx_key" {
+		t.Errorf("login = %+v", m)
+	}
+
+	// Heartbeats are consumed by Recv.
+	m, err := c.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if rej, ok := m.(*Rejected); !ok || rej.Token != "t1" {
+		t.Errorf("recv = %+v, want the Rejected", m)
+	}
+
+	if err := c.CancelOrder(&CancelOrder{Token: "t1"}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if m := <-got; m.(*CancelOrder).Token != "t1" {
+		t.Errorf("cancel = %+v", m)
+	}
+
+	c.Close()
+	select {
+	case m := <-got:
+		if _, ok := m.(*LogoutRequest); !ok {
+			t.Errorf("got %T, want LogoutRequest", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no logout received")
+	}
+	if err := c.EnterOrder(&EnterOrder{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("send after close = %v, want ErrClosed", err)
+	}
+}
+
+func TestDial_LoginRejected(t *testing.T) {
+	addr := fakeExchange(t, func(conn net.Conn, r *bufio.Reader) {
+		ReadClientMessage(r)
+		Write(conn, &LoginRejected{Reason: LoginRejectSessionActive})
+	})
+
+	_, err := Dial(addr, "broker1", "mx_key")
+	var le *LoginError
+	if !errors.As(err, &le) || le.Reason != LoginRejectSessionActive {
+		t.Fatalf("got %v, want a LoginError for an active session", err)
+	}
+}
+
+func TestClient_Heartbeats(t *testing.T) {
+	heartbeat := make(chan struct{}, 1)
+	addr := fakeExchange(t, func(conn net.Conn, r *bufio.Reader) {
+		ReadClientMessage(r)
+		Write(conn, &LoginAccepted{BrokerID: "broker1"})
+		for {
+			m, err := ReadClientMessage(r)
+			if err != nil {
+				return
+			}
+			if _, ok := m.(*Heartbeat); ok {
+				select {
+				case heartbeat <- struct{}{}:
+				default:
+				}
+			}
+		}
+	})
+
+	c, err := Dial(addr, "broker1", "mx_key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case <-heartbeat:
+	case <-time.After(3 * HeartbeatInterval):
+		t.Fatal("no heartbeat sent while idle")
+	}
+}
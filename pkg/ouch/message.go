@@ -0,0 +1,684 @@
+// Package ouch implements the exchange's binary order entry protocol, an
+// OUCH-style protocol of fixed-width messages over TCP, and a client for
+// it. The wire format is specified in design-documents/ouch-protocol.md.
+//
+// Every message is framed by a two-byte big-endian length that counts the
+// type byte and the body that follow it. Alpha fields are ASCII, left
+// justified and padded with spaces; integers are unsigned big-endian.
+// Prices are in cents and timestamps are nanoseconds since the Unix epoch.
+package ouch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Client → exchange message types.
+const (
+	TypeLoginRequest  byte = 'L'
+	TypeEnterOrder    byte = 'O'
+	TypeReplaceOrder  byte = 'U'
+	TypeCancelOrder   byte = 'X'
+	TypeHeartbeat     byte = 'H' // both directions
+	TypeLogoutRequest byte = 'Z'
+)
+
+// Exchange → client message types.
+const (
+	TypeLoginAccepted  byte = 'L'
+	TypeLoginRejected  byte = 'K'
+	TypeAccepted       byte = 'A'
+	TypeReplaced       byte = 'U'
+	TypeExecuted       byte = 'E'
+	TypeCanceled       byte = 'C'
+	TypeRejected       byte = 'J'
+	TypeCancelRejected byte = 'I'
+	TypeEndOfSession   byte = 'Z'
+)
+
+// Field widths of alpha fields.
+const (
+	BrokerIDWidth = 64
+	APIKeyWidth   = 64
+	TokenWidth    = 14
+	SymbolWidth   = 10
+	AccountWidth  = 32
+	OrderIDWidth  = 36
+	MatchIDWidth  = 36
+)
+
+// Order type values.
+const (
+	OrderTypeLimit  byte = 'L'
+	OrderTypeMarket byte = 'M'
+)
+
+// Side values.
+const (
+	SideBuy  byte = 'B'
+	SideSell byte = 'S'
+)
+
+// LoginRejected reasons.
+const (
+	LoginRejectNotAuthorized byte = 'A' // unknown broker, or an API key that may not act for it
+	LoginRejectSessionActive byte = 'S' // the broker is already logged on
+)
+
+// Canceled reasons.
+const (
+	CancelReasonUser    byte = 'U' // cancelled by a CancelOrder or ReplaceOrder
+	CancelReasonIOC     byte = 'I' // unfilled remainder of a market order
+	CancelReasonTimeout byte = 'T' // the limit order expired
+)
+
+// Rejected reasons.
+const (
+	RejectInvalid              byte = 'V' // failed validation
+	RejectDuplicateToken       byte = 'D'
+	RejectInsufficientBalance  byte = 'B'
+	RejectInsufficientHoldings byte = 'H'
+	RejectNoLiquidity          byte = 'L'
+	RejectOther                byte = 'O'
+)
+
+// CancelRejected reasons, for CancelOrder and ReplaceOrder.
+const (
+	CancelRejectUnknownToken byte = 'U'
+	CancelRejectTooLate      byte = 'T' // no longer open
+	CancelRejectInvalid      byte = 'V' // the replacement failed validation
+	CancelRejectOther        byte = 'O'
+)
+
+// MaxMessageSize is the largest frame payload (type byte and body) any
+// message uses.
+const MaxMessageSize = 1 + 128
+
+// ErrUnknownType is returned when decoding a message whose type byte is not
+// defined for the direction being read.
+var ErrUnknownType = errors.New("ouch: unknown message type")
+
+// Message is a single protocol message.
+type Message interface {
+	// Type returns the message's type byte.
+	Type() byte
+
+	size() int
+	encode(b []byte) error
+	decode(b []byte)
+}
+
+// LoginRequest must be the first message on a connection. APIKey is a key
+// that may act for the broker, as on the REST API.
+type LoginRequest struct {
+	BrokerID string
+	APIKey   string
+}
+
+// EnterOrder submits a new order. Limit orders carry a price and a time in
+// force, in seconds, after which they expire; market orders carry neither.
+type EnterOrder struct {
+	Token       string
+	OrderType   byte
+	Side        byte
+	Shares      uint32
+	Symbol      string
+	Price       uint64
+	TimeInForce uint32
+	Account     string // the document number
+}
+
+// ReplaceOrder cancels an open limit order and enters a replacement under a
+// new token. Shares is the new total quantity, including what the order
+// (and any order it replaced) has already executed. A TimeInForce of zero
+// keeps the original expiry.
+type ReplaceOrder struct {
+	ExistingToken    string
+	ReplacementToken string
+	Shares           uint32
+	Price            uint64
+	TimeInForce      uint32
+}
+
+// CancelOrder cancels an open order.
+type CancelOrder struct {
+	Token string
+}
+
+// Heartbeat is sent by either side after a second without other traffic.
+type Heartbeat struct{}
+
+// LogoutRequest ends the session.
+type LogoutRequest struct{}
+
+// LoginAccepted answers a valid LoginRequest.
+type LoginAccepted struct {
+	BrokerID string
+}
+
+// LoginRejected answers an invalid LoginRequest; the connection is closed
+// right after.
+type LoginRejected struct {
+	Reason byte
+}
+
+// Accepted acknowledges an order. OrderID is the exchange order ID used by
+// the REST and gRPC APIs.
+type Accepted struct {
+	Timestamp   time.Time
+	Token       string
+	OrderType   byte
+	Side        byte
+	Shares      uint32
+	Symbol      string
+	Price       uint64
+	TimeInForce uint32
+	Account     string
+	OrderID     string
+}
+
+// Replaced acknowledges a ReplaceOrder. Shares is the replacement's open
+// quantity; the previous token is no longer valid.
+type Replaced struct {
+	Timestamp        time.Time
+	ReplacementToken string
+	Shares           uint32
+	Price            uint64
+	TimeInForce      uint32
+	OrderID          string
+	PreviousToken    string
+}
+
+// Executed reports a fill.
+type Executed struct {
+	Timestamp      time.Time
+	Token          string
+	ExecutedShares uint32
+	ExecutionPrice uint64
+	MatchNumber    string // the trade ID
+}
+
+// Canceled reports that an order's open shares were cancelled.
+type Canceled struct {
+	Timestamp       time.Time
+	Token           string
+	DecrementShares uint32
+	Reason          byte
+}
+
+// Rejected reports an EnterOrder that did not become an order.
+type Rejected struct {
+	Timestamp time.Time
+	Token     string
+	Reason    byte
+}
+
+// CancelRejected reports a CancelOrder or ReplaceOrder that could not be
+// applied. Token is the token the request referred to.
+type CancelRejected struct {
+	Timestamp time.Time
+	Token     string
+	Reason    byte
+}
+
+// EndOfSession is sent before the exchange closes the connection, in
+// answer to a LogoutRequest or on shutdown.
+type EndOfSession struct{}
+
+func (*LoginRequest) Type() byte   { return TypeLoginRequest }
+func (*EnterOrder) Type() byte     { return TypeEnterOrder }
+func (*ReplaceOrder) Type() byte   { return TypeReplaceOrder }
+func (*CancelOrder) Type() byte    { return TypeCancelOrder }
+func (*Heartbeat) Type() byte      { return TypeHeartbeat }
+func (*LogoutRequest) Type() byte  { return TypeLogoutRequest }
+func (*LoginAccepted) Type() byte  { return TypeLoginAccepted }
+func (*LoginRejected) Type() byte  { return TypeLoginRejected }
+func (*Accepted) Type() byte       { return TypeAccepted }
+func (*Replaced) Type() byte       { return TypeReplaced }
+func (*Executed) Type() byte       { return TypeExecuted }
+func (*Canceled) Type() byte       { return TypeCanceled }
+func (*Rejected) Type() byte       { return TypeRejected }
+func (*CancelRejected) Type() byte { return TypeCancelRejected }
+func (*EndOfSession) Type() byte   { return TypeEndOfSession }
+
+func (*LoginRequest) size() int  { return BrokerIDWidth + APIKeyWidth }
+func (*EnterOrder) size() int    { return TokenWidth + 1 + 1 + 4 + SymbolWidth + 8 + 4 + AccountWidth }
+func (*ReplaceOrder) size() int  { return 2*TokenWidth + 4 + 8 + 4 }
+func (*CancelOrder) size() int   { return TokenWidth }
+func (*Heartbeat) size() int     { return 0 }
+func (*LogoutRequest) size() int { return 0 }
+func (*LoginAccepted) size() int { return BrokerIDWidth }
+func (*LoginRejected) size() int { return 1 }
+func (*Accepted) size() int {
+	return 8 + TokenWidth + 1 + 1 + 4 + SymbolWidth + 8 + 4 + AccountWidth + OrderIDWidth
+}
+func (*Replaced) size() int       { return 8 + TokenWidth + 4 + 8 + 4 + OrderIDWidth + TokenWidth }
+func (*Executed) size() int       { return 8 + TokenWidth + 4 + 8 + MatchIDWidth }
+func (*Canceled) size() int       { return 8 + TokenWidth + 4 + 1 }
+func (*Rejected) size() int       { return 8 + TokenWidth + 1 }
+func (*CancelRejected) size() int { return 8 + TokenWidth + 1 }
+func (*EndOfSession) size() int   { return 0 }
+
+func (m *LoginRequest) encode(b []byte) error {
+	w := writer{b: b}
+	w.alpha(m.BrokerID, BrokerIDWidth)
+	w.alpha(m.APIKey, APIKeyWidth)
+	return w.err
+}
+
+func (m *LoginRequest) decode(b []byte) {
+	r := reader{b: b}
+	m.BrokerID = r.alpha(BrokerIDWidth)
+	m.APIKey = r.alpha(APIKeyWidth)
+}
+
+func (m *EnterOrder) encode(b []byte) error {
+	w := writer{b: b}
+	w.alpha(m.Token, TokenWidth)
+	w.byte(m.OrderType)
+	w.byte(m.Side)
+	w.uint32(m.Shares)
+	w.alpha(m.Symbol, SymbolWidth)
+	w.uint64(m.Price)
+	w.uint32(m.TimeInForce)
+	w.alpha(m.Account, AccountWidth)
+	return w.err
+}
+
+func (m *EnterOrder) decode(b []byte) {
+	r := reader{b: b}
+	m.Token = r.alpha(TokenWidth)
+	m.OrderType = r.byte()
+	m.Side = r.byte()
+	m.Shares = r.uint32()
+	m.Symbol = r.alpha(SymbolWidth)
+	m.Price = r.uint64()
+	m.TimeInForce = r.uint32()
+	m.Account = r.alpha(AccountWidth)
+}
+
+func (m *ReplaceOrder) encode(b []byte) error {
+	w := writer{b: b}
+	w.alpha(m.ExistingToken, TokenWidth)
+	w.alpha(m.ReplacementToken, TokenWidth)
+	w.uint32(m.Shares)
+	w.uint64(m.Price)
+	w.uint32(m.TimeInForce)
+	return w.err
+}
+
+func (m *ReplaceOrder) decode(b []byte) {
+	r := reader{b: b}
+	m.ExistingToken = r.alpha(TokenWidth)
+	m.ReplacementToken = r.alpha(TokenWidth)
+	m.Shares = r.uint32()
+	m.Price = r.uint64()
+	m.TimeInForce = r.uint32()
+}
+
+func (m *CancelOrder) encode(b []byte) error {
+	w := writer{b: b}
+	w.alpha(m.Token, TokenWidth)
+	return w.err
+}
+
+func (m *CancelOrder) decode(b []byte) {
+	r := reader{b: b}
+	m.Token = r.alpha(TokenWidth)
+}
+
+func (*Heartbeat) encode([]byte) error     { return nil }
+func (*Heartbeat) decode([]byte)           {}
+func (*LogoutRequest) encode([]byte) error { return nil }
+func (*LogoutRequest) decode([]byte)       {}
+func (*EndOfSession) encode([]byte) error  { return nil }
+func (*EndOfSession) decode([]byte)        {}
+
+func (m *LoginAccepted) encode(b []byte) error {
+	w := writer{b: b}
+	w.alpha(m.BrokerID, BrokerIDWidth)
+	return w.err
+}
+
+func (m *LoginAccepted) decode(b []byte) {
+	r := reader{b: b}
+	m.BrokerID = r.alpha(BrokerIDWidth)
+}
+
+func (m *LoginRejected) encode(b []byte) error {
+	b[0] = m.Reason
+	return nil
+}
+
+func (m *LoginRejected) decode(b []byte) {
+	m.Reason = b[0]
+}
+
+func (m *Accepted) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.Token, TokenWidth)
+	w.byte(m.OrderType)
+	w.byte(m.Side)
+	w.uint32(m.Shares)
+	w.alpha(m.Symbol, SymbolWidth)
+	w.uint64(m.Price)
+	w.uint32(m.TimeInForce)
+	w.alpha(m.Account, AccountWidth)
+	w.alpha(m.OrderID, OrderIDWidth)
+	return w.err
+}
+
+func (m *Accepted) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.Token = r.alpha(TokenWidth)
+	m.OrderType = r.byte()
+	m.Side = r.byte()
+	m.Shares = r.uint32()
+	m.Symbol = r.alpha(SymbolWidth)
+	m.Price = r.uint64()
+	m.TimeInForce = r.uint32()
+	m.Account = r.alpha(AccountWidth)
+	m.OrderID = r.alpha(OrderIDWidth)
+}
+
+func (m *Replaced) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.ReplacementToken, TokenWidth)
+	w.uint32(m.Shares)
+	w.uint64(m.Price)
+	w.uint32(m.TimeInForce)
+	w.alpha(m.OrderID, OrderIDWidth)
+	w.alpha(m.PreviousToken, TokenWidth)
+	return w.err
+}
+
+func (m *Replaced) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.ReplacementToken = r.alpha(TokenWidth)
+	m.Shares = r.uint32()
+	m.Price = r.uint64()
+	m.TimeInForce = r.uint32()
+	m.OrderID = r.alpha(OrderIDWidth)
+	m.PreviousToken = r.alpha(TokenWidth)
+}
+
+func (m *Executed) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.Token, TokenWidth)
+	w.uint32(m.ExecutedShares)
+	w.uint64(m.ExecutionPrice)
+	w.alpha(m.MatchNumber, MatchIDWidth)
+	return w.err
+}
+
+func (m *Executed) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.Token = r.alpha(TokenWidth)
+	m.ExecutedShares = r.uint32()
+	m.ExecutionPrice = r.uint64()
+	m.MatchNumber = r.alpha(MatchIDWidth)
+}
+
+func (m *Canceled) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.Token, TokenWidth)
+	w.uint32(m.DecrementShares)
+	w.byte(m.Reason)
+	return w.err
+}
+
+func (m *Canceled) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.Token = r.alpha(TokenWidth)
+	m.DecrementShares = r.uint32()
+	m.Reason = r.byte()
+}
+
+func (m *Rejected) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.Token, TokenWidth)
+	w.byte(m.Reason)
+	return w.err
+}
+
+func (m *Rejected) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.Token = r.alpha(TokenWidth)
+	m.Reason = r.byte()
+}
+
+func (m *CancelRejected) encode(b []byte) error {
+	w := writer{b: b}
+	w.time(m.Timestamp)
+	w.alpha(m.Token, TokenWidth)
+	w.byte(m.Reason)
+	return w.err
+}
+
+func (m *CancelRejected) decode(b []byte) {
+	r := reader{b: b}
+	m.Timestamp = r.time()
+	m.Token = r.alpha(TokenWidth)
+	m.Reason = r.byte()
+}
+
+// Append appends m's frame, length prefix included, to dst. It fails if an
+// alpha field does not fit its width or is not printable ASCII.
+func Append(dst []byte, m Message) ([]byte, error) {
+	n := 1 + m.size()
+	start := len(dst)
+	dst = append(dst, make([]byte, 2+n)...)
+	frame := dst[start:]
+	binary.BigEndian.PutUint16(frame, uint16(n))
+	frame[2] = m.Type()
+	if err := m.encode(frame[3:]); err != nil {
+		return dst[:start], fmt.Errorf("ouch: encode %c: %w", m.Type(), err)
+	}
+	return dst, nil
+}
+
+// Write writes m's frame to w.
+func Write(w io.Writer, m Message) error {
+	b, err := Append(make([]byte, 0, 2+1+m.size()), m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ReadClientMessage reads one client → exchange message.
+func ReadClientMessage(r *bufio.Reader) (Message, error) {
+	return read(r, newClientMessage)
+}
+
+// ReadServerMessage reads one exchange → client message.
+func ReadServerMessage(r *bufio.Reader) (Message, error) {
+	return read(r, newServerMessage)
+}
+
+func newClientMessage(t byte) Message {
+	switch t {
+	case TypeLoginRequest:
+		return &LoginRequest{}
+	case TypeEnterOrder:
+		return &EnterOrder{}
+	case TypeReplaceOrder:
+		return &ReplaceOrder{}
+	case TypeCancelOrder:
+		return &CancelOrder{}
+	case TypeHeartbeat:
+		return &Heartbeat{}
+	case TypeLogoutRequest:
+		return &LogoutRequest{}
+	}
+	return nil
+}
+
+func newServerMessage(t byte) Message {
+	switch t {
+	case TypeLoginAccepted:
+		return &LoginAccepted{}
+	case TypeLoginRejected:
+		return &LoginRejected{}
+	case TypeAccepted:
+		return &Accepted{}
+	case TypeReplaced:
+		return &Replaced{}
+	case TypeExecuted:
+		return &Executed{}
+	case TypeCanceled:
+		return &Canceled{}
+	case TypeRejected:
+		return &Rejected{}
+	case TypeCancelRejected:
+		return &CancelRejected{}
+	case TypeHeartbeat:
+		return &Heartbeat{}
+	case TypeEndOfSession:
+		return &EndOfSession{}
+	}
+	return nil
+}
+
+// read reads one frame and decodes it with the message newMsg returns for
+// its type. A frame whose length does not match its type is an error: the
+// stream cannot be trusted after it.
+func read(r *bufio.Reader, newMsg func(byte) Message) (Message, error) {
+	var hdr [3]byte
+	if _, err := io.ReadFull(r, hdr[:2]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[:2]))
+	if n < 1 || n > MaxMessageSize {
+		return nil, fmt.Errorf("ouch: invalid frame length %d", n)
+	}
+	var buf [MaxMessageSize]byte
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	m := newMsg(buf[0])
+	if m == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, buf[0])
+	}
+	if n != 1+m.size() {
+		return nil, fmt.Errorf("ouch: %c frame length %d, want %d", buf[0], n, 1+m.size())
+	}
+	m.decode(buf[1:n])
+	return m, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// writer encodes fixed-width fields into b, remembering the first error.
+type writer struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (w *writer) alpha(s string, width int) {
+	if len(s) > width {
+		w.fail(fmt.Errorf("%q is longer than %d bytes", s, width))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			w.fail(fmt.Errorf("%q is not printable ASCII", s))
+			break
+		}
+	}
+	n := copy(w.b[w.off:w.off+width], s)
+	for i := w.off + n; i < w.off+width; i++ {
+		w.b[i] = ' '
+	}
+	w.off += width
+}
+
+func (w *writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *writer) byte(v byte) {
+	w.b[w.off] = v
+	w.off++
+}
+
+func (w *writer) uint32(v uint32) {
+	binary.BigEndian.PutUint32(w.b[w.off:], v)
+	w.off += 4
+}
+
+func (w *writer) uint64(v uint64) {
+	binary.BigEndian.PutUint64(w.b[w.off:], v)
+	w.off += 8
+}
+
+func (w *writer) time(t time.Time) {
+	var ns uint64
+	if !t.IsZero() {
+		ns = uint64(t.UnixNano())
+	}
+	w.uint64(ns)
+}
+
+// reader decodes fixed-width fields from b. Lengths are checked before
+// decoding, so it cannot run past the end.
+type reader struct {
+	b   []byte
+	off int
+}
+
+func (r *reader) alpha(width int) string {
+	s := strings.TrimRight(string(r.b[r.off:r.off+width]), " ")
+	r.off += width
+	return s
+}
+
+func (r *reader) byte() byte {
+	v := r.b[r.off]
+	r.off++
+	return v
+}
+
+func (r *reader) uint32() uint32 {
+	v := binary.BigEndian.Uint32(r.b[r.off:])
+	r.off += 4
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	v := binary.BigEndian.Uint64(r.b[r.off:])
+	r.off += 8
+	return v
+}
+
+func (r *reader) time() time.Time {
+	ns := r.uint64()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns)).UTC()
+}
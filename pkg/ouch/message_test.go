@@ -0,0 +1,138 @@
+package ouch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC)
+	client := []Message{
+		&LoginRequest{BrokerID: "broker-1", APIKey: "mx_Zk3cQ0uY2Yt8m1eV7PpN4xWb9sLh6aRj5oTi0nKd2qE"},
+		&EnterOrder{Token: "t1", OrderType: OrderTypeLimit, Side: SideBuy, Shares: 100, Symbol: "AAPL", Price: 15025, TimeInForce: 3600, Account: "12345678901"},
+		&ReplaceOrder{ExistingToken: "t1", ReplacementToken: "t2", Shares: 200, Price: 15000},
+		&CancelOrder{Token: "t2"},
+		&Heartbeat{},
+		&LogoutRequest{},
+	}
+	server := []Message{
+		&LoginAccepted{BrokerID: "broker-1"},
+		&LoginRejected{Reason: LoginRejectNotAuthorized},
+		&Accepted{Timestamp: ts, Token: "t1", OrderType: OrderTypeMarket, Side: SideSell, Shares: 5, Symbol: "AAPL", Account: "1", OrderID: "0b4f3c1e-4a1a-4d8e-9d7e-2b1f1c0a9e11"},
+		&Replaced{Timestamp: ts, ReplacementToken: "t2", Shares: 7, Price: 1, TimeInForce: 60, OrderID: "o2", PreviousToken: "t1"},
+		&Executed{Timestamp: ts, Token: "t2", ExecutedShares: 3, ExecutionPrice: 15000, MatchNumber: "m1"},
+		&Canceled{Timestamp: ts, Token: "t2", DecrementShares: 4, Reason: CancelReasonUser},
+		&Rejected{Timestamp: ts, Token: "t3", Reason: RejectNoLiquidity},
+		&CancelRejected{Timestamp: ts, Token: "t4", Reason: CancelRejectTooLate},
+		&Heartbeat{},
+		&EndOfSession{},
+	}
+
+	for _, tc := range []struct {
+		name string
+		msgs []Message
+		read func(*bufio.Reader) (Message, error)
+	}{
+		{"client", client, ReadClientMessage},
+		{"server", server, ReadServerMessage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			for _, m := range tc.msgs {
+				if err := Write(&buf, m); err != nil {
+					t.Fatalf("write %T: %v", m, err)
+				}
+			}
+			r := bufio.NewReader(&buf)
+			for _, want := range tc.msgs {
+				got, err := tc.read(r)
+				if err != nil {
+					t.Fatalf("read %T: %v", want, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+			}
+			if _, err := tc.read(r); err != io.EOF {
+				t.Errorf("expected io.EOF after the last message, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAppend_Layout(t *testing.T) {
+	b, err := Append(nil, &CancelOrder{Token: "abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := append([]byte{0, 15, 'X'}, []byte("abc           ")...)
+	if !bytes.Equal(b, want) {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	b, err = Append(nil, &EnterOrder{Token: "t", OrderType: OrderTypeLimit, Side: SideBuy, Shares: 1, Symbol: "AAPL", Price: 0x0102, TimeInForce: 1, Account: "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b) != 2+1+74 {
+		t.Fatalf("frame length = %d, want %d", len(b), 2+1+74)
+	}
+	// Price follows token (14), type, side, shares (4) and symbol (10).
+	price := b[3+14+1+1+4+10:][:8]
+	if !bytes.Equal(price, []byte{0, 0, 0, 0, 0, 0, 1, 2}) {
+		t.Errorf("price bytes = %v", price)
+	}
+}
+
+func TestAppend_InvalidAlpha(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"too long", &CancelOrder{Token: strings.Repeat("x", TokenWidth+1)}},
+		{"not printable", &CancelOrder{Token: "a\nb"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Append([]byte("keep"), tc.msg)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if string(b) != "keep" {
+				t.Errorf("dst = %q, want it unchanged", b)
+			}
+		})
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		isErr error
+	}{
+		{"unknown type", []byte{0, 1, 'Q'}, ErrUnknownType},
+		{"server type on client stream", []byte{0, 1, TypeAccepted}, ErrUnknownType},
+		{"length mismatch", []byte{0, 2, 'X', ' '}, nil},
+		{"zero length", []byte{0, 0}, nil},
+		{"too long", []byte{0xff, 0xff}, nil},
+		{"truncated", []byte{0, 15, 'X', 'a'}, io.ErrUnexpectedEOF},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadClientMessage(bufio.NewReader(bytes.NewReader(tc.frame)))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.isErr != nil && !errors.Is(err, tc.isErr) {
+				t.Errorf("got %v, want %v", err, tc.isErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+func signedHeader(secret, deliveryID string, ts time.Time, body []byte) http.Header {
+	h := http.Header{}
+	h.Set(HeaderDeliveryID, deliveryID)
+	h.Set(HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+	h.Set(HeaderSignature, Sign(secret, deliveryID, ts.Unix(), body))
+	return h
+}
+
+func TestSign_KnownValue(t *testing.T) {
+	// HMAC-SHA256("whsec_test", "d1.1700000000.{}"), computed independently.
+	got := Sign(testSecret, "d1", 1700000000, []byte("{}"))
+	want := "v1=9d3fb219def634b290b5d05f62dd904229477fde9efa2551399ced8ae86cd523"
+	if got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+	for name, other := range map[string]string{
+		"secret":    Sign("whsec_other", "d1", 1700000000, []byte("{}")),
+		"delivery":  Sign(testSecret, "d2", 1700000000, []byte("{}")),
+		"timestamp": Sign(testSecret, "d1", 1700000001, []byte("{}")),
+		"body":      Sign(testSecret, "d1", 1700000000, []byte("{ }")),
+	} {
+		if other == got {
+			t.Errorf("changing the %s did not change the signature", name)
+		}
+	}
+}
+
+func TestVerify_Valid(t *testing.T) {
+	body := []byte(`{"event":"trade.executed"}`)
+	h := signedHeader(testSecret, "d1", time.Now(), body)
+	if err := Verify(testSecret, h, body, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestVerify_AnyOfSeveralSignatures(t *testing.T) {
+	body := []byte(`{}`)
+	now := time.Now()
+	h := signedHeader("whsec_new", "d1", now, body)
+	h.Set(HeaderSignature, h.Get(HeaderSignature)+", "+Sign("whsec_old", "d1", now.Unix(), body))
+
+	for _, secret := range []string{"whsec_new", "whsec_old"} {
+		if err := Verify(secret, h, body, 0); err != nil {
+			t.Errorf("secret %s: unexpected error: %v", secret, err)
+		}
+	}
+	if err := Verify("whsec_other", h, body, 0); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("other secret: expected ErrSignatureMismatch, got %v", err)
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	body := []byte(`{"event":"trade.executed"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		header func() http.Header
+		body   []byte
+		want   error
+	}{
+		{"tampered body", func() http.Header { return signedHeader(testSecret, "d1", now, body) }, []byte(`{"event":"order.expired"}`), ErrSignatureMismatch},
+		{"wrong secret", func() http.Header { return signedHeader("whsec_other", "d1", now, body) }, body, ErrSignatureMismatch},
+		{"swapped delivery ID", func() http.Header {
+			h := signedHeader(testSecret, "d1", now, body)
+			h.Set(HeaderDeliveryID, "d2")
+			return h
+		}, body, ErrSignatureMismatch},
+		{"old timestamp", func() http.Header { return signedHeader(testSecret, "d1", now.Add(-10*time.Minute), body) }, body, ErrTimestampOutOfRange},
+		{"future timestamp", func() http.Header { return signedHeader(testSecret, "d1", now.Add(10*time.Minute), body) }, body, ErrTimestampOutOfRange},
+		{"bad timestamp", func() http.Header {
+			h := signedHeader(testSecret, "d1", now, body)
+			h.Set(HeaderTimestamp, "yesterday")
+			return h
+		}, body, ErrInvalidTimestamp},
+		{"missing signature", func() http.Header {
+			h := signedHeader(testSecret, "d1", now, body)
+			h.Del(HeaderSignature)
+			return h
+		}, body, ErrMissingHeader},
+		{"unknown version", func() http.Header {
+			h := signedHeader(testSecret, "d1", now, body)
+			h.Set(HeaderSignature, strings.Replace(h.Get(HeaderSignature), "v1=", "v0=", 1))
+			return h
+		}, body, ErrSignatureMismatch},
+		{"not hex", func() http.Header {
+			h := signedHeader(testSecret, "d1", now, body)
+			h.Set(HeaderSignature, "v1=zz")
+			return h
+		}, body, ErrSignatureMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(testSecret, tt.header(), tt.body, 0); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerify_CustomTolerance(t *testing.T) {
+	body := []byte(`{}`)
+	h := signedHeader(testSecret, "d1", time.Now().Add(-10*time.Minute), body)
+	if err := Verify(testSecret, h, body, time.Hour); err != nil {
+		t.Errorf("unexpected error with a one hour tolerance: %v", err)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"event":"order.cancelled"}`
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	for k, v := range signedHeader(testSecret, "d1", time.Now(), []byte(body)) {
+		r.Header[k] = v
+	}
+
+	got, err := VerifyRequest(r, testSecret, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+	again := make([]byte, len(body))
+	if n, _ := r.Body.Read(again); string(again[:n]) != body {
+		t.Errorf("r.Body should be readable again, got %q", again[:n])
+	}
+}
+
+func TestVerifyRequest_TooLarge(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(strings.Repeat("x", maxBodySize+1)))
+	if _, err := VerifyRequest(r, testSecret, 0); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+}